@@ -0,0 +1,191 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package pxlcompiler
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/gofrs/uuid"
+	"github.com/gogo/protobuf/jsonpb"
+	"github.com/gogo/protobuf/proto"
+	"github.com/gogo/protobuf/types"
+
+	"px.dev/pixie/src/api/proto/uuidpb"
+	"px.dev/pixie/src/carnot/goplanner"
+	"px.dev/pixie/src/carnot/planner/compilerpb"
+	"px.dev/pixie/src/carnot/planner/distributedpb"
+	"px.dev/pixie/src/carnot/planner/plannerpb"
+	"px.dev/pixie/src/carnot/udfspb"
+	"px.dev/pixie/src/e2e_test/vizier/planner/dump_schemas/godumpschemas"
+	"px.dev/pixie/src/table_store/schemapb"
+	"px.dev/pixie/src/utils"
+	funcs "px.dev/pixie/src/vizier/funcs/go"
+)
+
+// ErrPlannerUnavailable is returned when scripts are compiled by a binary that was built without cgo.
+var ErrPlannerUnavailable = errors.New("compiling scripts requires a binary built with cgo, which links the query planner")
+
+// CompileError is an error returned by the PxL compiler.
+type CompileError struct {
+	Line    int
+	Column  int
+	Message string
+}
+
+// Compiler compiles PxL scripts.
+type Compiler interface {
+	// Compile compiles the request and returns the compilation errors, if any. The returned error
+	// is set if the compiler itself failed.
+	Compile(req *plannerpb.QueryRequest) ([]*CompileError, error)
+}
+
+// LoadSchema loads the table schemas that scripts are compiled against. If path is empty, the
+// schemas of the data tables that Stirling collects are used. Otherwise the schemas are read from
+// the given file, which is either a binary or a JSON schemapb.Schema.
+func LoadSchema(path string) (*schemapb.Schema, error) {
+	if path == "" {
+		if !PlannerAvailable {
+			return nil, ErrPlannerUnavailable
+		}
+		return godumpschemas.DumpSchemas()
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	schema := &schemapb.Schema{}
+	if filepath.Ext(path) == ".json" {
+		err = jsonpb.Unmarshal(bytes.NewReader(b), schema)
+	} else {
+		err = proto.Unmarshal(b, schema)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema file %s: %w", path, err)
+	}
+	return schema, nil
+}
+
+func loadUDFInfo() (*udfspb.UDFInfo, error) {
+	b, err := funcs.Asset("src/vizier/funcs/data/udf.pb")
+	if err != nil {
+		return nil, err
+	}
+	udfInfo := &udfspb.UDFInfo{}
+	if err := proto.Unmarshal(b, udfInfo); err != nil {
+		return nil, err
+	}
+	return udfInfo, nil
+}
+
+// PlannerCompiler compiles scripts with the query planner, against a cluster with a single PEM and
+// Kelvin that have the given tables.
+type PlannerCompiler struct {
+	planner goplanner.GoPlanner
+	state   *distributedpb.LogicalPlannerState
+}
+
+// NewPlannerCompiler creates a compiler that compiles scripts against the given schema. The
+// compiler must be closed once it's no longer used. Returns ErrPlannerUnavailable if the binary was
+// built without cgo.
+func NewPlannerCompiler(schema *schemapb.Schema) (*PlannerCompiler, error) {
+	if !PlannerAvailable {
+		return nil, ErrPlannerUnavailable
+	}
+	udfInfo, err := loadUDFInfo()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load UDF info: %w", err)
+	}
+	planner, err := goplanner.New(udfInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	pemID := uuid.Must(uuid.NewV4())
+	kelvinID := uuid.Must(uuid.NewV4())
+	ds := &distributedpb.DistributedState{
+		CarnotInfo: []*distributedpb.CarnotInfo{
+			{
+				HasDataStore:       true,
+				ProcessesData:      true,
+				AgentID:            utils.ProtoFromUUID(pemID),
+				QueryBrokerAddress: "pem",
+			},
+			{
+				HasGRPCServer:        true,
+				ProcessesData:        true,
+				AcceptsRemoteSources: true,
+				AgentID:              utils.ProtoFromUUID(kelvinID),
+				QueryBrokerAddress:   "kelvin",
+				GRPCAddress:          "kelvin",
+				SSLTargetName:        "kelvin",
+			},
+		},
+	}
+	for name, rel := range schema.RelationMap {
+		ds.SchemaInfo = append(ds.SchemaInfo, &distributedpb.SchemaInfo{
+			Name:      name,
+			Relation:  rel,
+			AgentList: []*uuidpb.UUID{utils.ProtoFromUUID(pemID)},
+		})
+	}
+
+	return &PlannerCompiler{
+		planner: planner,
+		state: &distributedpb.LogicalPlannerState{
+			DistributedState:    ds,
+			ResultAddress:       "result",
+			ResultSSLTargetName: "result",
+		},
+	}, nil
+}
+
+// Compile implements the Compiler interface.
+func (c *PlannerCompiler) Compile(req *plannerpb.QueryRequest) ([]*CompileError, error) {
+	req.LogicalPlannerState = c.state
+	res, err := c.planner.Plan(req)
+	if err != nil {
+		return nil, err
+	}
+	if res.Status == nil || res.Status.ErrCode == 0 {
+		return nil, nil
+	}
+	errGroup := &compilerpb.CompilerErrorGroup{}
+	if res.Status.Context == nil || types.UnmarshalAny(res.Status.Context, errGroup) != nil {
+		return []*CompileError{{Message: res.Status.Msg}}, nil
+	}
+	var errs []*CompileError
+	for _, e := range errGroup.Errors {
+		if lc := e.GetLineColError(); lc != nil {
+			errs = append(errs, &CompileError{Line: int(lc.Line), Column: int(lc.Column), Message: lc.Message})
+		}
+	}
+	if len(errs) == 0 {
+		errs = append(errs, &CompileError{Message: res.Status.Msg})
+	}
+	return errs, nil
+}
+
+// Close frees the planner.
+func (c *PlannerCompiler) Close() {
+	c.planner.Free()
+}
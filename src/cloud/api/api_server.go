@@ -66,6 +66,13 @@ func init() {
 
 	pflag.String("auth_connector_name", "", "If any, the name of the auth connector to be used with Pixie")
 	pflag.String("auth_connector_callback_url", "", "If any, the callback URL for the auth connector")
+
+	pflag.Int("ptproxy_max_concurrent_queries_per_org", 100, "The maximum number of queries an org can run at once, 0 for no limit")
+	pflag.Int("ptproxy_max_concurrent_queries_per_cluster", 30, "The maximum number of queries that can run on a cluster at once, 0 for no limit")
+	pflag.Int("ptproxy_max_queued_queries", 100, "The maximum number of queries waiting for a slot per org or cluster, 0 for no limit")
+	pflag.Duration("ptproxy_queue_timeout", 10*time.Second, "How long a query waits for a slot before it is rejected")
+	pflag.Float64("ptproxy_user_query_rate", 5, "The number of queries per second a user can submit, 0 for no limit")
+	pflag.Int("ptproxy_user_query_burst", 20, "The number of queries a user can submit in a burst")
 }
 
 func main() {
@@ -105,6 +112,11 @@ func main() {
 		log.WithError(err).Fatal("Failed to init vzmgr clients")
 	}
 
+	vmd, err := apienv.NewVZMetadataServiceClient()
+	if err != nil {
+		log.WithError(err).Fatal("Failed to init vzmgr metadata client")
+	}
+
 	at, err := apienv.NewArtifactTrackerClient()
 	if err != nil {
 		log.WithError(err).Fatal("Failed to init artifact tracker client")
@@ -201,7 +213,7 @@ func main() {
 	}
 	cloudpb.RegisterArtifactTrackerServer(s.GRPCServer(), artifactTrackerServer)
 
-	cis := &controllers.VizierClusterInfo{VzMgr: vc, ArtifactTrackerClient: at}
+	cis := &controllers.VizierClusterInfo{VzMgr: vc, VzMetadata: vmd, ArtifactTrackerClient: at}
 	cloudpb.RegisterVizierClusterInfoServer(s.GRPCServer(), cis)
 
 	vdks := &controllers.VizierDeploymentKeyServer{VzDeploymentKey: vk}
@@ -213,7 +225,14 @@ func main() {
 	authServer := &controllers.AuthServer{AuthClient: ac}
 	cloudpb.RegisterAuthServiceServer(s.GRPCServer(), authServer)
 
-	vpt := ptproxy.NewVizierPassThroughProxy(nc, vc)
+	vpt := ptproxy.NewVizierPassThroughProxy(nc, vc, &ptproxy.AdmissionConfig{
+		MaxConcurrentPerOrg:     viper.GetInt("ptproxy_max_concurrent_queries_per_org"),
+		MaxConcurrentPerCluster: viper.GetInt("ptproxy_max_concurrent_queries_per_cluster"),
+		MaxQueued:               viper.GetInt("ptproxy_max_queued_queries"),
+		QueueTimeout:            viper.GetDuration("ptproxy_queue_timeout"),
+		UserRate:                viper.GetFloat64("ptproxy_user_query_rate"),
+		UserBurst:               viper.GetInt("ptproxy_user_query_burst"),
+	})
 	vizierpb.RegisterVizierServiceServer(s.GRPCServer(), vpt)
 	vizierpb.RegisterVizierDebugServiceServer(s.GRPCServer(), vpt)
 
@@ -232,6 +251,7 @@ func main() {
 	if err != nil {
 		log.WithError(err).Fatal("Failed to start elastic suggester")
 	}
+	esSuggester.SetScriptMgrClient(sm)
 
 	var br *script.BundleManager
 	var bundleErr error
@@ -277,6 +297,12 @@ func main() {
 	pss := &controllers.PluginServiceServer{PluginServiceClient: ps, DataRetentionPluginServiceClient: drps}
 	cloudpb.RegisterPluginServiceServer(s.GRPCServer(), pss)
 
+	eventHub, err := controllers.NewOrgEventHub(nc)
+	if err != nil {
+		log.WithError(err).Fatal("Failed to subscribe to org events")
+	}
+	defer eventHub.Stop()
+
 	gqlEnv := controllers.GraphQLEnv{
 		ArtifactTrackerServer: artifactTrackerServer,
 		VizierClusterInfo:     cis,
@@ -287,9 +313,12 @@ func main() {
 		OrgServer:             os,
 		UserServer:            us,
 		PluginServer:          pss,
+		EventHub:              eventHub,
 	}
 
 	mux.Handle("/api/graphql", controllers.WithAugmentedAuthMiddleware(env, controllers.NewGraphQLHandler(gqlEnv)))
+	mux.Handle("/api/graphql/ws", controllers.WithWebSocketOrigin(
+		controllers.WithAugmentedAuthMiddleware(env, controllers.NewGraphQLSubscriptionHandler(gqlEnv))))
 
 	mux.Handle("/api/unauthenticated/graphql", controllers.NewUnauthenticatedGraphQLHandler(gqlEnv))
 
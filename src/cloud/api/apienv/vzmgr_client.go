@@ -45,3 +45,18 @@ func NewVZMgrServiceClients() (vzmgrpb.VZMgrServiceClient, vzmgrpb.VZDeploymentK
 
 	return vzmgrpb.NewVZMgrServiceClient(vzMgrChan), vzmgrpb.NewVZDeploymentKeyServiceClient(vzMgrChan), nil
 }
+
+// NewVZMetadataServiceClient creates the vzmgr metadata RPC client stub.
+func NewVZMetadataServiceClient() (vzmgrpb.VZMetadataServiceClient, error) {
+	dialOpts, err := services.GetGRPCClientDialOpts()
+	if err != nil {
+		return nil, err
+	}
+
+	vzMgrChan, err := grpc.Dial(viper.GetString("vzmgr_service"), dialOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return vzmgrpb.NewVZMetadataServiceClient(vzMgrChan), nil
+}
@@ -156,6 +156,13 @@ type ClusterInfoResolver struct {
 	NumInstrumentedNodes          int32
 	PreviousStatus                *string
 	PreviousStatusTimeMs          *float64
+	Labels                        []ClusterLabelResolver
+}
+
+// ClusterLabelResolver is the resolver responsible for a key/value label on a cluster.
+type ClusterLabelResolver struct {
+	Key   string
+	Value string
 }
 
 // ID returns cluster ID.
@@ -200,8 +207,16 @@ func clusterInfoToResolver(cluster *cloudpb.ClusterInfo) (*ClusterInfoResolver,
 		UnhealthyDataPlanePodStatuses: mapPodStatusArray(cluster.UnhealthyDataPlanePodStatuses),
 		NumNodes:                      cluster.NumNodes,
 		NumInstrumentedNodes:          cluster.NumInstrumentedNodes,
+		Labels:                        make([]ClusterLabelResolver, 0, len(cluster.Labels)),
 	}
 
+	for k, v := range cluster.Labels {
+		resolver.Labels = append(resolver.Labels, ClusterLabelResolver{Key: k, Value: v})
+	}
+	sort.Slice(resolver.Labels, func(i, j int) bool {
+		return resolver.Labels[i].Key < resolver.Labels[j].Key
+	})
+
 	if cluster.PreviousStatusTime != nil {
 		status := cluster.PreviousStatus.String()
 		prevTime := timestampProtoToMillis(cluster.PreviousStatusTime)
@@ -212,10 +227,19 @@ func clusterInfoToResolver(cluster *cloudpb.ClusterInfo) (*ClusterInfoResolver,
 	return resolver, nil
 }
 
-// Clusters lists all of the clusters.
-func (q *QueryResolver) Clusters(ctx context.Context) ([]*ClusterInfoResolver, error) {
+type clustersArgs struct {
+	LabelSelector *string
+}
+
+// Clusters lists all of the clusters, optionally filtered by a label selector.
+func (q *QueryResolver) Clusters(ctx context.Context, args *clustersArgs) ([]*ClusterInfoResolver, error) {
+	req := &cloudpb.GetClusterInfoRequest{}
+	if args != nil && args.LabelSelector != nil {
+		req.LabelSelector = *args.LabelSelector
+	}
+
 	grpcAPI := q.Env.VizierClusterInfo
-	resp, err := grpcAPI.GetClusterInfo(ctx, &cloudpb.GetClusterInfoRequest{})
+	resp, err := grpcAPI.GetClusterInfo(ctx, req)
 	if err != nil {
 		return nil, rpcErrorHelper(err)
 	}
@@ -166,6 +166,63 @@ func TestClusterInfo(t *testing.T) {
 	}
 }
 
+func TestClustersWithLabelSelector(t *testing.T) {
+	gqlEnv, mockClients, cleanup := testutils.CreateTestGraphQLEnv(t)
+	defer cleanup()
+	ctx := CreateTestContext()
+
+	clusterInfo := &cloudpb.ClusterInfo{
+		ID:              utils.ProtoFromUUIDStrOrNil("7ba7b810-9dad-11d1-80b4-00c04fd430c8"),
+		Status:          cloudpb.CS_HEALTHY,
+		LastHeartbeatNs: 4 * 1000 * 1000,
+		ClusterName:     "clusterName",
+		Labels: map[string]string{
+			"region": "eu",
+			"env":    "prod",
+		},
+	}
+
+	mockClients.MockVizierClusterInfo.EXPECT().
+		GetClusterInfo(gomock.Any(), &cloudpb.GetClusterInfoRequest{
+			LabelSelector: "env=prod",
+		}).
+		Return(&cloudpb.GetClusterInfoResponse{
+			Clusters: []*cloudpb.ClusterInfo{clusterInfo},
+		}, nil)
+
+	gqlSchema := LoadSchema(gqlEnv)
+	gqltesting.RunTests(t, []*gqltesting.Test{
+		{
+			Schema:  gqlSchema,
+			Context: ctx,
+			Query: `
+				query {
+					clusters(labelSelector: "env=prod") {
+						id
+						clusterName
+						labels {
+							key
+							value
+						}
+					}
+				}
+			`,
+			ExpectedResult: `
+				{
+					"clusters": [{
+						"id":"7ba7b810-9dad-11d1-80b4-00c04fd430c8",
+						"clusterName": "clusterName",
+						"labels": [
+							{"key": "env", "value": "prod"},
+							{"key": "region", "value": "eu"}
+						]
+					}]
+				}
+			`,
+		},
+	})
+}
+
 func TestClusterInfoByName(t *testing.T) {
 	tests := []struct {
 		name string
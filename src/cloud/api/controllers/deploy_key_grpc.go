@@ -39,23 +39,43 @@ type VizierDeploymentKeyServer struct {
 }
 
 func deployKeyToCloudAPI(key *vzmgrpb.DeploymentKey) *cloudpb.DeploymentKey {
+	var usages []*cloudpb.DeploymentKeyUsage
+	for _, u := range key.Usages {
+		usages = append(usages, &cloudpb.DeploymentKeyUsage{
+			ClusterID:     u.ClusterID,
+			K8sClusterUID: u.K8sClusterUID,
+			ClusterName:   u.ClusterName,
+			RegisteredAt:  u.RegisteredAt,
+		})
+	}
 	return &cloudpb.DeploymentKey{
-		ID:        key.ID,
-		OrgID:     key.OrgID,
-		UserID:    key.UserID,
-		Key:       key.Key,
-		CreatedAt: key.CreatedAt,
-		Desc:      key.Desc,
+		ID:                  key.ID,
+		OrgID:               key.OrgID,
+		UserID:              key.UserID,
+		Key:                 key.Key,
+		CreatedAt:           key.CreatedAt,
+		Desc:                key.Desc,
+		ExpiresAt:           key.ExpiresAt,
+		MaxClusters:         key.MaxClusters,
+		AllowedClusterNames: key.AllowedClusterNames,
+		RevokedAt:           key.RevokedAt,
+		NumClusters:         key.NumClusters,
+		Usages:              usages,
 	}
 }
 
 func deployKeyMetadataToCloudAPI(key *vzmgrpb.DeploymentKeyMetadata) *cloudpb.DeploymentKeyMetadata {
 	return &cloudpb.DeploymentKeyMetadata{
-		ID:        key.ID,
-		OrgID:     key.OrgID,
-		UserID:    key.UserID,
-		CreatedAt: key.CreatedAt,
-		Desc:      key.Desc,
+		ID:                  key.ID,
+		OrgID:               key.OrgID,
+		UserID:              key.UserID,
+		CreatedAt:           key.CreatedAt,
+		Desc:                key.Desc,
+		ExpiresAt:           key.ExpiresAt,
+		MaxClusters:         key.MaxClusters,
+		AllowedClusterNames: key.AllowedClusterNames,
+		RevokedAt:           key.RevokedAt,
+		NumClusters:         key.NumClusters,
 	}
 }
 
@@ -78,9 +98,12 @@ func (v *VizierDeploymentKeyServer) Create(ctx context.Context, req *cloudpb.Cre
 		return nil, status.Error(codes.Internal, "error parsing user ID as UUID")
 	}
 	resp, err := v.VzDeploymentKey.Create(ctx, &vzmgrpb.CreateDeploymentKeyRequest{
-		Desc:   req.Desc,
-		OrgID:  orgID,
-		UserID: userID,
+		Desc:                req.Desc,
+		OrgID:               orgID,
+		UserID:              userID,
+		ExpiresAt:           req.ExpiresAt,
+		MaxClusters:         req.MaxClusters,
+		AllowedClusterNames: req.AllowedClusterNames,
 	})
 	if err != nil {
 		return nil, err
@@ -169,6 +192,27 @@ func (v *VizierDeploymentKeyServer) Delete(ctx context.Context, uuid *uuidpb.UUI
 	})
 }
 
+// Revoke revokes a specific deploy key in vzmgr.
+func (v *VizierDeploymentKeyServer) Revoke(ctx context.Context, uuid *uuidpb.UUID) (*types.Empty, error) {
+	ctx, err := contextWithAuthToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+	aCtx, err := authcontext.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	orgID := apiUtils.ProtoFromUUIDStrOrNil(aCtx.Claims.GetUserClaims().OrgID)
+	if orgID == nil {
+		return nil, status.Error(codes.Internal, "error parsing org ID as UUID")
+	}
+
+	return v.VzDeploymentKey.Revoke(ctx, &vzmgrpb.RevokeDeploymentKeyRequest{
+		OrgID: orgID,
+		ID:    uuid,
+	})
+}
+
 // LookupDeploymentKey gets the complete API key information using just the Key.
 func (v *VizierDeploymentKeyServer) LookupDeploymentKey(ctx context.Context, req *cloudpb.LookupDeploymentKeyRequest) (*cloudpb.LookupDeploymentKeyResponse, error) {
 	ctx, err := contextWithAuthToken(ctx)
@@ -243,6 +243,48 @@ func TestVizierDeploymentKeyServer_Delete(t *testing.T) {
 	}
 }
 
+func TestVizierDeploymentKeyServer_Revoke(t *testing.T) {
+	tests := []struct {
+		name string
+		ctx  context.Context
+	}{
+		{
+			name: "regular user",
+			ctx:  CreateTestContext(),
+		},
+		{
+			name: "api user",
+			ctx:  CreateAPIUserTestContext(),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			_, mockClients, cleanup := testutils.CreateTestAPIEnv(t)
+			defer cleanup()
+			ctx := test.ctx
+
+			id := utils.ProtoFromUUIDStrOrNil("6ba7b810-9dad-11d1-80b4-00c04fd430c9")
+			vzresp := &types.Empty{}
+			mockClients.MockVzDeployKey.EXPECT().
+				Revoke(gomock.Any(), &vzmgrpb.RevokeDeploymentKeyRequest{
+					ID:    id,
+					OrgID: utils.ProtoFromUUIDStrOrNil("6ba7b810-9dad-11d1-80b4-00c04fd430c8"),
+				}).Return(vzresp, nil)
+
+			vzDeployKeyServer := &controllers.VizierDeploymentKeyServer{
+				VzDeploymentKey: mockClients.MockVzDeployKey,
+			}
+			resp, err := vzDeployKeyServer.Revoke(ctx, id)
+			require.NoError(t, err)
+			assert.Equal(t, resp, vzresp)
+		})
+	}
+}
+
 func TestVizierDeploymentKeyServer_LookupDeploymentKeyAuthorized(t *testing.T) {
 	tests := []struct {
 		name string
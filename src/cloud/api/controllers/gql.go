@@ -42,6 +42,8 @@ type GraphQLEnv struct {
 	OrgServer             cloudpb.OrganizationServiceServer
 	UserServer            cloudpb.UserServiceServer
 	PluginServer          cloudpb.PluginServiceServer
+	// EventHub delivers the events that GraphQL subscriptions are fed by.
+	EventHub *OrgEventHub
 }
 
 // QueryResolver resolves queries for GQL.
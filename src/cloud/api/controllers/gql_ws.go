@@ -0,0 +1,368 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/graph-gophers/graphql-go"
+	log "github.com/sirupsen/logrus"
+
+	"px.dev/pixie/src/cloud/api/controllers/schema/complete"
+	"px.dev/pixie/src/shared/services/authcontext"
+)
+
+// graphqlWSProtocol is the websocket subprotocol used for GraphQL subscriptions.
+// See https://github.com/enisdenjo/graphql-ws/blob/master/PROTOCOL.md.
+const graphqlWSProtocol = "graphql-transport-ws"
+
+const (
+	wsConnectionInitTimeout = 10 * time.Second
+	wsPingInterval          = 30 * time.Second
+	wsWriteTimeout          = 10 * time.Second
+)
+
+// Message types of the graphql-transport-ws protocol.
+const (
+	wsMsgConnectionInit = "connection_init"
+	wsMsgConnectionAck  = "connection_ack"
+	wsMsgPing           = "ping"
+	wsMsgPong           = "pong"
+	wsMsgSubscribe      = "subscribe"
+	wsMsgNext           = "next"
+	wsMsgError          = "error"
+	wsMsgComplete       = "complete"
+)
+
+// Close codes of the graphql-transport-ws protocol.
+const (
+	wsCloseBadRequest          = 4400
+	wsCloseUnauthorized        = 4401
+	wsCloseInitTimeout         = 4408
+	wsCloseSubscriberExists    = 4409
+	wsCloseTooManyInitRequests = 4429
+)
+
+type wsMessage struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+type wsSubscribePayload struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+type subscriptionHandler struct {
+	schema   *graphql.Schema
+	upgrader websocket.Upgrader
+}
+
+// NewGraphQLSubscriptionHandler is the HTTP handler used for handling GraphQL subscriptions over
+// websockets. It must be wrapped in the auth middleware, the org of the authenticated user
+// determines which events are received.
+func NewGraphQLSubscriptionHandler(graphqlEnv GraphQLEnv) http.Handler {
+	schemaData := complete.MustLoadSchema()
+	opts := []graphql.SchemaOpt{graphql.UseFieldResolvers(), graphql.MaxParallelism(20)}
+	gqlSchema := graphql.MustParseSchema(schemaData, &QueryResolver{graphqlEnv}, opts...)
+	return &subscriptionHandler{
+		schema: gqlSchema,
+		upgrader: websocket.Upgrader{
+			Subprotocols: []string{graphqlWSProtocol},
+			CheckOrigin: func(r *http.Request) bool {
+				// Non-browser clients don't set an origin, and authenticate with a bearer token or API key.
+				origin := r.Header.Get("Origin")
+				if origin == "" {
+					return true
+				}
+				u, err := url.Parse(origin)
+				return err == nil && checkOrigin(u)
+			},
+		},
+	}
+}
+
+// WithWebSocketOrigin allows websocket handshakes to use session auth. Browsers don't send a referer
+// on websocket handshakes, so the origin is used for the CSRF check instead.
+func WithWebSocketOrigin(next http.Handler) http.Handler {
+	f := func(w http.ResponseWriter, r *http.Request) {
+		if r.Referer() == "" && websocket.IsWebSocketUpgrade(r) {
+			if origin := r.Header.Get("Origin"); origin != "" {
+				r.Header.Set("Referer", origin)
+			}
+		}
+		next.ServeHTTP(w, r)
+	}
+	return http.HandlerFunc(f)
+}
+
+func (h *subscriptionHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	sCtx, err := authcontext.FromContext(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// The upgrader has already replied with an error.
+		return
+	}
+	if conn.Subprotocol() != graphqlWSProtocol {
+		_ = conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseProtocolError, "Unsupported subprotocol"),
+			time.Now().Add(wsWriteTimeout))
+		conn.Close()
+		return
+	}
+
+	ctx := r.Context()
+	// The connection can't outlive the token it was authenticated with. Clients are expected to
+	// reconnect, which reauthenticates them.
+	if exp := sCtx.Claims.GetExpiresAt(); exp > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, time.Unix(exp, 0))
+		defer cancel()
+	}
+
+	c := &subscriptionConn{
+		conn:   conn,
+		schema: h.schema,
+		subs:   make(map[string]context.CancelFunc),
+	}
+	c.serve(ctx)
+}
+
+// subscriptionConn is a single websocket connection, which can carry many subscriptions.
+type subscriptionConn struct {
+	conn   *websocket.Conn
+	schema *graphql.Schema
+
+	// writeMu serializes writes, since websocket connections support only one concurrent writer.
+	writeMu sync.Mutex
+
+	mu   sync.Mutex
+	subs map[string]context.CancelFunc
+	wg   sync.WaitGroup
+}
+
+func (c *subscriptionConn) serve(ctx context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer func() {
+		cancel()
+		c.wg.Wait()
+		c.conn.Close()
+	}()
+
+	go func() {
+		<-ctx.Done()
+		if ctx.Err() == context.DeadlineExceeded {
+			c.close(websocket.CloseGoingAway, "Authentication expired")
+		}
+		// Unblocks the read loop.
+		c.conn.Close()
+	}()
+
+	if !c.init() {
+		return
+	}
+	go c.keepAlive(ctx)
+
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) && ctx.Err() == nil {
+				log.WithError(err).Info("GraphQL websocket closed unexpectedly")
+			}
+			return
+		}
+		var msg wsMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			c.close(wsCloseBadRequest, "Invalid message")
+			return
+		}
+
+		switch msg.Type {
+		case wsMsgPing:
+			c.write(&wsMessage{Type: wsMsgPong})
+		case wsMsgPong:
+		case wsMsgConnectionInit:
+			c.close(wsCloseTooManyInitRequests, "Too many initialisation requests")
+			return
+		case wsMsgSubscribe:
+			var payload wsSubscribePayload
+			if msg.ID == "" || json.Unmarshal(msg.Payload, &payload) != nil {
+				c.close(wsCloseBadRequest, "Invalid subscribe message")
+				return
+			}
+			if !c.subscribe(ctx, msg.ID, &payload) {
+				c.close(wsCloseSubscriberExists, fmt.Sprintf("Subscriber for %s already exists", msg.ID))
+				return
+			}
+		case wsMsgComplete:
+			c.unsubscribe(msg.ID)
+		default:
+			c.close(wsCloseBadRequest, fmt.Sprintf("Unsupported message type %q", msg.Type))
+			return
+		}
+	}
+}
+
+// init waits for the client to initialize the connection, and acknowledges it.
+func (c *subscriptionConn) init() bool {
+	_ = c.conn.SetReadDeadline(time.Now().Add(wsConnectionInitTimeout))
+	_, data, err := c.conn.ReadMessage()
+	if err != nil {
+		if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+			c.close(wsCloseInitTimeout, "Connection initialisation timeout")
+		}
+		return false
+	}
+	var msg wsMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		c.close(wsCloseBadRequest, "Invalid message")
+		return false
+	}
+	if msg.Type != wsMsgConnectionInit {
+		c.close(wsCloseUnauthorized, "Unauthorized")
+		return false
+	}
+	_ = c.conn.SetReadDeadline(time.Time{})
+	return c.write(&wsMessage{Type: wsMsgConnectionAck})
+}
+
+// keepAlive pings the client so that idle connections aren't closed by proxies.
+func (c *subscriptionConn) keepAlive(ctx context.Context) {
+	t := time.NewTicker(wsPingInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			if err := c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteTimeout)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// subscribe starts executing the operation. Returns false if a subscription with the same ID is
+// already running.
+func (c *subscriptionConn) subscribe(ctx context.Context, id string, payload *wsSubscribePayload) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.subs[id]; ok {
+		return false
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	c.subs[id] = cancel
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		defer cancel()
+
+		responses, err := c.schema.Subscribe(ctx, payload.Query, payload.OperationName, payload.Variables)
+		if err != nil {
+			c.finish(id, &wsMessage{ID: id, Type: wsMsgError, Payload: wsErrorPayload(err)})
+			return
+		}
+		failed := false
+		// The responses must be drained until the channel is closed, even once the subscription is
+		// cancelled, so that the GraphQL executor doesn't block.
+		for r := range responses {
+			if ctx.Err() != nil || failed {
+				continue
+			}
+			resp, ok := r.(*graphql.Response)
+			if !ok {
+				continue
+			}
+			if resp.Data == nil && len(resp.Errors) > 0 {
+				// Execution failed before producing any result, which ends the subscription.
+				b, _ := json.Marshal(resp.Errors)
+				c.finish(id, &wsMessage{ID: id, Type: wsMsgError, Payload: b})
+				failed = true
+				cancel()
+				continue
+			}
+			b, err := json.Marshal(resp)
+			if err != nil {
+				log.WithError(err).Error("Failed to marshal GraphQL response")
+				continue
+			}
+			c.write(&wsMessage{ID: id, Type: wsMsgNext, Payload: b})
+		}
+		if !failed {
+			c.finish(id, &wsMessage{ID: id, Type: wsMsgComplete})
+		}
+	}()
+	return true
+}
+
+// unsubscribe stops a subscription on request of the client.
+func (c *subscriptionConn) unsubscribe(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if cancel, ok := c.subs[id]; ok {
+		cancel()
+		delete(c.subs, id)
+	}
+}
+
+// finish removes a subscription that ended on the server, and notifies the client with the given
+// message. The client isn't notified about subscriptions it stopped itself.
+func (c *subscriptionConn) finish(id string, msg *wsMessage) {
+	c.mu.Lock()
+	_, ok := c.subs[id]
+	delete(c.subs, id)
+	c.mu.Unlock()
+	if ok {
+		c.write(msg)
+	}
+}
+
+func (c *subscriptionConn) write(msg *wsMessage) bool {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	_ = c.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+	return c.conn.WriteJSON(msg) == nil
+}
+
+func (c *subscriptionConn) close(code int, reason string) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	_ = c.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason),
+		time.Now().Add(wsWriteTimeout))
+}
+
+func wsErrorPayload(err error) json.RawMessage {
+	b, _ := json.Marshal([]map[string]string{{"message": err.Error()}})
+	return b
+}
@@ -0,0 +1,136 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package controllers
+
+import (
+	"context"
+	"sync"
+
+	"github.com/gofrs/uuid"
+	"github.com/nats-io/nats.go"
+	log "github.com/sirupsen/logrus"
+
+	"px.dev/pixie/src/cloud/shared/messages"
+	"px.dev/pixie/src/cloud/shared/messagespb"
+	"px.dev/pixie/src/utils"
+)
+
+// orgEventBufferSize is the number of events buffered for each subscriber. Events for slow
+// subscribers are dropped once the buffer is full.
+const orgEventBufferSize = 64
+
+// orgEvent is a change to one of an org's resources. Exactly one of the fields is set.
+type orgEvent struct {
+	clusterStatus *messagespb.VizierStatusChanged
+	cronScript    *messagespb.CronScriptChanged
+}
+
+// OrgEventHub listens for changes published by the other cloud services and fans them out to the
+// subscribers of the org that the change belongs to.
+type OrgEventHub struct {
+	subs []*nats.Subscription
+
+	mu        sync.Mutex
+	listeners map[uuid.UUID]map[chan orgEvent]struct{}
+}
+
+// NewOrgEventHub creates a new OrgEventHub that receives events from NATS.
+func NewOrgEventHub(nc *nats.Conn) (*OrgEventHub, error) {
+	h := &OrgEventHub{
+		listeners: make(map[uuid.UUID]map[chan orgEvent]struct{}),
+	}
+
+	clusterSub, err := nc.Subscribe(messages.VizierStatusChangedChannel, func(msg *nats.Msg) {
+		m := &messagespb.VizierStatusChanged{}
+		if err := m.Unmarshal(msg.Data); err != nil {
+			log.WithError(err).Error("Failed to unmarshal vizier status change")
+			return
+		}
+		h.publish(utils.UUIDFromProtoOrNil(m.OrgID), orgEvent{clusterStatus: m})
+	})
+	if err != nil {
+		return nil, err
+	}
+	h.subs = append(h.subs, clusterSub)
+
+	cronScriptSub, err := nc.Subscribe(messages.CronScriptChangedChannel, func(msg *nats.Msg) {
+		m := &messagespb.CronScriptChanged{}
+		if err := m.Unmarshal(msg.Data); err != nil {
+			log.WithError(err).Error("Failed to unmarshal cron script change")
+			return
+		}
+		h.publish(utils.UUIDFromProtoOrNil(m.OrgID), orgEvent{cronScript: m})
+	})
+	if err != nil {
+		h.Stop()
+		return nil, err
+	}
+	h.subs = append(h.subs, cronScriptSub)
+
+	return h, nil
+}
+
+// Stop stops listening for events.
+func (h *OrgEventHub) Stop() {
+	for _, sub := range h.subs {
+		if err := sub.Unsubscribe(); err != nil {
+			log.WithError(err).Error("Failed to unsubscribe from org events")
+		}
+	}
+	h.subs = nil
+}
+
+// Subscribe returns a channel that receives all events for the given org. The channel is closed
+// once the context is done.
+func (h *OrgEventHub) Subscribe(ctx context.Context, orgID uuid.UUID) <-chan orgEvent {
+	ch := make(chan orgEvent, orgEventBufferSize)
+
+	h.mu.Lock()
+	if _, ok := h.listeners[orgID]; !ok {
+		h.listeners[orgID] = make(map[chan orgEvent]struct{})
+	}
+	h.listeners[orgID][ch] = struct{}{}
+	h.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		delete(h.listeners[orgID], ch)
+		if len(h.listeners[orgID]) == 0 {
+			delete(h.listeners, orgID)
+		}
+		close(ch)
+	}()
+	return ch
+}
+
+func (h *OrgEventHub) publish(orgID uuid.UUID, ev orgEvent) {
+	if orgID == uuid.Nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.listeners[orgID] {
+		select {
+		case ch <- ev:
+		default:
+			log.WithField("orgID", orgID).Warn("Subscriber is too slow, dropping org event")
+		}
+	}
+}
@@ -57,7 +57,8 @@ func (p *PluginServiceServer) GetPlugins(ctx context.Context, req *cloudpb.GetPl
 	}
 
 	pluginsResp, err := p.PluginServiceClient.GetPlugins(ctx, &pluginpb.GetPluginsRequest{
-		Kind: kindCloudProtoToPluginProto(req.Kind),
+		Kind:  kindCloudProtoToPluginProto(req.Kind),
+		OrgID: orgID,
 	})
 	if err != nil {
 		return nil, err
@@ -119,16 +120,30 @@ func (p *PluginServiceServer) GetOrgRetentionPluginConfig(ctx context.Context, r
 		return nil, err
 	}
 
+	var overrides []*cloudpb.ClusterConfigOverride
+	for _, o := range pluginsResp.ClusterOverrides {
+		overrides = append(overrides, &cloudpb.ClusterConfigOverride{
+			ClusterID:       o.ClusterID,
+			Configs:         o.Configurations,
+			CustomExportUrl: o.CustomExportUrl,
+		})
+	}
+
 	return &cloudpb.GetOrgRetentionPluginConfigResponse{
-		Configs:         pluginsResp.Configurations,
-		CustomExportUrl: pluginsResp.CustomExportUrl,
-		InsecureTLS:     pluginsResp.InsecureTLS,
+		Configs:          pluginsResp.Configurations,
+		CustomExportUrl:  pluginsResp.CustomExportUrl,
+		InsecureTLS:      pluginsResp.InsecureTLS,
+		ClusterOverrides: overrides,
 	}, nil
 }
 
 // GetRetentionPluginInfo gets the retention plugin info for a particular plugin release.
 func (p *PluginServiceServer) GetRetentionPluginInfo(ctx context.Context, req *cloudpb.GetRetentionPluginInfoRequest) (*cloudpb.GetRetentionPluginInfoResponse, error) {
-	var err error
+	sCtx, err := authcontext.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	ctx, err = contextWithAuthToken(ctx)
 	if err != nil {
 		return nil, err
@@ -137,6 +152,7 @@ func (p *PluginServiceServer) GetRetentionPluginInfo(ctx context.Context, req *c
 	configResp, err := p.PluginServiceClient.GetRetentionPluginConfig(ctx, &pluginpb.GetRetentionPluginConfigRequest{
 		ID:      req.PluginId,
 		Version: req.Version,
+		OrgID:   utils.ProtoFromUUIDStrOrNil(sCtx.Claims.GetUserClaims().OrgID),
 	})
 	if err != nil {
 		return nil, err
@@ -164,7 +180,7 @@ func (p *PluginServiceServer) UpdateRetentionPluginConfig(ctx context.Context, r
 		return nil, err
 	}
 
-	_, err = p.DataRetentionPluginServiceClient.UpdateOrgRetentionPluginConfig(ctx, &pluginpb.UpdateOrgRetentionPluginConfigRequest{
+	updateReq := &pluginpb.UpdateOrgRetentionPluginConfigRequest{
 		PluginID:        req.PluginId,
 		OrgID:           orgID,
 		Configurations:  req.Configs,
@@ -173,12 +189,56 @@ func (p *PluginServiceServer) UpdateRetentionPluginConfig(ctx context.Context, r
 		CustomExportUrl: req.CustomExportUrl,
 		InsecureTLS:     req.InsecureTLS,
 		DisablePresets:  req.DisablePresets,
-	})
+		DryRun:          req.DryRun,
+	}
+	if req.ClusterOverrides != nil {
+		overrides := make([]*pluginpb.ClusterConfigOverride, len(req.ClusterOverrides.Value))
+		for i, o := range req.ClusterOverrides.Value {
+			overrides[i] = &pluginpb.ClusterConfigOverride{
+				ClusterID:       o.ClusterID,
+				Configurations:  o.Configs,
+				CustomExportUrl: o.CustomExportUrl,
+			}
+		}
+		updateReq.ClusterOverrides = &pluginpb.ClusterConfigOverrides{Value: overrides}
+	}
+
+	resp, err := p.DataRetentionPluginServiceClient.UpdateOrgRetentionPluginConfig(ctx, updateReq)
 	if err != nil {
 		return nil, err
 	}
 
-	return &cloudpb.UpdateRetentionPluginConfigResponse{}, nil
+	var changes []*cloudpb.RetentionScriptChange
+	for _, c := range resp.Changes {
+		changes = append(changes, &cloudpb.RetentionScriptChange{
+			Action:                 retentionScriptActionPluginProtoToCloudProto(c.Action),
+			ScriptID:               c.ScriptID,
+			ScriptName:             c.ScriptName,
+			ClusterIDs:             c.ClusterIDs,
+			PreviousConfigs:        c.PreviousConfigs,
+			Configs:                c.Configs,
+			ContentsChanged:        c.ContentsChanged,
+			PreviousClusterConfigs: c.PreviousClusterConfigs,
+			ClusterConfigs:         c.ClusterConfigs,
+		})
+	}
+
+	return &cloudpb.UpdateRetentionPluginConfigResponse{Changes: changes}, nil
+}
+
+func retentionScriptActionPluginProtoToCloudProto(a pluginpb.RetentionScriptAction) cloudpb.RetentionScriptAction {
+	switch a {
+	case pluginpb.RETENTION_SCRIPT_ACTION_CREATE:
+		return cloudpb.RSA_CREATE
+	case pluginpb.RETENTION_SCRIPT_ACTION_UPDATE:
+		return cloudpb.RSA_UPDATE
+	case pluginpb.RETENTION_SCRIPT_ACTION_DISABLE:
+		return cloudpb.RSA_DISABLE
+	case pluginpb.RETENTION_SCRIPT_ACTION_DELETE:
+		return cloudpb.RSA_DELETE
+	default:
+		return cloudpb.RSA_UNKNOWN
+	}
 }
 
 // GetRetentionScripts gets the retention scripts configured for the org.
@@ -205,14 +265,15 @@ func (p *PluginServiceServer) GetRetentionScripts(ctx context.Context, req *clou
 	scripts := make([]*cloudpb.RetentionScript, len(resp.Scripts))
 	for i, s := range resp.Scripts {
 		scripts[i] = &cloudpb.RetentionScript{
-			ScriptID:    s.ScriptID,
-			ScriptName:  s.ScriptName,
-			Description: s.Description,
-			FrequencyS:  s.FrequencyS,
-			ClusterIDs:  s.ClusterIDs,
-			PluginId:    s.PluginId,
-			Enabled:     s.Enabled,
-			IsPreset:    s.IsPreset,
+			ScriptID:        s.ScriptID,
+			ScriptName:      s.ScriptName,
+			Description:     s.Description,
+			FrequencyS:      s.FrequencyS,
+			ClusterIDs:      s.ClusterIDs,
+			PluginId:        s.PluginId,
+			Enabled:         s.Enabled,
+			IsPreset:        s.IsPreset,
+			ClusterSelector: s.ClusterSelector,
 		}
 	}
 	return &cloudpb.GetRetentionScriptsResponse{
@@ -242,20 +303,31 @@ func (p *PluginServiceServer) GetRetentionScript(ctx context.Context, req *cloud
 		return nil, err
 	}
 
+	var clusterConfigs []*cloudpb.ClusterScriptConfig
+	for _, c := range resp.Script.ClusterConfigs {
+		clusterConfigs = append(clusterConfigs, &cloudpb.ClusterScriptConfig{
+			ClusterID: c.ClusterID,
+			ExportURL: c.ExportURL,
+			Configs:   c.Configurations,
+		})
+	}
+
 	scriptDetails := resp.Script.Script
 	return &cloudpb.GetRetentionScriptResponse{
 		Script: &cloudpb.RetentionScript{
-			ScriptID:    scriptDetails.ScriptID,
-			ScriptName:  scriptDetails.ScriptName,
-			Description: scriptDetails.Description,
-			FrequencyS:  scriptDetails.FrequencyS,
-			ClusterIDs:  scriptDetails.ClusterIDs,
-			PluginId:    scriptDetails.PluginId,
-			Enabled:     scriptDetails.Enabled,
-			IsPreset:    scriptDetails.IsPreset,
+			ScriptID:        scriptDetails.ScriptID,
+			ScriptName:      scriptDetails.ScriptName,
+			Description:     scriptDetails.Description,
+			FrequencyS:      scriptDetails.FrequencyS,
+			ClusterIDs:      scriptDetails.ClusterIDs,
+			PluginId:        scriptDetails.PluginId,
+			Enabled:         scriptDetails.Enabled,
+			IsPreset:        scriptDetails.IsPreset,
+			ClusterSelector: scriptDetails.ClusterSelector,
 		},
-		Contents:  resp.Script.Contents,
-		ExportURL: resp.Script.ExportURL,
+		Contents:       resp.Script.Contents,
+		ExportURL:      resp.Script.ExportURL,
+		ClusterConfigs: clusterConfigs,
 	}, nil
 }
 
@@ -268,14 +340,15 @@ func (p *PluginServiceServer) UpdateRetentionScript(ctx context.Context, req *cl
 	}
 
 	_, err = p.DataRetentionPluginServiceClient.UpdateRetentionScript(ctx, &pluginpb.UpdateRetentionScriptRequest{
-		ScriptID:    req.ID,
-		ScriptName:  req.ScriptName,
-		Description: req.Description,
-		Enabled:     req.Enabled,
-		FrequencyS:  req.FrequencyS,
-		Contents:    req.Contents,
-		ExportUrl:   req.ExportUrl,
-		ClusterIDs:  req.ClusterIDs,
+		ScriptID:        req.ID,
+		ScriptName:      req.ScriptName,
+		Description:     req.Description,
+		Enabled:         req.Enabled,
+		FrequencyS:      req.FrequencyS,
+		Contents:        req.Contents,
+		ExportUrl:       req.ExportUrl,
+		ClusterIDs:      req.ClusterIDs,
+		ClusterSelector: req.ClusterSelector,
 	})
 	if err != nil {
 		return nil, err
@@ -301,13 +374,14 @@ func (p *PluginServiceServer) CreateRetentionScript(ctx context.Context, req *cl
 	resp, err := p.DataRetentionPluginServiceClient.CreateRetentionScript(ctx, &pluginpb.CreateRetentionScriptRequest{
 		Script: &pluginpb.DetailedRetentionScript{
 			Script: &pluginpb.RetentionScript{
-				ScriptName:  req.ScriptName,
-				Description: req.Description,
-				FrequencyS:  req.FrequencyS,
-				ClusterIDs:  req.ClusterIDs,
-				PluginId:    req.PluginId,
-				Enabled:     !req.Disabled,
-				IsPreset:    false,
+				ScriptName:      req.ScriptName,
+				Description:     req.Description,
+				FrequencyS:      req.FrequencyS,
+				ClusterIDs:      req.ClusterIDs,
+				PluginId:        req.PluginId,
+				Enabled:         !req.Disabled,
+				IsPreset:        false,
+				ClusterSelector: req.ClusterSelector,
 			},
 			Contents:  req.Contents,
 			ExportURL: req.ExportUrl,
@@ -171,7 +171,8 @@ func TestGetPlugins(t *testing.T) {
 			orgID := utils.ProtoFromUUIDStrOrNil("6ba7b810-9dad-11d1-80b4-00c04fd430c8")
 
 			mockReq1 := &pluginpb.GetPluginsRequest{
-				Kind: pluginpb.PLUGIN_KIND_RETENTION,
+				Kind:  pluginpb.PLUGIN_KIND_RETENTION,
+				OrgID: orgID,
 			}
 
 			mockClients.MockPlugin.EXPECT().GetPlugins(gomock.Any(), mockReq1).
@@ -251,6 +252,7 @@ func TestGetRetentionPluginInfo(t *testing.T) {
 	mockReq := &pluginpb.GetRetentionPluginConfigRequest{
 		Version: "2.0.0",
 		ID:      "test-plugin",
+		OrgID:   utils.ProtoFromUUIDStrOrNil("6ba7b810-9dad-11d1-80b4-00c04fd430c8"),
 	}
 
 	mockClients.MockPlugin.EXPECT().GetRetentionPluginConfig(gomock.Any(), mockReq).
@@ -608,3 +610,111 @@ func TestDeleteRetentionScript(t *testing.T) {
 
 	assert.Equal(t, &cloudpb.DeleteRetentionScriptResponse{}, resp)
 }
+
+func TestUpdateRetentionPluginConfigDryRun(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	_, mockClients, cleanup := testutils.CreateTestAPIEnv(t)
+	defer cleanup()
+	ctx := CreateTestContext()
+
+	orgID := utils.ProtoFromUUIDStrOrNil("6ba7b810-9dad-11d1-80b4-00c04fd430c8")
+	scriptID := utils.ProtoFromUUIDStrOrNil("1ba7b810-9dad-11d1-80b4-00c04fd430c8")
+
+	mockReq := &pluginpb.UpdateOrgRetentionPluginConfigRequest{
+		OrgID:    orgID,
+		PluginID: "test-plugin",
+		Version:  &types.StringValue{Value: "3.0.0"},
+		DryRun:   true,
+	}
+
+	mockClients.MockDataRetentionPlugin.EXPECT().UpdateOrgRetentionPluginConfig(gomock.Any(), mockReq).
+		Return(&pluginpb.UpdateOrgRetentionPluginConfigResponse{
+			Changes: []*pluginpb.RetentionScriptChange{
+				{
+					Action:     pluginpb.RETENTION_SCRIPT_ACTION_CREATE,
+					ScriptName: "new script",
+					Configs:    "new config",
+				},
+				{
+					Action:          pluginpb.RETENTION_SCRIPT_ACTION_DELETE,
+					ScriptID:        scriptID,
+					ScriptName:      "old script",
+					PreviousConfigs: "old config",
+				},
+			},
+		}, nil)
+
+	pServer := &controllers.PluginServiceServer{mockClients.MockPlugin, mockClients.MockDataRetentionPlugin}
+
+	resp, err := pServer.UpdateRetentionPluginConfig(ctx, &cloudpb.UpdateRetentionPluginConfigRequest{
+		PluginId: "test-plugin",
+		Version:  &types.StringValue{Value: "3.0.0"},
+		DryRun:   true,
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, &cloudpb.UpdateRetentionPluginConfigResponse{
+		Changes: []*cloudpb.RetentionScriptChange{
+			{
+				Action:     cloudpb.RSA_CREATE,
+				ScriptName: "new script",
+				Configs:    "new config",
+			},
+			{
+				Action:          cloudpb.RSA_DELETE,
+				ScriptID:        scriptID,
+				ScriptName:      "old script",
+				PreviousConfigs: "old config",
+			},
+		},
+	}, resp)
+}
+
+func TestUpdateRetentionPluginConfigClusterOverrides(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	_, mockClients, cleanup := testutils.CreateTestAPIEnv(t)
+	defer cleanup()
+	ctx := CreateTestContext()
+
+	orgID := utils.ProtoFromUUIDStrOrNil("6ba7b810-9dad-11d1-80b4-00c04fd430c8")
+	clusterID := utils.ProtoFromUUIDStrOrNil("7ba7b810-9dad-11d1-80b4-00c04fd430c8")
+
+	mockReq := &pluginpb.UpdateOrgRetentionPluginConfigRequest{
+		OrgID:    orgID,
+		PluginID: "test-plugin",
+		ClusterOverrides: &pluginpb.ClusterConfigOverrides{
+			Value: []*pluginpb.ClusterConfigOverride{
+				{
+					ClusterID:       clusterID,
+					Configurations:  map[string]string{"license_key": "cluster-key"},
+					CustomExportUrl: "https://cluster:443",
+				},
+			},
+		},
+	}
+
+	mockClients.MockDataRetentionPlugin.EXPECT().UpdateOrgRetentionPluginConfig(gomock.Any(), mockReq).
+		Return(&pluginpb.UpdateOrgRetentionPluginConfigResponse{}, nil)
+
+	pServer := &controllers.PluginServiceServer{mockClients.MockPlugin, mockClients.MockDataRetentionPlugin}
+
+	resp, err := pServer.UpdateRetentionPluginConfig(ctx, &cloudpb.UpdateRetentionPluginConfigRequest{
+		PluginId: "test-plugin",
+		ClusterOverrides: &cloudpb.ClusterConfigOverrides{
+			Value: []*cloudpb.ClusterConfigOverride{
+				{
+					ClusterID:       clusterID,
+					Configs:         map[string]string{"license_key": "cluster-key"},
+					CustomExportUrl: "https://cluster:443",
+				},
+			},
+		},
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, &cloudpb.UpdateRetentionPluginConfigResponse{}, resp)
+}
@@ -21,8 +21,12 @@ package controllers
 import (
 	"context"
 
+	"github.com/gogo/protobuf/types"
+
 	"px.dev/pixie/src/api/proto/cloudpb"
+	"px.dev/pixie/src/api/proto/uuidpb"
 	"px.dev/pixie/src/cloud/scriptmgr/scriptmgrpb"
+	"px.dev/pixie/src/shared/services/authcontext"
 	"px.dev/pixie/src/utils"
 )
 
@@ -31,13 +35,27 @@ type ScriptMgrServer struct {
 	ScriptMgr scriptmgrpb.ScriptMgrServiceClient
 }
 
+// orgIDFromContext returns the org of the user making the request, which determines which script
+// library is merged with the public scripts.
+func orgIDFromContext(ctx context.Context) (*uuidpb.UUID, error) {
+	sCtx, err := authcontext.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return utils.ProtoFromUUIDStrOrNil(sCtx.Claims.GetUserClaims().GetOrgID()), nil
+}
+
 // GetLiveViews returns a list of all available live views.
 func (s *ScriptMgrServer) GetLiveViews(ctx context.Context, req *cloudpb.GetLiveViewsReq) (*cloudpb.GetLiveViewsResp, error) {
-	ctx, err := contextWithAuthToken(ctx)
+	orgID, err := orgIDFromContext(ctx)
 	if err != nil {
 		return nil, err
 	}
-	smReq := &scriptmgrpb.GetLiveViewsReq{}
+	ctx, err = contextWithAuthToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+	smReq := &scriptmgrpb.GetLiveViewsReq{OrgID: orgID}
 	smResp, err := s.ScriptMgr.GetLiveViews(ctx, smReq)
 	if err != nil {
 		return nil, err
@@ -57,13 +75,18 @@ func (s *ScriptMgrServer) GetLiveViews(ctx context.Context, req *cloudpb.GetLive
 
 // GetLiveViewContents returns the pxl script, vis info, and metdata for a live view.
 func (s *ScriptMgrServer) GetLiveViewContents(ctx context.Context, req *cloudpb.GetLiveViewContentsReq) (*cloudpb.GetLiveViewContentsResp, error) {
-	ctx, err := contextWithAuthToken(ctx)
+	orgID, err := orgIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ctx, err = contextWithAuthToken(ctx)
 	if err != nil {
 		return nil, err
 	}
 
 	smReq := &scriptmgrpb.GetLiveViewContentsReq{
 		LiveViewID: utils.ProtoFromUUIDStrOrNil(req.LiveViewID),
+		OrgID:      orgID,
 	}
 	smResp, err := s.ScriptMgr.GetLiveViewContents(ctx, smReq)
 	if err != nil {
@@ -83,12 +106,16 @@ func (s *ScriptMgrServer) GetLiveViewContents(ctx context.Context, req *cloudpb.
 
 // GetScripts returns a list of all available scripts.
 func (s *ScriptMgrServer) GetScripts(ctx context.Context, req *cloudpb.GetScriptsReq) (*cloudpb.GetScriptsResp, error) {
-	ctx, err := contextWithAuthToken(ctx)
+	orgID, err := orgIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ctx, err = contextWithAuthToken(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	smReq := &scriptmgrpb.GetScriptsReq{}
+	smReq := &scriptmgrpb.GetScriptsReq{OrgID: orgID}
 	smResp, err := s.ScriptMgr.GetScripts(ctx, smReq)
 	if err != nil {
 		return nil, err
@@ -109,13 +136,18 @@ func (s *ScriptMgrServer) GetScripts(ctx context.Context, req *cloudpb.GetScript
 
 // GetScriptContents returns the pxl string of the script.
 func (s *ScriptMgrServer) GetScriptContents(ctx context.Context, req *cloudpb.GetScriptContentsReq) (*cloudpb.GetScriptContentsResp, error) {
-	ctx, err := contextWithAuthToken(ctx)
+	orgID, err := orgIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ctx, err = contextWithAuthToken(ctx)
 	if err != nil {
 		return nil, err
 	}
 
 	smReq := &scriptmgrpb.GetScriptContentsReq{
 		ScriptID: utils.ProtoFromUUIDStrOrNil(req.ScriptID),
+		OrgID:    orgID,
 	}
 	smResp, err := s.ScriptMgr.GetScriptContents(ctx, smReq)
 	if err != nil {
@@ -131,3 +163,150 @@ func (s *ScriptMgrServer) GetScriptContents(ctx context.Context, req *cloudpb.Ge
 		Contents: smResp.Contents,
 	}, nil
 }
+
+func orgScriptToCloudProto(script *scriptmgrpb.OrgScript) *cloudpb.OrgScript {
+	pb := &cloudpb.OrgScript{
+		ID:          utils.UUIDFromProtoOrNil(script.ID).String(),
+		Name:        script.Name,
+		Desc:        script.Desc,
+		PxlContents: script.PxlContents,
+		Vis:         script.Vis,
+		Tags:        script.Tags,
+		CreatedAt:   script.CreatedAt,
+		UpdatedAt:   script.UpdatedAt,
+	}
+	if script.OwnerID != nil {
+		pb.OwnerID = utils.UUIDFromProtoOrNil(script.OwnerID).String()
+	}
+	return pb
+}
+
+// GetOrgScripts returns all of the scripts in the org's script library.
+func (s *ScriptMgrServer) GetOrgScripts(ctx context.Context, req *cloudpb.GetOrgScriptsReq) (*cloudpb.GetOrgScriptsResp, error) {
+	orgID, err := orgIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ctx, err = contextWithAuthToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	smResp, err := s.ScriptMgr.GetOrgScripts(ctx, &scriptmgrpb.GetOrgScriptsReq{
+		OrgID: orgID,
+		Tags:  req.Tags,
+	})
+	if err != nil {
+		return nil, err
+	}
+	resp := &cloudpb.GetOrgScriptsResp{
+		Scripts: make([]*cloudpb.OrgScript, len(smResp.Scripts)),
+	}
+	for i, script := range smResp.Scripts {
+		resp.Scripts[i] = orgScriptToCloudProto(script)
+	}
+	return resp, nil
+}
+
+// GetOrgScript returns a script from the org's script library, by ID or by name.
+func (s *ScriptMgrServer) GetOrgScript(ctx context.Context, req *cloudpb.GetOrgScriptReq) (*cloudpb.OrgScript, error) {
+	orgID, err := orgIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ctx, err = contextWithAuthToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	smReq := &scriptmgrpb.GetOrgScriptReq{
+		OrgID: orgID,
+		Name:  req.Name,
+	}
+	if req.ID != "" {
+		smReq.ID = utils.ProtoFromUUIDStrOrNil(req.ID)
+	}
+	resp, err := s.ScriptMgr.GetOrgScript(ctx, smReq)
+	if err != nil {
+		return nil, err
+	}
+	return orgScriptToCloudProto(resp), nil
+}
+
+// CreateOrgScript publishes a script to the org's script library. The user making the request is
+// recorded as the owner of the script.
+func (s *ScriptMgrServer) CreateOrgScript(ctx context.Context, req *cloudpb.CreateOrgScriptReq) (*cloudpb.OrgScript, error) {
+	sCtx, err := authcontext.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	claims := sCtx.Claims.GetUserClaims()
+	ctx, err = contextWithAuthToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.ScriptMgr.CreateOrgScript(ctx, &scriptmgrpb.CreateOrgScriptReq{
+		OrgID:       utils.ProtoFromUUIDStrOrNil(claims.GetOrgID()),
+		OwnerID:     utils.ProtoFromUUIDStrOrNil(claims.GetUserID()),
+		Name:        req.Name,
+		Desc:        req.Desc,
+		PxlContents: req.PxlContents,
+		Vis:         req.Vis,
+		Tags:        req.Tags,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return orgScriptToCloudProto(resp), nil
+}
+
+// UpdateOrgScript updates a script in the org's script library.
+func (s *ScriptMgrServer) UpdateOrgScript(ctx context.Context, req *cloudpb.UpdateOrgScriptReq) (*cloudpb.OrgScript, error) {
+	orgID, err := orgIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ctx, err = contextWithAuthToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	smReq := &scriptmgrpb.UpdateOrgScriptReq{
+		OrgID:       orgID,
+		ID:          utils.ProtoFromUUIDStrOrNil(req.ID),
+		Name:        req.Name,
+		Desc:        req.Desc,
+		PxlContents: req.PxlContents,
+		Vis:         req.Vis,
+	}
+	if req.Tags != nil {
+		smReq.Tags = &scriptmgrpb.OrgScriptTags{Value: req.Tags.Value}
+	}
+	resp, err := s.ScriptMgr.UpdateOrgScript(ctx, smReq)
+	if err != nil {
+		return nil, err
+	}
+	return orgScriptToCloudProto(resp), nil
+}
+
+// DeleteOrgScript removes a script from the org's script library.
+func (s *ScriptMgrServer) DeleteOrgScript(ctx context.Context, req *cloudpb.DeleteOrgScriptReq) (*types.Empty, error) {
+	orgID, err := orgIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ctx, err = contextWithAuthToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.ScriptMgr.DeleteOrgScript(ctx, &scriptmgrpb.DeleteOrgScriptReq{
+		OrgID: orgID,
+		ID:    utils.ProtoFromUUIDStrOrNil(req.ID),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &types.Empty{}, nil
+}
@@ -62,6 +62,9 @@ func TestScriptMgr(t *testing.T) {
 
 	ID1 := uuid.Must(uuid.NewV4())
 	ID2 := uuid.Must(uuid.NewV4())
+	orgID := utils.ProtoFromUUIDStrOrNil("6ba7b810-9dad-11d1-80b4-00c04fd430c8")
+	userID := utils.ProtoFromUUIDStrOrNil("6ba7b810-9dad-11d1-80b4-00c04fd430c9")
+	createdAt := types.TimestampNow()
 	testCases := []struct {
 		name         string
 		endpoint     string
@@ -75,7 +78,7 @@ func TestScriptMgr(t *testing.T) {
 			name:     "GetLiveViews correctly translates from scriptmgrpb to cloudpb.",
 			endpoint: "GetLiveViews",
 			ctx:      CreateAPIUserTestContext(),
-			smReq:    &scriptmgrpb.GetLiveViewsReq{},
+			smReq:    &scriptmgrpb.GetLiveViewsReq{OrgID: orgID},
 			smResp: &scriptmgrpb.GetLiveViewsResp{
 				LiveViews: []*scriptmgrpb.LiveViewMetadata{
 					{
@@ -112,6 +115,7 @@ func TestScriptMgr(t *testing.T) {
 			ctx:      CreateTestContext(),
 			smReq: &scriptmgrpb.GetLiveViewContentsReq{
 				LiveViewID: utils.ProtoFromUUID(ID1),
+				OrgID:      orgID,
 			},
 			smResp: &scriptmgrpb.GetLiveViewContentsResp{
 				Metadata: &scriptmgrpb.LiveViewMetadata{
@@ -139,7 +143,7 @@ func TestScriptMgr(t *testing.T) {
 			name:     "GetScripts correctly translates between scriptmgr and cloudpb.",
 			endpoint: "GetScripts",
 			ctx:      CreateTestContext(),
-			smReq:    &scriptmgrpb.GetScriptsReq{},
+			smReq:    &scriptmgrpb.GetScriptsReq{OrgID: orgID},
 			smResp: &scriptmgrpb.GetScriptsResp{
 				Scripts: []*scriptmgrpb.ScriptMetadata{
 					{
@@ -180,6 +184,7 @@ func TestScriptMgr(t *testing.T) {
 			ctx:      CreateTestContext(),
 			smReq: &scriptmgrpb.GetScriptContentsReq{
 				ScriptID: utils.ProtoFromUUID(ID1),
+				OrgID:    orgID,
 			},
 			smResp: &scriptmgrpb.GetScriptContentsResp{
 				Metadata: &scriptmgrpb.ScriptMetadata{
@@ -203,6 +208,151 @@ func TestScriptMgr(t *testing.T) {
 				Contents: "Script1 pxl",
 			},
 		},
+		{
+			name:     "GetOrgScripts requests the scripts in the user's org.",
+			endpoint: "GetOrgScripts",
+			ctx:      CreateTestContext(),
+			smReq: &scriptmgrpb.GetOrgScriptsReq{
+				OrgID: orgID,
+				Tags:  []string{"team"},
+			},
+			smResp: &scriptmgrpb.GetOrgScriptsResp{
+				Scripts: []*scriptmgrpb.OrgScript{
+					{
+						ID:          utils.ProtoFromUUID(ID1),
+						OrgID:       orgID,
+						Name:        "team/script1",
+						Desc:        "script1 desc",
+						OwnerID:     userID,
+						PxlContents: "script1 pxl",
+						Vis:         testVis,
+						Tags:        []string{"team"},
+						CreatedAt:   createdAt,
+						UpdatedAt:   createdAt,
+					},
+				},
+			},
+			req: &cloudpb.GetOrgScriptsReq{
+				Tags: []string{"team"},
+			},
+			expectedResp: &cloudpb.GetOrgScriptsResp{
+				Scripts: []*cloudpb.OrgScript{
+					{
+						ID:          ID1.String(),
+						Name:        "team/script1",
+						Desc:        "script1 desc",
+						OwnerID:     "6ba7b810-9dad-11d1-80b4-00c04fd430c9",
+						PxlContents: "script1 pxl",
+						Vis:         testVis,
+						Tags:        []string{"team"},
+						CreatedAt:   createdAt,
+						UpdatedAt:   createdAt,
+					},
+				},
+			},
+		},
+		{
+			name:     "GetOrgScript looks up the script by name in the user's org.",
+			endpoint: "GetOrgScript",
+			ctx:      CreateTestContext(),
+			smReq: &scriptmgrpb.GetOrgScriptReq{
+				OrgID: orgID,
+				Name:  "org/script1",
+			},
+			smResp: &scriptmgrpb.OrgScript{
+				ID:          utils.ProtoFromUUID(ID1),
+				OrgID:       orgID,
+				Name:        "script1",
+				PxlContents: "script1 pxl",
+			},
+			req: &cloudpb.GetOrgScriptReq{
+				Name: "org/script1",
+			},
+			expectedResp: &cloudpb.OrgScript{
+				ID:          ID1.String(),
+				Name:        "script1",
+				PxlContents: "script1 pxl",
+			},
+		},
+		{
+			name:     "CreateOrgScript records the user as the owner.",
+			endpoint: "CreateOrgScript",
+			ctx:      CreateTestContext(),
+			smReq: &scriptmgrpb.CreateOrgScriptReq{
+				OrgID:       orgID,
+				OwnerID:     userID,
+				Name:        "script1",
+				Desc:        "script1 desc",
+				PxlContents: "script1 pxl",
+				Tags:        []string{"team"},
+			},
+			smResp: &scriptmgrpb.OrgScript{
+				ID:          utils.ProtoFromUUID(ID1),
+				OrgID:       orgID,
+				Name:        "script1",
+				Desc:        "script1 desc",
+				OwnerID:     userID,
+				PxlContents: "script1 pxl",
+				Tags:        []string{"team"},
+			},
+			req: &cloudpb.CreateOrgScriptReq{
+				Name:        "script1",
+				Desc:        "script1 desc",
+				PxlContents: "script1 pxl",
+				Tags:        []string{"team"},
+			},
+			expectedResp: &cloudpb.OrgScript{
+				ID:          ID1.String(),
+				Name:        "script1",
+				Desc:        "script1 desc",
+				OwnerID:     "6ba7b810-9dad-11d1-80b4-00c04fd430c9",
+				PxlContents: "script1 pxl",
+				Tags:        []string{"team"},
+			},
+		},
+		{
+			name:     "UpdateOrgScript correctly translates between scriptmgr and cloudpb.",
+			endpoint: "UpdateOrgScript",
+			ctx:      CreateTestContext(),
+			smReq: &scriptmgrpb.UpdateOrgScriptReq{
+				OrgID: orgID,
+				ID:    utils.ProtoFromUUID(ID1),
+				Desc:  &types.StringValue{Value: "new desc"},
+				Tags:  &scriptmgrpb.OrgScriptTags{Value: []string{}},
+			},
+			smResp: &scriptmgrpb.OrgScript{
+				ID:          utils.ProtoFromUUID(ID1),
+				OrgID:       orgID,
+				Name:        "script1",
+				Desc:        "new desc",
+				PxlContents: "script1 pxl",
+			},
+			req: &cloudpb.UpdateOrgScriptReq{
+				ID:   ID1.String(),
+				Desc: &types.StringValue{Value: "new desc"},
+				Tags: &cloudpb.OrgScriptTags{Value: []string{}},
+			},
+			expectedResp: &cloudpb.OrgScript{
+				ID:          ID1.String(),
+				Name:        "script1",
+				Desc:        "new desc",
+				PxlContents: "script1 pxl",
+			},
+		},
+		{
+			name:     "DeleteOrgScript deletes the script from the user's org.",
+			endpoint: "DeleteOrgScript",
+			ctx:      CreateTestContext(),
+			smReq: &scriptmgrpb.DeleteOrgScriptReq{
+				OrgID: orgID,
+				ID:    utils.ProtoFromUUID(ID1),
+			},
+			smResp: &scriptmgrpb.DeleteOrgScriptResp{},
+			req: &cloudpb.DeleteOrgScriptReq{
+				ID: ID1.String(),
+			},
+			expectedResp: &types.Empty{},
+		},
 	}
 
 	for _, tc := range testCases {
@@ -0,0 +1,176 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package controllers
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/graph-gophers/graphql-go"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"px.dev/pixie/src/api/proto/cloudpb"
+	"px.dev/pixie/src/utils"
+)
+
+const (
+	// The plugin service creates a retention script's cron script before it commits the retention
+	// script itself, so a lookup right after the change is published can miss it.
+	retentionScriptLookupAttempts = 3
+	retentionScriptLookupInterval = 500 * time.Millisecond
+)
+
+var errSubscriptionsUnavailable = errors.New("subscriptions are not available")
+
+func (q *QueryResolver) subscribeToOrgEvents(ctx context.Context) (<-chan orgEvent, error) {
+	if q.Env.EventHub == nil {
+		return nil, errSubscriptionsUnavailable
+	}
+	orgID, err := orgIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return q.Env.EventHub.Subscribe(ctx, utils.UUIDFromProtoOrNil(orgID)), nil
+}
+
+type clusterUpdatedArgs struct {
+	ID *graphql.ID
+}
+
+// ClusterUpdated streams the cluster info of the org's clusters whenever their status, version or
+// number of nodes changes. If an ID is specified, only updates for that cluster are sent.
+func (q *QueryResolver) ClusterUpdated(ctx context.Context, args *clusterUpdatedArgs) (<-chan *ClusterInfoResolver, error) {
+	var clusterID uuid.UUID
+	if args.ID != nil {
+		clusterID = uuid.FromStringOrNil(string(*args.ID))
+		if clusterID == uuid.Nil {
+			return nil, errors.New("invalid cluster ID")
+		}
+	}
+
+	events, err := q.subscribeToOrgEvents(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := make(chan *ClusterInfoResolver)
+	go func() {
+		defer close(updates)
+		for ev := range events {
+			if ev.clusterStatus == nil {
+				continue
+			}
+			vizierID := ev.clusterStatus.VizierID
+			if clusterID != uuid.Nil && utils.UUIDFromProtoOrNil(vizierID) != clusterID {
+				continue
+			}
+			// Fetch the full cluster info rather than using the event, so that the update contains
+			// everything a client can query for, and is subject to the same authorization checks.
+			resp, err := q.Env.VizierClusterInfo.GetClusterInfo(ctx, &cloudpb.GetClusterInfoRequest{ID: vizierID})
+			if err != nil || len(resp.Clusters) != 1 {
+				log.WithError(err).Error("Failed to fetch info for updated cluster")
+				continue
+			}
+			cluster, err := clusterInfoToResolver(resp.Clusters[0])
+			if err != nil {
+				continue
+			}
+			select {
+			case updates <- cluster:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return updates, nil
+}
+
+// RetentionScriptChangeResolver is the resolver responsible for changes to retention scripts.
+type RetentionScriptChangeResolver struct {
+	scriptID uuid.UUID
+	Deleted  bool
+	Script   *RetentionScriptResolver
+}
+
+// ID returns the ID of the changed retention script.
+func (r *RetentionScriptChangeResolver) ID() graphql.ID {
+	return graphql.ID(r.scriptID.String())
+}
+
+// RetentionScriptChanged streams changes to the org's retention scripts, such as a script being
+// created, deleted, enabled or disabled.
+func (q *QueryResolver) RetentionScriptChanged(ctx context.Context) (<-chan *RetentionScriptChangeResolver, error) {
+	events, err := q.subscribeToOrgEvents(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make(chan *RetentionScriptChangeResolver)
+	go func() {
+		defer close(changes)
+		for ev := range events {
+			if ev.cronScript == nil {
+				continue
+			}
+			change := &RetentionScriptChangeResolver{
+				scriptID: utils.UUIDFromProtoOrNil(ev.cronScript.ScriptID),
+				Deleted:  ev.cronScript.Deleted,
+			}
+			if !change.Deleted {
+				script, err := q.lookupRetentionScript(ctx, change.scriptID)
+				if err != nil {
+					// Cron scripts that don't belong to a retention plugin are not found, and are skipped.
+					if status.Code(err) != codes.NotFound {
+						log.WithError(err).Error("Failed to fetch updated retention script")
+					}
+					continue
+				}
+				change.Script = script
+			}
+			select {
+			case changes <- change:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return changes, nil
+}
+
+func (q *QueryResolver) lookupRetentionScript(ctx context.Context, id uuid.UUID) (*RetentionScriptResolver, error) {
+	var err error
+	for i := 0; i < retentionScriptLookupAttempts; i++ {
+		if i > 0 {
+			select {
+			case <-time.After(retentionScriptLookupInterval):
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+		var script *RetentionScriptResolver
+		script, err = q.RetentionScript(ctx, retentionScriptArgs{ID: id.String()})
+		if status.Code(err) != codes.NotFound {
+			return script, err
+		}
+	}
+	return nil, err
+}
@@ -0,0 +1,244 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package controllers_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/graph-gophers/graphql-go"
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"px.dev/pixie/src/api/proto/cloudpb"
+	"px.dev/pixie/src/cloud/api/controllers"
+	"px.dev/pixie/src/cloud/api/controllers/testutils"
+	"px.dev/pixie/src/cloud/shared/messages"
+	"px.dev/pixie/src/cloud/shared/messagespb"
+	"px.dev/pixie/src/shared/cvmsgspb"
+	"px.dev/pixie/src/utils"
+	"px.dev/pixie/src/utils/testingutils"
+)
+
+const (
+	testOrgID      = "6ba7b810-9dad-11d1-80b4-00c04fd430c8"
+	testOtherOrgID = "6ba7b810-9dad-11d1-80b4-00c04fd430c7"
+)
+
+func mustPublish(t *testing.T, nc *nats.Conn, channel string, msg interface{ Marshal() ([]byte, error) }) {
+	b, err := msg.Marshal()
+	require.NoError(t, err)
+	require.NoError(t, nc.Publish(channel, b))
+}
+
+func nextResponse(t *testing.T, ch <-chan interface{}) string {
+	select {
+	case r := <-ch:
+		resp, ok := r.(*graphql.Response)
+		require.True(t, ok)
+		require.Empty(t, resp.Errors)
+		return string(resp.Data)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for subscription response")
+	}
+	return ""
+}
+
+func TestClusterUpdatedSubscription(t *testing.T) {
+	gqlEnv, mockClients, cleanup := testutils.CreateTestGraphQLEnv(t)
+	defer cleanup()
+
+	nc, natsCleanup := testingutils.MustStartTestNATS(t)
+	defer natsCleanup()
+	hub, err := controllers.NewOrgEventHub(nc)
+	require.NoError(t, err)
+	defer hub.Stop()
+	gqlEnv.EventHub = hub
+
+	clusterID := utils.ProtoFromUUIDStrOrNil("7ba7b810-9dad-11d1-80b4-00c04fd430c8")
+	mockClients.MockVizierClusterInfo.EXPECT().
+		GetClusterInfo(gomock.Any(), &cloudpb.GetClusterInfoRequest{ID: clusterID}).
+		Return(&cloudpb.GetClusterInfoResponse{
+			Clusters: []*cloudpb.ClusterInfo{{
+				ID:                   clusterID,
+				Status:               cloudpb.CS_DISCONNECTED,
+				VizierVersion:        "vzVersion",
+				NumNodes:             3,
+				NumInstrumentedNodes: 2,
+			}},
+		}, nil)
+
+	ctx, cancel := context.WithCancel(CreateTestContext())
+	defer cancel()
+
+	gqlSchema := LoadSchema(gqlEnv)
+	ch, err := gqlSchema.Subscribe(ctx, `
+		subscription {
+			clusterUpdated(id: "7ba7b810-9dad-11d1-80b4-00c04fd430c8") {
+				id
+				status
+				vizierVersion
+				numNodes
+				numInstrumentedNodes
+			}
+		}
+	`, "", nil)
+	require.NoError(t, err)
+
+	// Updates for clusters in other orgs, or other clusters in the org, should not be sent.
+	mustPublish(t, nc, messages.VizierStatusChangedChannel, &messagespb.VizierStatusChanged{
+		VizierID: clusterID,
+		OrgID:    utils.ProtoFromUUIDStrOrNil(testOtherOrgID),
+		Status:   cvmsgspb.VZ_ST_DISCONNECTED,
+	})
+	mustPublish(t, nc, messages.VizierStatusChangedChannel, &messagespb.VizierStatusChanged{
+		VizierID: utils.ProtoFromUUIDStrOrNil("8ba7b810-9dad-11d1-80b4-00c04fd430c8"),
+		OrgID:    utils.ProtoFromUUIDStrOrNil(testOrgID),
+		Status:   cvmsgspb.VZ_ST_HEALTHY,
+	})
+	mustPublish(t, nc, messages.VizierStatusChangedChannel, &messagespb.VizierStatusChanged{
+		VizierID: clusterID,
+		OrgID:    utils.ProtoFromUUIDStrOrNil(testOrgID),
+		Status:   cvmsgspb.VZ_ST_DISCONNECTED,
+	})
+
+	assert.JSONEq(t, `
+		{
+			"clusterUpdated": {
+				"id": "7ba7b810-9dad-11d1-80b4-00c04fd430c8",
+				"status": "CS_DISCONNECTED",
+				"vizierVersion": "vzVersion",
+				"numNodes": 3,
+				"numInstrumentedNodes": 2
+			}
+		}
+	`, nextResponse(t, ch))
+}
+
+func TestRetentionScriptChangedSubscription(t *testing.T) {
+	gqlEnv, mockClients, cleanup := testutils.CreateTestGraphQLEnv(t)
+	defer cleanup()
+
+	nc, natsCleanup := testingutils.MustStartTestNATS(t)
+	defer natsCleanup()
+	hub, err := controllers.NewOrgEventHub(nc)
+	require.NoError(t, err)
+	defer hub.Stop()
+	gqlEnv.EventHub = hub
+
+	scriptID := utils.ProtoFromUUIDStrOrNil("1ba7b810-9dad-11d1-80b4-00c04fd430c8")
+	cronScriptID := utils.ProtoFromUUIDStrOrNil("1ba7b810-9dad-11d1-80b4-00c04fd430c9")
+	mockClients.MockPlugin.EXPECT().
+		GetRetentionScript(gomock.Any(), &cloudpb.GetRetentionScriptRequest{ID: cronScriptID}).
+		Return(nil, status.Error(codes.NotFound, "script not found")).
+		Times(3)
+	mockClients.MockPlugin.EXPECT().
+		GetRetentionScript(gomock.Any(), &cloudpb.GetRetentionScriptRequest{ID: scriptID}).
+		Return(&cloudpb.GetRetentionScriptResponse{
+			Script: &cloudpb.RetentionScript{
+				ScriptID:   scriptID,
+				ScriptName: "Test Script",
+				FrequencyS: 5,
+				PluginId:   "test-plugin",
+				Enabled:    false,
+			},
+			Contents: "px.display()",
+		}, nil)
+
+	ctx, cancel := context.WithCancel(CreateTestContext())
+	defer cancel()
+
+	gqlSchema := LoadSchema(gqlEnv)
+	ch, err := gqlSchema.Subscribe(ctx, `
+		subscription {
+			retentionScriptChanged {
+				id
+				deleted
+				script {
+					name
+					enabled
+					pluginID
+				}
+			}
+		}
+	`, "", nil)
+	require.NoError(t, err)
+
+	// Cron scripts that aren't retention scripts should be skipped.
+	mustPublish(t, nc, messages.CronScriptChangedChannel, &messagespb.CronScriptChanged{
+		ScriptID: cronScriptID,
+		OrgID:    utils.ProtoFromUUIDStrOrNil(testOrgID),
+		Enabled:  true,
+	})
+	mustPublish(t, nc, messages.CronScriptChangedChannel, &messagespb.CronScriptChanged{
+		ScriptID: scriptID,
+		OrgID:    utils.ProtoFromUUIDStrOrNil(testOrgID),
+		Enabled:  false,
+	})
+	mustPublish(t, nc, messages.CronScriptChangedChannel, &messagespb.CronScriptChanged{
+		ScriptID: scriptID,
+		OrgID:    utils.ProtoFromUUIDStrOrNil(testOrgID),
+		Deleted:  true,
+	})
+
+	assert.JSONEq(t, `
+		{
+			"retentionScriptChanged": {
+				"id": "1ba7b810-9dad-11d1-80b4-00c04fd430c8",
+				"deleted": false,
+				"script": {
+					"name": "Test Script",
+					"enabled": false,
+					"pluginID": "test-plugin"
+				}
+			}
+		}
+	`, nextResponse(t, ch))
+	assert.JSONEq(t, `
+		{
+			"retentionScriptChanged": {
+				"id": "1ba7b810-9dad-11d1-80b4-00c04fd430c8",
+				"deleted": true,
+				"script": null
+			}
+		}
+	`, nextResponse(t, ch))
+}
+
+func TestClusterUpdatedSubscription_NoEventHub(t *testing.T) {
+	gqlEnv, _, cleanup := testutils.CreateTestGraphQLEnv(t)
+	defer cleanup()
+
+	gqlSchema := LoadSchema(gqlEnv)
+	ch, err := gqlSchema.Subscribe(CreateTestContext(), `subscription { clusterUpdated { id } }`, "", nil)
+	require.NoError(t, err)
+
+	r := <-ch
+	resp, ok := r.(*graphql.Response)
+	require.True(t, ok)
+	require.Len(t, resp.Errors, 1)
+	b, err := json.Marshal(resp.Errors[0])
+	require.NoError(t, err)
+	assert.Contains(t, string(b), "subscriptions are not available")
+}
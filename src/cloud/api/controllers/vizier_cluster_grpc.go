@@ -21,6 +21,8 @@ package controllers
 import (
 	"context"
 	"fmt"
+	"io"
+	"strings"
 
 	"github.com/gofrs/uuid"
 	"golang.org/x/net/idna"
@@ -42,6 +44,7 @@ import (
 // VizierClusterInfo is the server that implements the VizierClusterInfo gRPC service.
 type VizierClusterInfo struct {
 	VzMgr                 vzmgrpb.VZMgrServiceClient
+	VzMetadata            vzmgrpb.VZMetadataServiceClient
 	ArtifactTrackerClient artifacttrackerpb.ArtifactTrackerClient
 }
 
@@ -79,6 +82,18 @@ func (v *VizierClusterInfo) GetClusterInfo(ctx context.Context, request *cloudpb
 	vzIDs := make([]*uuidpb.UUID, 0)
 	if request.ID != nil {
 		vzIDs = append(vzIDs, request.ID)
+	} else if request.LabelSelector != "" {
+		viziers, err := v.VzMgr.GetViziersByLabelSelector(ctx, &vzmgrpb.GetViziersByLabelSelectorRequest{
+			OrgID:         utils.ProtoFromUUID(orgID),
+			LabelSelector: request.LabelSelector,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(viziers.VizierIDs) == 0 {
+			return &cloudpb.GetClusterInfoResponse{}, nil
+		}
+		vzIDs = viziers.VizierIDs
 	} else {
 		viziers, err := v.VzMgr.GetViziersByOrg(ctx, utils.ProtoFromUUID(orgID))
 		if err != nil {
@@ -214,6 +229,7 @@ func (v *VizierClusterInfo) getClusterInfoForViziers(ctx context.Context, ids []
 			NumInstrumentedNodes:          vzInfo.NumInstrumentedNodes,
 			PreviousStatus:                prevS,
 			PreviousStatusTime:            vzInfo.PreviousStatusTime,
+			Labels:                        vzInfo.Labels,
 		})
 	}
 
@@ -247,6 +263,24 @@ func (v *VizierClusterInfo) GetClusterConnectionInfo(ctx context.Context, reques
 
 // UpdateClusterVizierConfig supports updates of VizierConfig for a cluster
 func (v *VizierClusterInfo) UpdateClusterVizierConfig(ctx context.Context, req *cloudpb.UpdateClusterVizierConfigRequest) (*cloudpb.UpdateClusterVizierConfigResponse, error) {
+	if req.ConfigUpdate == nil || req.ConfigUpdate.Labels == nil {
+		return &cloudpb.UpdateClusterVizierConfigResponse{}, nil
+	}
+
+	ctx, err := contextWithAuthToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = v.VzMgr.UpdateVizierConfig(ctx, &cvmsgspb.UpdateVizierConfigRequest{
+		VizierID: req.ID,
+		ConfigUpdate: &cvmsgspb.VizierConfigUpdate{
+			Labels: &cvmsgspb.VizierLabels{Value: req.ConfigUpdate.Labels.Value},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
 	return &cloudpb.UpdateClusterVizierConfigResponse{}, nil
 }
 
@@ -287,6 +321,140 @@ func (v *VizierClusterInfo) UpdateOrInstallCluster(ctx context.Context, req *clo
 	}, nil
 }
 
+// WatchClusterMetadata streams the changes to K8s resources in the given cluster.
+func (v *VizierClusterInfo) WatchClusterMetadata(req *cloudpb.WatchClusterMetadataRequest, srv cloudpb.VizierClusterInfo_WatchClusterMetadataServer) error {
+	ctx, err := contextWithAuthToken(srv.Context())
+	if err != nil {
+		return err
+	}
+
+	stream, err := v.VzMetadata.WatchMetadataUpdates(ctx, &vzmgrpb.WatchMetadataUpdatesRequest{
+		VizierID:          req.ClusterID,
+		FromUpdateVersion: req.FromUpdateVersion,
+		Follow:            req.Follow,
+	})
+	if err != nil {
+		return err
+	}
+
+	filter := newClusterMetadataFilter(req)
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var events []*cloudpb.ClusterMetadataEvent
+		for _, update := range resp.Updates {
+			ev := resourceUpdateToClusterMetadataEvent(update)
+			if ev == nil || !filter.matches(ev) {
+				continue
+			}
+			events = append(events, ev)
+		}
+		if len(events) == 0 {
+			continue
+		}
+		if err := srv.Send(&cloudpb.WatchClusterMetadataResponse{Events: events}); err != nil {
+			return err
+		}
+	}
+}
+
+type clusterMetadataFilter struct {
+	namespaces map[string]bool
+	kinds      map[cloudpb.K8SResourceKind]bool
+	name       string
+}
+
+func newClusterMetadataFilter(req *cloudpb.WatchClusterMetadataRequest) *clusterMetadataFilter {
+	f := &clusterMetadataFilter{
+		namespaces: make(map[string]bool),
+		kinds:      make(map[cloudpb.K8SResourceKind]bool),
+		name:       req.Name,
+	}
+	for _, ns := range req.Namespaces {
+		f.namespaces[ns] = true
+	}
+	for _, k := range req.Kinds {
+		f.kinds[k] = true
+	}
+	return f
+}
+
+func (f *clusterMetadataFilter) matches(ev *cloudpb.ClusterMetadataEvent) bool {
+	if len(f.kinds) > 0 && !f.kinds[ev.Kind] {
+		return false
+	}
+	if len(f.namespaces) > 0 {
+		// Namespaces are matched on their own name, since they don't belong to a namespace.
+		ns := ev.Namespace
+		if ev.Kind == cloudpb.KRK_NAMESPACE {
+			ns = ev.Name
+		}
+		if !f.namespaces[ns] {
+			return false
+		}
+	}
+	return f.name == "" || strings.Contains(ev.Name, f.name)
+}
+
+func updateEventType(stopTimestampNS int64) cloudpb.ClusterMetadataEventType {
+	if stopTimestampNS > 0 {
+		return cloudpb.CME_DELETED
+	}
+	return cloudpb.CME_UPDATED
+}
+
+// resourceUpdateToClusterMetadataEvent converts a metadata update to an event. Returns nil for
+// kinds of updates that aren't exposed through the API.
+func resourceUpdateToClusterMetadataEvent(update *metadatapb.ResourceUpdate) *cloudpb.ClusterMetadataEvent {
+	ev := &cloudpb.ClusterMetadataEvent{UpdateVersion: update.UpdateVersion}
+	switch u := update.Update.(type) {
+	case *metadatapb.ResourceUpdate_PodUpdate:
+		p := u.PodUpdate
+		ev.Kind = cloudpb.KRK_POD
+		ev.UID = p.UID
+		ev.Name = p.Name
+		ev.Namespace = p.Namespace
+		ev.StartTimestampNS = p.StartTimestampNS
+		ev.StopTimestampNS = p.StopTimestampNS
+		ev.Phase = convertPodPhase(p.Phase).String()
+		ev.NodeName = p.NodeName
+		ev.Message = p.Message
+	case *metadatapb.ResourceUpdate_ServiceUpdate:
+		svc := u.ServiceUpdate
+		ev.Kind = cloudpb.KRK_SERVICE
+		ev.UID = svc.UID
+		ev.Name = svc.Name
+		ev.Namespace = svc.Namespace
+		ev.StartTimestampNS = svc.StartTimestampNS
+		ev.StopTimestampNS = svc.StopTimestampNS
+	case *metadatapb.ResourceUpdate_NamespaceUpdate:
+		ns := u.NamespaceUpdate
+		ev.Kind = cloudpb.KRK_NAMESPACE
+		ev.UID = ns.UID
+		ev.Name = ns.Name
+		ev.StartTimestampNS = ns.StartTimestampNS
+		ev.StopTimestampNS = ns.StopTimestampNS
+	case *metadatapb.ResourceUpdate_NodeUpdate:
+		n := u.NodeUpdate
+		ev.Kind = cloudpb.KRK_NODE
+		ev.UID = n.UID
+		ev.Name = n.Name
+		ev.StartTimestampNS = n.StartTimestampNS
+		ev.StopTimestampNS = n.StopTimestampNS
+		ev.Phase = strings.TrimPrefix(n.Phase.String(), "NODE_PHASE_")
+	default:
+		return nil
+	}
+	ev.Type = updateEventType(ev.StopTimestampNS)
+	return ev
+}
+
 func vzStatusToClusterStatus(s cvmsgspb.VizierStatus) cloudpb.ClusterStatus {
 	switch s {
 	case cvmsgspb.VZ_ST_HEALTHY:
@@ -307,3 +475,105 @@ func vzStatusToClusterStatus(s cvmsgspb.VizierStatus) cloudpb.ClusterStatus {
 		return cloudpb.CS_UNKNOWN
 	}
 }
+
+func updatePolicyToCloudProto(p *vzmgrpb.VizierUpdatePolicy) *cloudpb.VizierUpdatePolicy {
+	policy := &cloudpb.VizierUpdatePolicy{
+		PinnedVersion:  p.PinnedVersion,
+		VersionsBehind: p.VersionsBehind,
+		Timezone:       p.Timezone,
+		TargetVersion:  p.TargetVersion,
+	}
+	if p.Channel == vzmgrpb.VUC_STABLE {
+		policy.Channel = cloudpb.VUC_STABLE
+	}
+	for _, w := range p.MaintenanceWindows {
+		policy.MaintenanceWindows = append(policy.MaintenanceWindows, &cloudpb.MaintenanceWindow{
+			Days:      w.Days,
+			StartHour: w.StartHour,
+			EndHour:   w.EndHour,
+		})
+	}
+	for _, w := range p.Waves {
+		policy.Waves = append(policy.Waves, &cloudpb.UpdateWave{
+			Name:       w.Name,
+			ClusterIDs: w.ClusterIDs,
+		})
+	}
+	return policy
+}
+
+func updatePolicyToVzMgrProto(orgID uuid.UUID, p *cloudpb.VizierUpdatePolicy) *vzmgrpb.VizierUpdatePolicy {
+	policy := &vzmgrpb.VizierUpdatePolicy{
+		OrgID:          utils.ProtoFromUUID(orgID),
+		PinnedVersion:  p.PinnedVersion,
+		VersionsBehind: p.VersionsBehind,
+		Timezone:       p.Timezone,
+	}
+	if p.Channel == cloudpb.VUC_STABLE {
+		policy.Channel = vzmgrpb.VUC_STABLE
+	}
+	for _, w := range p.MaintenanceWindows {
+		policy.MaintenanceWindows = append(policy.MaintenanceWindows, &vzmgrpb.MaintenanceWindow{
+			Days:      w.Days,
+			StartHour: w.StartHour,
+			EndHour:   w.EndHour,
+		})
+	}
+	for _, w := range p.Waves {
+		policy.Waves = append(policy.Waves, &vzmgrpb.UpdateWave{
+			Name:       w.Name,
+			ClusterIDs: w.ClusterIDs,
+		})
+	}
+	return policy
+}
+
+// GetVizierUpdatePolicy gets the policy for automatically updating the viziers in the current org.
+func (v *VizierClusterInfo) GetVizierUpdatePolicy(ctx context.Context, req *cloudpb.GetVizierUpdatePolicyRequest) (*cloudpb.VizierUpdatePolicy, error) {
+	sCtx, err := authcontext.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	orgID, err := uuid.FromString(sCtx.Claims.GetUserClaims().OrgID)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, err = contextWithAuthToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := v.VzMgr.GetVizierUpdatePolicy(ctx, utils.ProtoFromUUID(orgID))
+	if err != nil {
+		return nil, err
+	}
+	return updatePolicyToCloudProto(resp), nil
+}
+
+// SetVizierUpdatePolicy replaces the policy for automatically updating the viziers in the current org.
+func (v *VizierClusterInfo) SetVizierUpdatePolicy(ctx context.Context, req *cloudpb.SetVizierUpdatePolicyRequest) (*cloudpb.VizierUpdatePolicy, error) {
+	if req.Policy == nil {
+		return nil, status.Error(codes.InvalidArgument, "policy cannot be empty")
+	}
+
+	sCtx, err := authcontext.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	orgID, err := uuid.FromString(sCtx.Claims.GetUserClaims().OrgID)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, err = contextWithAuthToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := v.VzMgr.SetVizierUpdatePolicy(ctx, updatePolicyToVzMgrProto(orgID, req.Policy))
+	if err != nil {
+		return nil, err
+	}
+	return updatePolicyToCloudProto(resp), nil
+}
@@ -20,6 +20,7 @@ package controllers_test
 
 import (
 	"context"
+	"io"
 	"testing"
 
 	"github.com/gogo/protobuf/types"
@@ -28,11 +29,13 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"px.dev/pixie/src/api/proto/cloudpb"
+	mock_cloudpb "px.dev/pixie/src/api/proto/cloudpb/mock"
 	"px.dev/pixie/src/api/proto/uuidpb"
 	"px.dev/pixie/src/cloud/api/controllers"
 	"px.dev/pixie/src/cloud/api/controllers/testutils"
 	"px.dev/pixie/src/cloud/artifact_tracker/artifacttrackerpb"
 	"px.dev/pixie/src/cloud/vzmgr/vzmgrpb"
+	mock_vzmgrpb "px.dev/pixie/src/cloud/vzmgr/vzmgrpb/mock"
 	"px.dev/pixie/src/shared/artifacts/versionspb"
 	"px.dev/pixie/src/shared/cvmsgspb"
 	"px.dev/pixie/src/shared/k8s/metadatapb"
@@ -474,6 +477,85 @@ func TestVizierClusterInfo_UpdateClusterVizierConfig(t *testing.T) {
 	}
 }
 
+func TestVizierClusterInfo_GetClusterInfoWithLabelSelector(t *testing.T) {
+	clusterID := utils.ProtoFromUUIDStrOrNil("7ba7b810-9dad-11d1-80b4-00c04fd430c8")
+	assert.NotNil(t, clusterID)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	_, mockClients, cleanup := testutils.CreateTestAPIEnv(t)
+	defer cleanup()
+	ctx := CreateTestContext()
+
+	mockClients.MockVzMgr.EXPECT().GetViziersByLabelSelector(gomock.Any(), &vzmgrpb.GetViziersByLabelSelectorRequest{
+		OrgID:         utils.ProtoFromUUIDStrOrNil("6ba7b810-9dad-11d1-80b4-00c04fd430c8"),
+		LabelSelector: "env=prod",
+	}).Return(&vzmgrpb.GetViziersByOrgResponse{
+		VizierIDs: []*uuidpb.UUID{clusterID},
+	}, nil)
+
+	mockClients.MockVzMgr.EXPECT().GetVizierInfos(gomock.Any(), &vzmgrpb.GetVizierInfosRequest{
+		VizierIDs: []*uuidpb.UUID{clusterID},
+	}).Return(&vzmgrpb.GetVizierInfosResponse{
+		VizierInfos: []*cvmsgspb.VizierInfo{{
+			VizierID:        clusterID,
+			Status:          cvmsgspb.VZ_ST_HEALTHY,
+			LastHeartbeatNs: int64(1305646598000000000),
+			Config:          &cvmsgspb.VizierConfig{},
+			ClusterName:     "some cluster",
+			Labels:          map[string]string{"env": "prod"},
+		},
+		},
+	}, nil)
+
+	vzClusterInfoServer := &controllers.VizierClusterInfo{
+		VzMgr: mockClients.MockVzMgr,
+	}
+
+	resp, err := vzClusterInfoServer.GetClusterInfo(ctx, &cloudpb.GetClusterInfoRequest{
+		LabelSelector: "env=prod",
+	})
+
+	require.NoError(t, err)
+	require.Equal(t, 1, len(resp.Clusters))
+	assert.Equal(t, clusterID, resp.Clusters[0].ID)
+	assert.Equal(t, map[string]string{"env": "prod"}, resp.Clusters[0].Labels)
+}
+
+func TestVizierClusterInfo_UpdateClusterVizierConfigLabels(t *testing.T) {
+	clusterID := utils.ProtoFromUUIDStrOrNil("7ba7b810-9dad-11d1-80b4-00c04fd430c8")
+	assert.NotNil(t, clusterID)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	_, mockClients, cleanup := testutils.CreateTestAPIEnv(t)
+	defer cleanup()
+	ctx := CreateTestContext()
+
+	mockClients.MockVzMgr.EXPECT().UpdateVizierConfig(gomock.Any(), &cvmsgspb.UpdateVizierConfigRequest{
+		VizierID: clusterID,
+		ConfigUpdate: &cvmsgspb.VizierConfigUpdate{
+			Labels: &cvmsgspb.VizierLabels{Value: map[string]string{"env": "prod"}},
+		},
+	}).Return(&cvmsgspb.UpdateVizierConfigResponse{}, nil)
+
+	vzClusterInfoServer := &controllers.VizierClusterInfo{
+		VzMgr: mockClients.MockVzMgr,
+	}
+
+	resp, err := vzClusterInfoServer.UpdateClusterVizierConfig(ctx, &cloudpb.UpdateClusterVizierConfigRequest{
+		ID: clusterID,
+		ConfigUpdate: &cloudpb.VizierConfigUpdate{
+			Labels: &cloudpb.ClusterLabels{Value: map[string]string{"env": "prod"}},
+		},
+	})
+
+	require.NoError(t, err)
+	assert.NotNil(t, resp)
+}
+
 func TestVizierClusterInfo_UpdateOrInstallCluster(t *testing.T) {
 	tests := []struct {
 		name string
@@ -531,3 +613,176 @@ func TestVizierClusterInfo_UpdateOrInstallCluster(t *testing.T) {
 		})
 	}
 }
+
+func TestVizierClusterInfo_WatchClusterMetadata(t *testing.T) {
+	clusterID := utils.ProtoFromUUIDStrOrNil("7ba7b810-9dad-11d1-80b4-00c04fd430c8")
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockMD := mock_vzmgrpb.NewMockVZMetadataServiceClient(ctrl)
+	mockStream := mock_vzmgrpb.NewMockVZMetadataService_WatchMetadataUpdatesClient(ctrl)
+	mockSrv := mock_cloudpb.NewMockVizierClusterInfo_WatchClusterMetadataServer(ctrl)
+
+	mockSrv.EXPECT().Context().Return(CreateTestContext()).AnyTimes()
+	mockMD.EXPECT().WatchMetadataUpdates(gomock.Any(), &vzmgrpb.WatchMetadataUpdatesRequest{
+		VizierID:          clusterID,
+		FromUpdateVersion: 10,
+		Follow:            true,
+	}).Return(mockStream, nil)
+
+	gomock.InOrder(
+		mockStream.EXPECT().Recv().Return(&vzmgrpb.WatchMetadataUpdatesResponse{
+			Updates: []*metadatapb.ResourceUpdate{
+				{
+					UpdateVersion: 11,
+					Update: &metadatapb.ResourceUpdate_PodUpdate{PodUpdate: &metadatapb.PodUpdate{
+						UID: "pod-1", Name: "frontend-abc", Namespace: "prod", Phase: metadatapb.RUNNING, NodeName: "node-1",
+					}},
+				},
+				{
+					UpdateVersion: 12,
+					Update: &metadatapb.ResourceUpdate_PodUpdate{PodUpdate: &metadatapb.PodUpdate{
+						UID: "pod-2", Name: "frontend-def", Namespace: "staging",
+					}},
+				},
+				{
+					UpdateVersion: 13,
+					Update: &metadatapb.ResourceUpdate_ServiceUpdate{ServiceUpdate: &metadatapb.ServiceUpdate{
+						UID: "svc-1", Name: "frontend", Namespace: "prod", StopTimestampNS: 100,
+					}},
+				},
+				{
+					UpdateVersion: 14,
+					Update: &metadatapb.ResourceUpdate_ContainerUpdate{ContainerUpdate: &metadatapb.ContainerUpdate{
+						Name: "frontend",
+					}},
+				},
+			},
+		}, nil),
+		mockStream.EXPECT().Recv().Return(&vzmgrpb.WatchMetadataUpdatesResponse{
+			Updates: []*metadatapb.ResourceUpdate{
+				{
+					UpdateVersion: 15,
+					Update: &metadatapb.ResourceUpdate_NodeUpdate{NodeUpdate: &metadatapb.NodeUpdate{
+						UID: "node-1", Name: "node-1",
+					}},
+				},
+			},
+		}, nil),
+		mockStream.EXPECT().Recv().Return(nil, io.EOF),
+	)
+
+	// Only the pod and service in the prod namespace match the filter. The node update is filtered
+	// out, so nothing is sent for the second batch.
+	mockSrv.EXPECT().Send(&cloudpb.WatchClusterMetadataResponse{
+		Events: []*cloudpb.ClusterMetadataEvent{
+			{
+				UpdateVersion: 11,
+				Kind:          cloudpb.KRK_POD,
+				Type:          cloudpb.CME_UPDATED,
+				UID:           "pod-1",
+				Name:          "frontend-abc",
+				Namespace:     "prod",
+				Phase:         "RUNNING",
+				NodeName:      "node-1",
+			},
+			{
+				UpdateVersion:   13,
+				Kind:            cloudpb.KRK_SERVICE,
+				Type:            cloudpb.CME_DELETED,
+				UID:             "svc-1",
+				Name:            "frontend",
+				Namespace:       "prod",
+				StopTimestampNS: 100,
+			},
+		},
+	}).Return(nil)
+
+	vzClusterInfoServer := &controllers.VizierClusterInfo{
+		VzMetadata: mockMD,
+	}
+	err := vzClusterInfoServer.WatchClusterMetadata(&cloudpb.WatchClusterMetadataRequest{
+		ClusterID:         clusterID,
+		FromUpdateVersion: 10,
+		Follow:            true,
+		Namespaces:        []string{"prod"},
+		Name:              "frontend",
+	}, mockSrv)
+	require.NoError(t, err)
+}
+
+func TestVizierClusterInfo_GetVizierUpdatePolicy(t *testing.T) {
+	orgID := utils.ProtoFromUUIDStrOrNil("6ba7b810-9dad-11d1-80b4-00c04fd430c8")
+	clusterID := utils.ProtoFromUUIDStrOrNil("7ba7b810-9dad-11d1-80b4-00c04fd430c8")
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	_, mockClients, cleanup := testutils.CreateTestAPIEnv(t)
+	defer cleanup()
+
+	mockClients.MockVzMgr.EXPECT().GetVizierUpdatePolicy(gomock.Any(), orgID).Return(&vzmgrpb.VizierUpdatePolicy{
+		OrgID:          orgID,
+		Channel:        vzmgrpb.VUC_STABLE,
+		VersionsBehind: 1,
+		MaintenanceWindows: []*vzmgrpb.MaintenanceWindow{
+			{Days: []string{"sat"}, StartHour: 2, EndHour: 4},
+		},
+		Timezone:      "UTC",
+		Waves:         []*vzmgrpb.UpdateWave{{Name: "canary", ClusterIDs: []*uuidpb.UUID{clusterID}}},
+		TargetVersion: "0.12.0",
+	}, nil)
+
+	vzClusterInfoServer := &controllers.VizierClusterInfo{
+		VzMgr: mockClients.MockVzMgr,
+	}
+
+	resp, err := vzClusterInfoServer.GetVizierUpdatePolicy(CreateTestContext(), &cloudpb.GetVizierUpdatePolicyRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, &cloudpb.VizierUpdatePolicy{
+		Channel:        cloudpb.VUC_STABLE,
+		VersionsBehind: 1,
+		MaintenanceWindows: []*cloudpb.MaintenanceWindow{
+			{Days: []string{"sat"}, StartHour: 2, EndHour: 4},
+		},
+		Timezone:      "UTC",
+		Waves:         []*cloudpb.UpdateWave{{Name: "canary", ClusterIDs: []*uuidpb.UUID{clusterID}}},
+		TargetVersion: "0.12.0",
+	}, resp)
+}
+
+func TestVizierClusterInfo_SetVizierUpdatePolicy(t *testing.T) {
+	orgID := utils.ProtoFromUUIDStrOrNil("6ba7b810-9dad-11d1-80b4-00c04fd430c8")
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	_, mockClients, cleanup := testutils.CreateTestAPIEnv(t)
+	defer cleanup()
+
+	mockClients.MockVzMgr.EXPECT().SetVizierUpdatePolicy(gomock.Any(), &vzmgrpb.VizierUpdatePolicy{
+		OrgID:         orgID,
+		PinnedVersion: "0.11.0",
+		Timezone:      "Europe/Berlin",
+	}).Return(&vzmgrpb.VizierUpdatePolicy{
+		OrgID:         orgID,
+		PinnedVersion: "0.11.0",
+		Timezone:      "Europe/Berlin",
+		TargetVersion: "0.11.0",
+	}, nil)
+
+	vzClusterInfoServer := &controllers.VizierClusterInfo{
+		VzMgr: mockClients.MockVzMgr,
+	}
+
+	resp, err := vzClusterInfoServer.SetVizierUpdatePolicy(CreateTestContext(), &cloudpb.SetVizierUpdatePolicyRequest{
+		Policy: &cloudpb.VizierUpdatePolicy{
+			PinnedVersion: "0.11.0",
+			Timezone:      "Europe/Berlin",
+			TargetVersion: "0.12.0",
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "0.11.0", resp.TargetVersion)
+}
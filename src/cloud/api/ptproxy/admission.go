@@ -0,0 +1,299 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package ptproxy
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/time/rate"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	// ErrUserRateLimited occurs when a user submits queries faster than their rate limit allows.
+	ErrUserRateLimited = status.Error(codes.ResourceExhausted, "query rate limit exceeded, please slow down")
+	// ErrOrgQueueFull occurs when too many queries for the org are already waiting to run.
+	ErrOrgQueueFull = status.Error(codes.ResourceExhausted, "too many queries queued for org")
+	// ErrClusterQueueFull occurs when too many queries for the cluster are already waiting to run.
+	ErrClusterQueueFull = status.Error(codes.ResourceExhausted, "too many queries queued for cluster")
+	// ErrOrgConcurrencyLimit occurs when the query timed out waiting for one of the org's query slots.
+	ErrOrgConcurrencyLimit = status.Error(codes.ResourceExhausted, "timed out waiting for a query slot, too many concurrent queries for org")
+	// ErrClusterConcurrencyLimit occurs when the query timed out waiting for one of the cluster's query slots.
+	ErrClusterConcurrencyLimit = status.Error(codes.ResourceExhausted, "timed out waiting for a query slot, too many concurrent queries for cluster")
+
+	errQueueFull = errors.New("queue is full")
+)
+
+var (
+	activeQueries = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "ptproxy_active_queries",
+		Help: "Number of queries currently running through the passthrough proxy.",
+	}, []string{"scope", "id"})
+	queuedQueries = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "ptproxy_queued_queries",
+		Help: "Number of queries waiting for a slot in the passthrough proxy.",
+	}, []string{"scope", "id"})
+	rejectedQueries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "ptproxy_rejected_queries",
+		Help: "Number of queries rejected by passthrough proxy admission control.",
+	}, []string{"reason"})
+)
+
+func init() {
+	prometheus.MustRegister(activeQueries)
+	prometheus.MustRegister(queuedQueries)
+	prometheus.MustRegister(rejectedQueries)
+}
+
+// AdmissionConfig configures the admission control applied to queries before they are forwarded
+// to a vizier. Zero values disable the corresponding limit.
+type AdmissionConfig struct {
+	// MaxConcurrentPerOrg is the maximum number of queries that can run at once across an org.
+	MaxConcurrentPerOrg int
+	// MaxConcurrentPerCluster is the maximum number of queries that can run at once on a cluster.
+	MaxConcurrentPerCluster int
+	// MaxQueued is the maximum number of queries that can wait for a slot, per org or cluster.
+	MaxQueued int
+	// QueueTimeout is how long a query waits for a slot before it is rejected.
+	QueueTimeout time.Duration
+	// UserRate is the number of queries per second a single user may submit.
+	UserRate float64
+	// UserBurst is the number of queries a single user may submit at once.
+	UserBurst int
+}
+
+// concurrencyLimiter limits the number of concurrent holders per key. Requests that can't get a
+// slot right away are queued until one frees up.
+type concurrencyLimiter struct {
+	scope    string
+	max      int
+	maxQueue int
+
+	mu   sync.Mutex
+	sems map[string]*keySemaphore
+}
+
+type keySemaphore struct {
+	slots   chan struct{}
+	refs    int
+	waiting int
+}
+
+func newConcurrencyLimiter(scope string, max int, maxQueue int) *concurrencyLimiter {
+	return &concurrencyLimiter{
+		scope:    scope,
+		max:      max,
+		maxQueue: maxQueue,
+		sems:     make(map[string]*keySemaphore),
+	}
+}
+
+// acquire waits for a slot for the key. The returned function must be called to release the slot.
+func (l *concurrencyLimiter) acquire(ctx context.Context, key string) (func(), error) {
+	if l.max <= 0 {
+		return func() {}, nil
+	}
+
+	l.mu.Lock()
+	sem, ok := l.sems[key]
+	if !ok {
+		sem = &keySemaphore{slots: make(chan struct{}, l.max)}
+		l.sems[key] = sem
+	}
+	sem.refs++
+
+	select {
+	case sem.slots <- struct{}{}:
+		l.mu.Unlock()
+		activeQueries.WithLabelValues(l.scope, key).Inc()
+		return func() { l.release(key, sem) }, nil
+	default:
+	}
+
+	if l.maxQueue > 0 && sem.waiting >= l.maxQueue {
+		l.unrefLocked(key, sem)
+		l.mu.Unlock()
+		return nil, errQueueFull
+	}
+	sem.waiting++
+	l.mu.Unlock()
+	queuedQueries.WithLabelValues(l.scope, key).Inc()
+
+	var err error
+	select {
+	case sem.slots <- struct{}{}:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	queuedQueries.WithLabelValues(l.scope, key).Dec()
+	l.mu.Lock()
+	sem.waiting--
+	if err != nil {
+		l.unrefLocked(key, sem)
+		l.mu.Unlock()
+		return nil, err
+	}
+	l.mu.Unlock()
+	activeQueries.WithLabelValues(l.scope, key).Inc()
+	return func() { l.release(key, sem) }, nil
+}
+
+func (l *concurrencyLimiter) release(key string, sem *keySemaphore) {
+	<-sem.slots
+	activeQueries.WithLabelValues(l.scope, key).Dec()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.unrefLocked(key, sem)
+}
+
+// unrefLocked drops the reference to the semaphore, and cleans it up once nobody is using it.
+func (l *concurrencyLimiter) unrefLocked(key string, sem *keySemaphore) {
+	sem.refs--
+	if sem.refs > 0 {
+		return
+	}
+	delete(l.sems, key)
+	activeQueries.DeleteLabelValues(l.scope, key)
+	queuedQueries.DeleteLabelValues(l.scope, key)
+}
+
+// userRateLimiter keeps a token bucket per user.
+type userRateLimiter struct {
+	limit rate.Limit
+	burst int
+
+	mu             sync.Mutex
+	limiters       map[string]*userLimiter
+	lastCollection time.Time
+}
+
+type userLimiter struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// userLimiterTTL is how long an idle user's rate limiter is kept around.
+const userLimiterTTL = 10 * time.Minute
+
+func newUserRateLimiter(r float64, burst int) *userRateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &userRateLimiter{
+		limit:    rate.Limit(r),
+		burst:    burst,
+		limiters: make(map[string]*userLimiter),
+	}
+}
+
+func (l *userRateLimiter) allow(userID string) bool {
+	if l.limit <= 0 {
+		return true
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(l.lastCollection) > userLimiterTTL {
+		l.lastCollection = now
+		for id, ul := range l.limiters {
+			if now.Sub(ul.lastSeen) > userLimiterTTL {
+				delete(l.limiters, id)
+			}
+		}
+	}
+
+	ul, ok := l.limiters[userID]
+	if !ok {
+		ul = &userLimiter{limiter: rate.NewLimiter(l.limit, l.burst)}
+		l.limiters[userID] = ul
+	}
+	ul.lastSeen = now
+	return ul.limiter.AllowN(now, 1)
+}
+
+// admissionController decides whether a query may be forwarded to a vizier.
+type admissionController struct {
+	queueTimeout time.Duration
+	orgs         *concurrencyLimiter
+	clusters     *concurrencyLimiter
+	users        *userRateLimiter
+}
+
+func newAdmissionController(cfg *AdmissionConfig) *admissionController {
+	if cfg == nil {
+		cfg = &AdmissionConfig{}
+	}
+	return &admissionController{
+		queueTimeout: cfg.QueueTimeout,
+		orgs:         newConcurrencyLimiter("org", cfg.MaxConcurrentPerOrg, cfg.MaxQueued),
+		clusters:     newConcurrencyLimiter("cluster", cfg.MaxConcurrentPerCluster, cfg.MaxQueued),
+		users:        newUserRateLimiter(cfg.UserRate, cfg.UserBurst),
+	}
+}
+
+// admit blocks until the query is allowed to run, or returns a ResourceExhausted error if it is
+// rejected. On success, the returned function must be called once the query completes.
+func (a *admissionController) admit(ctx context.Context, orgID, userID, clusterID string) (func(), error) {
+	if userID != "" && !a.users.allow(userID) {
+		rejectedQueries.WithLabelValues("user_rate_limit").Inc()
+		return nil, ErrUserRateLimited
+	}
+
+	waitCtx := ctx
+	if a.queueTimeout > 0 {
+		var cancel context.CancelFunc
+		waitCtx, cancel = context.WithTimeout(ctx, a.queueTimeout)
+		defer cancel()
+	}
+
+	releaseOrg, err := a.orgs.acquire(waitCtx, orgID)
+	if err != nil {
+		return nil, a.admissionError(ctx, err, "org", ErrOrgQueueFull, ErrOrgConcurrencyLimit)
+	}
+	releaseCluster, err := a.clusters.acquire(waitCtx, clusterID)
+	if err != nil {
+		releaseOrg()
+		return nil, a.admissionError(ctx, err, "cluster", ErrClusterQueueFull, ErrClusterConcurrencyLimit)
+	}
+
+	return func() {
+		releaseCluster()
+		releaseOrg()
+	}, nil
+}
+
+func (a *admissionController) admissionError(ctx context.Context, err error, scope string, queueFullErr error, timeoutErr error) error {
+	if errors.Is(err, errQueueFull) {
+		rejectedQueries.WithLabelValues(scope + "_queue_full").Inc()
+		return queueFullErr
+	}
+	// If the caller went away, there's no need to report this as a rejection.
+	if ctx.Err() != nil {
+		return status.FromContextError(ctx.Err()).Err()
+	}
+	rejectedQueries.WithLabelValues(scope + "_queue_timeout").Inc()
+	return timeoutErr
+}
@@ -0,0 +1,158 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package ptproxy
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestAdmissionController_Disabled(t *testing.T) {
+	a := newAdmissionController(nil)
+	for i := 0; i < 100; i++ {
+		release, err := a.admit(context.Background(), "org", "user", "cluster")
+		require.NoError(t, err)
+		defer release()
+	}
+}
+
+func TestAdmissionController_UserRateLimit(t *testing.T) {
+	a := newAdmissionController(&AdmissionConfig{UserRate: 1, UserBurst: 2})
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		release, err := a.admit(ctx, "org", "user1", "cluster")
+		require.NoError(t, err)
+		release()
+	}
+	_, err := a.admit(ctx, "org", "user1", "cluster")
+	assert.Equal(t, ErrUserRateLimited, err)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	// Other users have their own limit.
+	release, err := a.admit(ctx, "org", "user2", "cluster")
+	require.NoError(t, err)
+	release()
+}
+
+func TestAdmissionController_OrgConcurrencyQueues(t *testing.T) {
+	a := newAdmissionController(&AdmissionConfig{
+		MaxConcurrentPerOrg: 1,
+		QueueTimeout:        5 * time.Second,
+	})
+	ctx := context.Background()
+
+	release1, err := a.admit(ctx, "org1", "user", "cluster1")
+	require.NoError(t, err)
+
+	// A different org is not affected.
+	release2, err := a.admit(ctx, "org2", "user", "cluster2")
+	require.NoError(t, err)
+	release2()
+
+	admitted := make(chan error)
+	go func() {
+		release, err := a.admit(ctx, "org1", "user", "cluster3")
+		if err == nil {
+			release()
+		}
+		admitted <- err
+	}()
+
+	select {
+	case <-admitted:
+		t.Fatal("query should be queued while the org is at its limit")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	release1()
+	select {
+	case err := <-admitted:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("queued query was not admitted")
+	}
+}
+
+func TestAdmissionController_QueueTimeout(t *testing.T) {
+	a := newAdmissionController(&AdmissionConfig{
+		MaxConcurrentPerCluster: 1,
+		QueueTimeout:            10 * time.Millisecond,
+	})
+	ctx := context.Background()
+
+	release, err := a.admit(ctx, "org", "user", "cluster")
+	require.NoError(t, err)
+	defer release()
+
+	_, err = a.admit(ctx, "org", "user", "cluster")
+	assert.Equal(t, ErrClusterConcurrencyLimit, err)
+}
+
+func TestAdmissionController_QueueFull(t *testing.T) {
+	a := newAdmissionController(&AdmissionConfig{
+		MaxConcurrentPerOrg: 1,
+		MaxQueued:           1,
+		QueueTimeout:        5 * time.Second,
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	release, err := a.admit(ctx, "org", "user", "cluster")
+	require.NoError(t, err)
+	defer release()
+
+	queued := make(chan error)
+	go func() {
+		_, err := a.admit(ctx, "org", "user", "cluster")
+		queued <- err
+	}()
+
+	require.Eventually(t, func() bool {
+		a.orgs.mu.Lock()
+		defer a.orgs.mu.Unlock()
+		return a.orgs.sems["org"].waiting == 1
+	}, 5*time.Second, time.Millisecond)
+
+	_, err = a.admit(ctx, "org", "user", "cluster")
+	assert.Equal(t, ErrOrgQueueFull, err)
+
+	// Cancelling the caller's context returns a cancellation rather than a rejection.
+	cancel()
+	assert.Equal(t, codes.Canceled, status.Code(<-queued))
+}
+
+func TestConcurrencyLimiter_CleansUpKeys(t *testing.T) {
+	l := newConcurrencyLimiter("test", 2, 0)
+	release1, err := l.acquire(context.Background(), "key")
+	require.NoError(t, err)
+	release2, err := l.acquire(context.Background(), "key")
+	require.NoError(t, err)
+
+	release1()
+	assert.Len(t, l.sems, 1)
+	release2()
+	assert.Len(t, l.sems, 0)
+}
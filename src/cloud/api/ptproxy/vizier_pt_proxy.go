@@ -39,13 +39,15 @@ type vzmgrClient interface {
 // VizierPassThroughProxy implements the VizierAPI and allows proxying the data to the actual
 // vizier cluster.
 type VizierPassThroughProxy struct {
-	nc *nats.Conn
-	vc vzmgrClient
+	nc        *nats.Conn
+	vc        vzmgrClient
+	admission *admissionController
 }
 
-// NewVizierPassThroughProxy creates a new passthrough proxy.
-func NewVizierPassThroughProxy(nc *nats.Conn, vc vzmgrClient) *VizierPassThroughProxy {
-	return &VizierPassThroughProxy{nc: nc, vc: vc}
+// NewVizierPassThroughProxy creates a new passthrough proxy. A nil admission config disables query
+// admission control.
+func NewVizierPassThroughProxy(nc *nats.Conn, vc vzmgrClient, admission *AdmissionConfig) *VizierPassThroughProxy {
+	return &VizierPassThroughProxy{nc: nc, vc: vc, admission: newAdmissionController(admission)}
 }
 
 // ExecuteScript is the GRPC stream method.
@@ -55,6 +57,13 @@ func (v *VizierPassThroughProxy) ExecuteScript(req *vizierpb.ExecuteScriptReques
 		return err
 	}
 	defer rp.Finish()
+
+	release, err := v.admitQuery(srv.Context(), rp.clusterID.String())
+	if err != nil {
+		return err
+	}
+	defer release()
+
 	vizReq := rp.prepareVizierRequest()
 	vizReq.Msg = &cvmsgspb.C2VAPIStreamRequest_ExecReq{ExecReq: req}
 	if err := rp.sendMessageToVizier(vizReq); err != nil {
@@ -147,6 +156,20 @@ func (v *VizierPassThroughProxy) DebugPods(req *vizierpb.DebugPodsRequest, srv v
 	return rp.Run()
 }
 
+// admitQuery waits until the query is allowed to run on the cluster under the admission limits.
+func (v *VizierPassThroughProxy) admitQuery(ctx context.Context, clusterID string) (func(), error) {
+	_, claims, err := getCredsFromCtx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	orgID := claims.GetUserClaims().GetOrgID()
+	userID := claims.GetUserClaims().GetUserID()
+	if userID == "" {
+		userID = claims.GetSubject()
+	}
+	return v.admission.admit(ctx, orgID, userID, clusterID)
+}
+
 func getCredsFromCtx(ctx context.Context) (string, *jwtpb.JWTClaims, error) {
 	aCtx, err := authcontext.FromContext(ctx)
 	if err != nil {
@@ -52,9 +52,29 @@ func init() {
 	pflag.String("artifact_manifest_url", "", "The url to the artifact manifest")
 	pflag.String("artifact_manifest_sha_url", "", "The url to the sha of the artifact manifest, "+
 		"if not set the server will use the manifest url with '.sha256' appended.")
+	pflag.String("artifact_manifest_sig_url", "", "The url to the detached signature of the artifact manifest, "+
+		"if not set the server will use the manifest url with '.sig' appended.")
+	pflag.String("artifact_manifest_public_keys_path", "", "The path to a PEM file of ed25519 public keys trusted to sign the "+
+		"artifact manifest. If set, manifests without a valid signature are rejected.")
 	pflag.Duration("manifest_poll_period", 1*time.Minute, "Specify how often to poll for manifest changes")
 }
 
+func loadManifestVerifier() *manifest.Verifier {
+	keysPath := viper.GetString("artifact_manifest_public_keys_path")
+	if keysPath == "" {
+		return nil
+	}
+	b, err := os.ReadFile(keysPath)
+	if err != nil {
+		log.WithError(err).Fatal("Failed to read artifact manifest public keys.")
+	}
+	keys, err := manifest.ParsePublicKeys(b)
+	if err != nil {
+		log.WithError(err).Fatal("Failed to parse artifact manifest public keys.")
+	}
+	return manifest.NewVerifier(keys...)
+}
+
 func loadServiceAccountConfig() *jwt.Config {
 	saKeyFile := viper.GetString("sa_key_path")
 	saKey, err := os.ReadFile(saKeyFile)
@@ -99,7 +119,8 @@ func main() {
 	env := artifacttrackerenv.New()
 
 	bucket := viper.GetString("artifact_bucket")
-	svr := controllers.NewServer(stiface.AdaptClient(client), bucket, saCfg)
+	verifier := loadManifestVerifier()
+	svr := controllers.NewServer(stiface.AdaptClient(client), bucket, saCfg, verifier)
 
 	// If any versions are not hardcoded, then we need to poll for the artifact manifest.
 	if (viper.GetString("vizier_version") == "") || (viper.GetString("cli_version") == "") || (viper.GetString("operator_version") == "") {
@@ -109,7 +130,16 @@ func main() {
 			shaURL = manifestURL + ".sha256"
 		}
 		pollPeriod := viper.GetDuration("manifest_poll_period")
-		httpManifest := manifest.NewHTTPLocation(shaURL, manifestURL)
+		var httpManifest manifest.Location
+		if verifier != nil {
+			sigURL := viper.GetString("artifact_manifest_sig_url")
+			if sigURL == "" {
+				sigURL = manifestURL + manifest.SignatureSuffix
+			}
+			httpManifest = manifest.NewSignedHTTPLocation(shaURL, manifestURL, sigURL)
+		} else {
+			httpManifest = manifest.NewHTTPLocation(shaURL, manifestURL)
+		}
 		poller := manifest.NewPoller(httpManifest, pollPeriod, svr.UpdateManifest)
 		start := time.Now()
 		if err := poller.Start(); err != nil {
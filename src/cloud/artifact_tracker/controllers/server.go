@@ -55,11 +55,14 @@ type Server struct {
 	artifactBucket string
 	gcsSA          *jwt.Config
 	m              *manifest.ArtifactManifest
+	// verifier checks manifest signatures. If nil, manifests are accepted without verification.
+	verifier *manifest.Verifier
 }
 
-// NewServer creates a new artifact tracker server.
-func NewServer(client stiface.Client, bucket string, gcsSA *jwt.Config) *Server {
-	return &Server{sc: client, artifactBucket: bucket, gcsSA: gcsSA}
+// NewServer creates a new artifact tracker server. If verifier is not nil, any manifest that does
+// not carry a valid signature is rejected.
+func NewServer(client stiface.Client, bucket string, gcsSA *jwt.Config, verifier *manifest.Verifier) *Server {
+	return &Server{sc: client, artifactBucket: bucket, gcsSA: gcsSA, verifier: verifier}
 }
 
 func (s *Server) getArtifactListSpecifiedVizier() (*vpb.ArtifactSet, error) {
@@ -231,26 +234,53 @@ func (s *Server) GetDownloadLink(ctx context.Context, in *apb.GetDownloadLinkReq
 
 	tpb, _ := types.TimestampProto(expires)
 
-	sha256ObjectPath := objectPath + ".sha256"
-	r, err := s.sc.Bucket(bucket).Object(sha256ObjectPath).NewReader(ctx)
+	// The .sha256 file next to the artifact isn't signed, so the digest listed in the manifest is preferred. Once
+	// manifests are verified, artifacts without a digest in the manifest can't be downloaded.
+	sha256, ok := s.manifestSHA256(name, versionStr, at)
+	if !ok {
+		if s.verifier != nil {
+			return nil, status.Error(codes.NotFound, "artifact digest not found in manifest")
+		}
 
-	if err != nil {
-		return nil, status.Error(codes.Internal, "failed to fetch sha256 file")
-	}
-	defer r.Close()
+		sha256ObjectPath := objectPath + ".sha256"
+		r, err := s.sc.Bucket(bucket).Object(sha256ObjectPath).NewReader(ctx)
 
-	sha256bytes, err := io.ReadAll(r)
-	if err != nil {
-		return nil, status.Error(codes.Internal, "failed to read sha256 file")
+		if err != nil {
+			return nil, status.Error(codes.Internal, "failed to fetch sha256 file")
+		}
+		defer r.Close()
+
+		sha256bytes, err := io.ReadAll(r)
+		if err != nil {
+			return nil, status.Error(codes.Internal, "failed to read sha256 file")
+		}
+		sha256 = string(sha256bytes)
 	}
 
 	return &apb.GetDownloadLinkResponse{
 		Url:        url,
-		SHA256:     strings.TrimSpace(string(sha256bytes)),
+		SHA256:     strings.TrimSpace(sha256),
 		ValidUntil: tpb,
 	}, nil
 }
 
+// manifestSHA256 returns the digest listed in the manifest for the given artifact, if there is one.
+func (s *Server) manifestSHA256(name string, versionStr string, at vpb.ArtifactType) (string, bool) {
+	if s.m == nil {
+		return "", false
+	}
+	a, err := s.m.GetArtifact(name, versionStr)
+	if err != nil {
+		return "", false
+	}
+	for _, am := range a.AvailableArtifactMirrors {
+		if am.ArtifactType == at && am.SHA256 != "" {
+			return am.SHA256, true
+		}
+	}
+	return "", false
+}
+
 func (s *Server) getDownloadLinkForMirrors(ctx context.Context, am *vpb.ArtifactMirrors) (*apb.GetDownloadLinkResponse, error) {
 	// For now we return a download link to the first mirror.
 	// In the future, the API will change to support returning multiple mirrors.
@@ -267,8 +297,15 @@ func (s *Server) getDownloadLinkForMirrors(ctx context.Context, am *vpb.Artifact
 	}, nil
 }
 
-// UpdateManifest switches the server's manifest to use the one given.
+// UpdateManifest switches the server's manifest to use the one given. If the server was configured
+// with a verifier, manifests without a valid signature are rejected and the current manifest is kept.
 func (s *Server) UpdateManifest(m *manifest.ArtifactManifest) error {
+	if s.verifier != nil {
+		if err := s.verifier.Verify(m); err != nil {
+			log.WithError(err).Error("Rejecting artifact manifest that failed signature verification")
+			return err
+		}
+	}
 	s.m = m
 	log.Info("Updated Artifact Manifest")
 	return nil
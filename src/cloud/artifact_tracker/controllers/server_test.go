@@ -20,6 +20,8 @@ package controllers_test
 
 import (
 	"context"
+	"crypto/ed25519"
+	"crypto/rand"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -173,7 +175,7 @@ func loadTestManifest(s *controllers.Server, ts *httptest.Server) error {
 }
 
 func TestServer_GetArtifactList(t *testing.T) {
-	server := controllers.NewServer(nil, "bucket", nil)
+	server := controllers.NewServer(nil, "bucket", nil, nil)
 
 	ts := startTestHTTPServer(t)
 	defer ts.Close()
@@ -307,7 +309,7 @@ func TestServer_GetDownloadLink(t *testing.T) {
 	server := controllers.NewServer(storageClient, "test-bucket", &jwt.Config{
 		Email:      "test@test.com",
 		PrivateKey: []byte("the-key"),
-	})
+	}, nil)
 
 	ts := startTestHTTPServer(t)
 	defer ts.Close()
@@ -399,3 +401,83 @@ func TestServer_GetDownloadLink(t *testing.T) {
 		})
 	}
 }
+
+func TestServer_UpdateManifestVerifiesSignature(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	contents := `[{"name": "cli", "artifact": [{"version_str": "1.2.3", "available_artifacts": ["AT_LINUX_AMD64"]}]}]`
+	tampered := `[{"name": "cli", "artifact": [{"version_str": "6.6.6", "available_artifacts": ["AT_LINUX_AMD64"]}]}]`
+
+	server := controllers.NewServer(nil, "bucket", nil, manifest.NewVerifier(pub))
+
+	m, err := manifest.ReadArtifactManifest(strings.NewReader(contents))
+	require.NoError(t, err)
+	require.Error(t, server.UpdateManifest(m), "unsigned manifest should be rejected")
+
+	m.SetSignature(manifest.Sign(priv, []byte(contents)))
+	require.NoError(t, server.UpdateManifest(m))
+
+	bad, err := manifest.ReadArtifactManifest(strings.NewReader(tampered))
+	require.NoError(t, err)
+	bad.SetSignature(manifest.Sign(priv, []byte(contents)))
+	require.ErrorIs(t, server.UpdateManifest(bad), manifest.ErrInvalidSignature)
+
+	// The previously verified manifest should still be served.
+	resp, err := server.GetArtifactList(context.Background(), &apb.GetArtifactListRequest{
+		ArtifactName: "cli",
+		ArtifactType: vpb.AT_LINUX_AMD64,
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.Artifact, 1)
+	assert.Equal(t, "1.2.3", resp.Artifact[0].VersionStr)
+}
+
+func TestServer_GetDownloadLinkUsesSignedDigest(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	storageClient := testingutils.NewMockGCSClient(map[string]*testingutils.MockGCSBucket{
+		"test-bucket": testingutils.NewMockGCSBucket(
+			map[string]*testingutils.MockGCSObject{
+				"cli/1.2.3/cli_linux_amd64.sha256": testingutils.NewMockGCSObject([]byte("unsigned-sha256"), nil),
+				"cli/1.2.3/cli_linux_amd64": testingutils.NewMockGCSObject([]byte("mybin"), &storage.ObjectAttrs{
+					MediaLink: "the-url",
+				}),
+				"cli/1.2.4/cli_linux_amd64.sha256": testingutils.NewMockGCSObject([]byte("unsigned-sha256"), nil),
+				"cli/1.2.4/cli_linux_amd64": testingutils.NewMockGCSObject([]byte("mybin"), &storage.ObjectAttrs{
+					MediaLink: "the-url",
+				}),
+			},
+			nil,
+		),
+	})
+	server := controllers.NewServer(storageClient, "test-bucket", nil, manifest.NewVerifier(pub))
+
+	contents := `[{"name": "cli", "artifact": [
+		{"version_str": "1.2.3", "available_artifacts": ["AT_LINUX_AMD64"],
+		 "available_artifact_mirrors": [{"artifact_type": "AT_LINUX_AMD64", "sha256": "signed-sha256"}]},
+		{"version_str": "1.2.4", "available_artifacts": ["AT_LINUX_AMD64"]}
+	]}]`
+	m, err := manifest.ReadArtifactManifest(strings.NewReader(contents))
+	require.NoError(t, err)
+	m.SetSignature(manifest.Sign(priv, []byte(contents)))
+	require.NoError(t, server.UpdateManifest(m))
+
+	resp, err := server.GetDownloadLink(context.Background(), &apb.GetDownloadLinkRequest{
+		ArtifactName: "cli",
+		VersionStr:   "1.2.3",
+		ArtifactType: vpb.AT_LINUX_AMD64,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "the-url", resp.Url)
+	assert.Equal(t, "signed-sha256", resp.SHA256)
+
+	// The unsigned .sha256 file isn't trusted when the manifest doesn't list a digest.
+	_, err = server.GetDownloadLink(context.Background(), &apb.GetDownloadLinkRequest{
+		ArtifactName: "cli",
+		VersionStr:   "1.2.4",
+		ArtifactType: vpb.AT_LINUX_AMD64,
+	})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
@@ -0,0 +1,151 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package autocomplete
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gofrs/uuid"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"google.golang.org/grpc/metadata"
+
+	"px.dev/pixie/src/api/proto/cloudpb"
+	"px.dev/pixie/src/api/proto/vispb"
+	"px.dev/pixie/src/cloud/scriptmgr/scriptmgrpb"
+	srvutils "px.dev/pixie/src/shared/services/utils"
+	"px.dev/pixie/src/utils"
+)
+
+const (
+	// orgScriptPrefix is the prefix of the names of scripts in an org's script library.
+	orgScriptPrefix = "org/"
+	// orgScriptTimeout is how long to wait for an org's script library before suggesting only the
+	// public scripts.
+	orgScriptTimeout = 2 * time.Second
+)
+
+// scriptCandidate is a script that can be suggested, along with the arguments it takes.
+type scriptCandidate struct {
+	orgID    string
+	desc     string
+	argKinds []cloudpb.AutocompleteEntityKind
+	argNames []string
+}
+
+// scriptCandidates is the set of scripts that can be suggested for a request.
+type scriptCandidates struct {
+	names  []string
+	byName map[string]*scriptCandidate
+}
+
+func newScriptCandidates() *scriptCandidates {
+	return &scriptCandidates{
+		byName: make(map[string]*scriptCandidate),
+	}
+}
+
+func (c *scriptCandidates) add(name, orgID, desc string, vis *vispb.Vis) {
+	script := &scriptCandidate{
+		orgID:    orgID,
+		desc:     desc,
+		argKinds: make([]cloudpb.AutocompleteEntityKind, 0),
+	}
+	if vis != nil {
+		for _, a := range vis.Variables {
+			aKind := cloudpb.AEK_UNKNOWN
+			if a.Type == vispb.PX_POD {
+				aKind = cloudpb.AEK_POD
+			} else if a.Type == vispb.PX_SERVICE {
+				aKind = cloudpb.AEK_SVC
+			}
+
+			if aKind != cloudpb.AEK_UNKNOWN {
+				script.argKinds = append(script.argKinds, aKind)
+				script.argNames = append(script.argNames, a.Name)
+			}
+		}
+	}
+	if _, ok := c.byName[name]; !ok {
+		c.names = append(c.names, name)
+	}
+	c.byName[name] = script
+}
+
+// merge returns the union of both sets of scripts. Scripts in other take precedence.
+func (c *scriptCandidates) merge(other *scriptCandidates) *scriptCandidates {
+	if other == nil || len(other.names) == 0 {
+		return c
+	}
+	merged := newScriptCandidates()
+	for _, set := range []*scriptCandidates{c, other} {
+		for _, name := range set.names {
+			if _, ok := merged.byName[name]; !ok {
+				merged.names = append(merged.names, name)
+			}
+			merged.byName[name] = set.byName[name]
+		}
+	}
+	return merged
+}
+
+// SetScriptMgrClient sets the client used to fetch the scripts in each org's script library, so that
+// they can be suggested along with the scripts in the bundle.
+func (e *ElasticSuggester) SetScriptMgrClient(sm scriptmgrpb.ScriptMgrServiceClient) {
+	e.sm = sm
+}
+
+// getOrgScripts returns the scripts in the org's script library. Failing to fetch them shouldn't
+// break autocomplete, so errors are logged and no scripts are returned.
+func (e *ElasticSuggester) getOrgScripts(orgID uuid.UUID) *scriptCandidates {
+	if e.sm == nil || orgID == uuid.Nil {
+		return nil
+	}
+
+	serviceAuthToken, err := getServiceCredentials(viper.GetString("jwt_signing_key"))
+	if err != nil {
+		log.WithError(err).Error("Failed to get service credentials")
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), orgScriptTimeout)
+	defer cancel()
+	ctx = metadata.AppendToOutgoingContext(ctx, "authorization",
+		fmt.Sprintf("bearer %s", serviceAuthToken))
+
+	resp, err := e.sm.GetOrgScripts(ctx, &scriptmgrpb.GetOrgScriptsReq{
+		OrgID: utils.ProtoFromUUID(orgID),
+	})
+	if err != nil {
+		log.WithError(err).WithField("orgID", orgID).Error("Failed to fetch org scripts for autocomplete")
+		return nil
+	}
+
+	scripts := newScriptCandidates()
+	for _, s := range resp.Scripts {
+		scripts.add(orgScriptPrefix+s.Name, orgID.String(), s.Desc, s.Vis)
+	}
+	return scripts
+}
+
+func getServiceCredentials(signingKey string) (string, error) {
+	claims := srvutils.GenerateJWTForService("Autocomplete", viper.GetString("domain_name"))
+	return srvutils.SignJWTClaims(claims, signingKey)
+}
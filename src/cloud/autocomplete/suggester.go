@@ -28,9 +28,9 @@ import (
 	"github.com/sahilm/fuzzy"
 
 	"px.dev/pixie/src/api/proto/cloudpb"
-	"px.dev/pixie/src/api/proto/vispb"
 	"px.dev/pixie/src/cloud/indexer/md"
 	"px.dev/pixie/src/cloud/profile/profilepb"
+	"px.dev/pixie/src/cloud/scriptmgr/scriptmgrpb"
 	"px.dev/pixie/src/utils/script"
 )
 
@@ -42,6 +42,8 @@ type ElasticSuggester struct {
 	pc              profilepb.ProfileServiceClient
 	// This is temporary, and will be removed once we start indexing scripts.
 	br *script.BundleManager
+	// sm is used to fetch the scripts in each org's script library.
+	sm scriptmgrpb.ScriptMgrServiceClient
 }
 
 const (
@@ -164,86 +166,73 @@ func (e *ElasticSuggester) GetSuggestions(reqs []*SuggestionRequest) ([]*Suggest
 	}
 
 	// Parse scripts to prepare for matching. This is temporary until we have script indexing.
-	scripts := []string{}
-	scriptArgMap := make(map[string][]cloudpb.AutocompleteEntityKind)
-	scriptArgNames := make(map[string][]string)
+	bundleScripts := newScriptCandidates()
 	if br != nil {
 		for _, s := range br.GetScripts() {
-			scripts = append(scripts, s.ScriptName)
-			scriptArgMap[s.ScriptName] = make([]cloudpb.AutocompleteEntityKind, 0)
-			for _, a := range s.Vis.Variables {
-				aKind := cloudpb.AEK_UNKNOWN
-				if a.Type == vispb.PX_POD {
-					aKind = cloudpb.AEK_POD
-				} else if a.Type == vispb.PX_SERVICE {
-					aKind = cloudpb.AEK_SVC
-				}
-
-				if aKind != cloudpb.AEK_UNKNOWN {
-					scriptArgMap[s.ScriptName] = append(scriptArgMap[s.ScriptName], aKind)
-					scriptArgNames[s.ScriptName] = append(scriptArgNames[s.ScriptName], a.Name)
-				}
-			}
+			bundleScripts.add(s.ScriptName, s.OrgID, s.LongDoc, s.Vis)
 		}
 	}
+	// Scripts in each org's script library, fetched at most once per org for this batch of requests.
+	orgScripts := make(map[uuid.UUID]*scriptCandidates)
 
 	for i, r := range resp.Responses {
 		// This is temporary until we index scripts in Elastic.
 		scriptResults := make([]*Suggestion, 0)
-		if br != nil {
-			for _, t := range reqs[i].AllowedKinds {
-				if t == cloudpb.AEK_SCRIPT { // Script is an allowed type for this tabstop, so we should find matching scripts.
-					matches := fuzzy.Find(reqs[i].Input, scripts)
-
-					if reqs[i].Input == "" { // The input is empty, so none of the scripts will match using the fuzzy search.
-						matches = make([]fuzzy.Match, len(scripts))
-						for i, s := range scripts {
-							matches[i] = fuzzy.Match{
-								Str:            s,
-								MatchedIndexes: make([]int, 0),
-							}
-						}
-					}
-					for _, m := range matches {
-						script := br.MustGetScript(m.Str)
-						scriptArgs := scriptArgMap[m.Str]
-						scriptNames := scriptArgNames[m.Str]
-						valid := true
-						if script.OrgID != reqs[i].OrgID.String() {
-							valid = false
-						}
+		for _, t := range reqs[i].AllowedKinds {
+			if t != cloudpb.AEK_SCRIPT {
+				continue
+			}
+			// Script is an allowed type for this tabstop, so we should find matching scripts.
+			orgID := reqs[i].OrgID
+			if _, ok := orgScripts[orgID]; !ok {
+				orgScripts[orgID] = e.getOrgScripts(orgID)
+			}
+			scripts := bundleScripts.merge(orgScripts[orgID])
 
-						for _, r := range reqs[i].AllowedArgs { // Check that the script takes the allowed args.
-							found := false
-							for _, arg := range scriptArgs {
-								if arg == r {
-									found = true
-									break
-								}
-							}
-							if !found {
-								valid = false
-								break
-							}
-						}
-						if valid {
-							matchedIdxs := make([]int64, len(m.MatchedIndexes))
-							for i, matched := range m.MatchedIndexes {
-								matchedIdxs[i] = int64(matched)
-							}
-							scriptResults = append(scriptResults, &Suggestion{
-								Name:           m.Str,
-								Kind:           cloudpb.AEK_SCRIPT,
-								Desc:           script.LongDoc,
-								ArgNames:       scriptNames,
-								ArgKinds:       scriptArgs,
-								MatchedIndexes: matchedIdxs,
-							})
+			matches := fuzzy.Find(reqs[i].Input, scripts.names)
+
+			if reqs[i].Input == "" { // The input is empty, so none of the scripts will match using the fuzzy search.
+				matches = make([]fuzzy.Match, len(scripts.names))
+				for i, s := range scripts.names {
+					matches[i] = fuzzy.Match{
+						Str:            s,
+						MatchedIndexes: make([]int, 0),
+					}
+				}
+			}
+			for _, m := range matches {
+				script := scripts.byName[m.Str]
+				valid := script.orgID == orgID.String()
+
+				for _, r := range reqs[i].AllowedArgs { // Check that the script takes the allowed args.
+					found := false
+					for _, arg := range script.argKinds {
+						if arg == r {
+							found = true
+							break
 						}
 					}
-					break
+					if !found {
+						valid = false
+						break
+					}
+				}
+				if valid {
+					matchedIdxs := make([]int64, len(m.MatchedIndexes))
+					for i, matched := range m.MatchedIndexes {
+						matchedIdxs[i] = int64(matched)
+					}
+					scriptResults = append(scriptResults, &Suggestion{
+						Name:           m.Str,
+						Kind:           cloudpb.AEK_SCRIPT,
+						Desc:           script.desc,
+						ArgNames:       script.argNames,
+						ArgKinds:       script.argKinds,
+						MatchedIndexes: matchedIdxs,
+					})
 				}
 			}
+			break
 		}
 		exactMatch := len(scriptResults) > 0 && scriptResults[0].Name == reqs[i].Input
 
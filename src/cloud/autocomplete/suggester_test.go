@@ -25,13 +25,19 @@ import (
 	"testing"
 
 	"github.com/gofrs/uuid"
+	"github.com/golang/mock/gomock"
 	"github.com/olivere/elastic/v7"
+	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"px.dev/pixie/src/api/proto/cloudpb"
+	"px.dev/pixie/src/api/proto/vispb"
 	"px.dev/pixie/src/cloud/autocomplete"
 	"px.dev/pixie/src/cloud/indexer/md"
+	"px.dev/pixie/src/cloud/scriptmgr/scriptmgrpb"
+	mock_scriptmgr "px.dev/pixie/src/cloud/scriptmgr/scriptmgrpb/mock"
+	"px.dev/pixie/src/utils"
 	"px.dev/pixie/src/utils/testingutils/docker"
 )
 
@@ -601,3 +607,86 @@ func TestGetSuggestions(t *testing.T) {
 		})
 	}
 }
+
+func TestGetSuggestions_OrgScripts(t *testing.T) {
+	viper.Set("jwt_signing_key", "key0")
+	org2 := uuid.Must(uuid.NewV4())
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	sm := mock_scriptmgr.NewMockScriptMgrServiceClient(ctrl)
+	sm.EXPECT().
+		GetOrgScripts(gomock.Any(), &scriptmgrpb.GetOrgScriptsReq{OrgID: utils.ProtoFromUUID(org1)}).
+		Return(&scriptmgrpb.GetOrgScriptsResp{
+			Scripts: []*scriptmgrpb.OrgScript{
+				{
+					Name: "team/svc_errors",
+					Desc: "Errors for a service",
+					Vis: &vispb.Vis{
+						Variables: []*vispb.Vis_Variable{
+							{Name: "svc", Type: vispb.PX_SERVICE},
+						},
+					},
+				},
+				{
+					Name: "team/latency",
+					Desc: "Latency across the cluster",
+				},
+			},
+		}, nil).
+		Times(1)
+	sm.EXPECT().
+		GetOrgScripts(gomock.Any(), &scriptmgrpb.GetOrgScriptsReq{OrgID: utils.ProtoFromUUID(org2)}).
+		Return(&scriptmgrpb.GetOrgScriptsResp{}, nil).
+		Times(1)
+
+	es, _ := autocomplete.NewElasticSuggester(elasticClient, indexName, "scripts", nil)
+	es.SetScriptMgrClient(sm)
+
+	results, err := es.GetSuggestions([]*autocomplete.SuggestionRequest{
+		{
+			Input:        "org/team/svc_errors",
+			OrgID:        org1,
+			AllowedKinds: []cloudpb.AutocompleteEntityKind{cloudpb.AEK_SCRIPT},
+			AllowedArgs:  []cloudpb.AutocompleteEntityKind{},
+		},
+		{
+			Input:        "team",
+			OrgID:        org1,
+			AllowedKinds: []cloudpb.AutocompleteEntityKind{cloudpb.AEK_SCRIPT},
+			AllowedArgs:  []cloudpb.AutocompleteEntityKind{cloudpb.AEK_SVC},
+		},
+		{
+			Input:        "team",
+			OrgID:        org2,
+			AllowedKinds: []cloudpb.AutocompleteEntityKind{cloudpb.AEK_SCRIPT},
+			AllowedArgs:  []cloudpb.AutocompleteEntityKind{},
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, 3, len(results))
+
+	expectedSvcErrors := &autocomplete.Suggestion{
+		Name:     "org/team/svc_errors",
+		Desc:     "Errors for a service",
+		Kind:     cloudpb.AEK_SCRIPT,
+		ArgNames: []string{"svc"},
+		ArgKinds: []cloudpb.AutocompleteEntityKind{cloudpb.AEK_SVC},
+	}
+	for _, r := range results {
+		for _, sugg := range r.Suggestions {
+			sugg.MatchedIndexes = nil
+		}
+	}
+
+	assert.True(t, results[0].ExactMatch)
+	require.Equal(t, 1, len(results[0].Suggestions))
+	assert.Equal(t, expectedSvcErrors, results[0].Suggestions[0])
+
+	// Only scripts that take a service are suggested.
+	require.Equal(t, 1, len(results[1].Suggestions))
+	assert.Equal(t, expectedSvcErrors, results[1].Suggestions[0])
+
+	// Scripts from another org's library are never suggested.
+	assert.Equal(t, 0, len(results[2].Suggestions))
+}
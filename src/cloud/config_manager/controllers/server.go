@@ -19,7 +19,10 @@
 package controllers
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"math"
@@ -75,6 +78,21 @@ func downloadFile(url string) (io.ReadCloser, error) {
 	return resp.Body, nil
 }
 
+// verifySHA256 checks the downloaded contents against the digest returned by the artifact tracker.
+// Artifacts without a digest are accepted for backwards compatibility with older manifests.
+func verifySHA256(contents []byte, expected string) error {
+	expected = strings.TrimSpace(expected)
+	if expected == "" {
+		log.Warn("Artifact has no sha256 digest, skipping verification")
+		return nil
+	}
+	sum := sha256.Sum256(contents)
+	if hex.EncodeToString(sum[:]) != strings.ToLower(expected) {
+		return fmt.Errorf("artifact sha256 mismatch: expected %s, got %s", expected, hex.EncodeToString(sum[:]))
+	}
+	return nil
+}
+
 func getServiceCredentials(signingKey string) (string, error) {
 	claims := srvutils.GenerateJWTForService("ConfigManager Service", viper.GetString("domain_name"))
 	return srvutils.SignJWTClaims(claims, signingKey)
@@ -318,7 +336,15 @@ func fetchVizierTemplates(ctx context.Context, authToken,
 	}
 	defer reader.Close()
 
-	yamlMap, err := tar.ReadTarFileFromReader(reader)
+	contents, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifySHA256(contents, resp.SHA256); err != nil {
+		return nil, err
+	}
+
+	yamlMap, err := tar.ReadTarFileFromReader(bytes.NewReader(contents))
 	if err != nil {
 		return nil, err
 	}
@@ -39,6 +39,8 @@ import (
 
 	"px.dev/pixie/src/api/proto/uuidpb"
 	"px.dev/pixie/src/cloud/cron_script/cronscriptpb"
+	"px.dev/pixie/src/cloud/shared/messages"
+	"px.dev/pixie/src/cloud/shared/messagespb"
 	"px.dev/pixie/src/cloud/shared/vzshard"
 	"px.dev/pixie/src/cloud/vzmgr/vzmgrpb"
 	"px.dev/pixie/src/shared/cvmsgs"
@@ -66,7 +68,9 @@ type Server struct {
 	nc          *nats.Conn
 	vzmgrClient vzmgrpb.VZMgrServiceClient
 
-	done chan struct{}
+	assigner       *vzshard.Assigner
+	shardedHandler *vzshard.ShardedHandler
+
 	once sync.Once
 }
 
@@ -77,7 +81,6 @@ func New(db *sqlx.DB, dbKey string, nc *nats.Conn, vzmgrClient vzmgrpb.VZMgrServ
 		dbKey:       dbKey,
 		nc:          nc,
 		vzmgrClient: vzmgrClient,
-		done:        make(chan struct{}),
 	}
 	s.handleRequests()
 
@@ -87,7 +90,12 @@ func New(db *sqlx.DB, dbKey string, nc *nats.Conn, vzmgrClient vzmgrpb.VZMgrServ
 // Stop performs any necessary cleanup before shutdown.
 func (s *Server) Stop() {
 	s.once.Do(func() {
-		close(s.done)
+		if s.shardedHandler != nil {
+			s.shardedHandler.Stop()
+		}
+		if s.assigner != nil {
+			s.assigner.Stop()
+		}
 	})
 }
 
@@ -100,41 +108,26 @@ type CronScript struct {
 	ConfigStr  string     `db:"configs"`
 	Enabled    bool       `db:"enabled"`
 	FrequencyS int64      `db:"frequency_s"`
+	// ClusterConfigs are configs which override ConfigStr on specific clusters.
+	ClusterConfigs ClusterConfigs `db:"cluster_configs"`
+	// ClusterSelector is a label selector for the clusters the script runs on. If set, it is used
+	// instead of ClusterIDs.
+	ClusterSelector string `db:"cluster_selector"`
 }
 
 func (s *Server) handleRequests() {
-	for _, shard := range vzshard.GenerateShardRange() {
-		s.startShardedHandler(shard, cvmsgs.CronScriptChecksumRequestChannel, s.HandleChecksumRequest)
-		s.startShardedHandler(shard, cvmsgs.GetCronScriptsRequestChannel, s.HandleScriptsRequest)
-	}
-}
-
-func (s *Server) startShardedHandler(shard string, topic string, handler HandleNATSMessageFunc) {
 	if s.nc == nil {
 		return
 	}
-	natsCh := make(chan *nats.Msg, 8192)
-	sub, err := s.nc.ChanSubscribe(fmt.Sprintf("v2c.%s.*.%s", shard, topic), natsCh)
+	assigner, err := vzshard.NewAssigner(s.nc, "cron_script")
 	if err != nil {
-		log.WithError(err).Fatal("Failed to subscribe to NATS channel")
+		log.WithError(err).Fatal("Failed to set up vizier shard assignment")
 	}
-
-	go func() {
-		for {
-			select {
-			case <-s.done:
-				sub.Unsubscribe()
-				return
-			case msg := <-natsCh:
-				pb := &cvmsgspb.V2CMessage{}
-				err := proto.Unmarshal(msg.Data, pb)
-				if err != nil {
-					log.WithError(err).Error("Could not unmarshal message")
-				}
-				handler(pb)
-			}
-		}
-	}()
+	s.assigner = assigner
+	s.shardedHandler = vzshard.NewShardedHandler(s.nc, assigner, map[string]vzshard.V2CHandlerFn{
+		cvmsgs.CronScriptChecksumRequestChannel: s.HandleChecksumRequest,
+		cvmsgs.GetCronScriptsRequestChannel:     s.HandleScriptsRequest,
+	})
 }
 
 // HandleChecksumRequest handles incoming requests for cronscript checksums.
@@ -204,7 +197,7 @@ func (s *Server) fetchScriptsForVizier(vizierID *uuidpb.UUID) (map[string]*cvmsg
 	}
 
 	// Fetch all scripts registered to this Vizier.
-	query := `SELECT id, script, cluster_ids, PGP_SYM_DECRYPT(configs, $1::text) as configs, frequency_s FROM cron_scripts WHERE org_id=$2 AND enabled=true`
+	query := `SELECT id, script, cluster_ids, PGP_SYM_DECRYPT(configs, $1::text) as configs, PGP_SYM_DECRYPT(cluster_configs, $1::text) as cluster_configs, cluster_selector, frequency_s FROM cron_scripts WHERE org_id=$2 AND enabled=true`
 	rows, err := s.db.Queryx(query, s.dbKey, utils.UUIDFromProtoOrNil(resp.OrgID))
 	if err != nil {
 		log.WithError(err).Error("Could not fetch scripts for org")
@@ -213,17 +206,33 @@ func (s *Server) fetchScriptsForVizier(vizierID *uuidpb.UUID) (map[string]*cvmsg
 	defer rows.Close()
 
 	scriptsMap := make(map[string]*cvmsgspb.CronScript)
+	// Cache of whether this Vizier matches each of the cluster selectors used by the org's scripts.
+	selectorMatches := make(map[string]bool)
 	for rows.Next() {
-		var s CronScript
-		err = rows.StructScan(&s)
+		var script CronScript
+		err = rows.StructScan(&script)
 		if err != nil {
 			continue
 		}
-		// If no cluster IDs are specified, script is registered to all orgs.
+		// If a cluster selector is specified, the script is registered to the clusters matching the selector.
+		// Otherwise, if no cluster IDs are specified, script is registered to all orgs.
 		// Otherwise, we should check if this cluster is in the list of clusters.
-		if len(s.ClusterIDs) != 0 {
+		if script.ClusterSelector != "" {
+			matches, ok := selectorMatches[script.ClusterSelector]
+			if !ok {
+				matches, err = s.vizierMatchesSelector(utils.UUIDFromProtoOrNil(resp.OrgID), vizierUUID, script.ClusterSelector)
+				if err != nil {
+					log.WithError(err).Error("Could not match cluster selector")
+					continue
+				}
+				selectorMatches[script.ClusterSelector] = matches
+			}
+			if !matches {
+				continue
+			}
+		} else if len(script.ClusterIDs) != 0 {
 			found := false
-			for _, c := range s.ClusterIDs {
+			for _, c := range script.ClusterIDs {
 				if c == vizierUUID {
 					found = true
 				}
@@ -232,11 +241,15 @@ func (s *Server) fetchScriptsForVizier(vizierID *uuidpb.UUID) (map[string]*cvmsg
 				continue
 			}
 		}
-		scriptsMap[s.ID.String()] = &cvmsgspb.CronScript{
-			ID:         utils.ProtoFromUUID(s.ID),
-			Script:     s.Script,
-			Configs:    s.ConfigStr,
-			FrequencyS: s.FrequencyS,
+		configs := script.ConfigStr
+		if c, ok := script.ClusterConfigs[vizierUUID.String()]; ok {
+			configs = c
+		}
+		scriptsMap[script.ID.String()] = &cvmsgspb.CronScript{
+			ID:         utils.ProtoFromUUID(script.ID),
+			Script:     script.Script,
+			Configs:    configs,
+			FrequencyS: script.FrequencyS,
 		}
 	}
 	return scriptsMap, nil
@@ -295,7 +308,7 @@ func (s *Server) GetScript(ctx context.Context, req *cronscriptpb.GetScriptReque
 	}
 	scriptID := utils.UUIDFromProtoOrNil(req.ID)
 
-	query := `SELECT id, org_id, script, cluster_ids, PGP_SYM_DECRYPT(configs, $1::text) as configs, enabled, frequency_s FROM cron_scripts WHERE org_id=$2 AND id=$3`
+	query := `SELECT id, org_id, script, cluster_ids, PGP_SYM_DECRYPT(configs, $1::text) as configs, PGP_SYM_DECRYPT(cluster_configs, $1::text) as cluster_configs, cluster_selector, enabled, frequency_s FROM cron_scripts WHERE org_id=$2 AND id=$3`
 	rows, err := s.db.Queryx(query, s.dbKey, orgID, scriptID)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "Failed to fetch cron script")
@@ -325,6 +338,9 @@ func (s *Server) GetScript(ctx context.Context, req *cronscriptpb.GetScriptReque
 			Configs:    script.ConfigStr,
 			Enabled:    script.Enabled,
 			FrequencyS: script.FrequencyS,
+			// Clusters without overrides use Configs.
+			ClusterConfigs:  script.ClusterConfigs,
+			ClusterSelector: script.ClusterSelector,
 		},
 	}, nil
 }
@@ -346,10 +362,10 @@ func (s *Server) GetScripts(ctx context.Context, req *cronscriptpb.GetScriptsReq
 		ids[i] = utils.UUIDFromProtoOrNil(id)
 	}
 
-	strQuery := "SELECT id, org_id, script, cluster_ids, PGP_SYM_DECRYPT(configs, ? ::text) as configs, enabled, frequency_s FROM cron_scripts WHERE org_id=? AND id IN (?)"
+	strQuery := "SELECT id, org_id, script, cluster_ids, PGP_SYM_DECRYPT(configs, ? ::text) as configs, PGP_SYM_DECRYPT(cluster_configs, ? ::text) as cluster_configs, cluster_selector, enabled, frequency_s FROM cron_scripts WHERE org_id=? AND id IN (?)"
 	cronErr := status.Error(codes.Internal, "Failed to get cron scripts")
 
-	query, args, err := sqlx.In(strQuery, s.dbKey, orgID, ids)
+	query, args, err := sqlx.In(strQuery, s.dbKey, s.dbKey, orgID, ids)
 
 	if err != nil {
 		log.WithError(err).Error("Failed to bind parameters for cron scripts query")
@@ -380,13 +396,15 @@ func (s *Server) GetScripts(ctx context.Context, req *cronscriptpb.GetScriptsReq
 		}
 
 		cpb := &cronscriptpb.CronScript{
-			ID:         utils.ProtoFromUUID(p.ID),
-			OrgID:      utils.ProtoFromUUID(p.OrgID),
-			Script:     p.Script,
-			ClusterIDs: clusterIDs,
-			Configs:    p.ConfigStr,
-			Enabled:    p.Enabled,
-			FrequencyS: p.FrequencyS,
+			ID:              utils.ProtoFromUUID(p.ID),
+			OrgID:           utils.ProtoFromUUID(p.OrgID),
+			Script:          p.Script,
+			ClusterIDs:      clusterIDs,
+			Configs:         p.ConfigStr,
+			Enabled:         p.Enabled,
+			FrequencyS:      p.FrequencyS,
+			ClusterConfigs:  p.ClusterConfigs,
+			ClusterSelector: p.ClusterSelector,
 		}
 		scripts = append(scripts, cpb)
 	}
@@ -406,13 +424,17 @@ func (s *Server) CreateScript(ctx context.Context, req *cronscriptpb.CreateScrip
 		orgID = uuid.FromStringOrNil(sCtx.Claims.GetUserClaims().OrgID)
 	}
 
+	if err := validateClusterSelector(req.ClusterSelector); err != nil {
+		return nil, err
+	}
+
 	clusterIDs := make([]uuid.UUID, len(req.ClusterIDs))
 	for i, c := range req.ClusterIDs {
 		clusterIDs[i] = utils.UUIDFromProtoOrNil(c)
 	}
 
-	query := `INSERT INTO cron_scripts(org_id, script, cluster_ids, configs, enabled, frequency_s) VALUES ($1, $2, $3, PGP_SYM_ENCRYPT($4, $5), $6, $7) RETURNING id`
-	rows, err := s.db.Queryx(query, orgID, req.Script, ClusterIDs(clusterIDs), req.Configs, s.dbKey, !req.Disabled, req.FrequencyS)
+	query := `INSERT INTO cron_scripts(org_id, script, cluster_ids, configs, enabled, frequency_s, cluster_configs, cluster_selector) VALUES ($1, $2, $3, PGP_SYM_ENCRYPT($4, $5), $6, $7, PGP_SYM_ENCRYPT($8, $5), $9) RETURNING id`
+	rows, err := s.db.Queryx(query, orgID, req.Script, ClusterIDs(clusterIDs), req.Configs, s.dbKey, !req.Disabled, req.FrequencyS, ClusterConfigs(req.ClusterConfigs), req.ClusterSelector)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "Failed to create cron script")
 	}
@@ -441,8 +463,13 @@ func (s *Server) CreateScript(ctx context.Context, req *cronscriptpb.CreateScrip
 					},
 				},
 			},
-		}, orgID, req.ClusterIDs)
+		}, orgID, req.ClusterIDs, req.ClusterSelector, req.ClusterConfigs)
 	}
+	s.publishCronScriptChanged(&messagespb.CronScriptChanged{
+		ScriptID: idPb,
+		OrgID:    utils.ProtoFromUUID(orgID),
+		Enabled:  !req.Disabled,
+	})
 
 	return &cronscriptpb.CreateScriptResponse{ID: idPb}, nil
 }
@@ -459,7 +486,7 @@ func (s *Server) UpdateScript(ctx context.Context, req *cronscriptpb.UpdateScrip
 	}
 	scriptID := utils.UUIDFromProtoOrNil(req.ScriptId)
 
-	query := `SELECT id, org_id, script, cluster_ids, PGP_SYM_DECRYPT(configs, $1::text) as configs, enabled, frequency_s FROM cron_scripts WHERE org_id=$2 AND id=$3`
+	query := `SELECT id, org_id, script, cluster_ids, PGP_SYM_DECRYPT(configs, $1::text) as configs, PGP_SYM_DECRYPT(cluster_configs, $1::text) as cluster_configs, cluster_selector, enabled, frequency_s FROM cron_scripts WHERE org_id=$2 AND id=$3`
 	rows, err := s.db.Queryx(query, s.dbKey, orgID, scriptID)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "Failed to fetch cron script")
@@ -495,6 +522,11 @@ func (s *Server) UpdateScript(ctx context.Context, req *cronscriptpb.UpdateScrip
 		freq = req.FrequencyS.Value
 	}
 
+	clusterConfigs := script.ClusterConfigs
+	if req.ClusterConfigs != nil {
+		clusterConfigs = req.ClusterConfigs.Value
+	}
+
 	clusterIDs := script.ClusterIDs
 	if req.ClusterIDs != nil {
 		clusterIDs = make([]uuid.UUID, len(req.ClusterIDs.Value))
@@ -503,8 +535,16 @@ func (s *Server) UpdateScript(ctx context.Context, req *cronscriptpb.UpdateScrip
 		}
 	}
 
-	query = `UPDATE cron_scripts SET script = $1, configs = PGP_SYM_ENCRYPT($2, $3), enabled = $4, frequency_s = $5, cluster_ids=$6 WHERE id = $7`
-	_, err = s.db.Exec(query, contents, configs, s.dbKey, enabled, freq, ClusterIDs(clusterIDs), scriptID)
+	clusterSelector := script.ClusterSelector
+	if req.ClusterSelector != nil {
+		if err := validateClusterSelector(req.ClusterSelector.Value); err != nil {
+			return nil, err
+		}
+		clusterSelector = req.ClusterSelector.Value
+	}
+
+	query = `UPDATE cron_scripts SET script = $1, configs = PGP_SYM_ENCRYPT($2, $3), enabled = $4, frequency_s = $5, cluster_ids=$6, cluster_configs = PGP_SYM_ENCRYPT($8, $3), cluster_selector = $9 WHERE id = $7`
+	_, err = s.db.Exec(query, contents, configs, s.dbKey, enabled, freq, ClusterIDs(clusterIDs), scriptID, clusterConfigs, clusterSelector)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "Failed to update cron script")
 	}
@@ -526,7 +566,7 @@ func (s *Server) UpdateScript(ctx context.Context, req *cronscriptpb.UpdateScrip
 				ScriptID: req.ScriptId,
 			},
 		},
-	}, orgID, prevClusterIDs)
+	}, orgID, prevClusterIDs, script.ClusterSelector, nil)
 
 	if enabled {
 		s.sendCronScriptUpdateToViziers(&cvmsgspb.CronScriptUpdate{
@@ -540,8 +580,13 @@ func (s *Server) UpdateScript(ctx context.Context, req *cronscriptpb.UpdateScrip
 					},
 				},
 			},
-		}, orgID, newClusterIDs)
+		}, orgID, newClusterIDs, clusterSelector, clusterConfigs)
 	}
+	s.publishCronScriptChanged(&messagespb.CronScriptChanged{
+		ScriptID: req.ScriptId,
+		OrgID:    utils.ProtoFromUUID(orgID),
+		Enabled:  enabled,
+	})
 
 	return &cronscriptpb.UpdateScriptResponse{}, nil
 }
@@ -558,7 +603,7 @@ func (s *Server) DeleteScript(ctx context.Context, req *cronscriptpb.DeleteScrip
 	}
 	scriptID := utils.UUIDFromProtoOrNil(req.ID)
 
-	query := `SELECT cluster_ids FROM cron_scripts WHERE org_id=$1 AND id=$2`
+	query := `SELECT cluster_ids, cluster_selector FROM cron_scripts WHERE org_id=$1 AND id=$2`
 	rows, err := s.db.Queryx(query, orgID, scriptID)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "Failed to fetch cron script")
@@ -568,7 +613,8 @@ func (s *Server) DeleteScript(ctx context.Context, req *cronscriptpb.DeleteScrip
 		return nil, status.Error(codes.NotFound, "cron script not found")
 	}
 	var clusterIDs ClusterIDs
-	err = rows.Scan(&clusterIDs)
+	var clusterSelector string
+	err = rows.Scan(&clusterIDs, &clusterSelector)
 	if err != nil {
 		return nil, status.Error(codes.Internal, "Failed to read cron script")
 	}
@@ -589,12 +635,35 @@ func (s *Server) DeleteScript(ctx context.Context, req *cronscriptpb.DeleteScrip
 				ScriptID: req.ID,
 			},
 		},
-	}, orgID, clusterIDProtos)
+	}, orgID, clusterIDProtos, clusterSelector, nil)
+	s.publishCronScriptChanged(&messagespb.CronScriptChanged{
+		ScriptID: req.ID,
+		OrgID:    utils.ProtoFromUUID(orgID),
+		Deleted:  true,
+	})
 
 	return &cronscriptpb.DeleteScriptResponse{}, nil
 }
 
-func (s *Server) sendCronScriptUpdateToViziers(msg *cvmsgspb.CronScriptUpdate, orgID uuid.UUID, clusterIDs []*uuidpb.UUID) {
+// publishCronScriptChanged notifies the rest of the cloud that a cron script was modified.
+func (s *Server) publishCronScriptChanged(msg *messagespb.CronScriptChanged) {
+	if s.nc == nil {
+		return
+	}
+	b, err := msg.Marshal()
+	if err != nil {
+		log.WithError(err).Error("Failed to marshal cron script change")
+		return
+	}
+	if err := s.nc.Publish(messages.CronScriptChangedChannel, b); err != nil {
+		log.WithError(err).Error("Failed to publish cron script change")
+	}
+}
+
+// sendCronScriptUpdateToViziers sends the update to the given viziers, or to the viziers matching the
+// cluster selector if one is specified. Upserted scripts use the configs in clusterConfigs on the
+// clusters which have them.
+func (s *Server) sendCronScriptUpdateToViziers(msg *cvmsgspb.CronScriptUpdate, orgID uuid.UUID, clusterIDs []*uuidpb.UUID, clusterSelector string, clusterConfigs map[string]string) {
 	msg.RequestID = uuid.Must(uuid.NewV4()).String()
 	msg.Timestamp = time.Now().UnixNano()
 
@@ -608,16 +677,26 @@ func (s *Server) sendCronScriptUpdateToViziers(msg *cvmsgspb.CronScriptUpdate, o
 	}
 
 	// Get healthy viziers for org.
-	svcJWT := jwtutils.GenerateJWTForAPIUser("", orgID.String(), time.Now().Add(time.Minute*10), viper.GetString("domain_name"))
-	svcClaims, err := jwtutils.SignJWTClaims(svcJWT, viper.GetString("jwt_signing_key"))
+	ctx, err := orgContext(orgID)
 	if err != nil {
 		log.WithError(err).Error("Failed to sign claims")
 		return
 	}
-	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization",
-		fmt.Sprintf("bearer %s", svcClaims))
 
-	if len(clusterIDs) == 0 { // If no clusterIDs specified, this message should be sent to all Viziers in the org.
+	if clusterSelector != "" {
+		viziers, err := s.vzmgrClient.GetViziersByLabelSelector(ctx, &vzmgrpb.GetViziersByLabelSelectorRequest{
+			OrgID:         utils.ProtoFromUUID(orgID),
+			LabelSelector: clusterSelector,
+		})
+		if err != nil {
+			log.WithError(err).Error("Could not get viziers matching cluster selector")
+			return
+		}
+		if len(viziers.VizierIDs) == 0 {
+			return
+		}
+		clusterIDs = viziers.VizierIDs
+	} else if len(clusterIDs) == 0 { // If no clusterIDs specified, this message should be sent to all Viziers in the org.
 		viziers, err := s.vzmgrClient.GetViziersByOrg(ctx, utils.ProtoFromUUID(orgID))
 		if err != nil {
 			log.WithError(err).Error("Could not get viziers for org")
@@ -637,11 +716,62 @@ func (s *Server) sendCronScriptUpdateToViziers(msg *cvmsgspb.CronScriptUpdate, o
 	for _, v := range vzInfoResp.VizierInfos {
 		vzUUID := utils.UUIDFromProtoOrNil(v.VizierID)
 		if v.Status != cvmsgspb.VZ_ST_DISCONNECTED && v.Status != cvmsgspb.VZ_ST_UNKNOWN {
-			go s.retryMessageUntilResponse(c2vMsg, vzshard.C2VTopic(cvmsgs.CronScriptUpdatesChannel, vzUUID), vzshard.V2CTopic(fmt.Sprintf("%s:%s", cvmsgs.CronScriptUpdatesResponseChannel, msg.RequestID), vzUUID))
+			vzMsg := c2vMsg
+			if c, ok := clusterConfigs[vzUUID.String()]; ok && msg.GetUpsertReq() != nil {
+				vzMsg, err = c2vMessageWithConfigs(msg, c)
+				if err != nil {
+					log.WithError(err).Error("Failed to marshal update script msg")
+					continue
+				}
+			}
+			go s.retryMessageUntilResponse(vzMsg, vzshard.C2VTopic(cvmsgs.CronScriptUpdatesChannel, vzUUID), vzshard.V2CTopic(fmt.Sprintf("%s:%s", cvmsgs.CronScriptUpdatesResponseChannel, msg.RequestID), vzUUID))
 		}
 	}
 }
 
+// orgContext creates a context which is authorized to make requests on behalf of the org.
+func orgContext(orgID uuid.UUID) (context.Context, error) {
+	svcJWT := jwtutils.GenerateJWTForAPIUser("", orgID.String(), time.Now().Add(time.Minute*10), viper.GetString("domain_name"))
+	svcClaims, err := jwtutils.SignJWTClaims(svcJWT, viper.GetString("jwt_signing_key"))
+	if err != nil {
+		return nil, err
+	}
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization",
+		fmt.Sprintf("bearer %s", svcClaims)), nil
+}
+
+// vizierMatchesSelector checks whether the labels of the given vizier match the cluster selector.
+func (s *Server) vizierMatchesSelector(orgID uuid.UUID, vizierID uuid.UUID, clusterSelector string) (bool, error) {
+	ctx, err := orgContext(orgID)
+	if err != nil {
+		return false, err
+	}
+	viziers, err := s.vzmgrClient.GetViziersByLabelSelector(ctx, &vzmgrpb.GetViziersByLabelSelectorRequest{
+		OrgID:         utils.ProtoFromUUID(orgID),
+		LabelSelector: clusterSelector,
+	})
+	if err != nil {
+		return false, err
+	}
+	for _, id := range viziers.VizierIDs {
+		if utils.UUIDFromProtoOrNil(id) == vizierID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// c2vMessageWithConfigs creates a message which upserts the script with the given configs.
+func c2vMessageWithConfigs(msg *cvmsgspb.CronScriptUpdate, configs string) (*cvmsgspb.C2VMessage, error) {
+	vzMsg := proto.Clone(msg).(*cvmsgspb.CronScriptUpdate)
+	vzMsg.GetUpsertReq().Script.Configs = configs
+	anyMsg, err := types.MarshalAny(vzMsg)
+	if err != nil {
+		return nil, err
+	}
+	return &cvmsgspb.C2VMessage{Msg: anyMsg}, nil
+}
+
 func (s *Server) retryMessageUntilResponse(msg *cvmsgspb.C2VMessage, publishTopic string, respTopic string) {
 	sendMsg := func() error {
 		return s.natsReplyAndResponse(msg, publishTopic, respTopic)
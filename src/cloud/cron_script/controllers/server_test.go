@@ -36,6 +36,8 @@ import (
 	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	"px.dev/pixie/src/api/proto/uuidpb"
 	"px.dev/pixie/src/cloud/cron_script/controllers"
@@ -680,3 +682,160 @@ func TestServer_HandleGetScriptsRequest(t *testing.T) {
 	s.HandleScriptsRequest(v2cMsg)
 	wg.Wait()
 }
+
+func TestServer_HandleGetScriptsRequestClusterConfigs(t *testing.T) {
+	mustLoadTestData(db)
+
+	vzID := "423e4567-e89b-12d3-a456-426655440001"
+	orgID := "223e4567-e89b-12d3-a456-426655440001"
+
+	db.MustExec(`UPDATE cron_scripts SET cluster_configs = PGP_SYM_ENCRYPT($1, $2) WHERE id=$3`,
+		controllers.ClusterConfigs(map[string]string{vzID: "testConfigYaml2: override"}), "test", "123e4567-e89b-12d3-a456-426655440001")
+
+	ctrl := gomock.NewController(t)
+	mockVZMgr := mock_vzmgrpb.NewMockVZMgrServiceClient(ctrl)
+
+	mockVZMgr.EXPECT().GetOrgFromVizier(gomock.Any(), utils.ProtoFromUUIDStrOrNil(vzID)).Return(&vzmgrpb.GetOrgFromVizierResponse{
+		OrgID: utils.ProtoFromUUIDStrOrNil(orgID)}, nil)
+
+	nc, natsCleanup := testingutils.MustStartTestNATS(t)
+	defer natsCleanup()
+
+	s := controllers.New(db, "test", nc, mockVZMgr)
+
+	resp, err := s.GetScript(createTestContext(), &cronscriptpb.GetScriptRequest{
+		ID:    utils.ProtoFromUUIDStrOrNil("123e4567-e89b-12d3-a456-426655440001"),
+		OrgID: utils.ProtoFromUUIDStrOrNil(orgID),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{vzID: "testConfigYaml2: override"}, resp.Script.ClusterConfigs)
+
+	req := &cvmsgspb.GetCronScriptsRequest{
+		Topic: "test",
+	}
+	anyMsg, err := types.MarshalAny(req)
+	require.NoError(t, err)
+	v2cMsg := &cvmsgspb.V2CMessage{
+		Msg:      anyMsg,
+		VizierID: vzID,
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+
+	csMap := map[string]*cvmsgspb.CronScript{
+		"123e4567-e89b-12d3-a456-426655440001": {
+			ID:         utils.ProtoFromUUIDStrOrNil("123e4567-e89b-12d3-a456-426655440001"),
+			Script:     "px.stream()",
+			FrequencyS: 10,
+			Configs:    "testConfigYaml2: override",
+		},
+	}
+	mdSub, err := nc.Subscribe(vzshard.C2VTopic(fmt.Sprintf("%s:%s", cvmsgs.GetCronScriptsResponseChannel, "test"), uuid.FromStringOrNil(vzID)), func(msg *nats.Msg) {
+		c2vMsg := &cvmsgspb.C2VMessage{}
+		err := proto.Unmarshal(msg.Data, c2vMsg)
+		require.NoError(t, err)
+		req := &cvmsgspb.GetCronScriptsResponse{}
+		err = types.UnmarshalAny(c2vMsg.Msg, req)
+		require.NoError(t, err)
+		assert.Equal(t, csMap, req.Scripts)
+		wg.Done()
+	})
+	defer func() {
+		err = mdSub.Unsubscribe()
+		require.NoError(t, err)
+	}()
+
+	s.HandleScriptsRequest(v2cMsg)
+	wg.Wait()
+}
+
+func TestServer_HandleGetScriptsRequestClusterSelector(t *testing.T) {
+	mustLoadTestData(db)
+
+	vzID := "523e4567-e89b-12d3-a456-426655440001"
+	orgID := "223e4567-e89b-12d3-a456-426655440001"
+
+	db.MustExec(`UPDATE cron_scripts SET cluster_selector = $1 WHERE id=$2`, "env=prod", "123e4567-e89b-12d3-a456-426655440001")
+	db.MustExec(`UPDATE cron_scripts SET cluster_selector = $1, enabled = true WHERE id=$2`, "env=staging", "123e4567-e89b-12d3-a456-426655440003")
+
+	ctrl := gomock.NewController(t)
+	mockVZMgr := mock_vzmgrpb.NewMockVZMgrServiceClient(ctrl)
+
+	mockVZMgr.EXPECT().GetOrgFromVizier(gomock.Any(), utils.ProtoFromUUIDStrOrNil(vzID)).Return(&vzmgrpb.GetOrgFromVizierResponse{
+		OrgID: utils.ProtoFromUUIDStrOrNil(orgID)}, nil)
+	mockVZMgr.EXPECT().GetViziersByLabelSelector(gomock.Any(), &vzmgrpb.GetViziersByLabelSelectorRequest{
+		OrgID:         utils.ProtoFromUUIDStrOrNil(orgID),
+		LabelSelector: "env=prod",
+	}).Return(&vzmgrpb.GetViziersByOrgResponse{
+		VizierIDs: []*uuidpb.UUID{utils.ProtoFromUUIDStrOrNil(vzID)},
+	}, nil)
+	mockVZMgr.EXPECT().GetViziersByLabelSelector(gomock.Any(), &vzmgrpb.GetViziersByLabelSelectorRequest{
+		OrgID:         utils.ProtoFromUUIDStrOrNil(orgID),
+		LabelSelector: "env=staging",
+	}).Return(&vzmgrpb.GetViziersByOrgResponse{
+		VizierIDs: []*uuidpb.UUID{utils.ProtoFromUUIDStrOrNil("423e4567-e89b-12d3-a456-426655440001")},
+	}, nil)
+
+	nc, natsCleanup := testingutils.MustStartTestNATS(t)
+	defer natsCleanup()
+
+	s := controllers.New(db, "test", nc, mockVZMgr)
+
+	req := &cvmsgspb.GetCronScriptsRequest{
+		Topic: "test",
+	}
+	anyMsg, err := types.MarshalAny(req)
+	require.NoError(t, err)
+	v2cMsg := &cvmsgspb.V2CMessage{
+		Msg:      anyMsg,
+		VizierID: vzID,
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+
+	csMap := map[string]*cvmsgspb.CronScript{
+		"123e4567-e89b-12d3-a456-426655440001": {
+			ID:         utils.ProtoFromUUIDStrOrNil("123e4567-e89b-12d3-a456-426655440001"),
+			Script:     "px.stream()",
+			FrequencyS: 10,
+			Configs:    "testConfigYaml2: efgh",
+		},
+	}
+	mdSub, err := nc.Subscribe(vzshard.C2VTopic(fmt.Sprintf("%s:%s", cvmsgs.GetCronScriptsResponseChannel, "test"), uuid.FromStringOrNil(vzID)), func(msg *nats.Msg) {
+		c2vMsg := &cvmsgspb.C2VMessage{}
+		err := proto.Unmarshal(msg.Data, c2vMsg)
+		require.NoError(t, err)
+		req := &cvmsgspb.GetCronScriptsResponse{}
+		err = types.UnmarshalAny(c2vMsg.Msg, req)
+		require.NoError(t, err)
+		assert.Equal(t, csMap, req.Scripts)
+		wg.Done()
+	})
+	defer func() {
+		err = mdSub.Unsubscribe()
+		require.NoError(t, err)
+	}()
+
+	s.HandleScriptsRequest(v2cMsg)
+	wg.Wait()
+}
+
+func TestServer_CreateScriptInvalidSelector(t *testing.T) {
+	mustLoadTestData(db)
+
+	ctrl := gomock.NewController(t)
+	mockVZMgr := mock_vzmgrpb.NewMockVZMgrServiceClient(ctrl)
+
+	s := controllers.New(db, "test", nil, mockVZMgr)
+
+	resp, err := s.CreateScript(createTestContext(), &cronscriptpb.CreateScriptRequest{
+		Script:          "px.display()",
+		FrequencyS:      11,
+		ClusterSelector: "env in (prod",
+	})
+	require.Error(t, err)
+	assert.Nil(t, resp)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
@@ -23,6 +23,9 @@ import (
 	"encoding/json"
 
 	"github.com/gofrs/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"k8s.io/apimachinery/pkg/labels"
 )
 
 // ClusterIDs represents an array of cluster IDs.
@@ -46,3 +49,40 @@ func (p *ClusterIDs) Scan(src interface{}) error {
 	}
 	return json.Unmarshal(data, p)
 }
+
+// ClusterConfigs represents configs keyed by cluster ID.
+type ClusterConfigs map[string]string
+
+// Value Returns a golang database/sql driver value for ClusterConfigs.
+func (p ClusterConfigs) Value() (driver.Value, error) {
+	if len(p) == 0 {
+		return nil, nil
+	}
+	b, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// Scan Scans the sqlx database type ([]bytes) into the ClusterConfigs type.
+func (p *ClusterConfigs) Scan(src interface{}) error {
+	var data []byte
+	switch v := src.(type) {
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	default:
+		return nil
+	}
+	return json.Unmarshal(data, p)
+}
+
+// validateClusterSelector checks that the cluster selector is a valid label selector.
+func validateClusterSelector(selector string) error {
+	if _, err := labels.Parse(selector); err != nil {
+		return status.Errorf(codes.InvalidArgument, "invalid cluster selector: %s", err.Error())
+	}
+	return nil
+}
@@ -110,6 +110,7 @@ func main() {
 	s := server.NewPLServer(env.New(viper.GetString("domain_name")), mux)
 
 	c := controllers.New(db, dbKey, nc, vzmgrClient)
+	defer c.Stop()
 
 	cronscriptpb.RegisterCronScriptServiceServer(s.GRPCServer(), c)
 
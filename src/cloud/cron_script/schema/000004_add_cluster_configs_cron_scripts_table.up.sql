@@ -0,0 +1,3 @@
+-- cluster_configs are configs which override the configs on specific clusters. The value is an
+-- encrypted JSON map from cluster ID to the YAML configs.
+ALTER TABLE cron_scripts ADD COLUMN cluster_configs bytea;
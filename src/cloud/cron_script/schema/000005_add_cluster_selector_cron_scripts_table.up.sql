@@ -0,0 +1,3 @@
+-- cluster_selector is a label selector for the clusters the script runs on. If set, it is used
+-- instead of cluster_ids.
+ALTER TABLE cron_scripts ADD COLUMN cluster_selector varchar(1000) NOT NULL DEFAULT '';
@@ -28,6 +28,7 @@ import (
 	log "github.com/sirupsen/logrus"
 
 	"px.dev/pixie/src/cloud/indexer/md"
+	"px.dev/pixie/src/cloud/shared/vzshard"
 	"px.dev/pixie/src/cloud/shared/vzutils"
 	"px.dev/pixie/src/cloud/vzmgr/vzmgrpb"
 	"px.dev/pixie/src/shared/services/msgbus"
@@ -53,6 +54,20 @@ func (c *concurrentIndexersMap) write(uid string, vz *md.VizierIndexer) {
 	c.unsafeMap[uid] = vz
 }
 
+// removeIf removes and returns all indexers that match the given predicate.
+func (c *concurrentIndexersMap) removeIf(pred func(*md.VizierIndexer) bool) []*md.VizierIndexer {
+	c.mapMu.Lock()
+	defer c.mapMu.Unlock()
+	var removed []*md.VizierIndexer
+	for uid, vz := range c.unsafeMap {
+		if pred(vz) {
+			removed = append(removed, vz)
+			delete(c.unsafeMap, uid)
+		}
+	}
+	return removed
+}
+
 func (c *concurrentIndexersMap) values() []*md.VizierIndexer {
 	c.mapMu.RLock()
 	defer c.mapMu.RUnlock()
@@ -77,9 +92,9 @@ type Indexer struct {
 }
 
 // NewIndexer creates a new Vizier indexer. This is a wrapper around the Vizier Watcher, which starts the indexer
-// for any active viziers.
-func NewIndexer(nc *nats.Conn, vzmgrClient vzmgrpb.VZMgrServiceClient, st msgbus.Streamer, es *elastic.Client, indexName, fromShardID, toShardID string) (*Indexer, error) {
-	watcher, err := vzutils.NewWatcher(nc, vzmgrClient, fromShardID, toShardID)
+// for any active viziers in the shards owned by the assigner.
+func NewIndexer(nc *nats.Conn, vzmgrClient vzmgrpb.VZMgrServiceClient, st msgbus.Streamer, es *elastic.Client, indexName string, assigner *vzshard.Assigner) (*Indexer, error) {
+	watcher, err := vzutils.NewWatcherWithAssigner(nc, vzmgrClient, assigner)
 	if err != nil {
 		return nil, err
 	}
@@ -92,6 +107,7 @@ func NewIndexer(nc *nats.Conn, vzmgrClient vzmgrpb.VZMgrServiceClient, st msgbus
 		indexName: indexName,
 	}
 
+	watcher.RegisterReleaseHandler(i.handleRelease)
 	err = watcher.RegisterVizierHandler(i.handleVizier)
 	if err != nil {
 		return nil, err
@@ -127,3 +143,17 @@ func (i *Indexer) handleVizier(id uuid.UUID, orgID uuid.UUID, uid string) error
 	i.clusters.write(uid, vzIndexer)
 	return nil
 }
+
+// handleRelease stops the indexers for any viziers in shards that were assigned to another replica.
+func (i *Indexer) handleRelease(shards []string) {
+	released := make(map[string]bool)
+	for _, shard := range shards {
+		released[shard] = true
+	}
+	removed := i.clusters.removeIf(func(vz *md.VizierIndexer) bool {
+		return released[vzshard.VizierIDToShard(vz.VizierID())]
+	})
+	for _, vz := range removed {
+		vz.Stop()
+	}
+}
@@ -32,6 +32,7 @@ import (
 	"px.dev/pixie/src/cloud/indexer/controllers"
 	"px.dev/pixie/src/cloud/indexer/md"
 	"px.dev/pixie/src/cloud/shared/esutils"
+	"px.dev/pixie/src/cloud/shared/vzshard"
 	"px.dev/pixie/src/cloud/vzmgr/vzmgrpb"
 	"px.dev/pixie/src/shared/services"
 	"px.dev/pixie/src/shared/services/env"
@@ -88,6 +89,7 @@ func mustConnectElastic() *elastic.Client {
 
 func main() {
 	services.SetupService("indexer-service", 51800)
+	vzshard.SetupFlags()
 	services.PostFlagSetupAndParse()
 	services.CheckServiceFlags()
 	services.SetupServiceLogging()
@@ -143,7 +145,13 @@ func main() {
 		log.WithError(err).Fatal("Could not connect to vzmgr")
 	}
 
-	indexer, err := controllers.NewIndexer(nc, vzmgrClient, strmr, es, indexName, "00", "ff")
+	assigner, err := vzshard.NewAssigner(nc, "indexer")
+	if err != nil {
+		log.WithError(err).Fatal("Could not set up vizier shard assignment")
+	}
+	defer assigner.Stop()
+
+	indexer, err := controllers.NewIndexer(nc, vzmgrClient, strmr, es, indexName, assigner)
 	if err != nil {
 		log.WithError(err).Fatal("Could not start indexer")
 	}
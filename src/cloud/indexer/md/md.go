@@ -151,6 +151,11 @@ func (v *VizierIndexer) Start(topic string) error {
 	return nil
 }
 
+// VizierID returns the ID of the vizier being indexed.
+func (v *VizierIndexer) VizierID() uuid.UUID {
+	return v.vizierID
+}
+
 // Stop stops the indexer.
 func (v *VizierIndexer) Stop() {
 	close(v.quitCh)
@@ -0,0 +1,243 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+
+	"github.com/gofrs/uuid"
+	"github.com/jmoiron/sqlx"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"px.dev/pixie/src/api/proto/uuidpb"
+	"px.dev/pixie/src/cloud/plugin/pluginpb"
+	"px.dev/pixie/src/utils"
+)
+
+// clusterOverride is an org's override of a plugin's configuration on a single cluster.
+type clusterOverride struct {
+	configurations map[string]string
+	exportURL      *string
+}
+
+// merge returns the configurations and export URL that a script uses on the cluster. The script's
+// own export URL takes precedence over the cluster's, which takes precedence over the org's.
+func (o *clusterOverride) merge(configMap map[string]string, orgExportURL string, scriptExportURL string) (map[string]string, string) {
+	merged := make(map[string]string)
+	for k, v := range configMap {
+		merged[k] = v
+	}
+	for k, v := range o.configurations {
+		merged[k] = v
+	}
+
+	exportURL := orgExportURL
+	if o.exportURL != nil && *o.exportURL != "" {
+		exportURL = *o.exportURL
+	}
+	if scriptExportURL != "" {
+		exportURL = scriptExportURL
+	}
+	return merged, exportURL
+}
+
+// getClusterOverrides fetches the org's overrides of the plugin's configuration, keyed by cluster.
+func (s *Server) getClusterOverrides(q sqlx.Queryer, orgID uuid.UUID, pluginID string) (map[uuid.UUID]*clusterOverride, error) {
+	query := `SELECT cluster_id, PGP_SYM_DECRYPT(configurations, $1::text), PGP_SYM_DECRYPT(custom_export_url, $1::text) FROM org_data_retention_plugin_cluster_overrides WHERE org_id=$2 AND plugin_id=$3`
+	rows, err := q.Queryx(query, s.dbKey, orgID, pluginID)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to fetch cluster overrides")
+	}
+	defer rows.Close()
+
+	overrides := make(map[uuid.UUID]*clusterOverride)
+	for rows.Next() {
+		var clusterID uuid.UUID
+		var configurationJSON []byte
+		var exportURL *string
+		err = rows.Scan(&clusterID, &configurationJSON, &exportURL)
+		if err != nil {
+			return nil, status.Error(codes.Internal, "failed to read cluster overrides")
+		}
+		o := &clusterOverride{exportURL: exportURL}
+		if len(configurationJSON) > 0 {
+			err = json.Unmarshal(configurationJSON, &o.configurations)
+			if err != nil {
+				return nil, status.Error(codes.Internal, "failed to read cluster overrides")
+			}
+		}
+		overrides[clusterID] = o
+	}
+	return overrides, nil
+}
+
+// setClusterOverrides replaces the org's overrides of the plugin's configuration. Overrides may only
+// be set for the org's clusters, and for configurations that the plugin release has.
+func (s *Server) setClusterOverrides(ctx context.Context, txn *sqlx.Tx, orgID uuid.UUID, pluginID string, overrides []*pluginpb.ClusterConfigOverride, pluginConfigs Configurations, allowCustomExportURL bool) error {
+	seen := make(map[uuid.UUID]bool)
+	for _, o := range overrides {
+		if utils.IsNilUUIDProto(o.ClusterID) {
+			return status.Error(codes.InvalidArgument, "Must specify cluster ID for cluster override")
+		}
+		clusterID := utils.UUIDFromProtoOrNil(o.ClusterID)
+		if seen[clusterID] {
+			return status.Errorf(codes.InvalidArgument, "Multiple overrides for cluster %s", clusterID.String())
+		}
+		seen[clusterID] = true
+
+		for k := range o.Configurations {
+			if _, ok := pluginConfigs[k]; !ok {
+				return status.Errorf(codes.InvalidArgument, "Plugin has no configuration %q", k)
+			}
+		}
+	}
+
+	if len(overrides) > 0 {
+		viziers, err := s.vzmgrClient.GetViziersByOrg(ctx, utils.ProtoFromUUID(orgID))
+		if err != nil {
+			return status.Error(codes.Internal, "failed to fetch clusters for org")
+		}
+		orgClusters := make(map[uuid.UUID]bool)
+		for _, id := range viziers.VizierIDs {
+			orgClusters[utils.UUIDFromProtoOrNil(id)] = true
+		}
+		for clusterID := range seen {
+			if !orgClusters[clusterID] {
+				return status.Errorf(codes.NotFound, "Cluster %s not found", clusterID.String())
+			}
+		}
+	}
+
+	err := s.deleteClusterOverrides(txn, orgID, pluginID)
+	if err != nil {
+		return err
+	}
+
+	query := `INSERT INTO org_data_retention_plugin_cluster_overrides (org_id, plugin_id, cluster_id, configurations, custom_export_url) VALUES ($1, $2, $3, PGP_SYM_ENCRYPT($4, $5), PGP_SYM_ENCRYPT($6, $5))`
+	for _, o := range overrides {
+		var configurations []byte
+		if len(o.Configurations) > 0 {
+			configurations, _ = json.Marshal(o.Configurations)
+		}
+		// Like the org's export URL, the cluster's export URL is only used if the plugin allows it.
+		var exportURL *string
+		if o.CustomExportUrl != "" && allowCustomExportURL {
+			exportURL = &o.CustomExportUrl
+		}
+		_, err = txn.Exec(query, orgID, pluginID, utils.UUIDFromProtoOrNil(o.ClusterID), configurations, s.dbKey, exportURL)
+		if err != nil {
+			return status.Error(codes.Internal, "failed to update cluster overrides")
+		}
+	}
+	return nil
+}
+
+// clearClusterOverrideExportURLs removes the export URLs from the org's overrides, for plugins
+// which don't allow custom export URLs.
+func (s *Server) clearClusterOverrideExportURLs(txn *sqlx.Tx, orgID uuid.UUID, pluginID string) error {
+	query := `UPDATE org_data_retention_plugin_cluster_overrides SET custom_export_url = NULL WHERE org_id=$1 AND plugin_id=$2`
+	_, err := txn.Exec(query, orgID, pluginID)
+	if err != nil {
+		return status.Error(codes.Internal, "failed to update cluster overrides")
+	}
+	return nil
+}
+
+func (s *Server) deleteClusterOverrides(txn *sqlx.Tx, orgID uuid.UUID, pluginID string) error {
+	query := `DELETE FROM org_data_retention_plugin_cluster_overrides WHERE org_id=$1 AND plugin_id=$2`
+	_, err := txn.Exec(query, orgID, pluginID)
+	if err != nil {
+		return status.Error(codes.Internal, "failed to delete cluster overrides")
+	}
+	return nil
+}
+
+// clusterScriptConfigs returns the YAML configs for a script on each cluster with an override,
+// keyed by cluster ID. Returns nil if there are no overrides.
+func clusterScriptConfigs(overrides map[uuid.UUID]*clusterOverride, configMap map[string]string, orgExportURL string, scriptExportURL string, insecureTLS bool) (map[string]string, error) {
+	if len(overrides) == 0 {
+		return nil, nil
+	}
+	clusterConfigs := make(map[string]string)
+	for clusterID, o := range overrides {
+		mergedConfigs, exportURL := o.merge(configMap, orgExportURL, scriptExportURL)
+		configYAML, err := scriptConfigToYAML(mergedConfigs, exportURL, insecureTLS)
+		if err != nil {
+			return nil, err
+		}
+		clusterConfigs[clusterID.String()] = configYAML
+	}
+	return clusterConfigs, nil
+}
+
+// sortedClusterIDs returns the IDs of the clusters with overrides, in order.
+func sortedClusterIDs(overrides map[uuid.UUID]*clusterOverride) []uuid.UUID {
+	ids := make([]uuid.UUID, 0, len(overrides))
+	for id := range overrides {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return ids[i].String() < ids[j].String()
+	})
+	return ids
+}
+
+// clusterOverridesToProto converts the overrides to protos, ordered by cluster ID.
+func clusterOverridesToProto(overrides map[uuid.UUID]*clusterOverride) []*pluginpb.ClusterConfigOverride {
+	var res []*pluginpb.ClusterConfigOverride
+	for _, id := range sortedClusterIDs(overrides) {
+		o := overrides[id]
+		p := &pluginpb.ClusterConfigOverride{
+			ClusterID:      utils.ProtoFromUUID(id),
+			Configurations: o.configurations,
+		}
+		if o.exportURL != nil {
+			p.CustomExportUrl = *o.exportURL
+		}
+		res = append(res, p)
+	}
+	return res
+}
+
+// clusterScriptConfigsToProto returns the configuration a script runs with on each of its clusters
+// with an override, ordered by cluster ID.
+func clusterScriptConfigsToProto(overrides map[uuid.UUID]*clusterOverride, clusterIDs []*uuidpb.UUID, configMap map[string]string, orgExportURL string, scriptExportURL string) []*pluginpb.ClusterScriptConfig {
+	// Scripts without clusters run on all clusters.
+	runsOn := make(map[uuid.UUID]bool)
+	for _, c := range clusterIDs {
+		runsOn[utils.UUIDFromProtoOrNil(c)] = true
+	}
+
+	var res []*pluginpb.ClusterScriptConfig
+	for _, id := range sortedClusterIDs(overrides) {
+		if len(runsOn) > 0 && !runsOn[id] {
+			continue
+		}
+		mergedConfigs, exportURL := overrides[id].merge(configMap, orgExportURL, scriptExportURL)
+		res = append(res, &pluginpb.ClusterScriptConfig{
+			ClusterID:      utils.ProtoFromUUID(id),
+			ExportURL:      exportURL,
+			Configurations: mergedConfigs,
+		})
+	}
+	return res
+}
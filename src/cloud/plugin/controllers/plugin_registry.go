@@ -0,0 +1,332 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/blang/semver"
+	"github.com/gofrs/uuid"
+	"github.com/jmoiron/sqlx"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"px.dev/pixie/src/carnot/planner/plannerpb"
+	"px.dev/pixie/src/carnot/planner/pxlcompiler"
+	"px.dev/pixie/src/cloud/plugin/pluginpb"
+	"px.dev/pixie/src/shared/services/authcontext"
+	claimsutils "px.dev/pixie/src/shared/services/utils"
+	"px.dev/pixie/src/utils"
+)
+
+const (
+	maxPluginNameLength = 1024
+	// The export URL that preset scripts are compiled against. Scripts are sent to the plugin's
+	// export URL at runtime.
+	compileExportURL = "plugin.export.url:443"
+)
+
+var (
+	pluginIDRegex = regexp.MustCompile(`^[a-z0-9]([a-z0-9._-]*[a-z0-9])?$`)
+	// Configurations are sent to the export endpoint as headers, so their keys must be valid header names.
+	configKeyRegex = regexp.MustCompile("^[A-Za-z0-9!#$%&'*+.^_`|~-]+$")
+)
+
+// SetScriptCompiler sets the compiler used to check the preset scripts of registered plugins. If
+// unset, preset scripts are not compiled.
+func (s *Server) SetScriptCompiler(c pxlcompiler.Compiler) {
+	s.compilerMu.Lock()
+	defer s.compilerMu.Unlock()
+	s.compiler = c
+}
+
+// authorizePluginOrg checks that the caller may manage plugins for the given org. Only services
+// may manage plugins which are available to all orgs.
+func authorizePluginOrg(ctx context.Context, orgID uuid.UUID) error {
+	sCtx, err := authcontext.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	switch claimsutils.GetClaimsType(sCtx.Claims) {
+	case claimsutils.ServiceClaimType:
+		return nil
+	case claimsutils.UserClaimType:
+		if orgID == uuid.Nil {
+			return status.Error(codes.PermissionDenied, "only Pixie Cloud may manage plugins available to all orgs")
+		}
+		if uuid.FromStringOrNil(sCtx.Claims.GetUserClaims().OrgID) != orgID {
+			return status.Error(codes.PermissionDenied, "user does not have permissions to manage plugins for org")
+		}
+		return nil
+	default:
+		return status.Error(codes.PermissionDenied, "unsupported claims type")
+	}
+}
+
+// checkPluginOwner checks that the plugin, if it was already registered, belongs to the given org.
+// It returns whether the plugin exists. The plugin ID stays locked until the transaction ends, so
+// that two orgs can't both register the same new plugin.
+func checkPluginOwner(txn *sqlx.Tx, pluginID string, orgID uuid.UUID) (bool, error) {
+	if _, err := txn.Exec(`SELECT pg_advisory_xact_lock(hashtext($1))`, pluginID); err != nil {
+		return false, status.Error(codes.Internal, "Failed to lock plugin")
+	}
+
+	query := `SELECT org_id FROM plugin_releases WHERE id=$1 LIMIT 1`
+	rows, err := txn.Queryx(query, pluginID)
+	if err != nil {
+		return false, status.Error(codes.Internal, "Failed to fetch plugin")
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return false, nil
+	}
+	var owner uuid.NullUUID
+	if err := rows.Scan(&owner); err != nil {
+		return false, status.Error(codes.Internal, "Failed to read plugin")
+	}
+	if owner.UUID != orgID {
+		return true, status.Errorf(codes.PermissionDenied, "plugin %s is registered by another org", pluginID)
+	}
+	return true, nil
+}
+
+func validatePluginURL(field string, u string) error {
+	parsed, err := url.Parse(u)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return status.Errorf(codes.InvalidArgument, "%s must be a valid http(s) URL", field)
+	}
+	return nil
+}
+
+func validateRegisterPluginRequest(req *pluginpb.RegisterPluginRequest) error {
+	if req.Name == "" || len(req.Name) > maxPluginNameLength {
+		return status.Errorf(codes.InvalidArgument, "plugin name must be between 1 and %d characters", maxPluginNameLength)
+	}
+	if !pluginIDRegex.MatchString(req.ID) {
+		return status.Error(codes.InvalidArgument, "plugin ID must consist of lowercase alphanumeric characters, '.', '_' or '-'")
+	}
+	if _, err := semver.Parse(req.Version); err != nil {
+		return status.Errorf(codes.InvalidArgument, "invalid plugin version %q: %v", req.Version, err)
+	}
+
+	r := req.Retention
+	if r == nil {
+		return nil
+	}
+	for k := range r.Configurations {
+		if !configKeyRegex.MatchString(k) {
+			return status.Errorf(codes.InvalidArgument, "configuration %q is not a valid header name", k)
+		}
+	}
+	if r.DocumentationURL != "" {
+		if err := validatePluginURL("documentation URL", r.DocumentationURL); err != nil {
+			return err
+		}
+	}
+	if r.DefaultExportURL == "" && !r.AllowCustomExportURL {
+		return status.Error(codes.InvalidArgument, "must specify a default export URL if custom export URLs are not allowed")
+	}
+
+	names := make(map[string]bool)
+	for _, p := range r.PresetScripts {
+		if p.Name == "" {
+			return status.Error(codes.InvalidArgument, "preset script name must not be empty")
+		}
+		if names[p.Name] {
+			return status.Errorf(codes.InvalidArgument, "duplicate preset script %q", p.Name)
+		}
+		names[p.Name] = true
+		if p.DefaultFrequencyS <= 0 {
+			return status.Errorf(codes.InvalidArgument, "preset script %q must have a positive frequency", p.Name)
+		}
+		if !strings.Contains(p.Script, "px.export") {
+			return status.Errorf(codes.InvalidArgument, "preset script %q must export its data with px.export", p.Name)
+		}
+	}
+	return nil
+}
+
+// compilePresetScripts compiles the preset scripts with the same configs they are run with.
+func (s *Server) compilePresetScripts(presets []*pluginpb.GetRetentionPluginConfigResponse_PresetScript) error {
+	s.compilerMu.Lock()
+	defer s.compilerMu.Unlock()
+	if s.compiler == nil {
+		return nil
+	}
+
+	for _, p := range presets {
+		endTime := time.Now()
+		startTime := endTime.Add(-time.Duration(p.DefaultFrequencyS) * time.Second)
+		errs, err := s.compiler.Compile(&plannerpb.QueryRequest{
+			QueryStr: p.Script,
+			Configs: &plannerpb.Configs{
+				OTelEndpointConfig: &plannerpb.Configs_OTelEndpointConfig{
+					URL: compileExportURL,
+				},
+				PluginConfig: &plannerpb.Configs_PluginConfig{
+					StartTimeNs: startTime.UnixNano(),
+					EndTimeNs:   endTime.UnixNano(),
+				},
+			},
+		})
+		if err != nil {
+			log.WithError(err).Error("Failed to compile preset script")
+			return status.Error(codes.Internal, "failed to compile preset scripts")
+		}
+		if len(errs) > 0 {
+			msgs := make([]string, len(errs))
+			for i, e := range errs {
+				msgs[i] = fmt.Sprintf("%d:%d: %s", e.Line, e.Column, e.Message)
+			}
+			return status.Errorf(codes.InvalidArgument, "preset script %q failed to compile: %s", p.Name, strings.Join(msgs, "; "))
+		}
+	}
+	return nil
+}
+
+// RegisterPlugin registers a new plugin release.
+func (s *Server) RegisterPlugin(ctx context.Context, req *pluginpb.RegisterPluginRequest) (*pluginpb.RegisterPluginResponse, error) {
+	orgID := utils.UUIDFromProtoOrNil(req.OrgID)
+	if err := authorizePluginOrg(ctx, orgID); err != nil {
+		return nil, err
+	}
+	if err := validateRegisterPluginRequest(req); err != nil {
+		return nil, err
+	}
+	if req.Retention != nil {
+		if err := s.compilePresetScripts(req.Retention.PresetScripts); err != nil {
+			return nil, err
+		}
+	}
+
+	txn, err := s.db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer txn.Rollback()
+
+	if _, err := checkPluginOwner(txn, req.ID, orgID); err != nil {
+		return nil, err
+	}
+
+	var exists bool
+	query := `SELECT EXISTS(SELECT 1 FROM plugin_releases WHERE id=$1 AND version=$2)`
+	if err := txn.QueryRow(query, req.ID, req.Version).Scan(&exists); err != nil {
+		return nil, status.Error(codes.Internal, "Failed to fetch plugin")
+	}
+	if exists {
+		return nil, status.Errorf(codes.AlreadyExists, "plugin %s already has a release %s", req.ID, req.Version)
+	}
+
+	var owner *uuid.UUID
+	if orgID != uuid.Nil {
+		owner = &orgID
+	}
+	query = `INSERT INTO plugin_releases (name, id, description, logo, version, data_retention_enabled, org_id) VALUES ($1, $2, $3, $4, $5, $6, $7)`
+	_, err = txn.Exec(query, req.Name, req.ID, req.Description, req.Logo, req.Version, req.Retention != nil, owner)
+	if err != nil {
+		log.WithError(err).Error("Failed to insert plugin release")
+		return nil, status.Error(codes.Internal, "Failed to register plugin")
+	}
+
+	if r := req.Retention; r != nil {
+		presets := make(PresetScripts, len(r.PresetScripts))
+		for i, p := range r.PresetScripts {
+			presets[i] = &PresetScript{
+				Name:              p.Name,
+				Description:       p.Description,
+				DefaultFrequencyS: p.DefaultFrequencyS,
+				Script:            p.Script,
+				DefaultDisabled:   p.DefaultDisabled,
+			}
+		}
+		query = `INSERT INTO data_retention_plugin_releases (plugin_id, version, configurations, documentation_url, default_export_url, allow_custom_export_url, preset_scripts, allow_insecure_tls) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+		_, err = txn.Exec(query, req.ID, req.Version, Configurations(r.Configurations), r.DocumentationURL,
+			r.DefaultExportURL, r.AllowCustomExportURL, presets, r.AllowInsecureTLS)
+		if err != nil {
+			log.WithError(err).Error("Failed to insert data retention plugin release")
+			return nil, status.Error(codes.Internal, "Failed to register plugin")
+		}
+	}
+
+	if err := txn.Commit(); err != nil {
+		return nil, status.Error(codes.Internal, "Failed to register plugin")
+	}
+	log.WithField("plugin_id", req.ID).WithField("version", req.Version).WithField("org", orgID).Info("Plugin registered")
+	return &pluginpb.RegisterPluginResponse{}, nil
+}
+
+// UpdatePluginRelease updates an existing plugin release.
+func (s *Server) UpdatePluginRelease(ctx context.Context, req *pluginpb.UpdatePluginReleaseRequest) (*pluginpb.UpdatePluginReleaseResponse, error) {
+	orgID := utils.UUIDFromProtoOrNil(req.OrgID)
+	if err := authorizePluginOrg(ctx, orgID); err != nil {
+		return nil, err
+	}
+	if req.Deprecated == nil && req.DeprecationMessage == nil {
+		return nil, status.Error(codes.InvalidArgument, "must specify at least one field to update")
+	}
+
+	txn, err := s.db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer txn.Rollback()
+
+	exists, err := checkPluginOwner(txn, req.ID, orgID)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, status.Error(codes.NotFound, "plugin not found")
+	}
+
+	if req.Deprecated != nil {
+		query := `UPDATE plugin_releases SET deprecated=$1 WHERE id=$2 AND version=$3`
+		res, err := txn.Exec(query, req.Deprecated.Value, req.ID, req.Version)
+		if err != nil {
+			return nil, status.Error(codes.Internal, "Failed to update plugin release")
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return nil, status.Error(codes.NotFound, "plugin release not found")
+		}
+	}
+	if req.DeprecationMessage != nil {
+		query := `UPDATE plugin_releases SET deprecation_message=$1 WHERE id=$2 AND version=$3`
+		res, err := txn.Exec(query, req.DeprecationMessage.Value, req.ID, req.Version)
+		if err != nil {
+			return nil, status.Error(codes.Internal, "Failed to update plugin release")
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return nil, status.Error(codes.NotFound, "plugin release not found")
+		}
+	}
+
+	if err := txn.Commit(); err != nil {
+		return nil, status.Error(codes.Internal, "Failed to update plugin release")
+	}
+	return &pluginpb.UpdatePluginReleaseResponse{}, nil
+}
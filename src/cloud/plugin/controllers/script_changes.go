@@ -0,0 +1,228 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"context"
+	"sort"
+
+	"github.com/gofrs/uuid"
+	"github.com/jmoiron/sqlx"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"px.dev/pixie/src/api/proto/uuidpb"
+	"px.dev/pixie/src/cloud/cron_script/cronscriptpb"
+	"px.dev/pixie/src/cloud/plugin/pluginpb"
+	"px.dev/pixie/src/utils"
+)
+
+// scriptChange is a change made to a single cron script.
+type scriptChange struct {
+	action          pluginpb.RetentionScriptAction
+	clusterIDs      []*uuidpb.UUID
+	previousConfigs string
+	configs         string
+	// The configs on clusters with overrides, keyed by cluster ID.
+	previousClusterConfigs map[string]string
+	clusterConfigs         map[string]string
+	contentsChanged        bool
+	clustersChanged        bool
+	// The order in which the script was first changed.
+	order int
+}
+
+// scriptChangeRecorder is a cron script client which records the changes made to cron scripts,
+// rather than applying them. Reads are forwarded to the underlying client.
+type scriptChangeRecorder struct {
+	cronscriptpb.CronScriptServiceClient
+
+	orgID   *uuidpb.UUID
+	changes map[uuid.UUID]*scriptChange
+	// The scripts as they were before any changes, fetched from the underlying client.
+	original map[uuid.UUID]*cronscriptpb.CronScript
+}
+
+func newScriptChangeRecorder(client cronscriptpb.CronScriptServiceClient, orgID *uuidpb.UUID) *scriptChangeRecorder {
+	return &scriptChangeRecorder{
+		CronScriptServiceClient: client,
+		orgID:                   orgID,
+		changes:                 make(map[uuid.UUID]*scriptChange),
+		original:                make(map[uuid.UUID]*cronscriptpb.CronScript),
+	}
+}
+
+// withCronScriptClient returns a server which manages cron scripts through the given client.
+func (s *Server) withCronScriptClient(client cronscriptpb.CronScriptServiceClient) *Server {
+	return &Server{
+		db:               s.db,
+		dbKey:            s.dbKey,
+		cronScriptClient: client,
+		done:             s.done,
+	}
+}
+
+// change returns the recorded change for the script, starting a new one from the script's current
+// state if this is the first change to it.
+func (r *scriptChangeRecorder) change(ctx context.Context, id uuid.UUID) (*scriptChange, error) {
+	if c, ok := r.changes[id]; ok {
+		return c, nil
+	}
+	resp, err := r.CronScriptServiceClient.GetScript(ctx, &cronscriptpb.GetScriptRequest{
+		ID:    utils.ProtoFromUUID(id),
+		OrgID: r.orgID,
+	})
+	if err != nil {
+		return nil, status.Error(codes.Internal, "Failed to fetch cron script")
+	}
+	r.original[id] = resp.Script
+	c := &scriptChange{
+		action:                 pluginpb.RETENTION_SCRIPT_ACTION_UPDATE,
+		clusterIDs:             resp.Script.ClusterIDs,
+		previousConfigs:        resp.Script.Configs,
+		configs:                resp.Script.Configs,
+		order:                  len(r.changes),
+		previousClusterConfigs: resp.Script.ClusterConfigs,
+		clusterConfigs:         resp.Script.ClusterConfigs,
+	}
+	r.changes[id] = c
+	return c, nil
+}
+
+// CreateScript records the creation of a script, and returns a placeholder ID for it.
+func (r *scriptChangeRecorder) CreateScript(ctx context.Context, req *cronscriptpb.CreateScriptRequest, opts ...grpc.CallOption) (*cronscriptpb.CreateScriptResponse, error) {
+	id := uuid.Must(uuid.NewV4())
+	r.changes[id] = &scriptChange{
+		action:          pluginpb.RETENTION_SCRIPT_ACTION_CREATE,
+		clusterIDs:      req.ClusterIDs,
+		configs:         req.Configs,
+		clusterConfigs:  req.ClusterConfigs,
+		contentsChanged: true,
+		order:           len(r.changes),
+	}
+	return &cronscriptpb.CreateScriptResponse{ID: utils.ProtoFromUUID(id)}, nil
+}
+
+// UpdateScript records an update to a script.
+func (r *scriptChangeRecorder) UpdateScript(ctx context.Context, req *cronscriptpb.UpdateScriptRequest, opts ...grpc.CallOption) (*cronscriptpb.UpdateScriptResponse, error) {
+	id := utils.UUIDFromProtoOrNil(req.ScriptId)
+	c, err := r.change(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if req.Configs != nil {
+		c.configs = req.Configs.Value
+	}
+	if req.ClusterConfigs != nil {
+		c.clusterConfigs = req.ClusterConfigs.Value
+	}
+	if req.ClusterIDs != nil {
+		c.clusterIDs = req.ClusterIDs.Value
+		c.clustersChanged = true
+	}
+	if req.Script != nil && (c.action == pluginpb.RETENTION_SCRIPT_ACTION_CREATE || req.Script.Value != r.original[id].Script) {
+		c.contentsChanged = true
+	}
+	if req.Enabled != nil && !req.Enabled.Value && c.action == pluginpb.RETENTION_SCRIPT_ACTION_UPDATE {
+		c.action = pluginpb.RETENTION_SCRIPT_ACTION_DISABLE
+	}
+	return &cronscriptpb.UpdateScriptResponse{}, nil
+}
+
+// DeleteScript records the deletion of a script.
+func (r *scriptChangeRecorder) DeleteScript(ctx context.Context, req *cronscriptpb.DeleteScriptRequest, opts ...grpc.CallOption) (*cronscriptpb.DeleteScriptResponse, error) {
+	id := utils.UUIDFromProtoOrNil(req.ID)
+	c, err := r.change(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	c.action = pluginpb.RETENTION_SCRIPT_ACTION_DELETE
+	c.configs = ""
+	c.clusterConfigs = nil
+	return &cronscriptpb.DeleteScriptResponse{}, nil
+}
+
+// retentionScriptNames fetches the names of the org's retention scripts for the plugin.
+func retentionScriptNames(txn *sqlx.Tx, orgID uuid.UUID, pluginID string, names map[uuid.UUID]string) error {
+	query := `SELECT script_id, script_name FROM plugin_retention_scripts WHERE org_id=$1 AND plugin_id=$2`
+	rows, err := txn.Queryx(query, orgID, pluginID)
+	if err != nil {
+		return status.Error(codes.Internal, "Failed to fetch scripts")
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id uuid.UUID
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			return status.Error(codes.Internal, "Failed to read scripts")
+		}
+		names[id] = name
+	}
+	return nil
+}
+
+// scriptChanges returns the recorded changes, in the order the scripts were first changed.
+func (r *scriptChangeRecorder) scriptChanges(names map[uuid.UUID]string) []*pluginpb.RetentionScriptChange {
+	ids := make([]uuid.UUID, 0, len(r.changes))
+	for id, c := range r.changes {
+		// Scripts are updated even if nothing changed, such as when the plugin's config is updated
+		// to the same values.
+		if c.action == pluginpb.RETENTION_SCRIPT_ACTION_UPDATE && c.previousConfigs == c.configs && configsEqual(c.previousClusterConfigs, c.clusterConfigs) && !c.contentsChanged && !c.clustersChanged {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return r.changes[ids[i]].order < r.changes[ids[j]].order
+	})
+
+	changes := make([]*pluginpb.RetentionScriptChange, len(ids))
+	for i, id := range ids {
+		c := r.changes[id]
+		changes[i] = &pluginpb.RetentionScriptChange{
+			Action:                 c.action,
+			ScriptName:             names[id],
+			ClusterIDs:             c.clusterIDs,
+			PreviousConfigs:        c.previousConfigs,
+			Configs:                c.configs,
+			ContentsChanged:        c.contentsChanged,
+			PreviousClusterConfigs: c.previousClusterConfigs,
+			ClusterConfigs:         c.clusterConfigs,
+		}
+		// Created scripts only have a placeholder ID.
+		if c.action != pluginpb.RETENTION_SCRIPT_ACTION_CREATE {
+			changes[i].ScriptID = utils.ProtoFromUUID(id)
+		}
+	}
+	return changes
+}
+
+// configsEqual returns whether the configs are the same, treating nil and empty configs as equal.
+func configsEqual(a map[string]string, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if w, ok := b[k]; !ok || v != w {
+			return false
+		}
+	}
+	return true
+}
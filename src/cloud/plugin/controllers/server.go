@@ -36,8 +36,10 @@ import (
 	"gopkg.in/yaml.v2"
 
 	"px.dev/pixie/src/api/proto/uuidpb"
+	"px.dev/pixie/src/carnot/planner/pxlcompiler"
 	"px.dev/pixie/src/cloud/cron_script/cronscriptpb"
 	"px.dev/pixie/src/cloud/plugin/pluginpb"
+	"px.dev/pixie/src/cloud/vzmgr/vzmgrpb"
 	"px.dev/pixie/src/shared/scripts"
 	"px.dev/pixie/src/shared/services/authcontext"
 	"px.dev/pixie/src/shared/services/events"
@@ -50,17 +52,22 @@ type Server struct {
 	dbKey string
 
 	cronScriptClient cronscriptpb.CronScriptServiceClient
+	vzmgrClient      vzmgrpb.VZMgrServiceClient
+
+	compilerMu sync.Mutex
+	compiler   pxlcompiler.Compiler
 
 	done chan struct{}
 	once sync.Once
 }
 
 // New creates a new server.
-func New(db *sqlx.DB, dbKey string, cronScriptClient cronscriptpb.CronScriptServiceClient) *Server {
+func New(db *sqlx.DB, dbKey string, cronScriptClient cronscriptpb.CronScriptServiceClient, vzmgrClient vzmgrpb.VZMgrServiceClient) *Server {
 	return &Server{
 		db:               db,
 		dbKey:            dbKey,
 		cronScriptClient: cronScriptClient,
+		vzmgrClient:      vzmgrClient,
 		done:             make(chan struct{}),
 	}
 }
@@ -91,19 +98,23 @@ type Plugin struct {
 	Logo                 *string `db:"logo"`
 	Version              string  `db:"version"`
 	DataRetentionEnabled bool    `db:"data_retention_enabled" yaml:"dataRetentionEnabled"`
+	// OrgID is the org that registered the plugin. It is unset for plugins available to all orgs.
+	OrgID uuid.NullUUID `db:"org_id" yaml:"-"`
 }
 
-// GetPlugins fetches all of the available, latest plugins.
+// GetPlugins fetches all of the available, latest plugins. Plugins registered by other orgs and
+// deprecated releases are excluded.
 func (s *Server) GetPlugins(ctx context.Context, req *pluginpb.GetPluginsRequest) (*pluginpb.GetPluginsResponse, error) {
-	query := `SELECT t1.name, t1.id, t1.description, t1.logo, t1.version, t1.data_retention_enabled FROM plugin_releases t1
-		JOIN (SELECT id, MAX(version) as version FROM plugin_releases GROUP BY id) t2
-	  	ON t1.id = t2.id AND t1.version = t2.version`
+	query := `SELECT t1.name, t1.id, t1.description, t1.logo, t1.version, t1.data_retention_enabled, t1.org_id FROM plugin_releases t1
+		JOIN (SELECT id, MAX(version) as version FROM plugin_releases WHERE NOT deprecated GROUP BY id) t2
+	  	ON t1.id = t2.id AND t1.version = t2.version
+		WHERE (t1.org_id IS NULL OR t1.org_id=$1)`
 
 	if req.Kind == pluginpb.PLUGIN_KIND_RETENTION {
-		query = fmt.Sprintf("%s %s", query, "WHERE data_retention_enabled='true'")
+		query = fmt.Sprintf("%s %s", query, "AND data_retention_enabled='true'")
 	}
 
-	rows, err := s.db.Queryx(query)
+	rows, err := s.db.Queryx(query, utils.UUIDFromProtoOrNil(req.OrgID))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return &pluginpb.GetPluginsResponse{Plugins: nil}, nil
@@ -131,6 +142,9 @@ func (s *Server) GetPlugins(ctx context.Context, req *pluginpb.GetPluginsRequest
 		if p.Logo != nil {
 			ppb.Logo = *p.Logo
 		}
+		if p.OrgID.Valid {
+			ppb.OrgID = utils.ProtoFromUUID(p.OrgID.UUID)
+		}
 		plugins = append(plugins, ppb)
 	}
 	return &pluginpb.GetPluginsResponse{Plugins: plugins}, nil
@@ -146,12 +160,16 @@ type RetentionPlugin struct {
 	AllowCustomExportURL bool           `db:"allow_custom_export_url" yaml:"allowCustomExportURL"`
 	AllowInsecureTLS     bool           `db:"allow_insecure_tls" yaml:"allowInsecureTLS"`
 	PresetScripts        PresetScripts  `db:"preset_scripts" yaml:"presetScripts"`
+	Deprecated           bool           `db:"deprecated" yaml:"-"`
+	DeprecationMessage   *string        `db:"deprecation_message" yaml:"-"`
 }
 
 // GetRetentionPluginConfig gets the config for a specific plugin release.
 func (s *Server) GetRetentionPluginConfig(ctx context.Context, req *pluginpb.GetRetentionPluginConfigRequest) (*pluginpb.GetRetentionPluginConfigResponse, error) {
-	query := `SELECT plugin_id, version, configurations, preset_scripts, documentation_url, default_export_url, allow_custom_export_url, allow_insecure_tls FROM data_retention_plugin_releases WHERE plugin_id=$1 AND version=$2`
-	rows, err := s.db.Queryx(query, req.ID, req.Version)
+	query := `SELECT d.plugin_id, d.version, d.configurations, d.preset_scripts, d.documentation_url, d.default_export_url, d.allow_custom_export_url, d.allow_insecure_tls, p.deprecated, p.deprecation_message
+		FROM data_retention_plugin_releases d JOIN plugin_releases p ON d.plugin_id = p.id AND d.version = p.version
+		WHERE d.plugin_id=$1 AND d.version=$2 AND (p.org_id IS NULL OR p.org_id=$3)`
+	rows, err := s.db.Queryx(query, req.ID, req.Version, utils.UUIDFromProtoOrNil(req.OrgID))
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "Failed to fetch plugin")
 	}
@@ -168,6 +186,10 @@ func (s *Server) GetRetentionPluginConfig(ctx context.Context, req *pluginpb.Get
 			AllowCustomExportURL: plugin.AllowCustomExportURL,
 			AllowInsecureTLS:     plugin.AllowInsecureTLS,
 			PresetScripts:        []*pluginpb.GetRetentionPluginConfigResponse_PresetScript{},
+			Deprecated:           plugin.Deprecated,
+		}
+		if plugin.DeprecationMessage != nil {
+			ppb.DeprecationMessage = *plugin.DeprecationMessage
 		}
 		if plugin.DocumentationURL != nil {
 			ppb.DocumentationURL = *plugin.DocumentationURL
@@ -182,6 +204,7 @@ func (s *Server) GetRetentionPluginConfig(ctx context.Context, req *pluginpb.Get
 					Description:       p.Description,
 					DefaultFrequencyS: p.DefaultFrequencyS,
 					Script:            p.Script,
+					DefaultDisabled:   p.DefaultDisabled,
 				})
 			}
 		}
@@ -259,6 +282,13 @@ func (s *Server) GetOrgRetentionPluginConfig(ctx context.Context, req *pluginpb.
 			resp.CustomExportUrl = *exportURL
 		}
 
+		rows.Close()
+		overrides, err := s.getClusterOverrides(s.db, orgID, req.PluginID)
+		if err != nil {
+			return nil, err
+		}
+		resp.ClusterOverrides = clusterOverridesToProto(overrides)
+
 		return resp, nil
 	}
 	return nil, status.Error(codes.NotFound, "plugin is not enabled")
@@ -298,7 +328,7 @@ func (s *Server) createPresetScripts(ctx context.Context, txn *sqlx.Tx, orgID uu
 			Description: j.Description,
 			IsPreset:    true,
 			ExportURL:   "",
-		}, j.Script, make([]*uuidpb.UUID, 0), j.DefaultFrequencyS, j.DefaultDisabled || disablePresets)
+		}, j.Script, make([]*uuidpb.UUID, 0), "", j.DefaultFrequencyS, j.DefaultDisabled || disablePresets)
 		if err != nil {
 			return status.Errorf(codes.Internal, "Failed to create preset scripts")
 		}
@@ -356,6 +386,11 @@ func (s *Server) disableOrgRetention(ctx context.Context, txn *sqlx.Tx, orgID uu
 	}
 	rows.Close()
 
+	err = s.deleteClusterOverrides(txn, orgID, pluginID)
+	if err != nil {
+		return err
+	}
+
 	query = `DELETE FROM org_data_retention_plugins WHERE org_id=$1 AND plugin_id=$2`
 	_, err = txn.Exec(query, orgID, pluginID)
 	return err
@@ -423,7 +458,7 @@ func (s *Server) updatePresetScripts(ctx context.Context, txn *sqlx.Tx, orgID uu
 				Description: j.Description,
 				IsPreset:    true,
 				ExportURL:   "",
-			}, j.Script, make([]*uuidpb.UUID, 0), j.DefaultFrequencyS, disablePresets || j.DefaultDisabled)
+			}, j.Script, make([]*uuidpb.UUID, 0), "", j.DefaultFrequencyS, disablePresets || j.DefaultDisabled)
 			if err != nil {
 				return err
 			}
@@ -446,8 +481,8 @@ func (s *Server) updatePresetScripts(ctx context.Context, txn *sqlx.Tx, orgID uu
 	if err != nil {
 		return err
 	}
-	query = s.db.Rebind(query)
-	rows, err = s.db.Queryx(query, args...)
+	query = txn.Rebind(query)
+	rows, err = txn.Queryx(query, args...)
 	if err != nil {
 		return err
 	}
@@ -456,10 +491,10 @@ func (s *Server) updatePresetScripts(ctx context.Context, txn *sqlx.Tx, orgID uu
 	return nil
 }
 
-func (s *Server) updateOrgRetentionConfigs(ctx context.Context, txn *sqlx.Tx, orgID uuid.UUID, pluginID string, version string, configurations []byte, customExportURL *string, insecureTLS bool) error {
+func (s *Server) updateOrgRetentionConfigs(ctx context.Context, txn *sqlx.Tx, orgID uuid.UUID, pluginID string, version string, configurations []byte, customExportURL *string, insecureTLS bool, overridesChanged bool) error {
 	query := `UPDATE org_data_retention_plugins SET version = $1, configurations = PGP_SYM_ENCRYPT($2, $3), custom_export_url = PGP_SYM_ENCRYPT($6, $3), insecure_tls=$7 WHERE org_id = $4 AND plugin_id = $5`
 
-	err := s.propagateConfigChangesToScripts(ctx, txn, orgID, pluginID, version, configurations, customExportURL, insecureTLS)
+	err := s.propagateConfigChangesToScripts(ctx, txn, orgID, pluginID, version, configurations, customExportURL, insecureTLS, overridesChanged)
 	if err != nil {
 		return err
 	}
@@ -468,7 +503,9 @@ func (s *Server) updateOrgRetentionConfigs(ctx context.Context, txn *sqlx.Tx, or
 	return err
 }
 
-func (s *Server) propagateConfigChangesToScripts(ctx context.Context, txn *sqlx.Tx, orgID uuid.UUID, pluginID string, version string, configurations []byte, customExportURL *string, insecureTLS bool) error {
+// propagateConfigChangesToScripts updates the configs of the plugin's scripts. The configs for
+// clusters with overrides are only sent if there are any, or if the overrides changed.
+func (s *Server) propagateConfigChangesToScripts(ctx context.Context, txn *sqlx.Tx, orgID uuid.UUID, pluginID string, version string, configurations []byte, customExportURL *string, insecureTLS bool, overridesChanged bool) error {
 	// Fetch default export URL for plugin.
 	pluginExportURL, _, _, err := s.getPluginConfigs(txn, orgID, pluginID)
 	if err != nil {
@@ -479,6 +516,11 @@ func (s *Server) propagateConfigChangesToScripts(ctx context.Context, txn *sqlx.
 		pluginExportURL = *customExportURL
 	}
 
+	overrides, err := s.getClusterOverrides(txn, orgID, pluginID)
+	if err != nil {
+		return err
+	}
+
 	// Fetch all scripts belonging to this plugin.
 	query := `SELECT script_id, PGP_SYM_DECRYPT(export_url, $1::text) as export_url from plugin_retention_scripts WHERE org_id=$2 AND plugin_id=$3`
 	rows, err := txn.Queryx(query, s.dbKey, orgID, pluginID)
@@ -525,11 +567,20 @@ func (s *Server) propagateConfigChangesToScripts(ctx context.Context, txn *sqlx.
 			return status.Error(codes.Internal, "failed to marshal configs")
 		}
 
-		_, err = s.cronScriptClient.UpdateScript(ctx, &cronscriptpb.UpdateScriptRequest{
+		clusterConfigs, err := clusterScriptConfigs(overrides, configMap, pluginExportURL, sc.ExportURL, insecureTLS)
+		if err != nil {
+			return err
+		}
+
+		updateReq := &cronscriptpb.UpdateScriptRequest{
 			ScriptId: utils.ProtoFromUUID(sc.ScriptID),
 			Configs:  &types.StringValue{Value: string(mConfig)},
 			OrgID:    utils.ProtoFromUUID(orgID),
-		})
+		}
+		if len(clusterConfigs) > 0 || overridesChanged {
+			updateReq.ClusterConfigs = &cronscriptpb.ClusterConfigs{Value: clusterConfigs}
+		}
+		_, err = s.cronScriptClient.UpdateScript(ctx, updateReq)
 		if err != nil {
 			log.WithError(err).Error("Failed to update cron script")
 			continue
@@ -539,7 +590,8 @@ func (s *Server) propagateConfigChangesToScripts(ctx context.Context, txn *sqlx.
 	return nil
 }
 
-// UpdateOrgRetentionPluginConfig updates an org's configuration for a plugin.
+// UpdateOrgRetentionPluginConfig updates an org's configuration for a plugin. For dry runs, the
+// changes to the org's retention scripts are returned instead of being applied.
 func (s *Server) UpdateOrgRetentionPluginConfig(ctx context.Context, req *pluginpb.UpdateOrgRetentionPluginConfigRequest) (*pluginpb.UpdateOrgRetentionPluginConfigResponse, error) {
 	if utils.IsNilUUIDProto(req.OrgID) {
 		return nil, status.Error(codes.InvalidArgument, "Must specify OrgID")
@@ -553,6 +605,57 @@ func (s *Server) UpdateOrgRetentionPluginConfig(ctx context.Context, req *plugin
 		return nil, status.Error(codes.InvalidArgument, "Must specify plugin version when enabling")
 	}
 
+	orgID := utils.UUIDFromProtoOrNil(req.OrgID)
+
+	txn, err := s.db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer txn.Rollback()
+
+	ctx, err = contextWithAuthToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if !req.DryRun {
+		track, err := s.updateOrgRetentionPluginConfig(ctx, txn, req)
+		if err != nil {
+			return nil, err
+		}
+		err = txn.Commit()
+		if err != nil {
+			return nil, err
+		}
+		if track != nil {
+			events.Client().Enqueue(track)
+		}
+		return &pluginpb.UpdateOrgRetentionPluginConfigResponse{}, nil
+	}
+
+	// Record the changes to the cron scripts instead of making them, and never commit the
+	// transaction.
+	names := make(map[uuid.UUID]string)
+	err = retentionScriptNames(txn, orgID, req.PluginID, names)
+	if err != nil {
+		return nil, err
+	}
+	recorder := newScriptChangeRecorder(s.cronScriptClient, req.OrgID)
+	_, err = s.withCronScriptClient(recorder).updateOrgRetentionPluginConfig(ctx, txn, req)
+	if err != nil {
+		return nil, err
+	}
+	// Scripts created by the update are only named once they've been added.
+	err = retentionScriptNames(txn, orgID, req.PluginID, names)
+	if err != nil {
+		return nil, err
+	}
+	return &pluginpb.UpdateOrgRetentionPluginConfigResponse{Changes: recorder.scriptChanges(names)}, nil
+}
+
+// updateOrgRetentionPluginConfig updates an org's configuration for a plugin within the
+// transaction. It returns the event to track once the transaction is committed, if any.
+func (s *Server) updateOrgRetentionPluginConfig(ctx context.Context, txn *sqlx.Tx, req *pluginpb.UpdateOrgRetentionPluginConfigRequest) (*analytics.Track, error) {
 	var configurations []byte
 	var version string
 
@@ -564,12 +667,6 @@ func (s *Server) UpdateOrgRetentionPluginConfig(ctx context.Context, req *plugin
 		configurations, _ = json.Marshal(req.Configurations)
 	}
 
-	txn, err := s.db.Beginx()
-	if err != nil {
-		return nil, err
-	}
-	defer txn.Rollback()
-
 	// Fetch current configs.
 	query := `SELECT version, PGP_SYM_DECRYPT(configurations, $1::text), PGP_SYM_DECRYPT(custom_export_url, $1::text), insecure_tls FROM org_data_retention_plugins WHERE org_id=$2 AND plugin_id=$3`
 	rows, err := txn.Queryx(query, s.dbKey, orgID, req.PluginID)
@@ -596,22 +693,40 @@ func (s *Server) UpdateOrgRetentionPluginConfig(ctx context.Context, req *plugin
 		version = origVersion
 	}
 
-	query = `SELECT allow_custom_export_url, allow_insecure_tls FROM data_retention_plugin_releases WHERE plugin_id=$1 AND version=$2`
-	rows, err = txn.Queryx(query, req.PluginID, version)
+	query = `SELECT d.configurations, d.allow_custom_export_url, d.allow_insecure_tls, p.deprecated FROM data_retention_plugin_releases d
+		JOIN plugin_releases p ON d.plugin_id = p.id AND d.version = p.version
+		WHERE d.plugin_id=$1 AND d.version=$2 AND (p.org_id IS NULL OR p.org_id=$3)`
+	rows, err = txn.Queryx(query, req.PluginID, version, orgID)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "Failed to fetch plugin")
 	}
 	defer rows.Close()
+	var pluginConfigs Configurations
 	var allowCustomExportURL bool
 	var allowInsecureTLS bool
+	var deprecated bool
+	releaseFound := false
 	if rows.Next() {
-		err := rows.Scan(&allowCustomExportURL, &allowInsecureTLS)
+		releaseFound = true
+		err := rows.Scan(&pluginConfigs, &allowCustomExportURL, &allowInsecureTLS, &deprecated)
 		if err != nil {
 			return nil, status.Error(codes.Internal, "failed to read plugin")
 		}
 	}
 	rows.Close()
 
+	// Orgs can only move to a release which is available to them, and hasn't been deprecated.
+	enabling := !enabled && req.Enabled != nil && req.Enabled.Value
+	updating := enabled && origVersion != version && (req.Enabled == nil || req.Enabled.Value)
+	if enabling || updating {
+		if !releaseFound {
+			return nil, status.Error(codes.NotFound, "plugin release not found")
+		}
+		if deprecated {
+			return nil, status.Error(codes.FailedPrecondition, "plugin release is deprecated")
+		}
+	}
+
 	if req.CustomExportUrl != nil && allowCustomExportURL {
 		customExportURL = &req.CustomExportUrl.Value
 	} else if !allowCustomExportURL {
@@ -624,9 +739,19 @@ func (s *Server) UpdateOrgRetentionPluginConfig(ctx context.Context, req *plugin
 		insecureTLS = false
 	}
 
-	ctx, err = contextWithAuthToken(ctx)
-	if err != nil {
-		return nil, err
+	// Cluster overrides must be in place before any scripts are created or updated, so that they
+	// are applied to the scripts.
+	overridesChanged := false
+	if enabled || enabling {
+		if req.ClusterOverrides != nil {
+			overridesChanged = true
+			err = s.setClusterOverrides(ctx, txn, orgID, req.PluginID, req.ClusterOverrides.Value, pluginConfigs, allowCustomExportURL)
+		} else if !allowCustomExportURL {
+			err = s.clearClusterOverrideExportURLs(txn, orgID, req.PluginID)
+		}
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	if !enabled && req.Enabled != nil && req.Enabled.Value { // Plugin was just enabled, we should create it.
@@ -640,43 +765,40 @@ func (s *Server) UpdateOrgRetentionPluginConfig(ctx context.Context, req *plugin
 			return nil, err
 		}
 
-		log.WithField("plugin_id", req.PluginID).WithField("version", req.Version).WithField("org", orgID).Info("Plugin enabled")
+		log.WithField("plugin_id", req.PluginID).WithField("version", req.Version).WithField("org", orgID).WithField("dry_run", req.DryRun).Info("Plugin enabled")
 
 		// Track enable event.
-		events.Client().Enqueue(&analytics.Track{
+		return &analytics.Track{
 			UserId: orgID.String(),
 			Event:  events.PluginEnabled,
 			Properties: analytics.NewProperties().
 				Set("plugin_id", req.PluginID).
 				Set("version", req.Version),
-		})
-
-		return &pluginpb.UpdateOrgRetentionPluginConfigResponse{}, txn.Commit()
+		}, nil
 	} else if enabled && req.Enabled != nil && !req.Enabled.Value { // Plugin was disabled, we should delete it.
 		err = s.disableOrgRetention(ctx, txn, orgID, req.PluginID)
 		if err != nil {
 			return nil, err
 		}
-		log.WithField("plugin_id", req.PluginID).WithField("org", orgID).Info("Plugin disabled")
+		log.WithField("plugin_id", req.PluginID).WithField("org", orgID).WithField("dry_run", req.DryRun).Info("Plugin disabled")
 
 		// Track disable event.
-		events.Client().Enqueue(&analytics.Track{
+		return &analytics.Track{
 			UserId: orgID.String(),
 			Event:  events.PluginDisabled,
 			Properties: analytics.NewProperties().
 				Set("plugin_id", req.PluginID),
-		})
-		return &pluginpb.UpdateOrgRetentionPluginConfigResponse{}, txn.Commit()
+		}, nil
 	} else if !enabled && req.Enabled != nil && !req.Enabled.Value {
 		// This is already disabled.
-		return &pluginpb.UpdateOrgRetentionPluginConfigResponse{}, nil
+		return nil, nil
 	}
 
 	if configurations == nil {
 		configurations = origConfig
 	}
 
-	err = s.updateOrgRetentionConfigs(ctx, txn, orgID, req.PluginID, version, configurations, customExportURL, insecureTLS)
+	err = s.updateOrgRetentionConfigs(ctx, txn, orgID, req.PluginID, version, configurations, customExportURL, insecureTLS, overridesChanged)
 	if err != nil {
 		return nil, status.Error(codes.Internal, "failed to update configs")
 	}
@@ -688,12 +810,7 @@ func (s *Server) UpdateOrgRetentionPluginConfig(ctx context.Context, req *plugin
 		}
 	}
 
-	err = txn.Commit()
-	if err != nil {
-		return nil, err
-	}
-
-	return &pluginpb.UpdateOrgRetentionPluginConfigResponse{}, nil
+	return nil, nil
 }
 
 // RetentionScript represents a retention script in the plugin system.
@@ -757,6 +874,7 @@ func (s *Server) GetRetentionScripts(ctx context.Context, req *pluginpb.GetReten
 			v.FrequencyS = c.FrequencyS
 			v.Enabled = c.Enabled
 			v.ClusterIDs = c.ClusterIDs
+			v.ClusterSelector = c.ClusterSelector
 		}
 	}
 
@@ -800,25 +918,42 @@ func (s *Server) GetRetentionScript(ctx context.Context, req *pluginpb.GetRetent
 	}
 	cronScript := cronScriptResp.Script
 
+	// Overrides are deleted when the plugin is disabled, so the plugin's configs are only needed if
+	// there are any.
+	overrides, err := s.getClusterOverrides(s.db, orgID, script.PluginID)
+	if err != nil {
+		return nil, err
+	}
+	var clusterConfigs []*pluginpb.ClusterScriptConfig
+	if len(overrides) > 0 {
+		pluginExportURL, configMap, _, err := s.getPluginConfigs(s.db, orgID, script.PluginID)
+		if err != nil {
+			return nil, err
+		}
+		clusterConfigs = clusterScriptConfigsToProto(overrides, cronScript.ClusterIDs, configMap, pluginExportURL, script.ExportURL)
+	}
+
 	return &pluginpb.GetRetentionScriptResponse{
 		Script: &pluginpb.DetailedRetentionScript{
 			Script: &pluginpb.RetentionScript{
-				ScriptID:    req.ScriptID,
-				ScriptName:  script.ScriptName,
-				Description: script.Description,
-				FrequencyS:  cronScript.FrequencyS,
-				ClusterIDs:  cronScript.ClusterIDs,
-				PluginId:    script.PluginID,
-				Enabled:     cronScript.Enabled,
-				IsPreset:    script.IsPreset,
+				ScriptID:        req.ScriptID,
+				ScriptName:      script.ScriptName,
+				Description:     script.Description,
+				FrequencyS:      cronScript.FrequencyS,
+				ClusterIDs:      cronScript.ClusterIDs,
+				PluginId:        script.PluginID,
+				Enabled:         cronScript.Enabled,
+				IsPreset:        script.IsPreset,
+				ClusterSelector: cronScript.ClusterSelector,
 			},
-			Contents:  cronScript.Script,
-			ExportURL: script.ExportURL,
+			Contents:       cronScript.Script,
+			ExportURL:      script.ExportURL,
+			ClusterConfigs: clusterConfigs,
 		},
 	}, nil
 }
 
-func (s *Server) createRetentionScript(ctx context.Context, txn *sqlx.Tx, orgID uuid.UUID, pluginID string, rs *RetentionScript, contents string, clusterIDs []*uuidpb.UUID, frequencyS int64, disabled bool) (*uuidpb.UUID, error) {
+func (s *Server) createRetentionScript(ctx context.Context, txn *sqlx.Tx, orgID uuid.UUID, pluginID string, rs *RetentionScript, contents string, clusterIDs []*uuidpb.UUID, clusterSelector string, frequencyS int64, disabled bool) (*uuidpb.UUID, error) {
 	pluginExportURL, configMap, insecureTLS, err := s.getPluginConfigs(txn, orgID, pluginID)
 	if err != nil {
 		return nil, err
@@ -833,13 +968,25 @@ func (s *Server) createRetentionScript(ctx context.Context, txn *sqlx.Tx, orgID
 	if err != nil {
 		return nil, err
 	}
+
+	overrides, err := s.getClusterOverrides(txn, orgID, pluginID)
+	if err != nil {
+		return nil, err
+	}
+	clusterConfigs, err := clusterScriptConfigs(overrides, configMap, pluginExportURL, rs.ExportURL, insecureTLS)
+	if err != nil {
+		return nil, err
+	}
+
 	cronScriptResp, err := s.cronScriptClient.CreateScript(ctx, &cronscriptpb.CreateScriptRequest{
-		Script:     contents,
-		ClusterIDs: clusterIDs,
-		Configs:    configYAML,
-		FrequencyS: frequencyS,
-		Disabled:   disabled,
-		OrgID:      utils.ProtoFromUUID(orgID),
+		Script:          contents,
+		ClusterIDs:      clusterIDs,
+		Configs:         configYAML,
+		FrequencyS:      frequencyS,
+		Disabled:        disabled,
+		OrgID:           utils.ProtoFromUUID(orgID),
+		ClusterConfigs:  clusterConfigs,
+		ClusterSelector: clusterSelector,
 	})
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "Failed to create cron script")
@@ -885,7 +1032,7 @@ func (s *Server) CreateRetentionScript(ctx context.Context, req *pluginpb.Create
 		Description: req.Script.Script.Description,
 		IsPreset:    req.Script.Script.IsPreset,
 		ExportURL:   req.Script.ExportURL,
-	}, req.Script.Contents, req.Script.Script.ClusterIDs, req.Script.Script.FrequencyS, !req.Script.Script.Enabled)
+	}, req.Script.Contents, req.Script.Script.ClusterIDs, req.Script.Script.ClusterSelector, req.Script.Script.FrequencyS, !req.Script.Script.Enabled)
 	if err != nil {
 		return nil, err
 	}
@@ -910,9 +1057,9 @@ func (s *Server) CreateRetentionScript(ctx context.Context, req *pluginpb.Create
 	}, nil
 }
 
-func (s *Server) getPluginConfigs(txn *sqlx.Tx, orgID uuid.UUID, pluginID string) (string, map[string]string, bool, error) {
+func (s *Server) getPluginConfigs(q sqlx.Queryer, orgID uuid.UUID, pluginID string) (string, map[string]string, bool, error) {
 	query := `SELECT PGP_SYM_DECRYPT(o.configurations, $1::text), r.default_export_url, PGP_SYM_DECRYPT(o.custom_export_url, $1::text), insecure_tls FROM org_data_retention_plugins o, data_retention_plugin_releases r WHERE org_id=$2 AND r.plugin_id=$3 AND o.plugin_id=r.plugin_id AND r.version = o.version`
-	rows, err := txn.Queryx(query, s.dbKey, orgID, pluginID)
+	rows, err := q.Queryx(query, s.dbKey, orgID, pluginID)
 	if err != nil {
 		return "", nil, false, status.Errorf(codes.Internal, "failed to fetch plugin")
 	}
@@ -1007,6 +1154,10 @@ func (s *Server) UpdateRetentionScript(ctx context.Context, req *pluginpb.Update
 	if err != nil {
 		return nil, err
 	}
+	overrides, err := s.getClusterOverrides(txn, script.OrgID, script.PluginID)
+	if err != nil {
+		return nil, err
+	}
 	scriptName := script.ScriptName
 	description := script.Description
 	exportURL := script.ExportURL
@@ -1042,17 +1193,26 @@ func (s *Server) UpdateRetentionScript(ctx context.Context, req *pluginpb.Update
 	if err != nil {
 		return nil, err
 	}
+	clusterConfigs, err := clusterScriptConfigs(overrides, configMap, pluginExportURL, exportURL, insecureTLS)
+	if err != nil {
+		return nil, err
+	}
 
 	// Update cron script.
-	_, err = s.cronScriptClient.UpdateScript(ctx, &cronscriptpb.UpdateScriptRequest{
-		Script:     req.Contents,
-		ClusterIDs: &cronscriptpb.ClusterIDs{Value: req.ClusterIDs},
-		Enabled:    req.Enabled,
-		FrequencyS: req.FrequencyS,
-		ScriptId:   req.ScriptID,
-		Configs:    &types.StringValue{Value: configYAML},
-		OrgID:      utils.ProtoFromUUIDStrOrNil(claimsOrgIDstr),
-	})
+	updateReq := &cronscriptpb.UpdateScriptRequest{
+		Script:          req.Contents,
+		ClusterIDs:      &cronscriptpb.ClusterIDs{Value: req.ClusterIDs},
+		Enabled:         req.Enabled,
+		FrequencyS:      req.FrequencyS,
+		ScriptId:        req.ScriptID,
+		Configs:         &types.StringValue{Value: configYAML},
+		OrgID:           utils.ProtoFromUUIDStrOrNil(claimsOrgIDstr),
+		ClusterSelector: req.ClusterSelector,
+	}
+	if len(clusterConfigs) > 0 {
+		updateReq.ClusterConfigs = &cronscriptpb.ClusterConfigs{Value: clusterConfigs}
+	}
+	_, err = s.cronScriptClient.UpdateScript(ctx, updateReq)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "Failed to update cron script")
 	}
@@ -1072,6 +1232,7 @@ func (s *Server) UpdateRetentionScript(ctx context.Context, req *pluginpb.Update
 	}
 	changedScript := req.Contents != nil
 	changedExportURL := req.ExportUrl != nil
+	changedClusterSelector := req.ClusterSelector != nil
 
 	clusterIDStrs := make([]string, len(req.ClusterIDs))
 	for i, c := range req.ClusterIDs {
@@ -1090,6 +1251,7 @@ func (s *Server) UpdateRetentionScript(ctx context.Context, req *pluginpb.Update
 			Set("changed_enabled", changedEnabled).
 			Set("changed_script", changedScript).
 			Set("changed_export_url", changedExportURL).
+			Set("changed_cluster_selector", changedClusterSelector).
 			Set("enabled", enabled).
 			Set("frequency_s", freq),
 	})
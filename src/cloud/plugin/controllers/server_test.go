@@ -23,6 +23,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"sync"
 	"testing"
 	"time"
 
@@ -34,14 +35,20 @@ import (
 	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"gopkg.in/yaml.v2"
 
 	"px.dev/pixie/src/api/proto/uuidpb"
+	"px.dev/pixie/src/carnot/planner/plannerpb"
+	"px.dev/pixie/src/carnot/planner/pxlcompiler"
 	"px.dev/pixie/src/cloud/cron_script/cronscriptpb"
 	mock_cronscriptpb "px.dev/pixie/src/cloud/cron_script/cronscriptpb/mock"
 	"px.dev/pixie/src/cloud/plugin/controllers"
 	"px.dev/pixie/src/cloud/plugin/pluginpb"
 	"px.dev/pixie/src/cloud/plugin/schema"
+	"px.dev/pixie/src/cloud/vzmgr/vzmgrpb"
+	mock_vzmgrpb "px.dev/pixie/src/cloud/vzmgr/vzmgrpb/mock"
 	"px.dev/pixie/src/shared/scripts"
 	"px.dev/pixie/src/shared/services/authcontext"
 	"px.dev/pixie/src/shared/services/pgtest"
@@ -84,6 +91,7 @@ func createTestContext() context.Context {
 }
 
 func mustLoadTestData(db *sqlx.DB) {
+	db.MustExec(`DELETE FROM org_data_retention_plugin_cluster_overrides`)
 	db.MustExec(`DELETE FROM plugin_retention_scripts`)
 	db.MustExec(`DELETE FROM org_data_retention_plugins`)
 	db.MustExec(`DELETE FROM data_retention_plugin_releases`)
@@ -174,7 +182,7 @@ func TestServer_GetPlugins(t *testing.T) {
 	defer ctrl.Finish()
 	mockCSClient := mock_cronscriptpb.NewMockCronScriptServiceClient(ctrl)
 
-	s := controllers.New(db, "test", mockCSClient)
+	s := controllers.New(db, "test", mockCSClient, nil)
 	resp, err := s.GetPlugins(createTestContext(), &pluginpb.GetPluginsRequest{})
 	require.NoError(t, err)
 	require.NotNil(t, resp)
@@ -207,7 +215,7 @@ func TestServer_GetPluginsWithKind(t *testing.T) {
 	defer ctrl.Finish()
 	mockCSClient := mock_cronscriptpb.NewMockCronScriptServiceClient(ctrl)
 
-	s := controllers.New(db, "test", mockCSClient)
+	s := controllers.New(db, "test", mockCSClient, nil)
 	resp, err := s.GetPlugins(createTestContext(), &pluginpb.GetPluginsRequest{Kind: pluginpb.PLUGIN_KIND_RETENTION})
 	require.NoError(t, err)
 	require.NotNil(t, resp)
@@ -232,7 +240,7 @@ func TestServer_GetRetentionPluginConfig(t *testing.T) {
 	defer ctrl.Finish()
 	mockCSClient := mock_cronscriptpb.NewMockCronScriptServiceClient(ctrl)
 
-	s := controllers.New(db, "test", mockCSClient)
+	s := controllers.New(db, "test", mockCSClient, nil)
 	resp, err := s.GetRetentionPluginConfig(createTestContext(), &pluginpb.GetRetentionPluginConfigRequest{
 		ID:      "test-plugin",
 		Version: "0.0.2",
@@ -272,7 +280,7 @@ func TestServer_GetRetentionPluginsForOrg(t *testing.T) {
 	defer ctrl.Finish()
 	mockCSClient := mock_cronscriptpb.NewMockCronScriptServiceClient(ctrl)
 
-	s := controllers.New(db, "test", mockCSClient)
+	s := controllers.New(db, "test", mockCSClient, nil)
 	resp, err := s.GetRetentionPluginsForOrg(createTestContext(), &pluginpb.GetRetentionPluginsForOrgRequest{
 		OrgID: utils.ProtoFromUUIDStrOrNil("223e4567-e89b-12d3-a456-426655440001"),
 	})
@@ -300,7 +308,7 @@ func TestServer_GetOrgRetentionPluginConfig(t *testing.T) {
 	defer ctrl.Finish()
 	mockCSClient := mock_cronscriptpb.NewMockCronScriptServiceClient(ctrl)
 
-	s := controllers.New(db, "test", mockCSClient)
+	s := controllers.New(db, "test", mockCSClient, nil)
 	resp, err := s.GetOrgRetentionPluginConfig(createTestContext(), &pluginpb.GetOrgRetentionPluginConfigRequest{
 		PluginID: "test-plugin",
 		OrgID:    utils.ProtoFromUUIDStrOrNil("223e4567-e89b-12d3-a456-426655440001"),
@@ -1101,7 +1109,7 @@ func TestServer_UpdateRetentionConfigs(t *testing.T) {
 				return &cronscriptpb.DeleteScriptResponse{}, nil
 			}).AnyTimes()
 
-			s := controllers.New(db, "test", mockCSClient)
+			s := controllers.New(db, "test", mockCSClient, nil)
 
 			resp, err := s.UpdateOrgRetentionPluginConfig(createTestContext(), test.request)
 
@@ -1201,7 +1209,7 @@ func TestServer_GetRetentionScripts(t *testing.T) {
 		},
 	}, nil)
 
-	s := controllers.New(db, "test", mockCSClient)
+	s := controllers.New(db, "test", mockCSClient, nil)
 	resp, err := s.GetRetentionScripts(createTestContext(), &pluginpb.GetRetentionScriptsRequest{
 		OrgID: utils.ProtoFromUUIDStrOrNil("223e4567-e89b-12d3-a456-426655440000"),
 	})
@@ -1260,7 +1268,7 @@ func TestServer_GetRetentionScript(t *testing.T) {
 		},
 	}, nil)
 
-	s := controllers.New(db, "test", mockCSClient)
+	s := controllers.New(db, "test", mockCSClient, nil)
 	resp, err := s.GetRetentionScript(createTestContext(), &pluginpb.GetRetentionScriptRequest{
 		OrgID:    utils.ProtoFromUUIDStrOrNil("223e4567-e89b-12d3-a456-426655440000"),
 		ScriptID: utils.ProtoFromUUIDStrOrNil("123e4567-e89b-12d3-a456-426655440000"),
@@ -1323,7 +1331,7 @@ func TestServer_CreateRetentionScript(t *testing.T) {
 		ID: utils.ProtoFromUUIDStrOrNil("323e4567-e89b-12d3-a456-426655440000"),
 	}, nil)
 
-	s := controllers.New(db, "test", mockCSClient)
+	s := controllers.New(db, "test", mockCSClient, nil)
 	resp, err := s.CreateRetentionScript(createTestContext(), &pluginpb.CreateRetentionScriptRequest{
 		Script: &pluginpb.DetailedRetentionScript{
 			Script: &pluginpb.RetentionScript{
@@ -1398,7 +1406,7 @@ func TestServer_CreateRetentionScriptNameDuplicatesAllowed(t *testing.T) {
 		ID: utils.ProtoFromUUIDStrOrNil("323e4567-e89b-12d3-a456-426655440000"),
 	}, nil)
 
-	s := controllers.New(db, "test", mockCSClient)
+	s := controllers.New(db, "test", mockCSClient, nil)
 	_, err = s.CreateRetentionScript(createTestContext(), &pluginpb.CreateRetentionScriptRequest{
 		Script: &pluginpb.DetailedRetentionScript{
 			Script: &pluginpb.RetentionScript{
@@ -1489,7 +1497,7 @@ func TestServer_UpdateRetentionScript(t *testing.T) {
 		OrgID:      utils.ProtoFromUUIDStrOrNil("223e4567-e89b-12d3-a456-426655440000"),
 	})
 
-	s := controllers.New(db, "test", mockCSClient)
+	s := controllers.New(db, "test", mockCSClient, nil)
 	resp, err := s.UpdateRetentionScript(createTestContext(), &pluginpb.UpdateRetentionScriptRequest{
 		ScriptID:   utils.ProtoFromUUIDStrOrNil("123e4567-e89b-12d3-a456-426655440000"),
 		ScriptName: &types.StringValue{Value: "Updated Script"},
@@ -1533,7 +1541,7 @@ func TestServer_DeleteRetentionScript(t *testing.T) {
 		OrgID: utils.ProtoFromUUIDStrOrNil("223e4567-e89b-12d3-a456-426655440000"),
 	}).Return(&cronscriptpb.DeleteScriptResponse{}, nil)
 
-	s := controllers.New(db, "test", mockCSClient)
+	s := controllers.New(db, "test", mockCSClient, nil)
 	resp, err := s.DeleteRetentionScript(createTestContext(), &pluginpb.DeleteRetentionScriptRequest{
 		ID:    utils.ProtoFromUUIDStrOrNil("123e4567-e89b-12d3-a456-426655440000"),
 		OrgID: utils.ProtoFromUUIDStrOrNil("223e4567-e89b-12d3-a456-426655440000"),
@@ -1555,7 +1563,7 @@ func TestServer_DeletePresetRetentionScript(t *testing.T) {
 	defer ctrl.Finish()
 	mockCSClient := mock_cronscriptpb.NewMockCronScriptServiceClient(ctrl)
 
-	s := controllers.New(db, "test", mockCSClient)
+	s := controllers.New(db, "test", mockCSClient, nil)
 	_, err := s.DeleteRetentionScript(createTestContext(), &pluginpb.DeleteRetentionScriptRequest{
 		ID:    utils.ProtoFromUUIDStrOrNil("123e4567-e89b-12d3-a456-426655440001"),
 		OrgID: utils.ProtoFromUUIDStrOrNil("223e4567-e89b-12d3-a456-426655440000"),
@@ -1568,3 +1576,612 @@ func TestServer_DeletePresetRetentionScript(t *testing.T) {
 	require.Nil(t, err)
 	require.True(t, rows.Next())
 }
+
+func createTestServiceContext() context.Context {
+	sCtx := authcontext.New()
+	sCtx.Claims = srvutils.GenerateJWTForService("PluginLoader", "pixie")
+	return authcontext.NewContext(context.Background(), sCtx)
+}
+
+type fakeCompiler struct {
+	errs []*pxlcompiler.CompileError
+}
+
+func (f *fakeCompiler) Compile(req *plannerpb.QueryRequest) ([]*pxlcompiler.CompileError, error) {
+	return f.errs, nil
+}
+
+func TestServer_RegisterPlugin(t *testing.T) {
+	validRetention := func() *pluginpb.RetentionPluginRelease {
+		return &pluginpb.RetentionPluginRelease{
+			Configurations:   map[string]string{"api-key": "The API key"},
+			DocumentationURL: "https://docs",
+			DefaultExportURL: "https://export:443",
+			PresetScripts: []*pluginpb.GetRetentionPluginConfigResponse_PresetScript{
+				{
+					Name:              "http data",
+					Description:       "Exports http data",
+					DefaultFrequencyS: 10,
+					Script:            "px.export(df, px.otel.Data())",
+					DefaultDisabled:   true,
+				},
+			},
+		}
+	}
+
+	tests := []struct {
+		name         string
+		ctx          context.Context
+		req          func() *pluginpb.RegisterPluginRequest
+		compileErrs  []*pxlcompiler.CompileError
+		expectedCode codes.Code
+	}{
+		{
+			name: "org plugin",
+			ctx:  createTestContext(),
+			req: func() *pluginpb.RegisterPluginRequest {
+				return &pluginpb.RegisterPluginRequest{
+					OrgID:     utils.ProtoFromUUIDStrOrNil("223e4567-e89b-12d3-a456-426655440000"),
+					Name:      "Org Plugin",
+					ID:        "org-plugin",
+					Version:   "1.0.0",
+					Retention: validRetention(),
+				}
+			},
+			expectedCode: codes.OK,
+		},
+		{
+			name: "global plugin from service",
+			ctx:  createTestServiceContext(),
+			req: func() *pluginpb.RegisterPluginRequest {
+				return &pluginpb.RegisterPluginRequest{
+					Name:      "Global Plugin",
+					ID:        "global-plugin",
+					Version:   "1.0.0",
+					Retention: validRetention(),
+				}
+			},
+			expectedCode: codes.OK,
+		},
+		{
+			name: "global plugin from user",
+			ctx:  createTestContext(),
+			req: func() *pluginpb.RegisterPluginRequest {
+				return &pluginpb.RegisterPluginRequest{
+					Name:    "Global Plugin",
+					ID:      "global-plugin",
+					Version: "1.0.0",
+				}
+			},
+			expectedCode: codes.PermissionDenied,
+		},
+		{
+			name: "another org",
+			ctx:  createTestContext(),
+			req: func() *pluginpb.RegisterPluginRequest {
+				return &pluginpb.RegisterPluginRequest{
+					OrgID:   utils.ProtoFromUUIDStrOrNil("223e4567-e89b-12d3-a456-426655440001"),
+					Name:    "Org Plugin",
+					ID:      "org-plugin",
+					Version: "1.0.0",
+				}
+			},
+			expectedCode: codes.PermissionDenied,
+		},
+		{
+			name: "plugin owned by another org",
+			ctx:  createTestContext(),
+			req: func() *pluginpb.RegisterPluginRequest {
+				return &pluginpb.RegisterPluginRequest{
+					OrgID:   utils.ProtoFromUUIDStrOrNil("223e4567-e89b-12d3-a456-426655440000"),
+					Name:    "test_plugin",
+					ID:      "test-plugin",
+					Version: "1.0.0",
+				}
+			},
+			expectedCode: codes.PermissionDenied,
+		},
+		{
+			name: "existing release",
+			ctx:  createTestServiceContext(),
+			req: func() *pluginpb.RegisterPluginRequest {
+				return &pluginpb.RegisterPluginRequest{
+					Name:    "test_plugin",
+					ID:      "test-plugin",
+					Version: "0.0.3",
+				}
+			},
+			expectedCode: codes.AlreadyExists,
+		},
+		{
+			name: "invalid ID",
+			ctx:  createTestServiceContext(),
+			req: func() *pluginpb.RegisterPluginRequest {
+				return &pluginpb.RegisterPluginRequest{
+					Name:    "Bad Plugin",
+					ID:      "Bad Plugin",
+					Version: "1.0.0",
+				}
+			},
+			expectedCode: codes.InvalidArgument,
+		},
+		{
+			name: "invalid version",
+			ctx:  createTestServiceContext(),
+			req: func() *pluginpb.RegisterPluginRequest {
+				return &pluginpb.RegisterPluginRequest{
+					Name:    "Bad Plugin",
+					ID:      "bad-plugin",
+					Version: "latest",
+				}
+			},
+			expectedCode: codes.InvalidArgument,
+		},
+		{
+			name: "invalid configuration key",
+			ctx:  createTestServiceContext(),
+			req: func() *pluginpb.RegisterPluginRequest {
+				r := validRetention()
+				r.Configurations["api key"] = "The API key"
+				return &pluginpb.RegisterPluginRequest{
+					Name:      "Bad Plugin",
+					ID:        "bad-plugin",
+					Version:   "1.0.0",
+					Retention: r,
+				}
+			},
+			expectedCode: codes.InvalidArgument,
+		},
+		{
+			name: "missing export URL",
+			ctx:  createTestServiceContext(),
+			req: func() *pluginpb.RegisterPluginRequest {
+				r := validRetention()
+				r.DefaultExportURL = ""
+				return &pluginpb.RegisterPluginRequest{
+					Name:      "Bad Plugin",
+					ID:        "bad-plugin",
+					Version:   "1.0.0",
+					Retention: r,
+				}
+			},
+			expectedCode: codes.InvalidArgument,
+		},
+		{
+			name: "preset without export",
+			ctx:  createTestServiceContext(),
+			req: func() *pluginpb.RegisterPluginRequest {
+				r := validRetention()
+				r.PresetScripts[0].Script = "px.display(df)"
+				return &pluginpb.RegisterPluginRequest{
+					Name:      "Bad Plugin",
+					ID:        "bad-plugin",
+					Version:   "1.0.0",
+					Retention: r,
+				}
+			},
+			expectedCode: codes.InvalidArgument,
+		},
+		{
+			name: "preset fails to compile",
+			ctx:  createTestServiceContext(),
+			req: func() *pluginpb.RegisterPluginRequest {
+				return &pluginpb.RegisterPluginRequest{
+					Name:      "Bad Plugin",
+					ID:        "bad-plugin",
+					Version:   "1.0.0",
+					Retention: validRetention(),
+				}
+			},
+			compileErrs:  []*pxlcompiler.CompileError{{Line: 1, Column: 2, Message: "name 'df' is not defined"}},
+			expectedCode: codes.InvalidArgument,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mustLoadTestData(db)
+
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			mockCSClient := mock_cronscriptpb.NewMockCronScriptServiceClient(ctrl)
+
+			s := controllers.New(db, "test", mockCSClient, nil)
+			s.SetScriptCompiler(&fakeCompiler{errs: test.compileErrs})
+
+			req := test.req()
+			_, err := s.RegisterPlugin(test.ctx, req)
+			require.Equal(t, test.expectedCode, status.Code(err))
+			if test.expectedCode != codes.OK {
+				return
+			}
+
+			resp, err := s.GetRetentionPluginConfig(createTestContext(), &pluginpb.GetRetentionPluginConfigRequest{
+				ID:      req.ID,
+				Version: req.Version,
+				OrgID:   utils.ProtoFromUUIDStrOrNil("223e4567-e89b-12d3-a456-426655440000"),
+			})
+			require.NoError(t, err)
+			assert.Equal(t, req.Retention.Configurations, resp.Configurations)
+			assert.Equal(t, req.Retention.DefaultExportURL, resp.DefaultExportURL)
+			assert.Equal(t, req.Retention.PresetScripts, resp.PresetScripts)
+
+			// Plugins registered by an org aren't visible to other orgs.
+			_, err = s.GetRetentionPluginConfig(createTestContext(), &pluginpb.GetRetentionPluginConfigRequest{
+				ID:      req.ID,
+				Version: req.Version,
+				OrgID:   utils.ProtoFromUUIDStrOrNil("223e4567-e89b-12d3-a456-426655440001"),
+			})
+			if req.OrgID != nil {
+				assert.Equal(t, codes.NotFound, status.Code(err))
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestServer_RegisterPlugin_Concurrent(t *testing.T) {
+	mustLoadTestData(db)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockCSClient := mock_cronscriptpb.NewMockCronScriptServiceClient(ctrl)
+
+	s := controllers.New(db, "test", mockCSClient, nil)
+	s.SetScriptCompiler(&fakeCompiler{})
+
+	// An org and the plugin loader register the same new plugin at once. Only one of them may own it.
+	reqs := []struct {
+		ctx context.Context
+		req *pluginpb.RegisterPluginRequest
+	}{
+		{
+			ctx: createTestContext(),
+			req: &pluginpb.RegisterPluginRequest{
+				OrgID:   utils.ProtoFromUUIDStrOrNil("223e4567-e89b-12d3-a456-426655440000"),
+				Name:    "New Plugin",
+				ID:      "new-plugin",
+				Version: "1.0.0",
+			},
+		},
+		{
+			ctx: createTestServiceContext(),
+			req: &pluginpb.RegisterPluginRequest{
+				Name:    "New Plugin",
+				ID:      "new-plugin",
+				Version: "2.0.0",
+			},
+		},
+	}
+
+	codesCh := make(chan codes.Code, len(reqs))
+	var wg sync.WaitGroup
+	for _, r := range reqs {
+		r := r
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := s.RegisterPlugin(r.ctx, r.req)
+			codesCh <- status.Code(err)
+		}()
+	}
+	wg.Wait()
+	close(codesCh)
+
+	var results []codes.Code
+	for c := range codesCh {
+		results = append(results, c)
+	}
+	assert.ElementsMatch(t, []codes.Code{codes.OK, codes.PermissionDenied}, results)
+
+	var owners int
+	require.NoError(t, db.Get(&owners, `SELECT COUNT(DISTINCT COALESCE(org_id::text, '')) FROM plugin_releases WHERE id='new-plugin'`))
+	assert.Equal(t, 1, owners)
+}
+
+func TestServer_UpdatePluginRelease(t *testing.T) {
+	mustLoadTestData(db)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockCSClient := mock_cronscriptpb.NewMockCronScriptServiceClient(ctrl)
+
+	s := controllers.New(db, "test", mockCSClient, nil)
+
+	// Users can't update plugins which are available to all orgs.
+	_, err := s.UpdatePluginRelease(createTestContext(), &pluginpb.UpdatePluginReleaseRequest{
+		ID:         "test-plugin",
+		Version:    "0.0.3",
+		Deprecated: &types.BoolValue{Value: true},
+	})
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = s.UpdatePluginRelease(createTestServiceContext(), &pluginpb.UpdatePluginReleaseRequest{
+		ID:                 "test-plugin",
+		Version:            "0.0.3",
+		Deprecated:         &types.BoolValue{Value: true},
+		DeprecationMessage: &types.StringValue{Value: "Use 0.0.2 instead"},
+	})
+	require.NoError(t, err)
+
+	_, err = s.UpdatePluginRelease(createTestServiceContext(), &pluginpb.UpdatePluginReleaseRequest{
+		ID:         "test-plugin",
+		Version:    "1.0.0",
+		Deprecated: &types.BoolValue{Value: true},
+	})
+	require.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = s.UpdatePluginRelease(createTestServiceContext(), &pluginpb.UpdatePluginReleaseRequest{
+		ID:      "test-plugin",
+		Version: "0.0.3",
+	})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	// The latest release skips deprecated releases.
+	pluginsResp, err := s.GetPlugins(createTestContext(), &pluginpb.GetPluginsRequest{Kind: pluginpb.PLUGIN_KIND_RETENTION})
+	require.NoError(t, err)
+	require.Equal(t, 1, len(pluginsResp.Plugins))
+	assert.Equal(t, "0.0.2", pluginsResp.Plugins[0].LatestVersion)
+
+	configResp, err := s.GetRetentionPluginConfig(createTestContext(), &pluginpb.GetRetentionPluginConfigRequest{
+		ID:      "test-plugin",
+		Version: "0.0.3",
+	})
+	require.NoError(t, err)
+	assert.True(t, configResp.Deprecated)
+	assert.Equal(t, "Use 0.0.2 instead", configResp.DeprecationMessage)
+
+	// Orgs can't move to a deprecated release.
+	_, err = s.UpdateOrgRetentionPluginConfig(createTestContext(), &pluginpb.UpdateOrgRetentionPluginConfigRequest{
+		OrgID:    utils.ProtoFromUUIDStrOrNil("223e4567-e89b-12d3-a456-426655440001"),
+		PluginID: "test-plugin",
+		Version:  &types.StringValue{Value: "0.0.3"},
+	})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+}
+
+func TestServer_UpdateRetentionConfigsDryRun(t *testing.T) {
+	mustLoadTestData(db)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockCSClient := mock_cronscriptpb.NewMockCronScriptServiceClient(ctrl)
+
+	orgID := utils.ProtoFromUUIDStrOrNil("223e4567-e89b-12d3-a456-426655440000")
+	clusterID := utils.ProtoFromUUIDStrOrNil("323e4567-e89b-12d3-a456-426655440000")
+
+	// Only the current scripts are fetched. The scripts must not be updated.
+	mockCSClient.EXPECT().GetScript(gomock.Any(), &cronscriptpb.GetScriptRequest{
+		ID:    utils.ProtoFromUUIDStrOrNil("123e4567-e89b-12d3-a456-426655440000"),
+		OrgID: orgID,
+	}).Return(&cronscriptpb.GetScriptResponse{
+		Script: &cronscriptpb.CronScript{
+			Script:     "px.display()",
+			Configs:    "old config 1",
+			ClusterIDs: []*uuidpb.UUID{clusterID},
+			Enabled:    true,
+		},
+	}, nil)
+	mockCSClient.EXPECT().GetScript(gomock.Any(), &cronscriptpb.GetScriptRequest{
+		ID:    utils.ProtoFromUUIDStrOrNil("123e4567-e89b-12d3-a456-426655440001"),
+		OrgID: orgID,
+	}).Return(&cronscriptpb.GetScriptResponse{
+		Script: &cronscriptpb.CronScript{
+			Script:  "px.display()",
+			Configs: "old config 2",
+			Enabled: true,
+		},
+	}, nil)
+
+	s := controllers.New(db, "test", mockCSClient, nil)
+	resp, err := s.UpdateOrgRetentionPluginConfig(createTestContext(), &pluginpb.UpdateOrgRetentionPluginConfigRequest{
+		OrgID:    orgID,
+		PluginID: "test-plugin",
+		Configurations: map[string]string{
+			"license_key3": "new key",
+		},
+		DryRun: true,
+	})
+	require.NoError(t, err)
+
+	newConfig := func(url string) string {
+		c, _ := yaml.Marshal(&scripts.Config{
+			OtelEndpointConfig: &scripts.OtelEndpointConfig{
+				URL:      url,
+				Headers:  map[string]string{"license_key3": "new key"},
+				Insecure: true,
+			},
+		})
+		return string(c)
+	}
+	assert.ElementsMatch(t, []*pluginpb.RetentionScriptChange{
+		{
+			Action:          pluginpb.RETENTION_SCRIPT_ACTION_UPDATE,
+			ScriptID:        utils.ProtoFromUUIDStrOrNil("123e4567-e89b-12d3-a456-426655440000"),
+			ScriptName:      "testScript",
+			ClusterIDs:      []*uuidpb.UUID{clusterID},
+			PreviousConfigs: "old config 1",
+			Configs:         newConfig("https://localhost:8080"),
+		},
+		{
+			Action:          pluginpb.RETENTION_SCRIPT_ACTION_UPDATE,
+			ScriptID:        utils.ProtoFromUUIDStrOrNil("123e4567-e89b-12d3-a456-426655440001"),
+			ScriptName:      "testScript2",
+			PreviousConfigs: "old config 2",
+			Configs:         newConfig("https://url"),
+		},
+	}, resp.Changes)
+
+	// The update should not have been applied.
+	configResp, err := s.GetOrgRetentionPluginConfig(createTestContext(), &pluginpb.GetOrgRetentionPluginConfigRequest{
+		OrgID:    orgID,
+		PluginID: "test-plugin",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"license_key2": "12345"}, configResp.Configurations)
+}
+
+func TestServer_UpdateRetentionConfigsClusterOverrides(t *testing.T) {
+	mustLoadTestData(db)
+	// A script without its own export URL, which uses the cluster's export URL.
+	db.MustExec(`INSERT INTO plugin_retention_scripts (org_id, plugin_id, script_id, script_name, description, is_preset, export_url) VALUES ($1, $2, $3, $4, $5, $6, PGP_SYM_ENCRYPT($7, $8))`,
+		"223e4567-e89b-12d3-a456-426655440000", "test-plugin", "123e4567-e89b-12d3-a456-426655440007", "testScript5", "This is another script", false, "", "test")
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockCSClient := mock_cronscriptpb.NewMockCronScriptServiceClient(ctrl)
+	mockVZMgr := mock_vzmgrpb.NewMockVZMgrServiceClient(ctrl)
+
+	orgID := utils.ProtoFromUUIDStrOrNil("223e4567-e89b-12d3-a456-426655440000")
+	clusterID := "323e4567-e89b-12d3-a456-426655440000"
+
+	mockVZMgr.EXPECT().GetViziersByOrg(gomock.Any(), orgID).Return(&vzmgrpb.GetViziersByOrgResponse{
+		VizierIDs: []*uuidpb.UUID{utils.ProtoFromUUIDStrOrNil(clusterID)},
+	}, nil)
+
+	config := func(url string, headers map[string]string) string {
+		c, _ := yaml.Marshal(&scripts.Config{
+			OtelEndpointConfig: &scripts.OtelEndpointConfig{
+				URL:      url,
+				Headers:  headers,
+				Insecure: true,
+			},
+		})
+		return string(c)
+	}
+	orgHeaders := map[string]string{"license_key2": "12345"}
+	clusterHeaders := map[string]string{"license_key2": "12345", "license_key3": "override"}
+
+	for _, sc := range []struct {
+		id               string
+		orgExportURL     string
+		clusterExportURL string
+	}{
+		{"123e4567-e89b-12d3-a456-426655440000", "https://localhost:8080", "https://localhost:8080"},
+		{"123e4567-e89b-12d3-a456-426655440001", "https://url", "https://url"},
+		{"123e4567-e89b-12d3-a456-426655440007", "https://localhost1:8080", "https://cluster:443"},
+	} {
+		mockCSClient.EXPECT().UpdateScript(gomock.Any(), &cronscriptpb.UpdateScriptRequest{
+			ScriptId: utils.ProtoFromUUIDStrOrNil(sc.id),
+			Configs:  &types.StringValue{Value: config(sc.orgExportURL, orgHeaders)},
+			OrgID:    orgID,
+			ClusterConfigs: &cronscriptpb.ClusterConfigs{
+				Value: map[string]string{
+					clusterID: config(sc.clusterExportURL, clusterHeaders),
+				},
+			},
+		}).Return(&cronscriptpb.UpdateScriptResponse{}, nil)
+	}
+
+	s := controllers.New(db, "test", mockCSClient, mockVZMgr)
+	_, err := s.UpdateOrgRetentionPluginConfig(createTestContext(), &pluginpb.UpdateOrgRetentionPluginConfigRequest{
+		OrgID:    orgID,
+		PluginID: "test-plugin",
+		ClusterOverrides: &pluginpb.ClusterConfigOverrides{
+			Value: []*pluginpb.ClusterConfigOverride{
+				{
+					ClusterID:       utils.ProtoFromUUIDStrOrNil(clusterID),
+					Configurations:  map[string]string{"license_key3": "override"},
+					CustomExportUrl: "https://cluster:443",
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	configResp, err := s.GetOrgRetentionPluginConfig(createTestContext(), &pluginpb.GetOrgRetentionPluginConfigRequest{
+		OrgID:    orgID,
+		PluginID: "test-plugin",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []*pluginpb.ClusterConfigOverride{
+		{
+			ClusterID:       utils.ProtoFromUUIDStrOrNil(clusterID),
+			Configurations:  map[string]string{"license_key3": "override"},
+			CustomExportUrl: "https://cluster:443",
+		},
+	}, configResp.ClusterOverrides)
+
+	mockCSClient.EXPECT().GetScript(gomock.Any(), &cronscriptpb.GetScriptRequest{
+		ID:    utils.ProtoFromUUIDStrOrNil("123e4567-e89b-12d3-a456-426655440007"),
+		OrgID: orgID,
+	}).Return(&cronscriptpb.GetScriptResponse{
+		Script: &cronscriptpb.CronScript{
+			Script:  "px.display()",
+			Enabled: true,
+		},
+	}, nil)
+
+	scriptResp, err := s.GetRetentionScript(createTestContext(), &pluginpb.GetRetentionScriptRequest{
+		OrgID:    orgID,
+		ScriptID: utils.ProtoFromUUIDStrOrNil("123e4567-e89b-12d3-a456-426655440007"),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []*pluginpb.ClusterScriptConfig{
+		{
+			ClusterID:      utils.ProtoFromUUIDStrOrNil(clusterID),
+			ExportURL:      "https://cluster:443",
+			Configurations: clusterHeaders,
+		},
+	}, scriptResp.Script.ClusterConfigs)
+}
+
+func TestServer_UpdateRetentionConfigsInvalidClusterOverrides(t *testing.T) {
+	orgID := utils.ProtoFromUUIDStrOrNil("223e4567-e89b-12d3-a456-426655440000")
+	clusterID := utils.ProtoFromUUIDStrOrNil("323e4567-e89b-12d3-a456-426655440000")
+
+	tests := []struct {
+		name         string
+		override     *pluginpb.ClusterConfigOverride
+		expectedCode codes.Code
+	}{
+		{
+			name: "cluster in another org",
+			override: &pluginpb.ClusterConfigOverride{
+				ClusterID:      utils.ProtoFromUUIDStrOrNil("323e4567-e89b-12d3-a456-426655440001"),
+				Configurations: map[string]string{"license_key3": "override"},
+			},
+			expectedCode: codes.NotFound,
+		},
+		{
+			name: "unknown configuration",
+			override: &pluginpb.ClusterConfigOverride{
+				ClusterID:      clusterID,
+				Configurations: map[string]string{"license_key": "override"},
+			},
+			expectedCode: codes.InvalidArgument,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mustLoadTestData(db)
+
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			mockCSClient := mock_cronscriptpb.NewMockCronScriptServiceClient(ctrl)
+			mockVZMgr := mock_vzmgrpb.NewMockVZMgrServiceClient(ctrl)
+			mockVZMgr.EXPECT().GetViziersByOrg(gomock.Any(), orgID).Return(&vzmgrpb.GetViziersByOrgResponse{
+				VizierIDs: []*uuidpb.UUID{clusterID},
+			}, nil).AnyTimes()
+
+			s := controllers.New(db, "test", mockCSClient, mockVZMgr)
+			_, err := s.UpdateOrgRetentionPluginConfig(createTestContext(), &pluginpb.UpdateOrgRetentionPluginConfigRequest{
+				OrgID:    orgID,
+				PluginID: "test-plugin",
+				ClusterOverrides: &pluginpb.ClusterConfigOverrides{
+					Value: []*pluginpb.ClusterConfigOverride{test.override},
+				},
+			})
+			assert.Equal(t, test.expectedCode, status.Code(err))
+
+			configResp, err := s.GetOrgRetentionPluginConfig(createTestContext(), &pluginpb.GetOrgRetentionPluginConfigRequest{
+				OrgID:    orgID,
+				PluginID: "test-plugin",
+			})
+			require.NoError(t, err)
+			assert.Empty(t, configResp.ClusterOverrides)
+		})
+	}
+}
@@ -240,9 +240,11 @@ func UpdatePlugins(db *sqlx.DB, pClient pluginpb.DataRetentionPluginServiceClien
 	}
 	defer txn.Rollback()
 
-	// Get the latest version for each available plugin major version.
+	// Get the latest version for each available plugin major version. Deprecated releases are skipped,
+	// since orgs shouldn't be updated to them.
 	latestVersions := make(map[string]map[uint64]semver.Version)
-	query := `SELECT plugin_id, version FROM data_retention_plugin_releases`
+	query := `SELECT d.plugin_id, d.version FROM data_retention_plugin_releases d
+		JOIN plugin_releases p ON d.plugin_id = p.id AND d.version = p.version WHERE NOT p.deprecated`
 	rows, err := txn.Queryx(query)
 	if err != nil {
 		log.WithError(err).Fatal("Failed to fetch plugin releases")
@@ -28,11 +28,13 @@ import (
 	"github.com/spf13/viper"
 	"google.golang.org/grpc"
 
+	"px.dev/pixie/src/carnot/planner/pxlcompiler"
 	"px.dev/pixie/src/cloud/cron_script/cronscriptpb"
 	"px.dev/pixie/src/cloud/plugin/controllers"
 	"px.dev/pixie/src/cloud/plugin/pluginpb"
 	"px.dev/pixie/src/cloud/plugin/schema"
 	"px.dev/pixie/src/cloud/shared/pgmigrate"
+	"px.dev/pixie/src/cloud/vzmgr/vzmgrpb"
 	"px.dev/pixie/src/shared/services"
 	"px.dev/pixie/src/shared/services/env"
 	"px.dev/pixie/src/shared/services/healthz"
@@ -42,6 +44,7 @@ import (
 
 func init() {
 	pflag.String("cron_script_service", "cron-script-service.plc.svc.cluster.local:50700", "The cronscript service url (load balancer/list is ok)")
+	pflag.String("vzmgr_service", "kubernetes:///vzmgr-service.plc:51800", "The vzmgr service url (load balancer/list is ok)")
 }
 
 // NewCronScriptServiceClient creates a new cron script service RPC client stub.
@@ -59,6 +62,29 @@ func NewCronScriptServiceClient() (cronscriptpb.CronScriptServiceClient, error)
 	return cronscriptpb.NewCronScriptServiceClient(csChannel), nil
 }
 
+// NewVZMgrServiceClient creates a new vzmgr RPC client stub.
+func NewVZMgrServiceClient() (vzmgrpb.VZMgrServiceClient, error) {
+	dialOpts, err := services.GetGRPCClientDialOpts()
+	if err != nil {
+		return nil, err
+	}
+
+	vzmgrChannel, err := grpc.Dial(viper.GetString("vzmgr_service"), dialOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return vzmgrpb.NewVZMgrServiceClient(vzmgrChannel), nil
+}
+
+func newScriptCompiler() (*pxlcompiler.PlannerCompiler, error) {
+	pxlSchema, err := pxlcompiler.LoadSchema("")
+	if err != nil {
+		return nil, err
+	}
+	return pxlcompiler.NewPlannerCompiler(pxlSchema)
+}
+
 func main() {
 	services.SetupService("plugin-service", 50600)
 	services.PostFlagSetupAndParse()
@@ -88,7 +114,24 @@ func main() {
 	if err != nil {
 		log.Fatal("Failed to start cronscript client")
 	}
-	c := controllers.New(db, dbKey, csClient)
+	vzmgrClient, err := NewVZMgrServiceClient()
+	if err != nil {
+		log.Fatal("Failed to start vzmgr client")
+	}
+	c := controllers.New(db, dbKey, csClient, vzmgrClient)
+
+	// Preset scripts of registered plugins are compiled before they're stored. The plugin_server
+	// binary links the planner, so it's only missing from non-cgo builds, eg. local go builds.
+	if pxlcompiler.PlannerAvailable {
+		compiler, err := newScriptCompiler()
+		if err != nil {
+			log.WithError(err).Fatal("Failed to set up the PxL compiler")
+		}
+		defer compiler.Close()
+		c.SetScriptCompiler(compiler)
+	} else {
+		log.Warn("Planner isn't linked into this build, preset scripts of registered plugins won't be compiled")
+	}
 
 	pluginpb.RegisterPluginServiceServer(s.GRPCServer(), c)
 	pluginpb.RegisterDataRetentionPluginServiceServer(s.GRPCServer(), c)
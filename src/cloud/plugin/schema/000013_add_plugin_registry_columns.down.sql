@@ -0,0 +1,3 @@
+ALTER TABLE plugin_releases DROP COLUMN deprecation_message;
+ALTER TABLE plugin_releases DROP COLUMN deprecated;
+ALTER TABLE plugin_releases DROP COLUMN org_id;
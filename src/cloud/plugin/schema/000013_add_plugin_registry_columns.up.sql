@@ -0,0 +1,6 @@
+-- org_id is the org that registered the plugin. Plugins without an org are available to all orgs.
+ALTER TABLE plugin_releases ADD org_id UUID;
+-- deprecated is whether the release is deprecated. Deprecated releases can't be enabled by orgs.
+ALTER TABLE plugin_releases ADD deprecated boolean NOT NULL DEFAULT false;
+-- deprecation_message explains why the release was deprecated.
+ALTER TABLE plugin_releases ADD deprecation_message varchar(1024);
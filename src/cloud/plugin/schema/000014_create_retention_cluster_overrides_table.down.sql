@@ -0,0 +1 @@
+DROP TABLE IF EXISTS org_data_retention_plugin_cluster_overrides;
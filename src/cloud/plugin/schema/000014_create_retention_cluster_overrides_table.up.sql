@@ -0,0 +1,15 @@
+CREATE TABLE org_data_retention_plugin_cluster_overrides (
+  -- org_id is the org which owns the cluster.
+  org_id UUID NOT NULL,
+  -- plugin_id is the ID of the plugin which the override applies to.
+  plugin_id varchar(1024) NOT NULL,
+  -- cluster_id is the cluster which uses the override instead of the org's configuration.
+  cluster_id UUID NOT NULL,
+  -- configurations contains the configuration values which override the org's configuration on the cluster.
+  -- The value is an encrypted JSON.
+  configurations bytea,
+  -- custom_export_url is the encrypted export URL which overrides the org's export URL on the cluster.
+  custom_export_url bytea,
+
+  PRIMARY KEY (org_id, plugin_id, cluster_id)
+);
@@ -0,0 +1,139 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package bundle
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Script is a single script in a bundle.
+type Script struct {
+	Pxl       string `json:"pxl"`
+	Vis       string `json:"vis"`
+	Placement string `json:"placement"`
+	ShortDoc  string `json:"ShortDoc"`
+	LongDoc   string `json:"LongDoc"`
+}
+
+// Bundle is a set of scripts keyed by name, in the format of bundle.json.
+type Bundle struct {
+	Scripts map[string]*Script `json:"scripts"`
+}
+
+// Decode reads a bundle.json.
+func Decode(r io.Reader) (*Bundle, error) {
+	var b Bundle
+	if err := json.NewDecoder(r).Decode(&b); err != nil {
+		return nil, err
+	}
+	if b.Scripts == nil {
+		b.Scripts = make(map[string]*Script)
+	}
+	return &b, nil
+}
+
+// merge layers the given bundles into a single bundle. Scripts in later bundles override scripts
+// with the same name in earlier bundles.
+func merge(bundles ...*Bundle) *Bundle {
+	merged := &Bundle{Scripts: make(map[string]*Script)}
+	for _, b := range bundles {
+		if b == nil {
+			continue
+		}
+		for name, s := range b.Scripts {
+			merged.Scripts[name] = s
+		}
+	}
+	return merged
+}
+
+const (
+	bundleFile    = "bundle.json"
+	visFile       = "vis.json"
+	placementFile = "placement.json"
+	manifestFile  = "manifest.yaml"
+	pxlExt        = ".pxl"
+)
+
+// isBundleFile returns whether the file at the given slash separated path is used to build a
+// bundle from a directory of scripts.
+func isBundleFile(p string) bool {
+	switch path.Base(p) {
+	case visFile, placementFile, manifestFile:
+		return true
+	}
+	return p == bundleFile || path.Ext(p) == pxlExt
+}
+
+type manifest struct {
+	Short string `yaml:"short"`
+	Long  string `yaml:"long"`
+}
+
+// bundleFromFiles builds a bundle from the contents of a directory of scripts, keyed by slash
+// separated path. If the directory has a bundle.json at its root, that is used as is. Otherwise
+// each directory containing a .pxl file is a script, named after its path. The directory may also
+// contain a vis.json, a placement.json and a manifest.yaml with the script's docs, which is the
+// layout of the pxl_scripts repo.
+func bundleFromFiles(files map[string][]byte) (*Bundle, error) {
+	if contents, ok := files[bundleFile]; ok {
+		return Decode(bytes.NewReader(contents))
+	}
+
+	b := &Bundle{Scripts: make(map[string]*Script)}
+	paths := make([]string, 0, len(files))
+	for p := range files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	for _, p := range paths {
+		if path.Ext(p) != pxlExt {
+			continue
+		}
+		dir := path.Dir(p)
+		if dir == "." {
+			return nil, fmt.Errorf("script %s must be in a directory named after the script", p)
+		}
+		if _, ok := b.Scripts[dir]; ok {
+			return nil, fmt.Errorf("script %s has more than one .pxl file", dir)
+		}
+		s := &Script{
+			Pxl:       string(files[p]),
+			Vis:       string(files[path.Join(dir, visFile)]),
+			Placement: string(files[path.Join(dir, placementFile)]),
+		}
+		if m, ok := files[path.Join(dir, manifestFile)]; ok {
+			var parsed manifest
+			if err := yaml.Unmarshal(m, &parsed); err != nil {
+				return nil, fmt.Errorf("invalid manifest for script %s: %w", dir, err)
+			}
+			s.ShortDoc = parsed.Short
+			s.LongDoc = parsed.Long
+		}
+		b.Scripts[dir] = s
+	}
+	return b, nil
+}
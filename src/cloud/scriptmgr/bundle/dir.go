@@ -0,0 +1,176 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package bundle
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/fsnotify/fsnotify"
+	log "github.com/sirupsen/logrus"
+)
+
+// DirSource loads a bundle from a directory on the local filesystem. See bundleFromFiles for the
+// supported layouts.
+type DirSource struct {
+	dir string
+}
+
+// NewDirSource creates a source for the scripts in the given directory.
+func NewDirSource(dir string) *DirSource {
+	return &DirSource{dir: dir}
+}
+
+// Name implements Source.
+func (s *DirSource) Name() string {
+	return "file://" + s.dir
+}
+
+// Fetch implements Source. The revision is a hash of the script files.
+func (s *DirSource) Fetch(ctx context.Context, rev string) (*Bundle, string, error) {
+	files, err := s.readFiles()
+	if err != nil {
+		return nil, "", err
+	}
+	newRev := hashFiles(files)
+	if newRev == rev {
+		return nil, rev, ErrNotModified
+	}
+	b, err := bundleFromFiles(files)
+	if err != nil {
+		return nil, "", err
+	}
+	return b, newRev, nil
+}
+
+func (s *DirSource) readFiles() (map[string][]byte, error) {
+	files := make(map[string][]byte)
+	err := filepath.WalkDir(s.dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if p != s.dir && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(s.dir, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if !isBundleFile(rel) {
+			return nil
+		}
+		contents, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		files[rel] = contents
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+func hashFiles(files map[string][]byte) string {
+	paths := make([]string, 0, len(files))
+	for p := range files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	h := sha256.New()
+	for _, p := range paths {
+		h.Write([]byte(p))
+		h.Write([]byte{0})
+		h.Write(files[p])
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Watch implements Watcher. It watches the directory and all of its subdirectories for changes.
+func (s *DirSource) Watch(ctx context.Context) (<-chan struct{}, error) {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.addWatches(w, s.dir); err != nil {
+		w.Close()
+		return nil, err
+	}
+
+	ch := make(chan struct{}, 1)
+	go func() {
+		defer close(ch)
+		defer w.Close()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case ev, ok := <-w.Events:
+				if !ok {
+					return
+				}
+				// fsnotify doesn't watch recursively, so new directories need to be added.
+				if ev.Has(fsnotify.Create) {
+					if info, err := os.Stat(ev.Name); err == nil && info.IsDir() {
+						if err := s.addWatches(w, ev.Name); err != nil {
+							log.WithError(err).WithField("dir", ev.Name).Error("Failed to watch script directory")
+						}
+					}
+				}
+				select {
+				case ch <- struct{}{}:
+				default:
+				}
+			case err, ok := <-w.Errors:
+				if !ok {
+					return
+				}
+				log.WithError(err).WithField("source", s.Name()).Error("Error watching bundle source")
+			}
+		}
+	}()
+	return ch, nil
+}
+
+func (s *DirSource) addWatches(w *fsnotify.Watcher, root string) error {
+	return filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if p != s.dir && strings.HasPrefix(d.Name(), ".") {
+			return filepath.SkipDir
+		}
+		return w.Add(p)
+	})
+}
@@ -0,0 +1,168 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package bundle_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"px.dev/pixie/src/cloud/scriptmgr/bundle"
+)
+
+func mustWriteFiles(t *testing.T, dir string, files map[string]string) {
+	for p, contents := range files {
+		full := filepath.Join(dir, p)
+		require.NoError(t, os.MkdirAll(filepath.Dir(full), 0755))
+		require.NoError(t, os.WriteFile(full, []byte(contents), 0644))
+	}
+}
+
+func TestDirSource_Fetch(t *testing.T) {
+	dir := t.TempDir()
+	mustWriteFiles(t, dir, map[string]string{
+		"px/http_data/http_data.pxl":   "http_data pxl",
+		"px/http_data/vis.json":        `{"widgets": []}`,
+		"px/http_data/manifest.yaml":   "short: HTTP Data\nlong: All of the HTTP data.\n",
+		"team/latency/latency.pxl":     "latency pxl",
+		"team/latency/placement.json":  `{"placement": true}`,
+		".git/objects/abc/ignored.pxl": "ignored",
+		"README.md":                    "ignored",
+	})
+
+	s := bundle.NewDirSource(dir)
+	b, rev, err := s.Fetch(context.Background(), "")
+	require.NoError(t, err)
+	assert.NotEmpty(t, rev)
+	assert.Equal(t, map[string]*bundle.Script{
+		"px/http_data": {
+			Pxl:      "http_data pxl",
+			Vis:      `{"widgets": []}`,
+			ShortDoc: "HTTP Data",
+			LongDoc:  "All of the HTTP data.",
+		},
+		"team/latency": {
+			Pxl:       "latency pxl",
+			Placement: `{"placement": true}`,
+		},
+	}, b.Scripts)
+
+	_, sameRev, err := s.Fetch(context.Background(), rev)
+	assert.ErrorIs(t, err, bundle.ErrNotModified)
+	assert.Equal(t, rev, sameRev)
+
+	mustWriteFiles(t, dir, map[string]string{
+		"team/latency/latency.pxl": "new latency pxl",
+	})
+	b, newRev, err := s.Fetch(context.Background(), rev)
+	require.NoError(t, err)
+	assert.NotEqual(t, rev, newRev)
+	assert.Equal(t, "new latency pxl", b.Scripts["team/latency"].Pxl)
+}
+
+func TestDirSource_FetchBundleJSON(t *testing.T) {
+	dir := t.TempDir()
+	mustWriteFiles(t, dir, map[string]string{
+		"bundle.json":          `{"scripts": {"px/cluster": {"pxl": "cluster pxl", "ShortDoc": "Cluster"}}}`,
+		"px/other/other.pxl":   "other pxl",
+		"px/other/vis.json":    "{}",
+		"px/other/description": "ignored",
+	})
+
+	b, _, err := bundle.NewDirSource(dir).Fetch(context.Background(), "")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]*bundle.Script{
+		"px/cluster": {
+			Pxl:      "cluster pxl",
+			ShortDoc: "Cluster",
+		},
+	}, b.Scripts)
+}
+
+func TestDirSource_FetchInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+	}{
+		{
+			name: "multiple pxl files",
+			files: map[string]string{
+				"px/script/a.pxl": "a",
+				"px/script/b.pxl": "b",
+			},
+		},
+		{
+			name: "pxl file at root",
+			files: map[string]string{
+				"script.pxl": "a",
+			},
+		},
+		{
+			name: "invalid manifest",
+			files: map[string]string{
+				"px/script/script.pxl":    "a",
+				"px/script/manifest.yaml": "short: [",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			mustWriteFiles(t, dir, test.files)
+			_, _, err := bundle.NewDirSource(dir).Fetch(context.Background(), "")
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestDirSource_Watch(t *testing.T) {
+	dir := t.TempDir()
+	mustWriteFiles(t, dir, map[string]string{
+		"px/script/script.pxl": "script pxl",
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch, err := bundle.NewDirSource(dir).Watch(ctx)
+	require.NoError(t, err)
+
+	mustWriteFiles(t, dir, map[string]string{
+		"px/new_script/new_script.pxl": "new script pxl",
+	})
+	select {
+	case <-ch:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for change notification")
+	}
+
+	cancel()
+	require.Eventually(t, func() bool {
+		select {
+		case _, ok := <-ch:
+			return !ok
+		default:
+			return false
+		}
+	}, 5*time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,68 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package bundle
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/googleapis/google-cloud-go-testing/storage/stiface"
+)
+
+// GCSSource loads a bundle.json from a GCS bucket.
+type GCSSource struct {
+	sc     stiface.Client
+	bucket string
+	path   string
+}
+
+// NewGCSSource creates a source for the bundle at the given path in a GCS bucket.
+func NewGCSSource(sc stiface.Client, bucket string, path string) *GCSSource {
+	return &GCSSource{sc: sc, bucket: bucket, path: path}
+}
+
+// Name implements Source.
+func (s *GCSSource) Name() string {
+	return fmt.Sprintf("gs://%s/%s", s.bucket, s.path)
+}
+
+// Fetch implements Source. The revision is the time the object was last updated.
+func (s *GCSSource) Fetch(ctx context.Context, rev string) (*Bundle, string, error) {
+	obj := s.sc.Bucket(s.bucket).Object(s.path)
+	attrs, err := obj.Attrs(ctx)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get attrs of bundle: %w", err)
+	}
+	newRev := attrs.Updated.UTC().Format(time.RFC3339Nano)
+	if newRev == rev {
+		return nil, rev, ErrNotModified
+	}
+
+	r, err := obj.NewReader(ctx)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to download bundle: %w", err)
+	}
+	defer r.Close()
+
+	b, err := Decode(r)
+	if err != nil {
+		return nil, "", err
+	}
+	return b, newRev, nil
+}
@@ -0,0 +1,193 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package bundle
+
+import (
+	"context"
+	"fmt"
+
+	git "gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/config"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"gopkg.in/src-d/go-git.v4/storage/memory"
+)
+
+// GitSource loads a bundle from a git repository at a branch or tag. See bundleFromFiles for the
+// supported layouts.
+type GitSource struct {
+	url    string
+	ref    string
+	subdir string
+}
+
+// NewGitSource creates a source for the scripts in the given repository. The ref is a branch or
+// tag name, and defaults to the repository's HEAD. If subdir is set, scripts are loaded from that
+// directory of the repository rather than its root.
+func NewGitSource(url string, ref string, subdir string) *GitSource {
+	return &GitSource{url: url, ref: ref, subdir: subdir}
+}
+
+// Name implements Source.
+func (s *GitSource) Name() string {
+	name := "git+" + s.url
+	if s.subdir != "" {
+		name += "?subdir=" + s.subdir
+	}
+	if s.ref != "" {
+		name += "#" + s.ref
+	}
+	return name
+}
+
+// Fetch implements Source. The revision is the hash that the ref points to. The remote's refs are
+// listed first, so the repository is only cloned when the ref has moved.
+func (s *GitSource) Fetch(ctx context.Context, rev string) (*Bundle, string, error) {
+	refs, err := s.listRefs(ctx)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to list refs: %w", err)
+	}
+	ref, err := s.resolveRef(refs)
+	if err != nil {
+		return nil, "", err
+	}
+	newRev := ref.Hash().String()
+	if newRev == rev {
+		return nil, rev, ErrNotModified
+	}
+
+	repo, err := git.CloneContext(ctx, memory.NewStorage(), nil, &git.CloneOptions{
+		URL:           s.url,
+		ReferenceName: ref.Name(),
+		SingleBranch:  true,
+		Depth:         1,
+		Tags:          git.NoTags,
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to clone: %w", err)
+	}
+	head, err := repo.Head()
+	if err != nil {
+		return nil, "", err
+	}
+	commit, err := commitForHash(repo, head.Hash())
+	if err != nil {
+		return nil, "", err
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, "", err
+	}
+	if s.subdir != "" {
+		tree, err = tree.Tree(s.subdir)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to find %s in repository: %w", s.subdir, err)
+		}
+	}
+
+	files := make(map[string][]byte)
+	err = tree.Files().ForEach(func(f *object.File) error {
+		if !isBundleFile(f.Name) {
+			return nil
+		}
+		contents, err := f.Contents()
+		if err != nil {
+			return err
+		}
+		files[f.Name] = []byte(contents)
+		return nil
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	b, err := bundleFromFiles(files)
+	if err != nil {
+		return nil, "", err
+	}
+	return b, newRev, nil
+}
+
+// listRefs lists the refs of the remote. Remote.List doesn't take a context, so it runs in the
+// background and is abandoned if the context is done first.
+func (s *GitSource) listRefs(ctx context.Context) ([]*plumbing.Reference, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	remote := git.NewRemote(memory.NewStorage(), &config.RemoteConfig{
+		Name: git.DefaultRemoteName,
+		URLs: []string{s.url},
+	})
+	type listResult struct {
+		refs []*plumbing.Reference
+		err  error
+	}
+	resCh := make(chan listResult, 1)
+	go func() {
+		refs, err := remote.List(&git.ListOptions{})
+		resCh <- listResult{refs: refs, err: err}
+	}()
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res := <-resCh:
+		return res.refs, res.err
+	}
+}
+
+// commitForHash returns the commit for a hash, which may be an annotated tag.
+func commitForHash(repo *git.Repository, h plumbing.Hash) (*object.Commit, error) {
+	if tag, err := repo.TagObject(h); err == nil {
+		return tag.Commit()
+	}
+	return repo.CommitObject(h)
+}
+
+// resolveRef finds the branch or tag in the remote's refs. Symbolic refs, such as HEAD, are
+// resolved to the ref they point to.
+func (s *GitSource) resolveRef(refs []*plumbing.Reference) (*plumbing.Reference, error) {
+	byName := make(map[plumbing.ReferenceName]*plumbing.Reference, len(refs))
+	for _, r := range refs {
+		byName[r.Name()] = r
+	}
+
+	var names []plumbing.ReferenceName
+	if s.ref == "" {
+		names = []plumbing.ReferenceName{plumbing.HEAD}
+	} else {
+		names = []plumbing.ReferenceName{
+			plumbing.NewBranchReferenceName(s.ref),
+			plumbing.NewTagReferenceName(s.ref),
+		}
+	}
+	for _, name := range names {
+		r, ok := byName[name]
+		// Follow symbolic refs, but not indefinitely.
+		for i := 0; ok && r.Type() == plumbing.SymbolicReference && i < 10; i++ {
+			r, ok = byName[r.Target()]
+		}
+		if ok && r.Type() == plumbing.HashReference {
+			return r, nil
+		}
+	}
+	ref := s.ref
+	if ref == "" {
+		ref = string(plumbing.HEAD)
+	}
+	return nil, fmt.Errorf("ref %s not found in %s", ref, s.url)
+}
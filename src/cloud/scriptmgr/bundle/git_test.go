@@ -0,0 +1,175 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package bundle_test
+
+import (
+	"context"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	git "gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/config"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+
+	"px.dev/pixie/src/cloud/scriptmgr/bundle"
+)
+
+var testSignature = &object.Signature{Name: "Pixie", Email: "test@px.dev", When: time.Unix(1600000000, 0)}
+
+func mustCommit(t *testing.T, repo *git.Repository, dir string, files map[string]string) plumbing.Hash {
+	mustWriteFiles(t, dir, files)
+	wt, err := repo.Worktree()
+	require.NoError(t, err)
+	require.NoError(t, wt.AddGlob("."))
+	h, err := wt.Commit("update scripts", &git.CommitOptions{Author: testSignature})
+	require.NoError(t, err)
+	return h
+}
+
+type testGitRepo struct {
+	url       string
+	master    plumbing.Hash
+	dev       plumbing.Hash
+	tagObject plumbing.Hash
+}
+
+// setupGitRepo creates a bare repository with a master branch, a dev branch and an annotated tag, and
+// returns its file:// URL.
+func setupGitRepo(t *testing.T) *testGitRepo {
+	// The file transport runs git-upload-pack and git-receive-pack.
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	bareDir := t.TempDir()
+	_, err := git.PlainInit(bareDir, true)
+	require.NoError(t, err)
+	r := &testGitRepo{url: "file://" + bareDir}
+
+	workDir := t.TempDir()
+	repo, err := git.PlainInit(workDir, false)
+	require.NoError(t, err)
+	r.master = mustCommit(t, repo, workDir, map[string]string{
+		"px/cluster/cluster.pxl":           "cluster v1",
+		"px/cluster/vis.json":              "{}",
+		"scripts/team/latency/latency.pxl": "latency pxl",
+		"README.md":                        "not a script",
+	})
+	tag, err := repo.CreateTag("v1", r.master, &git.CreateTagOptions{Tagger: testSignature, Message: "v1"})
+	require.NoError(t, err)
+	r.tagObject = tag.Hash()
+
+	wt, err := repo.Worktree()
+	require.NoError(t, err)
+	require.NoError(t, wt.Checkout(&git.CheckoutOptions{Branch: plumbing.NewBranchReferenceName("dev"), Create: true}))
+	r.dev = mustCommit(t, repo, workDir, map[string]string{
+		"px/cluster/cluster.pxl": "cluster v2",
+	})
+
+	_, err = repo.CreateRemote(&config.RemoteConfig{Name: "origin", URLs: []string{r.url}})
+	require.NoError(t, err)
+	require.NoError(t, repo.Push(&git.PushOptions{
+		RemoteName: "origin",
+		RefSpecs:   []config.RefSpec{"refs/heads/*:refs/heads/*", "refs/tags/*:refs/tags/*"},
+	}))
+	return r
+}
+
+func TestGitSource_Fetch(t *testing.T) {
+	r := setupGitRepo(t)
+
+	tests := []struct {
+		name        string
+		ref         string
+		subdir      string
+		expectedRev plumbing.Hash
+		expected    map[string]*bundle.Script
+	}{
+		{
+			name:        "default branch",
+			expectedRev: r.master,
+			expected: map[string]*bundle.Script{
+				"px/cluster":           {Pxl: "cluster v1", Vis: "{}"},
+				"scripts/team/latency": {Pxl: "latency pxl"},
+			},
+		},
+		{
+			name:        "branch",
+			ref:         "dev",
+			expectedRev: r.dev,
+			expected: map[string]*bundle.Script{
+				"px/cluster":           {Pxl: "cluster v2", Vis: "{}"},
+				"scripts/team/latency": {Pxl: "latency pxl"},
+			},
+		},
+		{
+			// The revision of an annotated tag is the hash of the tag object, not the commit.
+			name:        "annotated tag",
+			ref:         "v1",
+			expectedRev: r.tagObject,
+			expected: map[string]*bundle.Script{
+				"px/cluster":           {Pxl: "cluster v1", Vis: "{}"},
+				"scripts/team/latency": {Pxl: "latency pxl"},
+			},
+		},
+		{
+			name:        "subdir",
+			ref:         "dev",
+			subdir:      "scripts",
+			expectedRev: r.dev,
+			expected: map[string]*bundle.Script{
+				"team/latency": {Pxl: "latency pxl"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := bundle.NewGitSource(r.url, test.ref, test.subdir)
+			b, rev, err := s.Fetch(context.Background(), "")
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedRev.String(), rev)
+			assert.Equal(t, test.expected, b.Scripts)
+
+			// The repository isn't cloned again until the ref moves.
+			_, sameRev, err := s.Fetch(context.Background(), rev)
+			assert.ErrorIs(t, err, bundle.ErrNotModified)
+			assert.Equal(t, rev, sameRev)
+		})
+	}
+}
+
+func TestGitSource_FetchErrors(t *testing.T) {
+	r := setupGitRepo(t)
+
+	_, _, err := bundle.NewGitSource(r.url, "missing", "").Fetch(context.Background(), "")
+	assert.ErrorContains(t, err, "ref missing not found")
+
+	_, _, err = bundle.NewGitSource(r.url, "", "missing").Fetch(context.Background(), "")
+	assert.ErrorContains(t, err, "failed to find missing in repository")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, _, err = bundle.NewGitSource(r.url, "", "").Fetch(ctx, "")
+	assert.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,94 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package bundle
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+const httpFetchTimeout = 30 * time.Second
+
+// HTTPSource loads a bundle.json from a URL. The server's ETag is used to avoid downloading the
+// bundle again when it hasn't changed.
+type HTTPSource struct {
+	url    string
+	client *http.Client
+}
+
+// NewHTTPSource creates a source for the bundle at the given URL.
+func NewHTTPSource(url string) *HTTPSource {
+	return &HTTPSource{
+		url:    url,
+		client: &http.Client{Timeout: httpFetchTimeout},
+	}
+}
+
+// Name implements Source.
+func (s *HTTPSource) Name() string {
+	return s.url
+}
+
+// Fetch implements Source. The revision is the ETag of the bundle, or a hash of its contents if
+// the server doesn't send one.
+func (s *HTTPSource) Fetch(ctx context.Context, rev string) (*Bundle, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	if rev != "" {
+		req.Header.Set("If-None-Match", rev)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		return nil, rev, ErrNotModified
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("failed to download bundle: %s", resp.Status)
+	}
+
+	contents, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", err
+	}
+	newRev := resp.Header.Get("ETag")
+	if newRev == "" {
+		sum := sha256.Sum256(contents)
+		newRev = hex.EncodeToString(sum[:])
+	}
+	if newRev == rev {
+		return nil, rev, ErrNotModified
+	}
+
+	b, err := Decode(bytes.NewReader(contents))
+	if err != nil {
+		return nil, "", err
+	}
+	return b, newRev, nil
+}
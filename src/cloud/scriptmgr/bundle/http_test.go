@@ -0,0 +1,88 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package bundle_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"px.dev/pixie/src/cloud/scriptmgr/bundle"
+)
+
+const testBundleJSON = `{"scripts": {"px/cluster": {"pxl": "cluster pxl", "vis": "", "ShortDoc": "Cluster"}}}`
+
+func TestHTTPSource_FetchETag(t *testing.T) {
+	etag := `"v1"`
+	numDownloads := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		numDownloads++
+		w.Header().Set("ETag", etag)
+		_, _ = w.Write([]byte(testBundleJSON))
+	}))
+	defer srv.Close()
+
+	s := bundle.NewHTTPSource(srv.URL)
+	b, rev, err := s.Fetch(context.Background(), "")
+	require.NoError(t, err)
+	assert.Equal(t, etag, rev)
+	assert.Equal(t, "cluster pxl", b.Scripts["px/cluster"].Pxl)
+
+	_, _, err = s.Fetch(context.Background(), rev)
+	assert.ErrorIs(t, err, bundle.ErrNotModified)
+	assert.Equal(t, 1, numDownloads)
+
+	etag = `"v2"`
+	_, rev, err = s.Fetch(context.Background(), rev)
+	require.NoError(t, err)
+	assert.Equal(t, `"v2"`, rev)
+	assert.Equal(t, 2, numDownloads)
+}
+
+func TestHTTPSource_FetchWithoutETag(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(testBundleJSON))
+	}))
+	defer srv.Close()
+
+	s := bundle.NewHTTPSource(srv.URL)
+	_, rev, err := s.Fetch(context.Background(), "")
+	require.NoError(t, err)
+	assert.NotEmpty(t, rev)
+
+	_, _, err = s.Fetch(context.Background(), rev)
+	assert.ErrorIs(t, err, bundle.ErrNotModified)
+}
+
+func TestHTTPSource_FetchError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "not found", http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	_, _, err := bundle.NewHTTPSource(srv.URL).Fetch(context.Background(), "")
+	assert.Error(t, err)
+}
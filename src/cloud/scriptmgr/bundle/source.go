@@ -0,0 +1,85 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package bundle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/googleapis/google-cloud-go-testing/storage/stiface"
+)
+
+// ErrNotModified is returned by a source when the bundle hasn't changed since the given revision.
+var ErrNotModified = errors.New("bundle not modified")
+
+// Source is a location that a script bundle can be loaded from.
+type Source interface {
+	// Name identifies the source in logs and in the sync status.
+	Name() string
+	// Fetch loads the bundle and returns it along with its revision. The revision is opaque to
+	// callers, and is passed back to the next call to Fetch. If the bundle hasn't changed since
+	// that revision, Fetch returns ErrNotModified.
+	Fetch(ctx context.Context, rev string) (*Bundle, string, error)
+}
+
+// Watcher is implemented by sources that can notify the syncer when they change, rather than
+// waiting for the next poll.
+type Watcher interface {
+	// Watch returns a channel that receives a value whenever the source may have changed. The
+	// channel is closed when the context is cancelled.
+	Watch(ctx context.Context) (<-chan struct{}, error)
+}
+
+// ParseSource creates a source from its spec. The supported specs are:
+//
+//	gs://<bucket>/<path>             A bundle.json in a GCS bucket.
+//	http(s)://<host>/<path>          A bundle.json served over HTTP.
+//	file://<dir>                     A local directory of scripts, or containing a bundle.json.
+//	git+<url>[?subdir=<dir>][#<ref>] A git repository of scripts at a branch or tag.
+//
+// The GCS client is only used for gs:// sources, and may be nil otherwise.
+func ParseSource(spec string, sc stiface.Client) (Source, error) {
+	u, err := url.Parse(spec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid bundle source %q: %w", spec, err)
+	}
+	switch {
+	case u.Scheme == "gs":
+		if sc == nil {
+			return nil, fmt.Errorf("bundle source %q requires a GCS client", spec)
+		}
+		return NewGCSSource(sc, u.Host, strings.TrimPrefix(u.Path, "/")), nil
+	case u.Scheme == "http" || u.Scheme == "https":
+		return NewHTTPSource(spec), nil
+	case u.Scheme == "file":
+		return NewDirSource(u.Path), nil
+	case strings.HasPrefix(u.Scheme, "git+"):
+		ref := u.Fragment
+		subdir := u.Query().Get("subdir")
+		u.Scheme = strings.TrimPrefix(u.Scheme, "git+")
+		u.Fragment = ""
+		q := u.Query()
+		q.Del("subdir")
+		u.RawQuery = q.Encode()
+		return NewGitSource(u.String(), ref, subdir), nil
+	}
+	return nil, fmt.Errorf("unsupported bundle source %q", spec)
+}
@@ -0,0 +1,229 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package bundle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultSyncInterval = time.Minute
+	// fetchTimeout bounds how long a single source is fetched for, so that a hung source doesn't block
+	// the other sources or the syncs after it.
+	fetchTimeout = 2 * time.Minute
+	// watchDebounce is how long to wait for further changes after a source reports a change, so
+	// that a burst of file writes results in a single sync.
+	watchDebounce = 500 * time.Millisecond
+)
+
+// SourceStatus is the sync status of a single bundle source.
+type SourceStatus struct {
+	Name string
+	// Revision is the revision of the bundle that is currently loaded from the source.
+	Revision   string
+	NumScripts int
+	// LastSyncAttempt is when the source was last checked for changes.
+	LastSyncAttempt time.Time
+	// LastSynced is when the source was last successfully checked for changes.
+	LastSynced time.Time
+	// LastError is the error from the last sync attempt, or empty if it succeeded.
+	LastError string
+}
+
+type sourceState struct {
+	src    Source
+	bundle *Bundle
+	status SourceStatus
+}
+
+// Syncer keeps a bundle in sync with a list of sources. The sources are layered, so a script in a
+// later source overrides a script with the same name in an earlier source. If a source fails to
+// sync, the last bundle loaded from it is kept.
+type Syncer struct {
+	interval time.Duration
+	// syncMu serializes syncs.
+	syncMu sync.Mutex
+
+	// mu protects the state of the sources and the merged bundle.
+	mu      sync.Mutex
+	sources []*sourceState
+	bundle  *Bundle
+}
+
+// NewSyncer creates a syncer for the given sources, in increasing order of precedence. The sources
+// are polled for changes at the given interval.
+func NewSyncer(sources []Source, interval time.Duration) *Syncer {
+	if interval <= 0 {
+		interval = defaultSyncInterval
+	}
+	s := &Syncer{
+		interval: interval,
+		sources:  make([]*sourceState, len(sources)),
+		bundle:   merge(),
+	}
+	for i, src := range sources {
+		s.sources[i] = &sourceState{
+			src:    src,
+			status: SourceStatus{Name: src.Name()},
+		}
+	}
+	return s
+}
+
+// Sync fetches all of the sources, and returns whether the merged bundle changed.
+func (s *Syncer) Sync(ctx context.Context) (bool, error) {
+	// Sources are fetched without holding mu, so that the status can be read during a slow sync.
+	s.syncMu.Lock()
+	defer s.syncMu.Unlock()
+
+	changed := false
+	var errorMsgs []string
+	for _, st := range s.sources {
+		s.mu.Lock()
+		rev := st.status.Revision
+		s.mu.Unlock()
+
+		now := time.Now()
+		fetchCtx, cancel := context.WithTimeout(ctx, fetchTimeout)
+		b, newRev, err := st.src.Fetch(fetchCtx, rev)
+		cancel()
+		if err != nil && !errors.Is(err, ErrNotModified) {
+			errorMsgs = append(errorMsgs, fmt.Sprintf("%s: %s", st.status.Name, err.Error()))
+		}
+		if s.recordFetch(st, now, b, newRev, err) {
+			changed = true
+		}
+	}
+
+	if changed {
+		s.mu.Lock()
+		bundles := make([]*Bundle, len(s.sources))
+		for i, st := range s.sources {
+			bundles[i] = st.bundle
+		}
+		s.bundle = merge(bundles...)
+		s.mu.Unlock()
+	}
+
+	if len(errorMsgs) > 0 {
+		return changed, fmt.Errorf("failed to sync %d bundle sources: %s", len(errorMsgs), strings.Join(errorMsgs, "\n"))
+	}
+	return changed, nil
+}
+
+// recordFetch updates the source's state with the result of a fetch, and returns whether its
+// bundle changed.
+func (s *Syncer) recordFetch(st *sourceState, now time.Time, b *Bundle, rev string, err error) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st.status.LastSyncAttempt = now
+	if err != nil && !errors.Is(err, ErrNotModified) {
+		st.status.LastError = err.Error()
+		return false
+	}
+	st.status.LastSynced = now
+	st.status.LastError = ""
+	if err != nil {
+		return false
+	}
+	st.bundle = b
+	st.status.Revision = rev
+	st.status.NumScripts = len(b.Scripts)
+	return true
+}
+
+// Bundle returns the merged bundle from the last sync.
+func (s *Syncer) Bundle() *Bundle {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.bundle
+}
+
+// Status returns the sync status of each source, in order of precedence.
+func (s *Syncer) Status() []SourceStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	statuses := make([]SourceStatus, len(s.sources))
+	for i, st := range s.sources {
+		statuses[i] = st.status
+	}
+	return statuses
+}
+
+// Run syncs the sources until the context is cancelled, calling onUpdate with the merged bundle
+// whenever it changes. Sources are synced on every interval, and as soon as a source that
+// implements Watcher reports a change.
+func (s *Syncer) Run(ctx context.Context, onUpdate func(*Bundle)) {
+	changes := make(chan struct{}, 1)
+	for _, st := range s.sources {
+		w, ok := st.src.(Watcher)
+		if !ok {
+			continue
+		}
+		ch, err := w.Watch(ctx)
+		if err != nil {
+			log.WithError(err).WithField("source", st.status.Name).Error("Failed to watch bundle source, falling back to polling")
+			continue
+		}
+		go func() {
+			for range ch {
+				select {
+				case changes <- struct{}{}:
+				default:
+				}
+			}
+		}()
+	}
+
+	t := time.NewTicker(s.interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		case <-changes:
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(watchDebounce):
+			}
+			// Drop any changes reported while debouncing, since this sync picks them up.
+			select {
+			case <-changes:
+			default:
+			}
+		}
+
+		log.Trace("Syncing bundle sources...")
+		changed, err := s.Sync(ctx)
+		if err != nil {
+			log.WithError(err).Error("Failed to sync bundle sources")
+		}
+		if changed {
+			onUpdate(s.Bundle())
+		}
+	}
+}
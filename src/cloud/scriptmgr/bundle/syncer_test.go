@@ -0,0 +1,205 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package bundle_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"px.dev/pixie/src/cloud/scriptmgr/bundle"
+)
+
+type fakeSource struct {
+	name   string
+	rev    string
+	bundle *bundle.Bundle
+	err    error
+}
+
+func (s *fakeSource) Name() string {
+	return s.name
+}
+
+func (s *fakeSource) Fetch(ctx context.Context, rev string) (*bundle.Bundle, string, error) {
+	if s.err != nil {
+		return nil, "", s.err
+	}
+	if rev == s.rev {
+		return nil, rev, bundle.ErrNotModified
+	}
+	return s.bundle, s.rev, nil
+}
+
+func scripts(names ...string) *bundle.Bundle {
+	b := &bundle.Bundle{Scripts: make(map[string]*bundle.Script)}
+	for _, n := range names {
+		b.Scripts[n] = &bundle.Script{Pxl: n + " pxl"}
+	}
+	return b
+}
+
+func TestSyncer_Sync(t *testing.T) {
+	base := &fakeSource{name: "base", rev: "1", bundle: scripts("px/a", "px/b")}
+	override := &fakeSource{name: "override", rev: "1", bundle: &bundle.Bundle{
+		Scripts: map[string]*bundle.Script{
+			"px/b":   {Pxl: "overridden pxl"},
+			"team/c": {Pxl: "team/c pxl"},
+		},
+	}}
+	s := bundle.NewSyncer([]bundle.Source{base, override}, time.Minute)
+
+	changed, err := s.Sync(context.Background())
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, map[string]*bundle.Script{
+		"px/a":   {Pxl: "px/a pxl"},
+		"px/b":   {Pxl: "overridden pxl"},
+		"team/c": {Pxl: "team/c pxl"},
+	}, s.Bundle().Scripts)
+
+	changed, err = s.Sync(context.Background())
+	require.NoError(t, err)
+	assert.False(t, changed)
+
+	base.rev = "2"
+	base.bundle = scripts("px/a")
+	changed, err = s.Sync(context.Background())
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.ElementsMatch(t, []string{"px/a", "px/b", "team/c"}, keys(s.Bundle().Scripts))
+	assert.Equal(t, "overridden pxl", s.Bundle().Scripts["px/b"].Pxl)
+}
+
+func TestSyncer_SyncError(t *testing.T) {
+	base := &fakeSource{name: "base", rev: "1", bundle: scripts("px/a")}
+	other := &fakeSource{name: "other", rev: "1", bundle: scripts("px/b")}
+	s := bundle.NewSyncer([]bundle.Source{base, other}, time.Minute)
+
+	_, err := s.Sync(context.Background())
+	require.NoError(t, err)
+
+	// A failing source keeps the scripts from its last successful sync.
+	other.err = errors.New("connection refused")
+	base.rev = "2"
+	base.bundle = scripts("px/c")
+	changed, err := s.Sync(context.Background())
+	require.Error(t, err)
+	assert.True(t, changed)
+	assert.ElementsMatch(t, []string{"px/b", "px/c"}, keys(s.Bundle().Scripts))
+
+	status := s.Status()
+	require.Len(t, status, 2)
+	assert.Equal(t, "base", status[0].Name)
+	assert.Equal(t, "2", status[0].Revision)
+	assert.Equal(t, 1, status[0].NumScripts)
+	assert.Empty(t, status[0].LastError)
+	assert.Equal(t, status[0].LastSyncAttempt, status[0].LastSynced)
+
+	assert.Equal(t, "other", status[1].Name)
+	assert.Equal(t, "1", status[1].Revision)
+	assert.Equal(t, "connection refused", status[1].LastError)
+	assert.True(t, status[1].LastSyncAttempt.After(status[1].LastSynced))
+}
+
+func TestSyncer_RunWatch(t *testing.T) {
+	dir := t.TempDir()
+	mustWriteFiles(t, dir, map[string]string{
+		"px/a/a.pxl": "a pxl",
+	})
+	s := bundle.NewSyncer([]bundle.Source{bundle.NewDirSource(dir)}, time.Hour)
+	_, err := s.Sync(context.Background())
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	updates := make(chan *bundle.Bundle, 10)
+	go s.Run(ctx, func(b *bundle.Bundle) {
+		updates <- b
+	})
+
+	// Give the watcher a chance to start.
+	time.Sleep(100 * time.Millisecond)
+	mustWriteFiles(t, dir, map[string]string{
+		"px/b/b.pxl": "b pxl",
+	})
+	select {
+	case b := <-updates:
+		assert.ElementsMatch(t, []string{"px/a", "px/b"}, keys(b.Scripts))
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for bundle update")
+	}
+}
+
+func TestParseSource(t *testing.T) {
+	tests := []struct {
+		spec         string
+		expectedName string
+		expectErr    bool
+	}{
+		{
+			spec:         "https://artifacts.px.dev/pxl_scripts/bundle.json",
+			expectedName: "https://artifacts.px.dev/pxl_scripts/bundle.json",
+		},
+		{
+			spec:         "file:///etc/pixie/scripts",
+			expectedName: "file:///etc/pixie/scripts",
+		},
+		{
+			spec:         "git+https://github.com/pixie-io/pixie.git?subdir=src/pxl_scripts#main",
+			expectedName: "git+https://github.com/pixie-io/pixie.git?subdir=src/pxl_scripts#main",
+		},
+		{
+			spec:         "git+https://github.com/pixie-io/pixie.git",
+			expectedName: "git+https://github.com/pixie-io/pixie.git",
+		},
+		{
+			// There's no GCS client to load the bundle with.
+			spec:      "gs://pixie-prod-artifacts/script-bundles/bundle.json",
+			expectErr: true,
+		},
+		{
+			spec:      "ftp://example.com/bundle.json",
+			expectErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.spec, func(t *testing.T) {
+			src, err := bundle.ParseSource(test.spec, nil)
+			if test.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedName, src.Name())
+		})
+	}
+}
+
+func keys(m map[string]*bundle.Script) []string {
+	var k []string
+	for n := range m {
+		k = append(k, n)
+	}
+	return k
+}
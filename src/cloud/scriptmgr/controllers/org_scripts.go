@@ -0,0 +1,409 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/gogo/protobuf/jsonpb"
+	"github.com/gogo/protobuf/types"
+	"github.com/jackc/pgx"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"px.dev/pixie/src/api/proto/uuidpb"
+	"px.dev/pixie/src/api/proto/vispb"
+	"px.dev/pixie/src/cloud/scriptmgr/scriptmgrpb"
+	"px.dev/pixie/src/utils"
+)
+
+const (
+	// OrgScriptPrefix is the prefix of the names of scripts in an org's script library.
+	OrgScriptPrefix = "org/"
+
+	// See https://www.postgresql.org/docs/current/errcodes-appendix.html
+	// Code for `unique_violation`
+	uniqueViolation = "23505"
+)
+
+var orgScriptNameRegex = regexp.MustCompile(`^[a-zA-Z0-9_\-.]+(/[a-zA-Z0-9_\-.]+)*$`)
+
+// Tags is a list of script tags, stored as JSON.
+type Tags []string
+
+// Value returns a golang database/sql driver value for Tags.
+func (t Tags) Value() (driver.Value, error) {
+	if t == nil {
+		return json.Marshal([]string{})
+	}
+	return json.Marshal([]string(t))
+}
+
+// Scan scans the sqlx database type ([]bytes) into the Tags type.
+func (t *Tags) Scan(src interface{}) error {
+	var data []byte
+	switch v := src.(type) {
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	default:
+		return status.Error(codes.Internal, "could not scan script tags")
+	}
+	return json.Unmarshal(data, t)
+}
+
+// orgScriptModel is a script in an org's script library.
+type orgScriptModel struct {
+	ID          uuid.UUID     `db:"id"`
+	OrgID       uuid.UUID     `db:"org_id"`
+	Name        string        `db:"name"`
+	Description string        `db:"description"`
+	OwnerID     uuid.NullUUID `db:"owner_id"`
+	Pxl         string        `db:"pxl"`
+	Vis         string        `db:"vis"`
+	Tags        Tags          `db:"tags"`
+	CreatedAt   time.Time     `db:"created_at"`
+	UpdatedAt   time.Time     `db:"updated_at"`
+}
+
+const orgScriptColumns = `id, org_id, name, description, owner_id, pxl, vis, tags, created_at, updated_at`
+
+func (m *orgScriptModel) fullName() string {
+	return OrgScriptPrefix + m.Name
+}
+
+func (m *orgScriptModel) hasLiveView() bool {
+	return m.Vis != ""
+}
+
+func (m *orgScriptModel) hasTags(tags []string) bool {
+	for _, tag := range tags {
+		found := false
+		for _, t := range m.Tags {
+			if t == tag {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func (m *orgScriptModel) parseVis() (*vispb.Vis, error) {
+	if !m.hasLiveView() {
+		return nil, nil
+	}
+	var vis vispb.Vis
+	if err := jsonpb.UnmarshalString(m.Vis, &vis); err != nil {
+		return nil, err
+	}
+	return &vis, nil
+}
+
+func (m *orgScriptModel) toProto() (*scriptmgrpb.OrgScript, error) {
+	vis, err := m.parseVis()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "invalid vis spec for script %s", m.fullName())
+	}
+	createdAt, err := types.TimestampProto(m.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	updatedAt, err := types.TimestampProto(m.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	pb := &scriptmgrpb.OrgScript{
+		ID:          utils.ProtoFromUUID(m.ID),
+		OrgID:       utils.ProtoFromUUID(m.OrgID),
+		Name:        m.Name,
+		Desc:        m.Description,
+		PxlContents: m.Pxl,
+		Vis:         vis,
+		Tags:        m.Tags,
+		CreatedAt:   createdAt,
+		UpdatedAt:   updatedAt,
+	}
+	if m.OwnerID.Valid {
+		pb.OwnerID = utils.ProtoFromUUID(m.OwnerID.UUID)
+	}
+	return pb, nil
+}
+
+// normalizeOrgScriptName strips the org prefix from the name, and checks that what's left is a
+// valid script name.
+func normalizeOrgScriptName(name string) (string, error) {
+	name = strings.TrimPrefix(name, OrgScriptPrefix)
+	if !orgScriptNameRegex.MatchString(name) {
+		return "", status.Errorf(codes.InvalidArgument, "invalid script name %q, names may only contain letters, numbers, '-', '_', '.' and '/'", name)
+	}
+	return name, nil
+}
+
+// marshalVis converts the vis spec to the JSON that is stored in the database. An empty vis spec is
+// stored as an empty string, which means the script has no live view.
+func marshalVis(vis *vispb.Vis) (string, error) {
+	if vis == nil || vis.Size() == 0 {
+		return "", nil
+	}
+	m := jsonpb.Marshaler{}
+	s, err := m.MarshalToString(vis)
+	if err != nil {
+		return "", status.Errorf(codes.InvalidArgument, "invalid vis spec: %s", err.Error())
+	}
+	return s, nil
+}
+
+func normalizeTags(tags []string) Tags {
+	seen := make(map[string]bool)
+	res := Tags{}
+	for _, t := range tags {
+		t = strings.TrimSpace(t)
+		if t == "" || seen[t] {
+			continue
+		}
+		seen[t] = true
+		res = append(res, t)
+	}
+	sort.Strings(res)
+	return res
+}
+
+func orgIDFromProto(orgIDPb *uuidpb.UUID) (uuid.UUID, error) {
+	orgID := utils.UUIDFromProtoOrNil(orgIDPb)
+	if orgID == uuid.Nil {
+		return uuid.Nil, status.Error(codes.InvalidArgument, "invalid org ID")
+	}
+	return orgID, nil
+}
+
+func (s *Server) listOrgScripts(orgID uuid.UUID) ([]*orgScriptModel, error) {
+	query := `SELECT ` + orgScriptColumns + ` FROM org_scripts WHERE org_id=$1 ORDER BY name`
+	var scripts []*orgScriptModel
+	if err := s.db.Select(&scripts, query, orgID); err != nil {
+		return nil, status.Error(codes.Internal, "failed to fetch org scripts")
+	}
+	return scripts, nil
+}
+
+// getOrgScript returns the script with the given ID in the org, or nil if it doesn't exist.
+func (s *Server) getOrgScript(orgID uuid.UUID, id uuid.UUID) (*orgScriptModel, error) {
+	return s.findOrgScript(`id=$2`, orgID, id)
+}
+
+// getOrgScriptByName returns the script with the given name in the org, or nil if it doesn't exist.
+func (s *Server) getOrgScriptByName(orgID uuid.UUID, name string) (*orgScriptModel, error) {
+	return s.findOrgScript(`name=$2`, orgID, name)
+}
+
+func (s *Server) findOrgScript(cond string, orgID uuid.UUID, val interface{}) (*orgScriptModel, error) {
+	query := `SELECT ` + orgScriptColumns + ` FROM org_scripts WHERE org_id=$1 AND ` + cond
+	var script orgScriptModel
+	err := s.db.Get(&script, query, orgID, val)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to fetch org script")
+	}
+	return &script, nil
+}
+
+// listOrgScriptsForReq returns the org's scripts that should be merged with the public scripts. No
+// scripts are returned if the request isn't for an org.
+func (s *Server) listOrgScriptsForReq(orgIDPb *uuidpb.UUID) ([]*orgScriptModel, error) {
+	orgID := utils.UUIDFromProtoOrNil(orgIDPb)
+	if orgID == uuid.Nil {
+		return nil, nil
+	}
+	return s.listOrgScripts(orgID)
+}
+
+// getOrgScriptForReq looks up a script that isn't in the public bundle in the org's script library.
+func (s *Server) getOrgScriptForReq(orgIDPb *uuidpb.UUID, id uuid.UUID) (*orgScriptModel, error) {
+	orgID := utils.UUIDFromProtoOrNil(orgIDPb)
+	if orgID == uuid.Nil {
+		return nil, nil
+	}
+	return s.getOrgScript(orgID, id)
+}
+
+// GetOrgScripts returns all of the scripts in an org's script library.
+func (s *Server) GetOrgScripts(ctx context.Context, req *scriptmgrpb.GetOrgScriptsReq) (*scriptmgrpb.GetOrgScriptsResp, error) {
+	orgID, err := orgIDFromProto(req.OrgID)
+	if err != nil {
+		return nil, err
+	}
+	scripts, err := s.listOrgScripts(orgID)
+	if err != nil {
+		return nil, err
+	}
+	resp := &scriptmgrpb.GetOrgScriptsResp{}
+	for _, script := range scripts {
+		if !script.hasTags(req.Tags) {
+			continue
+		}
+		pb, err := script.toProto()
+		if err != nil {
+			return nil, err
+		}
+		resp.Scripts = append(resp.Scripts, pb)
+	}
+	return resp, nil
+}
+
+// GetOrgScript returns a script from an org's script library, by ID or by name.
+func (s *Server) GetOrgScript(ctx context.Context, req *scriptmgrpb.GetOrgScriptReq) (*scriptmgrpb.OrgScript, error) {
+	orgID, err := orgIDFromProto(req.OrgID)
+	if err != nil {
+		return nil, err
+	}
+
+	var script *orgScriptModel
+	if id := utils.UUIDFromProtoOrNil(req.ID); id != uuid.Nil {
+		script, err = s.getOrgScript(orgID, id)
+	} else if req.Name != "" {
+		script, err = s.getOrgScriptByName(orgID, strings.TrimPrefix(req.Name, OrgScriptPrefix))
+	} else {
+		return nil, status.Error(codes.InvalidArgument, "script ID or name must be specified")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if script == nil {
+		return nil, status.Error(codes.NotFound, "script not found")
+	}
+	return script.toProto()
+}
+
+// CreateOrgScript adds a script to an org's script library.
+func (s *Server) CreateOrgScript(ctx context.Context, req *scriptmgrpb.CreateOrgScriptReq) (*scriptmgrpb.OrgScript, error) {
+	orgID, err := orgIDFromProto(req.OrgID)
+	if err != nil {
+		return nil, err
+	}
+	name, err := normalizeOrgScriptName(req.Name)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(req.PxlContents) == "" {
+		return nil, status.Error(codes.InvalidArgument, "script must have pxl contents")
+	}
+	vis, err := marshalVis(req.Vis)
+	if err != nil {
+		return nil, err
+	}
+	ownerID := uuid.NullUUID{}
+	if id := utils.UUIDFromProtoOrNil(req.OwnerID); id != uuid.Nil {
+		ownerID = uuid.NullUUID{UUID: id, Valid: true}
+	}
+
+	query := `INSERT INTO org_scripts(org_id, name, description, owner_id, pxl, vis, tags)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING ` + orgScriptColumns
+	var script orgScriptModel
+	err = s.db.QueryRowx(query, orgID, name, req.Desc, ownerID, req.PxlContents, vis, normalizeTags(req.Tags)).StructScan(&script)
+	if err != nil {
+		return nil, orgScriptWriteError(err, name)
+	}
+	return script.toProto()
+}
+
+// UpdateOrgScript updates a script in an org's script library.
+func (s *Server) UpdateOrgScript(ctx context.Context, req *scriptmgrpb.UpdateOrgScriptReq) (*scriptmgrpb.OrgScript, error) {
+	orgID, err := orgIDFromProto(req.OrgID)
+	if err != nil {
+		return nil, err
+	}
+	script, err := s.getOrgScript(orgID, utils.UUIDFromProtoOrNil(req.ID))
+	if err != nil {
+		return nil, err
+	}
+	if script == nil {
+		return nil, status.Error(codes.NotFound, "script not found")
+	}
+
+	if req.Name != nil {
+		if script.Name, err = normalizeOrgScriptName(req.Name.Value); err != nil {
+			return nil, err
+		}
+	}
+	if req.Desc != nil {
+		script.Description = req.Desc.Value
+	}
+	if req.PxlContents != nil {
+		if strings.TrimSpace(req.PxlContents.Value) == "" {
+			return nil, status.Error(codes.InvalidArgument, "script must have pxl contents")
+		}
+		script.Pxl = req.PxlContents.Value
+	}
+	if req.Vis != nil {
+		if script.Vis, err = marshalVis(req.Vis); err != nil {
+			return nil, err
+		}
+	}
+	if req.Tags != nil {
+		script.Tags = normalizeTags(req.Tags.Value)
+	}
+
+	query := `UPDATE org_scripts SET name=$1, description=$2, pxl=$3, vis=$4, tags=$5, updated_at=NOW()
+		WHERE org_id=$6 AND id=$7 RETURNING ` + orgScriptColumns
+	var updated orgScriptModel
+	err = s.db.QueryRowx(query, script.Name, script.Description, script.Pxl, script.Vis, script.Tags, orgID, script.ID).StructScan(&updated)
+	if err != nil {
+		return nil, orgScriptWriteError(err, script.Name)
+	}
+	return updated.toProto()
+}
+
+// DeleteOrgScript removes a script from an org's script library.
+func (s *Server) DeleteOrgScript(ctx context.Context, req *scriptmgrpb.DeleteOrgScriptReq) (*scriptmgrpb.DeleteOrgScriptResp, error) {
+	orgID, err := orgIDFromProto(req.OrgID)
+	if err != nil {
+		return nil, err
+	}
+	res, err := s.db.Exec(`DELETE FROM org_scripts WHERE org_id=$1 AND id=$2`, orgID, utils.UUIDFromProtoOrNil(req.ID))
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to delete org script")
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return nil, status.Error(codes.NotFound, "script not found")
+	}
+	return &scriptmgrpb.DeleteOrgScriptResp{}, nil
+}
+
+func orgScriptWriteError(err error, name string) error {
+	var pgErr pgx.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+		return status.Errorf(codes.AlreadyExists, "script %s%s already exists", OrgScriptPrefix, name)
+	}
+	return status.Error(codes.Internal, "failed to save org script")
+}
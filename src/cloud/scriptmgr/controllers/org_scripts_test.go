@@ -0,0 +1,297 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gogo/protobuf/jsonpb"
+	"github.com/gogo/protobuf/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"px.dev/pixie/src/api/proto/vispb"
+	"px.dev/pixie/src/cloud/scriptmgr/controllers"
+	"px.dev/pixie/src/cloud/scriptmgr/scriptmgrpb"
+	"px.dev/pixie/src/utils"
+)
+
+func mustCreateOrgScriptServer(t *testing.T) *controllers.Server {
+	mustLoadTestData(db)
+	return controllers.NewServer(newTestSyncer(mustSetupFakeBucket(t, testBundle)), db)
+}
+
+func orgScriptNames(scripts []*scriptmgrpb.OrgScript) []string {
+	names := make([]string, len(scripts))
+	for i, s := range scripts {
+		names[i] = s.Name
+	}
+	return names
+}
+
+func TestScriptMgr_GetOrgScripts(t *testing.T) {
+	testCases := []struct {
+		name          string
+		orgID         string
+		tags          []string
+		expectedNames []string
+	}{
+		{
+			name:          "returns all scripts in the org",
+			orgID:         testOrgID,
+			expectedNames: []string{"latency", "team/http_errors"},
+		},
+		{
+			name:          "filters by tags",
+			orgID:         testOrgID,
+			tags:          []string{"team", "http"},
+			expectedNames: []string{"team/http_errors"},
+		},
+		{
+			name:          "returns nothing if no scripts have all of the tags",
+			orgID:         testOrgID,
+			tags:          []string{"team", "perf"},
+			expectedNames: []string{},
+		},
+		{
+			name:          "only returns the scripts for the requested org",
+			orgID:         testOtherOrgID,
+			expectedNames: []string{"other"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := mustCreateOrgScriptServer(t)
+			resp, err := s.GetOrgScripts(context.Background(), &scriptmgrpb.GetOrgScriptsReq{
+				OrgID: utils.ProtoFromUUIDStrOrNil(tc.orgID),
+				Tags:  tc.tags,
+			})
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedNames, orgScriptNames(resp.Scripts))
+		})
+	}
+}
+
+func TestScriptMgr_GetOrgScript(t *testing.T) {
+	s := mustCreateOrgScriptServer(t)
+
+	var vis vispb.Vis
+	require.NoError(t, jsonpb.UnmarshalString(testLiveView, &vis))
+
+	script, err := s.GetOrgScript(context.Background(), &scriptmgrpb.GetOrgScriptReq{
+		OrgID: utils.ProtoFromUUIDStrOrNil(testOrgID),
+		Name:  "org/team/http_errors",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, utils.ProtoFromUUIDStrOrNil(testOrgLiveViewID), script.ID)
+	assert.Equal(t, utils.ProtoFromUUIDStrOrNil(testUserID), script.OwnerID)
+	assert.Equal(t, "team/http_errors", script.Name)
+	assert.Equal(t, "http_errors desc", script.Desc)
+	assert.Equal(t, "http_errors pxl", script.PxlContents)
+	assert.Equal(t, &vis, script.Vis)
+	assert.Equal(t, []string{"http", "team"}, script.Tags)
+
+	script, err = s.GetOrgScript(context.Background(), &scriptmgrpb.GetOrgScriptReq{
+		OrgID: utils.ProtoFromUUIDStrOrNil(testOrgID),
+		ID:    utils.ProtoFromUUIDStrOrNil(testOrgScriptID),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "latency", script.Name)
+	assert.Nil(t, script.Vis)
+
+	_, err = s.GetOrgScript(context.Background(), &scriptmgrpb.GetOrgScriptReq{
+		OrgID: utils.ProtoFromUUIDStrOrNil(testOrgID),
+		ID:    utils.ProtoFromUUIDStrOrNil(testOtherOrgScriptID),
+	})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = s.GetOrgScript(context.Background(), &scriptmgrpb.GetOrgScriptReq{
+		OrgID: utils.ProtoFromUUIDStrOrNil(testOrgID),
+	})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestScriptMgr_CreateOrgScript(t *testing.T) {
+	var vis vispb.Vis
+	require.NoError(t, jsonpb.UnmarshalString(testLiveView, &vis))
+
+	testCases := []struct {
+		name    string
+		req     *scriptmgrpb.CreateOrgScriptReq
+		errCode codes.Code
+	}{
+		{
+			name: "creates a script",
+			req: &scriptmgrpb.CreateOrgScriptReq{
+				OrgID:       utils.ProtoFromUUIDStrOrNil(testOrgID),
+				OwnerID:     utils.ProtoFromUUIDStrOrNil(testUserID),
+				Name:        "org/team/new_script",
+				Desc:        "new desc",
+				PxlContents: "new pxl",
+				Vis:         &vis,
+				Tags:        []string{"team", " perf", "team"},
+			},
+			errCode: codes.OK,
+		},
+		{
+			name: "the same name can be used in another org",
+			req: &scriptmgrpb.CreateOrgScriptReq{
+				OrgID:       utils.ProtoFromUUIDStrOrNil(testOtherOrgID),
+				Name:        "latency",
+				PxlContents: "new pxl",
+			},
+			errCode: codes.OK,
+		},
+		{
+			name: "name already exists in the org",
+			req: &scriptmgrpb.CreateOrgScriptReq{
+				OrgID:       utils.ProtoFromUUIDStrOrNil(testOrgID),
+				Name:        "latency",
+				PxlContents: "new pxl",
+			},
+			errCode: codes.AlreadyExists,
+		},
+		{
+			name: "invalid name",
+			req: &scriptmgrpb.CreateOrgScriptReq{
+				OrgID:       utils.ProtoFromUUIDStrOrNil(testOrgID),
+				Name:        "my script",
+				PxlContents: "new pxl",
+			},
+			errCode: codes.InvalidArgument,
+		},
+		{
+			name: "missing pxl",
+			req: &scriptmgrpb.CreateOrgScriptReq{
+				OrgID: utils.ProtoFromUUIDStrOrNil(testOrgID),
+				Name:  "empty",
+			},
+			errCode: codes.InvalidArgument,
+		},
+		{
+			name: "missing org",
+			req: &scriptmgrpb.CreateOrgScriptReq{
+				Name:        "no_org",
+				PxlContents: "new pxl",
+			},
+			errCode: codes.InvalidArgument,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := mustCreateOrgScriptServer(t)
+			script, err := s.CreateOrgScript(context.Background(), tc.req)
+			if tc.errCode != codes.OK {
+				assert.Equal(t, tc.errCode, status.Code(err))
+				return
+			}
+			require.NoError(t, err)
+			assert.NotNil(t, script.ID)
+			assert.NotNil(t, script.CreatedAt)
+
+			fetched, err := s.GetOrgScript(context.Background(), &scriptmgrpb.GetOrgScriptReq{
+				OrgID: tc.req.OrgID,
+				ID:    script.ID,
+			})
+			require.NoError(t, err)
+			assert.Equal(t, script, fetched)
+		})
+	}
+
+	s := mustCreateOrgScriptServer(t)
+	script, err := s.CreateOrgScript(context.Background(), testCases[0].req)
+	require.NoError(t, err)
+	assert.Equal(t, "team/new_script", script.Name)
+	assert.Equal(t, utils.ProtoFromUUIDStrOrNil(testUserID), script.OwnerID)
+	assert.Equal(t, []string{"perf", "team"}, script.Tags)
+	assert.Equal(t, &vis, script.Vis)
+}
+
+func TestScriptMgr_UpdateOrgScript(t *testing.T) {
+	s := mustCreateOrgScriptServer(t)
+	ctx := context.Background()
+
+	script, err := s.UpdateOrgScript(ctx, &scriptmgrpb.UpdateOrgScriptReq{
+		OrgID:       utils.ProtoFromUUIDStrOrNil(testOrgID),
+		ID:          utils.ProtoFromUUIDStrOrNil(testOrgLiveViewID),
+		PxlContents: &types.StringValue{Value: "updated pxl"},
+		Vis:         &vispb.Vis{},
+		Tags:        &scriptmgrpb.OrgScriptTags{},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "team/http_errors", script.Name)
+	assert.Equal(t, "http_errors desc", script.Desc)
+	assert.Equal(t, "updated pxl", script.PxlContents)
+	assert.Nil(t, script.Vis)
+	assert.Equal(t, []string{}, script.Tags)
+
+	// The script is no longer a live view.
+	lvs, err := s.GetLiveViews(ctx, &scriptmgrpb.GetLiveViewsReq{OrgID: utils.ProtoFromUUIDStrOrNil(testOrgID)})
+	require.NoError(t, err)
+	for _, lv := range lvs.LiveViews {
+		assert.NotEqual(t, "org/team/http_errors", lv.Name)
+	}
+
+	_, err = s.UpdateOrgScript(ctx, &scriptmgrpb.UpdateOrgScriptReq{
+		OrgID: utils.ProtoFromUUIDStrOrNil(testOrgID),
+		ID:    utils.ProtoFromUUIDStrOrNil(testOrgLiveViewID),
+		Name:  &types.StringValue{Value: "org/latency"},
+	})
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+
+	_, err = s.UpdateOrgScript(ctx, &scriptmgrpb.UpdateOrgScriptReq{
+		OrgID:       utils.ProtoFromUUIDStrOrNil(testOrgID),
+		ID:          utils.ProtoFromUUIDStrOrNil(testOrgScriptID),
+		PxlContents: &types.StringValue{Value: " "},
+	})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = s.UpdateOrgScript(ctx, &scriptmgrpb.UpdateOrgScriptReq{
+		OrgID: utils.ProtoFromUUIDStrOrNil(testOrgID),
+		ID:    utils.ProtoFromUUIDStrOrNil(testOtherOrgScriptID),
+		Desc:  &types.StringValue{Value: "stolen"},
+	})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestScriptMgr_DeleteOrgScript(t *testing.T) {
+	s := mustCreateOrgScriptServer(t)
+	ctx := context.Background()
+
+	_, err := s.DeleteOrgScript(ctx, &scriptmgrpb.DeleteOrgScriptReq{
+		OrgID: utils.ProtoFromUUIDStrOrNil(testOrgID),
+		ID:    utils.ProtoFromUUIDStrOrNil(testOtherOrgScriptID),
+	})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = s.DeleteOrgScript(ctx, &scriptmgrpb.DeleteOrgScriptReq{
+		OrgID: utils.ProtoFromUUIDStrOrNil(testOrgID),
+		ID:    utils.ProtoFromUUIDStrOrNil(testOrgScriptID),
+	})
+	require.NoError(t, err)
+
+	resp, err := s.GetOrgScripts(ctx, &scriptmgrpb.GetOrgScriptsReq{OrgID: utils.ProtoFromUUIDStrOrNil(testOrgID)})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"team/http_errors"}, orgScriptNames(resp.Scripts))
+}
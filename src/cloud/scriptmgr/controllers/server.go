@@ -22,16 +22,18 @@ import (
 	"context"
 	"fmt"
 	"strings"
-	"time"
+	"sync"
 
 	"github.com/gofrs/uuid"
 	"github.com/gogo/protobuf/jsonpb"
-	"github.com/googleapis/google-cloud-go-testing/storage/stiface"
+	"github.com/gogo/protobuf/types"
+	"github.com/jmoiron/sqlx"
 	log "github.com/sirupsen/logrus"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
 	"px.dev/pixie/src/api/proto/vispb"
+	"px.dev/pixie/src/cloud/scriptmgr/bundle"
 	"px.dev/pixie/src/cloud/scriptmgr/scriptmgrpb"
 	"px.dev/pixie/src/utils"
 )
@@ -57,136 +59,139 @@ type scriptStore struct {
 
 // Server implements the GRPC Server for the scriptmgr service.
 type Server struct {
-	bundleBucket    string
-	bundlePath      string
-	sc              stiface.Client
-	store           *scriptStore
-	storeLastUpdate time.Time
-	SeedUUID        uuid.UUID
+	syncer   *bundle.Syncer
+	db       *sqlx.DB
+	storeMu  sync.RWMutex
+	store    *scriptStore
+	SeedUUID uuid.UUID
 }
 
-// NewServer creates a new GRPC scriptmgr server.
-func NewServer(bundleBucket string, bundlePath string, sc stiface.Client) *Server {
+// NewServer creates a new GRPC scriptmgr server. Scripts from the bundle sources are served along
+// with the scripts in each org's script library, which are stored in the given database.
+func NewServer(syncer *bundle.Syncer, db *sqlx.DB) *Server {
 	s := &Server{
-		bundleBucket: bundleBucket,
-		bundlePath:   bundlePath,
-		sc:           sc,
-		store: &scriptStore{
-			Scripts:   make(map[uuid.UUID]*scriptModel),
-			LiveViews: make(map[uuid.UUID]*liveViewModel),
-		},
-		storeLastUpdate: time.Unix(0, 0),
-		SeedUUID:        uuid.Must(uuid.NewV4()),
+		syncer:   syncer,
+		db:       db,
+		SeedUUID: uuid.Must(uuid.NewV4()),
 	}
-	err := s.updateStore()
-	if err != nil {
-		log.WithError(err).
-			WithField("bucket", s.bundleBucket).
-			WithField("path", s.bundlePath).
-			Error("Failed to update store using bundle.json from gcs.")
+	if _, err := syncer.Sync(context.Background()); err != nil {
+		log.WithError(err).Error("Failed to sync bundle sources.")
 	}
+	s.updateStore(syncer.Bundle())
 	return s
 }
 
-func (s *Server) addLiveView(name string, bundleScript *pixieScript) error {
-	id := uuid.NewV5(s.SeedUUID, name)
-
+func (s *Server) newLiveView(name string, bundleScript *bundle.Script) (*liveViewModel, error) {
 	var vis vispb.Vis
 	err := jsonpb.UnmarshalString(bundleScript.Vis, &vis)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	s.store.LiveViews[id] = &liveViewModel{
+	return &liveViewModel{
 		name:        name,
 		desc:        bundleScript.ShortDoc,
 		vis:         &vis,
 		pxlContents: bundleScript.Pxl,
-	}
-
-	return nil
-}
-
-func (s *Server) addScript(name string, bundleScript *pixieScript, hasLiveView bool) {
-	id := uuid.NewV5(s.SeedUUID, name)
-	s.store.Scripts[id] = &scriptModel{
-		name:        name,
-		desc:        bundleScript.ShortDoc,
-		pxl:         bundleScript.Pxl,
-		hasLiveView: hasLiveView,
-	}
+	}, nil
 }
 
-func (s *Server) updateStore() error {
-	b, err := getBundle(s.sc, s.bundleBucket, s.bundlePath)
-	if err != nil {
-		return err
+// updateStore replaces the store with the scripts in the bundle.
+func (s *Server) updateStore(b *bundle.Bundle) {
+	store := &scriptStore{
+		Scripts:   make(map[uuid.UUID]*scriptModel),
+		LiveViews: make(map[uuid.UUID]*liveViewModel),
 	}
 	var errorMsgs []string
 	for name, bundleScript := range b.Scripts {
+		id := uuid.NewV5(s.SeedUUID, name)
 		hasLiveView := bundleScript.Vis != ""
-		s.addScript(name, bundleScript, hasLiveView)
+		store.Scripts[id] = &scriptModel{
+			name:        name,
+			desc:        bundleScript.ShortDoc,
+			pxl:         bundleScript.Pxl,
+			hasLiveView: hasLiveView,
+		}
 		if hasLiveView {
-			err = s.addLiveView(name, bundleScript)
+			liveView, err := s.newLiveView(name, bundleScript)
 			if err != nil {
 				errorMsgs = append(errorMsgs, fmt.Sprintf("Error in Live View %s: %s", name, err.Error()))
+				continue
 			}
+			store.LiveViews[id] = liveView
 		}
 	}
-
 	if len(errorMsgs) > 0 {
-		return fmt.Errorf("Encountered %d errors: %s", len(errorMsgs), strings.Join(errorMsgs, "\n"))
+		log.WithError(fmt.Errorf("Encountered %d errors: %s", len(errorMsgs), strings.Join(errorMsgs, "\n"))).
+			Error("Failed to load some live views from the bundle.")
 	}
 
-	return nil
+	s.storeMu.Lock()
+	defer s.storeMu.Unlock()
+	s.store = store
+	log.
+		WithField("scripts", len(store.Scripts)).
+		WithField("live views", len(store.LiveViews)).
+		Trace("Finished updating bundle.")
 }
 
-func (s *Server) storeUpdater() {
-	t := time.NewTicker(time.Minute)
-	ctx := context.Background()
-	for range t.C {
-		log.Trace("Checking if bundle needs updating...")
-		attrs, err := s.sc.Bucket(s.bundleBucket).Object(s.bundlePath).Attrs(ctx)
-		if err != nil {
-			log.WithError(err).
-				WithField("bucket", s.bundleBucket).
-				WithField("path", s.bundlePath).
-				Error("Failed to get attrs of bundle.json")
-			continue
-		}
-		if attrs.Updated.After(s.storeLastUpdate) {
-			log.Trace("Update to bundle required. Updating...")
-			err := s.updateStore()
-			if err != nil {
-				log.WithError(err).
-					WithField("bucket", s.bundleBucket).
-					WithField("path", s.bundlePath).
-					Error("Failed to update bundle.json from gcs.")
-			}
-			log.
-				WithField("scripts", s.store.Scripts).
-				WithField("live views", s.store.LiveViews).
-				Trace("Finished updating bundle.")
-			s.storeLastUpdate = attrs.Updated
-		}
-	}
+func (s *Server) getStore() *scriptStore {
+	s.storeMu.RLock()
+	defer s.storeMu.RUnlock()
+	return s.store
 }
 
-// Start starts the store updater goroutine which checks for updates to the bundle.json.
+// Start starts syncing the bundle sources in the background.
 func (s *Server) Start() {
-	go s.storeUpdater()
+	go s.syncer.Run(context.Background(), s.updateStore)
+}
+
+// GetBundleSyncStatus returns the sync status of each of the bundle sources.
+func (s *Server) GetBundleSyncStatus(ctx context.Context, req *scriptmgrpb.GetBundleSyncStatusReq) (*scriptmgrpb.GetBundleSyncStatusResp, error) {
+	resp := &scriptmgrpb.GetBundleSyncStatusResp{}
+	for _, st := range s.syncer.Status() {
+		srcStatus := &scriptmgrpb.BundleSourceStatus{
+			Name:       st.Name,
+			Revision:   st.Revision,
+			NumScripts: int64(st.NumScripts),
+			LastError:  st.LastError,
+		}
+		if !st.LastSyncAttempt.IsZero() {
+			srcStatus.LastSyncAttempt, _ = types.TimestampProto(st.LastSyncAttempt)
+		}
+		if !st.LastSynced.IsZero() {
+			srcStatus.LastSynced, _ = types.TimestampProto(st.LastSynced)
+		}
+		resp.Sources = append(resp.Sources, srcStatus)
+	}
+	return resp, nil
 }
 
 // GetLiveViews returns a list of all available live views.
 func (s *Server) GetLiveViews(ctx context.Context, req *scriptmgrpb.GetLiveViewsReq) (*scriptmgrpb.GetLiveViewsResp, error) {
 	resp := &scriptmgrpb.GetLiveViewsResp{}
-	for id, liveView := range s.store.LiveViews {
+	for id, liveView := range s.getStore().LiveViews {
 		resp.LiveViews = append(resp.LiveViews, &scriptmgrpb.LiveViewMetadata{
 			Name: liveView.name,
 			Desc: liveView.desc,
 			ID:   utils.ProtoFromUUID(id),
 		})
 	}
+
+	orgScripts, err := s.listOrgScriptsForReq(req.OrgID)
+	if err != nil {
+		return nil, err
+	}
+	for _, script := range orgScripts {
+		if !script.hasLiveView() {
+			continue
+		}
+		resp.LiveViews = append(resp.LiveViews, &scriptmgrpb.LiveViewMetadata{
+			Name: script.fullName(),
+			Desc: script.Description,
+			ID:   utils.ProtoFromUUID(script.ID),
+		})
+	}
 	return resp, nil
 }
 
@@ -196,9 +201,25 @@ func (s *Server) GetLiveViewContents(ctx context.Context, req *scriptmgrpb.GetLi
 	if id == uuid.Nil {
 		return nil, status.Error(codes.InvalidArgument, "Invalid LiveViewID, bytes couldn't be parsed as UUID.")
 	}
-	liveView, ok := s.store.LiveViews[id]
+	liveView, ok := s.getStore().LiveViews[id]
 	if !ok {
-		return nil, status.Errorf(codes.InvalidArgument, "LiveViewID: %s, not found.", id.String())
+		script, err := s.getOrgScriptForReq(req.OrgID, id)
+		if err != nil {
+			return nil, err
+		}
+		if script == nil || !script.hasLiveView() {
+			return nil, status.Errorf(codes.InvalidArgument, "LiveViewID: %s, not found.", id.String())
+		}
+		vis, err := script.parseVis()
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "invalid vis spec for live view %s", script.fullName())
+		}
+		liveView = &liveViewModel{
+			name:        script.fullName(),
+			desc:        script.Description,
+			pxlContents: script.Pxl,
+			vis:         vis,
+		}
 	}
 
 	return &scriptmgrpb.GetLiveViewContentsResp{
@@ -215,7 +236,7 @@ func (s *Server) GetLiveViewContents(ctx context.Context, req *scriptmgrpb.GetLi
 // GetScripts returns a list of all available scripts.
 func (s *Server) GetScripts(ctx context.Context, req *scriptmgrpb.GetScriptsReq) (*scriptmgrpb.GetScriptsResp, error) {
 	resp := &scriptmgrpb.GetScriptsResp{}
-	for id, script := range s.store.Scripts {
+	for id, script := range s.getStore().Scripts {
 		resp.Scripts = append(resp.Scripts, &scriptmgrpb.ScriptMetadata{
 			ID:          utils.ProtoFromUUID(id),
 			Name:        script.name,
@@ -223,6 +244,19 @@ func (s *Server) GetScripts(ctx context.Context, req *scriptmgrpb.GetScriptsReq)
 			HasLiveView: script.hasLiveView,
 		})
 	}
+
+	orgScripts, err := s.listOrgScriptsForReq(req.OrgID)
+	if err != nil {
+		return nil, err
+	}
+	for _, script := range orgScripts {
+		resp.Scripts = append(resp.Scripts, &scriptmgrpb.ScriptMetadata{
+			ID:          utils.ProtoFromUUID(script.ID),
+			Name:        script.fullName(),
+			Desc:        script.Description,
+			HasLiveView: script.hasLiveView(),
+		})
+	}
 	return resp, nil
 }
 
@@ -232,9 +266,21 @@ func (s *Server) GetScriptContents(ctx context.Context, req *scriptmgrpb.GetScri
 	if id == uuid.Nil {
 		return nil, status.Error(codes.InvalidArgument, "Invalid ScriptID, bytes couldn't be parsed as UUID.")
 	}
-	script, ok := s.store.Scripts[id]
+	script, ok := s.getStore().Scripts[id]
 	if !ok {
-		return nil, status.Errorf(codes.InvalidArgument, "ScriptID: %s, not found.", id.String())
+		orgScript, err := s.getOrgScriptForReq(req.OrgID, id)
+		if err != nil {
+			return nil, err
+		}
+		if orgScript == nil {
+			return nil, status.Errorf(codes.InvalidArgument, "ScriptID: %s, not found.", id.String())
+		}
+		script = &scriptModel{
+			name:        orgScript.fullName(),
+			desc:        orgScript.Description,
+			pxl:         orgScript.Pxl,
+			hasLiveView: orgScript.hasLiveView(),
+		}
 	}
 	return &scriptmgrpb.GetScriptContentsResp{
 		Metadata: &scriptmgrpb.ScriptMetadata{
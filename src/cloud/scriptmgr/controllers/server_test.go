@@ -22,25 +22,77 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"os"
 	"testing"
 	"time"
 
 	"cloud.google.com/go/storage"
 	"github.com/gofrs/uuid"
 	"github.com/gogo/protobuf/jsonpb"
+	bindata "github.com/golang-migrate/migrate/source/go_bindata"
 	"github.com/googleapis/google-cloud-go-testing/storage/stiface"
+	"github.com/jmoiron/sqlx"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
 	"px.dev/pixie/src/api/proto/vispb"
+	"px.dev/pixie/src/cloud/scriptmgr/bundle"
 	"px.dev/pixie/src/cloud/scriptmgr/controllers"
+	"px.dev/pixie/src/cloud/scriptmgr/schema"
 	"px.dev/pixie/src/cloud/scriptmgr/scriptmgrpb"
+	"px.dev/pixie/src/shared/services/pgtest"
 	"px.dev/pixie/src/utils"
 	"px.dev/pixie/src/utils/testingutils"
 )
 
+var db *sqlx.DB
+
+func TestMain(m *testing.M) {
+	err := testMain(m)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Got error: %v\n", err)
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+func testMain(m *testing.M) error {
+	s := bindata.Resource(schema.AssetNames(), schema.Asset)
+	testDB, teardown, err := pgtest.SetupTestDB(s)
+	if err != nil {
+		return fmt.Errorf("failed to start test database: %w", err)
+	}
+
+	defer teardown()
+	db = testDB
+
+	if c := m.Run(); c != 0 {
+		return fmt.Errorf("some tests failed with code: %d", c)
+	}
+	return nil
+}
+
+const (
+	testOrgID      = "6ba7b810-9dad-11d1-80b4-00c04fd430c8"
+	testOtherOrgID = "223e4567-e89b-12d3-a456-426655440000"
+	testUserID     = "323e4567-e89b-12d3-a456-426655440000"
+
+	testOrgLiveViewID    = "123e4567-e89b-12d3-a456-426655440001"
+	testOrgScriptID      = "123e4567-e89b-12d3-a456-426655440002"
+	testOtherOrgScriptID = "123e4567-e89b-12d3-a456-426655440003"
+)
+
+func mustLoadTestData(db *sqlx.DB) {
+	db.MustExec(`DELETE FROM org_scripts`)
+
+	insertScript := `INSERT INTO org_scripts(id, org_id, name, description, owner_id, pxl, vis, tags) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+	db.MustExec(insertScript, testOrgLiveViewID, testOrgID, "team/http_errors", "http_errors desc", testUserID, "http_errors pxl", testLiveView, `["http", "team"]`)
+	db.MustExec(insertScript, testOrgScriptID, testOrgID, "latency", "latency desc", testUserID, "latency pxl", "", `["perf"]`)
+	db.MustExec(insertScript, testOtherOrgScriptID, testOtherOrgID, "other", "other desc", testUserID, "other pxl", "", `[]`)
+}
+
 const bundleBucket = "test-bucket"
 const bundlePath = "bundle.json"
 
@@ -107,9 +159,14 @@ func mustSetupFakeBucket(t *testing.T, testBundle map[string]scriptsDef) stiface
 	})
 }
 
+func newTestSyncer(c stiface.Client) *bundle.Syncer {
+	return bundle.NewSyncer([]bundle.Source{bundle.NewGCSSource(c, bundleBucket, bundlePath)}, time.Minute)
+}
+
 func TestScriptMgr_GetLiveViews(t *testing.T) {
 	testCases := []struct {
 		name         string
+		orgID        string
 		expectedResp *scriptmgrpb.GetLiveViewsResp
 		expectErr    bool
 	}{
@@ -126,15 +183,37 @@ func TestScriptMgr_GetLiveViews(t *testing.T) {
 			},
 			expectErr: false,
 		},
+		{
+			name:  "Request for an org also returns the live views in the org's script library.",
+			orgID: testOrgID,
+			expectedResp: &scriptmgrpb.GetLiveViewsResp{
+				LiveViews: []*scriptmgrpb.LiveViewMetadata{
+					{
+						ID:   nil,
+						Name: "liveview1",
+						Desc: "liveview1 desc",
+					},
+					{
+						ID:   nil,
+						Name: "org/team/http_errors",
+						Desc: "http_errors desc",
+					},
+				},
+			},
+			expectErr: false,
+		},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			c := mustSetupFakeBucket(t, testBundle)
-			s := controllers.NewServer(bundleBucket, bundlePath, c)
+			s := controllers.NewServer(newTestSyncer(c), db)
 			ctx := context.Background()
 
-			req := &scriptmgrpb.GetLiveViewsReq{}
+			mustLoadTestData(db)
+			req := &scriptmgrpb.GetLiveViewsReq{
+				OrgID: utils.ProtoFromUUIDStrOrNil(tc.orgID),
+			}
 			resp, err := s.GetLiveViews(ctx, req)
 			if tc.expectErr {
 				require.NotNil(t, err)
@@ -174,7 +253,7 @@ func TestScriptMgr_GetLiveViewContents(t *testing.T) {
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			c := mustSetupFakeBucket(t, testBundle)
-			s := controllers.NewServer(bundleBucket, bundlePath, c)
+			s := controllers.NewServer(newTestSyncer(c), db)
 			ctx := context.Background()
 
 			id := uuid.NewV5(s.SeedUUID, tc.liveViewName)
@@ -218,6 +297,7 @@ func TestScriptMgr_GetLiveViewContents(t *testing.T) {
 func TestScriptMgr_GetScripts(t *testing.T) {
 	testCases := []struct {
 		name         string
+		orgID        string
 		expectedResp *scriptmgrpb.GetScriptsResp
 		expectErr    bool
 	}{
@@ -247,15 +327,51 @@ func TestScriptMgr_GetScripts(t *testing.T) {
 			},
 			expectErr: false,
 		},
+		{
+			name:  "Request for an org also returns the scripts in the org's script library.",
+			orgID: testOtherOrgID,
+			expectedResp: &scriptmgrpb.GetScriptsResp{
+				Scripts: []*scriptmgrpb.ScriptMetadata{
+					{
+						ID:          nil,
+						Name:        "script1",
+						Desc:        "script1 desc",
+						HasLiveView: false,
+					},
+					{
+						ID:          nil,
+						Name:        "script2",
+						Desc:        "script2 desc",
+						HasLiveView: false,
+					},
+					{
+						ID:          nil,
+						Name:        "liveview1",
+						Desc:        "liveview1 desc",
+						HasLiveView: true,
+					},
+					{
+						ID:          nil,
+						Name:        "org/other",
+						Desc:        "other desc",
+						HasLiveView: false,
+					},
+				},
+			},
+			expectErr: false,
+		},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			c := mustSetupFakeBucket(t, testBundle)
-			s := controllers.NewServer(bundleBucket, bundlePath, c)
+			s := controllers.NewServer(newTestSyncer(c), db)
 			ctx := context.Background()
 
-			req := &scriptmgrpb.GetScriptsReq{}
+			mustLoadTestData(db)
+			req := &scriptmgrpb.GetScriptsReq{
+				OrgID: utils.ProtoFromUUIDStrOrNil(tc.orgID),
+			}
 			resp, err := s.GetScripts(ctx, req)
 			if tc.expectErr {
 				require.NotNil(t, err)
@@ -295,7 +411,7 @@ func TestScriptMgr_GetScriptContents(t *testing.T) {
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			c := mustSetupFakeBucket(t, testBundle)
-			s := controllers.NewServer(bundleBucket, bundlePath, c)
+			s := controllers.NewServer(newTestSyncer(c), db)
 			ctx := context.Background()
 			id := uuid.NewV5(s.SeedUUID, tc.scriptName)
 			req := &scriptmgrpb.GetScriptContentsReq{
@@ -323,3 +439,120 @@ func TestScriptMgr_GetScriptContents(t *testing.T) {
 		})
 	}
 }
+
+func TestScriptMgr_GetOrgScriptContents(t *testing.T) {
+	testCases := []struct {
+		name         string
+		orgID        string
+		scriptID     string
+		expectedResp *scriptmgrpb.GetScriptContentsResp
+		errCode      codes.Code
+	}{
+		{
+			name:     "Script in the org's library should be returned.",
+			orgID:    testOrgID,
+			scriptID: testOrgScriptID,
+			expectedResp: &scriptmgrpb.GetScriptContentsResp{
+				Metadata: &scriptmgrpb.ScriptMetadata{
+					ID:          utils.ProtoFromUUIDStrOrNil(testOrgScriptID),
+					Name:        "org/latency",
+					Desc:        "latency desc",
+					HasLiveView: false,
+				},
+				Contents: "latency pxl",
+			},
+		},
+		{
+			name:     "Script in another org's library returns error.",
+			orgID:    testOrgID,
+			scriptID: testOtherOrgScriptID,
+			errCode:  codes.InvalidArgument,
+		},
+		{
+			name:     "Request without an org returns error.",
+			scriptID: testOrgScriptID,
+			errCode:  codes.InvalidArgument,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mustLoadTestData(db)
+			c := mustSetupFakeBucket(t, testBundle)
+			s := controllers.NewServer(newTestSyncer(c), db)
+
+			resp, err := s.GetScriptContents(context.Background(), &scriptmgrpb.GetScriptContentsReq{
+				ScriptID: utils.ProtoFromUUIDStrOrNil(tc.scriptID),
+				OrgID:    utils.ProtoFromUUIDStrOrNil(tc.orgID),
+			})
+			if tc.expectedResp == nil {
+				require.NotNil(t, err)
+				assert.Equal(t, tc.errCode, status.Code(err))
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedResp, resp)
+		})
+	}
+}
+
+func TestScriptMgr_GetOrgLiveViewContents(t *testing.T) {
+	mustLoadTestData(db)
+	c := mustSetupFakeBucket(t, testBundle)
+	s := controllers.NewServer(newTestSyncer(c), db)
+
+	var vis vispb.Vis
+	require.NoError(t, jsonpb.UnmarshalString(testLiveView, &vis))
+
+	resp, err := s.GetLiveViewContents(context.Background(), &scriptmgrpb.GetLiveViewContentsReq{
+		LiveViewID: utils.ProtoFromUUIDStrOrNil(testOrgLiveViewID),
+		OrgID:      utils.ProtoFromUUIDStrOrNil(testOrgID),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, &scriptmgrpb.GetLiveViewContentsResp{
+		Metadata: &scriptmgrpb.LiveViewMetadata{
+			ID:   utils.ProtoFromUUIDStrOrNil(testOrgLiveViewID),
+			Name: "org/team/http_errors",
+			Desc: "http_errors desc",
+		},
+		PxlContents: "http_errors pxl",
+		Vis:         &vis,
+	}, resp)
+
+	// Scripts without a vis spec aren't live views.
+	_, err = s.GetLiveViewContents(context.Background(), &scriptmgrpb.GetLiveViewContentsReq{
+		LiveViewID: utils.ProtoFromUUIDStrOrNil(testOrgScriptID),
+		OrgID:      utils.ProtoFromUUIDStrOrNil(testOrgID),
+	})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestScriptMgr_GetBundleSyncStatus(t *testing.T) {
+	c := mustSetupFakeBucket(t, testBundle)
+	missing := bundle.NewGCSSource(c, bundleBucket, "missing.json")
+	syncer := bundle.NewSyncer([]bundle.Source{
+		bundle.NewGCSSource(c, bundleBucket, bundlePath),
+		missing,
+	}, time.Minute)
+	s := controllers.NewServer(syncer, db)
+
+	resp, err := s.GetBundleSyncStatus(context.Background(), &scriptmgrpb.GetBundleSyncStatusReq{})
+	require.NoError(t, err)
+	require.Len(t, resp.Sources, 2)
+
+	assert.Equal(t, fmt.Sprintf("gs://%s/%s", bundleBucket, bundlePath), resp.Sources[0].Name)
+	assert.Equal(t, int64(3), resp.Sources[0].NumScripts)
+	assert.NotEmpty(t, resp.Sources[0].Revision)
+	assert.Empty(t, resp.Sources[0].LastError)
+	assert.NotNil(t, resp.Sources[0].LastSynced)
+
+	assert.Equal(t, missing.Name(), resp.Sources[1].Name)
+	assert.NotEmpty(t, resp.Sources[1].LastError)
+	assert.NotNil(t, resp.Sources[1].LastSyncAttempt)
+	assert.Nil(t, resp.Sources[1].LastSynced)
+
+	// The scripts from the source that synced are still served.
+	scripts, err := s.GetScripts(context.Background(), &scriptmgrpb.GetScriptsReq{})
+	require.NoError(t, err)
+	assert.Len(t, scripts.Scripts, 3)
+}
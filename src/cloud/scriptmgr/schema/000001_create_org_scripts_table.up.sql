@@ -0,0 +1,25 @@
+CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
+
+CREATE TABLE org_scripts (
+  -- id is the ID of the script.
+  id UUID UNIQUE DEFAULT uuid_generate_v4(),
+  -- org_id is the org that owns this script.
+  org_id UUID NOT NULL,
+  -- name is the name of the script. It is run as org/<name>.
+  name varchar(1024) NOT NULL,
+  -- description is a short description of what the script does.
+  description varchar(65536) NOT NULL DEFAULT '',
+  -- owner_id is the ID of the user who created the script.
+  owner_id UUID,
+  -- pxl contains the actual PxL script.
+  pxl varchar NOT NULL,
+  -- vis is the JSON encoded vis spec of the script. If empty, the script can't be used as a live view.
+  vis varchar NOT NULL DEFAULT '',
+  -- tags is a JSON list of tags used to organize the script library.
+  tags json NOT NULL DEFAULT '[]',
+  created_at TIMESTAMP DEFAULT NOW(),
+  updated_at TIMESTAMP DEFAULT NOW(),
+
+  PRIMARY KEY (id),
+  UNIQUE (org_id, name)
+);
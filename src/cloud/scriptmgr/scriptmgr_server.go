@@ -20,27 +20,64 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	_ "net/http/pprof"
+	"strings"
+	"time"
 
 	"cloud.google.com/go/storage"
+	bindata "github.com/golang-migrate/migrate/source/go_bindata"
 	"github.com/googleapis/google-cloud-go-testing/storage/stiface"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 	"google.golang.org/api/option"
 
+	"px.dev/pixie/src/cloud/scriptmgr/bundle"
 	"px.dev/pixie/src/cloud/scriptmgr/controllers"
+	"px.dev/pixie/src/cloud/scriptmgr/schema"
 	"px.dev/pixie/src/cloud/scriptmgr/scriptmgrpb"
+	"px.dev/pixie/src/cloud/shared/pgmigrate"
 	"px.dev/pixie/src/shared/services"
 	"px.dev/pixie/src/shared/services/env"
 	"px.dev/pixie/src/shared/services/healthz"
+	"px.dev/pixie/src/shared/services/pg"
 	"px.dev/pixie/src/shared/services/server"
 )
 
 func init() {
 	pflag.String("bundle_bucket", "pixie-prod-artifacts", "GCS Bucket containing the bundle of scripts.")
 	pflag.String("bundle_path", "script-bundles/bundle.json", "Path to bundle within bucket.")
+	pflag.StringSlice("bundle_sources", nil, "Sources to load scripts from, in increasing order of precedence. "+
+		"Supports gs://<bucket>/<path>, http(s)://<url>, file://<dir> and git+<url>[?subdir=<dir>][#<ref>]. "+
+		"Defaults to the bundle in bundle_bucket.")
+	pflag.Duration("bundle_sync_interval", time.Minute, "How often to check the bundle sources for changes.")
+}
+
+func mustCreateBundleSources() []bundle.Source {
+	specs := viper.GetStringSlice("bundle_sources")
+	if len(specs) == 0 {
+		specs = []string{fmt.Sprintf("gs://%s/%s", viper.GetString("bundle_bucket"), viper.GetString("bundle_path"))}
+	}
+
+	var sc stiface.Client
+	sources := make([]bundle.Source, len(specs))
+	for i, spec := range specs {
+		if strings.HasPrefix(spec, "gs://") && sc == nil {
+			client, err := storage.NewClient(context.Background(), option.WithoutAuthentication())
+			if err != nil {
+				log.WithError(err).Fatal("Failed to initialize GCS client.")
+			}
+			sc = stiface.AdaptClient(client)
+		}
+		src, err := bundle.ParseSource(spec, sc)
+		if err != nil {
+			log.WithError(err).Fatal("Invalid bundle source.")
+		}
+		sources[i] = src
+	}
+	return sources
 }
 
 func main() {
@@ -54,17 +91,17 @@ func main() {
 	mux.Handle("/debug/", http.DefaultServeMux)
 	healthz.RegisterDefaultChecks(mux)
 
-	s := server.NewPLServer(env.New(viper.GetString("domain_name")), mux)
-
-	client, err := storage.NewClient(context.Background(), option.WithoutAuthentication())
+	db := pg.MustConnectDefaultPostgresDB()
+	err := pgmigrate.PerformMigrationsUsingBindata(db, "scriptmgr_service_migrations",
+		bindata.Resource(schema.AssetNames(), schema.Asset))
 	if err != nil {
-		log.WithError(err).Fatal("Failed to initialize GCS client.")
+		log.WithError(err).Fatal("Failed to apply migrations")
 	}
 
-	svr := controllers.NewServer(
-		viper.GetString("bundle_bucket"),
-		viper.GetString("bundle_path"),
-		stiface.AdaptClient(client))
+	s := server.NewPLServer(env.New(viper.GetString("domain_name")), mux)
+
+	syncer := bundle.NewSyncer(mustCreateBundleSources(), viper.GetDuration("bundle_sync_interval"))
+	svr := controllers.NewServer(syncer, db)
 	svr.Start()
 
 	scriptmgrpb.RegisterScriptMgrServiceServer(s.GRPCServer(), svr)
@@ -23,3 +23,11 @@ package messages
 // VizierConnectedChannel is the channel to listen to be notified of Viziers connecting.
 // The message passed along this channel is of type px.cloud.messages.VizierConnected.
 const VizierConnectedChannel = "VizierConnected"
+
+// VizierStatusChangedChannel is the channel to listen to be notified of changes to the status of
+// Viziers. The message passed along this channel is of type px.cloud.messages.VizierStatusChanged.
+const VizierStatusChangedChannel = "VizierStatusChanged"
+
+// CronScriptChangedChannel is the channel to listen to be notified of changes to cron scripts.
+// The message passed along this channel is of type px.cloud.messages.CronScriptChanged.
+const CronScriptChangedChannel = "CronScriptChanged"
@@ -0,0 +1,285 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package vzshard
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/nats-io/nats.go"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// numShards is the total number of vizier shards. Shards are identified by the last byte of the vizier ID.
+const numShards = 256
+
+// ShardListener is called whenever the set of shards owned by a replica changes.
+type ShardListener func(added []string, removed []string)
+
+// Assigner tracks the vizier shards that this replica is responsible for. When dynamic sharding is enabled, each
+// replica holds a lease in a NATS KV bucket, and the shards are split evenly across all replicas with live leases.
+// Shards are rebalanced whenever a replica joins or leaves.
+type Assigner struct {
+	kv        nats.KeyValue
+	replicaID string
+	leaseTTL  time.Duration
+
+	mu        sync.Mutex
+	replicas  map[string]bool
+	shards    map[string]bool
+	listeners []ShardListener
+
+	quitCh chan struct{}
+	once   sync.Once
+	wg     sync.WaitGroup
+}
+
+// NewStaticAssigner creates an assigner which owns the shard range configured by the vizier_shard_min and
+// vizier_shard_max flags.
+func NewStaticAssigner() *Assigner {
+	a := &Assigner{
+		shards: make(map[string]bool),
+		quitCh: make(chan struct{}),
+	}
+	for _, shard := range GenerateShardRange() {
+		a.shards[shard] = true
+	}
+	return a
+}
+
+// NewAssigner creates an assigner for the given service. If dynamic sharding is disabled, the assigner owns the
+// statically configured shard range.
+func NewAssigner(nc *nats.Conn, service string) (*Assigner, error) {
+	if !viper.GetBool("vizier_shard_dynamic") {
+		return NewStaticAssigner(), nil
+	}
+
+	js, err := nc.JetStream()
+	if err != nil {
+		return nil, err
+	}
+	leaseTTL := viper.GetDuration("vizier_shard_lease_ttl")
+	bucket := fmt.Sprintf("vzshard_%s", service)
+	kv, err := js.KeyValue(bucket)
+	if errors.Is(err, nats.ErrBucketNotFound) {
+		kv, err = js.CreateKeyValue(&nats.KeyValueConfig{
+			Bucket:  bucket,
+			TTL:     leaseTTL,
+			Storage: nats.MemoryStorage,
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	a := &Assigner{
+		kv:        kv,
+		replicaID: uuid.Must(uuid.NewV4()).String(),
+		leaseTTL:  leaseTTL,
+		shards:    make(map[string]bool),
+		quitCh:    make(chan struct{}),
+	}
+
+	// Acquire our lease before computing the first assignment, so that this replica is included in it.
+	if err := a.renewLease(); err != nil {
+		return nil, err
+	}
+	if err := a.rebalance(); err != nil {
+		return nil, err
+	}
+
+	watcher, err := kv.WatchAll(nats.UpdatesOnly())
+	if err != nil {
+		return nil, err
+	}
+
+	a.wg.Add(1)
+	go a.run(watcher)
+
+	return a, nil
+}
+
+// Register registers a listener for shard changes. The listener is immediately called with all of the shards
+// currently owned by this replica. Listeners are called serially and must not call back into the assigner.
+func (a *Assigner) Register(fn ShardListener) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.listeners = append(a.listeners, fn)
+	fn(a.sortedShards(), nil)
+}
+
+// Shards returns the shards currently owned by this replica.
+func (a *Assigner) Shards() []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.sortedShards()
+}
+
+// Owns returns whether the given shard is currently owned by this replica.
+func (a *Assigner) Owns(shard string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.shards[shard]
+}
+
+// Stop releases this replica's lease, so that the remaining replicas can take over its shards.
+func (a *Assigner) Stop() {
+	a.once.Do(func() {
+		close(a.quitCh)
+		a.wg.Wait()
+		if a.kv == nil {
+			return
+		}
+		if err := a.kv.Delete(a.replicaID); err != nil {
+			log.WithError(err).Error("Failed to release vizier shard lease")
+		}
+	})
+}
+
+func (a *Assigner) run(watcher nats.KeyWatcher) {
+	defer a.wg.Done()
+	defer watcher.Stop()
+
+	// Renew the lease well before it expires, so that a single missed renewal doesn't cause a rebalance. Live leases
+	// are also re-read on every renewal, since expired leases don't show up as updates in the watcher.
+	ticker := time.NewTicker(a.leaseTTL / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-a.quitCh:
+			return
+		case <-ticker.C:
+			if err := a.renewLease(); err != nil {
+				log.WithError(err).Error("Failed to renew vizier shard lease")
+				continue
+			}
+			if err := a.rebalance(); err != nil {
+				log.WithError(err).Error("Failed to rebalance vizier shards")
+			}
+		case entry := <-watcher.Updates():
+			if entry == nil {
+				continue
+			}
+			// Renewals of existing leases don't change the membership, so only joins and leaves trigger a rebalance.
+			if entry.Operation() == nats.KeyValuePut && a.isKnownReplica(entry.Key()) {
+				continue
+			}
+			if err := a.rebalance(); err != nil {
+				log.WithError(err).Error("Failed to rebalance vizier shards")
+			}
+		}
+	}
+}
+
+func (a *Assigner) renewLease() error {
+	_, err := a.kv.Put(a.replicaID, []byte(time.Now().UTC().Format(time.RFC3339)))
+	return err
+}
+
+func (a *Assigner) rebalance() error {
+	replicaIDs, err := a.kv.Keys()
+	if err != nil && !errors.Is(err, nats.ErrNoKeysFound) {
+		return err
+	}
+
+	owned := make(map[string]bool)
+	for _, shard := range AssignShards(replicaIDs, a.replicaID) {
+		owned[shard] = true
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.replicas = make(map[string]bool)
+	for _, id := range replicaIDs {
+		a.replicas[id] = true
+	}
+
+	var added, removed []string
+	for shard := range owned {
+		if !a.shards[shard] {
+			added = append(added, shard)
+		}
+	}
+	for shard := range a.shards {
+		if !owned[shard] {
+			removed = append(removed, shard)
+		}
+	}
+	if len(added) == 0 && len(removed) == 0 {
+		return nil
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+
+	log.WithField("replica", a.replicaID).
+		WithField("replicas", len(replicaIDs)).
+		WithField("added", len(added)).
+		WithField("removed", len(removed)).
+		Info("Rebalanced vizier shards")
+
+	a.shards = owned
+	for _, fn := range a.listeners {
+		fn(added, removed)
+	}
+	return nil
+}
+
+func (a *Assigner) isKnownReplica(replicaID string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.replicas[replicaID]
+}
+
+func (a *Assigner) sortedShards() []string {
+	shards := make([]string, 0, len(a.shards))
+	for shard := range a.shards {
+		shards = append(shards, shard)
+	}
+	sort.Strings(shards)
+	return shards
+}
+
+// AssignShards splits the shards into contiguous ranges across the given replicas, ordered by ID, and returns the
+// shards owned by replicaID. Every replica computes the same assignment from the same set of replicas.
+func AssignShards(replicaIDs []string, replicaID string) []string {
+	sorted := append([]string{}, replicaIDs...)
+	sort.Strings(sorted)
+
+	idx := sort.SearchStrings(sorted, replicaID)
+	if idx == len(sorted) || sorted[idx] != replicaID {
+		// This replica doesn't hold a lease, so it owns no shards.
+		return nil
+	}
+
+	min := idx * numShards / len(sorted)
+	max := (idx + 1) * numShards / len(sorted)
+	shards := make([]string, max-min)
+	for i := min; i < max; i++ {
+		shards[i-min] = shardIntToHex(i)
+	}
+	return shards
+}
@@ -0,0 +1,144 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package vzshard_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"px.dev/pixie/src/cloud/shared/vzshard"
+	"px.dev/pixie/src/utils/testingutils"
+)
+
+const (
+	testLeaseTTL  = time.Second
+	waitTimeout   = 10 * time.Second
+	checkInterval = 10 * time.Millisecond
+)
+
+func setupDynamicSharding(t *testing.T) {
+	viper.Set("vizier_shard_dynamic", true)
+	viper.Set("vizier_shard_lease_ttl", testLeaseTTL)
+	t.Cleanup(func() {
+		viper.Set("vizier_shard_dynamic", false)
+	})
+}
+
+func allShards() []string {
+	return vzshard.AssignShards([]string{"replica"}, "replica")
+}
+
+func TestAssigner_Static(t *testing.T) {
+	viper.Set("vizier_shard_min", 2)
+	viper.Set("vizier_shard_max", 4)
+	defer viper.Set("vizier_shard_min", 0)
+	defer viper.Set("vizier_shard_max", 255)
+
+	a, err := vzshard.NewAssigner(nil, "test")
+	require.NoError(t, err)
+	defer a.Stop()
+
+	assert.Equal(t, []string{"02", "03", "04"}, a.Shards())
+	assert.True(t, a.Owns("03"))
+	assert.False(t, a.Owns("05"))
+}
+
+func TestAssigner_JoinAndLeave(t *testing.T) {
+	nc, natsCleanup := testingutils.MustStartTestNATS(t)
+	defer natsCleanup()
+	setupDynamicSharding(t)
+
+	a1, err := vzshard.NewAssigner(nc, "test")
+	require.NoError(t, err)
+	defer a1.Stop()
+	assert.Equal(t, allShards(), a1.Shards())
+
+	var mu sync.Mutex
+	var added, removed []string
+	a1.Register(func(a []string, r []string) {
+		mu.Lock()
+		defer mu.Unlock()
+		added = append(added, a...)
+		removed = append(removed, r...)
+	})
+	mu.Lock()
+	assert.Equal(t, allShards(), added)
+	added = nil
+	mu.Unlock()
+
+	// The new replica sees both leases when it starts, so it immediately owns half of the shards.
+	a2, err := vzshard.NewAssigner(nc, "test")
+	require.NoError(t, err)
+	defer a2.Stop()
+	assert.Len(t, a2.Shards(), 128)
+
+	require.Eventually(t, func() bool {
+		return len(a1.Shards()) == 128
+	}, waitTimeout, checkInterval)
+	assert.ElementsMatch(t, allShards(), append(a1.Shards(), a2.Shards()...))
+	mu.Lock()
+	assert.Empty(t, added)
+	assert.Equal(t, a2.Shards(), removed)
+	removed = nil
+	mu.Unlock()
+
+	// Once the replica releases its lease, its shards are handed back.
+	released := a2.Shards()
+	a2.Stop()
+	require.Eventually(t, func() bool {
+		return len(a1.Shards()) == 256
+	}, waitTimeout, checkInterval)
+	mu.Lock()
+	assert.Equal(t, released, added)
+	assert.Empty(t, removed)
+	mu.Unlock()
+}
+
+func TestAssigner_LeaseExpiry(t *testing.T) {
+	nc, natsCleanup := testingutils.MustStartTestNATS(t)
+	defer natsCleanup()
+	setupDynamicSharding(t)
+
+	a, err := vzshard.NewAssigner(nc, "test")
+	require.NoError(t, err)
+	defer a.Stop()
+
+	// Add the lease of a replica that never renews it, as if it had crashed.
+	js, err := nc.JetStream()
+	require.NoError(t, err)
+	kv, err := js.KeyValue("vzshard_test")
+	require.NoError(t, err)
+	_, err = kv.Put("crashed-replica", []byte(time.Now().UTC().Format(time.RFC3339)))
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		return len(a.Shards()) == 128
+	}, waitTimeout, checkInterval)
+
+	// The assigner's own lease is renewed, so it takes over all of the shards once the other lease expires.
+	require.Eventually(t, func() bool {
+		return len(a.Shards()) == 256
+	}, waitTimeout, checkInterval)
+	assert.Equal(t, allShards(), a.Shards())
+}
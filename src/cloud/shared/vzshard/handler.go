@@ -0,0 +1,151 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package vzshard
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/nats-io/nats.go"
+	log "github.com/sirupsen/logrus"
+
+	"px.dev/pixie/src/shared/cvmsgspb"
+)
+
+// subscribeRetryInterval is how long to wait before retrying a failed subscription to a shard's topic.
+const subscribeRetryInterval = 5 * time.Second
+
+// V2CHandlerFn is the signature for a handler of messages sent from Viziers to the cloud.
+type V2CHandlerFn func(*cvmsgspb.V2CMessage)
+
+// ShardedHandler handles the V2C messages sent by the Viziers in the shards owned by an assigner. The topics of a
+// shard are subscribed to when the shard is assigned to this replica, and drained when it is assigned to another.
+type ShardedHandler struct {
+	nc       *nats.Conn
+	handlers map[string]V2CHandlerFn
+
+	mu      sync.Mutex
+	stopChs map[string]chan struct{}
+
+	done chan struct{}
+	once sync.Once
+}
+
+// NewShardedHandler creates a handler which calls handlers[topic] for the messages on each topic, for all shards
+// owned by the given assigner.
+func NewShardedHandler(nc *nats.Conn, assigner *Assigner, handlers map[string]V2CHandlerFn) *ShardedHandler {
+	h := &ShardedHandler{
+		nc:       nc,
+		handlers: handlers,
+		stopChs:  make(map[string]chan struct{}),
+		done:     make(chan struct{}),
+	}
+	assigner.Register(h.handleShardsChanged)
+	return h
+}
+
+// Stop unsubscribes from the topics of all shards. Messages that were already delivered are dropped.
+func (h *ShardedHandler) Stop() {
+	h.once.Do(func() {
+		close(h.done)
+	})
+}
+
+// handleShardsChanged starts the handlers for newly assigned shards, and drains the handlers for shards that were
+// assigned to other replicas.
+func (h *ShardedHandler) handleShardsChanged(added []string, removed []string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, shard := range removed {
+		if stopCh, ok := h.stopChs[shard]; ok {
+			close(stopCh)
+			delete(h.stopChs, shard)
+		}
+	}
+	for _, shard := range added {
+		stopCh := make(chan struct{})
+		h.stopChs[shard] = stopCh
+		for topic, handler := range h.handlers {
+			go h.handleTopic(shard, topic, handler, stopCh)
+		}
+	}
+}
+
+func (h *ShardedHandler) handleTopic(shard string, topic string, handler V2CHandlerFn, stopCh <-chan struct{}) {
+	natsCh := make(chan *nats.Msg, 8192)
+	sub := h.subscribe(fmt.Sprintf("v2c.%s.*.%s", shard, topic), natsCh, stopCh)
+	if sub == nil {
+		return
+	}
+
+	handleMsg := func(msg *nats.Msg) {
+		pb := &cvmsgspb.V2CMessage{}
+		err := proto.Unmarshal(msg.Data, pb)
+		if err != nil {
+			log.WithError(err).Error("Could not unmarshal message")
+			return
+		}
+		handler(pb)
+	}
+
+	for {
+		select {
+		case <-h.done:
+			_ = sub.Unsubscribe()
+			return
+		case <-stopCh:
+			// The shard was assigned to another replica. Stop receiving new messages, and finish handling the ones
+			// that were already delivered.
+			_ = sub.Unsubscribe()
+			for {
+				select {
+				case msg := <-natsCh:
+					handleMsg(msg)
+				default:
+					return
+				}
+			}
+		case msg := <-natsCh:
+			handleMsg(msg)
+		}
+	}
+}
+
+// subscribe subscribes to the given subject, retrying until it succeeds. Returns nil if the shard was released or the
+// handler was stopped before the subscription succeeded.
+func (h *ShardedHandler) subscribe(subject string, natsCh chan *nats.Msg, stopCh <-chan struct{}) *nats.Subscription {
+	for {
+		sub, err := h.nc.ChanSubscribe(subject, natsCh)
+		if err == nil {
+			return sub
+		}
+		log.WithError(err).WithField("subject", subject).Error("Failed to subscribe to NATS channel, retrying")
+
+		select {
+		case <-h.done:
+			return nil
+		case <-stopCh:
+			return nil
+		case <-time.After(subscribeRetryInterval):
+		}
+	}
+}
@@ -0,0 +1,146 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package vzshard_test
+
+import (
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"px.dev/pixie/src/cloud/shared/vzshard"
+	"px.dev/pixie/src/shared/cvmsgspb"
+	"px.dev/pixie/src/utils/testingutils"
+)
+
+// vizierInShard returns a random vizier ID in the given shard.
+func vizierInShard(t *testing.T, shard string) uuid.UUID {
+	b, err := hex.DecodeString(shard)
+	require.NoError(t, err)
+	id := uuid.Must(uuid.NewV4())
+	id[uuid.Size-1] = b[0]
+	return id
+}
+
+func newHeartbeatHandler(nc *nats.Conn, a *vzshard.Assigner) (*vzshard.ShardedHandler, chan string) {
+	ch := make(chan string, 1024)
+	h := vzshard.NewShardedHandler(nc, a, map[string]vzshard.V2CHandlerFn{
+		"heartbeat": func(msg *cvmsgspb.V2CMessage) {
+			ch <- msg.VizierID
+		},
+	})
+	return h, ch
+}
+
+// sendHeartbeat publishes a heartbeat from a new vizier in the given shard, and returns whether it was handled by
+// handledCh and not by any of the otherChs.
+func sendHeartbeat(t *testing.T, nc *nats.Conn, shard string, handledCh chan string, otherChs ...chan string) bool {
+	vzID := vizierInShard(t, shard)
+	b, err := (&cvmsgspb.V2CMessage{VizierID: vzID.String()}).Marshal()
+	require.NoError(t, err)
+	require.NoError(t, nc.Publish(vzshard.V2CTopic("heartbeat", vzID), b))
+	require.NoError(t, nc.Flush())
+
+	// Wait for the message to be delivered to all subscribers, including the ones that shouldn't receive it.
+	time.Sleep(100 * time.Millisecond)
+
+	received := func(ch chan string) bool {
+		found := false
+		for {
+			select {
+			case id := <-ch:
+				if id == vzID.String() {
+					found = true
+				}
+			default:
+				return found
+			}
+		}
+	}
+	handled := received(handledCh)
+	for _, ch := range otherChs {
+		if received(ch) {
+			return false
+		}
+	}
+	return handled
+}
+
+func TestShardedHandler(t *testing.T) {
+	nc, natsCleanup := testingutils.MustStartTestNATS(t)
+	defer natsCleanup()
+	setupDynamicSharding(t)
+
+	a1, err := vzshard.NewAssigner(nc, "test")
+	require.NoError(t, err)
+	defer a1.Stop()
+	h1, ch1 := newHeartbeatHandler(nc, a1)
+	defer h1.Stop()
+
+	require.Eventually(t, func() bool {
+		return sendHeartbeat(t, nc, "00", ch1) && sendHeartbeat(t, nc, "ff", ch1)
+	}, waitTimeout, checkInterval)
+
+	// Once a second replica joins, the shards assigned to it are only handled by its handler.
+	a2, err := vzshard.NewAssigner(nc, "test")
+	require.NoError(t, err)
+	defer a2.Stop()
+	h2, ch2 := newHeartbeatHandler(nc, a2)
+	defer h2.Stop()
+
+	require.Eventually(t, func() bool {
+		return len(a1.Shards()) == 128
+	}, waitTimeout, checkInterval)
+	shard1 := a1.Shards()[0]
+	shard2 := a2.Shards()[0]
+	require.Eventually(t, func() bool {
+		return sendHeartbeat(t, nc, shard2, ch2, ch1)
+	}, waitTimeout, checkInterval)
+	assert.True(t, sendHeartbeat(t, nc, shard1, ch1, ch2))
+
+	// Once the second replica leaves, its shards are handled by the first replica again.
+	h2.Stop()
+	a2.Stop()
+	require.Eventually(t, func() bool {
+		return sendHeartbeat(t, nc, shard2, ch1, ch2)
+	}, waitTimeout, checkInterval)
+}
+
+func TestShardedHandler_Stop(t *testing.T) {
+	nc, natsCleanup := testingutils.MustStartTestNATS(t)
+	defer natsCleanup()
+
+	a := vzshard.NewStaticAssigner()
+	defer a.Stop()
+	h, ch := newHeartbeatHandler(nc, a)
+
+	shard := a.Shards()[0]
+	require.Eventually(t, func() bool {
+		return sendHeartbeat(t, nc, shard, ch)
+	}, waitTimeout, checkInterval)
+
+	h.Stop()
+	require.Eventually(t, func() bool {
+		return !sendHeartbeat(t, nc, shard, ch)
+	}, waitTimeout, checkInterval)
+}
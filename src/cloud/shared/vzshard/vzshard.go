@@ -21,6 +21,7 @@ package vzshard
 import (
 	"encoding/hex"
 	"fmt"
+	"time"
 
 	"github.com/gofrs/uuid"
 	"github.com/spf13/pflag"
@@ -31,6 +32,11 @@ import (
 func SetupFlags() {
 	pflag.Int("vizier_shard_min", 0, "The min vizier shard for this program (inclusive)")
 	pflag.Int("vizier_shard_max", 255, "The max vizier shard for this program (inclusive)")
+	// With dynamic sharding, a shard that moves between replicas is unsubscribed by its old owner and subscribed by
+	// its new owner independently, as each of them sees the new leases. V2C messages published in between are not
+	// delivered to either replica, so handlers must tolerate dropped messages, as they already do across restarts.
+	pflag.Bool("vizier_shard_dynamic", false, "Whether to assign vizier shards dynamically across replicas, instead of using the min/max shard flags")
+	pflag.Duration("vizier_shard_lease_ttl", 30*time.Second, "How long a replica's shard lease lasts without being renewed")
 }
 
 func minShard() int {
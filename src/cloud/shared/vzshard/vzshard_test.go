@@ -119,3 +119,31 @@ func TestV2CDurableTopic(t *testing.T) {
 	assert.Equal(t, "v2c.d0.c5214a44-f04b-48a8-a1d4-a528f2b494d0.Durabletest",
 		vzshard.V2CDurableTopic("test", uuid.FromStringOrNil("c5214a44-f04b-48a8-a1d4-a528f2b494d0")))
 }
+
+func TestAssignShards(t *testing.T) {
+	replicas := []string{"c", "a", "b"}
+
+	a := vzshard.AssignShards(replicas, "a")
+	b := vzshard.AssignShards(replicas, "b")
+	c := vzshard.AssignShards(replicas, "c")
+
+	assert.Equal(t, 85, len(a))
+	assert.Equal(t, "00", a[0])
+	assert.Equal(t, "54", a[len(a)-1])
+	assert.Equal(t, 85, len(b))
+	assert.Equal(t, "55", b[0])
+	assert.Equal(t, 86, len(c))
+	assert.Equal(t, "ff", c[len(c)-1])
+
+	// Every shard is owned by exactly one replica.
+	all := append(append(a, b...), c...)
+	assert.Equal(t, len(vzshard.AssignShards([]string{"a"}, "a")), len(all))
+	seen := make(map[string]bool)
+	for _, shard := range all {
+		assert.False(t, seen[shard])
+		seen[shard] = true
+	}
+
+	assert.Nil(t, vzshard.AssignShards(replicas, "d"))
+	assert.Nil(t, vzshard.AssignShards(nil, "a"))
+}
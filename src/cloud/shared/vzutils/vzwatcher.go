@@ -21,6 +21,7 @@ package vzutils
 import (
 	"context"
 	"fmt"
+	"strconv"
 
 	"github.com/gofrs/uuid"
 	"github.com/gogo/protobuf/proto"
@@ -31,6 +32,7 @@ import (
 
 	"px.dev/pixie/src/cloud/shared/messages"
 	"px.dev/pixie/src/cloud/shared/messagespb"
+	"px.dev/pixie/src/cloud/shared/vzshard"
 	"px.dev/pixie/src/cloud/vzmgr/vzmgrpb"
 	svcutils "px.dev/pixie/src/shared/services/utils"
 	"px.dev/pixie/src/utils"
@@ -43,23 +45,57 @@ type VizierHandlerFn func(id uuid.UUID, orgID uuid.UUID, uid string) error
 // an error.
 type ErrorHandlerFn func(id uuid.UUID, orgID uuid.UUID, uid string, err error)
 
+// ReleaseHandlerFn is the function signature for the function that is called with the shards that were assigned to
+// another replica. Any work for Viziers in those shards should be stopped.
+type ReleaseHandlerFn func(shards []string)
+
 // Watcher tracks active Viziers and executes the registered task for each Vizier.
 type Watcher struct {
 	nc          *nats.Conn
 	vzmgrClient vzmgrpb.VZMgrServiceClient
 
-	vizierHandlerFn VizierHandlerFn
-	errorHandlerFn  ErrorHandlerFn
+	vizierHandlerFn  VizierHandlerFn
+	errorHandlerFn   ErrorHandlerFn
+	releaseHandlerFn ReleaseHandlerFn
 
 	quitCh      chan bool
 	ch          chan *nats.Msg
 	sub         *nats.Subscription
 	toShardID   string
 	fromShardID string
+	// assigner, if set, determines the shards that this watcher tracks instead of the static shard range.
+	assigner *vzshard.Assigner
 }
 
 // NewWatcher creates a new vizier watcher.
 func NewWatcher(nc *nats.Conn, vzmgrClient vzmgrpb.VZMgrServiceClient, fromShardID string, toShardID string) (*Watcher, error) {
+	vw, err := newWatcher(nc, vzmgrClient)
+	if err != nil {
+		return nil, err
+	}
+	vw.fromShardID = fromShardID
+	vw.toShardID = toShardID
+
+	go vw.runWatch()
+
+	return vw, nil
+}
+
+// NewWatcherWithAssigner creates a new vizier watcher which tracks the Viziers in the shards owned by the given
+// assigner. Viziers are picked up and released as the assigner rebalances shards across replicas.
+func NewWatcherWithAssigner(nc *nats.Conn, vzmgrClient vzmgrpb.VZMgrServiceClient, assigner *vzshard.Assigner) (*Watcher, error) {
+	vw, err := newWatcher(nc, vzmgrClient)
+	if err != nil {
+		return nil, err
+	}
+	vw.assigner = assigner
+
+	go vw.runWatch()
+
+	return vw, nil
+}
+
+func newWatcher(nc *nats.Conn, vzmgrClient vzmgrpb.VZMgrServiceClient) (*Watcher, error) {
 	ch := make(chan *nats.Msg, 4096)
 	sub, err := nc.ChanSubscribe(messages.VizierConnectedChannel, ch)
 	if err != nil {
@@ -67,19 +103,13 @@ func NewWatcher(nc *nats.Conn, vzmgrClient vzmgrpb.VZMgrServiceClient, fromShard
 		return nil, err
 	}
 
-	vw := &Watcher{
+	return &Watcher{
 		nc:          nc,
 		vzmgrClient: vzmgrClient,
 		quitCh:      make(chan bool),
 		ch:          ch,
 		sub:         sub,
-		toShardID:   toShardID,
-		fromShardID: fromShardID,
-	}
-
-	go vw.runWatch()
-
-	return vw, nil
+	}, nil
 }
 
 // runWatch subscribes to the NATS channel for any newly connected viziers, and executes the registered task for
@@ -101,6 +131,9 @@ func (w *Watcher) runWatch() {
 			}
 			vzID := utils.UUIDFromProtoOrNil(vcMsg.VizierID)
 			orgID := utils.UUIDFromProtoOrNil(vcMsg.OrgID)
+			if w.assigner != nil && !w.assigner.Owns(vzshard.VizierIDToShard(vzID)) {
+				continue
+			}
 			go w.onVizier(vzID, orgID, vcMsg.K8sUID)
 		}
 	}
@@ -122,7 +155,37 @@ func (w *Watcher) onVizier(id uuid.UUID, orgID uuid.UUID, uid string) {
 func (w *Watcher) RegisterVizierHandler(fn VizierHandlerFn) error {
 	w.vizierHandlerFn = fn
 
-	// Call VizierHandlerFn on all current-active viziers.
+	if w.assigner != nil {
+		w.assigner.Register(w.handleShardsChanged)
+		return nil
+	}
+
+	return w.handleShardRange(w.fromShardID, w.toShardID)
+}
+
+// handleShardsChanged picks up the Viziers in newly assigned shards, and releases the shards that were assigned to
+// other replicas.
+func (w *Watcher) handleShardsChanged(added []string, removed []string) {
+	if len(removed) > 0 && w.releaseHandlerFn != nil {
+		w.releaseHandlerFn(removed)
+	}
+	if len(added) == 0 {
+		return
+	}
+
+	// Fetching the Viziers may be slow, so don't block the assigner while doing so.
+	go func() {
+		for _, r := range shardRanges(added) {
+			err := w.handleShardRange(r[0], r[1])
+			if err != nil {
+				log.WithError(err).WithField("from", r[0]).WithField("to", r[1]).Error("Failed to fetch Viziers for shards")
+			}
+		}
+	}()
+}
+
+// handleShardRange calls the VizierHandlerFn on all currently-active Viziers in the given shard range.
+func (w *Watcher) handleShardRange(fromShardID string, toShardID string) error {
 	serviceAuthToken, err := getServiceCredentials(viper.GetString("jwt_signing_key"))
 	if err != nil {
 		return err
@@ -130,8 +193,8 @@ func (w *Watcher) RegisterVizierHandler(fn VizierHandlerFn) error {
 	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization",
 		fmt.Sprintf("bearer %s", serviceAuthToken))
 	vzmgrResp, err := w.vzmgrClient.GetViziersByShard(ctx, &vzmgrpb.GetViziersByShardRequest{
-		FromShardID: w.fromShardID,
-		ToShardID:   w.toShardID,
+		FromShardID: fromShardID,
+		ToShardID:   toShardID,
 	})
 	if err != nil {
 		return err
@@ -151,6 +214,11 @@ func (w *Watcher) RegisterErrorHandler(fn ErrorHandlerFn) {
 	w.errorHandlerFn = fn
 }
 
+// RegisterReleaseHandler registers the function that should be called when shards are assigned to another replica.
+func (w *Watcher) RegisterReleaseHandler(fn ReleaseHandlerFn) {
+	w.releaseHandlerFn = fn
+}
+
 // Stop stops the watcher.
 func (w *Watcher) Stop() {
 	close(w.quitCh)
@@ -160,3 +228,22 @@ func getServiceCredentials(signingKey string) (string, error) {
 	claims := svcutils.GenerateJWTForService("vzwatcher", viper.GetString("domain_name"))
 	return svcutils.SignJWTClaims(claims, signingKey)
 }
+
+// shardRanges groups the given sorted shards into contiguous ranges, so that they can be fetched with fewer requests.
+func shardRanges(shards []string) [][2]string {
+	var ranges [][2]string
+	prev := int64(-2)
+	for _, shard := range shards {
+		i, err := strconv.ParseInt(shard, 16, 32)
+		if err != nil {
+			continue
+		}
+		if i == prev+1 && len(ranges) > 0 {
+			ranges[len(ranges)-1][1] = shard
+		} else {
+			ranges = append(ranges, [2]string{shard, shard})
+		}
+		prev = i
+	}
+	return ranges
+}
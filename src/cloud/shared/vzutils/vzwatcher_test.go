@@ -19,9 +19,11 @@
 package vzutils_test
 
 import (
+	"encoding/hex"
 	"errors"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/gofrs/uuid"
 	"github.com/golang/mock/gomock"
@@ -31,6 +33,7 @@ import (
 
 	"px.dev/pixie/src/cloud/shared/messages"
 	"px.dev/pixie/src/cloud/shared/messagespb"
+	"px.dev/pixie/src/cloud/shared/vzshard"
 	"px.dev/pixie/src/cloud/shared/vzutils"
 	"px.dev/pixie/src/cloud/vzmgr/vzmgrpb"
 	mock_vzmgrpb "px.dev/pixie/src/cloud/vzmgr/vzmgrpb/mock"
@@ -138,3 +141,89 @@ func TestVzWatcher(t *testing.T) {
 		})
 	}
 }
+
+func TestVzWatcher_ReleaseShards(t *testing.T) {
+	viper.Set("jwt_signing_key", "jwtkey")
+	viper.Set("vizier_shard_dynamic", true)
+	viper.Set("vizier_shard_lease_ttl", time.Second)
+	defer viper.Set("vizier_shard_dynamic", false)
+
+	ctrl := gomock.NewController(t)
+	mockVZMgr := mock_vzmgrpb.NewMockVZMgrServiceClient(ctrl)
+	mockVZMgr.
+		EXPECT().
+		GetViziersByShard(gomock.Any(), gomock.Any()).
+		Return(&vzmgrpb.GetViziersByShardResponse{}, nil).
+		AnyTimes()
+
+	nc, natsCleanup := testingutils.MustStartTestNATS(t)
+	defer natsCleanup()
+
+	a1, err := vzshard.NewAssigner(nc, "test")
+	require.NoError(t, err)
+	defer a1.Stop()
+
+	w, err := vzutils.NewWatcherWithAssigner(nc, mockVZMgr, a1)
+	require.NoError(t, err)
+	defer w.Stop()
+
+	releasedCh := make(chan []string, 1)
+	w.RegisterReleaseHandler(func(shards []string) {
+		releasedCh <- shards
+	})
+
+	var mu sync.Mutex
+	handled := make(map[uuid.UUID]bool)
+	err = w.RegisterVizierHandler(func(id uuid.UUID, orgID uuid.UUID, uid string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		handled[id] = true
+		return nil
+	})
+	require.NoError(t, err)
+
+	// A second replica joins, and takes over half of the shards.
+	a2, err := vzshard.NewAssigner(nc, "test")
+	require.NoError(t, err)
+	defer a2.Stop()
+
+	select {
+	case released := <-releasedCh:
+		assert.Equal(t, a2.Shards(), released)
+	case <-time.After(10 * time.Second):
+		t.Fatal("Shards weren't released")
+	}
+
+	// Viziers that connect in a released shard are left to the other replica.
+	releasedVzID := vizierInShard(t, a2.Shards()[0])
+	ownedVzID := vizierInShard(t, a1.Shards()[0])
+	for _, id := range []uuid.UUID{releasedVzID, ownedVzID} {
+		msg := &messagespb.VizierConnected{
+			VizierID: utils.ProtoFromUUID(id),
+			OrgID:    utils.ProtoFromUUID(uuid.Must(uuid.NewV4())),
+			K8sUID:   "testUID",
+		}
+		b, err := msg.Marshal()
+		require.NoError(t, err)
+		require.NoError(t, nc.Publish(messages.VizierConnectedChannel, b))
+	}
+
+	// Connections are handled in order, so the released Vizier has been skipped once the owned one is handled.
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return handled[ownedVzID]
+	}, 10*time.Second, 10*time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	assert.False(t, handled[releasedVzID])
+}
+
+// vizierInShard returns a random vizier ID in the given shard.
+func vizierInShard(t *testing.T, shard string) uuid.UUID {
+	b, err := hex.DecodeString(shard)
+	require.NoError(t, err)
+	id := uuid.Must(uuid.NewV4())
+	id[uuid.Size-1] = b[0]
+	return id
+}
@@ -29,8 +29,10 @@ import (
 	"fmt"
 	"io"
 	"strings"
+	"time"
 
 	"github.com/gofrs/uuid"
+	"github.com/gogo/protobuf/types"
 	"github.com/nats-io/nats.go"
 	log "github.com/sirupsen/logrus"
 	"golang.org/x/sync/errgroup"
@@ -43,6 +45,25 @@ import (
 	"px.dev/pixie/src/shared/services/msgbus"
 )
 
+const (
+	// registerTopic is the topic of the RegisterVizierRequest that starts every stream.
+	registerTopic = "register"
+	// heartbeatTopic is the topic of the vizier's periodic heartbeat.
+	heartbeatTopic = "heartbeat"
+	// heartbeatAckTopic is the topic of the VizierHeartbeatAck sent by the bridge when rate limits
+	// are enabled, to report the throttle state of the vizier.
+	heartbeatAckTopic = "VizierHeartbeatAck"
+	// resumeTopic is the topic of the BridgeResumeRequest sent by the vizier after registration.
+	resumeTopic = "resume"
+	// resumeAckTopic is the topic of the BridgeResumeResponse sent by the cloud.
+	resumeAckTopic = "resumeAck"
+	// ackTopic is used by both sides to acknowledge processed messages when there is no other
+	// traffic to piggyback the acknowledgement on.
+	ackTopic = "bridgeAck"
+
+	ackInterval = 5 * time.Second
+)
+
 // NATSBridgeController is responsible for routing messages from Vizier to NATS. It assumes that all authentication/handshakes
 // are completed before being created.
 type NATSBridgeController struct {
@@ -58,6 +79,13 @@ type NATSBridgeController struct {
 
 	quitCh chan bool // Channel is used to signal that things should shutdown.
 	subCh  chan *nats.Msg
+
+	// session holds the sequence numbers and replay buffer that outlive this stream.
+	session *bridgeSession
+	// lastSentAck is the last V2C sequence number acknowledged to the vizier.
+	lastSentAck int64
+
+	limiter *clusterRateLimiter
 }
 
 // NewNATSBridgeController creates a NATSBridgeController.
@@ -76,6 +104,9 @@ func NewNATSBridgeController(clusterID uuid.UUID, srv vzconnpb.VZConnService_NAT
 
 		quitCh: make(chan bool),
 		subCh:  make(chan *nats.Msg, 4096),
+
+		session: &bridgeSession{buf: newReplayBuffer(defaultReplayBufferSize)},
+		limiter: newClusterRateLimiter(nil),
 	}
 }
 
@@ -97,6 +128,9 @@ func (s *NATSBridgeController) Run() error {
 		s.l.WithError(err).Error("error with ChanQueueSubscribe")
 		return err
 	}
+	// This runs after we unsubscribe, so that messages that were received but not yet forwarded
+	// can be replayed when the vizier reconnects.
+	defer s.bufferPendingMessages()
 	defer func() {
 		s.l.Infof("Unsubscribing from : %s", natsSub.Subject)
 		err := natsSub.Unsubscribe()
@@ -132,11 +166,16 @@ func (s *NATSBridgeController) Run() error {
 }
 
 func (s *NATSBridgeController) _run(ctx context.Context) error {
+	ackTicker := time.NewTicker(ackInterval)
+	defer ackTicker.Stop()
+
 	for {
 		var err error
 		select {
 		case <-s.quitCh:
 			return nil
+		case <-ackTicker.C:
+			s.sendAck()
 		case msg := <-s.subCh:
 			msgKind := cleanCloudToVizierMessageKind(msg.Subject)
 			cloudToVizierMsgCount.
@@ -148,6 +187,19 @@ func (s *NATSBridgeController) _run(ctx context.Context) error {
 
 			err = s.sendNATSMessageToGRPC(msg)
 		case msg := <-s.grpcInCh:
+			if !s.session.receiveV2C(msg) {
+				vizierToCloudDuplicateMsgCount.
+					WithLabelValues(s.clusterID.String()).
+					Inc()
+				continue
+			}
+			if msg.Topic == resumeTopic {
+				err = s.handleResume(msg)
+				break
+			}
+			if msg.Topic == ackTopic {
+				continue
+			}
 			msgKind := cleanVizierToCloudMessageKind(msg.Topic)
 			vizierToCloudMsgCount.
 				WithLabelValues(s.clusterID.String(), msgKind).
@@ -157,6 +209,9 @@ func (s *NATSBridgeController) _run(ctx context.Context) error {
 				Observe(float64(len(msg.Msg.Value)))
 
 			err = s.sendMessageToMessageBus(msg)
+			if err == nil && msg.Topic == heartbeatTopic && s.limiter.enabled() {
+				s.sendHeartbeatAck(msg)
+			}
 		case <-ctx.Done():
 			return ctx.Err()
 		}
@@ -187,11 +242,104 @@ func (s *NATSBridgeController) sendNATSMessageToGRPC(msg *nats.Msg) error {
 		Topic: topic,
 		Msg:   c2vMsg.Msg,
 	}
+	if s.session.nextC2V(outMsg) {
+		replayBufferEvictedCount.WithLabelValues(s.clusterID.String()).Inc()
+	}
 
 	s.grpcOutCh <- outMsg
 	return nil
 }
 
+// bufferPendingMessages adds the NATS messages that were received but not yet forwarded to the
+// replay buffer.
+func (s *NATSBridgeController) bufferPendingMessages() {
+	for {
+		select {
+		case msg := <-s.subCh:
+			c2vMsg := cvmsgspb.C2VMessage{}
+			if err := c2vMsg.Unmarshal(msg.Data); err != nil {
+				continue
+			}
+			outMsg := &vzconnpb.C2VBridgeMessage{
+				Topic: s.getRemoteSubject(msg.Subject),
+				Msg:   c2vMsg.Msg,
+			}
+			if s.session.nextC2V(outMsg) {
+				replayBufferEvictedCount.WithLabelValues(s.clusterID.String()).Inc()
+			}
+		default:
+			return
+		}
+	}
+}
+
+// handleResume resumes the bridge session and replays the messages the vizier missed.
+func (s *NATSBridgeController) handleResume(msg *vzconnpb.V2CBridgeMessage) error {
+	req := &vzconnpb.BridgeResumeRequest{}
+	if err := types.UnmarshalAny(msg.Msg, req); err != nil {
+		return ErrBadResumeMessage
+	}
+	resp, replay := s.session.resume(req)
+	s.l.WithField("lastReceivedSeq", req.LastReceivedSeq).
+		WithField("sessionID", req.SessionID).
+		WithField("replayCount", len(replay)).
+		WithField("replayComplete", resp.ReplayComplete).
+		Info("Resuming bridge session")
+
+	respAsAny, err := types.MarshalAny(resp)
+	if err != nil {
+		return err
+	}
+	s.grpcOutCh <- &vzconnpb.C2VBridgeMessage{
+		Topic: resumeAckTopic,
+		Msg:   respAsAny,
+	}
+	for _, m := range replay {
+		s.grpcOutCh <- m
+	}
+	replayedMsgCount.WithLabelValues(s.clusterID.String()).Add(float64(len(replay)))
+	s.lastSentAck = resp.LastReceivedSeq
+	return nil
+}
+
+// sendHeartbeatAck responds to a heartbeat with the vizier's current throttle state.
+func (s *NATSBridgeController) sendHeartbeatAck(msg *vzconnpb.V2CBridgeMessage) {
+	hb := &cvmsgspb.VizierHeartbeat{}
+	if err := types.UnmarshalAny(msg.Msg, hb); err != nil {
+		s.l.WithError(err).Error("Could not unmarshal heartbeat")
+		return
+	}
+	kinds := s.limiter.throttledKinds()
+	respAsAny, err := types.MarshalAny(&cvmsgspb.VizierHeartbeatAck{
+		Status:         cvmsgspb.HB_OK,
+		Time:           time.Now().UnixNano(),
+		SequenceNumber: hb.SequenceNumber,
+		Throttled:      len(kinds) > 0,
+		ThrottledKinds: kinds,
+	})
+	if err != nil {
+		s.l.WithError(err).Error("Could not marshal heartbeat ack")
+		return
+	}
+	s.grpcOutCh <- &vzconnpb.C2VBridgeMessage{
+		Topic: heartbeatAckTopic,
+		Msg:   respAsAny,
+	}
+}
+
+// sendAck acknowledges processed V2C messages if there are any new ones.
+func (s *NATSBridgeController) sendAck() {
+	ack, ok := s.session.pendingAck(s.lastSentAck)
+	if !ok {
+		return
+	}
+	s.grpcOutCh <- &vzconnpb.C2VBridgeMessage{
+		Topic: ackTopic,
+		Ack:   ack,
+	}
+	s.lastSentAck = ack
+}
+
 func (s *NATSBridgeController) sendMessageToMessageBus(msg *vzconnpb.V2CBridgeMessage) error {
 	cid := s.clusterID.String()
 	natsMsg := &cvmsgspb.V2CMessage{
@@ -235,11 +383,37 @@ func (s *NATSBridgeController) startStreamGRPCReader(ctx context.Context) error
 			if err != nil {
 				return err
 			}
+			if !s.admitMessage(ctx, msg) {
+				continue
+			}
 			s.grpcInCh <- msg
 		}
 	}
 }
 
+// admitMessage applies the rate limits to a message read from the stream. Throttled messages either
+// wait here, which stops us from reading the stream and pushes back on the vizier, or are dropped.
+func (s *NATSBridgeController) admitMessage(ctx context.Context, msg *vzconnpb.V2CBridgeMessage) bool {
+	kind := cleanVizierToCloudMessageKind(msg.Topic)
+	decision, err := s.limiter.admit(ctx, kind)
+	switch decision {
+	case admitDropped:
+		vizierToCloudThrottledMsgCount.
+			WithLabelValues(s.clusterID.String(), kind, "dropped").
+			Inc()
+		return false
+	case admitDelayed:
+		vizierToCloudThrottledMsgCount.
+			WithLabelValues(s.clusterID.String(), kind, "delayed").
+			Inc()
+	case admitCanceled:
+		vizierToCloudThrottledMsgCount.
+			WithLabelValues(s.clusterID.String(), kind, "canceled").
+			Inc()
+	}
+	return err == nil
+}
+
 func (s *NATSBridgeController) startStreamGRPCWriter(ctx context.Context) error {
 	s.l.Trace("Starting GRPC writer stream")
 	for {
@@ -29,6 +29,8 @@ var (
 	ErrRegistrationFailedUnknown = errors.New("registration failed unknown")
 	// ErrRegistrationFailedNotFound is the error for vizier registration failure when vizier is not found.
 	ErrRegistrationFailedNotFound = errors.New("registration failed not found")
+	// ErrBadResumeMessage is produced if a malformed resume message is received.
+	ErrBadResumeMessage = errors.New("Malformed resume message")
 	// ErrRequestChannelClosed is an error returned when the streams have already been closed.
 	ErrRequestChannelClosed = errors.New("request channel already closed")
 )
@@ -54,11 +54,14 @@ type GRPCServer struct {
 	vzDeploymentClient vzmgrpb.VZDeploymentServiceClient
 	nc                 *nats.Conn
 	st                 msgbus.Streamer
+	sessions           *sessionStore
+	rateLimits         *RateLimitConfig
 }
 
-// NewBridgeGRPCServer creates a new GRPCServer.
-func NewBridgeGRPCServer(vzmgrClient vzmgrpb.VZMgrServiceClient, vzDeploymentClient vzmgrpb.VZDeploymentServiceClient, nc *nats.Conn, st msgbus.Streamer) *GRPCServer {
-	return &GRPCServer{vzmgrClient, vzDeploymentClient, nc, st}
+// NewBridgeGRPCServer creates a new GRPCServer. If rateLimits is nil, messages from viziers are not rate limited.
+func NewBridgeGRPCServer(vzmgrClient vzmgrpb.VZMgrServiceClient, vzDeploymentClient vzmgrpb.VZDeploymentServiceClient, nc *nats.Conn, st msgbus.Streamer, rateLimits *RateLimitConfig) *GRPCServer {
+	sessions := newSessionStore(viper.GetInt("bridge_replay_buffer_size"), viper.GetDuration("bridge_session_ttl"))
+	return &GRPCServer{vzmgrClient, vzDeploymentClient, nc, st, sessions, rateLimits}
 }
 
 // RegisterVizierDeployment registers the vizier using the deployment key passed in on X-API-KEY.
@@ -104,7 +107,7 @@ func (s *GRPCServer) NATSBridge(srv vzconnpb.VZConnService_NATSBridgeServer) err
 	}
 
 	// We expect register message to be the first.
-	if msg.Topic != "register" {
+	if msg.Topic != registerTopic {
 		return convertToGRPCErr(ErrMissingRegistrationMessage)
 	}
 
@@ -123,7 +126,10 @@ func (s *GRPCServer) NATSBridge(srv vzconnpb.VZConnService_NATSBridgeServer) err
 
 	// Each Vizier calls this endpoint. Once it's called we will basically
 	// create NATS bridge and subscribe to the relevant channels.
-	c := NewNATSBridgeController(utils2.UUIDFromProtoOrNil(clusterID), srv, s.nc, s.st)
+	vzID := utils2.UUIDFromProtoOrNil(clusterID)
+	c := NewNATSBridgeController(vzID, srv, s.nc, s.st)
+	c.session = s.sessions.get(vzID)
+	c.limiter = newClusterRateLimiter(s.rateLimits)
 	bridgeMetricsCollector.Register(c)
 	defer bridgeMetricsCollector.Unregister(c)
 
@@ -176,6 +182,8 @@ func convertToGRPCErr(err error) error {
 	case ErrMissingRegistrationMessage:
 	case ErrBadRegistrationMessage:
 		return status.Error(codes.InvalidArgument, err.Error())
+	case ErrBadResumeMessage:
+		return status.Error(codes.InvalidArgument, err.Error())
 	case ErrRegistrationFailedUnknown:
 		return status.Error(codes.Unknown, err.Error())
 	case ErrRegistrationFailedNotFound:
@@ -81,7 +81,7 @@ func createTestState(t *testing.T, ctrl *gomock.Controller) (*testState, func(t
 		MaxAge:   1 * time.Minute,
 	})
 	require.NoError(t, err)
-	b := bridge.NewBridgeGRPCServer(mockVZMgr, mockVZDeployment, nc, st)
+	b := bridge.NewBridgeGRPCServer(mockVZMgr, mockVZDeployment, nc, st, nil)
 	vzconnpb.RegisterVZConnServiceServer(s, b)
 
 	eg := errgroup.Group{}
@@ -390,3 +390,82 @@ func TestNATSGRPCBridge_RegisterVizierDeployment(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, utils.ProtoFromUUID(vizierID), resp.VizierID)
 }
+
+func resumeSession(t *testing.T, stream vzconnpb.VZConnService_NATSBridgeClient, readCh chan readMsgWrapper, lastReceived int64) *vzconnpb.BridgeResumeResponse {
+	err := stream.Send(&vzconnpb.V2CBridgeMessage{
+		Topic: "resume",
+		Msg:   convertToAny(&vzconnpb.BridgeResumeRequest{LastReceivedSeq: lastReceived}),
+	})
+	require.NoError(t, err)
+
+	m := <-readCh
+	require.NoError(t, m.err)
+	require.Equal(t, "resumeAck", m.msg.Topic)
+	resp := &vzconnpb.BridgeResumeResponse{}
+	require.NoError(t, types.UnmarshalAny(m.msg.Msg, resp))
+	return resp
+}
+
+func TestNATSGRPCBridge_ResumeReplaysMissedMessages(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	ts, cleanup := createTestState(t, ctrl)
+	defer cleanup(t)
+
+	vizierID := uuid.Must(uuid.NewV4())
+	client := vzconnpb.NewVZConnServiceClient(ts.conn)
+
+	ctx1, cancel1 := context.WithCancel(context.Background())
+	stream1, err := client.NATSBridge(ctx1)
+	require.NoError(t, err)
+	readCh1 := grpcReader(stream1)
+	registerVizier(ts, vizierID, stream1, readCh1)
+	resp := resumeSession(t, stream1, readCh1, 0)
+	assert.True(t, resp.ReplayComplete)
+	assert.Equal(t, int64(0), resp.LastReceivedSeq)
+
+	t1Ch := make(chan *nats.Msg, 10)
+	sub, err := ts.nc.ChanSubscribe(vzshard.V2CTopic("t1", vizierID), t1Ch)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, sub.Unsubscribe())
+	}()
+
+	// A sequenced V2C message should be acknowledged on resume.
+	err = stream1.Send(&vzconnpb.V2CBridgeMessage{
+		Topic: "t1",
+		Seq:   1,
+		Msg: convertToAny(&cvmsgspb.VizierHeartbeat{
+			VizierID: utils.ProtoFromUUIDStrOrNil(vizierID.String()),
+		}),
+	})
+	require.NoError(t, err)
+	<-t1Ch
+
+	c2vMsg := &cvmsgspb.C2VMessage{
+		Msg: convertToAny(&cvmsgspb.VizierHeartbeatAck{}),
+	}
+	b, err := c2vMsg.Marshal()
+	require.NoError(t, err)
+	require.NoError(t, ts.nc.Publish(vzshard.C2VTopic("t2", vizierID), b))
+
+	m := <-readCh1
+	require.NoError(t, m.err)
+	assert.Equal(t, "t2", m.msg.Topic)
+	assert.Equal(t, int64(1), m.msg.Seq)
+
+	// Drop the stream without acknowledging the message and reconnect.
+	cancel1()
+
+	stream2, err := client.NATSBridge(context.Background())
+	require.NoError(t, err)
+	readCh2 := grpcReader(stream2)
+	registerVizier(ts, vizierID, stream2, readCh2)
+	resp = resumeSession(t, stream2, readCh2, 0)
+	assert.True(t, resp.ReplayComplete)
+	assert.Equal(t, int64(1), resp.LastReceivedSeq)
+
+	m = <-readCh2
+	require.NoError(t, m.err)
+	assert.Equal(t, "t2", m.msg.Topic)
+	assert.Equal(t, int64(1), m.msg.Seq)
+}
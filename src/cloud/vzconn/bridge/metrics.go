@@ -57,6 +57,24 @@ var (
 		Help: "Number of messages published to NATSfor each vizier",
 	}, []string{"vizier_id"})
 
+	vizierToCloudDuplicateMsgCount = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "vizier_to_cloud_duplicate_msg_count",
+		Help: "Number of replayed messages from vizier to cloud that were dropped as duplicates.",
+	}, []string{"vizier_id"})
+	replayedMsgCount = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "cloud_to_vizier_replayed_msg_count",
+		Help: "Number of messages from cloud to vizier replayed after the vizier resumed its session.",
+	}, []string{"vizier_id"})
+	replayBufferEvictedCount = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "cloud_to_vizier_replay_buffer_evicted_count",
+		Help: "Number of unacknowledged messages from cloud to vizier evicted from the replay buffer.",
+	}, []string{"vizier_id"})
+
+	vizierToCloudThrottledMsgCount = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "vizier_to_cloud_throttled_msg_count",
+		Help: "Number of messages from vizier to cloud that were rate limited, by the action taken (delayed, dropped, or canceled while waiting).",
+	}, []string{"vizier_id", "kind", "action"})
+
 	// Descriptions used for per vizier bridge metrics.
 	cloudToVizierNATSMsgQueueLenDesc = prometheus.NewDesc(
 		"cloud_to_vizier_nats_msg_queue_len",
@@ -73,6 +91,16 @@ var (
 		"Message queue length from vizier to cloud over GRPC.",
 		[]string{"vizier_id"},
 		nil)
+	vizierToCloudThrottledDesc = prometheus.NewDesc(
+		"vizier_to_cloud_throttled",
+		"Whether messages from the vizier are currently being rate limited.",
+		[]string{"vizier_id"},
+		nil)
+	cloudToVizierReplayBufferLenDesc = prometheus.NewDesc(
+		"cloud_to_vizier_replay_buffer_len",
+		"Number of unacknowledged messages from cloud to vizier held for replay.",
+		[]string{"vizier_id"},
+		nil)
 )
 
 func init() {
@@ -84,6 +112,11 @@ func init() {
 
 	prometheus.MustRegister(stanPublishCount)
 	prometheus.MustRegister(natsPublishCount)
+
+	prometheus.MustRegister(vizierToCloudDuplicateMsgCount)
+	prometheus.MustRegister(replayedMsgCount)
+	prometheus.MustRegister(replayBufferEvictedCount)
+	prometheus.MustRegister(vizierToCloudThrottledMsgCount)
 }
 
 func cleanVizierToCloudMessageKind(s string) string {
@@ -137,6 +170,8 @@ func (*natsBridgeMetricCollector) Describe(ch chan<- *prometheus.Desc) {
 	ch <- cloudToVizierNATSMsgQueueLenDesc
 	ch <- cloudToVizierGRPCMsgQueueLenDesc
 	ch <- vizierToCloudGRPCMsgQueueLenDesc
+	ch <- cloudToVizierReplayBufferLenDesc
+	ch <- vizierToCloudThrottledDesc
 }
 
 // Collect implements Collector.
@@ -160,6 +195,20 @@ func (n *natsBridgeMetricCollector) Collect(ch chan<- prometheus.Metric) {
 			prometheus.GaugeValue,
 			float64(len(b.grpcOutCh)),
 			cid)
+		ch <- prometheus.MustNewConstMetric(
+			cloudToVizierReplayBufferLenDesc,
+			prometheus.GaugeValue,
+			float64(b.session.bufferLen()),
+			cid)
+		throttled := 0.0
+		if len(b.limiter.throttledKinds()) > 0 {
+			throttled = 1
+		}
+		ch <- prometheus.MustNewConstMetric(
+			vizierToCloudThrottledDesc,
+			prometheus.GaugeValue,
+			throttled,
+			cid)
 
 		return true
 	})
@@ -0,0 +1,255 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package bridge
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"px.dev/pixie/src/shared/cvmsgs"
+)
+
+// messagePriority determines what happens to a V2C message when its rate limit is exceeded.
+type messagePriority int
+
+const (
+	// priorityLow messages are dropped when they are throttled.
+	priorityLow messagePriority = iota
+	// priorityNormal messages are delayed when they are throttled, which pushes back on the vizier
+	// through gRPC flow control.
+	priorityNormal
+	// priorityControl messages are never throttled. These are bridge control messages, and
+	// responses to requests made by the cloud, which someone is waiting on.
+	priorityControl
+)
+
+// throttleWindow is how long a cluster is reported as throttled after a message was rate limited.
+const throttleWindow = 30 * time.Second
+
+// lowPriorityKinds are the message kinds that are dropped first when a vizier is throttled.
+var lowPriorityKinds = map[string]bool{
+	cvmsgs.VizierMetricsChannel: true,
+}
+
+func messagePriorityForKind(kind string) messagePriority {
+	switch {
+	case kind == registerTopic || kind == resumeTopic || kind == ackTopic:
+		return priorityControl
+	case kind == "reply" || strings.HasSuffix(kind, "Response"):
+		return priorityControl
+	case lowPriorityKinds[kind]:
+		return priorityLow
+	}
+	return priorityNormal
+}
+
+// RateLimit is a token bucket configuration. Rate is in messages per second.
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+// RateLimitConfig configures the rate limits applied to messages from each vizier.
+type RateLimitConfig struct {
+	// Cluster is the limit on the total number of messages from a single vizier. A zero rate disables
+	// the limit.
+	Cluster RateLimit
+	// Kinds are limits on specific message kinds from a single vizier.
+	Kinds map[string]RateLimit
+}
+
+// Enabled returns whether any limits are configured.
+func (c *RateLimitConfig) Enabled() bool {
+	return c != nil && (c.Cluster.Rate > 0 || len(c.Kinds) > 0)
+}
+
+// ParseRateLimits parses per-kind rate limits of the form "kind=rate:burst,kind2=rate:burst".
+func ParseRateLimits(s string) (map[string]RateLimit, error) {
+	limits := make(map[string]RateLimit)
+	if strings.TrimSpace(s) == "" {
+		return limits, nil
+	}
+	for _, entry := range strings.Split(s, ",") {
+		kv := strings.SplitN(strings.TrimSpace(entry), "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return nil, fmt.Errorf("invalid rate limit %q, expected kind=rate:burst", entry)
+		}
+		l, err := parseRateLimit(kv[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid rate limit for %q: %w", kv[0], err)
+		}
+		limits[kv[0]] = l
+	}
+	return limits, nil
+}
+
+func parseRateLimit(s string) (RateLimit, error) {
+	parts := strings.SplitN(s, ":", 2)
+	r, err := strconv.ParseFloat(parts[0], 64)
+	if err != nil || r < 0 {
+		return RateLimit{}, fmt.Errorf("invalid rate %q", parts[0])
+	}
+	burst := int(r)
+	if len(parts) == 2 {
+		burst, err = strconv.Atoi(parts[1])
+		if err != nil || burst < 0 {
+			return RateLimit{}, fmt.Errorf("invalid burst %q", parts[1])
+		}
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	return RateLimit{Rate: r, Burst: burst}, nil
+}
+
+func newLimiter(l RateLimit) *rate.Limiter {
+	if l.Rate <= 0 {
+		return rate.NewLimiter(rate.Inf, 0)
+	}
+	return rate.NewLimiter(rate.Limit(l.Rate), l.Burst)
+}
+
+// admitDecision is the outcome of checking a message against the rate limits.
+type admitDecision int
+
+const (
+	admitAllowed admitDecision = iota
+	admitDelayed
+	admitDropped
+	// admitCanceled means the context ended while the message was waiting to be admitted.
+	admitCanceled
+)
+
+// clusterRateLimiter applies the rate limits for a single vizier's bridge stream.
+type clusterRateLimiter struct {
+	cluster *rate.Limiter
+	kinds   map[string]*rate.Limiter
+
+	mu sync.Mutex
+	// lastThrottled tracks when each message kind was last throttled.
+	lastThrottled map[string]time.Time
+}
+
+func newClusterRateLimiter(cfg *RateLimitConfig) *clusterRateLimiter {
+	l := &clusterRateLimiter{
+		kinds:         make(map[string]*rate.Limiter),
+		lastThrottled: make(map[string]time.Time),
+	}
+	if !cfg.Enabled() {
+		return l
+	}
+	l.cluster = newLimiter(cfg.Cluster)
+	for kind, kl := range cfg.Kinds {
+		l.kinds[kind] = newLimiter(kl)
+	}
+	return l
+}
+
+func (l *clusterRateLimiter) enabled() bool {
+	return l.cluster != nil
+}
+
+// admit checks the message against the per-kind and per-cluster limits. Low priority messages are
+// dropped if they exceed either limit. Other messages wait until they are within the limits, which
+// blocks the caller and so applies backpressure to the stream.
+func (l *clusterRateLimiter) admit(ctx context.Context, kind string) (admitDecision, error) {
+	if !l.enabled() {
+		return admitAllowed, nil
+	}
+	priority := messagePriorityForKind(kind)
+	if priority == priorityControl {
+		return admitAllowed, nil
+	}
+
+	limiters := []*rate.Limiter{l.cluster}
+	if kl, ok := l.kinds[kind]; ok {
+		limiters = append(limiters, kl)
+	}
+
+	now := time.Now()
+	var delay time.Duration
+	reservations := make([]*rate.Reservation, 0, len(limiters))
+	for _, lim := range limiters {
+		r := lim.ReserveN(now, 1)
+		if !r.OK() {
+			cancelReservations(reservations, now)
+			l.markThrottled(kind, now)
+			return admitDropped, nil
+		}
+		reservations = append(reservations, r)
+		if d := r.DelayFrom(now); d > delay {
+			delay = d
+		}
+	}
+	if delay == 0 {
+		return admitAllowed, nil
+	}
+
+	l.markThrottled(kind, now)
+	if priority == priorityLow {
+		cancelReservations(reservations, now)
+		return admitDropped, nil
+	}
+
+	t := time.NewTimer(delay)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return admitDelayed, nil
+	case <-ctx.Done():
+		cancelReservations(reservations, time.Now())
+		return admitCanceled, ctx.Err()
+	}
+}
+
+func cancelReservations(rs []*rate.Reservation, now time.Time) {
+	for _, r := range rs {
+		r.CancelAt(now)
+	}
+}
+
+func (l *clusterRateLimiter) markThrottled(kind string, now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lastThrottled[kind] = now
+}
+
+// throttledKinds returns the message kinds that were throttled within the throttle window.
+func (l *clusterRateLimiter) throttledKinds() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	var kinds []string
+	for kind, t := range l.lastThrottled {
+		if now.Sub(t) > throttleWindow {
+			delete(l.lastThrottled, kind)
+			continue
+		}
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
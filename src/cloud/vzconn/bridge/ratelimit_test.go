@@ -0,0 +1,122 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package bridge
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRateLimits(t *testing.T) {
+	limits, err := ParseRateLimits("heartbeat=1:5, VZMetrics=0.5")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]RateLimit{
+		"heartbeat": {Rate: 1, Burst: 5},
+		"VZMetrics": {Rate: 0.5, Burst: 1},
+	}, limits)
+
+	limits, err = ParseRateLimits("")
+	require.NoError(t, err)
+	assert.Empty(t, limits)
+
+	_, err = ParseRateLimits("heartbeat")
+	assert.Error(t, err)
+	_, err = ParseRateLimits("heartbeat=abc")
+	assert.Error(t, err)
+	_, err = ParseRateLimits("heartbeat=1:-1")
+	assert.Error(t, err)
+}
+
+func TestMessagePriorityForKind(t *testing.T) {
+	assert.Equal(t, priorityControl, messagePriorityForKind("resume"))
+	assert.Equal(t, priorityControl, messagePriorityForKind("reply"))
+	assert.Equal(t, priorityControl, messagePriorityForKind("VizierUpdateResponse"))
+	assert.Equal(t, priorityNormal, messagePriorityForKind("heartbeat"))
+	assert.Equal(t, priorityNormal, messagePriorityForKind("DurableMetadataUpdates"))
+	assert.Equal(t, priorityLow, messagePriorityForKind("VZMetrics"))
+}
+
+func TestClusterRateLimiter_Disabled(t *testing.T) {
+	l := newClusterRateLimiter(nil)
+	for i := 0; i < 100; i++ {
+		d, err := l.admit(context.Background(), "VZMetrics")
+		require.NoError(t, err)
+		assert.Equal(t, admitAllowed, d)
+	}
+	assert.Empty(t, l.throttledKinds())
+}
+
+func TestClusterRateLimiter_DropsLowPriorityFirst(t *testing.T) {
+	l := newClusterRateLimiter(&RateLimitConfig{
+		Cluster: RateLimit{Rate: 50, Burst: 2},
+	})
+	ctx := context.Background()
+
+	d, err := l.admit(ctx, "heartbeat")
+	require.NoError(t, err)
+	assert.Equal(t, admitAllowed, d)
+	d, err = l.admit(ctx, "VZMetrics")
+	require.NoError(t, err)
+	assert.Equal(t, admitAllowed, d)
+
+	// The cluster bucket is empty, so low priority messages are dropped...
+	d, err = l.admit(ctx, "VZMetrics")
+	require.NoError(t, err)
+	assert.Equal(t, admitDropped, d)
+	// ...while normal priority messages wait for a token.
+	start := time.Now()
+	d, err = l.admit(ctx, "heartbeat")
+	require.NoError(t, err)
+	assert.Equal(t, admitDelayed, d)
+	assert.True(t, time.Since(start) > 5*time.Millisecond)
+	// Control messages are never throttled.
+	d, err = l.admit(ctx, "reply")
+	require.NoError(t, err)
+	assert.Equal(t, admitAllowed, d)
+
+	assert.Equal(t, []string{"VZMetrics", "heartbeat"}, l.throttledKinds())
+}
+
+func TestClusterRateLimiter_PerKindLimits(t *testing.T) {
+	l := newClusterRateLimiter(&RateLimitConfig{
+		Kinds: map[string]RateLimit{
+			"DurableMetadataUpdates": {Rate: 1, Burst: 1},
+		},
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	d, err := l.admit(ctx, "DurableMetadataUpdates")
+	require.NoError(t, err)
+	assert.Equal(t, admitAllowed, d)
+	// Other kinds are not affected by the limit.
+	d, err = l.admit(ctx, "heartbeat")
+	require.NoError(t, err)
+	assert.Equal(t, admitAllowed, d)
+
+	// The next token is a second away, so we hit the context deadline while waiting.
+	d, err = l.admit(ctx, "DurableMetadataUpdates")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, admitCanceled, d)
+	assert.Equal(t, []string{"DurableMetadataUpdates"}, l.throttledKinds())
+}
@@ -0,0 +1,227 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package bridge
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid"
+
+	"px.dev/pixie/src/cloud/vzconn/vzconnpb"
+)
+
+const (
+	defaultReplayBufferSize = 1024
+	defaultSessionTTL       = 10 * time.Minute
+)
+
+// replayBuffer is a bounded buffer of C2V messages that have been sent to a vizier but not yet
+// acknowledged. When the buffer is full, the oldest message is evicted.
+type replayBuffer struct {
+	maxSize int
+	msgs    []*vzconnpb.C2VBridgeMessage
+	// evictedSeq is the highest sequence number evicted before it was acknowledged.
+	evictedSeq int64
+}
+
+func newReplayBuffer(maxSize int) *replayBuffer {
+	return &replayBuffer{maxSize: maxSize}
+}
+
+// push adds a message to the buffer, and returns true if an unacknowledged message had to be evicted.
+func (b *replayBuffer) push(msg *vzconnpb.C2VBridgeMessage) bool {
+	evicted := false
+	if len(b.msgs) >= b.maxSize {
+		b.evictedSeq = b.msgs[0].Seq
+		b.msgs = b.msgs[1:]
+		evicted = true
+	}
+	b.msgs = append(b.msgs, msg)
+	return evicted
+}
+
+// ack drops all messages with a sequence number less than or equal to seq.
+func (b *replayBuffer) ack(seq int64) {
+	i := 0
+	for i < len(b.msgs) && b.msgs[i].Seq <= seq {
+		i++
+	}
+	b.msgs = b.msgs[i:]
+}
+
+// since returns all buffered messages after seq. complete is false if messages after seq were
+// evicted and cannot be replayed.
+func (b *replayBuffer) since(seq int64) (msgs []*vzconnpb.C2VBridgeMessage, complete bool) {
+	for _, m := range b.msgs {
+		if m.Seq > seq {
+			msgs = append(msgs, m)
+		}
+	}
+	return msgs, b.evictedSeq <= seq
+}
+
+func (b *replayBuffer) len() int {
+	return len(b.msgs)
+}
+
+// bridgeSession holds the state of a vizier's bridge that outlives a single gRPC stream, so that a
+// reconnecting vizier can resume where it left off.
+type bridgeSession struct {
+	mu sync.Mutex
+	// resumable is set once the vizier has sent a resume request. Legacy viziers never do, so we
+	// don't buffer messages for them.
+	resumable bool
+	// lastC2VSeq is the last sequence number assigned to a C2V message.
+	lastC2VSeq int64
+	// lastV2CSeq is the highest V2C sequence number that was processed.
+	lastV2CSeq int64
+	// sessionID identifies the vizier process whose sequence numbers are tracked.
+	sessionID  string
+	buf        *replayBuffer
+	lastActive time.Time
+}
+
+// nextC2V assigns a sequence number to the message and buffers it for replay. Returns true if an
+// unacknowledged message was evicted to make room.
+func (s *bridgeSession) nextC2V(msg *vzconnpb.C2VBridgeMessage) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastC2VSeq++
+	msg.Seq = s.lastC2VSeq
+	if !s.resumable {
+		return false
+	}
+	msg.Ack = s.lastV2CSeq
+	return s.buf.push(msg)
+}
+
+// receiveV2C records the sequence number and acknowledgement of a V2C message. Returns false if
+// the message is a duplicate that has already been processed.
+func (s *bridgeSession) receiveV2C(msg *vzconnpb.V2CBridgeMessage) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastActive = time.Now()
+	if msg.Ack > 0 {
+		s.buf.ack(msg.Ack)
+	}
+	if msg.Seq == 0 {
+		return true
+	}
+	if msg.Seq <= s.lastV2CSeq {
+		return false
+	}
+	s.lastV2CSeq = msg.Seq
+	return true
+}
+
+// resume marks the session as resumable and returns the messages that need to be replayed.
+func (s *bridgeSession) resume(req *vzconnpb.BridgeResumeRequest) (*vzconnpb.BridgeResumeResponse, []*vzconnpb.C2VBridgeMessage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.resumable = true
+	s.lastActive = time.Now()
+	if req.SessionID != s.sessionID {
+		// The vizier restarted with a fresh sequence space. Its previous messages have already been
+		// processed, and the buffered messages were meant for the old process.
+		s.sessionID = req.SessionID
+		s.lastV2CSeq = 0
+		s.buf = newReplayBuffer(s.buf.maxSize)
+	}
+	// The vizier has processed everything up to last_received_seq, so there's no need to keep it around.
+	s.buf.ack(req.LastReceivedSeq)
+	msgs, complete := s.buf.since(req.LastReceivedSeq)
+	return &vzconnpb.BridgeResumeResponse{
+		LastReceivedSeq: s.lastV2CSeq,
+		ReplayComplete:  complete,
+	}, msgs
+}
+
+// pendingAck returns the V2C sequence number to acknowledge if it has changed since the last call.
+func (s *bridgeSession) pendingAck(lastSent int64) (int64, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.resumable || s.lastV2CSeq <= lastSent {
+		return lastSent, false
+	}
+	return s.lastV2CSeq, true
+}
+
+func (s *bridgeSession) bufferLen() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.buf.len()
+}
+
+// sessionStore keeps bridge sessions per cluster. Sessions that have been idle for longer than the
+// TTL are dropped, and a vizier reconnecting after that starts a fresh session.
+type sessionStore struct {
+	mu             sync.Mutex
+	sessions       map[uuid.UUID]*bridgeSession
+	maxBufferSize  int
+	ttl            time.Duration
+	lastCollection time.Time
+}
+
+func newSessionStore(maxBufferSize int, ttl time.Duration) *sessionStore {
+	if maxBufferSize <= 0 {
+		maxBufferSize = defaultReplayBufferSize
+	}
+	if ttl <= 0 {
+		ttl = defaultSessionTTL
+	}
+	return &sessionStore{
+		sessions:      make(map[uuid.UUID]*bridgeSession),
+		maxBufferSize: maxBufferSize,
+		ttl:           ttl,
+	}
+}
+
+// get returns the session for the cluster, creating one if it doesn't exist.
+func (st *sessionStore) get(clusterID uuid.UUID) *bridgeSession {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(st.lastCollection) > st.ttl {
+		st.collectLocked(now)
+	}
+
+	s, ok := st.sessions[clusterID]
+	if !ok {
+		s = &bridgeSession{buf: newReplayBuffer(st.maxBufferSize)}
+		st.sessions[clusterID] = s
+	}
+	s.mu.Lock()
+	s.lastActive = now
+	s.mu.Unlock()
+	return s
+}
+
+func (st *sessionStore) collectLocked(now time.Time) {
+	st.lastCollection = now
+	for id, s := range st.sessions {
+		s.mu.Lock()
+		expired := now.Sub(s.lastActive) > st.ttl
+		s.mu.Unlock()
+		if expired {
+			delete(st.sessions, id)
+		}
+	}
+}
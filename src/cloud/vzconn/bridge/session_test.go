@@ -0,0 +1,150 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package bridge
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"px.dev/pixie/src/cloud/vzconn/vzconnpb"
+)
+
+func seqs(msgs []*vzconnpb.C2VBridgeMessage) []int64 {
+	s := make([]int64, len(msgs))
+	for i, m := range msgs {
+		s[i] = m.Seq
+	}
+	return s
+}
+
+func TestBridgeSession_ReplayAfterResume(t *testing.T) {
+	s := &bridgeSession{buf: newReplayBuffer(10)}
+
+	// Messages sent before the vizier resumes are sequenced but not buffered.
+	first := &vzconnpb.C2VBridgeMessage{Topic: "t"}
+	assert.False(t, s.nextC2V(first))
+	assert.Equal(t, int64(1), first.Seq)
+	assert.Equal(t, 0, s.bufferLen())
+
+	resp, replay := s.resume(&vzconnpb.BridgeResumeRequest{})
+	assert.True(t, resp.ReplayComplete)
+	assert.Empty(t, replay)
+
+	for i := 0; i < 4; i++ {
+		s.nextC2V(&vzconnpb.C2VBridgeMessage{Topic: "t"})
+	}
+	assert.Equal(t, 4, s.bufferLen())
+
+	// The vizier acks up to 3 on a regular message.
+	assert.True(t, s.receiveV2C(&vzconnpb.V2CBridgeMessage{Topic: "hb", Seq: 1, Ack: 3}))
+	assert.Equal(t, 2, s.bufferLen())
+
+	// The stream drops, and the vizier reconnects having processed 4.
+	resp, replay = s.resume(&vzconnpb.BridgeResumeRequest{LastReceivedSeq: 4})
+	assert.True(t, resp.ReplayComplete)
+	assert.Equal(t, int64(1), resp.LastReceivedSeq)
+	assert.Equal(t, []int64{5}, seqs(replay))
+}
+
+func TestBridgeSession_DedupesV2C(t *testing.T) {
+	s := &bridgeSession{buf: newReplayBuffer(10)}
+
+	assert.True(t, s.receiveV2C(&vzconnpb.V2CBridgeMessage{Seq: 1}))
+	assert.True(t, s.receiveV2C(&vzconnpb.V2CBridgeMessage{Seq: 2}))
+	// Replayed messages that were already processed are dropped.
+	assert.False(t, s.receiveV2C(&vzconnpb.V2CBridgeMessage{Seq: 1}))
+	assert.False(t, s.receiveV2C(&vzconnpb.V2CBridgeMessage{Seq: 2}))
+	assert.True(t, s.receiveV2C(&vzconnpb.V2CBridgeMessage{Seq: 3}))
+	// Unsequenced messages from legacy viziers are always processed.
+	assert.True(t, s.receiveV2C(&vzconnpb.V2CBridgeMessage{}))
+	assert.True(t, s.receiveV2C(&vzconnpb.V2CBridgeMessage{}))
+
+	_, ok := s.pendingAck(0)
+	assert.False(t, ok, "acks are only sent on resumed sessions")
+	s.resume(&vzconnpb.BridgeResumeRequest{})
+	ack, ok := s.pendingAck(0)
+	require.True(t, ok)
+	assert.Equal(t, int64(3), ack)
+	_, ok = s.pendingAck(3)
+	assert.False(t, ok)
+}
+
+func TestBridgeSession_EvictionReportsIncompleteReplay(t *testing.T) {
+	s := &bridgeSession{buf: newReplayBuffer(2)}
+	s.resume(&vzconnpb.BridgeResumeRequest{})
+
+	assert.False(t, s.nextC2V(&vzconnpb.C2VBridgeMessage{}))
+	assert.False(t, s.nextC2V(&vzconnpb.C2VBridgeMessage{}))
+	assert.True(t, s.nextC2V(&vzconnpb.C2VBridgeMessage{}))
+
+	resp, replay := s.resume(&vzconnpb.BridgeResumeRequest{LastReceivedSeq: 0})
+	assert.False(t, resp.ReplayComplete)
+	assert.Equal(t, []int64{2, 3}, seqs(replay))
+
+	// If the vizier already processed the evicted message, the replay is complete.
+	resp, replay = s.resume(&vzconnpb.BridgeResumeRequest{LastReceivedSeq: 1})
+	assert.True(t, resp.ReplayComplete)
+	assert.Equal(t, []int64{2, 3}, seqs(replay))
+}
+
+func TestBridgeSession_ResumeAfterVizierRestart(t *testing.T) {
+	s := &bridgeSession{buf: newReplayBuffer(10)}
+	s.resume(&vzconnpb.BridgeResumeRequest{SessionID: "first"})
+
+	assert.True(t, s.receiveV2C(&vzconnpb.V2CBridgeMessage{Seq: 1}))
+	assert.True(t, s.receiveV2C(&vzconnpb.V2CBridgeMessage{Seq: 2}))
+	s.nextC2V(&vzconnpb.C2VBridgeMessage{Topic: "t"})
+	s.nextC2V(&vzconnpb.C2VBridgeMessage{Topic: "t"})
+	assert.Equal(t, 2, s.bufferLen())
+
+	// Reconnecting with the same session replays the unacknowledged messages.
+	resp, replay := s.resume(&vzconnpb.BridgeResumeRequest{SessionID: "first", LastReceivedSeq: 1})
+	assert.Equal(t, int64(2), resp.LastReceivedSeq)
+	assert.Equal(t, []int64{2}, seqs(replay))
+
+	// The vizier restarts, and starts its sequence numbers over.
+	resp, replay = s.resume(&vzconnpb.BridgeResumeRequest{SessionID: "second"})
+	assert.Equal(t, int64(0), resp.LastReceivedSeq)
+	assert.True(t, resp.ReplayComplete)
+	assert.Empty(t, replay)
+	assert.Equal(t, 0, s.bufferLen())
+
+	// The new process's messages aren't dropped as duplicates.
+	assert.True(t, s.receiveV2C(&vzconnpb.V2CBridgeMessage{Seq: 1}))
+	assert.False(t, s.receiveV2C(&vzconnpb.V2CBridgeMessage{Seq: 1}))
+}
+
+func TestSessionStore_Get(t *testing.T) {
+	st := newSessionStore(0, time.Minute)
+	id := uuid.Must(uuid.NewV4())
+
+	s := st.get(id)
+	assert.Equal(t, defaultReplayBufferSize, s.buf.maxSize)
+	assert.Same(t, s, st.get(id))
+	assert.NotSame(t, s, st.get(uuid.Must(uuid.NewV4())))
+
+	// Idle sessions are collected.
+	s.lastActive = time.Now().Add(-2 * time.Minute)
+	st.lastCollection = time.Now().Add(-2 * time.Minute)
+	assert.NotSame(t, s, st.get(id))
+}
@@ -21,6 +21,7 @@ package main
 import (
 	"net/http"
 	_ "net/http/pprof"
+	"time"
 
 	"github.com/nats-io/nats.go"
 	log "github.com/sirupsen/logrus"
@@ -45,6 +46,12 @@ var natsErrorCounter *messages.NatsErrorCounter
 func init() {
 	pflag.String("vzmgr_service", "kubernetes:///vzmgr-service.plc:51800", "The profile service url (load balancer/list is ok)")
 	pflag.String("domain_name", "dev.withpixie.dev", "The domain name of Pixie Cloud")
+	pflag.Int("bridge_replay_buffer_size", 1024, "The max number of unacknowledged messages buffered per vizier for replay on reconnect")
+	pflag.Duration("bridge_session_ttl", 10*time.Minute, "How long a disconnected vizier's bridge session is kept around for it to resume")
+	pflag.Float64("bridge_cluster_rate_limit", 0, "The max rate of messages per second accepted from a single vizier, 0 disables rate limiting")
+	pflag.Int("bridge_cluster_rate_burst", 500, "The burst size for the per vizier rate limit")
+	pflag.String("bridge_kind_rate_limits", "", "Per message kind rate limits for each vizier, as kind=rate:burst pairs separated by commas. "+
+		"For example: heartbeat=1:5,VZMetrics=10:20")
 
 	natsErrorCounter = messages.NewNatsErrorCounter()
 }
@@ -75,6 +82,24 @@ func mustSetupNATSAndJetStream() (*nats.Conn, msgbus.Streamer) {
 	return nc, strmr
 }
 
+func mustLoadRateLimits() *bridge.RateLimitConfig {
+	kinds, err := bridge.ParseRateLimits(viper.GetString("bridge_kind_rate_limits"))
+	if err != nil {
+		log.WithError(err).Fatal("Invalid bridge_kind_rate_limits")
+	}
+	cfg := &bridge.RateLimitConfig{
+		Cluster: bridge.RateLimit{
+			Rate:  viper.GetFloat64("bridge_cluster_rate_limit"),
+			Burst: viper.GetInt("bridge_cluster_rate_burst"),
+		},
+		Kinds: kinds,
+	}
+	if !cfg.Enabled() {
+		return nil
+	}
+	return cfg
+}
+
 func main() {
 	services.SetupService("vzconn-service", 51600)
 	services.PostFlagSetupAndParse()
@@ -111,7 +136,7 @@ func main() {
 		log.WithError(err).Fatal("failed to initialize vizer manager RPC client")
 		panic(err)
 	}
-	svr := bridge.NewBridgeGRPCServer(vzmgrClient, vzdeployClient, nc, strmr)
+	svr := bridge.NewBridgeGRPCServer(vzmgrClient, vzdeployClient, nc, strmr, mustLoadRateLimits())
 	vzconnpb.RegisterVZConnServiceServer(s.GRPCServer(), svr)
 
 	s.Start()
@@ -0,0 +1,105 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"px.dev/pixie/src/cloud/vzmgr/vzmgrpb"
+)
+
+func TestMaintenanceWindowContains(t *testing.T) {
+	// 2021-06-04 is a Friday.
+	at := func(day, hour int) time.Time {
+		return time.Date(2021, time.June, day, hour, 30, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name     string
+		window   *vzmgrpb.MaintenanceWindow
+		t        time.Time
+		expected bool
+	}{
+		{
+			name:     "in window",
+			window:   &vzmgrpb.MaintenanceWindow{StartHour: 2, EndHour: 6},
+			t:        at(4, 2),
+			expected: true,
+		},
+		{
+			name:   "at end hour",
+			window: &vzmgrpb.MaintenanceWindow{StartHour: 2, EndHour: 6},
+			t:      at(4, 6),
+		},
+		{
+			name:   "wrong day",
+			window: &vzmgrpb.MaintenanceWindow{Days: []string{"sat"}, StartHour: 2, EndHour: 6},
+			t:      at(4, 3),
+		},
+		{
+			name:     "wrap around before midnight",
+			window:   &vzmgrpb.MaintenanceWindow{StartHour: 22, EndHour: 4},
+			t:        at(4, 23),
+			expected: true,
+		},
+		{
+			name:     "wrap around after midnight",
+			window:   &vzmgrpb.MaintenanceWindow{StartHour: 22, EndHour: 4},
+			t:        at(5, 3),
+			expected: true,
+		},
+		{
+			name:   "wrap around outside window",
+			window: &vzmgrpb.MaintenanceWindow{StartHour: 22, EndHour: 4},
+			t:      at(4, 12),
+		},
+		{
+			name:   "wrap around at end hour",
+			window: &vzmgrpb.MaintenanceWindow{StartHour: 22, EndHour: 4},
+			t:      at(5, 4),
+		},
+		{
+			// A Friday night window continues into Saturday morning.
+			name:     "wrap around continues into next day",
+			window:   &vzmgrpb.MaintenanceWindow{Days: []string{"fri"}, StartHour: 22, EndHour: 4},
+			t:        at(5, 1),
+			expected: true,
+		},
+		{
+			name:   "wrap around started on previous day",
+			window: &vzmgrpb.MaintenanceWindow{Days: []string{"fri"}, StartHour: 22, EndHour: 4},
+			t:      at(4, 1),
+		},
+		{
+			name:     "wrap around from sunday into monday",
+			window:   &vzmgrpb.MaintenanceWindow{Days: []string{"sun"}, StartHour: 23, EndHour: 2},
+			t:        at(7, 0),
+			expected: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, maintenanceWindowContains(test.window, test.t))
+		})
+	}
+}
@@ -0,0 +1,192 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/gofrs/uuid"
+	"github.com/jmoiron/sqlx"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"px.dev/pixie/src/cloud/vzmgr/vzmgrpb"
+	"px.dev/pixie/src/shared/k8s/metadatapb"
+	"px.dev/pixie/src/shared/services/authcontext"
+	"px.dev/pixie/src/shared/services/msgbus"
+	"px.dev/pixie/src/utils"
+)
+
+// The maximum number of updates sent in a single watch response.
+const maxWatchBatchSize = 256
+
+// MetadataWatcher implements the VZMetadataService. It streams the metadata updates that the
+// MetadataReader applied for a vizier, by reading back the ordered updates published for the indexer.
+type MetadataWatcher struct {
+	db *sqlx.DB
+	st msgbus.Streamer
+}
+
+// NewMetadataWatcher creates a new MetadataWatcher. The streamer must be able to read from the
+// metadata index stream.
+func NewMetadataWatcher(db *sqlx.DB, st msgbus.Streamer) *MetadataWatcher {
+	return &MetadataWatcher{db: db, st: st}
+}
+
+// lookupK8sUID returns the K8s UID of the vizier, if it belongs to the caller's org.
+func (w *MetadataWatcher) lookupK8sUID(vizierID uuid.UUID, orgID string) (string, error) {
+	query := `SELECT cluster_uid FROM vizier_cluster WHERE id=$1 AND org_id=$2`
+	var k8sUID sql.NullString
+	err := w.db.QueryRow(query, vizierID, orgID).Scan(&k8sUID)
+	if err == sql.ErrNoRows {
+		return "", status.Error(codes.NotFound, "invalid cluster ID for org")
+	}
+	if err != nil {
+		return "", err
+	}
+	if k8sUID.String == "" {
+		return "", status.Error(codes.FailedPrecondition, "cluster has not connected yet")
+	}
+	return k8sUID.String, nil
+}
+
+func (w *MetadataWatcher) latestUpdateVersion(subject string) (int64, error) {
+	msg, err := w.st.PeekLatestMessage(subject)
+	if err != nil {
+		return 0, err
+	}
+	// nil message means the queue was empty.
+	if msg == nil {
+		return 0, nil
+	}
+	ru := metadatapb.ResourceUpdate{}
+	if err := ru.Unmarshal(msg.Data()); err != nil {
+		return 0, err
+	}
+	return ru.UpdateVersion, nil
+}
+
+// WatchMetadataUpdates streams the metadata updates for a vizier in the order they were applied.
+func (w *MetadataWatcher) WatchMetadataUpdates(req *vzmgrpb.WatchMetadataUpdatesRequest, srv vzmgrpb.VZMetadataService_WatchMetadataUpdatesServer) error {
+	ctx := srv.Context()
+	vizierID := utils.UUIDFromProtoOrNil(req.VizierID)
+	if vizierID == uuid.Nil {
+		return status.Error(codes.InvalidArgument, "invalid cluster id")
+	}
+
+	sCtx, err := authcontext.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+	k8sUID, err := w.lookupK8sUID(vizierID, sCtx.Claims.GetUserClaims().OrgID)
+	if err != nil {
+		return err
+	}
+
+	subject := fmt.Sprintf("%s.%s", indexerMetadataTopic, k8sUID)
+	latest, err := w.latestUpdateVersion(subject)
+	if err != nil {
+		return status.Error(codes.Internal, "failed to read latest metadata update")
+	}
+
+	from := req.FromUpdateVersion
+	if from < 0 {
+		from = latest
+	}
+	if !req.Follow && from >= latest {
+		return nil
+	}
+
+	// Each watch uses its own consumer, so that it reads the whole stream independently of the
+	// indexer and other watchers.
+	consumerID, err := uuid.NewV4()
+	if err != nil {
+		return err
+	}
+	msgCh := make(chan msgbus.Msg, maxWatchBatchSize)
+	done := make(chan struct{})
+	defer close(done)
+	sub, err := w.st.PersistentSubscribe(subject, fmt.Sprintf("vzmgr-watch-%s", consumerID), func(msg msgbus.Msg) {
+		select {
+		case msgCh <- msg:
+		case <-done:
+		}
+	})
+	if err != nil {
+		return status.Error(codes.Internal, "failed to subscribe to metadata updates")
+	}
+	defer func() {
+		if err := sub.Close(); err != nil {
+			log.WithError(err).Error("Failed to close metadata watch subscription")
+		}
+	}()
+
+	for {
+		var batch []*metadatapb.ResourceUpdate
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg := <-msgCh:
+			batch = appendWatchUpdate(batch, msg, from)
+		}
+		// Batch up any other updates that are already waiting.
+	drain:
+		for len(batch) < maxWatchBatchSize {
+			select {
+			case msg := <-msgCh:
+				batch = appendWatchUpdate(batch, msg, from)
+			default:
+				break drain
+			}
+		}
+		if len(batch) == 0 {
+			continue
+		}
+
+		if err := srv.Send(&vzmgrpb.WatchMetadataUpdatesResponse{Updates: batch}); err != nil {
+			return err
+		}
+		from = batch[len(batch)-1].UpdateVersion
+		if !req.Follow && from >= latest {
+			return nil
+		}
+	}
+}
+
+// appendWatchUpdate acks the message and appends the update it contains, if it is newer than from.
+func appendWatchUpdate(batch []*metadatapb.ResourceUpdate, msg msgbus.Msg, from int64) []*metadatapb.ResourceUpdate {
+	if err := msg.Ack(); err != nil {
+		log.WithError(err).Error("Failed to ack JetStream message")
+	}
+	update := &metadatapb.ResourceUpdate{}
+	if err := update.Unmarshal(msg.Data()); err != nil {
+		log.WithError(err).Error("Failed to unmarshal metadata update")
+		return batch
+	}
+	lastVersion := from
+	if len(batch) > 0 {
+		lastVersion = batch[len(batch)-1].UpdateVersion
+	}
+	if update.UpdateVersion <= lastVersion {
+		return batch
+	}
+	return append(batch, update)
+}
@@ -0,0 +1,155 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"px.dev/pixie/src/cloud/vzmgr/controllers"
+	"px.dev/pixie/src/cloud/vzmgr/vzmgrpb"
+	"px.dev/pixie/src/shared/k8s/metadatapb"
+	"px.dev/pixie/src/shared/services/msgbus"
+	"px.dev/pixie/src/utils"
+	"px.dev/pixie/src/utils/testingutils"
+)
+
+type fakeWatchServer struct {
+	grpc.ServerStream
+	ctx     context.Context
+	updates chan *metadatapb.ResourceUpdate
+}
+
+func (f *fakeWatchServer) Context() context.Context {
+	return f.ctx
+}
+
+func (f *fakeWatchServer) Send(resp *vzmgrpb.WatchMetadataUpdatesResponse) error {
+	for _, u := range resp.Updates {
+		f.updates <- u
+	}
+	return nil
+}
+
+func setupMetadataWatcher(t *testing.T, versions []int64) (*controllers.MetadataWatcher, nats.JetStreamContext, func()) {
+	nc, natsCleanup := testingutils.MustStartTestNATS(t)
+	js := msgbus.MustConnectJetStream(nc)
+	_ = js.PurgeStream("MetadataIndex")
+	st, err := msgbus.NewJetStreamStreamer(nc, js, &nats.StreamConfig{
+		Name:     "MetadataIndex",
+		Subjects: []string{"MetadataIndex.*"},
+		MaxAge:   time.Minute * 2,
+	})
+	require.NoError(t, err)
+
+	for _, v := range versions {
+		publishIndexUpdate(t, js, v)
+	}
+	return controllers.NewMetadataWatcher(db, st), js, natsCleanup
+}
+
+func publishIndexUpdate(t *testing.T, js nats.JetStreamContext, version int64) {
+	b, err := (&metadatapb.ResourceUpdate{UpdateVersion: version, PrevUpdateVersion: version - 1}).Marshal()
+	require.NoError(t, err)
+	_, err = js.Publish("MetadataIndex.cUID", b)
+	require.NoError(t, err)
+}
+
+func receiveVersions(t *testing.T, ch chan *metadatapb.ResourceUpdate, n int) []int64 {
+	var versions []int64
+	for i := 0; i < n; i++ {
+		select {
+		case u := <-ch:
+			versions = append(versions, u.UpdateVersion)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for update %d", i)
+		}
+	}
+	return versions
+}
+
+func TestMetadataWatcher_WatchMetadataUpdates_Replay(t *testing.T) {
+	mustLoadTestData(db)
+	w, _, cleanup := setupMetadataWatcher(t, []int64{1, 2, 3, 4, 5})
+	defer cleanup()
+
+	srv := &fakeWatchServer{ctx: CreateTestContext(), updates: make(chan *metadatapb.ResourceUpdate, 10)}
+	err := w.WatchMetadataUpdates(&vzmgrpb.WatchMetadataUpdatesRequest{
+		VizierID:          utils.ProtoFromUUIDStrOrNil("123e4567-e89b-12d3-a456-426655440001"),
+		FromUpdateVersion: 2,
+	}, srv)
+	require.NoError(t, err)
+	close(srv.updates)
+
+	var versions []int64
+	for u := range srv.updates {
+		versions = append(versions, u.UpdateVersion)
+	}
+	assert.Equal(t, []int64{3, 4, 5}, versions)
+}
+
+func TestMetadataWatcher_WatchMetadataUpdates_Follow(t *testing.T) {
+	mustLoadTestData(db)
+	w, js, cleanup := setupMetadataWatcher(t, []int64{1, 2, 3})
+	defer cleanup()
+
+	ctx, cancel := context.WithCancel(CreateTestContext())
+	srv := &fakeWatchServer{ctx: ctx, updates: make(chan *metadatapb.ResourceUpdate, 10)}
+	errCh := make(chan error)
+	go func() {
+		errCh <- w.WatchMetadataUpdates(&vzmgrpb.WatchMetadataUpdatesRequest{
+			VizierID:          utils.ProtoFromUUIDStrOrNil("123e4567-e89b-12d3-a456-426655440001"),
+			FromUpdateVersion: -1,
+			Follow:            true,
+		}, srv)
+	}()
+
+	publishIndexUpdate(t, js, 4)
+	publishIndexUpdate(t, js, 5)
+	assert.Equal(t, []int64{4, 5}, receiveVersions(t, srv.updates, 2))
+
+	cancel()
+	require.NoError(t, <-errCh)
+}
+
+func TestMetadataWatcher_WatchMetadataUpdates_OtherOrg(t *testing.T) {
+	mustLoadTestData(db)
+	w, _, cleanup := setupMetadataWatcher(t, nil)
+	defer cleanup()
+
+	srv := &fakeWatchServer{ctx: CreateTestContext(), updates: make(chan *metadatapb.ResourceUpdate, 10)}
+	err := w.WatchMetadataUpdates(&vzmgrpb.WatchMetadataUpdatesRequest{
+		VizierID: utils.ProtoFromUUIDStrOrNil("223e4567-e89b-12d3-a456-426655440003"),
+	}, srv)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	err = w.WatchMetadataUpdates(&vzmgrpb.WatchMetadataUpdatesRequest{
+		VizierID: utils.ProtoFromUUID(uuid.Nil),
+	}, srv)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
@@ -31,7 +31,6 @@ import (
 	"time"
 
 	"github.com/gofrs/uuid"
-	"github.com/gogo/protobuf/proto"
 	"github.com/gogo/protobuf/types"
 	"github.com/jmoiron/sqlx"
 	"github.com/nats-io/nats.go"
@@ -41,6 +40,7 @@ import (
 	"github.com/spf13/viper"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"k8s.io/apimachinery/pkg/labels"
 
 	"px.dev/pixie/src/api/proto/uuidpb"
 	"px.dev/pixie/src/cloud/shared/messages"
@@ -72,16 +72,19 @@ type Server struct {
 	nc      *nats.Conn
 	updater VzUpdater
 
-	done chan struct{}
+	assigner       *vzshard.Assigner
+	shardedHandler *vzshard.ShardedHandler
+
 	once sync.Once
 }
 
 // VzUpdater is the interface for the module responsible for updating Vizier.
 type VzUpdater interface {
 	UpdateOrInstallVizier(vizierID uuid.UUID, version string, redeployEtcd bool) (*cvmsgspb.V2CMessage, error)
-	VersionUpToDate(version string) bool
+	VersionUpToDate(version string, targetVersion string) bool
+	ResolveVersion(channel vzmgrpb.VizierUpdateChannel, versionsBehind int) string
 	// AddToUpdateQueue must be idempotent since we Queue based on heartbeats and reported version.
-	AddToUpdateQueue(vizierID uuid.UUID) bool
+	AddToUpdateQueue(vizierID uuid.UUID, version string) bool
 }
 
 // New creates a new server.
@@ -91,51 +94,39 @@ func New(db *sqlx.DB, dbKey string, nc *nats.Conn, updater VzUpdater) *Server {
 		dbKey:   dbKey,
 		nc:      nc,
 		updater: updater,
-		done:    make(chan struct{}),
 	}
 
 	_ = prometheus.Register(NewStatusMetricsCollector(db))
 
-	for _, shard := range vzshard.GenerateShardRange() {
-		s.startShardedHandler(shard, "heartbeat", s.HandleVizierHeartbeat)
-	}
+	s.handleRequests()
 
 	return s
 }
 
-// Stop performs any necessary cleanup before shutdown.
-func (s *Server) Stop() {
-	s.once.Do(func() {
-		close(s.done)
-	})
-}
-
-func (s *Server) startShardedHandler(shard string, topic string, handler HandleNATSMessageFunc) {
+func (s *Server) handleRequests() {
 	if s.nc == nil {
 		return
 	}
-	natsCh := make(chan *nats.Msg, 8192)
-	sub, err := s.nc.ChanSubscribe(fmt.Sprintf("v2c.%s.*.%s", shard, topic), natsCh)
-	if err != nil {
-		log.WithError(err).Fatal("Failed to subscribe to NATS channel")
-	}
-
-	go func() {
-		for {
-			select {
-			case <-s.done:
-				sub.Unsubscribe()
-				return
-			case msg := <-natsCh:
-				pb := &cvmsgspb.V2CMessage{}
-				err := proto.Unmarshal(msg.Data, pb)
-				if err != nil {
-					log.WithError(err).Error("Could not unmarshal message")
-				}
-				handler(pb)
-			}
+	assigner, err := vzshard.NewAssigner(s.nc, "vzmgr")
+	if err != nil {
+		log.WithError(err).Fatal("Failed to set up vizier shard assignment")
+	}
+	s.assigner = assigner
+	s.shardedHandler = vzshard.NewShardedHandler(s.nc, assigner, map[string]vzshard.V2CHandlerFn{
+		"heartbeat": s.HandleVizierHeartbeat,
+	})
+}
+
+// Stop performs any necessary cleanup before shutdown.
+func (s *Server) Stop() {
+	s.once.Do(func() {
+		if s.shardedHandler != nil {
+			s.shardedHandler.Stop()
+		}
+		if s.assigner != nil {
+			s.assigner.Stop()
 		}
-	}()
+	})
 }
 
 type vizierStatus cvmsgspb.VizierStatus
@@ -245,6 +236,38 @@ func (s *Server) GetViziersByOrg(ctx context.Context, orgID *uuidpb.UUID) (*vzmg
 	return &vzmgrpb.GetViziersByOrgResponse{VizierIDs: ids}, nil
 }
 
+// GetViziersByLabelSelector gets the list of viziers in an organization whose labels match the selector.
+func (s *Server) GetViziersByLabelSelector(ctx context.Context, req *vzmgrpb.GetViziersByLabelSelectorRequest) (*vzmgrpb.GetViziersByOrgResponse, error) {
+	if err := validateOrgID(ctx, req.OrgID); err != nil {
+		return nil, err
+	}
+	selector, err := parseLabelSelector(req.LabelSelector)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT id, labels from vizier_cluster WHERE org_id=$1`
+	rows, err := s.db.Queryx(query, utils.UUIDFromProtoOrNil(req.OrgID))
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to fetch viziers by org: %s", err.Error())
+	}
+	defer rows.Close()
+
+	ids := []*uuidpb.UUID{}
+	for rows.Next() {
+		var id uuid.UUID
+		var clusterLabels ClusterLabels
+		err = rows.Scan(&id, &clusterLabels)
+		if err != nil {
+			return nil, status.Error(codes.Internal, "failed to read viziers")
+		}
+		if selector.Matches(labels.Set(clusterLabels)) {
+			ids = append(ids, utils.ProtoFromUUID(id))
+		}
+	}
+	return &vzmgrpb.GetViziersByOrgResponse{VizierIDs: ids}, nil
+}
+
 // VizierInfo represents all info we want to fetch about a Vizier.
 type VizierInfo struct {
 	ID                            uuid.UUID     `db:"vizier_cluster_id"`
@@ -263,6 +286,7 @@ type VizierInfo struct {
 	OrgID                         uuid.UUID     `db:"org_id"`
 	PrevStatus                    *vizierStatus `db:"prev_status"`
 	PrevStatusTime                *time.Time    `db:"prev_status_time"`
+	Labels                        ClusterLabels `db:"labels"`
 }
 
 func vizierInfoToProto(vzInfo VizierInfo) *cvmsgspb.VizierInfo {
@@ -305,6 +329,11 @@ func vizierInfoToProto(vzInfo VizierInfo) *cvmsgspb.VizierInfo {
 		prevStatus = vzInfo.PrevStatus.ToProto()
 	}
 
+	var clusterLabels map[string]string
+	if len(vzInfo.Labels) > 0 {
+		clusterLabels = vzInfo.Labels
+	}
+
 	return &cvmsgspb.VizierInfo{
 		VizierID:                      utils.ProtoFromUUID(vzInfo.ID),
 		Status:                        vzInfo.Status.ToProto(),
@@ -322,6 +351,7 @@ func vizierInfoToProto(vzInfo VizierInfo) *cvmsgspb.VizierInfo {
 		NumInstrumentedNodes:          vzInfo.NumInstrumentedNodes,
 		PreviousStatus:                prevStatus,
 		PreviousStatusTime:            prevStatusTime,
+		Labels:                        clusterLabels,
 	}
 }
 
@@ -345,7 +375,7 @@ func (s *Server) GetVizierInfos(ctx context.Context, req *vzmgrpb.GetVizierInfos
 	strQuery := `SELECT i.vizier_cluster_id, c.cluster_uid, c.cluster_name, i.cluster_version, i.operator_version, i.vizier_version,
 			  c.org_id, i.status, (EXTRACT(EPOCH FROM age(now(), i.last_heartbeat))*1E9)::bigint as last_heartbeat,
               i.control_plane_pod_statuses, i.unhealthy_data_plane_pod_statuses,
-							i.num_nodes, i.num_instrumented_nodes, i.status_message, i.prev_status, i.prev_status_time
+							i.num_nodes, i.num_instrumented_nodes, i.status_message, i.prev_status, i.prev_status_time, c.labels
               FROM vizier_cluster_info as i, vizier_cluster as c
               WHERE i.vizier_cluster_id=c.id AND i.vizier_cluster_id IN (?) AND c.org_id=?`
 
@@ -397,7 +427,7 @@ func (s *Server) GetVizierInfo(ctx context.Context, req *uuidpb.UUID) (*cvmsgspb
 	query := `SELECT i.vizier_cluster_id, c.cluster_uid, c.cluster_name, i.cluster_version, i.operator_version, i.vizier_version,
 			  i.status, (EXTRACT(EPOCH FROM age(now(), i.last_heartbeat))*1E9)::bigint as last_heartbeat,
               i.control_plane_pod_statuses, i.unhealthy_data_plane_pod_statuses,
-							i.num_nodes, i.num_instrumented_nodes, i.status_message, i.prev_status, i.prev_status_time
+							i.num_nodes, i.num_instrumented_nodes, i.status_message, i.prev_status, i.prev_status_time, c.labels
               from vizier_cluster_info as i, vizier_cluster as c
               WHERE i.vizier_cluster_id=$1 AND i.vizier_cluster_id=c.id`
 	vzInfo := VizierInfo{}
@@ -432,6 +462,19 @@ func (s *Server) UpdateVizierConfig(ctx context.Context, req *cvmsgspb.UpdateViz
 		return nil, err
 	}
 
+	if req.ConfigUpdate != nil && req.ConfigUpdate.Labels != nil {
+		clusterLabels := ClusterLabels(req.ConfigUpdate.Labels.Value)
+		if err := validateClusterLabels(clusterLabels); err != nil {
+			return nil, err
+		}
+		query := `UPDATE vizier_cluster SET labels = $2 WHERE id = $1`
+		_, err := s.db.Exec(query, utils.UUIDFromProtoOrNil(req.VizierID), clusterLabels)
+		if err != nil {
+			log.WithError(err).Error("Failed to update cluster labels")
+			return nil, status.Error(codes.Internal, "failed to update cluster labels")
+		}
+	}
+
 	return &cvmsgspb.UpdateVizierConfigResponse{}, nil
 }
 
@@ -532,11 +575,13 @@ func (s *Server) VizierConnected(ctx context.Context, req *cvmsgspb.RegisterVizi
 	vzVersion := ""
 	clusterUID := ""
 	clusterName := ""
+	var crdLabels ClusterLabels
 
 	if req.ClusterInfo != nil {
 		vzVersion = req.ClusterInfo.VizierVersion
 		clusterUID = req.ClusterInfo.ClusterUID
 		clusterName = req.ClusterInfo.ClusterName
+		crdLabels = req.ClusterInfo.Labels
 	}
 
 	loggerWithCtx := log.WithContext(ctx).
@@ -572,6 +617,20 @@ func (s *Server) VizierConnected(ctx context.Context, req *cvmsgspb.RegisterVizi
 		return nil, status.Error(codes.NotFound, "no such cluster")
 	}
 
+	// Merge the labels specified in the Vizier CRD into the cluster's labels. Labels from the CRD
+	// take precedence over any existing labels with the same key.
+	if len(crdLabels) > 0 {
+		if err := validateClusterLabels(crdLabels); err != nil {
+			loggerWithCtx.WithError(err).Error("Ignoring invalid cluster labels from Vizier CRD")
+		} else {
+			query = `UPDATE vizier_cluster SET labels = (labels::jsonb || $2::jsonb)::json WHERE id = $1`
+			_, err = s.db.Exec(query, vizierID, crdLabels)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
 	// Send a message over NATS to signal that a Vizier has connected.
 	query = `SELECT org_id, cluster_name from vizier_cluster WHERE id=$1`
 	var val struct {
@@ -637,11 +696,13 @@ func (s *Server) HandleVizierHeartbeat(v2cMsg *cvmsgspb.V2CMessage) {
 		  OR ((x.auto_update_enabled IS NOT NULL) AND (x.auto_update_enabled != y.auto_update_enabled))
 		  OR ((x.cluster_version IS NOT NULL) AND (x.cluster_version != y.cluster_version))
 		  OR ((x.operator_version IS NOT NULL) AND (x.operator_version != y.operator_version))
-		  OR ((x.status_message is not NULL) AND (x.status_message != y.status_message))) as changed, x.vizier_version`
+		  OR ((x.status_message is not NULL) AND (x.status_message != y.status_message))) as changed, x.vizier_version,
+		  (SELECT org_id FROM vizier_cluster WHERE id = x.vizier_cluster_id) as org_id`
 
 	var info struct {
-		Changed bool   `db:"changed"`
-		Version string `db:"vizier_version"`
+		Changed bool      `db:"changed"`
+		Version string    `db:"vizier_version"`
+		OrgID   uuid.UUID `db:"org_id"`
 	}
 
 	rows, err := s.db.Queryx(query, time.Now(), vizierStatus(req.Status), PodStatuses(req.PodStatuses), req.NumNodes,
@@ -681,14 +742,36 @@ func (s *Server) HandleVizierHeartbeat(v2cMsg *cvmsgspb.V2CMessage) {
 				Set("auto_update_enabled", !req.DisableAutoUpdate).
 				Set("status_message", req.StatusMessage),
 		})
+		publishVizierStatusChanged(s.nc, &messagespb.VizierStatusChanged{
+			VizierID:             req.VizierID,
+			OrgID:                utils.ProtoFromUUID(info.OrgID),
+			Status:               req.Status,
+			StatusMessage:        req.StatusMessage,
+			NumNodes:             req.NumNodes,
+			NumInstrumentedNodes: req.NumInstrumentedNodes,
+			VizierVersion:        info.Version,
+			OperatorVersion:      req.OperatorVersion,
+			K8sClusterVersion:    req.K8sClusterVersion,
+		})
 	}
 
 	if req.Status == cvmsgspb.VZ_ST_UPDATING {
 		return
 	}
 
-	if !req.DisableAutoUpdate && !s.updater.VersionUpToDate(info.Version) {
-		s.updater.AddToUpdateQueue(vizierID)
+	if req.DisableAutoUpdate || s.updater.VersionUpToDate(info.Version, "") {
+		return
+	}
+
+	// The org's update policy is only looked up for viziers that are behind the latest version, so
+	// that most heartbeats don't need the extra query.
+	version, ok, err := s.autoUpdateVersion(vizierID, info.Version, time.Now())
+	if err != nil {
+		log.WithError(err).Error("Failed to evaluate vizier update policy")
+		return
+	}
+	if ok {
+		s.updater.AddToUpdateQueue(vizierID, version)
 	}
 }
 
@@ -805,7 +888,40 @@ func (s *Server) ProvisionOrClaimVizier(ctx context.Context, orgID uuid.UUID, us
 	}
 	defer tx.Rollback()
 
+	clusterID, finalName, named, err := s.ProvisionOrClaimVizierInTx(ctx, tx, orgID, userID, clusterUID, clusterName)
+	if err != nil {
+		return uuid.Nil, "", err
+	}
+	if !named {
+		return clusterID, finalName, nil
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.WithError(err).Error("Failed to commit transaction")
+		return uuid.Nil, "", vzerrors.ErrInternalDB
+	}
+	s.TrackVizierCreated(orgID, clusterID)
+	return clusterID, finalName, nil
+}
+
+// TrackVizierCreated sends the event for a Vizier that was provisioned or claimed. It should only be called once the
+// transaction that provisioned the Vizier is committed.
+func (s *Server) TrackVizierCreated(orgID uuid.UUID, clusterID uuid.UUID) {
+	events.Client().Enqueue(&analytics.Track{
+		UserId: clusterID.String(),
+		Event:  events.VizierCreated,
+		Properties: analytics.NewProperties().
+			Set("cluster_id", clusterID.String()).
+			Set("org_id", orgID.String()),
+	})
+}
+
+// ProvisionOrClaimVizierInTx provisions a given cluster or returns the ID if it already exists, in the given
+// transaction. The caller is responsible for committing the transaction, and for calling TrackVizierCreated once
+// it's committed if the cluster was assigned a new name, ie. it was created, claimed or renamed.
+func (s *Server) ProvisionOrClaimVizierInTx(ctx context.Context, tx *sqlx.Tx, orgID uuid.UUID, userID uuid.UUID, clusterUID string, clusterName string) (uuid.UUID, string, bool, error) {
 	var clusterID uuid.UUID
+	var err error
 	inputName := strings.TrimSpace(clusterName)
 
 	generateFromGivenName := func(i int) string {
@@ -821,20 +937,20 @@ func (s *Server) ProvisionOrClaimVizier(ctx context.Context, orgID uuid.UUID, us
 		return name
 	}
 
-	assignNameAndCommit := func() (uuid.UUID, string, error) {
+	assignName := func() (uuid.UUID, string, bool, error) {
 		// Check if cluster already has a name.
 		var existingName *string
 
 		query := `SELECT cluster_name from vizier_cluster WHERE id=$1`
 		err := tx.QueryRowxContext(ctx, query, clusterID).Scan(&existingName)
 		if err != nil {
-			return uuid.Nil, "", vzerrors.ErrInternalDB
+			return uuid.Nil, "", false, vzerrors.ErrInternalDB
 		}
 
 		if existingName != nil {
 			// No input name specified, so no need to change cluster name.
 			if inputName == "" {
-				return clusterID, *existingName, nil
+				return clusterID, *existingName, false, nil
 			}
 
 			// The existing name is already the same as the input name, or a derivation
@@ -845,14 +961,14 @@ func (s *Server) ProvisionOrClaimVizier(ctx context.Context, orgID uuid.UUID, us
 			// cannot distinguish between randomly generated names and actual-unaltered names.
 			dbName := *existingName
 			if inputName == dbName {
-				return clusterID, *existingName, nil
+				return clusterID, *existingName, false, nil
 			}
 			prefixIndex := strings.LastIndex(dbName, "_")
 			if prefixIndex != -1 {
 				dbName = dbName[:prefixIndex]
 			}
 			if inputName == dbName {
-				return clusterID, *existingName, nil
+				return clusterID, *existingName, false, nil
 			}
 		}
 
@@ -863,48 +979,35 @@ func (s *Server) ProvisionOrClaimVizier(ctx context.Context, orgID uuid.UUID, us
 
 		finalName, err := setClusterName(ctx, tx, clusterID, generateNameFunc)
 		if err != nil {
-			return uuid.Nil, "", vzerrors.ErrInternalDB
+			return uuid.Nil, "", false, vzerrors.ErrInternalDB
 		}
-
-		if err := tx.Commit(); err != nil {
-			log.WithError(err).Error("Failed to commit transaction")
-			return uuid.Nil, "", vzerrors.ErrInternalDB
-		}
-
-		events.Client().Enqueue(&analytics.Track{
-			UserId: clusterID.String(),
-			Event:  events.VizierCreated,
-			Properties: analytics.NewProperties().
-				Set("cluster_id", clusterID.String()).
-				Set("org_id", orgID.String()),
-		})
-		return clusterID, finalName, nil
+		return clusterID, finalName, true, nil
 	}
 
 	clusterID, status, err := findVizierWithUID(ctx, tx, orgID, clusterUID)
 	if err != nil {
-		return uuid.Nil, "", err
+		return uuid.Nil, "", false, err
 	}
 	if clusterID != uuid.Nil {
 		if status != vizierStatus(cvmsgspb.VZ_ST_DISCONNECTED) {
-			return uuid.Nil, "", vzerrors.ErrProvisionFailedVizierIsActive
+			return uuid.Nil, "", false, vzerrors.ErrProvisionFailedVizierIsActive
 		}
-		return assignNameAndCommit()
+		return assignName()
 	}
 
 	clusterID, _, err = findVizierWithEmptyUID(ctx, tx, orgID)
 	if err != nil {
-		return uuid.Nil, "", err
+		return uuid.Nil, "", false, err
 	}
 	if clusterID != uuid.Nil {
 		// Set the cluster ID.
 		query := `UPDATE vizier_cluster SET cluster_uid=$1 WHERE id=$2`
 		rows, err := tx.QueryxContext(ctx, query, clusterUID, clusterID)
 		if err != nil {
-			return uuid.Nil, "", err
+			return uuid.Nil, "", false, err
 		}
 		rows.Close()
-		return assignNameAndCommit()
+		return assignName()
 	}
 
 	// Insert new vizier case.
@@ -915,9 +1018,9 @@ func (s *Server) ProvisionOrClaimVizier(ctx context.Context, orgID uuid.UUID, us
 		INSERT INTO vizier_cluster_info(vizier_cluster_id, status) SELECT id, 'DISCONNECTED' FROM ins RETURNING vizier_cluster_id`
 	err = tx.QueryRowContext(ctx, query, orgID, DefaultProjectName, clusterUID).Scan(&clusterID)
 	if err != nil {
-		return uuid.Nil, "", err
+		return uuid.Nil, "", false, err
 	}
-	return assignNameAndCommit()
+	return assignName()
 }
 
 // GetOrgFromVizier fetches the org to which a Vizier belongs. This is intended to be for internal use only.
@@ -119,6 +119,7 @@ func testMain(m *testing.M) error {
 func mustLoadTestData(db *sqlx.DB) {
 	db.MustExec(`DELETE FROM vizier_cluster_info`)
 	db.MustExec(`DELETE FROM vizier_cluster`)
+	db.MustExec(`DELETE FROM vizier_update_policies`)
 
 	insertCluster := `INSERT INTO vizier_cluster(org_id, id, project_name, cluster_uid, cluster_name) VALUES ($1, $2, $3, $4, $5)`
 	db.MustExec(insertCluster, testAuthOrgID, "123e4567-e89b-12d3-a456-426655440000", testProjectName, "k8sID", "unknown_cluster")
@@ -228,6 +229,130 @@ func TestServer_GetViziersByOrg(t *testing.T) {
 	})
 }
 
+func TestServer_GetViziersByLabelSelector(t *testing.T) {
+	mustLoadTestData(db)
+	db.MustExec(`UPDATE vizier_cluster SET labels=$2 WHERE id=$1`, "123e4567-e89b-12d3-a456-426655440000", `{"env": "prod", "region": "eu"}`)
+	db.MustExec(`UPDATE vizier_cluster SET labels=$2 WHERE id=$1`, "123e4567-e89b-12d3-a456-426655440001", `{"env": "prod", "region": "us"}`)
+	db.MustExec(`UPDATE vizier_cluster SET labels=$2 WHERE id=$1`, "123e4567-e89b-12d3-a456-426655440002", `{"env": "staging"}`)
+	db.MustExec(`UPDATE vizier_cluster SET labels=$2 WHERE id=$1`, "223e4567-e89b-12d3-a456-426655440003", `{"env": "prod"}`)
+
+	s := controllers.New(db, "test", nil, nil)
+
+	tests := []struct {
+		name         string
+		selector     string
+		expectedIDs  []string
+		expectedCode codes.Code
+	}{
+		{
+			name:     "equality",
+			selector: "env=prod",
+			expectedIDs: []string{
+				"123e4567-e89b-12d3-a456-426655440000",
+				"123e4567-e89b-12d3-a456-426655440001",
+			},
+		},
+		{
+			name:     "multiple requirements",
+			selector: "env=prod,region in (eu)",
+			expectedIDs: []string{
+				"123e4567-e89b-12d3-a456-426655440000",
+			},
+		},
+		{
+			name:     "exists",
+			selector: "!region",
+			expectedIDs: []string{
+				"123e4567-e89b-12d3-a456-426655440002",
+				"123e4567-e89b-12d3-a456-426655440003",
+				"823e4567-e89b-12d3-a456-426655440008",
+				"923e4567-e89b-12d3-a456-426655440008",
+			},
+		},
+		{
+			name:        "no match",
+			selector:    "env=dev",
+			expectedIDs: []string{},
+		},
+		{
+			name:         "invalid selector",
+			selector:     "env==,",
+			expectedCode: codes.InvalidArgument,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resp, err := s.GetViziersByLabelSelector(CreateTestContext(), &vzmgrpb.GetViziersByLabelSelectorRequest{
+				OrgID:         utils.ProtoFromUUIDStrOrNil(testAuthOrgID),
+				LabelSelector: test.selector,
+			})
+			if test.expectedCode != codes.OK {
+				require.NotNil(t, err)
+				assert.Equal(t, test.expectedCode, status.Code(err))
+				return
+			}
+			require.NoError(t, err)
+
+			ids := []string{}
+			for _, val := range resp.VizierIDs {
+				ids = append(ids, utils.UUIDFromProtoOrNil(val).String())
+			}
+			sort.Strings(ids)
+			assert.Equal(t, test.expectedIDs, ids)
+		})
+	}
+
+	t.Run("mismatched input org id", func(t *testing.T) {
+		resp, err := s.GetViziersByLabelSelector(CreateTestContext(), &vzmgrpb.GetViziersByLabelSelectorRequest{
+			OrgID:         utils.ProtoFromUUIDStrOrNil(testNonAuthOrgID),
+			LabelSelector: "env=prod",
+		})
+		require.NotNil(t, err)
+		assert.Nil(t, resp)
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	})
+}
+
+func TestServer_UpdateVizierConfigLabels(t *testing.T) {
+	mustLoadTestData(db)
+
+	s := controllers.New(db, "test", nil, nil)
+	vizierID := utils.ProtoFromUUIDStrOrNil("123e4567-e89b-12d3-a456-426655440001")
+
+	_, err := s.UpdateVizierConfig(CreateTestContext(), &cvmsgspb.UpdateVizierConfigRequest{
+		VizierID: vizierID,
+		ConfigUpdate: &cvmsgspb.VizierConfigUpdate{
+			Labels: &cvmsgspb.VizierLabels{Value: map[string]string{"env": "prod", "px.dev/team": "infra"}},
+		},
+	})
+	require.NoError(t, err)
+
+	info, err := s.GetVizierInfo(CreateTestContext(), vizierID)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"env": "prod", "px.dev/team": "infra"}, info.Labels)
+
+	_, err = s.UpdateVizierConfig(CreateTestContext(), &cvmsgspb.UpdateVizierConfigRequest{
+		VizierID: vizierID,
+		ConfigUpdate: &cvmsgspb.VizierConfigUpdate{
+			Labels: &cvmsgspb.VizierLabels{Value: map[string]string{"not a key": "prod"}},
+		},
+	})
+	require.NotNil(t, err)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	// Clearing the labels removes all of them.
+	_, err = s.UpdateVizierConfig(CreateTestContext(), &cvmsgspb.UpdateVizierConfigRequest{
+		VizierID:     vizierID,
+		ConfigUpdate: &cvmsgspb.VizierConfigUpdate{Labels: &cvmsgspb.VizierLabels{}},
+	})
+	require.NoError(t, err)
+
+	info, err = s.GetVizierInfo(CreateTestContext(), vizierID)
+	require.NoError(t, err)
+	assert.Nil(t, info.Labels)
+}
+
 func TestServer_GetVizierInfo(t *testing.T) {
 	mustLoadTestData(db)
 
@@ -378,6 +503,54 @@ func TestServer_VizierConnectedHealthy(t *testing.T) {
 	// TODO(zasgar): write more tests here.
 }
 
+func TestServer_VizierConnectedCRDLabels(t *testing.T) {
+	mustLoadTestData(db)
+
+	nc, cleanup := testingutils.MustStartTestNATS(t)
+	defer cleanup()
+
+	s := controllers.New(db, "test", nc, nil)
+	vizierID := utils.ProtoFromUUIDStrOrNil("123e4567-e89b-12d3-a456-426655440001")
+
+	_, err := s.UpdateVizierConfig(CreateTestContext(), &cvmsgspb.UpdateVizierConfigRequest{
+		VizierID: vizierID,
+		ConfigUpdate: &cvmsgspb.VizierConfigUpdate{
+			Labels: &cvmsgspb.VizierLabels{Value: map[string]string{"env": "dev", "team": "infra"}},
+		},
+	})
+	require.NoError(t, err)
+
+	// Labels from the Vizier CRD take precedence over the existing labels with the same key.
+	_, err = s.VizierConnected(context.Background(), &cvmsgspb.RegisterVizierRequest{
+		VizierID: vizierID,
+		JwtKey:   "the-token",
+		ClusterInfo: &cvmsgspb.VizierClusterInfo{
+			ClusterUID: "cUID",
+			Labels:     map[string]string{"env": "prod"},
+		},
+	})
+	require.NoError(t, err)
+
+	info, err := s.GetVizierInfo(CreateTestContext(), vizierID)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"env": "prod", "team": "infra"}, info.Labels)
+
+	// Invalid labels are ignored, without failing the registration.
+	_, err = s.VizierConnected(context.Background(), &cvmsgspb.RegisterVizierRequest{
+		VizierID: vizierID,
+		JwtKey:   "the-token",
+		ClusterInfo: &cvmsgspb.VizierClusterInfo{
+			ClusterUID: "cUID",
+			Labels:     map[string]string{"not a key": "prod"},
+		},
+	})
+	require.NoError(t, err)
+
+	info, err = s.GetVizierInfo(CreateTestContext(), vizierID)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"env": "prod", "team": "infra"}, info.Labels)
+}
+
 func TestServer_HandleVizierHeartbeat(t *testing.T) {
 	mustLoadTestData(db)
 
@@ -499,14 +672,14 @@ func TestServer_HandleVizierHeartbeat(t *testing.T) {
 			if tc.checkVersion {
 				updater.
 					EXPECT().
-					VersionUpToDate(gomock.Any()).
+					VersionUpToDate(gomock.Any(), "").
 					Return(!tc.versionUpdated)
 			}
 
 			if tc.versionUpdated {
 				updater.
 					EXPECT().
-					AddToUpdateQueue(uuid.FromStringOrNil(tc.vizierID))
+					AddToUpdateQueue(uuid.FromStringOrNil(tc.vizierID), "")
 			}
 
 			nestedMsg := &cvmsgspb.VizierHeartbeat{
@@ -25,11 +25,15 @@ import (
 
 	"github.com/gofrs/uuid"
 	"github.com/jmoiron/sqlx"
+	"github.com/nats-io/nats.go"
 	"github.com/segmentio/analytics-go/v3"
 	log "github.com/sirupsen/logrus"
 
+	"px.dev/pixie/src/cloud/shared/messages"
+	"px.dev/pixie/src/cloud/shared/messagespb"
 	"px.dev/pixie/src/shared/cvmsgspb"
 	"px.dev/pixie/src/shared/services/events"
+	"px.dev/pixie/src/utils"
 )
 
 const (
@@ -46,14 +50,17 @@ const (
 // It has a routine that is periodically invoked.
 type StatusMonitor struct {
 	db     *sqlx.DB
+	nc     *nats.Conn
 	quitCh chan struct{}
 	once   sync.Once
 }
 
-// NewStatusMonitor creates a new StatusMonitor operating on the passed in DB and starts it.
-func NewStatusMonitor(db *sqlx.DB) *StatusMonitor {
+// NewStatusMonitor creates a new StatusMonitor operating on the passed in DB and starts it. Viziers
+// that are marked as disconnected are published to NATS.
+func NewStatusMonitor(db *sqlx.DB, nc *nats.Conn) *StatusMonitor {
 	sm := &StatusMonitor{
 		db:     db,
+		nc:     nc,
 		quitCh: make(chan struct{}),
 	}
 	sm.start()
@@ -95,7 +102,9 @@ func (s *StatusMonitor) UpdateDBEntries() {
 		     WHERE (last_heartbeat < NOW() - INTERVAL '%f seconds' AND status != 'UPDATING' AND status != 'DISCONNECTED')
 			   OR (last_heartbeat < NOW() - INTERVAL '%f seconds' AND status = 'UPDATING')) y
      WHERE x.vizier_cluster_id = y.vizier_cluster_id
-     RETURNING y.vizier_cluster_id;`
+     RETURNING y.vizier_cluster_id, (SELECT org_id FROM vizier_cluster WHERE id = y.vizier_cluster_id),
+       COALESCE(y.num_nodes, 0), COALESCE(y.num_instrumented_nodes, 0), COALESCE(y.vizier_version, ''),
+       COALESCE(y.operator_version, ''), COALESCE(y.cluster_version, '');`
 	// Variable substitution does not seem to work for intervals. Since we control this entire
 	// query and input data it should be safe to add the value to the query using
 	// a format directive.
@@ -111,8 +120,12 @@ func (s *StatusMonitor) UpdateDBEntries() {
 	defer rows.Close()
 	for rows.Next() {
 		entryUpdated++
-		var vizierID uuid.UUID
-		err = rows.Scan(&vizierID)
+		var vizierID, orgID uuid.UUID
+		msg := &messagespb.VizierStatusChanged{
+			Status: cvmsgspb.VZ_ST_DISCONNECTED,
+		}
+		err = rows.Scan(&vizierID, &orgID, &msg.NumNodes, &msg.NumInstrumentedNodes, &msg.VizierVersion,
+			&msg.OperatorVersion, &msg.K8sClusterVersion)
 		if err != nil {
 			log.Info("Failed to read data for updated vizier, ignoring")
 		} else {
@@ -123,9 +136,24 @@ func (s *StatusMonitor) UpdateDBEntries() {
 					Set("cluster_id", vizierID.String()).
 					Set("status", vizierStatus(cvmsgspb.VZ_ST_DISCONNECTED).Stringify()),
 			})
+			msg.VizierID = utils.ProtoFromUUID(vizierID)
+			msg.OrgID = utils.ProtoFromUUID(orgID)
+			publishVizierStatusChanged(s.nc, msg)
 		}
 	}
 	log.WithField("entries_update", entryUpdated).
 		WithField("update_time", time.Since(start)).
 		Info("Heartbeat Update Complete")
 }
+
+// publishVizierStatusChanged notifies the rest of the cloud that the status of a vizier changed.
+func publishVizierStatusChanged(nc *nats.Conn, msg *messagespb.VizierStatusChanged) {
+	b, err := msg.Marshal()
+	if err != nil {
+		log.WithError(err).Error("Failed to marshal vizier status change")
+		return
+	}
+	if err := nc.Publish(messages.VizierStatusChangedChannel, b); err != nil {
+		log.WithError(err).Error("Failed to publish vizier status change")
+	}
+}
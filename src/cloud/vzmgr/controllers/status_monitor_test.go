@@ -20,13 +20,20 @@ package controllers_test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/gofrs/uuid"
 	"github.com/jmoiron/sqlx"
+	"github.com/nats-io/nats.go"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"px.dev/pixie/src/cloud/shared/messages"
+	"px.dev/pixie/src/cloud/shared/messagespb"
 	"px.dev/pixie/src/cloud/vzmgr/controllers"
+	"px.dev/pixie/src/shared/cvmsgspb"
+	"px.dev/pixie/src/utils"
+	"px.dev/pixie/src/utils/testingutils"
 )
 
 func mustLoadStatusMonitorTestData(db *sqlx.DB) {
@@ -68,7 +75,18 @@ func TestStatusMonitor_Start(t *testing.T) {
 	assert.Equal(t, vizInfo.Address, "addr0")
 	assert.Equal(t, vizInfo.Status, "HEALTHY")
 
-	sm := controllers.NewStatusMonitor(db)
+	nc, cleanup := testingutils.MustStartTestNATS(t)
+	defer cleanup()
+
+	subCh := make(chan *nats.Msg, 10)
+	natsSub, err := nc.ChanSubscribe(messages.VizierStatusChangedChannel, subCh)
+	require.NoError(t, err)
+	defer func() {
+		err = natsSub.Unsubscribe()
+		require.NoError(t, err)
+	}()
+
+	sm := controllers.NewStatusMonitor(db, nc)
 	defer sm.Stop()
 
 	// For call update, just to make sure it was run and the state was updated.
@@ -87,4 +105,21 @@ func TestStatusMonitor_Start(t *testing.T) {
 	err = db.Get(&vizInfo, query, uuid.FromStringOrNil("123e4567-e89b-12d3-a456-426655440002"))
 	require.NoError(t, err)
 	assert.Equal(t, vizInfo.Status, "DISCONNECTED")
+
+	disconnected := make(map[string]string)
+	for i := 0; i < 2; i++ {
+		select {
+		case msg := <-subCh:
+			m := &messagespb.VizierStatusChanged{}
+			require.NoError(t, m.Unmarshal(msg.Data))
+			assert.Equal(t, cvmsgspb.VZ_ST_DISCONNECTED, m.Status)
+			disconnected[utils.UUIDFromProtoOrNil(m.VizierID).String()] = utils.UUIDFromProtoOrNil(m.OrgID).String()
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timed out waiting for vizier status change")
+		}
+	}
+	assert.Equal(t, map[string]string{
+		"123e4567-e89b-12d3-a456-426655440000": "223e4567-e89b-12d3-a456-426655440000",
+		"123e4567-e89b-12d3-a456-426655440002": "223e4567-e89b-12d3-a456-426655440000",
+	}, disconnected)
 }
@@ -0,0 +1,430 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/jmoiron/sqlx"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"px.dev/pixie/src/api/proto/uuidpb"
+	"px.dev/pixie/src/cloud/vzmgr/vzmgrpb"
+	"px.dev/pixie/src/shared/cvmsgspb"
+	"px.dev/pixie/src/utils"
+)
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// MaintenanceWindows Type to use in sqlx for the maintenance windows of an update policy.
+type MaintenanceWindows []*vzmgrpb.MaintenanceWindow
+
+// Value Returns a golang database/sql driver value for MaintenanceWindows.
+func (m MaintenanceWindows) Value() (driver.Value, error) {
+	if m == nil {
+		m = MaintenanceWindows{}
+	}
+	return json.Marshal(m)
+}
+
+// Scan Scans the sqlx database type ([]bytes) into the MaintenanceWindows type.
+func (m *MaintenanceWindows) Scan(src interface{}) error {
+	jsonText, ok := src.([]byte)
+	if !ok || json.Unmarshal(jsonText, m) != nil {
+		return status.Error(codes.Internal, "could not unmarshal maintenance windows")
+	}
+	return nil
+}
+
+// UpdateWaves Type to use in sqlx for the waves of an update policy.
+type UpdateWaves []*vzmgrpb.UpdateWave
+
+// Value Returns a golang database/sql driver value for UpdateWaves.
+func (w UpdateWaves) Value() (driver.Value, error) {
+	if w == nil {
+		w = UpdateWaves{}
+	}
+	return json.Marshal(w)
+}
+
+// Scan Scans the sqlx database type ([]bytes) into the UpdateWaves type.
+func (w *UpdateWaves) Scan(src interface{}) error {
+	jsonText, ok := src.([]byte)
+	if !ok || json.Unmarshal(jsonText, w) != nil {
+		return status.Error(codes.Internal, "could not unmarshal update waves")
+	}
+	return nil
+}
+
+// updatePolicy is an org's policy for automatically updating its viziers.
+type updatePolicy struct {
+	OrgID              uuid.UUID          `db:"org_id"`
+	PinnedVersion      string             `db:"pinned_version"`
+	Channel            string             `db:"channel"`
+	VersionsBehind     int                `db:"versions_behind"`
+	MaintenanceWindows MaintenanceWindows `db:"maintenance_windows"`
+	Timezone           string             `db:"timezone"`
+	Waves              UpdateWaves        `db:"waves"`
+}
+
+func defaultUpdatePolicy(orgID uuid.UUID) *updatePolicy {
+	return &updatePolicy{
+		OrgID:    orgID,
+		Channel:  updateChannelName(vzmgrpb.VUC_LATEST),
+		Timezone: "UTC",
+	}
+}
+
+func updateChannelName(c vzmgrpb.VizierUpdateChannel) string {
+	return strings.TrimPrefix(c.String(), "VUC_")
+}
+
+func (p *updatePolicy) channel() vzmgrpb.VizierUpdateChannel {
+	return vzmgrpb.VizierUpdateChannel(vzmgrpb.VizierUpdateChannel_value["VUC_"+p.Channel])
+}
+
+// inMaintenanceWindow returns whether updates are allowed at the given time.
+func (p *updatePolicy) inMaintenanceWindow(t time.Time) bool {
+	if len(p.MaintenanceWindows) == 0 {
+		return true
+	}
+	loc, err := time.LoadLocation(p.Timezone)
+	if err != nil {
+		return false
+	}
+	t = t.In(loc)
+	for _, w := range p.MaintenanceWindows {
+		if maintenanceWindowContains(w, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// maintenanceWindowContains returns whether the time is in the maintenance window. Windows whose end
+// hour is before their start hour, such as 22-4, wrap around midnight. They start on the window's days
+// and end on the following day.
+func maintenanceWindowContains(w *vzmgrpb.MaintenanceWindow, t time.Time) bool {
+	hour := t.Hour()
+	day := t.Weekday()
+	if w.StartHour < w.EndHour {
+		if hour < int(w.StartHour) || hour >= int(w.EndHour) {
+			return false
+		}
+	} else {
+		switch {
+		case hour >= int(w.StartHour):
+		case hour < int(w.EndHour):
+			// The window started on the previous day.
+			day = (day + 6) % 7
+		default:
+			return false
+		}
+	}
+	if len(w.Days) == 0 {
+		return true
+	}
+	for _, d := range w.Days {
+		if weekdays[strings.ToLower(d)] == day {
+			return true
+		}
+	}
+	return false
+}
+
+// clustersBeforeWave returns the viziers in the waves that must be updated before the given vizier.
+// Viziers that aren't in any wave are updated after all of the waves.
+func (p *updatePolicy) clustersBeforeWave(vizierID uuid.UUID) []uuid.UUID {
+	var ids []uuid.UUID
+	for _, w := range p.Waves {
+		waveIDs := make([]uuid.UUID, len(w.ClusterIDs))
+		inWave := false
+		for i, id := range w.ClusterIDs {
+			waveIDs[i] = utils.UUIDFromProtoOrNil(id)
+			if waveIDs[i] == vizierID {
+				inWave = true
+			}
+		}
+		if inWave {
+			return ids
+		}
+		ids = append(ids, waveIDs...)
+	}
+	return ids
+}
+
+func (p *updatePolicy) toProto() *vzmgrpb.VizierUpdatePolicy {
+	return &vzmgrpb.VizierUpdatePolicy{
+		OrgID:              utils.ProtoFromUUID(p.OrgID),
+		PinnedVersion:      p.PinnedVersion,
+		Channel:            p.channel(),
+		VersionsBehind:     int32(p.VersionsBehind),
+		MaintenanceWindows: p.MaintenanceWindows,
+		Timezone:           p.Timezone,
+		Waves:              p.Waves,
+	}
+}
+
+func (s *Server) validateUpdatePolicy(orgID uuid.UUID, req *vzmgrpb.VizierUpdatePolicy) error {
+	if req.PinnedVersion != "" {
+		if _, err := parseVersion(req.PinnedVersion); err != nil {
+			return status.Errorf(codes.InvalidArgument, "invalid pinned version %q", req.PinnedVersion)
+		}
+	}
+	if _, ok := vzmgrpb.VizierUpdateChannel_name[int32(req.Channel)]; !ok {
+		return status.Error(codes.InvalidArgument, "invalid update channel")
+	}
+	if req.VersionsBehind < 0 || req.VersionsBehind >= versionHistoryLimit {
+		return status.Errorf(codes.InvalidArgument, "versions behind must be between 0 and %d", versionHistoryLimit-1)
+	}
+	if req.Timezone != "" {
+		if _, err := time.LoadLocation(req.Timezone); err != nil {
+			return status.Errorf(codes.InvalidArgument, "invalid timezone %q", req.Timezone)
+		}
+	}
+	for _, w := range req.MaintenanceWindows {
+		// Windows may wrap around midnight, so the end hour can be before the start hour.
+		if w.StartHour < 0 || w.StartHour > 23 || w.EndHour < 0 || w.EndHour > 24 || w.StartHour == w.EndHour {
+			return status.Errorf(codes.InvalidArgument, "invalid maintenance window hours %d-%d", w.StartHour, w.EndHour)
+		}
+		for _, d := range w.Days {
+			if _, ok := weekdays[strings.ToLower(d)]; !ok {
+				return status.Errorf(codes.InvalidArgument, "invalid maintenance window day %q", d)
+			}
+		}
+	}
+
+	names := make(map[string]bool)
+	var ids []uuid.UUID
+	seen := make(map[uuid.UUID]string)
+	for _, w := range req.Waves {
+		if w.Name == "" {
+			return status.Error(codes.InvalidArgument, "update waves must have a name")
+		}
+		if names[w.Name] {
+			return status.Errorf(codes.InvalidArgument, "duplicate update wave %q", w.Name)
+		}
+		names[w.Name] = true
+		for _, idPb := range w.ClusterIDs {
+			id := utils.UUIDFromProtoOrNil(idPb)
+			if id == uuid.Nil {
+				return status.Errorf(codes.InvalidArgument, "invalid cluster id in update wave %q", w.Name)
+			}
+			if other, ok := seen[id]; ok {
+				return status.Errorf(codes.InvalidArgument, "cluster %s is in update waves %q and %q", id, other, w.Name)
+			}
+			seen[id] = w.Name
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	query, args, err := sqlx.In(`SELECT COUNT(*) FROM vizier_cluster WHERE org_id=? AND id IN (?)`, orgID, ids)
+	if err != nil {
+		return status.Error(codes.Internal, "failed to validate update waves")
+	}
+	var count int
+	err = s.db.QueryRow(s.db.Rebind(query), args...).Scan(&count)
+	if err != nil {
+		return status.Error(codes.Internal, "failed to validate update waves")
+	}
+	if count != len(ids) {
+		return status.Error(codes.InvalidArgument, "update waves contain clusters that don't belong to the org")
+	}
+	return nil
+}
+
+func (s *Server) getUpdatePolicy(orgID uuid.UUID) (*updatePolicy, error) {
+	query := `SELECT org_id, pinned_version, channel, versions_behind, maintenance_windows, timezone, waves
+		FROM vizier_update_policies WHERE org_id=$1`
+	p := &updatePolicy{}
+	err := s.db.QueryRowx(query, orgID).StructScan(p)
+	if err == sql.ErrNoRows {
+		return defaultUpdatePolicy(orgID), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// getUpdatePolicyForVizier returns the update policy of the org that owns the vizier, or nil if the
+// org has no policy.
+func (s *Server) getUpdatePolicyForVizier(vizierID uuid.UUID) (*updatePolicy, error) {
+	query := `SELECT p.org_id, p.pinned_version, p.channel, p.versions_behind, p.maintenance_windows, p.timezone, p.waves
+		FROM vizier_update_policies p INNER JOIN vizier_cluster c ON p.org_id = c.org_id WHERE c.id=$1`
+	p := &updatePolicy{}
+	err := s.db.QueryRowx(query, vizierID).StructScan(p)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// policyTargetVersion returns the version that the policy's viziers should be updated to. Viziers
+// are never updated past the pinned version.
+func (s *Server) policyTargetVersion(p *updatePolicy) string {
+	target := s.updater.ResolveVersion(p.channel(), p.VersionsBehind)
+	if p.PinnedVersion == "" {
+		return target
+	}
+	if target == "" || s.updater.VersionUpToDate(target, p.PinnedVersion) {
+		return p.PinnedVersion
+	}
+	return target
+}
+
+// earlierWavesUpdated returns whether all of the viziers in the waves before the given vizier's wave
+// are healthy on the target version. Disconnected viziers, and viziers that have auto-update
+// disabled, don't hold up later waves.
+func (s *Server) earlierWavesUpdated(p *updatePolicy, vizierID uuid.UUID, target string) (bool, error) {
+	ids := p.clustersBeforeWave(vizierID)
+	if len(ids) == 0 {
+		return true, nil
+	}
+
+	query, args, err := sqlx.In(`SELECT vizier_cluster_id, status, vizier_version, auto_update_enabled
+		FROM vizier_cluster_info WHERE vizier_cluster_id IN (?)`, ids)
+	if err != nil {
+		return false, err
+	}
+	rows, err := s.db.Queryx(s.db.Rebind(query), args...)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var info struct {
+			ID                uuid.UUID    `db:"vizier_cluster_id"`
+			Status            vizierStatus `db:"status"`
+			Version           *string      `db:"vizier_version"`
+			AutoUpdateEnabled *bool        `db:"auto_update_enabled"`
+		}
+		if err := rows.StructScan(&info); err != nil {
+			return false, err
+		}
+		if info.Status.ToProto() == cvmsgspb.VZ_ST_DISCONNECTED || (info.AutoUpdateEnabled != nil && !*info.AutoUpdateEnabled) {
+			continue
+		}
+		if info.Status.ToProto() != cvmsgspb.VZ_ST_HEALTHY || info.Version == nil || !s.updater.VersionUpToDate(*info.Version, target) {
+			return false, nil
+		}
+	}
+	return true, rows.Err()
+}
+
+// autoUpdateVersion returns the version that the vizier should be automatically updated to right
+// now, or false if it shouldn't be updated yet.
+func (s *Server) autoUpdateVersion(vizierID uuid.UUID, currentVersion string, now time.Time) (string, bool, error) {
+	p, err := s.getUpdatePolicyForVizier(vizierID)
+	if err != nil {
+		return "", false, err
+	}
+	if p == nil {
+		// Without a policy, viziers are updated to the latest version as soon as possible.
+		return "", true, nil
+	}
+
+	target := s.policyTargetVersion(p)
+	if target == "" || s.updater.VersionUpToDate(currentVersion, target) {
+		return "", false, nil
+	}
+	if !p.inMaintenanceWindow(now) {
+		return "", false, nil
+	}
+	ready, err := s.earlierWavesUpdated(p, vizierID, target)
+	if err != nil || !ready {
+		return "", false, err
+	}
+	return target, true, nil
+}
+
+// GetVizierUpdatePolicy gets the policy for automatically updating the viziers in the given org.
+func (s *Server) GetVizierUpdatePolicy(ctx context.Context, orgID *uuidpb.UUID) (*vzmgrpb.VizierUpdatePolicy, error) {
+	if err := validateOrgID(ctx, orgID); err != nil {
+		return nil, err
+	}
+
+	p, err := s.getUpdatePolicy(utils.UUIDFromProtoOrNil(orgID))
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to fetch update policy: %s", err.Error())
+	}
+	resp := p.toProto()
+	resp.TargetVersion = s.policyTargetVersion(p)
+	return resp, nil
+}
+
+// SetVizierUpdatePolicy replaces the policy for automatically updating the viziers in an org.
+func (s *Server) SetVizierUpdatePolicy(ctx context.Context, req *vzmgrpb.VizierUpdatePolicy) (*vzmgrpb.VizierUpdatePolicy, error) {
+	if err := validateOrgID(ctx, req.OrgID); err != nil {
+		return nil, err
+	}
+	orgID := utils.UUIDFromProtoOrNil(req.OrgID)
+	if err := s.validateUpdatePolicy(orgID, req); err != nil {
+		return nil, err
+	}
+
+	p := &updatePolicy{
+		OrgID:              orgID,
+		PinnedVersion:      req.PinnedVersion,
+		Channel:            updateChannelName(req.Channel),
+		VersionsBehind:     int(req.VersionsBehind),
+		MaintenanceWindows: req.MaintenanceWindows,
+		Timezone:           req.Timezone,
+		Waves:              req.Waves,
+	}
+	if p.Timezone == "" {
+		p.Timezone = "UTC"
+	}
+
+	query := `INSERT INTO vizier_update_policies (org_id, pinned_version, channel, versions_behind, maintenance_windows, timezone, waves, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW())
+		ON CONFLICT (org_id) DO UPDATE SET pinned_version = EXCLUDED.pinned_version, channel = EXCLUDED.channel,
+			versions_behind = EXCLUDED.versions_behind, maintenance_windows = EXCLUDED.maintenance_windows,
+			timezone = EXCLUDED.timezone, waves = EXCLUDED.waves, updated_at = EXCLUDED.updated_at`
+	_, err := s.db.Exec(query, p.OrgID, p.PinnedVersion, p.Channel, p.VersionsBehind, p.MaintenanceWindows, p.Timezone, p.Waves)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to save update policy: %s", err.Error())
+	}
+
+	resp := p.toProto()
+	resp.TargetVersion = s.policyTargetVersion(p)
+	return resp, nil
+}
@@ -0,0 +1,338 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package controllers_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/blang/semver"
+	"github.com/gofrs/uuid"
+	"github.com/gogo/protobuf/types"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"px.dev/pixie/src/api/proto/uuidpb"
+	"px.dev/pixie/src/cloud/vzmgr/controllers"
+	mock_controllers "px.dev/pixie/src/cloud/vzmgr/controllers/mock"
+	"px.dev/pixie/src/cloud/vzmgr/vzmgrpb"
+	"px.dev/pixie/src/shared/cvmsgspb"
+	"px.dev/pixie/src/utils"
+	"px.dev/pixie/src/utils/testingutils"
+)
+
+const testLatestVersion = "0.5.0"
+
+// newPolicyTestUpdater returns a mock updater that compares versions like the real updater, with
+// testLatestVersion as the latest version.
+func newPolicyTestUpdater(ctrl *gomock.Controller) *mock_controllers.MockVzUpdater {
+	updater := mock_controllers.NewMockVzUpdater(ctrl)
+	updater.EXPECT().ResolveVersion(gomock.Any(), gomock.Any()).Return(testLatestVersion).AnyTimes()
+	updater.EXPECT().VersionUpToDate(gomock.Any(), gomock.Any()).DoAndReturn(func(version, target string) bool {
+		if target == "" {
+			target = testLatestVersion
+		}
+		v, err := semver.Parse(version)
+		if err != nil {
+			return true
+		}
+		return v.GTE(semver.MustParse(target))
+	}).AnyTimes()
+	return updater
+}
+
+func TestServer_GetVizierUpdatePolicy(t *testing.T) {
+	mustLoadTestData(db)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	s := controllers.New(db, "test", nil, newPolicyTestUpdater(ctrl))
+
+	t.Run("default policy", func(t *testing.T) {
+		resp, err := s.GetVizierUpdatePolicy(CreateTestContext(), utils.ProtoFromUUIDStrOrNil(testAuthOrgID))
+		require.NoError(t, err)
+		assert.Equal(t, &vzmgrpb.VizierUpdatePolicy{
+			OrgID:         utils.ProtoFromUUIDStrOrNil(testAuthOrgID),
+			Channel:       vzmgrpb.VUC_LATEST,
+			Timezone:      "UTC",
+			TargetVersion: testLatestVersion,
+		}, resp)
+	})
+
+	t.Run("wrong org", func(t *testing.T) {
+		resp, err := s.GetVizierUpdatePolicy(CreateTestContext(), utils.ProtoFromUUIDStrOrNil(testNonAuthOrgID))
+		assert.Nil(t, resp)
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	})
+}
+
+func TestServer_SetVizierUpdatePolicy(t *testing.T) {
+	mustLoadTestData(db)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	s := controllers.New(db, "test", nil, newPolicyTestUpdater(ctrl))
+	orgID := utils.ProtoFromUUIDStrOrNil(testAuthOrgID)
+
+	tests := []struct {
+		name         string
+		policy       *vzmgrpb.VizierUpdatePolicy
+		expectedCode codes.Code
+	}{
+		{
+			name:         "wrong org",
+			policy:       &vzmgrpb.VizierUpdatePolicy{OrgID: utils.ProtoFromUUIDStrOrNil(testNonAuthOrgID)},
+			expectedCode: codes.PermissionDenied,
+		},
+		{
+			name:         "invalid pinned version",
+			policy:       &vzmgrpb.VizierUpdatePolicy{OrgID: orgID, PinnedVersion: "abc"},
+			expectedCode: codes.InvalidArgument,
+		},
+		{
+			name:         "too many versions behind",
+			policy:       &vzmgrpb.VizierUpdatePolicy{OrgID: orgID, VersionsBehind: 100},
+			expectedCode: codes.InvalidArgument,
+		},
+		{
+			name:         "invalid timezone",
+			policy:       &vzmgrpb.VizierUpdatePolicy{OrgID: orgID, Timezone: "Mars/Olympus_Mons"},
+			expectedCode: codes.InvalidArgument,
+		},
+		{
+			name: "empty window",
+			policy: &vzmgrpb.VizierUpdatePolicy{OrgID: orgID, MaintenanceWindows: []*vzmgrpb.MaintenanceWindow{
+				{StartHour: 10, EndHour: 10},
+			}},
+			expectedCode: codes.InvalidArgument,
+		},
+		{
+			name: "invalid window hours",
+			policy: &vzmgrpb.VizierUpdatePolicy{OrgID: orgID, MaintenanceWindows: []*vzmgrpb.MaintenanceWindow{
+				{StartHour: 10, EndHour: 25},
+			}},
+			expectedCode: codes.InvalidArgument,
+		},
+		{
+			name: "invalid window start hour",
+			policy: &vzmgrpb.VizierUpdatePolicy{OrgID: orgID, MaintenanceWindows: []*vzmgrpb.MaintenanceWindow{
+				{StartHour: 24, EndHour: 2},
+			}},
+			expectedCode: codes.InvalidArgument,
+		},
+		{
+			name: "invalid window day",
+			policy: &vzmgrpb.VizierUpdatePolicy{OrgID: orgID, MaintenanceWindows: []*vzmgrpb.MaintenanceWindow{
+				{Days: []string{"someday"}, StartHour: 2, EndHour: 4},
+			}},
+			expectedCode: codes.InvalidArgument,
+		},
+		{
+			name: "duplicate wave",
+			policy: &vzmgrpb.VizierUpdatePolicy{OrgID: orgID, Waves: []*vzmgrpb.UpdateWave{
+				{Name: "canary"},
+				{Name: "canary"},
+			}},
+			expectedCode: codes.InvalidArgument,
+		},
+		{
+			name: "cluster in multiple waves",
+			policy: &vzmgrpb.VizierUpdatePolicy{OrgID: orgID, Waves: []*vzmgrpb.UpdateWave{
+				{Name: "canary", ClusterIDs: []*uuidpb.UUID{utils.ProtoFromUUIDStrOrNil("123e4567-e89b-12d3-a456-426655440001")}},
+				{Name: "prod", ClusterIDs: []*uuidpb.UUID{utils.ProtoFromUUIDStrOrNil("123e4567-e89b-12d3-a456-426655440001")}},
+			}},
+			expectedCode: codes.InvalidArgument,
+		},
+		{
+			name: "cluster in other org",
+			policy: &vzmgrpb.VizierUpdatePolicy{OrgID: orgID, Waves: []*vzmgrpb.UpdateWave{
+				{Name: "canary", ClusterIDs: []*uuidpb.UUID{utils.ProtoFromUUIDStrOrNil("223e4567-e89b-12d3-a456-426655440003")}},
+			}},
+			expectedCode: codes.InvalidArgument,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resp, err := s.SetVizierUpdatePolicy(CreateTestContext(), test.policy)
+			assert.Nil(t, resp)
+			assert.Equal(t, test.expectedCode, status.Code(err))
+		})
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		policy := &vzmgrpb.VizierUpdatePolicy{
+			OrgID:          orgID,
+			PinnedVersion:  "0.4.2",
+			Channel:        vzmgrpb.VUC_STABLE,
+			VersionsBehind: 1,
+			MaintenanceWindows: []*vzmgrpb.MaintenanceWindow{
+				{Days: []string{"sat", "sun"}, StartHour: 2, EndHour: 6},
+				{Days: []string{"fri"}, StartHour: 22, EndHour: 4},
+			},
+			Timezone: "America/Los_Angeles",
+			Waves: []*vzmgrpb.UpdateWave{
+				{Name: "canary", ClusterIDs: []*uuidpb.UUID{utils.ProtoFromUUIDStrOrNil("123e4567-e89b-12d3-a456-426655440002")}},
+				{Name: "prod", ClusterIDs: []*uuidpb.UUID{utils.ProtoFromUUIDStrOrNil("123e4567-e89b-12d3-a456-426655440001")}},
+			},
+		}
+		resp, err := s.SetVizierUpdatePolicy(CreateTestContext(), policy)
+		require.NoError(t, err)
+		assert.Equal(t, "0.4.2", resp.TargetVersion)
+
+		resp, err = s.GetVizierUpdatePolicy(CreateTestContext(), orgID)
+		require.NoError(t, err)
+		policy.TargetVersion = "0.4.2"
+		assert.Equal(t, policy, resp)
+	})
+}
+
+func TestServer_HandleVizierHeartbeat_UpdatePolicy(t *testing.T) {
+	vizierID := uuid.FromStringOrNil("123e4567-e89b-12d3-a456-426655440001")
+	canaryID := uuid.FromStringOrNil("123e4567-e89b-12d3-a456-426655440002")
+	today := strings.ToLower(time.Now().UTC().Weekday().String()[:3])
+	tomorrow := strings.ToLower(time.Now().UTC().Add(24 * time.Hour).Weekday().String()[:3])
+
+	tests := []struct {
+		name            string
+		policy          *vzmgrpb.VizierUpdatePolicy
+		canaryStatus    string
+		canaryVersion   string
+		expectedVersion string
+		expectUpdate    bool
+	}{
+		{
+			name:            "no restrictions",
+			policy:          &vzmgrpb.VizierUpdatePolicy{},
+			expectedVersion: testLatestVersion,
+			expectUpdate:    true,
+		},
+		{
+			name:            "pinned",
+			policy:          &vzmgrpb.VizierUpdatePolicy{PinnedVersion: "0.4.2"},
+			expectedVersion: "0.4.2",
+			expectUpdate:    true,
+		},
+		{
+			name:   "already on pinned version",
+			policy: &vzmgrpb.VizierUpdatePolicy{PinnedVersion: "0.4.0"},
+		},
+		{
+			name: "in maintenance window",
+			policy: &vzmgrpb.VizierUpdatePolicy{MaintenanceWindows: []*vzmgrpb.MaintenanceWindow{
+				{Days: []string{today}, StartHour: 0, EndHour: 24},
+			}},
+			expectedVersion: testLatestVersion,
+			expectUpdate:    true,
+		},
+		{
+			name: "outside maintenance window",
+			policy: &vzmgrpb.VizierUpdatePolicy{MaintenanceWindows: []*vzmgrpb.MaintenanceWindow{
+				{Days: []string{tomorrow}, StartHour: 0, EndHour: 24},
+			}},
+		},
+		{
+			name: "earlier wave not updated",
+			policy: &vzmgrpb.VizierUpdatePolicy{Waves: []*vzmgrpb.UpdateWave{
+				{Name: "canary", ClusterIDs: []*uuidpb.UUID{utils.ProtoFromUUID(canaryID)}},
+			}},
+			canaryStatus:  "HEALTHY",
+			canaryVersion: "0.4.0",
+		},
+		{
+			name: "earlier wave unhealthy",
+			policy: &vzmgrpb.VizierUpdatePolicy{Waves: []*vzmgrpb.UpdateWave{
+				{Name: "canary", ClusterIDs: []*uuidpb.UUID{utils.ProtoFromUUID(canaryID)}},
+			}},
+			canaryStatus:  "UNHEALTHY",
+			canaryVersion: testLatestVersion,
+		},
+		{
+			name: "earlier wave disconnected",
+			policy: &vzmgrpb.VizierUpdatePolicy{Waves: []*vzmgrpb.UpdateWave{
+				{Name: "canary", ClusterIDs: []*uuidpb.UUID{utils.ProtoFromUUID(canaryID)}},
+			}},
+			canaryStatus:    "DISCONNECTED",
+			canaryVersion:   "0.4.0",
+			expectedVersion: testLatestVersion,
+			expectUpdate:    true,
+		},
+		{
+			name: "earlier wave updated",
+			policy: &vzmgrpb.VizierUpdatePolicy{Waves: []*vzmgrpb.UpdateWave{
+				{Name: "canary", ClusterIDs: []*uuidpb.UUID{utils.ProtoFromUUID(canaryID)}},
+			}},
+			canaryStatus:    "HEALTHY",
+			canaryVersion:   testLatestVersion,
+			expectedVersion: testLatestVersion,
+			expectUpdate:    true,
+		},
+		{
+			name: "first wave",
+			policy: &vzmgrpb.VizierUpdatePolicy{Waves: []*vzmgrpb.UpdateWave{
+				{Name: "canary", ClusterIDs: []*uuidpb.UUID{utils.ProtoFromUUID(vizierID)}},
+				{Name: "prod", ClusterIDs: []*uuidpb.UUID{utils.ProtoFromUUID(canaryID)}},
+			}},
+			canaryStatus:    "UNHEALTHY",
+			canaryVersion:   "0.4.0",
+			expectedVersion: testLatestVersion,
+			expectUpdate:    true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mustLoadTestData(db)
+			db.MustExec(`UPDATE vizier_cluster_info SET vizier_version='0.4.0' WHERE vizier_cluster_id=$1`, vizierID)
+			if test.canaryStatus != "" {
+				db.MustExec(`UPDATE vizier_cluster_info SET status=$1, vizier_version=$2 WHERE vizier_cluster_id=$3`,
+					test.canaryStatus, test.canaryVersion, canaryID)
+			}
+
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			nc, cleanup := testingutils.MustStartTestNATS(t)
+			defer cleanup()
+
+			updater := newPolicyTestUpdater(ctrl)
+			if test.expectUpdate {
+				updater.EXPECT().AddToUpdateQueue(vizierID, test.expectedVersion).Return(true)
+			}
+			s := controllers.New(db, "test", nc, updater)
+
+			test.policy.OrgID = utils.ProtoFromUUIDStrOrNil(testAuthOrgID)
+			_, err := s.SetVizierUpdatePolicy(CreateTestContext(), test.policy)
+			require.NoError(t, err)
+
+			heartbeat, err := types.MarshalAny(&cvmsgspb.VizierHeartbeat{
+				VizierID: utils.ProtoFromUUID(vizierID),
+				Status:   cvmsgspb.VZ_ST_HEALTHY,
+			})
+			require.NoError(t, err)
+			s.HandleVizierHeartbeat(&cvmsgspb.V2CMessage{Msg: heartbeat})
+		})
+	}
+}
@@ -21,9 +21,13 @@ package controllers
 import (
 	"database/sql/driver"
 	"encoding/json"
+	"fmt"
+	"strings"
 
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/validation"
 
 	"px.dev/pixie/src/shared/cvmsgspb"
 )
@@ -54,3 +58,55 @@ func (p *PodStatuses) Scan(src interface{}) error {
 
 	return nil
 }
+
+// ClusterLabels Type to use in sqlx for the map of cluster labels.
+type ClusterLabels map[string]string
+
+// Value Returns a golang database/sql driver value for ClusterLabels.
+func (l ClusterLabels) Value() (driver.Value, error) {
+	if l == nil {
+		l = ClusterLabels{}
+	}
+	res, err := json.Marshal(l)
+	if err != nil {
+		return res, err
+	}
+	return driver.Value(res), err
+}
+
+// Scan Scans the sqlx database type ([]bytes) into the ClusterLabels type.
+func (l *ClusterLabels) Scan(src interface{}) error {
+	switch jsonText := src.(type) {
+	case []byte:
+		err := json.Unmarshal(jsonText, l)
+		if err != nil {
+			return status.Error(codes.Internal, "could not unmarshal cluster labels")
+		}
+	default:
+		return status.Error(codes.Internal, "could not unmarshal cluster labels")
+	}
+
+	return nil
+}
+
+// validateClusterLabels checks that the given labels are valid Kubernetes label keys and values.
+func validateClusterLabels(l map[string]string) error {
+	for k, v := range l {
+		if errs := validation.IsQualifiedName(k); len(errs) > 0 {
+			return status.Errorf(codes.InvalidArgument, "invalid label key %q: %s", k, strings.Join(errs, "; "))
+		}
+		if errs := validation.IsValidLabelValue(v); len(errs) > 0 {
+			return status.Errorf(codes.InvalidArgument, "invalid value for label %q: %s", k, strings.Join(errs, "; "))
+		}
+	}
+	return nil
+}
+
+// parseLabelSelector parses a Kubernetes style label selector.
+func parseLabelSelector(selector string) (labels.Selector, error) {
+	sel, err := labels.Parse(selector)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, fmt.Sprintf("invalid label selector: %s", err.Error()))
+	}
+	return sel, nil
+}
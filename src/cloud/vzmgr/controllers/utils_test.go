@@ -74,3 +74,21 @@ func TestPodStatusScan(t *testing.T) {
 
 	assert.Equal(t, inputPodStatuses, outputPodStatuses)
 }
+
+func TestClusterLabelsScan(t *testing.T) {
+	inputLabels := controllers.ClusterLabels{
+		"env":              "prod",
+		"px.dev/region":    "eu",
+		"team.example.com": "",
+	}
+
+	var outputLabels controllers.ClusterLabels
+
+	serialized, err := inputLabels.Value()
+	require.NoError(t, err)
+
+	err = outputLabels.Scan(serialized)
+	require.NoError(t, err)
+
+	assert.Equal(t, inputLabels, outputLabels)
+}
@@ -38,22 +38,47 @@ import (
 
 	"px.dev/pixie/src/cloud/artifact_tracker/artifacttrackerpb"
 	"px.dev/pixie/src/cloud/shared/vzshard"
+	"px.dev/pixie/src/cloud/vzmgr/vzmgrpb"
 	"px.dev/pixie/src/shared/artifacts/versionspb"
 	"px.dev/pixie/src/shared/cvmsgspb"
 	jwtutils "px.dev/pixie/src/shared/services/utils"
 	"px.dev/pixie/src/utils"
 )
 
+const (
+	// versionHistoryLimit is the number of recent Vizier releases to track, which bounds how far
+	// behind the newest release an update policy can stay.
+	versionHistoryLimit = 20
+	// stableSoakPeriod is how long a release must have been available before it is considered stable.
+	stableSoakPeriod = 7 * 24 * time.Hour
+)
+
+// vizierRelease is a released Vizier version.
+type vizierRelease struct {
+	version    string
+	releasedAt time.Time
+}
+
+// queuedUpdate is a request to update a Vizier to a specific version.
+type queuedUpdate struct {
+	vizierID uuid.UUID
+	// version is the version to update to. The latest version is used if empty.
+	version string
+}
+
 // Updater is responsible for tracking and updating Viziers.
 type Updater struct {
 	latestVersion string // The latest Vizier version.
 
+	versionsMu sync.RWMutex
+	versions   []vizierRelease // Recent Vizier releases, newest first.
+
 	db       *sqlx.DB
 	atClient artifacttrackerpb.ArtifactTrackerClient
 	nc       *nats.Conn
 
 	quitCh        chan bool
-	updateQueue   chan uuid.UUID
+	updateQueue   chan queuedUpdate
 	queuedViziers map[uuid.UUID]bool // Map to track which viziers are already in the queue.
 	queueMu       sync.Mutex
 }
@@ -65,16 +90,16 @@ func NewUpdater(db *sqlx.DB, atClient artifacttrackerpb.ArtifactTrackerClient, n
 		atClient:      atClient,
 		nc:            nc,
 		quitCh:        make(chan bool),
-		updateQueue:   make(chan uuid.UUID, 32),
+		updateQueue:   make(chan queuedUpdate, 32),
 		queuedViziers: make(map[uuid.UUID]bool),
 	}
 
-	latestVersion, err := updater.getLatestVizierVersion()
+	versions, err := updater.getVizierVersions()
 	if err != nil {
 		return nil, err
 	}
 
-	updater.latestVersion = latestVersion
+	updater.setVersions(versions)
 
 	go updater.pollVizierVersion()
 
@@ -96,18 +121,32 @@ func (u *Updater) pollVizierVersion() {
 			log.Info("Quit signal, stopping Vizier version polling")
 			return
 		case <-ticker.C:
-			vzVersion, err := u.getLatestVizierVersion()
+			versions, err := u.getVizierVersions()
 			if err == nil {
-				u.latestVersion = vzVersion
+				u.setVersions(versions)
 			}
 		}
 	}
 }
 
-func (u *Updater) getLatestVizierVersion() (string, error) {
+func (u *Updater) setVersions(versions []vizierRelease) {
+	u.versionsMu.Lock()
+	defer u.versionsMu.Unlock()
+	u.versions = versions
+	u.latestVersion = versions[0].version
+}
+
+func (u *Updater) getLatestVersion() string {
+	u.versionsMu.RLock()
+	defer u.versionsMu.RUnlock()
+	return u.latestVersion
+}
+
+// getVizierVersions returns the most recent Vizier releases, newest first.
+func (u *Updater) getVizierVersions() ([]vizierRelease, error) {
 	serviceAuthToken, err := getServiceCredentials(viper.GetString("jwt_signing_key"))
 	if err != nil {
-		return "", errors.New("Could not get service creds")
+		return nil, errors.New("Could not get service creds")
 	}
 	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization",
 		fmt.Sprintf("bearer %s", serviceAuthToken))
@@ -115,19 +154,60 @@ func (u *Updater) getLatestVizierVersion() (string, error) {
 	req := &artifacttrackerpb.GetArtifactListRequest{
 		ArtifactName: "vizier",
 		ArtifactType: versionspb.AT_CONTAINER_SET_YAMLS,
-		Limit:        1,
+		Limit:        versionHistoryLimit,
 	}
 
 	resp, err := u.atClient.GetArtifactList(ctx, req)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+
+	if len(resp.Artifact) == 0 {
+		return nil, errors.New("Could not find Vizier artifact")
+	}
+
+	versions := make([]vizierRelease, len(resp.Artifact))
+	for i, a := range resp.Artifact {
+		versions[i] = vizierRelease{version: a.VersionStr}
+		if a.Timestamp != nil {
+			versions[i].releasedAt, _ = types.TimestampFromProto(a.Timestamp)
+		}
 	}
+	return versions, nil
+}
 
-	if len(resp.Artifact) != 1 {
-		return "", errors.New("Could not find Vizier artifact")
+// ResolveVersion returns the version that Viziers following the given channel should run, staying
+// versionsBehind releases behind the newest release in the channel. If there aren't enough
+// releases in the channel, the oldest known release in the channel is returned.
+func (u *Updater) ResolveVersion(channel vzmgrpb.VizierUpdateChannel, versionsBehind int) string {
+	u.versionsMu.RLock()
+	defer u.versionsMu.RUnlock()
+
+	var candidates []string
+	for _, v := range u.versions {
+		if channel == vzmgrpb.VUC_STABLE && time.Since(v.releasedAt) < stableSoakPeriod {
+			continue
+		}
+		candidates = append(candidates, v.version)
+	}
+	if len(candidates) == 0 {
+		return ""
+	}
+	if versionsBehind >= len(candidates) {
+		return candidates[len(candidates)-1]
 	}
+	return candidates[versionsBehind]
+}
 
-	return resp.Artifact[0].VersionStr, nil
+func (u *Updater) isKnownVersion(version string) bool {
+	u.versionsMu.RLock()
+	defer u.versionsMu.RUnlock()
+	for _, v := range u.versions {
+		if v.version == version {
+			return true
+		}
+	}
+	return false
 }
 
 // UpdateOrInstallVizier immediately updates or installs the Vizier instance. This should be used in cases where
@@ -146,10 +226,10 @@ func (u *Updater) updateOrInstallVizier(vizierID uuid.UUID, version string, rede
 	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization",
 		fmt.Sprintf("bearer %s", serviceAuthToken))
 
-	// Validate version.
+	// Validate version. Versions fetched from the artifact tracker are already known to be valid.
 	if version == "" {
-		version = u.latestVersion
-	} else {
+		version = u.getLatestVersion()
+	} else if !u.isKnownVersion(version) {
 		atReq := &artifacttrackerpb.GetDownloadLinkRequest{
 			ArtifactName: "vizier",
 			VersionStr:   version,
@@ -244,18 +324,30 @@ func (u *Updater) sendNATSMessage(topic string, msg *types.Any, vizierID uuid.UU
 	}
 }
 
-// VersionUpToDate checks if the given version string is up to date with the current vizier version.
-func (u *Updater) VersionUpToDate(version string) bool {
-	latestVersion := semver.MustParse(u.latestVersion)
+// parseVersion parses a version string. We have a set of viziers where the meta in the version
+// isn't tolerated by the semver lib. To successfully parse those versions, just drop the meta before
+// parsing.
+func parseVersion(version string) (semver.Version, error) {
+	versionNoMeta, _, _ := strings.Cut(version, "+")
+	return semver.Parse(versionNoMeta)
+}
+
+// VersionUpToDate checks if the given version string is up to date with the target version. The
+// latest vizier version is used if the target version is empty.
+func (u *Updater) VersionUpToDate(version string, targetVersion string) bool {
+	if targetVersion == "" {
+		targetVersion = u.getLatestVersion()
+	}
+	target, err := parseVersion(targetVersion)
+	if err != nil {
+		log.WithError(err).Error("Invalid target version")
+		return true
+	}
 	if len(version) == 0 {
 		// TODO(vihang): Investigate and consider marking these as needing an update.
 		return true // This happens for some viziers, let's call them uptodate for now.
 	}
-	// We have a set of viziers where the meta in the version isn't tolerated by
-	// the semver lib. To successfully parse those versions, just drop the meta before
-	// parsing.
-	versionNoMeta, _, _ := strings.Cut(version, "+")
-	vzVersion, err := semver.Parse(versionNoMeta)
+	vzVersion, err := parseVersion(version)
 	if err != nil {
 		log.WithError(err).Error("Invalid version string reported")
 		return true
@@ -264,14 +356,15 @@ func (u *Updater) VersionUpToDate(version string) bool {
 	if devVersionRange(vzVersion) {
 		return true // We should not update dev versions.
 	}
-	if vzVersion.Compare(latestVersion) < 0 {
+	if vzVersion.Compare(target) < 0 {
 		return false
 	}
 	return true
 }
 
-// AddToUpdateQueue queues the given Vizier for an update to the latest version.
-func (u *Updater) AddToUpdateQueue(vizierID uuid.UUID) bool {
+// AddToUpdateQueue queues the given Vizier for an update to the given version. The latest version is
+// used if the version is empty.
+func (u *Updater) AddToUpdateQueue(vizierID uuid.UUID, version string) bool {
 	u.queueMu.Lock()
 	defer u.queueMu.Unlock()
 
@@ -282,7 +375,7 @@ func (u *Updater) AddToUpdateQueue(vizierID uuid.UUID) bool {
 	// Add to queue if possible, else we will add it next time around.
 	// This helps buffer updates and rolls our the update slowly.
 	select {
-	case u.updateQueue <- vizierID:
+	case u.updateQueue <- queuedUpdate{vizierID: vizierID, version: version}:
 		u.queuedViziers[vizierID] = true
 		return true
 	default:
@@ -298,15 +391,15 @@ func (u *Updater) ProcessUpdateQueue() {
 		case <-u.quitCh:
 			log.Info("Quit signal, stopping Vizier updates")
 			return
-		case vzID := <-u.updateQueue:
+		case update := <-u.updateQueue:
 			vizierUpdatedCounter.Inc()
-			_, err := u.updateOrInstallVizier(vzID, "", false)
+			_, err := u.updateOrInstallVizier(update.vizierID, update.version, false)
 			if err != nil {
 				log.WithError(err).Error("Failed to send update to Vizier.")
 			}
 
 			u.queueMu.Lock()
-			delete(u.queuedViziers, vzID)
+			delete(u.queuedViziers, update.vizierID)
 			u.queueMu.Unlock()
 		}
 	}
@@ -21,6 +21,7 @@ package controllers_test
 import (
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/gofrs/uuid"
 	"github.com/gogo/protobuf/proto"
@@ -36,6 +37,7 @@ import (
 	mock_artifacttrackerpb "px.dev/pixie/src/cloud/artifact_tracker/artifacttrackerpb/mock"
 	"px.dev/pixie/src/cloud/shared/vzshard"
 	"px.dev/pixie/src/cloud/vzmgr/controllers"
+	"px.dev/pixie/src/cloud/vzmgr/vzmgrpb"
 	"px.dev/pixie/src/shared/artifacts/versionspb"
 	"px.dev/pixie/src/shared/cvmsgspb"
 	srvutils "px.dev/pixie/src/shared/services/utils"
@@ -55,7 +57,7 @@ func setUpUpdater(t *testing.T) (*controllers.Updater, *nats.Conn, *sqlx.DB, *mo
 	atReq := &artifacttrackerpb.GetArtifactListRequest{
 		ArtifactName: "vizier",
 		ArtifactType: versionspb.AT_CONTAINER_SET_YAMLS,
-		Limit:        1,
+		Limit:        20,
 	}
 	mockArtifactTrackerClient.EXPECT().GetArtifactList(
 		gomock.Any(), atReq).Return(&versionspb.ArtifactSet{
@@ -130,14 +132,88 @@ func TestUpdater_VersionUpToDate(t *testing.T) {
 	updater, _, _, _, cleanup := setUpUpdater(t)
 	defer cleanup()
 
-	assert.True(t, updater.VersionUpToDate("0.4.1"))
-	assert.True(t, updater.VersionUpToDate("0.4.2-pre-rc1"))
-	assert.True(t, updater.VersionUpToDate("0.4.1+meta.is.good"))
-	assert.True(t, updater.VersionUpToDate("0.4.1+meta.is...bad"))
-	assert.False(t, updater.VersionUpToDate("0.3.1"))
-	assert.False(t, updater.VersionUpToDate("0.3.1+meta.is.good"))
-	assert.False(t, updater.VersionUpToDate("0.3.1+meta.is...bad"))
-	assert.True(t, updater.VersionUpToDate("0.0.0-dev+Modified.0000000.19700101000000.0"))
+	assert.True(t, updater.VersionUpToDate("0.4.1", ""))
+	assert.True(t, updater.VersionUpToDate("0.4.2-pre-rc1", ""))
+	assert.True(t, updater.VersionUpToDate("0.4.1+meta.is.good", ""))
+	assert.True(t, updater.VersionUpToDate("0.4.1+meta.is...bad", ""))
+	assert.False(t, updater.VersionUpToDate("0.3.1", ""))
+	assert.False(t, updater.VersionUpToDate("0.3.1+meta.is.good", ""))
+	assert.False(t, updater.VersionUpToDate("0.3.1+meta.is...bad", ""))
+	assert.True(t, updater.VersionUpToDate("0.0.0-dev+Modified.0000000.19700101000000.0", ""))
+}
+
+func TestUpdater_ResolveVersion(t *testing.T) {
+	viper.Set("jwt_signing_key", "jwtkey")
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	nc, natsCleanup := testingutils.MustStartTestNATS(t)
+	defer natsCleanup()
+
+	releasedAt := func(age time.Duration) *types.Timestamp {
+		ts, _ := types.TimestampProto(time.Now().Add(-age))
+		return ts
+	}
+
+	mockArtifactTrackerClient := mock_artifacttrackerpb.NewMockArtifactTrackerClient(ctrl)
+	mockArtifactTrackerClient.EXPECT().GetArtifactList(gomock.Any(), gomock.Any()).Return(&versionspb.ArtifactSet{
+		Name: "vizier",
+		Artifact: []*versionspb.Artifact{
+			{VersionStr: "0.5.0", Timestamp: releasedAt(24 * time.Hour)},
+			{VersionStr: "0.4.1", Timestamp: releasedAt(10 * 24 * time.Hour)},
+			{VersionStr: "0.4.0", Timestamp: releasedAt(30 * 24 * time.Hour)},
+		},
+	}, nil).AnyTimes()
+
+	updater, err := controllers.NewUpdater(db, mockArtifactTrackerClient, nc)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name           string
+		channel        vzmgrpb.VizierUpdateChannel
+		versionsBehind int
+		expected       string
+	}{
+		{
+			name:     "latest",
+			channel:  vzmgrpb.VUC_LATEST,
+			expected: "0.5.0",
+		},
+		{
+			name:           "latest behind",
+			channel:        vzmgrpb.VUC_LATEST,
+			versionsBehind: 1,
+			expected:       "0.4.1",
+		},
+		{
+			name:     "stable",
+			channel:  vzmgrpb.VUC_STABLE,
+			expected: "0.4.1",
+		},
+		{
+			name:           "stable behind",
+			channel:        vzmgrpb.VUC_STABLE,
+			versionsBehind: 1,
+			expected:       "0.4.0",
+		},
+		{
+			name:           "past oldest known version",
+			channel:        vzmgrpb.VUC_STABLE,
+			versionsBehind: 5,
+			expected:       "0.4.0",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, updater.ResolveVersion(test.channel, test.versionsBehind))
+		})
+	}
+
+	assert.False(t, updater.VersionUpToDate("0.4.1", ""))
+	assert.True(t, updater.VersionUpToDate("0.4.1", "0.4.1"))
+	assert.False(t, updater.VersionUpToDate("0.4.0", "0.4.1"))
 }
 
 func TestUpdater_AddToUpdateQueue(t *testing.T) {
@@ -147,10 +223,10 @@ func TestUpdater_AddToUpdateQueue(t *testing.T) {
 	id1 := uuid.Must(uuid.NewV4())
 	id2 := uuid.Must(uuid.NewV4())
 
-	assert.True(t, updater.AddToUpdateQueue(id1))
-	assert.True(t, updater.AddToUpdateQueue(id2))
-	assert.False(t, updater.AddToUpdateQueue(id1))
-	assert.False(t, updater.AddToUpdateQueue(id2))
+	assert.True(t, updater.AddToUpdateQueue(id1, ""))
+	assert.True(t, updater.AddToUpdateQueue(id2, ""))
+	assert.False(t, updater.AddToUpdateQueue(id1, ""))
+	assert.False(t, updater.AddToUpdateQueue(id2, ""))
 }
 
 func TestUpdater_AddToUpdateQueueNoDeadlock(t *testing.T) {
@@ -160,12 +236,12 @@ func TestUpdater_AddToUpdateQueueNoDeadlock(t *testing.T) {
 	// Fill the update queue.
 	for i := 0; i < 32; i++ {
 		id := uuid.Must(uuid.NewV4())
-		assert.True(t, updater.AddToUpdateQueue(id))
+		assert.True(t, updater.AddToUpdateQueue(id, ""))
 	}
 	// Update queue no longer accepts updates.s
 	for i := 0; i < 32; i++ {
 		id := uuid.Must(uuid.NewV4())
-		assert.False(t, updater.AddToUpdateQueue(id))
+		assert.False(t, updater.AddToUpdateQueue(id, ""))
 	}
 
 	// Drain the update queue.
@@ -185,7 +261,7 @@ func TestUpdater_AddToUpdateQueueNoDeadlock(t *testing.T) {
 
 	// We should be able to add another update to the queue.
 	id := uuid.Must(uuid.NewV4())
-	assert.True(t, updater.AddToUpdateQueue(id))
+	assert.True(t, updater.AddToUpdateQueue(id, ""))
 
 	updater.Stop()
 }
@@ -196,7 +272,7 @@ func TestUpdater_ProcessUpdateQueue(t *testing.T) {
 	vizierID, _ := uuid.FromString("123e4567-e89b-12d3-a456-426655440001")
 	viper.Set("domain_name", "withpixie.ai")
 
-	assert.True(t, updater.AddToUpdateQueue(vizierID))
+	assert.True(t, updater.AddToUpdateQueue(vizierID, ""))
 
 	var wg sync.WaitGroup
 	wg.Add(1)
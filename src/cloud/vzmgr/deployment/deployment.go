@@ -22,6 +22,7 @@ import (
 	"context"
 
 	"github.com/gofrs/uuid"
+	"github.com/jmoiron/sqlx"
 	log "github.com/sirupsen/logrus"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -33,15 +34,25 @@ import (
 
 // InfoFetcher fetches information about deployments using the key.
 type InfoFetcher interface {
-	FetchOrgUserIDUsingDeploymentKey(context.Context, string) (uuid.UUID, uuid.UUID, uuid.UUID, error)
+	// FetchOrgUserIDUsingDeploymentKey gets the org, user and key ID for a key that can register the cluster with the
+	// given UID.
+	FetchOrgUserIDUsingDeploymentKey(ctx context.Context, key string, k8sClusterUID string) (uuid.UUID, uuid.UUID, uuid.UUID, error)
+	// ValidateClusterNameForDeploymentKey checks that a cluster with the given name can be registered using the key.
+	ValidateClusterNameForDeploymentKey(ctx context.Context, keyID uuid.UUID, clusterName string) error
+	// RegisterClusterWithDeploymentKey checks that the key can register the cluster, calls provision to provision it
+	// and records that the cluster was registered using the key, all in the transaction passed to provision.
+	RegisterClusterWithDeploymentKey(ctx context.Context, keyID uuid.UUID, k8sClusterUID string, provision func(tx *sqlx.Tx) (uuid.UUID, string, error)) (uuid.UUID, string, error)
 }
 
 // VizierProvisioner provisions a new Vizier.
 type VizierProvisioner interface {
 	// ProvisionVizier creates the vizier, with specified org_id, user_id, cluster_uid. Returns
 	// Cluster ID or error. If it already exists it will return the current cluster ID. Will return an error if the cluster is
-	// currently active (ie. Not disconnected).
-	ProvisionOrClaimVizier(context.Context, uuid.UUID, uuid.UUID, string, string) (uuid.UUID, string, error)
+	// currently active (ie. Not disconnected). The vizier is provisioned in the given transaction, which is committed
+	// by the caller. Also returns whether the vizier was assigned a new name.
+	ProvisionOrClaimVizierInTx(context.Context, *sqlx.Tx, uuid.UUID, uuid.UUID, string, string) (uuid.UUID, string, bool, error)
+	// TrackVizierCreated sends the event for a vizier that was assigned a new name, once its transaction is committed.
+	TrackVizierCreated(orgID uuid.UUID, clusterID uuid.UUID)
 }
 
 // Service is the deployment service.
@@ -61,19 +72,36 @@ func (s *Service) RegisterVizierDeployment(ctx context.Context, req *vzmgrpb.Reg
 		return nil, status.Error(codes.InvalidArgument, "empty cluster UID is not allowed")
 	}
 	// Fetch the orgID and userID based on the deployment key.
-	orgID, userID, keyID, err := s.deploymentInfoFetcher.FetchOrgUserIDUsingDeploymentKey(ctx, req.DeploymentKey)
+	orgID, userID, keyID, err := s.deploymentInfoFetcher.FetchOrgUserIDUsingDeploymentKey(ctx, req.DeploymentKey, req.K8sClusterUID)
 	if err != nil {
+		switch err {
+		case vzerrors.ErrDeploymentKeyExpired, vzerrors.ErrDeploymentKeyRevoked, vzerrors.ErrDeploymentKeyExhausted:
+			return nil, vzerrors.ToGRPCError(err)
+		}
 		return nil, status.Error(codes.Unauthenticated, "invalid/unknown deployment key")
 	}
+	err = s.deploymentInfoFetcher.ValidateClusterNameForDeploymentKey(ctx, keyID, req.K8sClusterName)
+	if err != nil {
+		return nil, vzerrors.ToGRPCError(err)
+	}
 	// Now we know the org and user ID to use for deployment. The process is as follows:
 	// 1. Try to fetch a cluster with either an empty UID or one where the UID matches the one in the protobuf.
 	// 2. If the UID matches then return that cluster.
 	// 3. Otherwise, pick a cluster with no UID specified and claim it.
 	// 4. If no empty clusters exist then we create a new cluster.
-	clusterID, clusterName, err := s.vp.ProvisionOrClaimVizier(ctx, orgID, userID, req.K8sClusterUID, req.K8sClusterName)
+	// The cluster is only provisioned if the key hasn't registered its maximum number of clusters.
+	var named bool
+	clusterID, clusterName, err := s.deploymentInfoFetcher.RegisterClusterWithDeploymentKey(ctx, keyID, req.K8sClusterUID, func(tx *sqlx.Tx) (uuid.UUID, string, error) {
+		id, name, isNamed, err := s.vp.ProvisionOrClaimVizierInTx(ctx, tx, orgID, userID, req.K8sClusterUID, req.K8sClusterName)
+		named = isNamed
+		return id, name, err
+	})
 	if err != nil {
 		return nil, vzerrors.ToGRPCError(err)
 	}
+	if named {
+		s.vp.TrackVizierCreated(orgID, clusterID)
+	}
 
 	log.WithField("orgID", orgID).WithField("keyID", keyID).WithField("clusterID", clusterID).WithField("clusterName", clusterName).Info("Successfully registered Vizier deployment")
 
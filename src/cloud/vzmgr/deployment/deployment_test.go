@@ -24,6 +24,7 @@ import (
 	"testing"
 
 	"github.com/gofrs/uuid"
+	"github.com/jmoiron/sqlx"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"google.golang.org/grpc/codes"
@@ -42,33 +43,77 @@ var (
 
 	testValidClusterID = uuid.FromStringOrNil("553e4567-e89b-12d3-a456-426655440000")
 
-	testValidDeploymentKey = "883e4567-e89b-12d3-a456-426655440000"
+	testValidDeploymentKey   = "883e4567-e89b-12d3-a456-426655440000"
+	testRevokedDeploymentKey = "993e4567-e89b-12d3-a456-426655440000"
+
+	// testNewClusterUID is the UID of a cluster that isn't registered with the test key, which has reached its
+	// maximum number of clusters.
+	testNewClusterUID = "cluster3"
+	// testRacingClusterUID is the UID of a cluster whose registration takes the key's last cluster after another
+	// registration has already taken it.
+	testRacingClusterUID = "cluster4"
 )
 
-type fakeDF struct{}
+type fakeDF struct {
+	usages []uuid.UUID
+}
 
-func (f *fakeDF) FetchOrgUserIDUsingDeploymentKey(ctx context.Context, key string) (uuid.UUID, uuid.UUID, uuid.UUID, error) {
+func (f *fakeDF) FetchOrgUserIDUsingDeploymentKey(ctx context.Context, key string, k8sClusterUID string) (uuid.UUID, uuid.UUID, uuid.UUID, error) {
+	if key == testValidDeploymentKey && k8sClusterUID == testNewClusterUID {
+		return uuid.Nil, uuid.Nil, uuid.Nil, vzerrors.ErrDeploymentKeyExhausted
+	}
 	if key == testValidDeploymentKey {
 		return testOrgID, testUserID, testKeyID, nil
 	}
+	if key == testRevokedDeploymentKey {
+		return uuid.Nil, uuid.Nil, uuid.Nil, vzerrors.ErrDeploymentKeyRevoked
+	}
 	return uuid.Nil, uuid.Nil, uuid.Nil, vzerrors.ErrDeploymentKeyNotFound
 }
 
+func (f *fakeDF) ValidateClusterNameForDeploymentKey(ctx context.Context, keyID uuid.UUID, clusterName string) error {
+	if clusterName == "not-allowed" {
+		return vzerrors.ErrClusterNameNotAllowed
+	}
+	return nil
+}
+
+func (f *fakeDF) RegisterClusterWithDeploymentKey(ctx context.Context, keyID uuid.UUID, k8sClusterUID string, provision func(tx *sqlx.Tx) (uuid.UUID, string, error)) (uuid.UUID, string, error) {
+	if k8sClusterUID == testRacingClusterUID {
+		return uuid.Nil, "", vzerrors.ErrDeploymentKeyExhausted
+	}
+	clusterID, clusterName, err := provision(nil)
+	if err != nil {
+		return uuid.Nil, "", err
+	}
+	f.usages = append(f.usages, clusterID)
+	return clusterID, clusterName, nil
+}
+
 type fakeProvisioner struct {
+	numCalls int
+	created  []uuid.UUID
 }
 
-func (f *fakeProvisioner) ProvisionOrClaimVizier(ctx context.Context, orgID uuid.UUID, userID uuid.UUID, clusterUID string, clusterName string) (uuid.UUID, string, error) {
+func (f *fakeProvisioner) ProvisionOrClaimVizierInTx(ctx context.Context, tx *sqlx.Tx, orgID uuid.UUID, userID uuid.UUID, clusterUID string, clusterName string) (uuid.UUID, string, bool, error) {
+	f.numCalls++
 	if testOrgID == orgID && testUserID == userID && clusterUID == "cluster1" && clusterName == "test" {
-		return testValidClusterID, clusterName, nil
+		return testValidClusterID, clusterName, true, nil
 	}
 	if testOrgID == orgID && testUserID == userID && clusterUID == "cluster2" {
-		return uuid.Nil, "", vzerrors.ErrProvisionFailedVizierIsActive
+		return uuid.Nil, "", false, vzerrors.ErrProvisionFailedVizierIsActive
 	}
-	return uuid.Nil, "", errors.New("bad request")
+	return uuid.Nil, "", false, errors.New("bad request")
+}
+
+func (f *fakeProvisioner) TrackVizierCreated(orgID uuid.UUID, clusterID uuid.UUID) {
+	f.created = append(f.created, clusterID)
 }
 
 func TestService_RegisterVizierDeployment(t *testing.T) {
-	svc := deployment.New(&fakeDF{}, &fakeProvisioner{})
+	df := &fakeDF{}
+	vp := &fakeProvisioner{}
+	svc := deployment.New(df, vp)
 
 	ctx := context.Background()
 	resp, err := svc.RegisterVizierDeployment(ctx, &vzmgrpb.RegisterVizierDeploymentRequest{
@@ -79,6 +124,8 @@ func TestService_RegisterVizierDeployment(t *testing.T) {
 	require.NoError(t, err)
 	assert.NotNil(t, resp)
 	assert.Equal(t, testValidClusterID, utils.UUIDFromProtoOrNil(resp.VizierID))
+	assert.Equal(t, []uuid.UUID{testValidClusterID}, df.usages)
+	assert.Equal(t, []uuid.UUID{testValidClusterID}, vp.created)
 }
 
 func TestService_RegisterVizierDeployment_ClusterAlreadyRunning(t *testing.T) {
@@ -106,3 +153,69 @@ func TestService_RegisterVizierDeployment_InvalidDeployKey(t *testing.T) {
 	assert.NotNil(t, err)
 	assert.Equal(t, codes.Unauthenticated, status.Code(err))
 }
+
+func TestService_RegisterVizierDeployment_RevokedDeployKey(t *testing.T) {
+	svc := deployment.New(&fakeDF{}, &fakeProvisioner{})
+
+	ctx := context.Background()
+	resp, err := svc.RegisterVizierDeployment(ctx, &vzmgrpb.RegisterVizierDeploymentRequest{
+		K8sClusterUID:  "cluster1",
+		DeploymentKey:  testRevokedDeploymentKey,
+		K8sClusterName: "test",
+	})
+	assert.Nil(t, resp)
+	assert.NotNil(t, err)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestService_RegisterVizierDeployment_ClusterNameNotAllowed(t *testing.T) {
+	df := &fakeDF{}
+	svc := deployment.New(df, &fakeProvisioner{})
+
+	ctx := context.Background()
+	resp, err := svc.RegisterVizierDeployment(ctx, &vzmgrpb.RegisterVizierDeploymentRequest{
+		K8sClusterUID:  "cluster1",
+		DeploymentKey:  testValidDeploymentKey,
+		K8sClusterName: "not-allowed",
+	})
+	assert.Nil(t, resp)
+	assert.NotNil(t, err)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	assert.Empty(t, df.usages)
+}
+
+func TestService_RegisterVizierDeployment_KeyExhausted(t *testing.T) {
+	df := &fakeDF{}
+	vp := &fakeProvisioner{}
+	svc := deployment.New(df, vp)
+
+	ctx := context.Background()
+	resp, err := svc.RegisterVizierDeployment(ctx, &vzmgrpb.RegisterVizierDeploymentRequest{
+		K8sClusterUID:  testNewClusterUID,
+		DeploymentKey:  testValidDeploymentKey,
+		K8sClusterName: "test",
+	})
+	assert.Nil(t, resp)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.Equal(t, vzerrors.ErrDeploymentKeyExhausted.Error(), status.Convert(err).Message())
+	// The cluster isn't provisioned if the key can't register it.
+	assert.Equal(t, 0, vp.numCalls)
+	assert.Empty(t, df.usages)
+}
+
+func TestService_RegisterVizierDeployment_KeyExhaustedDuringRegistration(t *testing.T) {
+	df := &fakeDF{}
+	vp := &fakeProvisioner{}
+	svc := deployment.New(df, vp)
+
+	ctx := context.Background()
+	resp, err := svc.RegisterVizierDeployment(ctx, &vzmgrpb.RegisterVizierDeploymentRequest{
+		K8sClusterUID:  testRacingClusterUID,
+		DeploymentKey:  testValidDeploymentKey,
+		K8sClusterName: "test",
+	})
+	assert.Nil(t, resp)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.Equal(t, 0, vp.numCalls)
+	assert.Empty(t, vp.created)
+}
@@ -21,7 +21,11 @@ package deploymentkey
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"path"
 	"strings"
 	"time"
 
@@ -40,8 +44,115 @@ import (
 const (
 	// deployKeyPrefox is applied to all deploy keys to make them easier to identify.
 	deployKeyPrefix = "px-dep-"
+	// keyLimitsColumns are the columns selected to populate keyLimits. The key table must be aliased as k.
+	keyLimitsColumns = `k.expires_at, k.max_clusters, k.allowed_cluster_names, k.revoked_at,
+                (SELECT COUNT(*) FROM vizier_deployment_key_usages u WHERE u.deployment_key_id=k.id)`
 )
 
+// ClusterNamePatterns is a list of glob patterns that cluster names must match.
+type ClusterNamePatterns []string
+
+// Value implements the driver.Valuer interface.
+func (p ClusterNamePatterns) Value() (driver.Value, error) {
+	if p == nil {
+		return json.Marshal([]string{})
+	}
+	return json.Marshal(p)
+}
+
+// Scan implements the sql.Scanner interface.
+func (p *ClusterNamePatterns) Scan(src interface{}) error {
+	var b []byte
+	switch v := src.(type) {
+	case nil:
+		*p = nil
+		return nil
+	case []byte:
+		b = v
+	case string:
+		b = []byte(v)
+	default:
+		return errors.New("unsupported type for cluster name patterns")
+	}
+	var patterns []string
+	if err := json.Unmarshal(b, &patterns); err != nil {
+		return err
+	}
+	if len(patterns) == 0 {
+		patterns = nil
+	}
+	*p = patterns
+	return nil
+}
+
+// Matches returns whether the cluster name is allowed by the patterns. All names are allowed if there are no
+// patterns.
+func (p ClusterNamePatterns) Matches(clusterName string) bool {
+	if len(p) == 0 {
+		return true
+	}
+	for _, pattern := range p {
+		if ok, _ := path.Match(pattern, clusterName); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// keyLimits are the restrictions on how a deployment key can be used.
+type keyLimits struct {
+	expiresAt           *time.Time
+	maxClusters         int64
+	allowedClusterNames ClusterNamePatterns
+	revokedAt           *time.Time
+	numClusters         int64
+}
+
+// scanDest returns the scan destinations for keyLimitsColumns.
+func (l *keyLimits) scanDest() []interface{} {
+	return []interface{}{&l.expiresAt, &l.maxClusters, &l.allowedClusterNames, &l.revokedAt, &l.numClusters}
+}
+
+func timestampProtoOrNil(t *time.Time) *types.Timestamp {
+	if t == nil {
+		return nil
+	}
+	tp, _ := types.TimestampProto(*t)
+	return tp
+}
+
+func (l *keyLimits) toKeyProto(key *vzmgrpb.DeploymentKey) {
+	key.ExpiresAt = timestampProtoOrNil(l.expiresAt)
+	key.MaxClusters = l.maxClusters
+	key.AllowedClusterNames = l.allowedClusterNames
+	key.RevokedAt = timestampProtoOrNil(l.revokedAt)
+	key.NumClusters = l.numClusters
+}
+
+func (l *keyLimits) toMetadataProto(key *vzmgrpb.DeploymentKeyMetadata) {
+	key.ExpiresAt = timestampProtoOrNil(l.expiresAt)
+	key.MaxClusters = l.maxClusters
+	key.AllowedClusterNames = l.allowedClusterNames
+	key.RevokedAt = timestampProtoOrNil(l.revokedAt)
+	key.NumClusters = l.numClusters
+}
+
+// validate checks whether the key can still be used to register a cluster. Clusters that were already registered
+// with the key can always register again, so a key that has registered its maximum number of clusters is only
+// rejected for new clusters.
+func (l *keyLimits) validate(registered bool) error {
+	if l.revokedAt != nil {
+		return vzerrors.ErrDeploymentKeyRevoked
+	}
+	if l.expiresAt != nil && time.Now().After(*l.expiresAt) {
+		return vzerrors.ErrDeploymentKeyExpired
+	}
+	if !registered && l.maxClusters > 0 && l.numClusters >= l.maxClusters {
+		return vzerrors.ErrDeploymentKeyExhausted
+	}
+	return nil
+}
+
 // Service is used to provision and manage deployment keys.
 type Service struct {
 	db    *sqlx.DB
@@ -68,17 +179,39 @@ func (s *Service) Create(ctx context.Context, req *vzmgrpb.CreateDeploymentKeyRe
 		return nil, status.Error(codes.InvalidArgument, "invalid user id format")
 	}
 
+	var expiresAt *time.Time
+	if req.ExpiresAt != nil {
+		t, err := types.TimestampFromProto(req.ExpiresAt)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, "invalid expiry time")
+		}
+		if t.Before(time.Now()) {
+			return nil, status.Error(codes.InvalidArgument, "expiry time must be in the future")
+		}
+		expiresAt = &t
+	}
+	if req.MaxClusters < 0 {
+		return nil, status.Error(codes.InvalidArgument, "max clusters must not be negative")
+	}
+	for _, pattern := range req.AllowedClusterNames {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid cluster name pattern '%s'", pattern)
+		}
+	}
+
 	var id uuid.UUID
 	var ts time.Time
-	query := `INSERT INTO vizier_deployment_keys(org_id, user_id, hashed_key, encrypted_key, description)
-                VALUES($1, $2, sha256($3), PGP_SYM_ENCRYPT($3::text, $4::text), $5)
+	query := `INSERT INTO vizier_deployment_keys(org_id, user_id, hashed_key, encrypted_key, description,
+                expires_at, max_clusters, allowed_cluster_names)
+                VALUES($1, $2, sha256($3), PGP_SYM_ENCRYPT($3::text, $4::text), $5, $6, $7, $8)
               RETURNING id, created_at`
 	keyID, err := uuid.NewV4()
 	if err != nil {
 		return nil, err
 	}
 	key := deployKeyPrefix + keyID.String()
-	err = s.db.QueryRowxContext(ctx, query, orgID, userID, key, s.dbKey, req.Desc).
+	err = s.db.QueryRowxContext(ctx, query, orgID, userID, key, s.dbKey, req.Desc,
+		expiresAt, req.MaxClusters, ClusterNamePatterns(req.AllowedClusterNames)).
 		Scan(&id, &ts)
 	if err != nil {
 		log.WithError(err).Error("Failed to insert deployment keys")
@@ -87,9 +220,12 @@ func (s *Service) Create(ctx context.Context, req *vzmgrpb.CreateDeploymentKeyRe
 
 	tp, _ := types.TimestampProto(ts)
 	return &vzmgrpb.DeploymentKey{
-		ID:        utils.ProtoFromUUID(id),
-		Key:       key,
-		CreatedAt: tp,
+		ID:                  utils.ProtoFromUUID(id),
+		Key:                 key,
+		CreatedAt:           tp,
+		ExpiresAt:           req.ExpiresAt,
+		MaxClusters:         req.MaxClusters,
+		AllowedClusterNames: req.AllowedClusterNames,
 	}, nil
 }
 
@@ -101,10 +237,10 @@ func (s *Service) List(ctx context.Context, req *vzmgrpb.ListDeploymentKeyReques
 	}
 
 	// Return all clusters when the OrgID matches.
-	query := `SELECT id, org_id, user_id, created_at, description
-                FROM vizier_deployment_keys
-                WHERE org_id=$1
-                ORDER BY created_at`
+	query := `SELECT k.id, k.org_id, k.user_id, k.created_at, k.description, ` + keyLimitsColumns + `
+                FROM vizier_deployment_keys k
+                WHERE k.org_id=$1
+                ORDER BY k.created_at`
 	rows, err := s.db.QueryxContext(ctx, query, orgID)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -122,19 +258,22 @@ func (s *Service) List(ctx context.Context, req *vzmgrpb.ListDeploymentKeyReques
 		var userID uuid.UUID
 		var createdAt time.Time
 		var desc string
-		err = rows.Scan(&id, &orgID, &userID, &createdAt, &desc)
+		var limits keyLimits
+		err = rows.Scan(append([]interface{}{&id, &orgID, &userID, &createdAt, &desc}, limits.scanDest()...)...)
 		if err != nil {
 			log.WithError(err).Error("Failed to read data from postgres")
 			return nil, status.Error(codes.Internal, "failed to read data")
 		}
 		tProto, _ := types.TimestampProto(createdAt)
-		keys = append(keys, &vzmgrpb.DeploymentKeyMetadata{
+		key := &vzmgrpb.DeploymentKeyMetadata{
 			ID:        utils.ProtoFromUUIDStrOrNil(id),
 			OrgID:     utils.ProtoFromUUID(orgID),
 			UserID:    utils.ProtoFromUUID(userID),
 			CreatedAt: tProto,
 			Desc:      desc,
-		})
+		}
+		limits.toMetadataProto(key)
+		keys = append(keys, key)
 	}
 	return &vzmgrpb.ListDeploymentKeyResponse{
 		Keys: keys,
@@ -156,24 +295,66 @@ func (s *Service) Get(ctx context.Context, req *vzmgrpb.GetDeploymentKeyRequest)
 	var key string
 	var createdAt time.Time
 	var desc string
-	query := `SELECT CONVERT_FROM(PGP_SYM_DECRYPT(encrypted_key, $3::text)::bytea, 'UTF8'), user_id, created_at, description
-                FROM vizier_deployment_keys
-                WHERE org_id=$1 AND id=$2`
+	var limits keyLimits
+	query := `SELECT CONVERT_FROM(PGP_SYM_DECRYPT(k.encrypted_key, $3::text)::bytea, 'UTF8'), k.user_id, k.created_at, k.description, ` + keyLimitsColumns + `
+                FROM vizier_deployment_keys k
+                WHERE k.org_id=$1 AND k.id=$2`
 	err = s.db.QueryRowxContext(ctx, query, orgID, tokenID, s.dbKey).
-		Scan(&key, &userID, &createdAt, &desc)
+		Scan(append([]interface{}{&key, &userID, &createdAt, &desc}, limits.scanDest()...)...)
 	if err != nil {
 		return nil, status.Error(codes.NotFound, "No such deployment key")
 	}
 
+	usages, err := s.fetchUsages(ctx, tokenID)
+	if err != nil {
+		log.WithError(err).Error("Failed to fetch deployment key usages")
+		return nil, status.Error(codes.Internal, "failed to fetch deployment key usages")
+	}
+
 	createdAtProto, _ := types.TimestampProto(createdAt)
-	return &vzmgrpb.GetDeploymentKeyResponse{Key: &vzmgrpb.DeploymentKey{
+	resp := &vzmgrpb.DeploymentKey{
 		ID:        req.ID,
 		OrgID:     utils.ProtoFromUUID(orgID),
 		UserID:    utils.ProtoFromUUID(userID),
 		Key:       key,
 		CreatedAt: createdAtProto,
 		Desc:      desc,
-	}}, nil
+		Usages:    usages,
+	}
+	limits.toKeyProto(resp)
+	return &vzmgrpb.GetDeploymentKeyResponse{Key: resp}, nil
+}
+
+func (s *Service) fetchUsages(ctx context.Context, keyID uuid.UUID) ([]*vzmgrpb.DeploymentKeyUsage, error) {
+	query := `SELECT vizier_cluster_id, k8s_cluster_uid, cluster_name, registered_at
+                FROM vizier_deployment_key_usages
+                WHERE deployment_key_id=$1
+                ORDER BY registered_at`
+	rows, err := s.db.QueryxContext(ctx, query, keyID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var usages []*vzmgrpb.DeploymentKeyUsage
+	for rows.Next() {
+		var clusterID uuid.UUID
+		var k8sClusterUID string
+		var clusterName string
+		var registeredAt time.Time
+		err = rows.Scan(&clusterID, &k8sClusterUID, &clusterName, &registeredAt)
+		if err != nil {
+			return nil, err
+		}
+		registeredAtProto, _ := types.TimestampProto(registeredAt)
+		usages = append(usages, &vzmgrpb.DeploymentKeyUsage{
+			ClusterID:     utils.ProtoFromUUID(clusterID),
+			K8sClusterUID: k8sClusterUID,
+			ClusterName:   clusterName,
+			RegisteredAt:  registeredAtProto,
+		})
+	}
+	return usages, nil
 }
 
 // Delete will remove the key.
@@ -208,9 +389,44 @@ func (s *Service) Delete(ctx context.Context, req *vzmgrpb.DeleteDeploymentKeyRe
 	return &types.Empty{}, nil
 }
 
-// FetchOrgUserIDUsingDeploymentKey gets the org and user ID based on the deployment key.
-func (s *Service) FetchOrgUserIDUsingDeploymentKey(ctx context.Context, key string) (uuid.UUID, uuid.UUID, uuid.UUID, error) {
-	resp, err := s.fetchDeploymentKeyUsingKeyFromDB(ctx, key)
+// Revoke marks the key as revoked, so that it can no longer be used to register clusters.
+func (s *Service) Revoke(ctx context.Context, req *vzmgrpb.RevokeDeploymentKeyRequest) (*types.Empty, error) {
+	tokenID, err := utils.UUIDFromProto(req.ID)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid deploy key id format")
+	}
+	orgID, err := utils.UUIDFromProto(req.OrgID)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid org id format")
+	}
+
+	query := `UPDATE vizier_deployment_keys
+                SET revoked_at=COALESCE(revoked_at, NOW())
+                WHERE org_id=$1 AND id=$2`
+	res, err := s.db.ExecContext(ctx, query, orgID, tokenID)
+	if err != nil {
+		log.WithError(err).Error("Failed to revoke deployment token")
+		return nil, status.Error(codes.Internal, "failed to revoke deployment token")
+	}
+
+	c, err := res.RowsAffected()
+	if err != nil {
+		log.WithError(err).Error("Failed to revoke deployment token")
+		return nil, status.Error(codes.Internal, "failed to revoke deployment token")
+	}
+
+	if c == 0 {
+		return nil, status.Error(codes.NotFound, "no such token to revoke")
+	}
+
+	return &types.Empty{}, nil
+}
+
+// FetchOrgUserIDUsingDeploymentKey gets the org and user ID based on the deployment key. Keys that are expired or
+// revoked are rejected, as are keys that have registered their maximum number of clusters, unless the cluster with
+// the given UID was already registered with the key.
+func (s *Service) FetchOrgUserIDUsingDeploymentKey(ctx context.Context, key string, k8sClusterUID string) (uuid.UUID, uuid.UUID, uuid.UUID, error) {
+	resp, limits, err := s.fetchDeploymentKeyUsingKeyFromDB(ctx, key)
 	if err != nil {
 		return uuid.Nil, uuid.Nil, uuid.Nil, err
 	}
@@ -226,12 +442,20 @@ func (s *Service) FetchOrgUserIDUsingDeploymentKey(ctx context.Context, key stri
 	if err != nil {
 		return uuid.Nil, uuid.Nil, uuid.Nil, err
 	}
+	registered, err := isClusterRegistered(ctx, s.db, keyID, k8sClusterUID)
+	if err != nil {
+		return uuid.Nil, uuid.Nil, uuid.Nil, err
+	}
+	err = limits.validate(registered)
+	if err != nil {
+		return uuid.Nil, uuid.Nil, uuid.Nil, err
+	}
 	return oid, uid, keyID, nil
 }
 
 // LookupDeploymentKey gets the complete Deployment key information using just the Key.
 func (s *Service) LookupDeploymentKey(ctx context.Context, req *vzmgrpb.LookupDeploymentKeyRequest) (*vzmgrpb.LookupDeploymentKeyResponse, error) {
-	resp, err := s.fetchDeploymentKeyUsingKeyFromDB(ctx, req.Key)
+	resp, _, err := s.fetchDeploymentKeyUsingKeyFromDB(ctx, req.Key)
 	if err != nil {
 		if err == vzerrors.ErrDeploymentKeyNotFound {
 			return nil, status.Error(codes.NotFound, "deployment key not found")
@@ -241,7 +465,100 @@ func (s *Service) LookupDeploymentKey(ctx context.Context, req *vzmgrpb.LookupDe
 	return &vzmgrpb.LookupDeploymentKeyResponse{Key: resp}, nil
 }
 
-func (s *Service) fetchDeploymentKeyUsingKeyFromDB(ctx context.Context, key string) (*vzmgrpb.DeploymentKey, error) {
+// ValidateClusterNameForDeploymentKey checks that a cluster with the given name can be registered using the key.
+func (s *Service) ValidateClusterNameForDeploymentKey(ctx context.Context, keyID uuid.UUID, clusterName string) error {
+	var patterns ClusterNamePatterns
+	query := `SELECT allowed_cluster_names FROM vizier_deployment_keys WHERE id=$1`
+	err := s.db.QueryRowxContext(ctx, query, keyID).Scan(&patterns)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return vzerrors.ErrDeploymentKeyNotFound
+		}
+		return vzerrors.ErrInternalDB
+	}
+	if !patterns.Matches(clusterName) {
+		return vzerrors.ErrClusterNameNotAllowed
+	}
+	return nil
+}
+
+// isClusterRegistered checks whether the cluster with the given UID was registered with the key. Clusters without a
+// UID are never considered to be registered.
+func isClusterRegistered(ctx context.Context, q sqlx.QueryerContext, keyID uuid.UUID, k8sClusterUID string) (bool, error) {
+	if k8sClusterUID == "" {
+		return false, nil
+	}
+	var registered bool
+	query := `SELECT EXISTS(SELECT 1 FROM vizier_deployment_key_usages WHERE deployment_key_id=$1 AND k8s_cluster_uid=$2)`
+	err := q.QueryRowxContext(ctx, query, keyID, k8sClusterUID).Scan(&registered)
+	if err != nil {
+		return false, vzerrors.ErrInternalDB
+	}
+	return registered, nil
+}
+
+// RegisterClusterWithDeploymentKey checks that the key can register the cluster with the given UID, calls provision
+// to provision the cluster, and records that the cluster was registered using the key. provision is called with the
+// transaction that records the usage, so that the cluster is only provisioned if the usage is recorded. Clusters that
+// were already registered with the key don't count towards its maximum number of clusters. Registrations that use
+// the same key are serialized, so that concurrent registrations can't exceed the maximum.
+func (s *Service) RegisterClusterWithDeploymentKey(ctx context.Context, keyID uuid.UUID, k8sClusterUID string,
+	provision func(tx *sqlx.Tx) (uuid.UUID, string, error)) (uuid.UUID, string, error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return uuid.Nil, "", vzerrors.ErrInternalDB
+	}
+	defer tx.Rollback()
+
+	// Lock the key until the usage is recorded.
+	var maxClusters int64
+	query := `SELECT max_clusters FROM vizier_deployment_keys WHERE id=$1 FOR UPDATE`
+	err = tx.QueryRowxContext(ctx, query, keyID).Scan(&maxClusters)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return uuid.Nil, "", vzerrors.ErrDeploymentKeyNotFound
+		}
+		return uuid.Nil, "", vzerrors.ErrInternalDB
+	}
+
+	if maxClusters > 0 {
+		registered, err := isClusterRegistered(ctx, tx, keyID, k8sClusterUID)
+		if err != nil {
+			return uuid.Nil, "", err
+		}
+		var numClusters int64
+		query = `SELECT COUNT(*) FROM vizier_deployment_key_usages WHERE deployment_key_id=$1`
+		err = tx.QueryRowxContext(ctx, query, keyID).Scan(&numClusters)
+		if err != nil {
+			return uuid.Nil, "", vzerrors.ErrInternalDB
+		}
+		if !registered && numClusters >= maxClusters {
+			return uuid.Nil, "", vzerrors.ErrDeploymentKeyExhausted
+		}
+	}
+
+	clusterID, clusterName, err := provision(tx)
+	if err != nil {
+		return uuid.Nil, "", err
+	}
+
+	query = `INSERT INTO vizier_deployment_key_usages(deployment_key_id, vizier_cluster_id, k8s_cluster_uid, cluster_name)
+                VALUES($1, $2, $3, $4)
+              ON CONFLICT (deployment_key_id, vizier_cluster_id) DO UPDATE
+                SET k8s_cluster_uid=EXCLUDED.k8s_cluster_uid, cluster_name=EXCLUDED.cluster_name, registered_at=NOW()`
+	_, err = tx.ExecContext(ctx, query, keyID, clusterID, k8sClusterUID, clusterName)
+	if err != nil {
+		log.WithError(err).WithField("keyID", keyID).WithField("clusterID", clusterID).Error("Failed to record deployment key usage")
+		return uuid.Nil, "", vzerrors.ErrInternalDB
+	}
+	if err := tx.Commit(); err != nil {
+		log.WithError(err).WithField("keyID", keyID).WithField("clusterID", clusterID).Error("Failed to commit deployment key usage")
+		return uuid.Nil, "", vzerrors.ErrInternalDB
+	}
+	return clusterID, clusterName, nil
+}
+
+func (s *Service) fetchDeploymentKeyUsingKeyFromDB(ctx context.Context, key string) (*vzmgrpb.DeploymentKey, *keyLimits, error) {
 	// For backwards compatibility add in deployKeyPrefix the front of the keys.
 	if !strings.HasPrefix(key, deployKeyPrefix) {
 		key = deployKeyPrefix + key
@@ -251,25 +568,28 @@ func (s *Service) fetchDeploymentKeyUsingKeyFromDB(ctx context.Context, key stri
 	var userID uuid.UUID
 	var createdAt time.Time
 	var desc string
-	query := `SELECT id, org_id, user_id, created_at, description
-                FROM vizier_deployment_keys
-                WHERE hashed_key=sha256($1) AND PGP_SYM_DECRYPT(encrypted_key::bytea, $2::text)::bytea=$1`
+	limits := &keyLimits{}
+	query := `SELECT k.id, k.org_id, k.user_id, k.created_at, k.description, ` + keyLimitsColumns + `
+                FROM vizier_deployment_keys k
+                WHERE k.hashed_key=sha256($1) AND PGP_SYM_DECRYPT(k.encrypted_key::bytea, $2::text)::bytea=$1`
 	err := s.db.QueryRowxContext(ctx, query, key, s.dbKey).
-		Scan(&id, &orgID, &userID, &createdAt, &desc)
+		Scan(append([]interface{}{&id, &orgID, &userID, &createdAt, &desc}, limits.scanDest()...)...)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, vzerrors.ErrDeploymentKeyNotFound
+			return nil, nil, vzerrors.ErrDeploymentKeyNotFound
 		}
-		return nil, fmt.Errorf("failed to query database for API key")
+		return nil, nil, fmt.Errorf("failed to query database for API key")
 	}
 
 	createdAtProto, _ := types.TimestampProto(createdAt)
-	return &vzmgrpb.DeploymentKey{
+	resp := &vzmgrpb.DeploymentKey{
 		ID:        utils.ProtoFromUUID(id),
 		OrgID:     utils.ProtoFromUUID(orgID),
 		UserID:    utils.ProtoFromUUID(userID),
 		Key:       key,
 		CreatedAt: createdAtProto,
 		Desc:      desc,
-	}, nil
+	}
+	limits.toKeyProto(resp)
+	return resp, limits, nil
 }
@@ -23,6 +23,7 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -98,6 +99,7 @@ func createTestAPIUserContext() context.Context {
 
 func mustLoadTestData(db *sqlx.DB) {
 	db.MustExec(`DELETE FROM vizier_deployment_keys`)
+	db.MustExec(`DELETE FROM vizier_cluster`)
 
 	insertVizierDeploymentKeys := `INSERT INTO vizier_deployment_keys(id, org_id, user_id, hashed_key, encrypted_key, description)
                                      VALUES ($1, $2, $3, sha256($4), PGP_SYM_ENCRYPT($4::text, $5::text), $6)`
@@ -454,7 +456,7 @@ func TestService_FetchOrgUserIDUsingDeploymentKey(t *testing.T) {
 			ctx := test.ctx
 			svc := New(db, testDBKey)
 
-			orgID, userID, keyID, err := svc.FetchOrgUserIDUsingDeploymentKey(ctx, "px-dep-key1")
+			orgID, userID, keyID, err := svc.FetchOrgUserIDUsingDeploymentKey(ctx, "px-dep-key1", "uid1")
 			require.NoError(t, err)
 			assert.Equal(t, testAuthOrgID, orgID)
 			assert.Equal(t, testAuthUserID, userID)
@@ -485,7 +487,7 @@ func TestService_FetchOrgUserIDUsingDeploymentKey_OldKeys(t *testing.T) {
 			ctx := test.ctx
 			svc := New(db, testDBKey)
 
-			orgID, userID, keyID, err := svc.FetchOrgUserIDUsingDeploymentKey(ctx, "key1")
+			orgID, userID, keyID, err := svc.FetchOrgUserIDUsingDeploymentKey(ctx, "key1", "uid1")
 			require.NoError(t, err)
 			assert.Equal(t, testAuthOrgID, orgID)
 			assert.Equal(t, testAuthUserID, userID)
@@ -515,7 +517,7 @@ func TestService_FetchOrgUserIDUsingDeploymentKey_BadKey(t *testing.T) {
 			ctx := test.ctx
 			svc := New(db, testDBKey)
 
-			orgID, userID, keyID, err := svc.FetchOrgUserIDUsingDeploymentKey(ctx, "some rando key that does not exist")
+			orgID, userID, keyID, err := svc.FetchOrgUserIDUsingDeploymentKey(ctx, "some rando key that does not exist", "uid1")
 			assert.NotNil(t, err)
 			assert.Equal(t, vzerrors.ErrDeploymentKeyNotFound, err)
 			assert.Equal(t, uuid.Nil, orgID)
@@ -553,3 +555,349 @@ func TestService_LookupDeploymentKey(t *testing.T) {
 		})
 	}
 }
+
+func TestDeploymentKeyService_CreateWithLimits(t *testing.T) {
+	mustLoadTestData(db)
+
+	ctx := createTestContext()
+	svc := New(db, testDBKey)
+
+	expiresAt, _ := types.TimestampProto(time.Now().Add(time.Hour))
+	resp, err := svc.Create(ctx, &vzmgrpb.CreateDeploymentKeyRequest{
+		OrgID:               utils.ProtoFromUUID(testAuthOrgID),
+		UserID:              utils.ProtoFromUUID(testAuthUserID),
+		Desc:                "limited key",
+		ExpiresAt:           expiresAt,
+		MaxClusters:         2,
+		AllowedClusterNames: []string{"prod-*"},
+	})
+	require.NoError(t, err)
+
+	getResp, err := svc.Get(ctx, &vzmgrpb.GetDeploymentKeyRequest{
+		ID:    resp.ID,
+		OrgID: utils.ProtoFromUUID(testAuthOrgID),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), getResp.Key.MaxClusters)
+	assert.Equal(t, []string{"prod-*"}, getResp.Key.AllowedClusterNames)
+	assert.Equal(t, expiresAt.Seconds, getResp.Key.ExpiresAt.Seconds)
+	assert.Nil(t, getResp.Key.RevokedAt)
+	assert.Equal(t, int64(0), getResp.Key.NumClusters)
+}
+
+func TestDeploymentKeyService_CreateWithInvalidLimits(t *testing.T) {
+	mustLoadTestData(db)
+
+	ctx := createTestContext()
+	svc := New(db, testDBKey)
+
+	expiredAt, _ := types.TimestampProto(time.Now().Add(-1 * time.Hour))
+	tests := []struct {
+		name string
+		req  *vzmgrpb.CreateDeploymentKeyRequest
+	}{
+		{
+			name: "expiry in the past",
+			req:  &vzmgrpb.CreateDeploymentKeyRequest{ExpiresAt: expiredAt},
+		},
+		{
+			name: "negative max clusters",
+			req:  &vzmgrpb.CreateDeploymentKeyRequest{MaxClusters: -1},
+		},
+		{
+			name: "bad pattern",
+			req:  &vzmgrpb.CreateDeploymentKeyRequest{AllowedClusterNames: []string{"prod-["}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.req.OrgID = utils.ProtoFromUUID(testAuthOrgID)
+			test.req.UserID = utils.ProtoFromUUID(testAuthUserID)
+			resp, err := svc.Create(ctx, test.req)
+			assert.Nil(t, resp)
+			assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		})
+	}
+}
+
+func TestService_FetchOrgUserIDUsingDeploymentKey_Limits(t *testing.T) {
+	tests := []struct {
+		name          string
+		update        string
+		k8sClusterUID string
+		expectedErr   error
+	}{
+		{
+			name:          "expired",
+			update:        `UPDATE vizier_deployment_keys SET expires_at=NOW() - INTERVAL '1 hour' WHERE id=$1`,
+			k8sClusterUID: "uid1",
+			expectedErr:   vzerrors.ErrDeploymentKeyExpired,
+		},
+		{
+			name:          "revoked",
+			update:        `UPDATE vizier_deployment_keys SET revoked_at=NOW() WHERE id=$1`,
+			k8sClusterUID: "uid1",
+			expectedErr:   vzerrors.ErrDeploymentKeyRevoked,
+		},
+		{
+			name:          "max clusters",
+			update:        `UPDATE vizier_deployment_keys SET max_clusters=1 WHERE id=$1`,
+			k8sClusterUID: "uid2",
+			expectedErr:   vzerrors.ErrDeploymentKeyExhausted,
+		},
+		{
+			// Clusters that were already registered with the key can register again.
+			name:          "max clusters registered cluster",
+			update:        `UPDATE vizier_deployment_keys SET max_clusters=1 WHERE id=$1`,
+			k8sClusterUID: "uid1",
+		},
+		{
+			name:          "max clusters empty UID",
+			update:        `UPDATE vizier_deployment_keys SET max_clusters=1 WHERE id=$1`,
+			k8sClusterUID: "",
+			expectedErr:   vzerrors.ErrDeploymentKeyExhausted,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mustLoadTestData(db)
+
+			ctx := createTestContext()
+			svc := New(db, testDBKey)
+
+			mustRegisterCluster(t, svc, testKey1ID, uuid.Must(uuid.NewV4()), "uid1", "cluster1")
+			db.MustExec(test.update, testKey1ID)
+
+			_, _, _, err := svc.FetchOrgUserIDUsingDeploymentKey(ctx, "px-dep-key1", test.k8sClusterUID)
+			assert.Equal(t, test.expectedErr, err)
+		})
+	}
+}
+
+func TestDeploymentKeyService_Revoke(t *testing.T) {
+	mustLoadTestData(db)
+
+	ctx := createTestContext()
+	svc := New(db, testDBKey)
+
+	_, err := svc.Revoke(ctx, &vzmgrpb.RevokeDeploymentKeyRequest{
+		ID:    utils.ProtoFromUUID(testKey1ID),
+		OrgID: utils.ProtoFromUUID(testAuthOrgID),
+	})
+	require.NoError(t, err)
+
+	resp, err := svc.Get(ctx, &vzmgrpb.GetDeploymentKeyRequest{
+		ID:    utils.ProtoFromUUID(testKey1ID),
+		OrgID: utils.ProtoFromUUID(testAuthOrgID),
+	})
+	require.NoError(t, err)
+	assert.NotNil(t, resp.Key.RevokedAt)
+
+	// Keys owned by other orgs can't be revoked.
+	_, err = svc.Revoke(ctx, &vzmgrpb.RevokeDeploymentKeyRequest{
+		ID:    utils.ProtoFromUUID(testKey3ID),
+		OrgID: utils.ProtoFromUUID(testAuthOrgID),
+	})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestDeploymentKeyService_Usages(t *testing.T) {
+	mustLoadTestData(db)
+
+	ctx := createTestContext()
+	svc := New(db, testDBKey)
+
+	clusterID := uuid.Must(uuid.NewV4())
+	mustRegisterCluster(t, svc, testKey1ID, clusterID, "uid1", "cluster1")
+	// Registering the same cluster again shouldn't count as another usage.
+	mustRegisterCluster(t, svc, testKey1ID, clusterID, "uid1", "cluster1-renamed")
+
+	resp, err := svc.Get(ctx, &vzmgrpb.GetDeploymentKeyRequest{
+		ID:    utils.ProtoFromUUID(testKey1ID),
+		OrgID: utils.ProtoFromUUID(testAuthOrgID),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), resp.Key.NumClusters)
+	require.Equal(t, 1, len(resp.Key.Usages))
+	assert.Equal(t, clusterID, utils.UUIDFromProtoOrNil(resp.Key.Usages[0].ClusterID))
+	assert.Equal(t, "uid1", resp.Key.Usages[0].K8sClusterUID)
+	assert.Equal(t, "cluster1-renamed", resp.Key.Usages[0].ClusterName)
+}
+
+func TestDeploymentKeyService_ValidateClusterName(t *testing.T) {
+	mustLoadTestData(db)
+	db.MustExec(`UPDATE vizier_deployment_keys SET allowed_cluster_names='["prod-*", "staging"]' WHERE id=$1`, testKey1ID)
+
+	ctx := createTestContext()
+	svc := New(db, testDBKey)
+
+	assert.NoError(t, svc.ValidateClusterNameForDeploymentKey(ctx, testKey1ID, "prod-us-west"))
+	assert.NoError(t, svc.ValidateClusterNameForDeploymentKey(ctx, testKey1ID, "staging"))
+	assert.Equal(t, vzerrors.ErrClusterNameNotAllowed, svc.ValidateClusterNameForDeploymentKey(ctx, testKey1ID, "dev"))
+	assert.Equal(t, vzerrors.ErrClusterNameNotAllowed, svc.ValidateClusterNameForDeploymentKey(ctx, testKey1ID, ""))
+	// Keys without patterns allow any name.
+	assert.NoError(t, svc.ValidateClusterNameForDeploymentKey(ctx, testKey2ID, "dev"))
+}
+
+func mustRegisterCluster(t *testing.T, svc *Service, keyID uuid.UUID, clusterID uuid.UUID, k8sClusterUID string, clusterName string) {
+	id, name, err := svc.RegisterClusterWithDeploymentKey(createTestContext(), keyID, k8sClusterUID, func(*sqlx.Tx) (uuid.UUID, string, error) {
+		return clusterID, clusterName, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, clusterID, id)
+	assert.Equal(t, clusterName, name)
+}
+
+func TestDeploymentKeyService_RegisterCluster_MaxClusters(t *testing.T) {
+	mustLoadTestData(db)
+	db.MustExec(`UPDATE vizier_deployment_keys SET max_clusters=1 WHERE id=$1`, testKey1ID)
+
+	ctx := createTestContext()
+	svc := New(db, testDBKey)
+
+	clusterID := uuid.Must(uuid.NewV4())
+	mustRegisterCluster(t, svc, testKey1ID, clusterID, "uid1", "cluster1")
+	// A cluster that was already registered with the key can register again, eg. when Vizier is redeployed.
+	mustRegisterCluster(t, svc, testKey1ID, clusterID, "uid1", "cluster1")
+
+	// New clusters are rejected without being provisioned.
+	_, _, err := svc.RegisterClusterWithDeploymentKey(ctx, testKey1ID, "uid2", func(*sqlx.Tx) (uuid.UUID, string, error) {
+		t.Fatal("Cluster shouldn't be provisioned")
+		return uuid.Nil, "", nil
+	})
+	assert.Equal(t, vzerrors.ErrDeploymentKeyExhausted, err)
+
+	resp, err := svc.Get(ctx, &vzmgrpb.GetDeploymentKeyRequest{
+		ID:    utils.ProtoFromUUID(testKey1ID),
+		OrgID: utils.ProtoFromUUID(testAuthOrgID),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), resp.Key.NumClusters)
+}
+
+func TestDeploymentKeyService_RegisterCluster_Concurrent(t *testing.T) {
+	mustLoadTestData(db)
+	db.MustExec(`UPDATE vizier_deployment_keys SET max_clusters=1 WHERE id=$1`, testKey1ID)
+
+	ctx := createTestContext()
+	svc := New(db, testDBKey)
+
+	const numClusters = 5
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	numProvisioned := 0
+	errs := make([]error, numClusters)
+	for i := 0; i < numClusters; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, _, errs[i] = svc.RegisterClusterWithDeploymentKey(ctx, testKey1ID, fmt.Sprintf("uid%d", i), func(*sqlx.Tx) (uuid.UUID, string, error) {
+				mu.Lock()
+				defer mu.Unlock()
+				numProvisioned++
+				return uuid.Must(uuid.NewV4()), fmt.Sprintf("cluster%d", i), nil
+			})
+		}(i)
+	}
+	wg.Wait()
+
+	// Only one of the clusters can be registered, even though they all registered at the same time.
+	assert.Equal(t, 1, numProvisioned)
+	numExhausted := 0
+	for _, err := range errs {
+		if err == vzerrors.ErrDeploymentKeyExhausted {
+			numExhausted++
+		}
+	}
+	assert.Equal(t, numClusters-1, numExhausted)
+}
+
+func TestDeploymentKeyService_RegisterCluster_RaceForLastCluster(t *testing.T) {
+	mustLoadTestData(db)
+	db.MustExec(`UPDATE vizier_deployment_keys SET max_clusters=2 WHERE id=$1`, testKey1ID)
+
+	ctx := createTestContext()
+	svc := New(db, testDBKey)
+	mustRegisterCluster(t, svc, testKey1ID, uuid.Must(uuid.NewV4()), "uid0", "cluster0")
+
+	// Both registrations pass the key's limits before either of them registers its cluster.
+	for _, uid := range []string{"uid1", "uid2"} {
+		_, _, _, err := svc.FetchOrgUserIDUsingDeploymentKey(ctx, "px-dep-key1", uid)
+		require.NoError(t, err)
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, 2)
+	for i, uid := range []string{"uid1", "uid2"} {
+		wg.Add(1)
+		go func(i int, uid string) {
+			defer wg.Done()
+			_, _, errs[i] = svc.RegisterClusterWithDeploymentKey(ctx, testKey1ID, uid, func(tx *sqlx.Tx) (uuid.UUID, string, error) {
+				var clusterID uuid.UUID
+				query := `INSERT INTO vizier_cluster (org_id, project_name, cluster_uid) VALUES($1, 'default', $2) RETURNING id`
+				err := tx.QueryRowxContext(ctx, query, testAuthOrgID, uid).Scan(&clusterID)
+				return clusterID, uid, err
+			})
+		}(i, uid)
+	}
+	wg.Wait()
+
+	// Only one of the registrations takes the last cluster, and the cluster provisioned by the other one is rolled back.
+	assert.ElementsMatch(t, []error{nil, vzerrors.ErrDeploymentKeyExhausted}, errs)
+	var numProvisioned int
+	err := db.Get(&numProvisioned, `SELECT COUNT(*) FROM vizier_cluster WHERE cluster_uid IN ('uid1', 'uid2')`)
+	require.NoError(t, err)
+	assert.Equal(t, 1, numProvisioned)
+
+	resp, err := svc.Get(ctx, &vzmgrpb.GetDeploymentKeyRequest{
+		ID:    utils.ProtoFromUUID(testKey1ID),
+		OrgID: utils.ProtoFromUUID(testAuthOrgID),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), resp.Key.NumClusters)
+}
+
+func TestDeploymentKeyService_RegisterCluster_EmptyUID(t *testing.T) {
+	mustLoadTestData(db)
+	db.MustExec(`UPDATE vizier_deployment_keys SET max_clusters=1 WHERE id=$1`, testKey1ID)
+
+	ctx := createTestContext()
+	svc := New(db, testDBKey)
+	mustRegisterCluster(t, svc, testKey1ID, uuid.Must(uuid.NewV4()), "", "cluster1")
+
+	// Clusters without a UID can't be told apart, so they always count as new clusters.
+	_, _, err := svc.RegisterClusterWithDeploymentKey(ctx, testKey1ID, "", func(*sqlx.Tx) (uuid.UUID, string, error) {
+		t.Fatal("Cluster shouldn't be provisioned")
+		return uuid.Nil, "", nil
+	})
+	assert.Equal(t, vzerrors.ErrDeploymentKeyExhausted, err)
+}
+
+func TestDeploymentKeyService_RegisterCluster_ProvisionFailed(t *testing.T) {
+	mustLoadTestData(db)
+
+	ctx := createTestContext()
+	svc := New(db, testDBKey)
+
+	_, _, err := svc.RegisterClusterWithDeploymentKey(ctx, testKey1ID, "uid1", func(tx *sqlx.Tx) (uuid.UUID, string, error) {
+		_, err := tx.ExecContext(ctx, `INSERT INTO vizier_cluster (org_id, project_name, cluster_uid) VALUES($1, 'default', 'uid1')`, testAuthOrgID)
+		require.NoError(t, err)
+		return uuid.Nil, "", vzerrors.ErrProvisionFailedVizierIsActive
+	})
+	assert.Equal(t, vzerrors.ErrProvisionFailedVizierIsActive, err)
+
+	// The changes made while provisioning are rolled back.
+	var numProvisioned int
+	err = db.Get(&numProvisioned, `SELECT COUNT(*) FROM vizier_cluster WHERE cluster_uid='uid1'`)
+	require.NoError(t, err)
+	assert.Equal(t, 0, numProvisioned)
+
+	resp, err := svc.Get(ctx, &vzmgrpb.GetDeploymentKeyRequest{
+		ID:    utils.ProtoFromUUID(testKey1ID),
+		OrgID: utils.ProtoFromUUID(testAuthOrgID),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), resp.Key.NumClusters)
+}
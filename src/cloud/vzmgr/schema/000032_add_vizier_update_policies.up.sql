@@ -0,0 +1,21 @@
+-- This table contains the org-wide policies for automatically updating viziers.
+CREATE TABLE vizier_update_policies (
+  -- org_id is the org that this policy applies to.
+  org_id UUID NOT NULL,
+  -- If set, viziers are not automatically updated past this version.
+  pinned_version varchar(1000) NOT NULL DEFAULT '',
+  -- The release channel that viziers are updated to, either LATEST or STABLE.
+  channel varchar(50) NOT NULL DEFAULT 'LATEST',
+  -- The number of releases in the channel to stay behind the newest release.
+  versions_behind integer NOT NULL DEFAULT 0,
+  -- The maintenance windows that updates are allowed in. Updates are allowed at any time if empty.
+  maintenance_windows json NOT NULL DEFAULT '[]',
+  -- The IANA timezone that the maintenance windows are specified in.
+  timezone varchar(100) NOT NULL DEFAULT 'UTC',
+  -- The ordered groups of viziers to update. Each wave is only updated once the viziers in the
+  -- earlier waves are healthy on the new version.
+  waves json NOT NULL DEFAULT '[]',
+  updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+
+  PRIMARY KEY(org_id)
+);
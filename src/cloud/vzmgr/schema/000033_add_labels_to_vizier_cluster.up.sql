@@ -0,0 +1,2 @@
+ALTER TABLE vizier_cluster
+  ADD COLUMN labels json NOT NULL DEFAULT '{}';
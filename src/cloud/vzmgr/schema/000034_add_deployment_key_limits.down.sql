@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS vizier_deployment_key_usages;
+
+ALTER TABLE vizier_deployment_keys
+  DROP COLUMN IF EXISTS expires_at,
+  DROP COLUMN IF EXISTS max_clusters,
+  DROP COLUMN IF EXISTS allowed_cluster_names,
+  DROP COLUMN IF EXISTS revoked_at;
@@ -0,0 +1,27 @@
+-- The time after which the key can no longer be used. NULL if the key never expires.
+ALTER TABLE vizier_deployment_keys
+  ADD COLUMN expires_at TIMESTAMP;
+
+-- The maximum number of clusters that can be registered with the key. 0 means unlimited.
+ALTER TABLE vizier_deployment_keys
+  ADD COLUMN max_clusters integer NOT NULL DEFAULT 0;
+
+-- Glob patterns that the names of clusters registered with the key must match. Any name is allowed if empty.
+ALTER TABLE vizier_deployment_keys
+  ADD COLUMN allowed_cluster_names json NOT NULL DEFAULT '[]';
+
+-- The time the key was revoked. NULL if the key hasn't been revoked.
+ALTER TABLE vizier_deployment_keys
+  ADD COLUMN revoked_at TIMESTAMP;
+
+-- This table records the clusters that were registered with each deployment key.
+CREATE TABLE vizier_deployment_key_usages (
+  deployment_key_id UUID NOT NULL REFERENCES vizier_deployment_keys(id) ON DELETE CASCADE,
+  -- The ID of the vizier cluster that was registered.
+  vizier_cluster_id UUID NOT NULL,
+  k8s_cluster_uid varchar(1000) NOT NULL DEFAULT '',
+  cluster_name varchar(1000) NOT NULL DEFAULT '',
+  registered_at TIMESTAMP NOT NULL DEFAULT NOW(),
+
+  PRIMARY KEY(deployment_key_id, vizier_cluster_id)
+);
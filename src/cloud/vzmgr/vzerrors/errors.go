@@ -28,6 +28,14 @@ import (
 var (
 	// ErrDeploymentKeyNotFound is used when specified key cannot be located.
 	ErrDeploymentKeyNotFound = errors.New("invalid deployment key")
+	// ErrDeploymentKeyExpired is used when the specified key is past its expiry time.
+	ErrDeploymentKeyExpired = errors.New("deployment key has expired")
+	// ErrDeploymentKeyRevoked is used when the specified key has been revoked.
+	ErrDeploymentKeyRevoked = errors.New("deployment key has been revoked")
+	// ErrDeploymentKeyExhausted is used when the specified key has already registered its maximum number of clusters.
+	ErrDeploymentKeyExhausted = errors.New("deployment key has reached its maximum number of clusters")
+	// ErrClusterNameNotAllowed is used when the cluster name doesn't match any of the names allowed by the key.
+	ErrClusterNameNotAllowed = errors.New("cluster name is not allowed by the deployment key")
 	// ErrProvisionFailedVizierIsActive errors when the specified vizier is active and not disconnected.
 	ErrProvisionFailedVizierIsActive = errors.New("provisioning failed because vizier with specified UID is already active")
 	// ErrInternalDB is used for internal errors related to DB.
@@ -41,6 +49,12 @@ func ToGRPCError(err error) error {
 		return status.Error(codes.ResourceExhausted, err.Error())
 	case ErrDeploymentKeyNotFound:
 		return status.Error(codes.NotFound, err.Error())
+	case ErrDeploymentKeyExpired, ErrDeploymentKeyRevoked:
+		return status.Error(codes.Unauthenticated, err.Error())
+	case ErrDeploymentKeyExhausted:
+		return status.Error(codes.ResourceExhausted, err.Error())
+	case ErrClusterNameNotAllowed:
+		return status.Error(codes.PermissionDenied, err.Error())
 	case ErrInternalDB:
 		return status.Error(codes.Internal, err.Error())
 	}
@@ -85,16 +85,20 @@ func (r *readinessCheck) Check() error {
 	return r.err
 }
 
-func mustSetupNATSAndJetStream() (*nats.Conn, msgbus.Streamer) {
+func mustSetupNATSAndJetStream() (*nats.Conn, msgbus.Streamer, msgbus.Streamer) {
 	nc := msgbus.MustConnectNATS()
 	js := msgbus.MustConnectJetStream(nc)
 	strmr, err := msgbus.NewJetStreamStreamer(nc, js, msgbus.V2CDurableStream)
 	if err != nil {
 		log.WithError(err).Fatal("Could not start JetStream streamer")
 	}
+	mdStrmr, err := msgbus.NewJetStreamStreamer(nc, js, msgbus.MetadataIndexStream)
+	if err != nil {
+		log.WithError(err).Fatal("Could not start JetStream streamer for metadata index")
+	}
 
 	nc.SetErrorHandler(natsErrorCounter.HandleNatsError)
-	return nc, strmr
+	return nc, strmr, mdStrmr
 }
 
 func main() {
@@ -136,7 +140,7 @@ func main() {
 	}
 
 	// Connect to NATS.
-	nc, strmr := mustSetupNATSAndJetStream()
+	nc, strmr, mdStrmr := mustSetupNATSAndJetStream()
 	defer nc.Close()
 
 	at, err := NewArtifactTrackerServiceClient()
@@ -152,14 +156,16 @@ func main() {
 	defer updater.Stop()
 
 	c := controllers.New(db, dbKey, nc, updater)
+	defer c.Stop()
 	dks := deploymentkey.New(db, dbKey)
 	ds := deployment.New(dks, c)
 
-	sm := controllers.NewStatusMonitor(db)
+	sm := controllers.NewStatusMonitor(db, nc)
 	defer sm.Stop()
 	vzmgrpb.RegisterVZMgrServiceServer(s.GRPCServer(), c)
 	vzmgrpb.RegisterVZDeploymentKeyServiceServer(s.GRPCServer(), dks)
 	vzmgrpb.RegisterVZDeploymentServiceServer(s.GRPCServer(), ds)
+	vzmgrpb.RegisterVZMetadataServiceServer(s.GRPCServer(), controllers.NewMetadataWatcher(db, mdStrmr))
 
 	var mdr *controllers.MetadataReader
 	go func() {
@@ -118,6 +118,8 @@ func defaultForType(pxType vispb.PXType) string {
 		return ""
 	case vispb.PX_SERVICE, vispb.PX_POD, vispb.PX_CONTAINER, vispb.PX_NAMESPACE, vispb.PX_NODE:
 		return "pl"
+	case vispb.PX_TIME:
+		return "-5m"
 	case vispb.PX_LIST:
 		return "[]"
 	case vispb.PX_STRING_LIST:
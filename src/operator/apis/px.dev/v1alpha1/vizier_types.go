@@ -48,6 +48,9 @@ type VizierSpec struct {
 	// ClusterName is a name for the Vizier instance, usually specifying which cluster the Vizier is
 	// deployed to. If not specified, a random name will be generated.
 	ClusterName string `json:"clusterName,omitempty"`
+	// ClusterLabels are key/value pairs attached to the cluster in Pixie Cloud. They can be used
+	// to select groups of clusters when running scripts or querying cluster info.
+	ClusterLabels map[string]string `json:"clusterLabels,omitempty"`
 	// CloudAddr is the address of the cloud instance that the Vizier should be pointing to.
 	CloudAddr string `json:"cloudAddr,omitempty"`
 	// DevCloudNamespace should be specified only for dev versions of Pixie cloud which have no ingress to help
@@ -30,14 +30,12 @@ import (
 	"strings"
 	"time"
 
-	"github.com/blang/semver"
 	"github.com/cenkalti/backoff/v4"
 	"github.com/gogo/protobuf/types"
 	log "github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
 	appsv1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
-	storagev1 "k8s.io/api/storage/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
@@ -50,6 +48,7 @@ import (
 	"px.dev/pixie/src/api/proto/cloudpb"
 	"px.dev/pixie/src/api/proto/vizierconfigpb"
 	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
+	"px.dev/pixie/src/operator/storage"
 	version "px.dev/pixie/src/shared/goversion"
 	"px.dev/pixie/src/shared/services"
 	"px.dev/pixie/src/shared/status"
@@ -61,6 +60,10 @@ const (
 	// This is the key for the annotation that the operator applies on all of its deployed resources for a CRD.
 	operatorAnnotation  = "vizier-name"
 	clusterSecretJWTKey = "jwt-signing-key"
+	// clusterConfigName is the ConfigMap with the Vizier's cluster settings, and clusterLabelsConfigKey is its key
+	// for the cluster labels.
+	clusterConfigName      = "pl-cluster-config"
+	clusterLabelsConfigKey = "PL_CLUSTER_LABELS"
 	// updatingFailedTimeout is the amount of time we wait since an Updated started
 	// before we consider the Update Failed.
 	updatingFailedTimeout = 10 * time.Minute
@@ -68,10 +71,6 @@ const (
 	updatingVizierCheckPeriod = 1 * time.Minute
 )
 
-// defaultClassAnnotationKey is the key in the annotation map which indicates
-// a storage class is default.
-var defaultClassAnnotationKeys = []string{"storageclass.kubernetes.io/is-default-class", "storageclass.beta.kubernetes.io/is-default-class"}
-
 // The k8s API kinds that should be excluded from a Vizier's nodeSelector setting.
 // Resources such as DaemonSets should run on all nodes of the cluster, so applying
 // the nodeSelector uniformly across all pods leads to unexpected behavior.
@@ -135,122 +134,6 @@ func getLatestVizierVersion(ctx context.Context, client cloudpb.ArtifactTrackerC
 	return resp.Artifact[0].VersionStr, nil
 }
 
-// Kubernetes used to have in-tree plugins for a variety of vendor CSI drivers.
-// These provisioners had "kubernetes.io/" prefixes. Often times these provisioner names
-// are still used in the storageclass but the calls are redirected to CSIDrivers under new names.
-// This map maintains that list of redirects.
-// See https://kubernetes.io/docs/concepts/storage/volumes and
-// https://kubernetes.io/docs/concepts/storage/storage-classes/#provisioner.
-var migratedCSIDrivers = map[string]string{
-	"kubernetes.io/aws-ebs":         "ebs.csi.aws.com",
-	"kubernetes.io/azure-disk":      "disk.csi.azure.com",
-	"kubernetes.io/azure-file":      "file.csi.azure.com",
-	"kubernetes.io/cinder":          "cinder.csi.openstack.org",
-	"kubernetes.io/gce-pd":          "pd.csi.storage.gke.io",
-	"kubernetes.io/portworx-volume": "pxd.portworx.com",
-	"kubernetes.io/vsphere-volume":  "csi.vsphere.vmware.com",
-}
-
-func hasCSIDriver(clientset *kubernetes.Clientset, name string) (bool, error) {
-	_, err := clientset.StorageV1().CSIDrivers().Get(context.Background(), name, metav1.GetOptions{})
-	if err != nil && !k8serrors.IsNotFound(err) {
-		return false, err
-	} else if k8serrors.IsNotFound(err) {
-		return false, nil
-	}
-	return true, nil
-}
-
-func defaultStorageClassHasCSIDriver(clientset *kubernetes.Clientset) (bool, error) {
-	storageClasses, err := clientset.StorageV1().StorageClasses().List(context.Background(), metav1.ListOptions{})
-	if err != nil {
-		return false, err
-	}
-
-	var defaultStorageClass *storagev1.StorageClass
-	// Check annotations map on each storage class to see if default is set to "true".
-	for _, storageClass := range storageClasses.Items {
-		annotationsMap := storageClass.GetAnnotations()
-		for _, key := range defaultClassAnnotationKeys {
-			if annotationsMap[key] == "true" {
-				defaultStorageClass = &storageClass
-				break
-			}
-		}
-	}
-	if defaultStorageClass == nil {
-		return false, errors.New("no default storage class")
-	}
-	csi := defaultStorageClass.Provisioner
-	if migrated, ok := migratedCSIDrivers[csi]; ok {
-		csi = migrated
-	}
-
-	// For all non-migrated kubernetes provisioners, kubernetes itself will have an internal provisioner,
-	// so no need to check for a CSIDriver.
-	if strings.HasPrefix(csi, "kubernetes.io/") {
-		return true, nil
-	}
-	// If the provisioner contains a "/" then we assume it is a custom provisioner that doesn't use the CSI pattern,
-	// so we skip checking for a CSIDriver and hope the provisioner works.
-	if strings.Contains(csi, "/") {
-		return true, nil
-	}
-
-	return hasCSIDriver(clientset, csi)
-}
-
-// missingNecessaryCSIDriver checks if the user is running an EKS cluster, and if so, whether they are
-// missing the CSIDriver. Without the CSI driver, persistent volumes may not be able to be deployed.
-func missingNecessaryCSIDriver(clientset *kubernetes.Clientset, k8sVersion string) bool {
-	// This check only needs to be done for eks clusters with K8s version > 1.22.0.
-	if !strings.Contains(k8sVersion, "-eks-") {
-		return false
-	}
-
-	parsedVersion, err := semver.ParseTolerant(k8sVersion)
-	if err != nil {
-		log.WithError(err).Error("Failed to parse K8s cluster version")
-		return false
-	}
-	driverVersionRange, _ := semver.ParseRange("<=1.22.0")
-	if driverVersionRange(parsedVersion) {
-		return false
-	}
-
-	hasDriver, err := defaultStorageClassHasCSIDriver(clientset)
-	if err != nil {
-		log.WithError(err).Warn("failed to determine if the default storage class has a valid CSI driver")
-		return false
-	}
-	return !hasDriver
-}
-
-// validateNumDefaultStorageClasses returns a boolean whether there is exactly
-// 1 default storage class or not.
-func validateNumDefaultStorageClasses(clientset *kubernetes.Clientset) (bool, error) {
-	storageClasses, err := clientset.StorageV1().StorageClasses().List(context.Background(), metav1.ListOptions{})
-	if err != nil {
-		return false, err
-	}
-
-	defaultClassCount := 0
-
-	// Check annotations map on each storage class to see if default is set to "true".
-	for _, storageClass := range storageClasses.Items {
-		annotationsMap := storageClass.GetAnnotations()
-		for _, key := range defaultClassAnnotationKeys {
-			if annotationsMap[key] == "true" {
-				// It is possible for some storageClasses to have both the beta/non-beta annotation.
-				// We break here so that we don't double count this storageClass.
-				defaultClassCount++
-				break
-			}
-		}
-	}
-	return defaultClassCount == 1, nil
-}
-
 // Reconcile updates the Vizier running in the cluster to match the expected state.
 func (r *VizierReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	log.WithField("req", req).Info("Reconciling Vizier...")
@@ -469,11 +352,11 @@ func (r *VizierReconciler) deployVizier(ctx context.Context, req ctrl.Request, v
 		// Check if the cluster offers PVC support.
 		// If it does not, we should default to using the etcd operator, which does not
 		// require PVC support.
-		defaultStorageExists, err := validateNumDefaultStorageClasses(r.Clientset)
+		defaultStorageExists, err := storage.ValidateNumDefaultStorageClasses(r.Clientset)
 		if err != nil {
 			log.WithError(err).Error("Error checking default storage classes")
 		}
-		missingCSIDriver := missingNecessaryCSIDriver(r.Clientset, r.K8sVersion)
+		missingCSIDriver := storage.MissingNecessaryCSIDriver(r.Clientset, r.K8sVersion)
 
 		if !defaultStorageExists || missingCSIDriver {
 			log.Warn("No default storage class detected for cluster. Deploying etcd operator instead of statefulset for metadata backend.")
@@ -715,10 +598,33 @@ func (r *VizierReconciler) deployVizierConfigs(ctx context.Context, namespace st
 		if err != nil {
 			return err
 		}
+		err = addClusterLabelsToConfig(r, vz.Spec.ClusterLabels)
+		if err != nil {
+			return err
+		}
 	}
 	return k8s.ApplyResources(r.Clientset, r.RestConfig, resources, namespace, nil, false)
 }
 
+// addClusterLabelsToConfig sets the cluster labels from the Vizier CRD in the pl-cluster-config ConfigMap, as a
+// comma-separated list of key=value pairs. The cloud connector sends them to Pixie Cloud when it registers the
+// Vizier, where they're merged into the cluster's labels.
+func addClusterLabelsToConfig(resource *k8s.Resource, clusterLabels map[string]string) error {
+	if resource.Object.GetKind() != "ConfigMap" || resource.Object.GetName() != clusterConfigName {
+		return nil
+	}
+	keys := make([]string, 0, len(clusterLabels))
+	for k := range clusterLabels {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	pairs := make([]string, len(keys))
+	for i, k := range keys {
+		pairs[i] = fmt.Sprintf("%s=%s", k, clusterLabels[k])
+	}
+	return unstructured.SetNestedField(resource.Object.Object, strings.Join(pairs, ","), "data", clusterLabelsConfigKey)
+}
+
 // deployNATSStatefulset deploys nats to the given namespace.
 func (r *VizierReconciler) deployNATSStatefulset(ctx context.Context, namespace string, vz *v1alpha1.Vizier, yamlMap map[string]string) error {
 	log.Info("Deploying NATS")
@@ -823,6 +729,7 @@ func generateVizierYAMLsConfig(ctx context.Context, ns string, k8sVersion string
 			DisableAutoUpdate:     vz.Spec.DisableAutoUpdate,
 			UseEtcdOperator:       vz.Spec.UseEtcdOperator,
 			ClusterName:           vz.Spec.ClusterName,
+			ClusterLabels:         vz.Spec.ClusterLabels,
 			CloudAddr:             vz.Spec.CloudAddr,
 			DevCloudNamespace:     vz.Spec.DevCloudNamespace,
 			PemMemoryLimit:        vz.Spec.PemMemoryLimit,
@@ -0,0 +1,181 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+// Package storage checks whether a cluster can provision the persistent volumes that Vizier needs.
+package storage
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/blang/semver"
+	log "github.com/sirupsen/logrus"
+	storagev1 "k8s.io/api/storage/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// DefaultClassAnnotationKeys are the keys in the annotation map which indicate
+// a storage class is default.
+var DefaultClassAnnotationKeys = []string{"storageclass.kubernetes.io/is-default-class", "storageclass.beta.kubernetes.io/is-default-class"}
+
+// Kubernetes used to have in-tree plugins for a variety of vendor CSI drivers.
+// These provisioners had "kubernetes.io/" prefixes. Often times these provisioner names
+// are still used in the storageclass but the calls are redirected to CSIDrivers under new names.
+// This map maintains that list of redirects.
+// See https://kubernetes.io/docs/concepts/storage/volumes and
+// https://kubernetes.io/docs/concepts/storage/storage-classes/#provisioner.
+var migratedCSIDrivers = map[string]string{
+	"kubernetes.io/aws-ebs":         "ebs.csi.aws.com",
+	"kubernetes.io/azure-disk":      "disk.csi.azure.com",
+	"kubernetes.io/azure-file":      "file.csi.azure.com",
+	"kubernetes.io/cinder":          "cinder.csi.openstack.org",
+	"kubernetes.io/gce-pd":          "pd.csi.storage.gke.io",
+	"kubernetes.io/portworx-volume": "pxd.portworx.com",
+	"kubernetes.io/vsphere-volume":  "csi.vsphere.vmware.com",
+}
+
+// IsDefaultClass returns whether the storage class is annotated as the default.
+func IsDefaultClass(storageClass *storagev1.StorageClass) bool {
+	annotationsMap := storageClass.GetAnnotations()
+	for _, key := range DefaultClassAnnotationKeys {
+		if annotationsMap[key] == "true" {
+			return true
+		}
+	}
+	return false
+}
+
+// CSIDriverName returns the name of the CSIDriver that the provisioner uses. Returns an empty string if
+// the provisioner doesn't need a CSIDriver.
+func CSIDriverName(provisioner string) string {
+	csi := provisioner
+	if migrated, ok := migratedCSIDrivers[csi]; ok {
+		csi = migrated
+	}
+
+	// For all non-migrated kubernetes provisioners, kubernetes itself will have an internal provisioner,
+	// so no need to check for a CSIDriver.
+	if strings.HasPrefix(csi, "kubernetes.io/") {
+		return ""
+	}
+	// If the provisioner contains a "/" then we assume it is a custom provisioner that doesn't use the CSI pattern,
+	// so we skip checking for a CSIDriver and hope the provisioner works.
+	if strings.Contains(csi, "/") {
+		return ""
+	}
+	return csi
+}
+
+// HasCSIDriver returns whether the CSIDriver is installed on the cluster.
+func HasCSIDriver(clientset kubernetes.Interface, name string) (bool, error) {
+	_, err := clientset.StorageV1().CSIDrivers().Get(context.Background(), name, metav1.GetOptions{})
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return false, err
+	} else if k8serrors.IsNotFound(err) {
+		return false, nil
+	}
+	return true, nil
+}
+
+// DefaultStorageClassHasCSIDriver returns whether the CSIDriver used by the default storage class is installed.
+func DefaultStorageClassHasCSIDriver(clientset kubernetes.Interface) (bool, error) {
+	storageClasses, err := clientset.StorageV1().StorageClasses().List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		return false, err
+	}
+
+	var defaultStorageClass *storagev1.StorageClass
+	// Check annotations map on each storage class to see if default is set to "true".
+	for i := range storageClasses.Items {
+		if IsDefaultClass(&storageClasses.Items[i]) {
+			defaultStorageClass = &storageClasses.Items[i]
+		}
+	}
+	if defaultStorageClass == nil {
+		return false, errors.New("no default storage class")
+	}
+
+	csi := CSIDriverName(defaultStorageClass.Provisioner)
+	if csi == "" {
+		return true, nil
+	}
+	return HasCSIDriver(clientset, csi)
+}
+
+// NeedsCSIDriverCheck returns whether the cluster is an EKS cluster with a K8s version that no longer
+// has in-tree volume plugins, and so needs a CSIDriver to provision volumes.
+func NeedsCSIDriverCheck(k8sVersion string) bool {
+	// This check only needs to be done for eks clusters with K8s version > 1.22.0.
+	if !strings.Contains(k8sVersion, "-eks-") {
+		return false
+	}
+
+	parsedVersion, err := semver.ParseTolerant(k8sVersion)
+	if err != nil {
+		log.WithError(err).Error("Failed to parse K8s cluster version")
+		return false
+	}
+	driverVersionRange, _ := semver.ParseRange("<=1.22.0")
+	return !driverVersionRange(parsedVersion)
+}
+
+// MissingNecessaryCSIDriver checks if the user is running an EKS cluster, and if so, whether they are
+// missing the CSIDriver. Without the CSI driver, persistent volumes may not be able to be deployed.
+func MissingNecessaryCSIDriver(clientset kubernetes.Interface, k8sVersion string) bool {
+	if !NeedsCSIDriverCheck(k8sVersion) {
+		return false
+	}
+
+	hasDriver, err := DefaultStorageClassHasCSIDriver(clientset)
+	if err != nil {
+		log.WithError(err).Warn("failed to determine if the default storage class has a valid CSI driver")
+		return false
+	}
+	return !hasDriver
+}
+
+// NumDefaultStorageClasses returns the number of storage classes that are annotated as the default.
+func NumDefaultStorageClasses(clientset kubernetes.Interface) (int, error) {
+	storageClasses, err := clientset.StorageV1().StorageClasses().List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		return 0, err
+	}
+
+	defaultClassCount := 0
+	for i := range storageClasses.Items {
+		// It is possible for some storageClasses to have both the beta/non-beta annotation.
+		// IsDefaultClass only counts them once.
+		if IsDefaultClass(&storageClasses.Items[i]) {
+			defaultClassCount++
+		}
+	}
+	return defaultClassCount, nil
+}
+
+// ValidateNumDefaultStorageClasses returns a boolean whether there is exactly
+// 1 default storage class or not.
+func ValidateNumDefaultStorageClasses(clientset kubernetes.Interface) (bool, error) {
+	n, err := NumDefaultStorageClasses(clientset)
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
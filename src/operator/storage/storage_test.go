@@ -0,0 +1,108 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package storage_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"px.dev/pixie/src/operator/storage"
+)
+
+func storageClass(name, provisioner string, annotations map[string]string) *storagev1.StorageClass {
+	return &storagev1.StorageClass{
+		ObjectMeta:  metav1.ObjectMeta{Name: name, Annotations: annotations},
+		Provisioner: provisioner,
+	}
+}
+
+func TestCSIDriverName(t *testing.T) {
+	assert.Equal(t, "ebs.csi.aws.com", storage.CSIDriverName("kubernetes.io/aws-ebs"))
+	assert.Equal(t, "ebs.csi.aws.com", storage.CSIDriverName("ebs.csi.aws.com"))
+	assert.Equal(t, "", storage.CSIDriverName("kubernetes.io/no-provisioner"))
+	assert.Equal(t, "", storage.CSIDriverName("rancher.io/local-path"))
+}
+
+func TestValidateNumDefaultStorageClasses(t *testing.T) {
+	tests := []struct {
+		name     string
+		classes  []*storagev1.StorageClass
+		expected bool
+	}{
+		{
+			name:     "no storage classes",
+			expected: false,
+		},
+		{
+			name: "one default",
+			classes: []*storagev1.StorageClass{
+				storageClass("standard", "kubernetes.io/gce-pd", map[string]string{
+					"storageclass.kubernetes.io/is-default-class":      "true",
+					"storageclass.beta.kubernetes.io/is-default-class": "true",
+				}),
+				storageClass("fast", "kubernetes.io/gce-pd", nil),
+			},
+			expected: true,
+		},
+		{
+			name: "two defaults",
+			classes: []*storagev1.StorageClass{
+				storageClass("standard", "kubernetes.io/gce-pd", map[string]string{"storageclass.kubernetes.io/is-default-class": "true"}),
+				storageClass("fast", "kubernetes.io/gce-pd", map[string]string{"storageclass.beta.kubernetes.io/is-default-class": "true"}),
+			},
+			expected: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			clientset := fake.NewSimpleClientset()
+			for _, sc := range test.classes {
+				_, err := clientset.StorageV1().StorageClasses().Create(context.Background(), sc, metav1.CreateOptions{})
+				require.NoError(t, err)
+			}
+			ok, err := storage.ValidateNumDefaultStorageClasses(clientset)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, ok)
+		})
+	}
+}
+
+func TestMissingNecessaryCSIDriver(t *testing.T) {
+	clientset := fake.NewSimpleClientset(
+		storageClass("gp2", "kubernetes.io/aws-ebs", map[string]string{"storageclass.kubernetes.io/is-default-class": "true"}),
+	)
+
+	// Older EKS clusters and non-EKS clusters still have the in-tree plugin.
+	assert.False(t, storage.MissingNecessaryCSIDriver(clientset, "v1.22.0-eks-1234"))
+	assert.False(t, storage.MissingNecessaryCSIDriver(clientset, "v1.25.0"))
+	assert.True(t, storage.MissingNecessaryCSIDriver(clientset, "v1.23.7-eks-4721010"))
+
+	_, err := clientset.StorageV1().CSIDrivers().Create(context.Background(), &storagev1.CSIDriver{
+		ObjectMeta: metav1.ObjectMeta{Name: "ebs.csi.aws.com"},
+	}, metav1.CreateOptions{})
+	require.NoError(t, err)
+	assert.False(t, storage.MissingNecessaryCSIDriver(clientset, "v1.23.7-eks-4721010"))
+}
@@ -55,9 +55,18 @@ var localServerRedirectURL = "http://localhost:8085/auth_complete"
 var localServerPort = int32(8085)
 var sentSegmentAlias = false
 
-// SaveRefreshToken saves the refresh token in default spot.
+// authFilePath returns the auth file for the active context. When no context is in use, the default
+// auth file is used.
+func authFilePath() (string, error) {
+	if ctx := pxconfig.ActiveContext(); ctx != nil {
+		return utils.EnsureContextAuthFilePath(ctx.Name)
+	}
+	return utils.EnsureDefaultAuthFilePath()
+}
+
+// SaveRefreshToken saves the refresh token for the active context.
 func SaveRefreshToken(token *RefreshToken) error {
-	pixieAuthFilePath, err := utils.EnsureDefaultAuthFilePath()
+	pixieAuthFilePath, err := authFilePath()
 	if err != nil {
 		return err
 	}
@@ -71,9 +80,9 @@ func SaveRefreshToken(token *RefreshToken) error {
 	return json.NewEncoder(f).Encode(token)
 }
 
-// LoadDefaultCredentials loads the default credentials for the user.
+// LoadDefaultCredentials loads the credentials of the active context for the user.
 func LoadDefaultCredentials() (*RefreshToken, error) {
-	pixieAuthFilePath, err := utils.EnsureDefaultAuthFilePath()
+	pixieAuthFilePath, err := authFilePath()
 	if err != nil {
 		return nil, err
 	}
@@ -135,7 +144,11 @@ func MustLoadDefaultCredentials() *RefreshToken {
 	token, err := LoadDefaultCredentials()
 
 	if err != nil && os.IsNotExist(err) {
-		utils.Error("You must be logged in to perform this operation. Please run `px auth login`.")
+		if ctx := pxconfig.ActiveContext(); ctx != nil {
+			utils.Errorf("You must be logged in to perform this operation. Please run `px auth login --context %s`.", ctx.Name)
+		} else {
+			utils.Error("You must be logged in to perform this operation. Please run `px auth login`.")
+		}
 	} else if err != nil {
 		utils.Errorf("Failed to get auth credentials: %s", err.Error())
 	}
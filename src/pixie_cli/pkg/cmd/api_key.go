@@ -22,7 +22,6 @@ import (
 	"context"
 	"fmt"
 	"os"
-	"strings"
 	"syscall"
 
 	"github.com/gofrs/uuid"
@@ -50,7 +49,7 @@ func init() {
 
 	DeleteAPIKeyCmd.Flags().StringP("id", "i", "", "The API key to delete")
 
-	ListAPIKeyCmd.Flags().StringP("output", "o", "", "Output format: one of: json|proto")
+	ListAPIKeyCmd.Flags().StringP("output", "o", "", components.OutputFormatHelp)
 
 	LookupAPIKeyCmd.Flags().StringP("key", "k", "", "Value of the key. Leave blank to be prompted.")
 }
@@ -128,8 +127,7 @@ var ListAPIKeyCmd = &cobra.Command{
 	},
 	Run: func(cmd *cobra.Command, args []string) {
 		cloudAddr := viper.GetString("cloud_addr")
-		format, _ := cmd.Flags().GetString("output")
-		format = strings.ToLower(format)
+		format := mustGetOutputFormat(cmd)
 
 		keys, err := listAPIKeyMetadatas(cloudAddr)
 		if err != nil {
@@ -141,7 +139,7 @@ var ListAPIKeyCmd = &cobra.Command{
 		defer w.Finish()
 		w.SetHeader("api-keys", []string{"ID", "Key", "CreatedAt", "Description"})
 		for _, k := range keys {
-			_ = w.Write([]interface{}{utils2.UUIDFromProtoOrNil(k.ID), "<hidden>", k.CreatedAt,
+			mustWriteRow(w, []interface{}{utils2.UUIDFromProtoOrNil(k.ID), "<hidden>", k.CreatedAt,
 				k.Desc})
 		}
 	},
@@ -153,8 +151,7 @@ var LookupAPIKeyCmd = &cobra.Command{
 	Short: "Lookup API key based on the value of the key",
 	Run: func(cmd *cobra.Command, args []string) {
 		cloudAddr := viper.GetString("cloud_addr")
-		format, _ := cmd.Flags().GetString("output")
-		format = strings.ToLower(format)
+		format := mustGetOutputFormat(cmd)
 		apiKey, err := cmd.Flags().GetString("key")
 		if err != nil || len(apiKey) == 0 {
 			fmt.Print("\nEnter API Key (won't echo): ")
@@ -174,7 +171,7 @@ var LookupAPIKeyCmd = &cobra.Command{
 		w := components.CreateStreamWriter(format, os.Stdout)
 		defer w.Finish()
 		w.SetHeader("api-keys", []string{"ID", "Key", "CreatedAt", "Description"})
-		_ = w.Write([]interface{}{utils2.UUIDFromProtoOrNil(k.ID), "<hidden>", k.CreatedAt,
+		mustWriteRow(w, []interface{}{utils2.UUIDFromProtoOrNil(k.ID), "<hidden>", k.CreatedAt,
 			k.Desc})
 	},
 }
@@ -185,8 +182,7 @@ var GetAPIKeyCmd = &cobra.Command{
 	Short: "Get API key details for a specific key",
 	Run: func(cmd *cobra.Command, args []string) {
 		cloudAddr := viper.GetString("cloud_addr")
-		format, _ := cmd.Flags().GetString("output")
-		format = strings.ToLower(format)
+		format := mustGetOutputFormat(cmd)
 
 		if len(args) != 1 {
 			utils.Fatal("Expected a single argument 'key id'.")
@@ -205,7 +201,7 @@ var GetAPIKeyCmd = &cobra.Command{
 		w := components.CreateStreamWriter(format, os.Stdout)
 		defer w.Finish()
 		w.SetHeader("api-keys", []string{"ID", "Key", "CreatedAt", "Description"})
-		_ = w.Write([]interface{}{utils2.UUIDFromProtoOrNil(k.ID), k.Key, k.CreatedAt,
+		mustWriteRow(w, []interface{}{utils2.UUIDFromProtoOrNil(k.ID), k.Key, k.CreatedAt,
 			k.Desc})
 	},
 }
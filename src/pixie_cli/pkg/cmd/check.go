@@ -0,0 +1,133 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"px.dev/pixie/src/pixie_cli/pkg/components"
+	"px.dev/pixie/src/pixie_cli/pkg/utils"
+)
+
+func init() {
+	CheckCmd.Flags().StringP("output", "o", "", "Output format: one of: table|json")
+	CheckCmd.Flags().StringP("namespace", "n", "pl", "The namespace Pixie will be deployed to")
+	CheckCmd.Flags().String("registry", "", "The custom image registry Pixie will be deployed with, rather than Pixie's default (gcr.io)")
+	CheckCmd.Flags().StringP("pem_memory_limit", "p", "", "The memory limit Pixie's PEMs will be deployed with (default 2Gi)")
+	CheckCmd.Flags().String("probe_image", utils.DefaultProbeImage, "The image used by the probe pod that inspects a node")
+	CheckCmd.Flags().Bool("skip_probe", false, "Skip the checks that need to run a privileged probe pod on the cluster")
+	CheckCmd.Flags().StringArray("custom_checks", []string{}, "YAML files with custom checks to run in addition to the built-in checks")
+}
+
+// checkReport is the JSON output of `px check`.
+type checkReport struct {
+	Passed bool                 `json:"passed"`
+	Checks []*utils.CheckResult `json:"checks"`
+}
+
+// CheckCmd is the "check" command.
+var CheckCmd = &cobra.Command{
+	Use:   "check",
+	Short: "Check whether the cluster in the current kubeconfig can run Pixie",
+	Long: `Check whether the cluster in the current kubeconfig can run Pixie.
+
+Runs the same checks as "px deploy", and additional checks for node kernels, BPF and BTF support,
+cgroups, pod security restrictions, storage, the image registry and node memory. Some of these
+checks start a short-lived privileged probe pod on the cluster, use --skip_probe to disable them.
+
+Failed checks come with a hint on how to fix the problem. Only failures of required checks make
+the command exit with a non-zero status; the other checks are reported as warnings.
+
+Custom checks can be added with --custom_checks, which takes a YAML file of the form:
+
+  checks:
+  - name: Nodes are labeled for Pixie
+    command: kubectl get nodes -l pixie=allowed -o name
+    expectOutput: "node/"
+    remediation: Label the nodes that should run Pixie with pixie=allowed.
+    required: true
+`,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		viper.BindPFlag("namespace", cmd.Flags().Lookup("namespace"))
+		viper.BindPFlag("pem_memory_limit", cmd.Flags().Lookup("pem_memory_limit"))
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		format, _ := cmd.Flags().GetString("output")
+		format = strings.ToLower(format)
+		if format != "" && format != "table" && format != "json" {
+			utils.Fatalf("Unsupported output format %q, expected table or json", format)
+		}
+		registry, _ := cmd.Flags().GetString("registry")
+		probeImage, _ := cmd.Flags().GetString("probe_image")
+		skipProbe, _ := cmd.Flags().GetBool("skip_probe")
+		customCheckFiles, _ := cmd.Flags().GetStringArray("custom_checks")
+
+		checks := utils.PreflightChecks(&utils.PreflightOptions{
+			Namespace:      viper.GetString("namespace"),
+			Registry:       registry,
+			PEMMemoryLimit: viper.GetString("pem_memory_limit"),
+			ProbeImage:     probeImage,
+			SkipProbe:      skipProbe,
+		})
+		for _, f := range customCheckFiles {
+			custom, err := utils.LoadCustomChecks(f)
+			if err != nil {
+				utils.WithError(err).Fatalf("Failed to load custom checks from %s", f)
+			}
+			checks = append(checks, custom...)
+		}
+
+		if format != "json" {
+			utils.Infof("Running %d cluster checks...", len(checks))
+		}
+		results := utils.RunCheckSuite(checks)
+		passed := utils.CheckSuitePassed(results)
+
+		if format == "json" {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(&checkReport{Passed: passed, Checks: results}); err != nil {
+				utils.WithError(err).Fatal("Failed to write check report")
+			}
+		} else {
+			renderCheckResults(results)
+		}
+
+		if !passed {
+			os.Exit(1)
+		}
+	},
+}
+
+func renderCheckResults(results []*utils.CheckResult) {
+	w := components.CreateStreamWriter("table", os.Stdout)
+	w.SetHeader("checks", []string{"Check", "Status", "Required", "Message", "Remediation"})
+	for _, r := range results {
+		err := w.Write([]interface{}{r.Name, strings.ToUpper(string(r.Status)), r.Required, r.Message, r.Remediation})
+		if err != nil {
+			utils.WithError(err).Fatal("Failed to write check results")
+		}
+	}
+	w.Finish()
+}
@@ -19,36 +19,195 @@
 package cmd
 
 import (
+	"bytes"
+	"context"
 	"fmt"
+	"os"
+	"path"
+	"strings"
 	"time"
 
+	"github.com/gofrs/uuid"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
+	"px.dev/pixie/src/api/proto/vizierpb"
+	"px.dev/pixie/src/operator/client/versioned"
+	"px.dev/pixie/src/pixie_cli/pkg/components"
+	"px.dev/pixie/src/pixie_cli/pkg/supportbundle"
 	"px.dev/pixie/src/pixie_cli/pkg/utils"
+	"px.dev/pixie/src/pixie_cli/pkg/vizier"
+	version "px.dev/pixie/src/shared/goversion"
+	"px.dev/pixie/src/utils/script"
 	"px.dev/pixie/src/utils/shared/k8s"
 )
 
+// bundleScripts are run on the Vizier, and their output is added to the support bundle.
+var bundleScripts = []string{"px/cluster", script.AgentStatusScript}
+
+const bundleScriptTimeout = 30 * time.Second
+
 func init() {
 	CollectLogsCmd.Flags().StringP("namespace", "n", "", "The namespace vizier is deployed in")
+	CollectLogsCmd.Flags().String("operator_namespace", "", "The namespace the operator is deployed in. Found automatically if not set")
+	CollectLogsCmd.Flags().StringP("output", "o", "", "The file to write the support bundle to (default pixie_support_bundle_<time>.tar.gz)")
+	CollectLogsCmd.Flags().Int64("max_log_bytes", supportbundle.DefaultMaxLogBytes, "The maximum size of the logs to collect for each container")
+	CollectLogsCmd.Flags().Bool("skip_scripts", false, "Don't run px/cluster and px/agent_status on the Vizier, for example when Pixie Cloud is unreachable")
+	CollectLogsCmd.Flags().StringP("cluster", "c", "", "The ID of the cluster to run the scripts on. Defaults to the cluster in the current kubeconfig")
 }
 
-// CollectLogsCmd is the "deploy" command.
+// CollectLogsCmd is the "collect-logs" command.
 var CollectLogsCmd = &cobra.Command{
 	Use:   "collect-logs",
-	Short: "Collect Pixie logs on the cluster",
+	Short: "Collect a support bundle of Pixie logs and state on the cluster",
+	Long: `Collect a support bundle of Pixie logs and state on the cluster.
+
+The bundle is a single tar.gz file with the Vizier spec and status, the logs of the operator and all
+Vizier pods (including previous containers), events, node info, config maps, and the output of the
+px/cluster and px/agent_status scripts. Secrets are never collected, and credentials are redacted
+from everything that is. Use "px bundle inspect" to summarize a bundle.`,
 	PreRun: func(cmd *cobra.Command, args []string) {
 		viper.BindPFlag("namespace", cmd.Flags().Lookup("namespace"))
 	},
 	Run: func(cmd *cobra.Command, args []string) {
-		c := k8s.NewLogCollector()
-		fName := fmt.Sprintf("pixie_logs_%s.zip", time.Now().Format("20060102150405"))
-		err := c.CollectPixieLogs(fName)
+		kubeConfig := k8s.GetConfig()
+		clientset := k8s.GetClientset(kubeConfig)
+		vzClient, err := versioned.NewForConfig(kubeConfig)
+		if err != nil {
+			log.WithError(err).Fatal("Failed to create Vizier client")
+		}
+
+		ns := viper.GetString("namespace")
+		if ns == "" {
+			ns, err = vizier.FindVizierNamespace(clientset)
+			if err != nil {
+				utils.WithError(err).Fatal("Failed to find Vizier namespace")
+			}
+			if ns == "" {
+				utils.Fatal("Cannot find running Vizier instance, use --namespace to set the Vizier namespace")
+			}
+		}
+		opNs, _ := cmd.Flags().GetString("operator_namespace")
+		if opNs == "" {
+			opNs, err = vizier.FindOperatorNamespace(clientset)
+			if err != nil {
+				utils.WithError(err).Error("Failed to find operator namespace, skipping operator logs")
+			}
+		}
+
+		fName, _ := cmd.Flags().GetString("output")
+		if fName == "" {
+			fName = fmt.Sprintf("pixie_support_bundle_%s.tar.gz", time.Now().Format("20060102150405"))
+		}
+		f, err := os.Create(fName)
 		if err != nil {
-			log.WithError(err).Fatal("Failed to get log files")
+			utils.WithError(err).Fatalf("Failed to create %s", fName)
+		}
+		defer f.Close()
+
+		manifest := &supportbundle.Manifest{
+			CLIVersion:        version.GetVersion().ToString(),
+			ClusterID:         vizier.GetClusterIDFromKubeConfig(kubeConfig).String(),
+			VizierNamespace:   ns,
+			OperatorNamespace: opNs,
 		}
+		w := supportbundle.NewWriter(f, manifest)
 
-		utils.Infof("Logs written to %s", fName)
+		ctx, cleanup := utils.WithSignalCancellable(context.Background())
+		defer cleanup()
+
+		maxLogBytes, _ := cmd.Flags().GetInt64("max_log_bytes")
+		utils.Infof("Collecting Pixie state from namespaces %s", strings.Join(nonEmpty(ns, opNs), ", "))
+		c := supportbundle.NewCollector(clientset, vzClient, ns, opNs, maxLogBytes)
+		if err := c.Collect(ctx, w); err != nil {
+			utils.WithError(err).Fatal("Failed to collect support bundle")
+		}
+
+		if skip, _ := cmd.Flags().GetBool("skip_scripts"); !skip {
+			selectedCluster, _ := cmd.Flags().GetString("cluster")
+			utils.Info("Running scripts on Vizier")
+			collectScriptOutputs(ctx, w, selectedCluster)
+		}
+
+		if err := w.Close(); err != nil {
+			utils.WithError(err).Fatal("Failed to write support bundle")
+		}
+		utils.Infof("Support bundle written to %s", fName)
+		if len(manifest.Errors) > 0 {
+			utils.Infof("%d items could not be collected, run `px bundle inspect %s` for details", len(manifest.Errors), fName)
+		}
 	},
 }
+
+func nonEmpty(strs ...string) []string {
+	var out []string
+	for _, s := range strs {
+		if s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// collectScriptOutputs adds the output of the bundle scripts to the support bundle. Failures are
+// recorded in the bundle, since the Vizier that the bundle is collected for is likely to be unhealthy.
+func collectScriptOutputs(ctx context.Context, w *supportbundle.Writer, selectedCluster string) {
+	cloudAddr := viper.GetString("cloud_addr")
+	br, err := createBundleReader()
+	if err != nil {
+		w.AddError(supportbundle.KindScriptOutput, "script bundle", err)
+		return
+	}
+
+	clusterID := uuid.FromStringOrNil(selectedCluster)
+	if clusterID == uuid.Nil {
+		clusterID, err = getVizier(cloudAddr)
+		if err != nil {
+			w.AddError(supportbundle.KindScriptOutput, "vizier", err)
+			return
+		}
+	}
+	conn, err := vizier.ConnectionToVizierByID(cloudAddr, clusterID)
+	if err != nil {
+		w.AddError(supportbundle.KindScriptOutput, clusterID.String(), err)
+		return
+	}
+
+	for _, name := range bundleScripts {
+		out, err := runScriptToJSON(ctx, conn, br, name)
+		if err != nil {
+			w.AddError(supportbundle.KindScriptOutput, name, err)
+			continue
+		}
+		entry := &supportbundle.FileEntry{
+			Path: path.Join("scripts", strings.ReplaceAll(name, "/", "_")+".json"),
+			Kind: supportbundle.KindScriptOutput,
+		}
+		if err := w.Add(entry, out); err != nil {
+			w.AddError(supportbundle.KindScriptOutput, name, err)
+		}
+	}
+}
+
+func runScriptToJSON(ctx context.Context, conn *vizier.Connector, br *script.BundleManager, name string) ([]byte, error) {
+	execScript, err := br.GetScript(name)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, bundleScriptTimeout)
+	defer cancel()
+
+	resp, err := vizier.RunScript(ctx, []*vizier.Connector{conn}, execScript, nil)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	tw := vizier.NewStreamOutputAdapterWithFactory(ctx, resp, "json", nil, func(*vizierpb.ExecuteScriptResponse_MetaData) components.OutputStreamWriter {
+		return components.CreateStreamWriter("json", &buf)
+	})
+	if err := tw.Finish(); err != nil {
+		return nil, err
+	}
+	return []byte(supportbundle.RedactText(buf.String())), nil
+}
@@ -0,0 +1,187 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package cmd
+
+import (
+	"os"
+	"strings"
+
+	"github.com/gofrs/uuid"
+	"github.com/spf13/cobra"
+
+	"px.dev/pixie/src/pixie_cli/pkg/components"
+	"px.dev/pixie/src/pixie_cli/pkg/pxconfig"
+	"px.dev/pixie/src/pixie_cli/pkg/utils"
+)
+
+func init() {
+	GetContextsCmd.Flags().StringP("output", "o", "", "Output format: one of: json|proto")
+
+	UseContextCmd.Flags().Bool("none", false, "Stop using a context, and fall back to the flags and the default credentials")
+
+	SetContextCmd.Flags().String("cloud-addr", "", "The address of the Pixie Cloud used by the context")
+	SetContextCmd.Flags().String("cluster", "", "The ID of the cluster used by default in the context")
+	SetContextCmd.Flags().String("direct-vizier-addr", "", "If set, the context connects directly to the Vizier service at the given address")
+	SetContextCmd.Flags().String("direct-vizier-key", "", "The key used to authenticate with the Vizier service when direct-vizier-addr is set")
+	SetContextCmd.Flags().Bool("use", false, "Whether to also make this the current context")
+
+	ConfigCmd.AddCommand(GetContextsCmd)
+	ConfigCmd.AddCommand(CurrentContextCmd)
+	ConfigCmd.AddCommand(UseContextCmd)
+	ConfigCmd.AddCommand(SetContextCmd)
+}
+
+// ConfigCmd is the "config" command.
+var ConfigCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Manage the CLI config and contexts",
+	Long: `Manage the CLI config and contexts.
+
+A context is a named profile holding a cloud address, credentials, a default cluster and
+direct vizier settings. Use "px auth login --context <name>" to log in to a context.`,
+}
+
+// GetContextsCmd is the "config get-contexts" command.
+var GetContextsCmd = &cobra.Command{
+	Use:   "get-contexts",
+	Short: "List the configured contexts",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		format, _ := cmd.Flags().GetString("output")
+		format = strings.ToLower(format)
+
+		cfg := pxconfig.Cfg()
+		active := cfg.ActiveContextName()
+
+		w := components.CreateStreamWriter(format, os.Stdout)
+		defer w.Finish()
+		w.SetHeader("contexts", []string{"Current", "Name", "Cloud Address", "Cluster ID", "Direct Vizier Address"})
+		for _, ctx := range cfg.Contexts {
+			current := ""
+			if ctx.Name == active {
+				current = "*"
+			}
+			mustWriteRow(w, []interface{}{current, ctx.Name, ctx.CloudAddr, ctx.ClusterID, ctx.DirectVizierAddr})
+		}
+	},
+}
+
+// CurrentContextCmd is the "config current-context" command.
+var CurrentContextCmd = &cobra.Command{
+	Use:   "current-context",
+	Short: "Print the name of the context in use",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := mustGetActiveContext()
+		if ctx == nil {
+			utils.Info("No context is in use")
+			return
+		}
+		utils.Info(ctx.Name)
+	},
+}
+
+// UseContextCmd is the "config use-context" command.
+var UseContextCmd = &cobra.Command{
+	Use:   "use-context [name]",
+	Short: "Set the current context",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		none, _ := cmd.Flags().GetBool("none")
+		if none == (len(args) == 1) {
+			utils.Error("Exactly one of a context name or --none must be provided")
+			os.Exit(1)
+		}
+
+		name := ""
+		if len(args) == 1 {
+			name = args[0]
+		}
+		cfg := pxconfig.Cfg()
+		if err := cfg.UseContext(name); err != nil {
+			utils.Error(err.Error())
+			os.Exit(1)
+		}
+		if err := cfg.Save(); err != nil {
+			utils.WithError(err).Fatal("Failed to save config")
+		}
+
+		if name == "" {
+			utils.Info("No longer using a context")
+			return
+		}
+		utils.Infof("Switched to context %q", name)
+	},
+}
+
+// SetContextCmd is the "config set-context" command.
+var SetContextCmd = &cobra.Command{
+	Use:   "set-context <name>",
+	Short: "Create a context, or update the settings of an existing context",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := args[0]
+		cfg := pxconfig.Cfg()
+
+		ctx := &pxconfig.Context{Name: name}
+		created := true
+		if existing := cfg.GetContext(name); existing != nil {
+			ctx = existing
+			created = false
+		}
+
+		// Only overwrite the settings that were passed in, so that a single setting can be updated.
+		if cmd.Flags().Changed("cloud-addr") {
+			ctx.CloudAddr, _ = cmd.Flags().GetString("cloud-addr")
+		}
+		if cmd.Flags().Changed("cluster") {
+			clusterID, _ := cmd.Flags().GetString("cluster")
+			if clusterID != "" {
+				if _, err := uuid.FromString(clusterID); err != nil {
+					utils.Errorf("Invalid cluster ID %q", clusterID)
+					os.Exit(1)
+				}
+			}
+			ctx.ClusterID = clusterID
+		}
+		if cmd.Flags().Changed("direct-vizier-addr") {
+			ctx.DirectVizierAddr, _ = cmd.Flags().GetString("direct-vizier-addr")
+		}
+		if cmd.Flags().Changed("direct-vizier-key") {
+			ctx.DirectVizierKey, _ = cmd.Flags().GetString("direct-vizier-key")
+		}
+
+		if err := cfg.SetContext(ctx); err != nil {
+			utils.Error(err.Error())
+			os.Exit(1)
+		}
+		if use, _ := cmd.Flags().GetBool("use"); use {
+			_ = cfg.UseContext(name)
+		}
+		if err := cfg.Save(); err != nil {
+			utils.WithError(err).Fatal("Failed to save config")
+		}
+
+		if created {
+			utils.Infof("Created context %q", name)
+		} else {
+			utils.Infof("Updated context %q", name)
+		}
+	},
+}
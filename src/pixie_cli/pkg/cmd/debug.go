@@ -182,7 +182,7 @@ var DebugPodsCmd = &cobra.Command{
 		defer w.Finish()
 		w.SetHeader("pods", []string{"Name", "Phase", "Restarts", "Message", "Reason", "Start Time"})
 		for _, pod := range pods {
-			_ = w.Write([]interface{}{
+			mustWriteRow(w, []interface{}{
 				pod.Name, pod.Phase, pod.RestartCount, pod.Message, pod.Reason, time.Unix(0, pod.CreatedAt),
 			})
 		}
@@ -206,7 +206,7 @@ var DebugContainersCmd = &cobra.Command{
 		w.SetHeader("containers", []string{"Name", "Pod", "State", "Restarts", "Message", "Reason", "Start Time"})
 		for _, pod := range pods {
 			for _, c := range pod.ContainerStatuses {
-				_ = w.Write([]interface{}{
+				mustWriteRow(w, []interface{}{
 					c.Name, pod.Name, c.ContainerState, c.RestartCount, c.Message, c.Reason, time.Unix(0, c.StartTimestampNS),
 				})
 			}
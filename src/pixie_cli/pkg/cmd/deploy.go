@@ -45,7 +45,6 @@ import (
 	vztypes "px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
 	"px.dev/pixie/src/operator/client/versioned"
 	"px.dev/pixie/src/pixie_cli/pkg/auth"
-	"px.dev/pixie/src/pixie_cli/pkg/components"
 	"px.dev/pixie/src/pixie_cli/pkg/pxanalytics"
 	"px.dev/pixie/src/pixie_cli/pkg/pxconfig"
 	"px.dev/pixie/src/pixie_cli/pkg/utils"
@@ -88,6 +87,8 @@ func init() {
 	DeployCmd.Flags().String("pem_flags", "", "Flags to be set on the PEM.")
 	DeployCmd.Flags().String("registry", "", "The custom image registry to use rather than Pixie's default (gcr.io).")
 	DeployCmd.Flags().BoolP("disable_auto_update", "d", false, "Disable the auto-update feature for the vizier client.")
+	DeployCmd.Flags().Bool("plan", false, "Show the Kubernetes objects that the deploy would create or change, without deploying.")
+	DeployCmd.Flags().String("progress_format", "", "Format of the deploy's progress: either empty for human readable output, or 'json' for a JSON event per line on stdout. JSON implies -y.")
 
 	// Flags for deploying OLM.
 	DeployCmd.Flags().String("operator_version", "", "Operator version to deploy")
@@ -105,6 +106,21 @@ func init() {
 var DeployCmd = &cobra.Command{
 	Use:   "deploy",
 	Short: "Deploys Pixie on the current K8s cluster",
+	Long: `Deploys Pixie on the current K8s cluster.
+
+Use --plan to show the Kubernetes objects that the deploy would create or change, as a diff
+from a server-side dry run against the cluster. Use -y and --progress_format=json to run deploys
+from scripts.
+
+Exit codes:
+  0  Success
+  1  Unexpected failure
+  2  Invalid flags
+  3  Cluster checks failed
+  4  Failed to communicate with Pixie Cloud
+  5  Failed to apply Pixie to the Kubernetes cluster
+  6  Deploy aborted
+  7  Pixie failed its healthcheck after deploying`,
 	PreRun: func(cmd *cobra.Command, args []string) {
 		viper.BindPFlag("extract_yaml", cmd.Flags().Lookup("extract_yaml"))
 		viper.BindPFlag("vizier_version", cmd.Flags().Lookup("vizier_version"))
@@ -128,11 +144,16 @@ var DeployCmd = &cobra.Command{
 		viper.BindPFlag("datastream_buffer_size", cmd.Flags().Lookup("datastream_buffer_size"))
 		viper.BindPFlag("datastream_buffer_spike_size", cmd.Flags().Lookup("datastream_buffer_spike_size"))
 		viper.BindPFlag("disable_auto_update", cmd.Flags().Lookup("disable_auto_update"))
+		viper.BindPFlag("plan", cmd.Flags().Lookup("plan"))
+		viper.BindPFlag("progress_format", cmd.Flags().Lookup("progress_format"))
 	},
 	PostRun: func(cmd *cobra.Command, args []string) {
 		if cmd.Annotations["status"] != DeploySuccess {
 			return
 		}
+		if format, _ := cmd.Flags().GetString("progress_format"); format == "json" {
+			return
+		}
 
 		p := func(s string, a ...interface{}) {
 			fmt.Fprintf(os.Stderr, s, a...)
@@ -225,130 +246,164 @@ func getLatestOperatorVersion(conn *grpc.ClientConn) (string, error) {
 	return resp.Artifact[0].VersionStr, nil
 }
 
-func runDeployCmd(cmd *cobra.Command, args []string) {
-	check, _ := cmd.Flags().GetBool("check")
-	checkOnly, _ := cmd.Flags().GetBool("check_only")
-	extractPath, _ := cmd.Flags().GetString("extract_yaml")
+// deployOptions are the options of a deploy, parsed from the command's flags.
+type deployOptions struct {
+	check       bool
+	checkOnly   bool
+	extractPath string
+	// plan shows the changes the deploy would make to the cluster, without deploying.
+	plan bool
+
+	deployOLM            bool
+	olmNamespace         string
+	olmOperatorNamespace string
+
+	namespace         string
+	deployKey         string
+	useEtcdOperator   bool
+	disableAutoUpdate bool
+	clusterName       string
+	pemMemoryLimit    string
+	pemMemoryRequest  string
+	registry          string
+	dataAccess        vztypes.DataAccessLevel
+
+	labels              map[string]string
+	annotations         map[string]string
+	patches             map[string]string
+	dataCollectorParams map[string]interface{}
+}
 
-	// OLM flags.
-	deployOLM, _ := cmd.Flags().GetBool("deploy_olm")
-	olmNamespace, _ := cmd.Flags().GetString("olm_namespace")
-	olmOperatorNamespace, _ := cmd.Flags().GetString("olm_operator_namespace")
+func newDeployOptions(cmd *cobra.Command) (*deployOptions, error) {
+	o := &deployOptions{}
+	o.check, _ = cmd.Flags().GetBool("check")
+	o.checkOnly, _ = cmd.Flags().GetBool("check_only")
+	o.extractPath, _ = cmd.Flags().GetString("extract_yaml")
+	o.plan, _ = cmd.Flags().GetBool("plan")
 
-	deployKey, _ := cmd.Flags().GetString("deploy_key")
-	useEtcdOperator, _ := cmd.Flags().GetBool("use_etcd_operator")
-	disableAutoUpdate, _ := cmd.Flags().GetBool("disable_auto_update")
+	// OLM flags.
+	o.deployOLM, _ = cmd.Flags().GetBool("deploy_olm")
+	o.olmNamespace, _ = cmd.Flags().GetString("olm_namespace")
+	o.olmOperatorNamespace, _ = cmd.Flags().GetString("olm_operator_namespace")
+
+	o.namespace, _ = cmd.Flags().GetString("namespace")
+	o.deployKey, _ = cmd.Flags().GetString("deploy_key")
+	o.useEtcdOperator, _ = cmd.Flags().GetBool("use_etcd_operator")
+	o.disableAutoUpdate, _ = cmd.Flags().GetBool("disable_auto_update")
+	o.clusterName, _ = cmd.Flags().GetString("cluster_name")
+	o.pemMemoryLimit, _ = cmd.Flags().GetString("pem_memory_limit")
+	o.pemMemoryRequest, _ = cmd.Flags().GetString("pem_memory_request")
+	o.registry, _ = cmd.Flags().GetString("registry")
 	customLabels, _ := cmd.Flags().GetString("labels")
 	customAnnotations, _ := cmd.Flags().GetString("annotations")
-	pemMemoryLimit, _ := cmd.Flags().GetString("pem_memory_limit")
-	pemMemoryRequest, _ := cmd.Flags().GetString("pem_memory_request")
 	pemFlags, _ := cmd.Flags().GetString("pem_flags")
 	patches, _ := cmd.Flags().GetStringArray("patches")
 	dataAccess, _ := cmd.Flags().GetString("data_access")
 	datastreamBufferSize, _ := cmd.Flags().GetUint32("datastream_buffer_size")
 	datastreamBufferSpikeSize, _ := cmd.Flags().GetUint32("datastream_buffer_spike_size")
-	registry, _ := cmd.Flags().GetString("registry")
 
-	labelMap := make(map[string]string)
+	o.labels = make(map[string]string)
 	if customLabels != "" {
 		lm, err := k8s.KeyValueStringToMap(customLabels)
 		if err != nil {
-			utils.WithError(err).Fatal("--labels must be specified through the following format: label1=value1,label2=value2")
+			return nil, newDeployError(deployExitInvalidFlags, err, "--labels must be specified through the following format: label1=value1,label2=value2")
 		}
-		labelMap = lm
+		o.labels = lm
 	}
 	// Check that none of the labels override ours.
 	for _, l := range BlockListedLabels {
-		if _, ok := labelMap[l]; ok {
+		if _, ok := o.labels[l]; ok {
 			joinedLabels := strings.Join(BlockListedLabels, ", ")
-			utils.Fatalf("Custom labels must not be one of: %s.", joinedLabels)
+			return nil, newDeployError(deployExitInvalidFlags, nil, fmt.Sprintf("Custom labels must not be one of: %s.", joinedLabels))
 		}
 	}
-	annotationMap := make(map[string]string)
+	o.annotations = make(map[string]string)
 	if customAnnotations != "" {
 		am, err := k8s.KeyValueStringToMap(customAnnotations)
 		if err != nil {
-			utils.WithError(err).Fatal("--annotations must be specified through the following format: annotation1=value1,annotation2=value2")
+			return nil, newDeployError(deployExitInvalidFlags, err, "--annotations must be specified through the following format: annotation1=value1,annotation2=value2")
 		}
-		annotationMap = am
-	}
-	patchesMap := make(map[string]string)
-	if len(patches) != 0 {
-		for _, p := range patches {
-			colon := strings.Index(p, ":")
-			if colon == -1 {
-				continue
-			}
-			patchesMap[p[:colon]] = p[colon+1:]
+		o.annotations = am
+	}
+	o.patches = make(map[string]string)
+	for _, p := range patches {
+		colon := strings.Index(p, ":")
+		if colon == -1 {
+			continue
 		}
+		o.patches[p[:colon]] = p[colon+1:]
 	}
 	pemFlagsMap := make(map[string]string)
 	if pemFlags != "" {
 		pf, err := k8s.KeyValueStringToMap(pemFlags)
 		if err != nil {
-			utils.WithError(err).Fatal("--pem_flags must be specified through the following format: PL_KEY_1=value1,PL_KEY_2=value2")
+			return nil, newDeployError(deployExitInvalidFlags, err, "--pem_flags must be specified through the following format: PL_KEY_1=value1,PL_KEY_2=value2")
 		}
 		pemFlagsMap = pf
 	}
-	dataCollectorParams := make(map[string]interface{})
-	dataCollectorParams["customPEMFlags"] = pemFlagsMap
+	o.dataCollectorParams = make(map[string]interface{})
+	o.dataCollectorParams["customPEMFlags"] = pemFlagsMap
 	if datastreamBufferSize != 0 {
-		dataCollectorParams["datastreamBufferSize"] = datastreamBufferSize
+		o.dataCollectorParams["datastreamBufferSize"] = datastreamBufferSize
 	}
 	if datastreamBufferSpikeSize != 0 {
-		dataCollectorParams["datastreamBufferSpikeSize"] = datastreamBufferSpikeSize
+		o.dataCollectorParams["datastreamBufferSpikeSize"] = datastreamBufferSpikeSize
 	}
 
-	castedDataAccess := vztypes.DataAccessLevel(dataAccess)
-	if castedDataAccess != vztypes.DataAccessFull && castedDataAccess != vztypes.DataAccessRestricted {
-		utils.Fatal("--data_access must be a valid data access level")
+	o.dataAccess = vztypes.DataAccessLevel(dataAccess)
+	if o.dataAccess != vztypes.DataAccessFull && o.dataAccess != vztypes.DataAccessRestricted {
+		return nil, newDeployError(deployExitInvalidFlags, nil, "--data_access must be a valid data access level")
 	}
 
-	if deployKey == "" && extractPath != "" {
-		utils.Fatal("--deploy_key must be specified when running with --extract_yaml. Please run px deploy-key create.")
+	if o.deployKey == "" && o.extractPath != "" {
+		return nil, newDeployError(deployExitInvalidFlags, nil, "--deploy_key must be specified when running with --extract_yaml. Please run px deploy-key create.")
 	}
+	if o.plan && (o.extractPath != "" || o.checkOnly) {
+		return nil, newDeployError(deployExitInvalidFlags, nil, "--plan cannot be used with --extract_yaml or --check_only")
+	}
+	return o, nil
+}
 
-	if (check || checkOnly) && extractPath == "" {
-		_ = pxanalytics.Client().Enqueue(&analytics.Track{
-			UserId: pxconfig.Cfg().UniqueClientID,
-			Event:  "Cluster Check Run",
-		})
-
-		err := utils.RunDefaultClusterChecks()
-		if err != nil {
-			_ = pxanalytics.Client().Enqueue(&analytics.Track{
-				UserId: pxconfig.Cfg().UniqueClientID,
-				Event:  "Cluster Check Failed",
-				Properties: analytics.NewProperties().
-					Set("error", err.Error()),
-			})
-			utils.WithError(err).Fatal("Check pre-check has failed. To bypass pass in --check=false.")
-		}
+func runDeployCmd(cmd *cobra.Command, args []string) {
+	r, err := newDeployReporter(cmd)
+	if err != nil {
+		r.fail(err)
+	}
+	opts, err := newDeployOptions(cmd)
+	if err != nil {
+		r.fail(err)
+	}
+	clusterID, err := runDeploy(opts, r)
+	if err != nil {
+		r.fail(err)
+	}
+	if clusterID == uuid.Nil {
+		// Nothing was deployed, e.g. because only the checks or the plan were run.
+		r.done(nil)
+		return
+	}
+	r.done(map[string]interface{}{"clusterID": clusterID.String()})
 
-		if checkOnly {
-			log.Info("All Required Checks Passed!")
-			os.Exit(0)
-		}
+	cmd.Annotations = make(map[string]string)
+	cmd.Annotations["status"] = DeploySuccess
+}
 
-		err = utils.RunExtraClusterChecks()
-		if err != nil {
-			clusterOk := components.YNPrompt("Some cluster checks failed. Pixie may not work properly on your cluster. Continue with deploy?", true)
-			if !clusterOk {
-				utils.Error("Deploy cancelled. Aborting...")
-				return
-			}
+// runDeploy runs the deploy, and returns the ID of the deployed cluster. The ID is nil if nothing
+// was deployed.
+func runDeploy(o *deployOptions, r *deployReporter) (uuid.UUID, error) {
+	if (o.check || o.checkOnly) && o.extractPath == "" {
+		if err := runDeployChecks(o, r); err != nil || o.checkOnly {
+			return uuid.Nil, err
 		}
 	}
 
-	namespace, _ := cmd.Flags().GetString("namespace")
 	devCloudNS := viper.GetString("dev_cloud_namespace")
 	cloudAddr := viper.GetString("cloud_addr")
 
 	// Get grpc connection to cloud.
 	cloudConn, err := utils.GetCloudClientConnection(cloudAddr)
 	if err != nil {
-		// Using log.Fatal rather than CLI log in order to track this unexpected error in Sentry.
-		log.WithError(err).Fatalln("Failed to get grpc connection to cloud")
+		return uuid.Nil, newDeployError(deployExitCloud, err, "Failed to get grpc connection to cloud")
 	}
 
 	versionString := viper.GetString("vizier_version")
@@ -356,18 +411,16 @@ func runDeployCmd(cmd *cobra.Command, args []string) {
 		// Fetch latest version.
 		versionString, err = getLatestVizierVersion(cloudConn)
 		if err != nil {
-			// Using log.Fatal rather than CLI log in order to track this unexpected error in Sentry.
-			log.WithError(err).Fatal("Failed to fetch Vizier versions")
+			return uuid.Nil, newDeployError(deployExitCloud, err, "Failed to fetch Vizier versions")
 		}
 	}
-	utils.Infof("Installing Vizier version: %s", versionString)
+	r.infof(map[string]interface{}{"vizierVersion": versionString}, "Installing Vizier version: %s", versionString)
 
 	operatorVersion := viper.GetString("operator_version")
 	if len(operatorVersion) == 0 {
 		operatorVersion, err = getLatestOperatorVersion(cloudConn)
 		if err != nil {
-			// Using log.Fatal rather than CLI log in order to track this unexpected error in Sentry.
-			log.WithError(err).Fatal("Failed to fetch Operator versions")
+			return uuid.Nil, newDeployError(deployExitCloud, err, "Failed to fetch Operator versions")
 		}
 	}
 	olmBundleChannel := "stable"
@@ -375,13 +428,17 @@ func runDeployCmd(cmd *cobra.Command, args []string) {
 		olmBundleChannel = "dev"
 	}
 
-	// Get deploy key, if not already specified.
-	var deployKeyID string
+	// Get deploy key, if not already specified. A plan doesn't deploy anything, so it uses a
+	// placeholder rather than generating a key.
+	deployKey := o.deployKey
+	if deployKey == "" && o.plan {
+		deployKey = planDeployKeyPlaceholder
+	}
 	if deployKey == "" {
+		var deployKeyID string
 		deployKeyID, deployKey, err = generateDeployKey(cloudAddr, "Auto-generated by the Pixie CLI")
 		if err != nil {
-			// Using log.Fatal rather than CLI log in order to track this unexpected error in Sentry.
-			log.WithError(err).Fatal("Failed to generate deployment key")
+			return uuid.Nil, newDeployError(deployExitCloud, err, "Failed to generate deployment key")
 		}
 		defer func() {
 			err := deleteDeployKey(cloudAddr, uuid.FromStringOrNil(deployKeyID))
@@ -396,17 +453,17 @@ func runDeployCmd(cmd *cobra.Command, args []string) {
 	clientset := k8s.GetClientset(kubeConfig)
 	vzClient, err := versioned.NewForConfig(kubeConfig)
 	if err != nil {
-		log.WithError(err).Fatal("Could not start vizier client")
+		return uuid.Nil, newDeployError(deployExitKubernetes, err, "Could not start vizier client")
 	}
 
-	utils.Infof("Generating YAMLs for Pixie")
+	r.infof(nil, "Generating YAMLs for Pixie")
 
 	templatedYAMLs, err := artifacts.FetchOperatorTemplates(cloudConn, operatorVersion)
 	if err != nil {
-		log.WithError(err).Fatal("Could not fetch Vizier YAMLs")
+		return uuid.Nil, newDeployError(deployExitCloud, err, "Could not fetch Vizier YAMLs")
 	}
 
-	clusterName, _ := cmd.Flags().GetString("cluster_name")
+	clusterName := o.clusterName
 	if clusterName == "" {
 		clusterName = kubeAPIConfig.CurrentContext
 	}
@@ -419,45 +476,45 @@ func runDeployCmd(cmd *cobra.Command, args []string) {
 	// Fill in template values.
 	tmplArgs := &yamlsutils.YAMLTmplArguments{
 		Values: &map[string]interface{}{
-			"deployOLM":            deployOLM,
-			"olmNamespace":         olmNamespace,
+			"deployOLM":            o.deployOLM,
+			"olmNamespace":         o.olmNamespace,
 			"olmBundleChannel":     olmBundleChannel,
-			"olmOperatorNamespace": olmOperatorNamespace,
+			"olmOperatorNamespace": o.olmOperatorNamespace,
 			"name":                 "pixie",
 			"version":              versionString,
 			"deployKey":            deployKey,
 			"cloudAddr":            tmplCloudAddr,
 			"clusterName":          clusterName,
-			"disableAutoUpdate":    disableAutoUpdate,
-			"useEtcdOperator":      useEtcdOperator,
+			"disableAutoUpdate":    o.disableAutoUpdate,
+			"useEtcdOperator":      o.useEtcdOperator,
 			"devCloudNamespace":    devCloudNS,
-			"pemMemoryLimit":       pemMemoryLimit,
-			"pemMemoryRequest":     pemMemoryRequest,
+			"pemMemoryLimit":       o.pemMemoryLimit,
+			"pemMemoryRequest":     o.pemMemoryRequest,
 			"pod": &map[string]interface{}{
-				"annotations": annotationMap,
-				"labels":      labelMap,
+				"annotations": o.annotations,
+				"labels":      o.labels,
 			},
-			"patches":             patchesMap,
-			"dataAccess":          castedDataAccess,
-			"dataCollectorParams": dataCollectorParams,
-			"registry":            registry,
+			"patches":             o.patches,
+			"dataAccess":          o.dataAccess,
+			"dataCollectorParams": o.dataCollectorParams,
+			"registry":            o.registry,
 		},
 		Release: &map[string]interface{}{
-			"Namespace": namespace,
+			"Namespace": o.namespace,
 		},
 	}
 
 	yamls, err := yamlsutils.ExecuteTemplatedYAMLs(templatedYAMLs, tmplArgs)
 	if err != nil {
-		log.WithError(err).Fatal("Failed to fill in templated deployment YAMLs")
+		return uuid.Nil, newDeployError(deployExitFailed, err, "Failed to fill in templated deployment YAMLs")
 	}
 
 	// If extract_path is specified, write out yamls to file.
-	if extractPath != "" {
-		if err := yamlsutils.ExtractYAMLs(yamls, extractPath, "pixie_yamls", yamlsutils.MultiFileExtractYAMLFormat); err != nil {
-			log.WithError(err).Fatal("failed to extract deployment YAMLs")
+	if o.extractPath != "" {
+		if err := yamlsutils.ExtractYAMLs(yamls, o.extractPath, "pixie_yamls", yamlsutils.MultiFileExtractYAMLFormat); err != nil {
+			return uuid.Nil, newDeployError(deployExitFailed, err, "failed to extract deployment YAMLs")
 		}
-		return
+		return uuid.Nil, nil
 	}
 
 	// Map from the YAML name to the YAML contents.
@@ -466,6 +523,12 @@ func runDeployCmd(cmd *cobra.Command, args []string) {
 		yamlMap[y.Name] = y.YAML
 	}
 
+	currentCluster := kubeAPIConfig.CurrentContext
+	if o.plan {
+		r.infof(map[string]interface{}{"cluster": currentCluster}, "Planning deploy of Pixie to the following cluster: %s", currentCluster)
+		return uuid.Nil, planDeploy(kubeConfig, yamlMap, o, r)
+	}
+
 	_ = pxanalytics.Client().Enqueue(&analytics.Track{
 		UserId: pxconfig.Cfg().UniqueClientID,
 		Event:  "Deploy Initiated",
@@ -480,35 +543,74 @@ func runDeployCmd(cmd *cobra.Command, args []string) {
 			Set("cloud_addr", cloudAddr),
 	})
 
-	currentCluster := kubeAPIConfig.CurrentContext
-	utils.Infof("Deploying Pixie to the following cluster: %s", currentCluster)
-	clusterOk := components.YNPrompt("Is the cluster correct?", true)
-	if !clusterOk {
-		utils.Error("Cluster is not correct. Aborting.")
-		return
+	r.infof(map[string]interface{}{"cluster": currentCluster}, "Deploying Pixie to the following cluster: %s", currentCluster)
+	if !r.confirm("Is the cluster correct?", true) {
+		return uuid.Nil, newDeployError(deployExitAborted, nil, "Cluster is not correct. Aborting.")
 	}
 
 	// Get the number of nodes.
 	numNodes, err := getNumNodes(clientset)
 	if err != nil {
-		utils.Error(err.Error())
+		return uuid.Nil, newDeployError(deployExitKubernetes, err, "Failed to list the cluster's nodes")
 	}
 	if numNodes == 0 {
-		utils.Error("Cluster has no nodes. Try deploying Pixie to a cluster with at least one node.")
-		return
+		return uuid.Nil, newDeployError(deployExitClusterCheck, nil, "Cluster has no nodes. Try deploying Pixie to a cluster with at least one node.")
 	}
 
-	utils.Infof("Found %v nodes", numNodes)
+	r.infof(map[string]interface{}{"nodes": numNodes}, "Found %v nodes", numNodes)
 
-	clusterID := deploy(cloudConn, clientset, vzClient, kubeConfig, yamlMap, deployOLM, olmNamespace, olmOperatorNamespace, namespace)
+	clusterID, err := deploy(r, cloudConn, clientset, vzClient, kubeConfig, yamlMap, o.deployOLM, o.olmNamespace, o.olmOperatorNamespace, o.namespace)
+	if err != nil {
+		return uuid.Nil, err
+	}
 
-	waitForHealthCheck(cloudAddr, clusterID, clientset, namespace, numNodes)
+	if err := waitForHealthCheck(r, cloudAddr, clusterID, clientset, o.namespace, numNodes); err != nil {
+		return uuid.Nil, err
+	}
+	return clusterID, nil
+}
 
-	cmd.Annotations = make(map[string]string)
-	cmd.Annotations["status"] = DeploySuccess
+// runDeployChecks checks whether the cluster can run Pixie.
+func runDeployChecks(o *deployOptions, r *deployReporter) error {
+	_ = pxanalytics.Client().Enqueue(&analytics.Track{
+		UserId: pxconfig.Cfg().UniqueClientID,
+		Event:  "Cluster Check Run",
+	})
+
+	err := r.runClusterChecks(false)
+	if err != nil {
+		_ = pxanalytics.Client().Enqueue(&analytics.Track{
+			UserId: pxconfig.Cfg().UniqueClientID,
+			Event:  "Cluster Check Failed",
+			Properties: analytics.NewProperties().
+				Set("error", err.Error()),
+		})
+		return newDeployError(deployExitClusterCheck, err, "Check pre-check has failed. To bypass pass in --check=false.")
+	}
+
+	if o.checkOnly {
+		r.infof(nil, "All Required Checks Passed!")
+		return nil
+	}
+
+	err = r.runClusterChecks(true)
+	if err != nil {
+		if !r.confirm("Some cluster checks failed. Pixie may not work properly on your cluster. Continue with deploy?", true) {
+			return newDeployError(deployExitAborted, nil, "Deploy cancelled. Aborting...")
+		}
+	}
+	return nil
+}
+
+// deployYAMLOrder returns the names of the deploy YAMLs in the order they are applied.
+func deployYAMLOrder(deployOLM bool) []string {
+	if deployOLM {
+		return []string{"olm_crd", "olm", "px_olm", "vizier_crd", "catalog", "subscription", "vizier"}
+	}
+	return []string{"vizier_crd", "px_olm", "catalog", "subscription", "vizier"}
 }
 
-func deploy(cloudConn *grpc.ClientConn, clientset *kubernetes.Clientset, vzClient *versioned.Clientset, kubeConfig *rest.Config, yamlMap map[string]string, deployOLM bool, olmNs, olmOpNs, namespace string) uuid.UUID {
+func deploy(r *deployReporter, cloudConn *grpc.ClientConn, clientset *kubernetes.Clientset, vzClient *versioned.Clientset, kubeConfig *rest.Config, yamlMap map[string]string, deployOLM bool, olmNs, olmOpNs, namespace string) (uuid.UUID, error) {
 	olmCRDJob := newTaskWrapper("Installing OLM CRDs", func() error {
 		return retryDeploy(clientset, kubeConfig, yamlMap["olm_crd"])
 	})
@@ -561,8 +663,7 @@ func deploy(cloudConn *grpc.ClientConn, clientset *kubernetes.Clientset, vzClien
 		for !clusterIDExists { // Wait for secret to be updated with clusterID.
 			select {
 			case <-ctx.Done():
-				// Using log.Fatal rather than CLI log in order to track this unexpected error in Sentry.
-				log.Fatal("Timed out waiting for cluster ID assignment")
+				return errors.New("timed out waiting for cluster ID assignment")
 			case <-t.C:
 				s := k8s.GetSecret(clientset, namespace, "pl-cluster-secrets")
 				if s == nil {
@@ -588,7 +689,7 @@ func deploy(cloudConn *grpc.ClientConn, clientset *kubernetes.Clientset, vzClien
 		}
 	}
 
-	jr := utils.NewSerialTaskRunner(deployJobs)
+	jr := r.taskRunner("deploy", deployJobs)
 	err := jr.RunAndMonitor()
 	if err != nil {
 		_ = pxanalytics.Client().Enqueue(&analytics.Track{
@@ -597,11 +698,10 @@ func deploy(cloudConn *grpc.ClientConn, clientset *kubernetes.Clientset, vzClien
 			Properties: analytics.NewProperties().
 				Set("err", err.Error()),
 		})
-		// Using log.Fatal rather than CLI log in order to track this error in Sentry.
-		log.WithError(err).Fatal("Failed to deploy Vizier")
+		return uuid.Nil, newDeployError(deployExitKubernetes, err, "Failed to deploy Vizier")
 	}
 
-	return clusterID
+	return clusterID, nil
 }
 
 func runSimpleHealthCheckScript(cloudAddr string, clusterID uuid.UUID) error {
@@ -678,8 +778,8 @@ func waitForHealthCheckTaskGenerator(cloudAddr string, clusterID uuid.UUID) func
 	}
 }
 
-func waitForHealthCheck(cloudAddr string, clusterID uuid.UUID, clientset *kubernetes.Clientset, namespace string, numNodes int) {
-	utils.Info("Waiting for Pixie to pass healthcheck")
+func waitForHealthCheck(r *deployReporter, cloudAddr string, clusterID uuid.UUID, clientset *kubernetes.Clientset, namespace string, numNodes int) error {
+	r.infof(nil, "Waiting for Pixie to pass healthcheck")
 
 	healthCheckJobs := []utils.Task{
 		newTaskWrapper("Wait for PEMs/Kelvin", func() error {
@@ -688,7 +788,7 @@ func waitForHealthCheck(cloudAddr string, clusterID uuid.UUID, clientset *kubern
 		newTaskWrapper("Wait for healthcheck", waitForHealthCheckTaskGenerator(cloudAddr, clusterID)),
 	}
 
-	hc := utils.NewSerialTaskRunner(healthCheckJobs)
+	hc := r.taskRunner("healthcheck", healthCheckJobs)
 	err := hc.RunAndMonitor()
 	if err != nil {
 		_ = pxanalytics.Client().Enqueue(&analytics.Track{
@@ -697,12 +797,13 @@ func waitForHealthCheck(cloudAddr string, clusterID uuid.UUID, clientset *kubern
 			Properties: analytics.NewProperties().
 				Set("err", err.Error()),
 		})
-		utils.WithError(err).Fatal("Failed Pixie healthcheck")
+		return newDeployError(deployExitHealthCheck, err, "Failed Pixie healthcheck")
 	}
 	_ = pxanalytics.Client().Enqueue(&analytics.Track{
 		UserId: pxconfig.Cfg().UniqueClientID,
 		Event:  "Deploy Healthcheck Passed",
 	})
+	return nil
 }
 
 func waitForCluster(ctx context.Context, conn *grpc.ClientConn, clusterID uuid.UUID) error {
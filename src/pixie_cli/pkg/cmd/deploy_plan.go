@@ -0,0 +1,438 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package cmd
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/pmezard/go-difflib/difflib"
+	v1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	k8syaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
+	"sigs.k8s.io/yaml"
+
+	"px.dev/pixie/src/pixie_cli/pkg/utils"
+)
+
+const (
+	// planDeployKeyPlaceholder is used as the deploy key when planning a deploy without a key, so
+	// that planning doesn't create one.
+	planDeployKeyPlaceholder = "<generated-deploy-key>"
+	// planFieldManager is the field manager of the server-side dry-run apply.
+	planFieldManager = "px-cli"
+)
+
+type planAction string
+
+const (
+	planCreate    planAction = "create"
+	planUpdate    planAction = "update"
+	planUnchanged planAction = "unchanged"
+)
+
+// objectPlan is the change that a deploy would make to a single Kubernetes object.
+type objectPlan struct {
+	// yaml is the name of the deploy YAML that the object is defined in.
+	yaml      string
+	kind      string
+	namespace string
+	name      string
+	action    planAction
+	diff      string
+	// note explains why the object could not be checked with a server-side dry run.
+	note string
+}
+
+func (p *objectPlan) String() string {
+	if p.namespace == "" {
+		return fmt.Sprintf("%s %s", p.kind, p.name)
+	}
+	return fmt.Sprintf("%s %s/%s", p.kind, p.namespace, p.name)
+}
+
+func (p *objectPlan) eventData() map[string]interface{} {
+	data := map[string]interface{}{
+		"yaml":   p.yaml,
+		"kind":   p.kind,
+		"name":   p.name,
+		"action": string(p.action),
+	}
+	if p.namespace != "" {
+		data["namespace"] = p.namespace
+	}
+	if p.diff != "" {
+		data["diff"] = p.diff
+	}
+	if p.note != "" {
+		data["note"] = p.note
+	}
+	return data
+}
+
+// deployPlanner computes the changes that applying the deploy YAMLs would make to the cluster, using
+// server-side dry runs.
+type deployPlanner struct {
+	client dynamic.Interface
+	mapper meta.RESTMapper
+	// pendingNamespaces are namespaces that don't exist yet, but are created by earlier objects in the plan.
+	pendingNamespaces map[string]bool
+}
+
+func newDeployPlanner(config *rest.Config) (*deployPlanner, error) {
+	dc, err := discovery.NewDiscoveryClientForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	client, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return &deployPlanner{
+		client:            client,
+		mapper:            restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(dc)),
+		pendingNamespaces: make(map[string]bool),
+	}, nil
+}
+
+// planDeploy shows the changes that the deploy would make to the cluster.
+func planDeploy(kubeConfig *rest.Config, yamlMap map[string]string, o *deployOptions, r *deployReporter) error {
+	planner, err := newDeployPlanner(kubeConfig)
+	if err != nil {
+		return newDeployError(deployExitKubernetes, err, "Failed to connect to the cluster")
+	}
+
+	// The deploy creates the Vizier namespace just before it deploys Vizier.
+	nsObj := &unstructured.Unstructured{}
+	nsObj.SetGroupVersionKind(v1.SchemeGroupVersion.WithKind("Namespace"))
+	nsObj.SetName(o.namespace)
+
+	ctx := context.Background()
+	var plans []*objectPlan
+	for _, name := range deployYAMLOrder(o.deployOLM) {
+		if name == "vizier" {
+			p, err := planner.planObject(ctx, nsObj)
+			if err != nil {
+				return newDeployError(deployExitKubernetes, err, "Failed to plan deploy")
+			}
+			p.yaml = "namespace"
+			plans = append(plans, p)
+		}
+		objs, err := parseYAMLObjects(strings.NewReader(yamlMap[name]))
+		if err != nil {
+			return newDeployError(deployExitFailed, err, fmt.Sprintf("Failed to parse the %s YAML", name))
+		}
+		for _, obj := range objs {
+			p, err := planner.planObject(ctx, obj)
+			if err != nil {
+				return newDeployError(deployExitKubernetes, err, "Failed to plan deploy")
+			}
+			p.yaml = name
+			plans = append(plans, p)
+		}
+	}
+
+	if r.jsonOutput() {
+		for _, p := range plans {
+			_ = r.events.Write(&utils.Event{Phase: "plan", Step: p.String(), Status: utils.EventInfo, Data: p.eventData()})
+		}
+		return nil
+	}
+	printDeployPlan(os.Stdout, plans)
+	return nil
+}
+
+// printDeployPlan prints the changes of each object, followed by a summary.
+func printDeployPlan(w io.Writer, plans []*objectPlan) {
+	counts := make(map[planAction]int)
+	for _, p := range plans {
+		counts[p.action]++
+		switch p.action {
+		case planCreate:
+			color.New(color.FgGreen).Fprintf(w, "+ %s (create)\n", p)
+		case planUpdate:
+			color.New(color.FgYellow).Fprintf(w, "~ %s (update)\n", p)
+		default:
+			fmt.Fprintf(w, "  %s (unchanged)\n", p)
+		}
+		if p.note != "" {
+			fmt.Fprintf(w, "    # %s\n", p.note)
+		}
+		if p.action == planUpdate {
+			for _, l := range strings.SplitAfter(strings.TrimSuffix(p.diff, "\n"), "\n") {
+				switch {
+				case strings.HasPrefix(l, "+"):
+					color.New(color.FgGreen).Fprint(w, "    "+l)
+				case strings.HasPrefix(l, "-"):
+					color.New(color.FgRed).Fprint(w, "    "+l)
+				default:
+					fmt.Fprint(w, "    "+l)
+				}
+			}
+			fmt.Fprintln(w)
+		}
+	}
+	fmt.Fprintf(w, "\nPlan: %d to create, %d to update, %d unchanged.\n",
+		counts[planCreate], counts[planUpdate], counts[planUnchanged])
+}
+
+// planObject dry runs the apply of the object against the cluster, and returns the changes it makes.
+func (p *deployPlanner) planObject(ctx context.Context, obj *unstructured.Unstructured) (*objectPlan, error) {
+	gvk := obj.GroupVersionKind()
+	op := &objectPlan{
+		kind:      gvk.Kind,
+		namespace: obj.GetNamespace(),
+		name:      obj.GetName(),
+	}
+
+	mapping, err := p.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if meta.IsNoMatchError(err) {
+		// The resource type is defined by a CRD that is installed by an earlier step of the deploy.
+		op.action = planCreate
+		op.note = fmt.Sprintf("%s is not installed on the cluster yet, so the object is not validated", gvk.Kind)
+		op.diff, err = objectDiff(nil, obj)
+		return op, err
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var ri dynamic.ResourceInterface
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		if op.namespace == "" {
+			op.namespace = metav1.NamespaceDefault
+		}
+		ri = p.client.Resource(mapping.Resource).Namespace(op.namespace)
+	} else {
+		op.namespace = ""
+		ri = p.client.Resource(mapping.Resource)
+	}
+
+	live, err := ri.Get(ctx, op.name, metav1.GetOptions{})
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return nil, fmt.Errorf("failed to get %s: %w", op, err)
+	}
+	if err != nil {
+		op.action = planCreate
+		if gvk.Kind == "Namespace" {
+			p.pendingNamespaces[op.name] = true
+		}
+		if p.pendingNamespaces[op.namespace] {
+			op.note = fmt.Sprintf("namespace %s is created by the deploy, so the object is not validated", op.namespace)
+			op.diff, err = objectDiff(nil, obj)
+			return op, err
+		}
+		created, err := ri.Create(ctx, obj, metav1.CreateOptions{
+			DryRun:       []string{metav1.DryRunAll},
+			FieldManager: planFieldManager,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("dry run create of %s failed: %w", op, err)
+		}
+		op.diff, err = objectDiff(nil, created)
+		return op, err
+	}
+
+	data, err := obj.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	force := true
+	applied, err := ri.Patch(ctx, op.name, types.ApplyPatchType, data, metav1.PatchOptions{
+		DryRun:       []string{metav1.DryRunAll},
+		FieldManager: planFieldManager,
+		Force:        &force,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("dry run apply of %s failed: %w", op, err)
+	}
+	// The placeholder deploy key always differs from the key on the cluster, but the deploy generates a
+	// new key anyway, so it isn't reported as a change.
+	for _, key := range placeholderSecretKeys(obj) {
+		removeSecretKey(live, key)
+		removeSecretKey(applied, key)
+	}
+	op.diff, err = objectDiff(live, applied)
+	if err != nil {
+		return nil, err
+	}
+	op.action = planUpdate
+	if op.diff == "" {
+		op.action = planUnchanged
+	}
+	return op, nil
+}
+
+// parseYAMLObjects parses the Kubernetes objects in a multi-document YAML.
+func parseYAMLObjects(r io.Reader) ([]*unstructured.Unstructured, error) {
+	dec := k8syaml.NewYAMLOrJSONDecoder(r, 4096)
+	var objs []*unstructured.Unstructured
+	for {
+		var m map[string]interface{}
+		err := dec.Decode(&m)
+		if errors.Is(err, io.EOF) {
+			return objs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(m) == 0 {
+			continue
+		}
+		// Round-trip through JSON so that numbers are decoded as the types that Kubernetes expects.
+		b, err := json.Marshal(m)
+		if err != nil {
+			return nil, err
+		}
+		obj := &unstructured.Unstructured{}
+		if err := obj.UnmarshalJSON(b); err != nil {
+			return nil, err
+		}
+		objs = append(objs, obj)
+	}
+}
+
+// placeholderSecretKeys returns the keys of the Secret whose value is the placeholder deploy key.
+func placeholderSecretKeys(obj *unstructured.Unstructured) []string {
+	if obj.GetKind() != "Secret" {
+		return nil
+	}
+	var keys []string
+	stringData, _, _ := unstructured.NestedStringMap(obj.Object, "stringData")
+	for k, v := range stringData {
+		if v == planDeployKeyPlaceholder {
+			keys = append(keys, k)
+		}
+	}
+	encoded := base64.StdEncoding.EncodeToString([]byte(planDeployKeyPlaceholder))
+	data, _, _ := unstructured.NestedStringMap(obj.Object, "data")
+	for k, v := range data {
+		if v == encoded {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// removeSecretKey removes the key from the data of the Secret, so that it is left out of diffs.
+func removeSecretKey(obj *unstructured.Unstructured, key string) {
+	unstructured.RemoveNestedField(obj.Object, "data", key)
+	unstructured.RemoveNestedField(obj.Object, "stringData", key)
+}
+
+// ignoredMetadataFields are set by the API server, and are left out of diffs.
+var ignoredMetadataFields = []string{
+	"managedFields",
+	"resourceVersion",
+	"generation",
+	"uid",
+	"creationTimestamp",
+	"selfLink",
+}
+
+// normalizedYAML returns the object as YAML, without the fields that are managed by the API server.
+// Secret values are replaced by a hash so that changes are visible, but the values are not.
+func normalizedYAML(obj *unstructured.Unstructured) (string, error) {
+	if obj == nil {
+		return "", nil
+	}
+	o := obj.DeepCopy()
+	unstructured.RemoveNestedField(o.Object, "status")
+	for _, f := range ignoredMetadataFields {
+		unstructured.RemoveNestedField(o.Object, "metadata", f)
+	}
+	annotations := o.GetAnnotations()
+	delete(annotations, v1.LastAppliedConfigAnnotation)
+	if len(annotations) == 0 {
+		unstructured.RemoveNestedField(o.Object, "metadata", "annotations")
+	} else {
+		o.SetAnnotations(annotations)
+	}
+	if o.GetKind() == "Secret" {
+		for _, field := range []string{"data", "stringData"} {
+			values, ok := o.Object[field].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			for k, v := range values {
+				sum := sha256.Sum256([]byte(fmt.Sprint(v)))
+				values[k] = fmt.Sprintf("<redacted sha256:%x>", sum[:8])
+			}
+		}
+	}
+	b, err := yaml.Marshal(o.Object)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// objectDiff returns a unified diff between the two versions of the object, or an empty string if
+// they are the same. A nil object is treated as empty.
+func objectDiff(before, after *unstructured.Unstructured) (string, error) {
+	a, err := normalizedYAML(before)
+	if err != nil {
+		return "", err
+	}
+	b, err := normalizedYAML(after)
+	if err != nil {
+		return "", err
+	}
+	if a == b {
+		return "", nil
+	}
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        diffLines(a),
+		B:        diffLines(b),
+		FromFile: "live",
+		ToFile:   "planned",
+		Context:  3,
+	})
+}
+
+// diffLines splits the text into lines for a diff, keeping the line endings.
+func diffLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(s, "\n")
+	if last := len(lines) - 1; lines[last] == "" {
+		lines = lines[:last]
+	} else {
+		lines[last] += "\n"
+	}
+	return lines
+}
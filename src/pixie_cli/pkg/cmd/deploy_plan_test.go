@@ -0,0 +1,507 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package cmd
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func mustParseObject(t *testing.T, yaml string) *unstructured.Unstructured {
+	objs, err := parseYAMLObjects(strings.NewReader(yaml))
+	require.NoError(t, err)
+	require.Len(t, objs, 1)
+	return objs[0]
+}
+
+func TestParseYAMLObjects(t *testing.T) {
+	tests := []struct {
+		name      string
+		yaml      string
+		wantKinds []string
+		wantErr   bool
+	}{
+		{
+			name:      "empty",
+			yaml:      "",
+			wantKinds: nil,
+		},
+		{
+			name: "multiple documents",
+			yaml: `
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: pl
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: pl-cloud-config
+  namespace: pl
+data:
+  PL_CLOUD_ADDR: withpixie.ai:443
+`,
+			wantKinds: []string{"Namespace", "ConfigMap"},
+		},
+		{
+			name: "empty documents are skipped",
+			yaml: `
+---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: pl
+---
+---
+`,
+			wantKinds: []string{"Namespace"},
+		},
+		{
+			name:    "invalid yaml",
+			yaml:    "kind: [Namespace",
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			objs, err := parseYAMLObjects(strings.NewReader(test.yaml))
+			if test.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			var kinds []string
+			for _, obj := range objs {
+				kinds = append(kinds, obj.GetKind())
+			}
+			assert.Equal(t, test.wantKinds, kinds)
+		})
+	}
+}
+
+func TestParseYAMLObjects_Numbers(t *testing.T) {
+	obj := mustParseObject(t, `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: kelvin
+spec:
+  replicas: 2
+`)
+	replicas, found, err := unstructured.NestedInt64(obj.Object, "spec", "replicas")
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, int64(2), replicas)
+}
+
+func TestNormalizedYAML(t *testing.T) {
+	tests := []struct {
+		name       string
+		yaml       string
+		want       string
+		contains   []string
+		notContain []string
+	}{
+		{
+			name: "server managed fields are removed",
+			yaml: `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: pl-cloud-config
+  resourceVersion: "123"
+  uid: 9fa3c2a0-2c4b-4c1e-9f55-6c4ffb7c1d2e
+  generation: 4
+  creationTimestamp: "2021-01-01T00:00:00Z"
+  annotations:
+    kubectl.kubernetes.io/last-applied-configuration: "{}"
+status:
+  phase: Active
+`,
+			want: "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: pl-cloud-config\n",
+		},
+		{
+			name: "other annotations are kept",
+			yaml: `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: pl-cloud-config
+  annotations:
+    kubectl.kubernetes.io/last-applied-configuration: "{}"
+    px.dev/owner: cli
+`,
+			contains:   []string{"px.dev/owner: cli"},
+			notContain: []string{"last-applied-configuration"},
+		},
+		{
+			name: "secret values are redacted",
+			yaml: `
+apiVersion: v1
+kind: Secret
+metadata:
+  name: pl-deploy-secrets
+data:
+  deploy-key: c3VwZXItc2VjcmV0LWtleQ==
+stringData:
+  cluster-name: my-secret-cluster
+`,
+			contains:   []string{"deploy-key: <redacted sha256:", "cluster-name: <redacted sha256:"},
+			notContain: []string{"c3VwZXItc2VjcmV0LWtleQ==", "my-secret-cluster"},
+		},
+		{
+			name: "configmap values are not redacted",
+			yaml: `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: pl-cloud-config
+data:
+  PL_CLOUD_ADDR: withpixie.ai:443
+`,
+			contains: []string{"PL_CLOUD_ADDR: withpixie.ai:443"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			obj := mustParseObject(t, test.yaml)
+			out, err := normalizedYAML(obj)
+			require.NoError(t, err)
+			if test.want != "" {
+				assert.Equal(t, test.want, out)
+			}
+			for _, s := range test.contains {
+				assert.Contains(t, out, s)
+			}
+			for _, s := range test.notContain {
+				assert.NotContains(t, out, s)
+			}
+		})
+	}
+}
+
+func TestNormalizedYAML_DoesNotModifyObject(t *testing.T) {
+	obj := mustParseObject(t, `
+apiVersion: v1
+kind: Secret
+metadata:
+  name: pl-deploy-secrets
+  resourceVersion: "123"
+data:
+  deploy-key: c3VwZXItc2VjcmV0LWtleQ==
+`)
+	_, err := normalizedYAML(obj)
+	require.NoError(t, err)
+	assert.Equal(t, "123", obj.GetResourceVersion())
+	key, _, _ := unstructured.NestedString(obj.Object, "data", "deploy-key")
+	assert.Equal(t, "c3VwZXItc2VjcmV0LWtleQ==", key)
+}
+
+func TestNormalizedYAML_Nil(t *testing.T) {
+	out, err := normalizedYAML(nil)
+	require.NoError(t, err)
+	assert.Equal(t, "", out)
+}
+
+func TestObjectDiff(t *testing.T) {
+	configMap := `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: pl-cloud-config
+  namespace: pl
+data:
+  PL_CLOUD_ADDR: withpixie.ai:443
+`
+
+	tests := []struct {
+		name       string
+		before     string
+		after      string
+		wantEmpty  bool
+		contains   []string
+		notContain []string
+	}{
+		{
+			name:      "unchanged",
+			before:    configMap,
+			after:     configMap,
+			wantEmpty: true,
+		},
+		{
+			name:   "only server managed fields changed",
+			before: configMap,
+			after: `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: pl-cloud-config
+  namespace: pl
+  resourceVersion: "456"
+data:
+  PL_CLOUD_ADDR: withpixie.ai:443
+`,
+			wantEmpty: true,
+		},
+		{
+			name:   "created",
+			before: "",
+			after:  configMap,
+			contains: []string{
+				"--- live\n",
+				"+++ planned\n",
+				"+kind: ConfigMap\n",
+				"+  PL_CLOUD_ADDR: withpixie.ai:443\n",
+			},
+		},
+		{
+			name:   "value changed",
+			before: configMap,
+			after: `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: pl-cloud-config
+  namespace: pl
+data:
+  PL_CLOUD_ADDR: dev.withpixie.dev:443
+`,
+			contains: []string{
+				"-  PL_CLOUD_ADDR: withpixie.ai:443\n",
+				"+  PL_CLOUD_ADDR: dev.withpixie.dev:443\n",
+			},
+		},
+		{
+			name: "secret data never appears",
+			before: `
+apiVersion: v1
+kind: Secret
+metadata:
+  name: pl-deploy-secrets
+  namespace: pl
+data:
+  deploy-key: b2xkLXNlY3JldC1rZXk=
+stringData:
+  token: old-plain-token
+`,
+			after: `
+apiVersion: v1
+kind: Secret
+metadata:
+  name: pl-deploy-secrets
+  namespace: pl
+data:
+  deploy-key: bmV3LXNlY3JldC1rZXk=
+stringData:
+  token: new-plain-token
+`,
+			contains: []string{
+				"-  deploy-key: <redacted sha256:",
+				"+  deploy-key: <redacted sha256:",
+				"-  token: <redacted sha256:",
+				"+  token: <redacted sha256:",
+			},
+			notContain: []string{
+				"b2xkLXNlY3JldC1rZXk=",
+				"bmV3LXNlY3JldC1rZXk=",
+				"old-secret-key",
+				"new-secret-key",
+				"old-plain-token",
+				"new-plain-token",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var before, after *unstructured.Unstructured
+			if test.before != "" {
+				before = mustParseObject(t, test.before)
+			}
+			if test.after != "" {
+				after = mustParseObject(t, test.after)
+			}
+			diff, err := objectDiff(before, after)
+			require.NoError(t, err)
+			if test.wantEmpty {
+				assert.Equal(t, "", diff)
+				return
+			}
+			assert.NotEqual(t, "", diff)
+			for _, s := range test.contains {
+				assert.Contains(t, diff, s)
+			}
+			for _, s := range test.notContain {
+				assert.NotContains(t, diff, s)
+			}
+		})
+	}
+}
+
+func TestDiffLines(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want []string
+	}{
+		{
+			name: "empty",
+			in:   "",
+			want: nil,
+		},
+		{
+			name: "trailing newline",
+			in:   "a: 1\nb: 2\n",
+			want: []string{"a: 1\n", "b: 2\n"},
+		},
+		{
+			name: "no trailing newline",
+			in:   "a: 1\nb: 2",
+			want: []string{"a: 1\n", "b: 2\n"},
+		},
+		{
+			name: "blank lines are kept",
+			in:   "a: 1\n\nb: 2\n",
+			want: []string{"a: 1\n", "\n", "b: 2\n"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.want, diffLines(test.in))
+		})
+	}
+}
+
+func TestPlaceholderSecretKeys(t *testing.T) {
+	encoded := base64.StdEncoding.EncodeToString([]byte(planDeployKeyPlaceholder))
+
+	tests := []struct {
+		name string
+		yaml string
+		want []string
+	}{
+		{
+			name: "string data",
+			yaml: `
+apiVersion: v1
+kind: Secret
+metadata:
+  name: pl-deploy-secrets
+stringData:
+  deploy-key: "` + planDeployKeyPlaceholder + `"
+  cluster-name: my-cluster
+`,
+			want: []string{"deploy-key"},
+		},
+		{
+			name: "encoded data",
+			yaml: `
+apiVersion: v1
+kind: Secret
+metadata:
+  name: pl-deploy-secrets
+data:
+  deploy-key: ` + encoded + `
+`,
+			want: []string{"deploy-key"},
+		},
+		{
+			name: "real deploy key",
+			yaml: `
+apiVersion: v1
+kind: Secret
+metadata:
+  name: pl-deploy-secrets
+stringData:
+  deploy-key: px-dep-1234
+`,
+			want: nil,
+		},
+		{
+			name: "not a secret",
+			yaml: `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: pl-cloud-config
+data:
+  deploy-key: "` + planDeployKeyPlaceholder + `"
+`,
+			want: nil,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.want, placeholderSecretKeys(mustParseObject(t, test.yaml)))
+		})
+	}
+}
+
+func TestObjectDiff_PlaceholderDeployKeyIgnored(t *testing.T) {
+	planned := mustParseObject(t, `
+apiVersion: v1
+kind: Secret
+metadata:
+  name: pl-deploy-secrets
+  namespace: pl
+stringData:
+  deploy-key: "`+planDeployKeyPlaceholder+`"
+`)
+	live := mustParseObject(t, `
+apiVersion: v1
+kind: Secret
+metadata:
+  name: pl-deploy-secrets
+  namespace: pl
+data:
+  deploy-key: cHgtZGVwLTEyMzQ=
+`)
+	// The dry run apply merges the string data into the data of the Secret.
+	applied := mustParseObject(t, `
+apiVersion: v1
+kind: Secret
+metadata:
+  name: pl-deploy-secrets
+  namespace: pl
+data:
+  deploy-key: `+base64.StdEncoding.EncodeToString([]byte(planDeployKeyPlaceholder))+`
+`)
+
+	diff, err := objectDiff(live, applied)
+	require.NoError(t, err)
+	assert.NotEqual(t, "", diff)
+
+	for _, key := range placeholderSecretKeys(planned) {
+		removeSecretKey(live, key)
+		removeSecretKey(applied, key)
+	}
+	diff, err = objectDiff(live, applied)
+	require.NoError(t, err)
+	assert.Equal(t, "", diff)
+}
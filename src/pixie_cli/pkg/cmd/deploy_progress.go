@@ -0,0 +1,172 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"px.dev/pixie/src/pixie_cli/pkg/components"
+	"px.dev/pixie/src/pixie_cli/pkg/utils"
+)
+
+// Exit codes of px deploy. Each class of failure has its own exit code so that scripts can tell
+// them apart, so these must not change.
+const (
+	deployExitFailed       = 1
+	deployExitInvalidFlags = 2
+	deployExitClusterCheck = 3
+	deployExitCloud        = 4
+	deployExitKubernetes   = 5
+	deployExitAborted      = 6
+	deployExitHealthCheck  = 7
+)
+
+// deployError is a deploy failure, with the exit code for its class of failure.
+type deployError struct {
+	code int
+	msg  string
+	err  error
+}
+
+func newDeployError(code int, err error, msg string) *deployError {
+	return &deployError{code: code, msg: msg, err: err}
+}
+
+func (e *deployError) Error() string {
+	if e.err == nil {
+		return e.msg
+	}
+	return fmt.Sprintf("%s: %s", e.msg, e.err.Error())
+}
+
+func (e *deployError) Unwrap() error {
+	return e.err
+}
+
+// unexpected returns whether the failure is unexpected, rather than a problem with the user's input
+// or cluster.
+func (e *deployError) unexpected() bool {
+	return e.code == deployExitFailed || e.code == deployExitCloud || e.code == deployExitKubernetes
+}
+
+// deployReporter reports the progress of a deploy, either as human readable output or as JSON events.
+type deployReporter struct {
+	// events is nil when the output is human readable.
+	events *utils.EventWriter
+}
+
+func newDeployReporter(cmd *cobra.Command) (*deployReporter, error) {
+	format, _ := cmd.Flags().GetString("progress_format")
+	switch format {
+	case "":
+		return &deployReporter{}, nil
+	case "json":
+		return &deployReporter{events: utils.NewEventWriter(os.Stdout)}, nil
+	}
+	return &deployReporter{}, newDeployError(deployExitInvalidFlags, nil, fmt.Sprintf("Invalid --progress_format %q, must be empty or 'json'", format))
+}
+
+func (r *deployReporter) jsonOutput() bool {
+	return r.events != nil
+}
+
+// infof reports a message. The data is only included in JSON events.
+func (r *deployReporter) infof(data map[string]interface{}, format string, args ...interface{}) {
+	if r.jsonOutput() {
+		_ = r.events.Info("deploy", fmt.Sprintf(format, args...), data)
+		return
+	}
+	utils.Infof(format, args...)
+}
+
+// confirm asks the user to confirm. JSON output is never interactive, so the default is used.
+func (r *deployReporter) confirm(message string, defaultValue bool) bool {
+	if r.jsonOutput() || viper.GetBool("y") {
+		if r.jsonOutput() {
+			_ = r.events.Info("deploy", message, map[string]interface{}{"answer": defaultValue})
+		}
+		return defaultValue
+	}
+	return components.YNPrompt(message, defaultValue)
+}
+
+// taskRunner returns the runner for the tasks of the phase.
+func (r *deployReporter) taskRunner(phase string, tasks []utils.Task) utils.TaskRunner {
+	if r.jsonOutput() {
+		return utils.NewEventTaskRunner(tasks, phase, r.events)
+	}
+	return utils.NewSerialTaskRunner(tasks)
+}
+
+// runClusterChecks runs either the default or the extra cluster checks.
+func (r *deployReporter) runClusterChecks(extra bool) error {
+	if r.jsonOutput() {
+		checks := utils.DefaultClusterChecks
+		if extra {
+			checks = utils.ExtraClusterChecks
+		}
+		return utils.RunClusterChecksWithEvents(checks, r.events)
+	}
+	if extra {
+		return utils.RunExtraClusterChecks()
+	}
+	return utils.RunDefaultClusterChecks()
+}
+
+// done reports that the deploy completed successfully.
+func (r *deployReporter) done(data map[string]interface{}) {
+	if r.jsonOutput() {
+		_ = r.events.Write(&utils.Event{Phase: "result", Status: utils.EventSucceeded, Data: data})
+	}
+}
+
+// fail reports the error and exits with the exit code for its class of failure.
+func (r *deployReporter) fail(err error) {
+	de, ok := err.(*deployError)
+	if !ok {
+		de = newDeployError(deployExitFailed, err, "Failed to deploy Pixie")
+	}
+	if r.jsonOutput() {
+		ev := &utils.Event{
+			Phase:   "result",
+			Status:  utils.EventFailed,
+			Message: de.msg,
+			Data:    map[string]interface{}{"exitCode": de.code},
+		}
+		if de.err != nil {
+			ev.Error = de.err.Error()
+		}
+		_ = r.events.Write(ev)
+	}
+	switch {
+	case de.unexpected():
+		// Using log rather than CLI log in order to track this unexpected error in Sentry.
+		log.WithError(de.err).Error(de.msg)
+	case de.err != nil:
+		utils.WithError(de.err).Error(de.msg)
+	default:
+		utils.Error(de.msg)
+	}
+	os.Exit(de.code)
+}
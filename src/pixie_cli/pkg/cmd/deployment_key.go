@@ -24,8 +24,10 @@ import (
 	"os"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/gofrs/uuid"
+	"github.com/gogo/protobuf/types"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -41,16 +43,22 @@ import (
 func init() {
 	DeployKeyCmd.AddCommand(CreateDeployKeyCmd)
 	DeployKeyCmd.AddCommand(DeleteDeployKeyCmd)
+	DeployKeyCmd.AddCommand(RevokeDeployKeyCmd)
 	DeployKeyCmd.AddCommand(ListDeployKeyCmd)
 	DeployKeyCmd.AddCommand(GetDeployKeyCmd)
 	DeployKeyCmd.AddCommand(LookupDeployKeyCmd)
 
 	CreateDeployKeyCmd.Flags().StringP("desc", "d", "", "A description for the deploy key")
 	CreateDeployKeyCmd.Flags().BoolP("short", "s", false, "Return only the created deploy key, for use to pipe to other tools")
+	CreateDeployKeyCmd.Flags().Duration("expires_in", 0, "How long the deploy key can be used for, e.g. 720h. The key never expires if zero")
+	CreateDeployKeyCmd.Flags().Int64("max_clusters", 0, "The maximum number of clusters that can be registered with the deploy key. Unlimited if zero")
+	CreateDeployKeyCmd.Flags().StringSlice("allowed_cluster_names", nil, "Glob patterns that the names of clusters registered with the deploy key must match, e.g. prod-*")
 
 	DeleteDeployKeyCmd.Flags().StringP("id", "i", "", "The deploy key to delete")
 
-	ListDeployKeyCmd.Flags().StringP("output", "o", "", "Output format: one of: json|proto")
+	RevokeDeployKeyCmd.Flags().StringP("id", "i", "", "The deploy key to revoke")
+
+	ListDeployKeyCmd.Flags().StringP("output", "o", "", components.OutputFormatHelp)
 
 	LookupDeployKeyCmd.Flags().StringP("key", "k", "", "Value of the key. Leave blank to be prompted.")
 }
@@ -77,8 +85,20 @@ var CreateDeployKeyCmd = &cobra.Command{
 		cloudAddr := viper.GetString("cloud_addr")
 		desc := viper.GetString("desc")
 		short, _ := cmd.Flags().GetBool("short")
+		expiresIn, _ := cmd.Flags().GetDuration("expires_in")
+		maxClusters, _ := cmd.Flags().GetInt64("max_clusters")
+		allowedClusterNames, _ := cmd.Flags().GetStringSlice("allowed_cluster_names")
+
+		req := &cloudpb.CreateDeploymentKeyRequest{
+			Desc:                desc,
+			MaxClusters:         maxClusters,
+			AllowedClusterNames: allowedClusterNames,
+		}
+		if expiresIn > 0 {
+			req.ExpiresAt, _ = types.TimestampProto(time.Now().Add(expiresIn))
+		}
 
-		keyID, key, err := generateDeployKey(cloudAddr, desc)
+		keyID, key, err := createDeployKey(cloudAddr, req)
 		if err != nil {
 			// Using log.Fatal rather than CLI log in order to track this unexpected error in Sentry.
 			log.WithError(err).Fatal("Failed to generate deployment key")
@@ -120,6 +140,35 @@ var DeleteDeployKeyCmd = &cobra.Command{
 	},
 }
 
+// RevokeDeployKeyCmd is the Revoke sub-command of DeployKey.
+var RevokeDeployKeyCmd = &cobra.Command{
+	Use:   "revoke",
+	Short: "Revoke a deploy key, so that it can no longer be used to register clusters",
+	PreRun: func(cmd *cobra.Command, args []string) {
+		viper.BindPFlag("id", cmd.Flags().Lookup("id"))
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		cloudAddr := viper.GetString("cloud_addr")
+		id, _ := cmd.Flags().GetString("id")
+
+		if id == "" {
+			utils.Fatal("Deployment key ID must be specified using --id flag")
+		}
+
+		idUUID, err := uuid.FromString(id)
+		if err != nil {
+			utils.WithError(err).Fatal("Invalid deployment key ID")
+		}
+
+		err = revokeDeployKey(cloudAddr, idUUID)
+		if err != nil {
+			// Using log.Fatal rather than CLI log in order to track this unexpected error in Sentry.
+			log.WithError(err).Fatal("Failed to revoke deployment key")
+		}
+		utils.Info("Successfully revoked deployment key")
+	},
+}
+
 // ListDeployKeyCmd is the List sub-command of DeployKey.
 var ListDeployKeyCmd = &cobra.Command{
 	Use:   "list",
@@ -129,8 +178,7 @@ var ListDeployKeyCmd = &cobra.Command{
 	},
 	Run: func(cmd *cobra.Command, args []string) {
 		cloudAddr := viper.GetString("cloud_addr")
-		format, _ := cmd.Flags().GetString("output")
-		format = strings.ToLower(format)
+		format := mustGetOutputFormat(cmd)
 
 		keys, err := listDeployKeys(cloudAddr)
 		if err != nil {
@@ -140,10 +188,11 @@ var ListDeployKeyCmd = &cobra.Command{
 		// Throw keys into table.
 		w := components.CreateStreamWriter(format, os.Stdout)
 		defer w.Finish()
-		w.SetHeader("deployment-keys", []string{"ID", "Key", "CreatedAt", "Description"})
+		w.SetHeader("deployment-keys", []string{"ID", "Key", "CreatedAt", "Description", "Status", "Clusters", "ExpiresAt"})
 		for _, k := range keys {
-			_ = w.Write([]interface{}{utils2.UUIDFromProtoOrNil(k.ID), "<hidden>", k.CreatedAt,
-				k.Desc})
+			mustWriteRow(w, []interface{}{utils2.UUIDFromProtoOrNil(k.ID), "<hidden>", k.CreatedAt,
+				k.Desc, deployKeyStatus(k.RevokedAt, k.ExpiresAt, k.MaxClusters, k.NumClusters),
+				deployKeyClusterCount(k.MaxClusters, k.NumClusters), deployKeyExpiry(k.ExpiresAt)})
 		}
 	},
 }
@@ -154,8 +203,7 @@ var LookupDeployKeyCmd = &cobra.Command{
 	Short: "Lookup deployment key based on the value of the key",
 	Run: func(cmd *cobra.Command, args []string) {
 		cloudAddr := viper.GetString("cloud_addr")
-		format, _ := cmd.Flags().GetString("output")
-		format = strings.ToLower(format)
+		format := mustGetOutputFormat(cmd)
 
 		deployKey, err := cmd.Flags().GetString("key")
 		if err != nil || len(deployKey) == 0 {
@@ -176,7 +224,7 @@ var LookupDeployKeyCmd = &cobra.Command{
 		w := components.CreateStreamWriter(format, os.Stdout)
 		defer w.Finish()
 		w.SetHeader("api-keys", []string{"ID", "Key", "CreatedAt", "Description"})
-		_ = w.Write([]interface{}{utils2.UUIDFromProtoOrNil(k.ID), "<hidden>", k.CreatedAt,
+		mustWriteRow(w, []interface{}{utils2.UUIDFromProtoOrNil(k.ID), "<hidden>", k.CreatedAt,
 			k.Desc})
 	},
 }
@@ -187,8 +235,7 @@ var GetDeployKeyCmd = &cobra.Command{
 	Short: "Get deployment key details for a single key",
 	Run: func(cmd *cobra.Command, args []string) {
 		cloudAddr := viper.GetString("cloud_addr")
-		format, _ := cmd.Flags().GetString("output")
-		format = strings.ToLower(format)
+		format := mustGetOutputFormat(cmd)
 
 		if len(args) != 1 {
 			utils.Fatal("Expected a single argument 'key id'.")
@@ -205,13 +252,63 @@ var GetDeployKeyCmd = &cobra.Command{
 		}
 		// Throw keys into table.
 		w := components.CreateStreamWriter(format, os.Stdout)
-		defer w.Finish()
-		w.SetHeader("deployment-keys", []string{"ID", "Key", "CreatedAt", "Description"})
-		_ = w.Write([]interface{}{utils2.UUIDFromProtoOrNil(k.ID), k.Key, k.CreatedAt,
-			k.Desc})
+		w.SetHeader("deployment-keys", []string{"ID", "Key", "CreatedAt", "Description", "Status", "Clusters",
+			"ExpiresAt", "AllowedClusterNames"})
+		mustWriteRow(w, []interface{}{utils2.UUIDFromProtoOrNil(k.ID), k.Key, k.CreatedAt,
+			k.Desc, deployKeyStatus(k.RevokedAt, k.ExpiresAt, k.MaxClusters, k.NumClusters),
+			deployKeyClusterCount(k.MaxClusters, k.NumClusters), deployKeyExpiry(k.ExpiresAt),
+			strings.Join(k.AllowedClusterNames, ",")})
+		w.Finish()
+
+		if len(k.Usages) == 0 {
+			return
+		}
+		// Show the clusters that were registered with the key.
+		cw := components.CreateStreamWriter(format, os.Stdout)
+		defer cw.Finish()
+		cw.SetHeader("deployment-key-clusters", []string{"ClusterID", "ClusterName", "K8sClusterUID", "RegisteredAt"})
+		for _, u := range k.Usages {
+			mustWriteRow(cw, []interface{}{utils2.UUIDFromProtoOrNil(u.ClusterID), u.ClusterName, u.K8sClusterUID,
+				u.RegisteredAt})
+		}
 	},
 }
 
+// deployKeyStatus returns whether the key can still be used to register clusters.
+func deployKeyStatus(revokedAt *types.Timestamp, expiresAt *types.Timestamp, maxClusters int64, numClusters int64) string {
+	if revokedAt != nil {
+		return "Revoked"
+	}
+	if expiresAt != nil {
+		t, err := types.TimestampFromProto(expiresAt)
+		if err == nil && time.Now().After(t) {
+			return "Expired"
+		}
+	}
+	if maxClusters > 0 && numClusters >= maxClusters {
+		return "Exhausted"
+	}
+	return "Active"
+}
+
+func deployKeyClusterCount(maxClusters int64, numClusters int64) string {
+	if maxClusters == 0 {
+		return fmt.Sprintf("%d", numClusters)
+	}
+	return fmt.Sprintf("%d/%d", numClusters, maxClusters)
+}
+
+func deployKeyExpiry(expiresAt *types.Timestamp) string {
+	if expiresAt == nil {
+		return "Never"
+	}
+	t, err := types.TimestampFromProto(expiresAt)
+	if err != nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
 func getClientAndContext(cloudAddr string) (cloudpb.VizierDeploymentKeyManagerClient, context.Context, error) {
 	// Get grpc connection to cloud.
 	cloudConn, err := utils.GetCloudClientConnection(cloudAddr)
@@ -228,12 +325,16 @@ func getClientAndContext(cloudAddr string) (cloudpb.VizierDeploymentKeyManagerCl
 }
 
 func generateDeployKey(cloudAddr string, desc string) (string, string, error) {
+	return createDeployKey(cloudAddr, &cloudpb.CreateDeploymentKeyRequest{Desc: desc})
+}
+
+func createDeployKey(cloudAddr string, req *cloudpb.CreateDeploymentKeyRequest) (string, string, error) {
 	deployMgrClient, ctxWithCreds, err := getClientAndContext(cloudAddr)
 	if err != nil {
 		return "", "", err
 	}
 
-	resp, err := deployMgrClient.Create(ctxWithCreds, &cloudpb.CreateDeploymentKeyRequest{Desc: desc})
+	resp, err := deployMgrClient.Create(ctxWithCreds, req)
 	if err != nil {
 		return "", "", err
 	}
@@ -251,6 +352,16 @@ func deleteDeployKey(cloudAddr string, keyID uuid.UUID) error {
 	return err
 }
 
+func revokeDeployKey(cloudAddr string, keyID uuid.UUID) error {
+	deployMgrClient, ctxWithCreds, err := getClientAndContext(cloudAddr)
+	if err != nil {
+		return err
+	}
+
+	_, err = deployMgrClient.Revoke(ctxWithCreds, utils2.ProtoFromUUID(keyID))
+	return err
+}
+
 func listDeployKeys(cloudAddr string) ([]*cloudpb.DeploymentKeyMetadata, error) {
 	deployMgrClient, ctxWithCreds, err := getClientAndContext(cloudAddr)
 	if err != nil {
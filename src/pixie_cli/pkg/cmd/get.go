@@ -32,6 +32,8 @@ import (
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	"px.dev/pixie/src/pixie_cli/pkg/components"
 	cliUtils "px.dev/pixie/src/pixie_cli/pkg/utils"
@@ -42,7 +44,7 @@ import (
 )
 
 func init() {
-	GetCmd.PersistentFlags().StringP("output", "o", "", "Output format: one of: json|proto")
+	GetCmd.PersistentFlags().StringP("output", "o", "", components.OutputFormatHelp)
 
 	GetPEMsCmd.Flags().BoolP("all-clusters", "d", false, "Run script across all clusters")
 	GetPEMsCmd.Flags().StringP("cluster", "c", "", "Run only on selected cluster")
@@ -52,8 +54,10 @@ func init() {
 	GetClusterCmd.Flags().Bool("cloud-addr", false, "Whether to only fetch the cloud address from the cluster running in the current kubeconfig")
 
 	GetCmd.AddCommand(GetPEMsCmd)
+	GetViziersCmd.Flags().StringP("selector", "l", "", "Label selector to filter viziers by, such as env=prod")
 	GetCmd.AddCommand(GetViziersCmd)
 	GetCmd.AddCommand(GetClusterCmd)
+	GetCmd.AddCommand(GetEventsCmd)
 }
 
 // GetPEMsCmd is the "get pem" command.
@@ -63,8 +67,7 @@ var GetPEMsCmd = &cobra.Command{
 	Short:   "Get information about running pems",
 	Run: func(cmd *cobra.Command, args []string) {
 		cloudAddr := viper.GetString("cloud_addr")
-		format, _ := cmd.Flags().GetString("output")
-		format = strings.ToLower(format)
+		format := mustGetOutputFormat(cmd)
 		br := mustCreateBundleReader()
 		execScript := br.MustGetScript(script.AgentStatusScript)
 
@@ -96,16 +99,19 @@ var GetViziersCmd = &cobra.Command{
 	Short:   "Get information about registered viziers",
 	Run: func(cmd *cobra.Command, args []string) {
 		cloudAddr := viper.GetString("cloud_addr")
-		format, _ := cmd.Flags().GetString("output")
-		format = strings.ToLower(format)
+		format := mustGetOutputFormat(cmd)
 
 		l, err := vizier.NewLister(cloudAddr)
 		if err != nil {
 			// Using log.Fatal rather than CLI log in order to track this unexpected error in Sentry.
 			log.WithError(err).Fatal("Failed to create Vizier lister")
 		}
-		vzs, err := l.GetViziersInfo()
+		selector, _ := cmd.Flags().GetString("selector")
+		vzs, err := l.GetViziersInfoBySelector(selector)
 		if err != nil {
+			if status.Code(err) == codes.InvalidArgument {
+				cliUtils.WithError(err).Fatal("Invalid label selector")
+			}
 			// Using log.Fatal rather than CLI log in order to track this unexpected error in Sentry.
 			log.WithError(err).Fatalln("Failed to get vizier information")
 		}
@@ -114,7 +120,7 @@ var GetViziersCmd = &cobra.Command{
 
 		w := components.CreateStreamWriter(format, os.Stdout)
 		defer w.Finish()
-		w.SetHeader("viziers", []string{"ClusterName", "ID", "K8s Version", "Operator Version", "Vizier Version", "Last Heartbeat", "Status", "Status Message"})
+		w.SetHeader("viziers", []string{"ClusterName", "ID", "K8s Version", "Operator Version", "Vizier Version", "Last Heartbeat", "Status", "Status Message", "Labels"})
 
 		for _, vz := range vzs {
 			var lastHeartbeat interface{}
@@ -126,12 +132,22 @@ var GetViziersCmd = &cobra.Command{
 							time.Since(time.Unix(0, vz.LastHeartbeatNs)).Nanoseconds()))
 				}
 			}
-			_ = w.Write([]interface{}{vz.ClusterName, utils.UUIDFromProtoOrNil(vz.ID), vz.ClusterVersion,
-				prettyVersion(vz.OperatorVersion), prettyVersion(vz.VizierVersion), lastHeartbeat, vz.Status, vz.StatusMessage})
+			mustWriteRow(w, []interface{}{vz.ClusterName, utils.UUIDFromProtoOrNil(vz.ID), vz.ClusterVersion,
+				prettyVersion(vz.OperatorVersion), prettyVersion(vz.VizierVersion), lastHeartbeat, vz.Status, vz.StatusMessage, prettyLabels(vz.Labels)})
 		}
 	},
 }
 
+// prettyLabels formats the labels as a sorted list of key=value pairs.
+func prettyLabels(labels map[string]string) string {
+	pairs := make([]string, 0, len(labels))
+	for k, v := range labels {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", k, v))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
 // prettyVersion returns a pretty form of the version string.
 func prettyVersion(version string) string {
 	sb := strings.Builder{}
@@ -0,0 +1,208 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/gofrs/uuid"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"px.dev/pixie/src/api/proto/cloudpb"
+	"px.dev/pixie/src/pixie_cli/pkg/components"
+	cliUtils "px.dev/pixie/src/pixie_cli/pkg/utils"
+	"px.dev/pixie/src/pixie_cli/pkg/vizier"
+	"px.dev/pixie/src/utils"
+)
+
+func init() {
+	GetEventsCmd.Flags().StringP("cluster", "c", "", "The ID of the cluster to get events for. Defaults to the current cluster")
+	GetEventsCmd.Flags().BoolP("watch", "w", false, "Keep streaming changes as they happen")
+	GetEventsCmd.Flags().StringSliceP("namespace", "n", nil, "Only show changes in these namespaces")
+	GetEventsCmd.Flags().StringSlice("kind", nil, "Only show changes to these kinds of resources: one of: pod|service|namespace|node")
+	GetEventsCmd.Flags().String("name", "", "Only show changes to resources whose name contains this string")
+	GetEventsCmd.Flags().Int64("from-version", 0, "Only show changes after this update version. "+
+		"Defaults to all retained changes, or only new changes when watching")
+}
+
+var resourceKinds = map[string]cloudpb.K8SResourceKind{
+	"pod":        cloudpb.KRK_POD,
+	"pods":       cloudpb.KRK_POD,
+	"po":         cloudpb.KRK_POD,
+	"service":    cloudpb.KRK_SERVICE,
+	"services":   cloudpb.KRK_SERVICE,
+	"svc":        cloudpb.KRK_SERVICE,
+	"namespace":  cloudpb.KRK_NAMESPACE,
+	"namespaces": cloudpb.KRK_NAMESPACE,
+	"ns":         cloudpb.KRK_NAMESPACE,
+	"node":       cloudpb.KRK_NODE,
+	"nodes":      cloudpb.KRK_NODE,
+	"no":         cloudpb.KRK_NODE,
+}
+
+func parseResourceKinds(kinds []string) ([]cloudpb.K8SResourceKind, error) {
+	var parsed []cloudpb.K8SResourceKind
+	for _, k := range kinds {
+		kind, ok := resourceKinds[strings.ToLower(k)]
+		if !ok {
+			return nil, fmt.Errorf("unknown resource kind %q", k)
+		}
+		parsed = append(parsed, kind)
+	}
+	return parsed, nil
+}
+
+var eventsHeader = []string{"Version", "Kind", "Event", "Namespace", "Name", "Phase", "Node", "Started", "Stopped"}
+
+func formatEventTimestamp(ns int64) interface{} {
+	if ns <= 0 {
+		return ""
+	}
+	return time.Unix(0, ns)
+}
+
+func eventRow(ev *cloudpb.ClusterMetadataEvent) []interface{} {
+	return []interface{}{
+		ev.UpdateVersion,
+		strings.TrimPrefix(ev.Kind.String(), "KRK_"),
+		strings.TrimPrefix(ev.Type.String(), "CME_"),
+		ev.Namespace,
+		ev.Name,
+		ev.Phase,
+		ev.NodeName,
+		formatEventTimestamp(ev.StartTimestampNS),
+		formatEventTimestamp(ev.StopTimestampNS),
+	}
+}
+
+// watchTableWriter writes each row as soon as it is received, since the regular table writer only
+// renders once all of the data has been received.
+type watchTableWriter struct {
+	tw *tabwriter.Writer
+}
+
+func newWatchTableWriter(w io.Writer) *watchTableWriter {
+	return &watchTableWriter{tw: tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)}
+}
+
+func (w *watchTableWriter) SetHeader(id string, headerValues []string) {
+	fmt.Fprintln(w.tw, strings.ToUpper(strings.Join(headerValues, "\t")))
+	w.tw.Flush()
+}
+
+func (w *watchTableWriter) Write(data []interface{}) error {
+	vals := make([]string, len(data))
+	for i, d := range data {
+		if t, ok := d.(time.Time); ok {
+			vals[i] = t.Format(time.RFC3339)
+			continue
+		}
+		vals[i] = fmt.Sprintf("%v", d)
+	}
+	fmt.Fprintln(w.tw, strings.Join(vals, "\t"))
+	return w.tw.Flush()
+}
+
+func (w *watchTableWriter) Finish() {}
+
+// GetEventsCmd is the "get events" command.
+var GetEventsCmd = &cobra.Command{
+	Use:   "events",
+	Short: "Get the changes to pods, services, namespaces and nodes in a cluster",
+	Run: func(cmd *cobra.Command, args []string) {
+		cloudAddr := viper.GetString("cloud_addr")
+		format := mustGetOutputFormat(cmd)
+		watch, _ := cmd.Flags().GetBool("watch")
+		namespaces, _ := cmd.Flags().GetStringSlice("namespace")
+		kindFlags, _ := cmd.Flags().GetStringSlice("kind")
+		name, _ := cmd.Flags().GetString("name")
+		fromVersion, _ := cmd.Flags().GetInt64("from-version")
+		if watch && !cmd.Flags().Changed("from-version") {
+			// Only stream new changes, rather than replaying all of the retained history.
+			fromVersion = -1
+		}
+
+		kinds, err := parseResourceKinds(kindFlags)
+		if err != nil {
+			cliUtils.WithError(err).Fatal("Invalid --kind")
+		}
+
+		selectedCluster, _ := cmd.Flags().GetString("cluster")
+		clusterID := uuid.FromStringOrNil(selectedCluster)
+		if clusterID == uuid.Nil {
+			clusterID, err = vizier.GetCurrentVizier(cloudAddr)
+			if err != nil {
+				cliUtils.WithError(err).Fatal("Could not fetch healthy vizier")
+			}
+		}
+
+		l, err := vizier.NewLister(cloudAddr)
+		if err != nil {
+			// Using log.Fatal rather than CLI log in order to track this unexpected error in Sentry.
+			log.WithError(err).Fatal("Failed to create Vizier lister")
+		}
+
+		ctx, cleanup := cliUtils.WithSignalCancellable(context.Background())
+		defer cleanup()
+		stream, err := l.WatchClusterMetadata(ctx, &cloudpb.WatchClusterMetadataRequest{
+			ClusterID:         utils.ProtoFromUUID(clusterID),
+			FromUpdateVersion: fromVersion,
+			Follow:            watch,
+			Namespaces:        namespaces,
+			Kinds:             kinds,
+			Name:              name,
+		})
+		if err != nil {
+			cliUtils.WithError(err).Fatal("Failed to get events")
+		}
+
+		var w components.OutputStreamWriter
+		if watch && (format == "" || format == "table") {
+			w = newWatchTableWriter(os.Stdout)
+		} else {
+			w = components.CreateStreamWriter(format, os.Stdout)
+		}
+		defer w.Finish()
+		w.SetHeader("events", eventsHeader)
+
+		for {
+			resp, err := stream.Recv()
+			if err == io.EOF || status.Code(err) == codes.Canceled {
+				return
+			}
+			if err != nil {
+				cliUtils.WithError(err).Error("Failed to receive events")
+				return
+			}
+			for _, ev := range resp.Events {
+				mustWriteRow(w, eventRow(ev))
+			}
+		}
+	},
+}
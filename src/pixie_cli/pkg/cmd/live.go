@@ -39,6 +39,8 @@ func init() {
 	LiveCmd.Flags().StringP("file", "f", "", "Script file, specify - for STDIN")
 	LiveCmd.Flags().BoolP("new_autocomplete", "n", false, "Whether to use the new autocomplete")
 	LiveCmd.Flags().BoolP("e2e_encryption", "e", true, "Enable E2E encryption")
+	LiveCmd.Flags().Duration("refresh_interval", 0, "How often to re-run the script, e.g. 30s. Disabled if zero")
+	LiveCmd.Flags().String("time_range", "", "Override the start_time of scripts, either as a duration such as 15m or an absolute start time such as '2006-01-02 15:04'")
 
 	LiveCmd.Flags().BoolP("all-clusters", "d", false, "Run script across all clusters")
 	LiveCmd.Flags().StringP("cluster", "c", "", "Run only on selected cluster")
@@ -63,7 +65,7 @@ var LiveCmd = &cobra.Command{
 		if scriptFile == "" {
 			if len(args) > 0 {
 				scriptName := args[0]
-				execScript = br.MustGetScript(scriptName)
+				execScript = mustGetScript(br, scriptName)
 				scriptArgs = args[1:]
 			}
 		} else {
@@ -110,9 +112,18 @@ var LiveCmd = &cobra.Command{
 		}
 
 		useEncryption, _ := cmd.Flags().GetBool("e2e_encryption")
+		refreshInterval, _ := cmd.Flags().GetDuration("refresh_interval")
+		var timeRange *live.TimeRange
+		if tr, _ := cmd.Flags().GetString("time_range"); tr != "" {
+			timeRange, err = live.ParseTimeRange(tr)
+			if err != nil {
+				utils.WithError(err).Fatal("Invalid time range")
+			}
+		}
 
 		viziers := vizier.MustConnectHealthyDefaultVizier(cloudAddr, allClusters, clusterUUID)
-		lv, err := live.New(br, viziers, cloudAddr, aClient, execScript, useNewAC, useEncryption, clusterUUID)
+		lv, err := live.New(br, viziers, cloudAddr, aClient, execScript, useNewAC, useEncryption, clusterUUID,
+			refreshInterval, timeRange)
 		if err != nil {
 			utils.WithError(err).Fatal("Failed to initialize live view")
 		}
@@ -33,6 +33,7 @@ import (
 	"golang.org/x/exp/slices"
 
 	"px.dev/pixie/src/pixie_cli/pkg/auth"
+	"px.dev/pixie/src/pixie_cli/pkg/components"
 	"px.dev/pixie/src/pixie_cli/pkg/pxanalytics"
 	"px.dev/pixie/src/pixie_cli/pkg/pxconfig"
 	"px.dev/pixie/src/pixie_cli/pkg/update"
@@ -62,6 +63,9 @@ func init() {
 	RootCmd.PersistentFlags().BoolP("y", "y", false, "Whether to accept all user input")
 	viper.BindPFlag("y", RootCmd.PersistentFlags().Lookup("y"))
 
+	RootCmd.PersistentFlags().Bool("no-headers", false, "Don't print headers for table, csv and custom-columns output")
+	viper.BindPFlag("no-headers", RootCmd.PersistentFlags().Lookup("no-headers"))
+
 	RootCmd.PersistentFlags().BoolP("quiet", "q", false, "quiet mode")
 	viper.BindPFlag("quiet", RootCmd.PersistentFlags().Lookup("quiet"))
 
@@ -71,17 +75,23 @@ func init() {
 	RootCmd.PersistentFlags().String("direct_vizier_addr", "", "If set, connect directly to the Vizier service at the given address.")
 	viper.BindPFlag("direct_vizier_addr", RootCmd.PersistentFlags().Lookup("direct_vizier_addr"))
 
+	RootCmd.PersistentFlags().String("context", "", "The name of the CLI context to use. Overrides the current context set by `px config use-context`.")
+	viper.BindPFlag("context", RootCmd.PersistentFlags().Lookup("context"))
+
 	RootCmd.PersistentFlags().String("direct_vizier_key", "", "Should be set if direct_vizier_addr is set, the key to authenticate whether the user has permissions to connect to the Vizier service.")
 	viper.BindPFlag("direct_vizier_key", RootCmd.PersistentFlags().Lookup("direct_vizier_key"))
 
 	RootCmd.AddCommand(VersionCmd)
 	RootCmd.AddCommand(AuthCmd)
 	RootCmd.AddCommand(CollectLogsCmd)
+	RootCmd.AddCommand(SupportBundleCmd)
 	RootCmd.AddCommand(CreateCloudCertsCmd)
 	RootCmd.AddCommand(DemoCmd)
 	RootCmd.AddCommand(DeployCmd)
+	RootCmd.AddCommand(CheckCmd)
 	RootCmd.AddCommand(DeleteCmd)
 	RootCmd.AddCommand(UpdateCmd)
+	RootCmd.AddCommand(UpdatePolicyCmd)
 	RootCmd.AddCommand(RunCmd)
 	RootCmd.AddCommand(LiveCmd)
 	RootCmd.AddCommand(GetCmd)
@@ -90,6 +100,7 @@ func init() {
 	RootCmd.AddCommand(DeployKeyCmd)
 	RootCmd.AddCommand(APIKeyCmd)
 	RootCmd.AddCommand(DebugCmd)
+	RootCmd.AddCommand(ConfigCmd)
 
 	RootCmd.PersistentFlags().MarkHidden("cloud_addr")
 	RootCmd.PersistentFlags().MarkHidden("dev_cloud_namespace")
@@ -106,6 +117,7 @@ func init() {
 	viper.BindEnv("vizier_version", "PX_VIZIER_VERSION", "PL_VIZIER_VERSION")
 	viper.BindEnv("direct_vizier_key", "PX_DIRECT_VIZIER_KEY")
 	viper.BindEnv("direct_vizier_addr", "PX_DIRECT_VIZIER_ADDR")
+	viper.BindEnv("context", "PX_CONTEXT")
 
 	viper.BindPFlags(pflag.CommandLine)
 
@@ -144,6 +156,7 @@ var RootCmd = &cobra.Command{
 	PersistentPreRun: func(cmd *cobra.Command, args []string) {
 		printEnvVars()
 
+		applyDirectVizierContext(cmd)
 		cloudAddr := getCloudAddrIfRequired(cmd)
 
 		if matched, err := regexp.MatchString(".+:[0-9]+$", cloudAddr); !matched && err == nil {
@@ -203,8 +216,16 @@ var RootCmd = &cobra.Command{
 
 // Name a variable to store a slice of commands that don't require cloudAddr
 var cmdsCloudAddrNotReqd = []*cobra.Command{
+	CheckCmd,
 	CollectLogsCmd,
+	InspectSupportBundleCmd,
+	ScriptLintCmd,
+	ScriptTestCmd,
 	VersionCmd,
+	GetContextsCmd,
+	CurrentContextCmd,
+	UseContextCmd,
+	SetContextCmd,
 }
 
 func getCloudAddrIfRequired(cmd *cobra.Command) string {
@@ -216,6 +237,12 @@ func getCloudAddrIfRequired(cmd *cobra.Command) string {
 	interactiveCloudSelect := viper.GetBool("interactive_cloud_select")
 
 	cloudAddr := viper.GetString("cloud_addr")
+	// An explicitly set cloud address takes precedence over the one in the context.
+	if ctx := mustGetActiveContext(); ctx != nil && ctx.CloudAddr != "" && !isSetByUser(cmd, "cloud_addr", "PX_CLOUD_ADDR", "PL_CLOUD_ADDR") {
+		cloudAddr = ctx.CloudAddr
+		viper.Set("cloud_addr", cloudAddr)
+		return cloudAddr
+	}
 	if interactiveCloudSelect {
 		if !isatty.IsTerminal(os.Stdin.Fd()) {
 			utils.Errorf("No cloud address provided during run within non-interactive shell. Please set the cloud address using the `--cloud_addr` flag or `PX_CLOUD_ADDR` environment variable.")
@@ -238,6 +265,48 @@ func getCloudAddrIfRequired(cmd *cobra.Command) string {
 	return cloudAddr
 }
 
+// mustGetActiveContext returns the context selected by `--context` or the current context, and exits
+// if the selected context doesn't exist.
+func mustGetActiveContext() *pxconfig.Context {
+	ctx, err := pxconfig.Cfg().ActiveContext()
+	if err != nil {
+		utils.Error(err.Error())
+		os.Exit(1)
+	}
+	return ctx
+}
+
+// isSetByUser returns whether the setting was explicitly provided as a flag or environment variable.
+func isSetByUser(cmd *cobra.Command, flag string, envs ...string) bool {
+	if f := cmd.Flags().Lookup(flag); f != nil && f.Changed {
+		return true
+	}
+	for _, env := range envs {
+		if _, ok := os.LookupEnv(env); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// applyDirectVizierContext uses the direct vizier settings from the active context, unless they
+// were explicitly provided.
+func applyDirectVizierContext(cmd *cobra.Command) {
+	if slices.Contains(cmdsCloudAddrNotReqd, cmd) || cmd.Short == "Help about any command" {
+		return
+	}
+	ctx := mustGetActiveContext()
+	if ctx == nil || ctx.DirectVizierAddr == "" {
+		return
+	}
+	if !isSetByUser(cmd, "direct_vizier_addr", "PX_DIRECT_VIZIER_ADDR") {
+		viper.Set("direct_vizier_addr", ctx.DirectVizierAddr)
+	}
+	if !isSetByUser(cmd, "direct_vizier_key", "PX_DIRECT_VIZIER_KEY") {
+		viper.Set("direct_vizier_key", ctx.DirectVizierKey)
+	}
+}
+
 func checkAuthForCmd(c *cobra.Command) {
 	if viper.GetString("direct_vizier_addr") != "" {
 		if viper.GetString("direct_vizier_key") == "" {
@@ -264,6 +333,24 @@ func checkAuthForCmd(c *cobra.Command) {
 	}
 }
 
+// mustGetOutputFormat returns the command's normalized output format, and exits if it is invalid.
+func mustGetOutputFormat(cmd *cobra.Command) string {
+	format, _ := cmd.Flags().GetString("output")
+	format = components.NormalizeOutputFormat(format)
+	if err := components.ValidateOutputFormat(format); err != nil {
+		utils.WithError(err).Fatal("Invalid output format")
+	}
+	return format
+}
+
+// mustWriteRow writes a row of output. Rows can fail to be written when a template in the output format
+// doesn't match the data, and the command exits with an error rather than printing partial output.
+func mustWriteRow(w components.OutputStreamWriter, row []interface{}) {
+	if err := w.Write(row); err != nil {
+		utils.WithError(err).Fatal("Failed to write output")
+	}
+}
+
 // Execute is the main function for the Cobra CLI.
 func Execute() {
 	if err := RootCmd.Execute(); err != nil {
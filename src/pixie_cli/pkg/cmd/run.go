@@ -24,7 +24,7 @@ import (
 	"flag"
 	"fmt"
 	"os"
-	"strings"
+	"time"
 
 	"github.com/fatih/color"
 	"github.com/gofrs/uuid"
@@ -32,20 +32,26 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
+	"px.dev/pixie/src/api/proto/cloudpb"
 	"px.dev/pixie/src/cloud/api/ptproxy"
+	"px.dev/pixie/src/pixie_cli/pkg/components"
+	"px.dev/pixie/src/pixie_cli/pkg/scriptargs"
 	"px.dev/pixie/src/pixie_cli/pkg/utils"
 	"px.dev/pixie/src/pixie_cli/pkg/vizier"
 	"px.dev/pixie/src/utils/script"
 )
 
 func init() {
-	RunCmd.Flags().StringP("output", "o", "", "Output format: one of: json|table|csv")
+	RunCmd.Flags().StringP("output", "o", "", components.OutputFormatHelp+"|live")
 	RunCmd.Flags().StringP("file", "f", "", "Script file, specify - for STDIN")
 	RunCmd.Flags().BoolP("list", "l", false, "List available scripts")
 	RunCmd.Flags().BoolP("e2e_encryption", "e", true, "Enable E2E encryption")
+	RunCmd.Flags().Bool("validate_args", true, "Check the script arguments before running the script")
 	RunCmd.Flags().BoolP("all-clusters", "d", false, "Run script across all clusters")
 	RunCmd.Flags().StringP("cluster", "c", "", "ID of the cluster to run on. "+
 		"Use 'px get viziers' to find the ID")
+	RunCmd.Flags().String("selector", "", "Label selector of the clusters to run on, such as env=prod. "+
+		"The script is run on all matching clusters")
 	RunCmd.Flags().MarkHidden("all-clusters")
 
 	RunCmd.Flags().StringP("bundle", "b", "", "Path/URL to bundle file")
@@ -65,13 +71,16 @@ func init() {
 			if idx == 0 {
 				continue
 			}
-			execScript, err := br.GetScript(scriptName)
+			execScript, err := getScript(br, scriptName)
 			if err != nil {
 				// Not found.
 				continue
 			}
 
 			fs := execScript.GetFlagSet()
+			if fs != nil && execScript.Vis != nil {
+				fs = scriptargs.NewFlagSet(scriptName, execScript.Vis)
+			}
 			name := command.Name()
 			flagsMarker := ""
 			if fs != nil {
@@ -115,11 +124,10 @@ func createNewCobraCommand() *cobra.Command {
 		},
 		Run: func(cmd *cobra.Command, args []string) {
 			cloudAddr := viper.GetString("cloud_addr")
-			format, _ := cmd.Flags().GetString("output")
+			format := mustGetOutputFormat(cmd)
 			directVzAddr := viper.GetString("direct_vizier_addr")
 			directVzKey := viper.GetString("direct_vizier_key")
 
-			format = strings.ToLower(format)
 			if format == "live" {
 				LiveCmd.Run(cmd, args)
 				return
@@ -147,7 +155,7 @@ func createNewCobraCommand() *cobra.Command {
 					utils.Fatal("Expected script_name with script args.")
 				}
 				scriptName := args[0]
-				execScript = br.MustGetScript(scriptName)
+				execScript = mustGetScript(br, scriptName)
 				scriptArgs = args[1:]
 			} else {
 				execScript, err = loadScriptFromFile(scriptFile)
@@ -158,13 +166,50 @@ func createNewCobraCommand() *cobra.Command {
 			}
 
 			fs := execScript.GetFlagSet()
+			argFS := fs
 			if fs != nil {
-				if err := fs.Parse(scriptArgs); err != nil {
+				if execScript.Vis != nil {
+					argFS = scriptargs.NewFlagSet(execScript.ScriptName, execScript.Vis)
+				}
+				if err := argFS.Parse(scriptArgs); err != nil {
 					if err == flag.ErrHelp {
 						os.Exit(0)
 					}
 					utils.WithError(err).Fatal("Failed to parse script flags")
 				}
+			}
+
+			allClusters, _ := cmd.Flags().GetBool("all-clusters")
+			selectedCluster, _ := cmd.Flags().GetString("cluster")
+			clusterID := uuid.FromStringOrNil(selectedCluster)
+			selector, _ := cmd.Flags().GetString("selector")
+			if selector != "" && (allClusters || clusterID != uuid.Nil || directVzAddr != "") {
+				utils.Fatal("--selector cannot be used with --cluster, --all-clusters or direct vizier mode")
+			}
+			multiCluster := allClusters || selector != ""
+
+			if !multiCluster && clusterID == uuid.Nil && directVzAddr == "" {
+				clusterID, err = vizier.GetCurrentVizier(cloudAddr)
+				if err != nil {
+					utils.WithError(err).Fatal("Could not fetch healthy vizier")
+				}
+			}
+
+			if fs != nil {
+				values := scriptargs.Values(argFS)
+				if validateArgs, _ := cmd.Flags().GetBool("validate_args"); validateArgs && execScript.Vis != nil {
+					var resolver scriptargs.EntityResolver
+					// Entities can only be looked up when running against a single cluster through the cloud.
+					if !multiCluster && directVzAddr == "" {
+						resolver = newScriptArgsResolver(cloudAddr, clusterID)
+					}
+					mustValidateScriptArgs(execScript, values, resolver)
+				}
+				for name, value := range values {
+					if err := fs.Set(name, value); err != nil {
+						utils.WithError(err).Fatal("Failed to parse script flags")
+					}
+				}
 				err := execScript.UpdateFlags(fs)
 				if err != nil {
 					if errors.Is(err, script.ErrMissingRequiredArgument) {
@@ -176,18 +221,15 @@ func createNewCobraCommand() *cobra.Command {
 				}
 			}
 
-			allClusters, _ := cmd.Flags().GetBool("all-clusters")
-			selectedCluster, _ := cmd.Flags().GetString("cluster")
-			clusterID := uuid.FromStringOrNil(selectedCluster)
-
-			if !allClusters && clusterID == uuid.Nil && directVzAddr == "" {
-				clusterID, err = vizier.GetCurrentVizier(cloudAddr)
+			var conns []*vizier.Connector
+			if selector != "" {
+				conns, err = vizier.ConnectToViziersBySelector(cloudAddr, selector)
 				if err != nil {
-					utils.WithError(err).Fatal("Could not fetch healthy vizier")
+					utils.WithError(err).Fatal("Failed to connect to vizier")
 				}
+			} else {
+				conns = vizier.MustConnectVizier(cloudAddr, allClusters, clusterID, directVzAddr, directVzKey)
 			}
-
-			conns := vizier.MustConnectVizier(cloudAddr, allClusters, clusterID, directVzAddr, directVzKey)
 			useEncryption, _ := cmd.Flags().GetBool("e2e_encryption")
 			if directVzAddr != "" {
 				// There is no e2e encryption for direct mode.
@@ -211,8 +253,8 @@ func createNewCobraCommand() *cobra.Command {
 				}
 			}
 
-			// Don't print cloudAddr live view link for direct mode.
-			if directVzAddr != "" {
+			// Don't print cloudAddr live view link for direct mode, or when running on multiple clusters.
+			if directVzAddr != "" || selector != "" {
 				return
 			}
 
@@ -245,6 +287,46 @@ func createNewCobraCommand() *cobra.Command {
 	}
 }
 
+// newScriptArgsResolver creates a resolver that checks script arguments against the metadata of the
+// given cluster. Returns nil if the cloud can't be reached, in which case the check is skipped.
+func newScriptArgsResolver(cloudAddr string, clusterID uuid.UUID) scriptargs.EntityResolver {
+	if clusterID == uuid.Nil {
+		return nil
+	}
+	cloudConn, err := utils.GetCloudClientConnection(cloudAddr)
+	if err != nil {
+		return nil
+	}
+	return scriptargs.NewCloudResolver(cloudpb.NewAutocompleteServiceClient(cloudConn), clusterID)
+}
+
+// mustValidateScriptArgs checks the arguments passed to the script, and exits after reporting all of
+// the bad arguments if any are invalid. Arguments that don't match an entity in the cluster are only
+// reported as warnings, since scripts may filter on part of an entity's name.
+func mustValidateScriptArgs(execScript *script.ExecutableScript, values map[string]string, resolver scriptargs.EntityResolver) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	warnings, err := scriptargs.Validate(ctx, execScript.Vis, values, resolver)
+	for _, w := range warnings {
+		utils.Errorf("Warning: %s", w)
+	}
+	if err == nil {
+		return
+	}
+	utils.Errorf("Invalid arguments for script %s:", execScript.ScriptName)
+	var vErr *scriptargs.ValidationError
+	if errors.As(err, &vErr) {
+		for _, argErr := range vErr.Errors {
+			utils.Errorf("  %s", argErr)
+		}
+	} else {
+		utils.Errorf("  %s", err)
+	}
+	utils.Errorf("Run 'px run %s -- --help' to see the script's arguments, or pass --validate_args=false to skip this check.",
+		execScript.ScriptName)
+	os.Exit(1)
+}
+
 // RunCmd is the "query" command.
 var RunCmd = createNewCobraCommand()
 
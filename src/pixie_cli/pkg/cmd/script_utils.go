@@ -19,24 +19,32 @@
 package cmd
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path"
+	"strings"
+	"time"
 
 	"github.com/bmatcuk/doublestar"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
 
+	"px.dev/pixie/src/api/proto/cloudpb"
 	"px.dev/pixie/src/pixie_cli/pkg/auth"
 	"px.dev/pixie/src/pixie_cli/pkg/components"
+	"px.dev/pixie/src/pixie_cli/pkg/utils"
 	"px.dev/pixie/src/utils/script"
 )
 
 const defaultBundleFile = "https://storage.googleapis.com/pixie-prod-artifacts/script-bundles/bundle-core.json"
 const ossBundleFile = "https://artifacts.px.dev/pxl_scripts/bundle.json"
 
+// orgScriptPrefix is the prefix used to refer to scripts from the org's private script library.
+const orgScriptPrefix = "org/"
+
 func mustCreateBundleReader() *script.BundleManager {
 	br, err := createBundleReader()
 	if err != nil {
@@ -74,16 +82,89 @@ func listBundleScripts(br *script.BundleManager, format string) {
 	defer w.Finish()
 	w.SetHeader("script_list", []string{"Name", "Description"})
 	scripts := br.GetScripts()
+	scripts = append(scripts, listOrgScripts()...)
 
 	for _, script := range scripts {
 		if script.Hidden {
 			continue
 		}
-		err := w.Write([]interface{}{script.ScriptName, script.ShortDoc})
-		if err != nil {
-			log.WithError(err).Error("Failed to write to stream")
-		}
+		mustWriteRow(w, []interface{}{script.ScriptName, script.ShortDoc})
+	}
+}
+
+func getScriptMgrClient() (cloudpb.ScriptMgrClient, error) {
+	conn, err := utils.GetCloudClientConnection(viper.GetString("cloud_addr"))
+	if err != nil {
+		return nil, err
+	}
+	return cloudpb.NewScriptMgrClient(conn), nil
+}
+
+func orgScriptToExecutableScript(s *cloudpb.OrgScript) *script.ExecutableScript {
+	return &script.ExecutableScript{
+		ScriptName:   orgScriptPrefix + s.Name,
+		ScriptString: s.PxlContents,
+		ShortDoc:     s.Desc,
+		LongDoc:      s.Desc,
+		Vis:          s.Vis,
+	}
+}
+
+// listOrgScripts returns the scripts in the org's private script library. The org library is
+// optional, so failures are logged and an empty list is returned.
+func listOrgScripts() []*script.ExecutableScript {
+	if viper.GetString("direct_vizier_addr") != "" {
+		return nil
+	}
+	client, err := getScriptMgrClient()
+	if err != nil {
+		log.WithError(err).Debug("Failed to connect to cloud to list org scripts")
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	resp, err := client.GetOrgScripts(auth.CtxWithCreds(ctx), &cloudpb.GetOrgScriptsReq{})
+	if err != nil {
+		log.WithError(err).Debug("Failed to list org scripts")
+		return nil
+	}
+	scripts := make([]*script.ExecutableScript, len(resp.Scripts))
+	for i, s := range resp.Scripts {
+		scripts[i] = orgScriptToExecutableScript(s)
+	}
+	return scripts
+}
+
+// getScript looks up the script with the given name. Names starting with "org/" are fetched from
+// the org's private script library, everything else comes from the bundle.
+func getScript(br *script.BundleManager, name string) (*script.ExecutableScript, error) {
+	if !strings.HasPrefix(name, orgScriptPrefix) {
+		return br.GetScript(name)
+	}
+	if viper.GetString("direct_vizier_addr") != "" {
+		return nil, errors.New("org scripts are not available in direct mode")
+	}
+	client, err := getScriptMgrClient()
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	resp, err := client.GetOrgScript(auth.CtxWithCreds(ctx), &cloudpb.GetOrgScriptReq{
+		Name: strings.TrimPrefix(name, orgScriptPrefix),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return orgScriptToExecutableScript(resp), nil
+}
+
+func mustGetScript(br *script.BundleManager, name string) *script.ExecutableScript {
+	s, err := getScript(br, name)
+	if err != nil {
+		utils.WithError(err).Fatalf("Failed to get script %s", name)
 	}
+	return s
 }
 
 func fileExists(filename string) bool {
@@ -19,22 +19,41 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
 
 	"github.com/alecthomas/chroma/quick"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+
+	"px.dev/pixie/src/carnot/planner/pxlcompiler"
+	"px.dev/pixie/src/pixie_cli/pkg/components"
+	"px.dev/pixie/src/pixie_cli/pkg/scriptdev"
+	"px.dev/pixie/src/pixie_cli/pkg/utils"
 )
 
 func init() {
 	ScriptCmd.AddCommand(ScriptListCmd)
 	ScriptCmd.AddCommand(ScriptShowCmd)
+	ScriptCmd.AddCommand(ScriptLintCmd)
+	ScriptCmd.AddCommand(ScriptTestCmd)
 	// Allow run as an alias to keep scripts self contained.
 	ScriptCmd.AddCommand(RunSubCmd)
 
 	ScriptCmd.PersistentFlags().StringP("bundle", "b", "", "Path/URL to bundle file")
 	ScriptListCmd.Flags().StringP("output", "o", "", "Output format: one of: json|table")
+
+	ScriptLintCmd.Flags().StringP("output", "o", "", components.OutputFormatHelp)
+	ScriptLintCmd.Flags().String("schema", "", "Path to the table schemas to compile against, as a binary or JSON schemapb.Schema. Defaults to the tables collected by Pixie")
+	ScriptLintCmd.Flags().Bool("skip_compile", false, "Only check the vis specs, without compiling the scripts. Needed when px is built without cgo")
+
+	ScriptTestCmd.Flags().StringP("output", "o", "", components.OutputFormatHelp)
+	ScriptTestCmd.Flags().String("carnot_executable", "carnot_executable", "Path to the carnot_executable binary that runs the scripts")
+	ScriptTestCmd.Flags().Bool("update", false, "Update the golden files with the output of the scripts")
 }
 
 // ScriptCmd is the "script" command.
@@ -70,10 +89,158 @@ var ScriptShowCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		br := mustCreateBundleReader()
 		scriptName := args[0]
-		execScript := br.MustGetScript(scriptName)
+		execScript := mustGetScript(br, scriptName)
 		err := quick.Highlight(os.Stdout, execScript.ScriptString, "python3", "terminal16m", "monokai")
 		if err != nil {
 			fmt.Fprint(os.Stdout, execScript.ScriptString)
 		}
 	},
 }
+
+func mustFindLocalScripts(dirs []string) []*scriptdev.Script {
+	if len(dirs) == 0 {
+		dirs = []string{"."}
+	}
+	scripts, err := scriptdev.FindScripts(dirs...)
+	if err != nil {
+		utils.WithError(err).Fatal("Failed to find scripts")
+	}
+	if len(scripts) == 0 {
+		utils.Fatal("No pxl scripts found")
+	}
+	return scripts
+}
+
+// ScriptLintCmd is the "script lint" command.
+var ScriptLintCmd = &cobra.Command{
+	Use:   "lint [dir...]",
+	Short: "Check local pxl scripts and their vis specs for errors",
+	Long: `Check local pxl scripts and their vis specs for errors.
+
+Each directory that contains a .pxl file is a script, along with the vis.json in the same directory.
+The vis spec is checked against the functions that the script defines, and the script is compiled
+against the table schemas.
+
+Compiling scripts needs the query planner, which is only linked into px when it is built with cgo.
+The released px binaries are built without cgo, so with them lint fails unless --skip_compile is
+passed to only check the vis specs. Build px with cgo to compile scripts locally:
+
+  bazel build //src/pixie_cli:px_cgo`,
+	Run: func(cmd *cobra.Command, args []string) {
+		format := mustGetOutputFormat(cmd)
+		scripts := mustFindLocalScripts(args)
+
+		var compiler pxlcompiler.Compiler
+		if skipCompile, _ := cmd.Flags().GetBool("skip_compile"); !skipCompile {
+			schemaPath, _ := cmd.Flags().GetString("schema")
+			c, err := newScriptCompiler(schemaPath)
+			if err != nil {
+				// Failing here rather than only checking the vis specs keeps lint from passing
+				// scripts that it never compiled.
+				utils.WithError(err).Fatal("Unable to compile scripts. Pass --skip_compile to only check the vis specs")
+			}
+			compiler = c
+		}
+
+		var issues []*scriptdev.Issue
+		for _, s := range scripts {
+			issues = append(issues, scriptdev.Lint(s, compiler)...)
+		}
+		if c, ok := compiler.(*pxlcompiler.PlannerCompiler); ok {
+			c.Close()
+		}
+
+		w := components.CreateStreamWriter(format, os.Stdout)
+		w.SetHeader("lint", []string{"Script", "Severity", "Location", "Message"})
+		for _, i := range issues {
+			location := filepath.Base(i.File)
+			if i.Line > 0 {
+				location += ":" + strconv.Itoa(i.Line)
+			}
+			mustWriteRow(w, []interface{}{i.Script, i.Severity, location, i.Message})
+		}
+		w.Finish()
+
+		if scriptdev.HasErrors(issues) {
+			os.Exit(1)
+		}
+		if len(issues) == 0 && !components.IsStructuredFormat(format) {
+			utils.Infof("Checked %d scripts, no issues found", len(scripts))
+		}
+	},
+}
+
+func newScriptCompiler(schemaPath string) (*pxlcompiler.PlannerCompiler, error) {
+	schema, err := pxlcompiler.LoadSchema(schemaPath)
+	if err != nil {
+		return nil, err
+	}
+	return pxlcompiler.NewPlannerCompiler(schema)
+}
+
+// ScriptTestCmd is the "script test" command.
+var ScriptTestCmd = &cobra.Command{
+	Use:   "test [dir...]",
+	Short: "Run local pxl scripts against fixture tables and compare their output with golden files",
+	Long: `Run local pxl scripts against fixture tables and compare their output with golden files.
+
+The tests of each script are defined in a pxl_test.yaml file in the script's directory:
+
+  tests:
+  - name: errors_by_service
+    func: http_errors
+    args:
+      start_time: -5m
+    table: http_events
+    fixture: testdata/http_events.csv
+    golden: testdata/errors_by_service.csv
+
+Fixtures are CSV files where the first row has the type of each column (int64, uint128, float64,
+boolean, string or time64ns) and the second row has the column names.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		format := mustGetOutputFormat(cmd)
+		scripts := mustFindLocalScripts(args)
+
+		carnotPath, _ := cmd.Flags().GetString("carnot_executable")
+		carnotPath, err := exec.LookPath(carnotPath)
+		if err != nil {
+			utils.WithError(err).Fatal("Could not find carnot_executable. Build it with 'bazel build //src/carnot:carnot_executable' and pass its path with --carnot_executable")
+		}
+		update, _ := cmd.Flags().GetBool("update")
+		r := &scriptdev.TestRunner{
+			CarnotExecutable: carnotPath,
+			Update:           update,
+		}
+
+		ctx, cleanup := utils.WithSignalCancellable(context.Background())
+		defer cleanup()
+
+		w := components.CreateStreamWriter(format, os.Stdout)
+		w.SetHeader("tests", []string{"Script", "Test", "Result", "Message"})
+		failed := 0
+		total := 0
+		for _, s := range scripts {
+			results, err := r.Run(ctx, s)
+			if err != nil {
+				results = []*scriptdev.TestResult{{Script: s.Name, Message: err.Error()}}
+			}
+			for _, res := range results {
+				total++
+				result := "PASS"
+				if !res.Passed {
+					result = "FAIL"
+					failed++
+				}
+				mustWriteRow(w, []interface{}{res.Script, res.Test, result, res.Message})
+			}
+		}
+		w.Finish()
+
+		if !components.IsStructuredFormat(format) {
+			utils.Infof("%d/%d tests passed", total-failed, total)
+		}
+		if failed > 0 {
+			os.Exit(1)
+		}
+	},
+}
@@ -0,0 +1,143 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"px.dev/pixie/src/pixie_cli/pkg/components"
+	"px.dev/pixie/src/pixie_cli/pkg/supportbundle"
+	"px.dev/pixie/src/pixie_cli/pkg/utils"
+)
+
+// maxInspectRows limits the number of events and logs shown by `px bundle inspect`.
+const maxInspectRows = 20
+
+func init() {
+	SupportBundleCmd.AddCommand(InspectSupportBundleCmd)
+
+	InspectSupportBundleCmd.Flags().StringP("output", "o", "", "Output format: one of: table|json")
+}
+
+// SupportBundleCmd is the "bundle" command.
+var SupportBundleCmd = &cobra.Command{
+	Use:   "bundle",
+	Short: "Work with support bundles collected by `px collect-logs`",
+}
+
+// InspectSupportBundleCmd is the "bundle inspect" command.
+var InspectSupportBundleCmd = &cobra.Command{
+	Use:   "inspect <bundle.tar.gz>",
+	Short: "Summarize a support bundle, without access to the cluster",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		format, _ := cmd.Flags().GetString("output")
+		format = strings.ToLower(format)
+
+		b, err := supportbundle.ReadFile(args[0])
+		if err != nil {
+			utils.WithError(err).Fatalf("Failed to read support bundle %s", args[0])
+		}
+		s, err := supportbundle.Summarize(b)
+		if err != nil {
+			utils.WithError(err).Fatal("Failed to summarize support bundle")
+		}
+
+		if format == "json" {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(s); err != nil {
+				utils.WithError(err).Fatal("Failed to write summary")
+			}
+			return
+		}
+		printSupportBundleSummary(s)
+	},
+}
+
+func printSupportBundleSummary(s *supportbundle.Summary) {
+	m := s.Manifest
+	fmt.Printf("Collected:          %s\n", m.CreatedAt.Format("2006-01-02 15:04:05 MST"))
+	fmt.Printf("CLI version:        %s\n", m.CLIVersion)
+	fmt.Printf("Cluster ID:         %s\n", m.ClusterID)
+	fmt.Printf("Vizier namespace:   %s\n", m.VizierNamespace)
+	fmt.Printf("Operator namespace: %s\n", m.OperatorNamespace)
+	fmt.Printf("Files:              %d\n", len(m.Files))
+
+	printTable := func(title string, header []string, rows [][]interface{}) {
+		fmt.Printf("\n%s:\n", title)
+		if len(rows) == 0 {
+			fmt.Println("  none")
+			return
+		}
+		w := components.CreateStreamWriter("table", os.Stdout)
+		w.SetHeader(strings.ToLower(title), header)
+		for _, r := range rows {
+			mustWriteRow(w, r)
+		}
+		w.Finish()
+	}
+
+	var rows [][]interface{}
+	for _, vz := range s.Viziers {
+		rows = append(rows, []interface{}{vz.Name, vz.Version, vz.OperatorVersion, vz.Phase, vz.Reason, vz.ReconciliationPhase, vz.Message})
+	}
+	printTable("Viziers", []string{"Name", "Version", "Operator Version", "Phase", "Reason", "Reconciliation", "Message"}, rows)
+
+	rows = nil
+	for _, p := range s.Pods {
+		rows = append(rows, []interface{}{p.Namespace, p.Name, p.Phase, p.Ready, p.Restarts, p.Reason, p.Node})
+	}
+	printTable("Pods", []string{"Namespace", "Name", "Phase", "Ready", "Restarts", "Reason", "Node"}, rows)
+
+	rows = nil
+	for _, n := range s.Nodes {
+		rows = append(rows, []interface{}{n.Name, n.Ready, n.KernelVersion, n.KubeletVersion, n.OSImage, n.Memory})
+	}
+	printTable("Nodes", []string{"Name", "Ready", "Kernel", "Kubelet", "OS", "Memory"}, rows)
+
+	rows = nil
+	for i, e := range s.WarningEvents {
+		if i == maxInspectRows {
+			break
+		}
+		rows = append(rows, []interface{}{e.Namespace, e.Object, e.Reason, e.Count, e.Message})
+	}
+	printTable("Warning Events", []string{"Namespace", "Object", "Reason", "Count", "Message"}, rows)
+
+	rows = nil
+	for i, l := range s.Logs {
+		if i == maxInspectRows || l.ErrorLines == 0 {
+			break
+		}
+		rows = append(rows, []interface{}{l.Path, l.ErrorLines, l.Lines, l.LastError})
+	}
+	printTable("Logs With Errors", []string{"Log", "Errors", "Lines", "Last Error"}, rows)
+
+	rows = nil
+	for _, e := range m.Errors {
+		rows = append(rows, []interface{}{e.Kind, e.Source, e.Error})
+	}
+	printTable("Collection Errors", []string{"Kind", "Source", "Error"}, rows)
+}
@@ -0,0 +1,266 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/gofrs/uuid"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"px.dev/pixie/src/api/proto/cloudpb"
+	"px.dev/pixie/src/api/proto/uuidpb"
+	"px.dev/pixie/src/pixie_cli/pkg/components"
+	"px.dev/pixie/src/pixie_cli/pkg/utils"
+	"px.dev/pixie/src/pixie_cli/pkg/vizier"
+	utils2 "px.dev/pixie/src/utils"
+)
+
+func init() {
+	UpdatePolicyCmd.AddCommand(GetUpdatePolicyCmd)
+	UpdatePolicyCmd.AddCommand(SetUpdatePolicyCmd)
+
+	GetUpdatePolicyCmd.Flags().StringP("output", "o", "", components.OutputFormatHelp)
+
+	SetUpdatePolicyCmd.Flags().String("pin", "", "Don't update viziers past this version. Set to an empty string to unpin")
+	SetUpdatePolicyCmd.Flags().String("channel", "", "The release channel to update to, either 'latest' or 'stable'")
+	SetUpdatePolicyCmd.Flags().Int("versions_behind", 0, "The number of releases to stay behind the newest release in the channel")
+	SetUpdatePolicyCmd.Flags().StringArray("window", nil,
+		"A maintenance window in which updates are allowed, such as 'sat,sun 2-6', '1-5' for every day, or 'fri 22-4' for a window that ends the next day. Can be repeated")
+	SetUpdatePolicyCmd.Flags().Bool("clear_windows", false, "Remove all maintenance windows, allowing updates at any time")
+	SetUpdatePolicyCmd.Flags().String("timezone", "", "The IANA timezone of the maintenance windows, such as 'America/New_York'")
+	SetUpdatePolicyCmd.Flags().StringArray("wave", nil,
+		"An update wave of cluster names or IDs, such as 'canary=staging-1,staging-2'. Waves are updated in the order given. Can be repeated")
+	SetUpdatePolicyCmd.Flags().Bool("clear_waves", false, "Remove all update waves")
+}
+
+// UpdatePolicyCmd is the update-policy sub-command of the CLI.
+var UpdatePolicyCmd = &cobra.Command{
+	Use:   "update-policy",
+	Short: "Manage the policy for automatically updating Pixie in your org",
+	Run: func(cmd *cobra.Command, args []string) {
+		utils.Info("Nothing here... Please execute one of the subcommands")
+		cmd.Help()
+	},
+}
+
+// GetUpdatePolicyCmd is the get sub-command of UpdatePolicy.
+var GetUpdatePolicyCmd = &cobra.Command{
+	Use:   "get",
+	Short: "Get the policy for automatically updating Pixie",
+	PreRun: func(cmd *cobra.Command, args []string) {
+		viper.BindPFlag("output", cmd.Flags().Lookup("output"))
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		cloudAddr := viper.GetString("cloud_addr")
+		format := mustGetOutputFormat(cmd)
+
+		l, err := vizier.NewLister(cloudAddr)
+		if err != nil {
+			// Using log.Fatal rather than CLI log in order to track this unexpected error in Sentry.
+			log.WithError(err).Fatal("Failed to create Vizier lister")
+		}
+		policy, err := l.GetUpdatePolicy()
+		if err != nil {
+			utils.WithError(err).Fatal("Failed to get update policy")
+		}
+		printUpdatePolicy(l, format, policy)
+	},
+}
+
+// SetUpdatePolicyCmd is the set sub-command of UpdatePolicy.
+var SetUpdatePolicyCmd = &cobra.Command{
+	Use:   "set",
+	Short: "Change the policy for automatically updating Pixie. Only the given settings are changed",
+	Run: func(cmd *cobra.Command, args []string) {
+		cloudAddr := viper.GetString("cloud_addr")
+
+		l, err := vizier.NewLister(cloudAddr)
+		if err != nil {
+			// Using log.Fatal rather than CLI log in order to track this unexpected error in Sentry.
+			log.WithError(err).Fatal("Failed to create Vizier lister")
+		}
+		policy, err := l.GetUpdatePolicy()
+		if err != nil {
+			utils.WithError(err).Fatal("Failed to get update policy")
+		}
+
+		flags := cmd.Flags()
+		if flags.Changed("pin") {
+			policy.PinnedVersion, _ = flags.GetString("pin")
+		}
+		if flags.Changed("channel") {
+			channel, _ := flags.GetString("channel")
+			c, ok := cloudpb.VizierUpdateChannel_value["VUC_"+strings.ToUpper(channel)]
+			if !ok {
+				utils.Fatalf("Invalid channel %q, expected 'latest' or 'stable'", channel)
+			}
+			policy.Channel = cloudpb.VizierUpdateChannel(c)
+		}
+		if flags.Changed("versions_behind") {
+			behind, _ := flags.GetInt("versions_behind")
+			policy.VersionsBehind = int32(behind)
+		}
+		if flags.Changed("timezone") {
+			policy.Timezone, _ = flags.GetString("timezone")
+		}
+		if clear, _ := flags.GetBool("clear_windows"); clear {
+			policy.MaintenanceWindows = nil
+		}
+		windows, _ := flags.GetStringArray("window")
+		for _, w := range windows {
+			window, err := parseMaintenanceWindow(w)
+			if err != nil {
+				utils.WithError(err).Fatal("Invalid maintenance window")
+			}
+			policy.MaintenanceWindows = append(policy.MaintenanceWindows, window)
+		}
+		if clear, _ := flags.GetBool("clear_waves"); clear {
+			policy.Waves = nil
+		}
+		waves, _ := flags.GetStringArray("wave")
+		if len(waves) > 0 {
+			clusterIDs := mustGetClusterIDsByName(l)
+			for _, w := range waves {
+				wave, err := parseUpdateWave(w, clusterIDs)
+				if err != nil {
+					utils.WithError(err).Fatal("Invalid update wave")
+				}
+				policy.Waves = append(policy.Waves, wave)
+			}
+		}
+
+		policy, err = l.SetUpdatePolicy(policy)
+		if err != nil {
+			utils.WithError(err).Fatal("Failed to set update policy")
+		}
+		utils.Info("Successfully updated the update policy")
+		printUpdatePolicy(l, "", policy)
+	},
+}
+
+func mustGetClusterIDsByName(l *vizier.Lister) map[string]*uuidpb.UUID {
+	vzs, err := l.GetViziersInfo()
+	if err != nil {
+		// Using log.Fatal rather than CLI log in order to track this unexpected error in Sentry.
+		log.WithError(err).Fatal("Failed to get vizier information")
+	}
+	ids := make(map[string]*uuidpb.UUID)
+	for _, vz := range vzs {
+		ids[vz.ClusterName] = vz.ID
+	}
+	return ids
+}
+
+// parseMaintenanceWindow parses a window of the form "[day,day ]start-end".
+func parseMaintenanceWindow(s string) (*cloudpb.MaintenanceWindow, error) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 || len(fields) > 2 {
+		return nil, fmt.Errorf("expected '[days] start-end', got %q", s)
+	}
+	window := &cloudpb.MaintenanceWindow{}
+	if len(fields) == 2 {
+		window.Days = strings.Split(strings.ToLower(fields[0]), ",")
+	}
+	start, end, ok := strings.Cut(fields[len(fields)-1], "-")
+	if !ok {
+		return nil, fmt.Errorf("expected hours of the form start-end, got %q", fields[len(fields)-1])
+	}
+	startHour, err := strconv.Atoi(start)
+	if err != nil {
+		return nil, fmt.Errorf("invalid start hour %q", start)
+	}
+	endHour, err := strconv.Atoi(end)
+	if err != nil {
+		return nil, fmt.Errorf("invalid end hour %q", end)
+	}
+	window.StartHour = int32(startHour)
+	window.EndHour = int32(endHour)
+	return window, nil
+}
+
+// parseUpdateWave parses a wave of the form "name=cluster,cluster", where clusters are names or IDs.
+func parseUpdateWave(s string, clusterIDs map[string]*uuidpb.UUID) (*cloudpb.UpdateWave, error) {
+	name, clusters, ok := strings.Cut(s, "=")
+	if !ok || name == "" {
+		return nil, fmt.Errorf("expected 'name=cluster,cluster', got %q", s)
+	}
+	wave := &cloudpb.UpdateWave{Name: name}
+	for _, c := range strings.Split(clusters, ",") {
+		c = strings.TrimSpace(c)
+		if c == "" {
+			continue
+		}
+		if id, err := uuid.FromString(c); err == nil {
+			wave.ClusterIDs = append(wave.ClusterIDs, utils2.ProtoFromUUID(id))
+			continue
+		}
+		id, ok := clusterIDs[c]
+		if !ok {
+			return nil, fmt.Errorf("unknown cluster %q", c)
+		}
+		wave.ClusterIDs = append(wave.ClusterIDs, id)
+	}
+	return wave, nil
+}
+
+func formatMaintenanceWindow(w *cloudpb.MaintenanceWindow) string {
+	hours := fmt.Sprintf("%d-%d", w.StartHour, w.EndHour)
+	if len(w.Days) == 0 {
+		return hours
+	}
+	return fmt.Sprintf("%s %s", strings.Join(w.Days, ","), hours)
+}
+
+func printUpdatePolicy(l *vizier.Lister, format string, policy *cloudpb.VizierUpdatePolicy) {
+	clusterNames := make(map[uuid.UUID]string)
+	if len(policy.Waves) > 0 {
+		for name, id := range mustGetClusterIDsByName(l) {
+			clusterNames[utils2.UUIDFromProtoOrNil(id)] = name
+		}
+	}
+
+	windows := make([]string, len(policy.MaintenanceWindows))
+	for i, w := range policy.MaintenanceWindows {
+		windows[i] = formatMaintenanceWindow(w)
+	}
+	waves := make([]string, len(policy.Waves))
+	for i, w := range policy.Waves {
+		clusters := make([]string, len(w.ClusterIDs))
+		for j, idPb := range w.ClusterIDs {
+			id := utils2.UUIDFromProtoOrNil(idPb)
+			clusters[j] = id.String()
+			if name, ok := clusterNames[id]; ok {
+				clusters[j] = name
+			}
+		}
+		waves[i] = fmt.Sprintf("%s=%s", w.Name, strings.Join(clusters, ","))
+	}
+
+	w := components.CreateStreamWriter(format, os.Stdout)
+	defer w.Finish()
+	w.SetHeader("update-policy", []string{"Target Version", "Pinned Version", "Channel", "Versions Behind", "Maintenance Windows", "Timezone", "Waves"})
+	mustWriteRow(w, []interface{}{policy.TargetVersion, policy.PinnedVersion, strings.ToLower(strings.TrimPrefix(policy.Channel.String(), "VUC_")),
+		policy.VersionsBehind, strings.Join(windows, "; "), policy.Timezone, strings.Join(waves, "; ")})
+}
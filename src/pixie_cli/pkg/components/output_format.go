@@ -0,0 +1,335 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package components
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/viper"
+	"k8s.io/client-go/util/jsonpath"
+)
+
+// Output formats that take an argument, for example `-o jsonpath={.ID}`.
+const (
+	FormatGoTemplate    = "go-template"
+	FormatJSONPath      = "jsonpath"
+	FormatCustomColumns = "custom-columns"
+)
+
+// OutputFormatHelp describes all the output formats that are supported by CreateStreamWriter.
+const OutputFormatHelp = "Output format: one of: table|json|csv|go-template=...|jsonpath=...|custom-columns=<HEADER>:<jsonpath>,..."
+
+// ParseOutputFormat splits an output format into its name and argument. The name is case insensitive.
+func ParseOutputFormat(format string) (name, arg string) {
+	name, arg, _ = strings.Cut(format, "=")
+	return strings.ToLower(strings.TrimSpace(name)), arg
+}
+
+// NormalizeOutputFormat lowercases the name of the output format, leaving its argument untouched.
+func NormalizeOutputFormat(format string) string {
+	name, arg := ParseOutputFormat(format)
+	if strings.Contains(format, "=") {
+		return name + "=" + arg
+	}
+	return name
+}
+
+// IsStructuredFormat returns whether the output format works on the raw data, rather than the
+// values formatted for humans.
+func IsStructuredFormat(format string) bool {
+	name, _ := ParseOutputFormat(format)
+	return name == "json" || name == FormatGoTemplate || name == FormatJSONPath
+}
+
+// ValidateOutputFormat checks that the output format's argument is valid.
+func ValidateOutputFormat(format string) error {
+	name, arg := ParseOutputFormat(format)
+	switch name {
+	case FormatGoTemplate:
+		_, err := parseGoTemplate(arg)
+		return err
+	case FormatJSONPath:
+		_, err := parseJSONPath(arg)
+		return err
+	case FormatCustomColumns:
+		_, _, err := parseCustomColumns(arg)
+		return err
+	}
+	return nil
+}
+
+func noHeaders() bool {
+	return viper.GetBool("no-headers")
+}
+
+func parseGoTemplate(arg string) (*template.Template, error) {
+	if arg == "" {
+		return nil, errors.New("go-template format requires a template, for example: -o go-template='{{.ID}}'")
+	}
+	t, err := template.New("output").Parse(arg)
+	if err != nil {
+		return nil, fmt.Errorf("invalid go-template: %w", err)
+	}
+	return t, nil
+}
+
+// relaxedJSONPath wraps bare JSONPath expressions in braces, so that both `.ID` and `{.ID}` work.
+func relaxedJSONPath(expr string) string {
+	if strings.Contains(expr, "{") {
+		return expr
+	}
+	if !strings.HasPrefix(expr, ".") {
+		expr = "." + expr
+	}
+	return "{" + expr + "}"
+}
+
+func parseJSONPath(arg string) (*jsonpath.JSONPath, error) {
+	if arg == "" {
+		return nil, errors.New("jsonpath format requires an expression, for example: -o jsonpath='{.ID}'")
+	}
+	j := jsonpath.New("output").AllowMissingKeys(true)
+	if err := j.Parse(relaxedJSONPath(arg)); err != nil {
+		return nil, fmt.Errorf("invalid jsonpath: %w", err)
+	}
+	return j, nil
+}
+
+func parseCustomColumns(arg string) ([]string, []*jsonpath.JSONPath, error) {
+	if arg == "" {
+		return nil, nil, errors.New("custom-columns format requires columns, for example: -o custom-columns=NAME:.ClusterName,ID:.ID")
+	}
+	var headers []string
+	var paths []*jsonpath.JSONPath
+	for _, col := range strings.Split(arg, ",") {
+		header, expr, ok := strings.Cut(col, ":")
+		if !ok || header == "" || expr == "" {
+			return nil, nil, fmt.Errorf("invalid custom column %q, expected <HEADER>:<jsonpath>", col)
+		}
+		j := jsonpath.New(header).AllowMissingKeys(true)
+		if err := j.Parse(relaxedJSONPath(expr)); err != nil {
+			return nil, nil, fmt.Errorf("invalid jsonpath for column %s: %w", header, err)
+		}
+		headers = append(headers, header)
+		paths = append(paths, j)
+	}
+	return headers, paths, nil
+}
+
+// templateKey turns a header value into a key that can be used directly in templates, for example
+// "K8s Version" becomes "K8sVersion".
+func templateKey(header string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' {
+			return r
+		}
+		return -1
+	}, header)
+}
+
+// rowObject converts a row into the object that templates are executed against. Values are converted
+// through JSON, so they look the same as in the JSON output. Each column is available both under its
+// header value, and under the header with spaces and punctuation removed.
+func rowObject(id string, headerValues []string, data []interface{}) (map[string]interface{}, error) {
+	if len(data) != len(headerValues) {
+		return nil, errors.New("header/data length mismatch")
+	}
+	val := make(MapSlice, len(data)+1)
+	val[0] = MapItem{Key: tableNameKey, Value: id}
+	for i, d := range data {
+		val[i+1] = MapItem{Key: headerValues[i], Value: d}
+	}
+	b, err := json.Marshal(val)
+	if err != nil {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(b))
+	// Keep large integers, such as timestamps, from being printed in scientific notation.
+	dec.UseNumber()
+	obj := make(map[string]interface{})
+	if err := dec.Decode(&obj); err != nil {
+		return nil, err
+	}
+	for _, h := range headerValues {
+		if k := templateKey(h); k != h {
+			if _, exists := obj[k]; !exists {
+				obj[k] = obj[h]
+			}
+		}
+	}
+	return obj, nil
+}
+
+// TemplateStreamWriter executes a template against each row, and writes one line of output per row.
+type TemplateStreamWriter struct {
+	w            io.Writer
+	id           string
+	headerValues []string
+	execute      func(w io.Writer, obj map[string]interface{}) error
+	err          error
+}
+
+// NewGoTemplateStreamWriter creates a writer that executes the Go template against each row.
+func NewGoTemplateStreamWriter(w io.Writer, tmpl string) *TemplateStreamWriter {
+	t, err := parseGoTemplate(tmpl)
+	return &TemplateStreamWriter{
+		w:   w,
+		err: err,
+		execute: func(w io.Writer, obj map[string]interface{}) error {
+			return t.Execute(w, obj)
+		},
+	}
+}
+
+// NewJSONPathStreamWriter creates a writer that evaluates the JSONPath expression against each row.
+func NewJSONPathStreamWriter(w io.Writer, expr string) *TemplateStreamWriter {
+	j, err := parseJSONPath(expr)
+	return &TemplateStreamWriter{
+		w:   w,
+		err: err,
+		execute: func(w io.Writer, obj map[string]interface{}) error {
+			return j.Execute(w, obj)
+		},
+	}
+}
+
+// SetHeader is called to set the key values for each of the data values. Must be called before Write is.
+func (t *TemplateStreamWriter) SetHeader(id string, headerValues []string) {
+	t.id = id
+	t.headerValues = headerValues
+}
+
+// Write is called for each record of data.
+func (t *TemplateStreamWriter) Write(data []interface{}) error {
+	if t.err != nil {
+		return t.err
+	}
+	obj, err := rowObject(t.id, t.headerValues, data)
+	if err != nil {
+		return err
+	}
+	buf := &bytes.Buffer{}
+	if err := t.execute(buf, obj); err != nil {
+		return fmt.Errorf("failed to execute output template: %w", err)
+	}
+	if buf.Len() == 0 || buf.Bytes()[buf.Len()-1] != '\n' {
+		buf.WriteByte('\n')
+	}
+	_, err = t.w.Write(buf.Bytes())
+	return err
+}
+
+// Finish is called to flush all the data.
+func (t *TemplateStreamWriter) Finish() {
+	// Since the template writer outputs records right away there is nothing to do here.
+}
+
+// CustomColumnsStreamWriter writes a table with columns that are selected by JSONPath expressions.
+type CustomColumnsStreamWriter struct {
+	w            io.Writer
+	id           string
+	headerValues []string
+	columns      []string
+	paths        []*jsonpath.JSONPath
+	rows         [][]string
+	err          error
+}
+
+// NewCustomColumnsStreamWriter creates a CustomColumnsStreamWriter from a spec of the form
+// <HEADER>:<jsonpath>,<HEADER>:<jsonpath>.
+func NewCustomColumnsStreamWriter(w io.Writer, spec string) *CustomColumnsStreamWriter {
+	columns, paths, err := parseCustomColumns(spec)
+	return &CustomColumnsStreamWriter{w: w, columns: columns, paths: paths, err: err}
+}
+
+// SetHeader is called to set the key values for each of the data values. Must be called before Write is.
+func (c *CustomColumnsStreamWriter) SetHeader(id string, headerValues []string) {
+	c.id = id
+	c.headerValues = headerValues
+}
+
+// Write is called for each record of data.
+func (c *CustomColumnsStreamWriter) Write(data []interface{}) error {
+	if c.err != nil {
+		return c.err
+	}
+	obj, err := rowObject(c.id, c.headerValues, data)
+	if err != nil {
+		return err
+	}
+	row := make([]string, len(c.paths))
+	for i, p := range c.paths {
+		buf := &bytes.Buffer{}
+		if err := p.Execute(buf, obj); err != nil {
+			return err
+		}
+		row[i] = buf.String()
+		if row[i] == "" {
+			row[i] = "<none>"
+		}
+	}
+	c.rows = append(c.rows, row)
+	return nil
+}
+
+// Finish is called when all the data has been sent, and renders the table.
+func (c *CustomColumnsStreamWriter) Finish() {
+	if c.err != nil {
+		return
+	}
+	var header []string
+	if !noHeaders() {
+		header = c.columns
+	}
+	renderTable(c.w, header, c.rows)
+}
+
+// renderTable renders the rows as a borderless table. The header is omitted if it's empty.
+func renderTable(w io.Writer, header []string, rows [][]string) {
+	table := tablewriter.NewWriter(w)
+	if len(header) > 0 {
+		table.SetHeader(header)
+	}
+
+	table.SetAutoFormatHeaders(true)
+	table.SetAutoWrapText(false)
+	table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	table.SetColWidth(30)
+	table.SetReflowDuringAutoWrap(true)
+	table.SetCenterSeparator("")
+	table.SetColumnSeparator("")
+	table.SetRowSeparator("")
+	table.SetHeaderLine(false)
+	table.SetBorder(false)
+	table.SetTablePadding("\t")
+	table.SetNoWhiteSpace(false)
+
+	table.AppendBulk(rows)
+	table.Render()
+}
@@ -0,0 +1,147 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package components_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"px.dev/pixie/src/pixie_cli/pkg/components"
+)
+
+var (
+	testClusterID = uuid.Must(uuid.FromString("5b27f024-eccb-4d07-b28d-84ab8d88e6a3"))
+	testHeader    = []string{"ClusterName", "ID", "K8s Version", "Last Heartbeat"}
+	testRows      = [][]interface{}{
+		{"dev", testClusterID, "v1.24.1", int64(1666000000000000000)},
+		{"prod", uuid.Nil, "v1.25.0", int64(-1)},
+	}
+)
+
+func writeRows(t *testing.T, format string) string {
+	buf := &bytes.Buffer{}
+	w := components.CreateStreamWriter(format, buf)
+	w.SetHeader("viziers", testHeader)
+	for _, r := range testRows {
+		require.NoError(t, w.Write(r))
+	}
+	w.Finish()
+	return buf.String()
+}
+
+func TestGoTemplateStreamWriter(t *testing.T) {
+	assert.Equal(t, "dev 5b27f024-eccb-4d07-b28d-84ab8d88e6a3\nprod 00000000-0000-0000-0000-000000000000\n",
+		writeRows(t, `go-template={{.ClusterName}} {{.ID}}`))
+	// Headers with spaces can be used either directly with index, or with the spaces removed.
+	assert.Equal(t, "v1.24.1 1666000000000000000\nv1.25.0 -1\n",
+		writeRows(t, `go-template={{index . "K8s Version"}} {{.LastHeartbeat}}`))
+	assert.Equal(t, "viziers\nviziers\n", writeRows(t, `go-template={{._tableName_}}{{"\n"}}`))
+}
+
+func TestJSONPathStreamWriter(t *testing.T) {
+	assert.Equal(t, "5b27f024-eccb-4d07-b28d-84ab8d88e6a3\n00000000-0000-0000-0000-000000000000\n",
+		writeRows(t, "jsonpath={.ID}"))
+	assert.Equal(t, "dev v1.24.1\nprod v1.25.0\n", writeRows(t, "jsonpath={.ClusterName} {.K8sVersion}"))
+	// Bare expressions are wrapped in braces.
+	assert.Equal(t, "dev\nprod\n", writeRows(t, "jsonpath=.ClusterName"))
+	// The format name is case insensitive, but its argument isn't.
+	assert.Equal(t, "dev\nprod\n", writeRows(t, "JSONPath={.ClusterName}"))
+}
+
+func TestTemplateStreamWriter_ExecuteError(t *testing.T) {
+	for _, format := range []string{
+		`go-template={{.ClusterName.Foo}}`,
+		"jsonpath={.ClusterName[0]}",
+	} {
+		t.Run(format, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			w := components.CreateStreamWriter(format, buf)
+			w.SetHeader("viziers", testHeader)
+			err := w.Write(testRows[0])
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "failed to execute output template")
+			w.Finish()
+			assert.Empty(t, buf.String())
+		})
+	}
+}
+
+func TestCustomColumnsStreamWriter(t *testing.T) {
+	out := writeRows(t, "custom-columns=NAME:.ClusterName,VERSION:{.K8sVersion},MISSING:.Foo")
+	assert.Equal(t, [][]string{
+		{"NAME", "VERSION", "MISSING"},
+		{"dev", "v1.24.1", "<none>"},
+		{"prod", "v1.25.0", "<none>"},
+	}, tableFields(out))
+}
+
+func TestNoHeaders(t *testing.T) {
+	viper.Set("no-headers", true)
+	defer viper.Set("no-headers", false)
+
+	assert.Equal(t, [][]string{{"dev", "v1.24.1"}, {"prod", "v1.25.0"}},
+		tableFields(writeRows(t, "custom-columns=NAME:.ClusterName,VERSION:.K8sVersion")))
+
+	out := writeRows(t, "csv")
+	assert.Equal(t, "viziers,dev,5b27f024-eccb-4d07-b28d-84ab8d88e6a3,v1.24.1,1666000000000000000\n"+
+		"viziers,prod,00000000-0000-0000-0000-000000000000,v1.25.0,-1\n", out)
+}
+
+func TestValidateOutputFormat(t *testing.T) {
+	assert.NoError(t, components.ValidateOutputFormat(""))
+	assert.NoError(t, components.ValidateOutputFormat("json"))
+	assert.NoError(t, components.ValidateOutputFormat("go-template={{.ID}}"))
+	assert.NoError(t, components.ValidateOutputFormat("custom-columns=ID:.ID"))
+
+	assert.Error(t, components.ValidateOutputFormat("go-template={{.ID"))
+	assert.Error(t, components.ValidateOutputFormat("go-template"))
+	assert.Error(t, components.ValidateOutputFormat("jsonpath={.ID"))
+	assert.Error(t, components.ValidateOutputFormat("custom-columns=ID"))
+	assert.Error(t, components.ValidateOutputFormat("custom-columns=ID:{.ID"))
+}
+
+func TestNormalizeOutputFormat(t *testing.T) {
+	assert.Equal(t, "json", components.NormalizeOutputFormat("JSON"))
+	assert.Equal(t, "go-template={{.ClusterName}}", components.NormalizeOutputFormat("Go-Template={{.ClusterName}}"))
+	assert.True(t, components.IsStructuredFormat("jsonpath={.ID}"))
+	assert.False(t, components.IsStructuredFormat("custom-columns=ID:.ID"))
+}
+
+func TestTemplateStreamWriter_Time(t *testing.T) {
+	buf := &bytes.Buffer{}
+	w := components.CreateStreamWriter("jsonpath={.CreatedAt}", buf)
+	w.SetHeader("keys", []string{"CreatedAt"})
+	require.NoError(t, w.Write([]interface{}{time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)}))
+	assert.Equal(t, "2023-01-02T03:04:05Z\n", buf.String())
+}
+
+func tableFields(s string) [][]string {
+	var rows [][]string
+	for _, l := range strings.Split(strings.TrimRight(s, "\n"), "\n") {
+		rows = append(rows, strings.Fields(l))
+	}
+	return rows
+}
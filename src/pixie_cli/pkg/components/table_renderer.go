@@ -26,8 +26,6 @@ import (
 	"io"
 	"strings"
 	"time"
-
-	"github.com/olekukonko/tablewriter"
 )
 
 // OutputStreamWriter is the default interface for all output writers.
@@ -44,9 +42,11 @@ type TableView interface {
 	Data() [][]interface{}
 }
 
-// CreateStreamWriter creates a formatted writer with the default options.
+// CreateStreamWriter creates a formatted writer with the default options. Formats that take an
+// argument are of the form name=arg, for example "jsonpath={.ID}".
 func CreateStreamWriter(format string, w io.Writer) OutputStreamWriter {
-	switch format {
+	name, arg := ParseOutputFormat(format)
+	switch name {
 	case "json":
 		return NewJSONStreamWriter(w)
 	case "table":
@@ -57,6 +57,12 @@ func CreateStreamWriter(format string, w io.Writer) OutputStreamWriter {
 		return &NullStreamWriter{}
 	case "inmemory":
 		return NewTableAccumulator()
+	case FormatGoTemplate:
+		return NewGoTemplateStreamWriter(w, arg)
+	case FormatJSONPath:
+		return NewJSONPathStreamWriter(w, arg)
+	case FormatCustomColumns:
+		return NewCustomColumnsStreamWriter(w, arg)
 	default:
 		return NewTableStreamWriter(w)
 	}
@@ -120,29 +126,16 @@ func (t *TableStreamWriter) stringifyRow(row []interface{}) []string {
 
 // Finish is called when all the data has been sent. In the case of the table we can now render all the values.
 func (t *TableStreamWriter) Finish() {
-	fmt.Printf("Table ID: %s\n", t.id)
-	table := tablewriter.NewWriter(t.w)
-	table.SetHeader(t.headerValues)
-
-	table.SetAutoFormatHeaders(true)
-	table.SetAutoWrapText(false)
-	table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
-	table.SetAlignment(tablewriter.ALIGN_LEFT)
-	table.SetColWidth(30)
-	table.SetReflowDuringAutoWrap(true)
-	table.SetCenterSeparator("")
-	table.SetColumnSeparator("")
-	table.SetRowSeparator("")
-	table.SetHeaderLine(false)
-	table.SetBorder(false)
-	table.SetTablePadding("\t")
-	table.SetNoWhiteSpace(false)
-
-	for _, row := range t.data {
-		table.Append(t.stringifyRow(row))
+	rows := make([][]string, len(t.data))
+	for i, row := range t.data {
+		rows[i] = t.stringifyRow(row)
 	}
-
-	table.Render()
+	if noHeaders() {
+		renderTable(t.w, nil, rows)
+		return
+	}
+	fmt.Printf("Table ID: %s\n", t.id)
+	renderTable(t.w, t.headerValues, rows)
 }
 
 const tableNameKey = "_tableName_"
@@ -310,7 +303,7 @@ func (c *CSVStreamWriter) writeHeader() error {
 
 // Write is called for each record of data.
 func (c *CSVStreamWriter) Write(data []interface{}) error {
-	if !c.headerWritten {
+	if !c.headerWritten && !noHeaders() {
 		if err := c.writeHeader(); err != nil {
 			return err
 		}
@@ -0,0 +1,687 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package live
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gogo/protobuf/types"
+
+	"px.dev/pixie/src/api/proto/vispb"
+	"px.dev/pixie/src/pixie_cli/pkg/components"
+	"px.dev/pixie/src/pixie_cli/pkg/vizier"
+)
+
+// timeColumn is the column that timeseries charts use as the x-axis.
+const timeColumn = "time_"
+
+// seriesColors are the tview colors used for each series of a chart.
+var seriesColors = []string{logoColor, "yellow", "lime", "fuchsia", "orange", "dodgerblue", "red", "silver"}
+
+var ansiRegex = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+// chartSpec is the display spec of a widget that can be rendered as a chart.
+type chartSpec struct {
+	timeseries *vispb.TimeseriesChart
+	bar        *vispb.BarChart
+}
+
+func chartSpecFromWidget(w *vispb.Widget) *chartSpec {
+	if w.DisplaySpec == nil {
+		return nil
+	}
+	switch {
+	case types.Is(w.DisplaySpec, &vispb.TimeseriesChart{}):
+		ts := &vispb.TimeseriesChart{}
+		if err := types.UnmarshalAny(w.DisplaySpec, ts); err != nil || len(ts.Timeseries) == 0 {
+			return nil
+		}
+		return &chartSpec{timeseries: ts}
+	case types.Is(w.DisplaySpec, &vispb.BarChart{}):
+		bc := &vispb.BarChart{}
+		if err := types.UnmarshalAny(w.DisplaySpec, bc); err != nil || bc.Bar == nil {
+			return nil
+		}
+		return &chartSpec{bar: bc}
+	}
+	return nil
+}
+
+// chartSpecsForVis returns the chart specs of the widgets in the vis, keyed by the name of the
+// output table that each widget displays.
+func chartSpecsForVis(vis *vispb.Vis) map[string]*chartSpec {
+	specs := make(map[string]*chartSpec)
+	if vis == nil {
+		return specs
+	}
+	for _, w := range vis.Widgets {
+		name := w.Name
+		if ref, ok := w.FuncOrRef.(*vispb.Widget_GlobalFuncOutputName); ok {
+			name = ref.GlobalFuncOutputName
+		}
+		if name == "" {
+			continue
+		}
+		if spec := chartSpecFromWidget(w); spec != nil {
+			specs[name] = spec
+		}
+	}
+	return specs
+}
+
+// chartSpecForTable finds the chart spec for the output table. Tables are named after the widget that
+// produced them, and funcs with several outputs add a suffix to the widget name.
+func chartSpecForTable(specs map[string]*chartSpec, tableName string) *chartSpec {
+	if spec, ok := specs[tableName]; ok {
+		return spec
+	}
+	var best string
+	for name := range specs {
+		if len(name) <= len(best) || !strings.HasPrefix(tableName, name) {
+			continue
+		}
+		if next := tableName[len(name)]; next == '[' || next == '.' || next == '_' {
+			best = name
+		}
+	}
+	if best == "" {
+		return nil
+	}
+	return specs[best]
+}
+
+type chartPoint struct {
+	x float64
+	y float64
+}
+
+type chartSeries struct {
+	name   string
+	points []chartPoint
+}
+
+type barSegment struct {
+	series int
+	value  float64
+}
+
+type chartBar struct {
+	label    string
+	segments []barSegment
+}
+
+func (b *chartBar) total() float64 {
+	t := 0.0
+	for _, s := range b.segments {
+		t += s.value
+	}
+	return t
+}
+
+// chart is the data of a chart, ready to be rendered in the terminal.
+type chart struct {
+	title  string
+	xLabel string
+	yLabel string
+
+	// series are the lines of a timeseries chart, or the stacks of a bar chart.
+	series []*chartSeries
+	// bars are only set for bar charts.
+	bars  []*chartBar
+	isBar bool
+
+	formatX func(float64) string
+	formatY func(float64) string
+}
+
+// columnIndex returns the index of the column in the header. Columns can be referenced as
+// "outputs[N].column" when a func has several outputs.
+func columnIndex(header []string, col string) int {
+	if strings.HasPrefix(col, "outputs[") {
+		if i := strings.Index(col, "."); i != -1 {
+			col = col[i+1:]
+		}
+	}
+	for i, h := range header {
+		if h == col {
+			return i
+		}
+	}
+	return -1
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case bool:
+		if n {
+			return 1, true
+		}
+		return 0, true
+	case time.Time:
+		return float64(n.UnixNano()), true
+	}
+	return 0, false
+}
+
+// valueFormatter formats axis values with the semantic type of the column, e.g. as a latency or a
+// number of bytes. Values are converted back to the column's type since the formatter depends on it.
+func valueFormatter(f vizier.DataFormatter, colIdx int, sample interface{}) func(float64) string {
+	return func(v float64) string {
+		var val interface{} = v
+		switch sample.(type) {
+		case int64:
+			val = int64(math.Round(v))
+		case uint64:
+			val = uint64(math.Round(math.Max(v, 0)))
+		}
+		s := fmt.Sprint(f.FormatValue(colIdx, val))
+		return ansiRegex.ReplaceAllString(s, "")
+	}
+}
+
+func formatTimeAxis(v float64) string {
+	return time.Unix(0, int64(v)).Local().Format("15:04:05")
+}
+
+func axisLabel(a *vispb.Axis) string {
+	if a == nil {
+		return ""
+	}
+	return a.Label
+}
+
+// newChart builds the chart for the table according to the spec.
+func newChart(spec *chartSpec, t components.TableView, f vizier.DataFormatter) (*chart, error) {
+	if spec.timeseries != nil {
+		return newTimeseriesChart(spec.timeseries, t, f)
+	}
+	if spec.bar != nil {
+		return newBarChart(spec.bar, t, f)
+	}
+	return nil, errors.New("unsupported chart")
+}
+
+func newTimeseriesChart(spec *vispb.TimeseriesChart, t components.TableView, f vizier.DataFormatter) (*chart, error) {
+	header := t.Header()
+	data := t.Data()
+	timeIdx := columnIndex(header, timeColumn)
+	if timeIdx == -1 {
+		return nil, fmt.Errorf("table %s has no %s column", t.Name(), timeColumn)
+	}
+
+	c := &chart{
+		title:   spec.Title,
+		xLabel:  axisLabel(spec.XAxis),
+		yLabel:  axisLabel(spec.YAxis),
+		formatX: formatTimeAxis,
+	}
+	for _, ts := range spec.Timeseries {
+		valueIdx := columnIndex(header, ts.Value)
+		if valueIdx == -1 {
+			return nil, fmt.Errorf("table %s has no %s column", t.Name(), ts.Value)
+		}
+		seriesIdx := -1
+		if ts.Series != "" {
+			if seriesIdx = columnIndex(header, ts.Series); seriesIdx == -1 {
+				return nil, fmt.Errorf("table %s has no %s column", t.Name(), ts.Series)
+			}
+		}
+
+		byName := make(map[string]*chartSeries)
+		var names []string
+		for _, row := range data {
+			x, okX := toFloat(row[timeIdx])
+			y, okY := toFloat(row[valueIdx])
+			if !okX || !okY {
+				continue
+			}
+			if c.formatY == nil {
+				c.formatY = valueFormatter(f, valueIdx, row[valueIdx])
+			}
+			name := ts.Value
+			if seriesIdx != -1 {
+				name = fmt.Sprint(row[seriesIdx])
+				if len(spec.Timeseries) > 1 {
+					name += " " + ts.Value
+				}
+			}
+			s, ok := byName[name]
+			if !ok {
+				s = &chartSeries{name: name}
+				byName[name] = s
+				names = append(names, name)
+			}
+			s.points = append(s.points, chartPoint{x: x, y: y})
+		}
+
+		sort.Strings(names)
+		// Stacked series are drawn on top of the sum of the series before them.
+		stacked := make(map[float64]float64)
+		for _, name := range names {
+			s := byName[name]
+			sort.SliceStable(s.points, func(i, j int) bool { return s.points[i].x < s.points[j].x })
+			if ts.StackBySeries && seriesIdx != -1 {
+				for i, p := range s.points {
+					s.points[i].y += stacked[p.x]
+					stacked[p.x] = s.points[i].y
+				}
+			}
+			c.series = append(c.series, s)
+		}
+	}
+	if c.formatY == nil {
+		c.formatY = func(v float64) string { return fmt.Sprintf("%.4g", v) }
+	}
+	return c, nil
+}
+
+func newBarChart(spec *vispb.BarChart, t components.TableView, f vizier.DataFormatter) (*chart, error) {
+	header := t.Header()
+	bar := spec.Bar
+	valueIdx := columnIndex(header, bar.Value)
+	labelIdx := columnIndex(header, bar.Label)
+	if valueIdx == -1 || labelIdx == -1 {
+		return nil, fmt.Errorf("table %s is missing the %s or %s column", t.Name(), bar.Value, bar.Label)
+	}
+	stackIdx, groupIdx := -1, -1
+	if bar.StackBy != "" {
+		stackIdx = columnIndex(header, bar.StackBy)
+	}
+	if bar.GroupBy != "" {
+		groupIdx = columnIndex(header, bar.GroupBy)
+	}
+
+	// The terminal chart is always horizontal, with the label axis on the left and the value axis
+	// along the bottom.
+	c := &chart{
+		title:  spec.Title,
+		xLabel: axisLabel(spec.YAxis),
+		yLabel: axisLabel(spec.XAxis),
+		isBar:  true,
+	}
+	bars := make(map[string]*chartBar)
+	seriesIdx := make(map[string]int)
+	for _, row := range t.Data() {
+		v, ok := toFloat(row[valueIdx])
+		if !ok {
+			continue
+		}
+		if c.formatY == nil {
+			c.formatY = valueFormatter(f, valueIdx, row[valueIdx])
+		}
+		label := fmt.Sprint(row[labelIdx])
+		if groupIdx != -1 {
+			label = fmt.Sprint(row[groupIdx]) + "/" + label
+		}
+		stack := bar.Value
+		if stackIdx != -1 {
+			stack = fmt.Sprint(row[stackIdx])
+		}
+
+		b, ok := bars[label]
+		if !ok {
+			b = &chartBar{label: label}
+			bars[label] = b
+			c.bars = append(c.bars, b)
+		}
+		si, ok := seriesIdx[stack]
+		if !ok {
+			si = len(c.series)
+			seriesIdx[stack] = si
+			c.series = append(c.series, &chartSeries{name: stack})
+		}
+		found := false
+		for i := range b.segments {
+			if b.segments[i].series == si {
+				b.segments[i].value += v
+				found = true
+			}
+		}
+		if !found {
+			b.segments = append(b.segments, barSegment{series: si, value: v})
+		}
+	}
+	if c.formatY == nil {
+		c.formatY = func(v float64) string { return fmt.Sprintf("%.4g", v) }
+	}
+	return c, nil
+}
+
+func seriesColor(i int) string {
+	return seriesColors[i%len(seriesColors)]
+}
+
+// render draws the chart into lines of text with tview color tags, fitting the given size.
+func (c *chart) render(width, height int) []string {
+	if width <= 0 || height <= 0 {
+		return nil
+	}
+	var lines []string
+	if c.title != "" {
+		lines = append(lines, withAccent(c.title))
+	}
+	if c.yLabel != "" {
+		lines = append(lines, c.yLabel)
+	}
+
+	var footer []string
+	if c.xLabel != "" {
+		footer = append(footer, strings.Repeat(" ", max(0, (width-len(c.xLabel))/2))+c.xLabel)
+	}
+	if len(c.series) > 1 {
+		footer = append(footer, c.legend())
+	}
+
+	if c.isBar {
+		lines = append(lines, c.renderBars(width, height-len(lines)-len(footer))...)
+	} else {
+		lines = append(lines, c.renderTimeseries(width, height-len(lines)-len(footer))...)
+	}
+	lines = append(lines, footer...)
+	if len(lines) > height {
+		lines = lines[:height]
+	}
+	return lines
+}
+
+func (c *chart) legend() string {
+	parts := make([]string, len(c.series))
+	for i, s := range c.series {
+		parts[i] = fmt.Sprintf("[%s]■[%s] %s", seriesColor(i), textColor, s.name)
+	}
+	return strings.Join(parts, "  ")
+}
+
+// renderTimeseries draws the series as braille line plots, with the y-axis ticks on the left and the
+// time axis along the bottom.
+func (c *chart) renderTimeseries(width, height int) []string {
+	// Reserve two lines for the x-axis and its labels.
+	plotHeight := height - 2
+	if plotHeight < 2 {
+		return []string{"Not enough space to draw the chart."}
+	}
+
+	minX, maxX := math.Inf(1), math.Inf(-1)
+	minY, maxY := 0.0, math.Inf(-1)
+	for _, s := range c.series {
+		for _, p := range s.points {
+			minX, maxX = math.Min(minX, p.x), math.Max(maxX, p.x)
+			minY, maxY = math.Min(minY, p.y), math.Max(maxY, p.y)
+		}
+	}
+	if math.IsInf(maxX, -1) {
+		return []string{"No data."}
+	}
+	if maxY <= minY {
+		maxY = minY + 1
+	}
+	if maxX <= minX {
+		maxX = minX + 1
+	}
+
+	yTicks := map[int]string{
+		0:              c.formatY(maxY),
+		plotHeight / 2: c.formatY(minY + (maxY-minY)*float64(plotHeight-1-plotHeight/2)/float64(plotHeight-1)),
+		plotHeight - 1: c.formatY(minY),
+	}
+	gutter := 0
+	for _, t := range yTicks {
+		gutter = max(gutter, len([]rune(t)))
+	}
+	plotWidth := width - gutter - 2
+	if plotWidth < 2 {
+		return []string{"Not enough space to draw the chart."}
+	}
+
+	canvas := newBrailleCanvas(plotWidth, plotHeight)
+	dotW, dotH := float64(plotWidth*2-1), float64(plotHeight*4-1)
+	for i, s := range c.series {
+		prevX, prevY := -1, -1
+		for _, p := range s.points {
+			x := int(math.Round((p.x - minX) / (maxX - minX) * dotW))
+			y := int(math.Round((maxY - p.y) / (maxY - minY) * dotH))
+			if prevX == -1 {
+				canvas.set(x, y, i)
+			} else {
+				canvas.line(prevX, prevY, x, y, i)
+			}
+			prevX, prevY = x, y
+		}
+	}
+
+	lines := make([]string, 0, height)
+	for row, cells := range canvas.rows() {
+		axis := "│"
+		tick, ok := yTicks[row]
+		if ok {
+			axis = "┤"
+		}
+		lines = append(lines, fmt.Sprintf("%*s %s%s", gutter, tick, axis, cells))
+	}
+	lines = append(lines, strings.Repeat(" ", gutter+1)+"└"+strings.Repeat("─", plotWidth))
+
+	// Label the start, middle and end of the time axis.
+	labels := []rune(strings.Repeat(" ", plotWidth))
+	place := func(pos int, s string) {
+		r := []rune(s)
+		pos = min(max(pos, 0), len(labels)-len(r))
+		if pos < 0 {
+			return
+		}
+		copy(labels[pos:], r)
+	}
+	start, mid, end := c.formatX(minX), c.formatX((minX+maxX)/2), c.formatX(maxX)
+	place(0, start)
+	if plotWidth > len(start)+len(mid)+len(end)+4 {
+		place(plotWidth/2-len(mid)/2, mid)
+	}
+	if plotWidth > len(start)+len(end)+2 {
+		place(plotWidth-len(end), end)
+	}
+	lines = append(lines, strings.Repeat(" ", gutter+2)+string(labels))
+	return lines
+}
+
+var partialBlocks = []rune{' ', '▏', '▎', '▍', '▌', '▋', '▊', '▉'}
+
+// renderBars draws one horizontal bar per label. Stacked segments are drawn in the color of their series.
+func (c *chart) renderBars(width, height int) []string {
+	if len(c.bars) == 0 {
+		return []string{"No data."}
+	}
+	if height < 1 {
+		return nil
+	}
+
+	maxTotal := 0.0
+	labelWidth := 0
+	valueWidth := 0
+	for _, b := range c.bars {
+		maxTotal = math.Max(maxTotal, b.total())
+		labelWidth = max(labelWidth, len([]rune(b.label)))
+		valueWidth = max(valueWidth, len([]rune(c.formatY(b.total()))))
+	}
+	labelWidth = min(labelWidth, width/3)
+	barWidth := width - labelWidth - valueWidth - 2
+	if barWidth < 1 {
+		return []string{"Not enough space to draw the chart."}
+	}
+	if maxTotal <= 0 {
+		maxTotal = 1
+	}
+
+	bars := c.bars
+	truncated := 0
+	if len(bars) > height {
+		truncated = len(bars) - (height - 1)
+		bars = bars[:height-1]
+	}
+
+	lines := make([]string, 0, len(bars)+1)
+	for _, b := range bars {
+		label := []rune(b.label)
+		if len(label) > labelWidth {
+			label = append(label[:max(labelWidth-1, 0)], '…')
+		}
+		sb := strings.Builder{}
+		sb.WriteString(fmt.Sprintf("%*s ", labelWidth, string(label)))
+
+		// Each segment ends at the cumulative value of the bar so far, measured in eighths of a cell.
+		cum := 0.0
+		drawn := 0
+		for i, seg := range b.segments {
+			cum += math.Max(seg.value, 0)
+			eighths := int(math.Round(cum / maxTotal * float64(barWidth*8)))
+			cells := eighths/8 - drawn
+			sb.WriteString(fmt.Sprintf("[%s]", seriesColor(seg.series)))
+			sb.WriteString(strings.Repeat("█", max(cells, 0)))
+			drawn += max(cells, 0)
+			if i == len(b.segments)-1 && eighths%8 != 0 {
+				sb.WriteRune(partialBlocks[eighths%8])
+				drawn++
+			}
+		}
+		sb.WriteString(fmt.Sprintf("[%s]", textColor))
+		sb.WriteString(strings.Repeat(" ", max(barWidth-drawn, 0)))
+		sb.WriteString(" " + c.formatY(b.total()))
+		lines = append(lines, sb.String())
+	}
+	if truncated > 0 {
+		lines = append(lines, fmt.Sprintf("%*s … %d more", labelWidth, "", truncated))
+	}
+	return lines
+}
+
+// brailleCanvas is a grid of braille characters, where each character is 2x4 dots.
+type brailleCanvas struct {
+	width, height int
+	cells         [][]rune
+	colors        [][]int
+}
+
+func newBrailleCanvas(width, height int) *brailleCanvas {
+	c := &brailleCanvas{
+		width:  width,
+		height: height,
+		cells:  make([][]rune, height),
+		colors: make([][]int, height),
+	}
+	for i := range c.cells {
+		c.cells[i] = make([]rune, width)
+		c.colors[i] = make([]int, width)
+	}
+	return c
+}
+
+// brailleDots maps the dot position within a cell to the bit of the braille character.
+var brailleDots = [4][2]rune{
+	{0x01, 0x08},
+	{0x02, 0x10},
+	{0x04, 0x20},
+	{0x40, 0x80},
+}
+
+// set turns on the dot at the given dot coordinates, drawn in the color of the series.
+func (c *brailleCanvas) set(x, y, series int) {
+	if x < 0 || y < 0 || x >= c.width*2 || y >= c.height*4 {
+		return
+	}
+	c.cells[y/4][x/2] |= brailleDots[y%4][x%2]
+	c.colors[y/4][x/2] = series
+}
+
+// line draws a line between two dots using Bresenham's algorithm.
+func (c *brailleCanvas) line(x0, y0, x1, y1, series int) {
+	dx := x1 - x0
+	if dx < 0 {
+		dx = -dx
+	}
+	dy := y1 - y0
+	if dy > 0 {
+		dy = -dy
+	}
+	sx, sy := 1, 1
+	if x0 > x1 {
+		sx = -1
+	}
+	if y0 > y1 {
+		sy = -1
+	}
+	e := dx + dy
+	for {
+		c.set(x0, y0, series)
+		if x0 == x1 && y0 == y1 {
+			return
+		}
+		e2 := 2 * e
+		if e2 >= dy {
+			e += dy
+			x0 += sx
+		}
+		if e2 <= dx {
+			e += dx
+			y0 += sy
+		}
+	}
+}
+
+// rows returns each row of the canvas as a string with tview color tags.
+func (c *brailleCanvas) rows() []string {
+	rows := make([]string, c.height)
+	for y := range c.cells {
+		sb := strings.Builder{}
+		color := -1
+		for x, bits := range c.cells[y] {
+			if bits == 0 {
+				sb.WriteRune(' ')
+				continue
+			}
+			if c.colors[y][x] != color {
+				color = c.colors[y][x]
+				sb.WriteString(fmt.Sprintf("[%s]", seriesColor(color)))
+			}
+			sb.WriteRune(0x2800 + bits)
+		}
+		if color != -1 {
+			sb.WriteString(fmt.Sprintf("[%s]", textColor))
+		}
+		rows[y] = sb.String()
+	}
+	return rows
+}
@@ -0,0 +1,182 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package live
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gogo/protobuf/types"
+	"github.com/rivo/tview"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"px.dev/pixie/src/api/proto/vispb"
+)
+
+type fakeTable struct {
+	name   string
+	header []string
+	data   [][]interface{}
+}
+
+func (t *fakeTable) Name() string          { return t.name }
+func (t *fakeTable) Header() []string      { return t.header }
+func (t *fakeTable) Data() [][]interface{} { return t.data }
+
+// fakeFormatter formats values with a unit, and colors them like the real formatter.
+type fakeFormatter struct{}
+
+func (f *fakeFormatter) FormatValue(colIdx int, val interface{}) interface{} {
+	return fmt.Sprintf("\x1b[32m%vms\x1b[0m", val)
+}
+
+func timeseriesWidget(t *testing.T, name string, ts *vispb.TimeseriesChart) *vispb.Widget {
+	spec, err := types.MarshalAny(ts)
+	require.NoError(t, err)
+	return &vispb.Widget{Name: name, DisplaySpec: spec}
+}
+
+func TestChartSpecsForVis(t *testing.T) {
+	barSpec, err := types.MarshalAny(&vispb.BarChart{Bar: &vispb.BarChart_Bar{Value: "count", Label: "svc"}})
+	require.NoError(t, err)
+	tableSpec, err := types.MarshalAny(&vispb.Table{})
+	require.NoError(t, err)
+
+	vis := &vispb.Vis{
+		Widgets: []*vispb.Widget{
+			timeseriesWidget(t, "latency", &vispb.TimeseriesChart{
+				Timeseries: []*vispb.TimeseriesChart_Timeseries{{Value: "latency_p50"}},
+			}),
+			{Name: "bars", DisplaySpec: barSpec},
+			{Name: "table", DisplaySpec: tableSpec},
+			{
+				FuncOrRef:   &vispb.Widget_GlobalFuncOutputName{GlobalFuncOutputName: "global_latency"},
+				DisplaySpec: barSpec,
+			},
+		},
+	}
+	specs := chartSpecsForVis(vis)
+	assert.Len(t, specs, 3)
+	assert.NotNil(t, specs["latency"].timeseries)
+	assert.NotNil(t, specs["bars"].bar)
+	assert.NotNil(t, specs["global_latency"].bar)
+
+	assert.Equal(t, specs["latency"], chartSpecForTable(specs, "latency"))
+	assert.Equal(t, specs["latency"], chartSpecForTable(specs, "latency[0]"))
+	assert.Equal(t, specs["global_latency"], chartSpecForTable(specs, "global_latency"))
+	assert.Nil(t, chartSpecForTable(specs, "table"))
+	assert.Nil(t, chartSpecForTable(specs, "latencyx"))
+}
+
+func TestNewTimeseriesChart(t *testing.T) {
+	start := time.Unix(1600000000, 0)
+	table := &fakeTable{
+		name:   "latency",
+		header: []string{"time_", "service", "latency_p50"},
+		data: [][]interface{}{
+			{start.Add(10 * time.Second), "a", int64(20)},
+			{start, "a", int64(10)},
+			{start, "b", int64(5)},
+			{start.Add(10 * time.Second), "b", int64(7)},
+		},
+	}
+	spec := &chartSpec{timeseries: &vispb.TimeseriesChart{
+		Title: "Latency",
+		Timeseries: []*vispb.TimeseriesChart_Timeseries{
+			{Value: "latency_p50", Series: "service", StackBySeries: true},
+		},
+	}}
+
+	c, err := newChart(spec, table, &fakeFormatter{})
+	require.NoError(t, err)
+	require.Len(t, c.series, 2)
+	assert.Equal(t, "a", c.series[0].name)
+	assert.Equal(t, []chartPoint{
+		{x: float64(start.UnixNano()), y: 10},
+		{x: float64(start.Add(10 * time.Second).UnixNano()), y: 20},
+	}, c.series[0].points)
+	// The second series is stacked on top of the first.
+	assert.Equal(t, "b", c.series[1].name)
+	assert.Equal(t, 15.0, c.series[1].points[0].y)
+	assert.Equal(t, 27.0, c.series[1].points[1].y)
+
+	// The formatter's colors are stripped, and values keep their type.
+	assert.Equal(t, "27ms", c.formatY(27.2))
+
+	lines := c.render(60, 15)
+	assert.Len(t, lines, 15)
+	for _, l := range lines {
+		assert.LessOrEqual(t, tview.TaggedStringWidth(l), 60)
+	}
+	assert.Contains(t, lines[0], "Latency")
+	assert.Contains(t, lines[1], "27ms")
+	assert.Contains(t, lines[len(lines)-1], "■")
+}
+
+func TestNewTimeseriesChart_MissingColumn(t *testing.T) {
+	table := &fakeTable{
+		name:   "latency",
+		header: []string{"service", "latency_p50"},
+	}
+	spec := &chartSpec{timeseries: &vispb.TimeseriesChart{
+		Timeseries: []*vispb.TimeseriesChart_Timeseries{{Value: "latency_p50"}},
+	}}
+	_, err := newChart(spec, table, &fakeFormatter{})
+	assert.Error(t, err)
+}
+
+func TestNewBarChart(t *testing.T) {
+	table := &fakeTable{
+		name:   "bars",
+		header: []string{"service", "status", "count"},
+		data: [][]interface{}{
+			{"a", "200", float64(30)},
+			{"a", "500", float64(10)},
+			{"b", "200", float64(20)},
+			{"c", "200", float64(5)},
+			{"d", "200", float64(1)},
+		},
+	}
+	spec := &chartSpec{bar: &vispb.BarChart{
+		Bar: &vispb.BarChart_Bar{Value: "count", Label: "service", StackBy: "status"},
+	}}
+
+	c, err := newChart(spec, table, &fakeFormatter{})
+	require.NoError(t, err)
+	require.Len(t, c.bars, 4)
+	assert.Equal(t, "a", c.bars[0].label)
+	assert.Equal(t, 40.0, c.bars[0].total())
+	require.Len(t, c.series, 2)
+	assert.Equal(t, "200", c.series[0].name)
+	assert.Equal(t, "500", c.series[1].name)
+
+	lines := c.render(40, 4)
+	require.Len(t, lines, 4)
+	for _, l := range lines {
+		assert.LessOrEqual(t, tview.TaggedStringWidth(l), 40)
+	}
+	// The longest bar fills the available width.
+	assert.True(t, strings.HasPrefix(lines[0], "a ["))
+	assert.Contains(t, lines[0], "40ms")
+	assert.Contains(t, lines[2], "… 2 more")
+	assert.Contains(t, lines[3], "■")
+}
@@ -0,0 +1,48 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package live
+
+import (
+	"github.com/gdamore/tcell"
+	"github.com/rivo/tview"
+)
+
+// chartView is a primitive that draws a chart, resized to fit the space it is given.
+type chartView struct {
+	*tview.Box
+	chart *chart
+}
+
+func newChartView(c *chart) *chartView {
+	v := &chartView{
+		Box:   tview.NewBox(),
+		chart: c,
+	}
+	v.SetBorderPadding(1, 0, 1, 1)
+	return v
+}
+
+// Draw draws the chart onto the screen.
+func (v *chartView) Draw(screen tcell.Screen) {
+	v.Box.Draw(screen)
+	x, y, width, height := v.GetInnerRect()
+	for i, line := range v.chart.render(width, height) {
+		tview.Print(screen, line, x, y+i, width, tview.AlignLeft, tcell.ColorWhite)
+	}
+}
@@ -43,9 +43,15 @@ func (h *helpModal) Show(app *tview.Application) tview.Primitive {
 		{[]string{"?"}, "Show this help menu"},
 		{[]string{"ctrl", "s"}, "Search for text (\"/\")"},
 		{[]string{"ctrl", "k"}, "Show Pixie command menu"},
+		{[]string{"ctrl", "p"}, "Search script history and saved queries"},
+		{[]string{"ctrl", "n"}, "Select the next table"},
+		{[]string{"ctrl", "b"}, "Select the previous table"},
 		{[]string{"ctrl", "c"}, "Quit the application"},
 		{[]string{"ctrl", "v"}, "View the underlying script"},
 		{[]string{"ctrl", "r"}, "Run current script (again)"},
+		{[]string{"ctrl", "a"}, "Change the auto-refresh interval"},
+		{[]string{"ctrl", "t"}, "Change the time range"},
+		{[]string{"c"}, "Toggle between chart and table"},
 		{[]string{"escape"}, "Close dialogs/modals"},
 	}
 
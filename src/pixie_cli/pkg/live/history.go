@@ -0,0 +1,315 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package live
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sahilm/fuzzy"
+
+	"px.dev/pixie/src/utils/script"
+)
+
+// maxHistoryEntries is the number of executed scripts kept in the history of each cluster.
+const maxHistoryEntries = 200
+
+// historyEntry is a script and the arguments it was executed with.
+type historyEntry struct {
+	ScriptName string            `json:"scriptName"`
+	Args       map[string]string `json:"args,omitempty"`
+	// ScriptString is only stored for local scripts. Bundle scripts are looked up by name, so that
+	// re-running an entry picks up updates to the bundle.
+	ScriptString string    `json:"scriptString,omitempty"`
+	IsLocal      bool      `json:"isLocal,omitempty"`
+	ExecutedAt   time.Time `json:"executedAt"`
+}
+
+// savedQuery is a history entry that the user pinned under a name.
+type savedQuery struct {
+	Name  string        `json:"name"`
+	Entry *historyEntry `json:"entry"`
+}
+
+func newHistoryEntry(es *script.ExecutableScript) *historyEntry {
+	e := &historyEntry{
+		ScriptName: es.ScriptName,
+		IsLocal:    es.IsLocal,
+		ExecutedAt: time.Now(),
+	}
+	if len(es.Args) > 0 {
+		e.Args = make(map[string]string, len(es.Args))
+		for name, arg := range es.Args {
+			e.Args[name] = arg.Value
+		}
+	}
+	if es.IsLocal {
+		e.ScriptString = es.ScriptString
+	}
+	return e
+}
+
+// sameInvocation returns whether both entries ran the same script with the same arguments.
+func (e *historyEntry) sameInvocation(o *historyEntry) bool {
+	if e.ScriptName != o.ScriptName || e.IsLocal != o.IsLocal || e.ScriptString != o.ScriptString {
+		return false
+	}
+	if len(e.Args) != len(o.Args) {
+		return false
+	}
+	for k, v := range e.Args {
+		if ov, ok := o.Args[k]; !ok || ov != v {
+			return false
+		}
+	}
+	return true
+}
+
+// String returns the entry as it would be typed into the command menu.
+func (e *historyEntry) String() string {
+	sb := strings.Builder{}
+	sb.WriteString(e.ScriptName)
+	names := make([]string, 0, len(e.Args))
+	for name := range e.Args {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		sb.WriteString(fmt.Sprintf(" --%s=%s", name, e.Args[name]))
+	}
+	return sb.String()
+}
+
+// executableScript turns the entry back into a script that can be run.
+func (e *historyEntry) executableScript(br *script.BundleManager) (*script.ExecutableScript, error) {
+	if e.IsLocal {
+		es := &script.ExecutableScript{
+			ScriptName:   e.ScriptName,
+			ScriptString: e.ScriptString,
+			ShortDoc:     "Script supplied by user",
+			LongDoc:      "Script supplied by user",
+			IsLocal:      true,
+		}
+		if len(e.Args) > 0 {
+			es.Args = make(map[string]script.Arg, len(e.Args))
+			for name, value := range e.Args {
+				es.Args[name] = script.Arg{Name: name, Value: value}
+			}
+		}
+		return es, nil
+	}
+
+	if br == nil {
+		return nil, errors.New("no script bundle available")
+	}
+	es, err := br.GetScript(e.ScriptName)
+	if err != nil {
+		return nil, err
+	}
+	fs := es.GetFlagSet()
+	if fs == nil || len(e.Args) == 0 {
+		return es, nil
+	}
+	for name, value := range e.Args {
+		if err := fs.Set(name, value); err != nil {
+			return nil, err
+		}
+	}
+	if err := es.UpdateFlags(fs); err != nil {
+		return nil, err
+	}
+	return es, nil
+}
+
+// scriptHistory is the persisted history and saved queries of a single cluster.
+type scriptHistory struct {
+	// Entries are ordered from most to least recently executed.
+	Entries []*historyEntry `json:"entries,omitempty"`
+	Saved   []*savedQuery   `json:"saved,omitempty"`
+
+	path string
+}
+
+// loadScriptHistory reads the history from the given path. A missing file results in an empty history.
+func loadScriptHistory(path string) (*scriptHistory, error) {
+	h := &scriptHistory{path: path}
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return h, nil
+	}
+	if err != nil {
+		return h, err
+	}
+	defer f.Close()
+
+	if err := json.NewDecoder(f).Decode(h); err != nil {
+		return &scriptHistory{path: path}, err
+	}
+	return h, nil
+}
+
+// save persists the history. It is a noop for histories that aren't backed by a file.
+func (h *scriptHistory) save() error {
+	if h.path == "" {
+		return nil
+	}
+	f, err := os.OpenFile(h.path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return json.NewEncoder(f).Encode(h)
+}
+
+// add records an executed script. Re-running a script with the same arguments moves it to the top
+// of the history instead of adding a duplicate.
+func (h *scriptHistory) add(e *historyEntry) {
+	entries := []*historyEntry{e}
+	for _, existing := range h.Entries {
+		if existing.sameInvocation(e) {
+			continue
+		}
+		entries = append(entries, existing)
+	}
+	if len(entries) > maxHistoryEntries {
+		entries = entries[:maxHistoryEntries]
+	}
+	h.Entries = entries
+}
+
+// savedQuery returns the saved query with the given name, or nil if it doesn't exist.
+func (h *scriptHistory) savedQuery(name string) *savedQuery {
+	for _, q := range h.Saved {
+		if q.Name == name {
+			return q
+		}
+	}
+	return nil
+}
+
+// pin saves the entry under the given name, replacing any saved query with the same name.
+func (h *scriptHistory) pin(name string, e *historyEntry) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return errors.New("saved query name must not be empty")
+	}
+	if strings.ContainsAny(name, " \t") {
+		return errors.New("saved query name must not contain whitespace")
+	}
+	if q := h.savedQuery(name); q != nil {
+		q.Entry = e
+		return nil
+	}
+	h.Saved = append(h.Saved, &savedQuery{Name: name, Entry: e})
+	sort.Slice(h.Saved, func(i, j int) bool { return h.Saved[i].Name < h.Saved[j].Name })
+	return nil
+}
+
+// unpin deletes the saved query with the given name.
+func (h *scriptHistory) unpin(name string) {
+	for i, q := range h.Saved {
+		if q.Name == name {
+			h.Saved = append(h.Saved[:i], h.Saved[i+1:]...)
+			return
+		}
+	}
+}
+
+// savedQuerySuggestions returns autocomplete suggestions for the saved queries whose names fuzzy
+// match the input.
+func (h *scriptHistory) savedQuerySuggestions(input string) []suggestion {
+	var suggestions []suggestion
+	if input == "" {
+		for _, q := range h.Saved {
+			suggestions = append(suggestions, suggestion{name: q.Name, desc: q.Entry.String(), savedQuery: q})
+		}
+		return suggestions
+	}
+
+	names := make([]string, len(h.Saved))
+	for i, q := range h.Saved {
+		names[i] = q.Name
+	}
+	for _, m := range fuzzy.Find(input, names) {
+		q := h.Saved[m.Index]
+		suggestions = append(suggestions, suggestion{
+			name:           q.Name,
+			desc:           q.Entry.String(),
+			savedQuery:     q,
+			matchedIndexes: m.MatchedIndexes,
+		})
+	}
+	return suggestions
+}
+
+// historyItem is either a saved query or a history entry, as listed by the history browser.
+type historyItem struct {
+	saved *savedQuery
+	entry *historyEntry
+
+	matchedIndexes []int
+}
+
+func (i *historyItem) String() string {
+	if i.saved != nil {
+		return i.saved.Name + ": " + i.saved.Entry.String()
+	}
+	return i.entry.String()
+}
+
+// search returns the saved queries followed by the history entries that fuzzy match the query. An
+// empty query returns everything in order.
+func (h *scriptHistory) search(query string) []*historyItem {
+	items := make([]*historyItem, 0, len(h.Saved)+len(h.Entries))
+	for _, q := range h.Saved {
+		items = append(items, &historyItem{saved: q, entry: q.Entry})
+	}
+	for _, e := range h.Entries {
+		items = append(items, &historyItem{entry: e})
+	}
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return items
+	}
+
+	strs := make([]string, len(items))
+	for i, item := range items {
+		strs[i] = item.String()
+	}
+	matches := fuzzy.Find(query, strs)
+	// Keep saved queries ahead of history entries, and otherwise order by match score.
+	sort.SliceStable(matches, func(i, j int) bool {
+		si := items[matches[i].Index].saved != nil
+		sj := items[matches[j].Index].saved != nil
+		return si && !sj
+	})
+	results := make([]*historyItem, len(matches))
+	for i, m := range matches {
+		item := *items[m.Index]
+		item.matchedIndexes = m.MatchedIndexes
+		results[i] = &item
+	}
+	return results
+}
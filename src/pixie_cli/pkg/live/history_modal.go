@@ -0,0 +1,282 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package live
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gdamore/tcell"
+	"github.com/rivo/tview"
+
+	"px.dev/pixie/src/utils/script"
+)
+
+const (
+	historySearchLabel = "Search: "
+	historySaveLabel   = "Save as: "
+)
+
+// historyModal is the history browser. It lists saved queries and previously executed scripts, which
+// can be searched, re-run and pinned.
+type historyModal struct {
+	// Reference to the parent view.
+	s *appState
+
+	// The input box, used for searching, and for naming a saved query.
+	ib *tview.InputField
+	// The list of matching history items.
+	hl *tview.List
+	// The details of the selected item.
+	dt *tview.TextView
+
+	layout *tview.Flex
+
+	items          []*historyItem
+	scriptExecFunc func(*script.ExecutableScript)
+	// pinning is the item being saved, while the user is typing a name for it.
+	pinning *historyItem
+}
+
+func newHistoryModal(st *appState) *historyModal {
+	// The history view has the same layout as the autocomplete modal:
+	//  ------------------------------------------
+	//  | Text box for search                    |
+	//  |________________________________________|
+	//  |  History           | Details           |
+	//  |  List              |                   |
+	//  |____________________|___________________|
+	//
+	// Saved queries are listed before the history. Hitting enter runs the selected item, ctrl-s
+	// saves it under a name, and ctrl-d deletes a saved query.
+
+	inputBox := tview.NewInputField()
+	inputBox.SetBackgroundColor(tcell.ColorBlack)
+	inputBox.
+		SetLabel(historySearchLabel).
+		SetFieldBackgroundColor(tcell.ColorBlack).
+		SetBorder(true)
+
+	historyList := tview.NewList()
+	historyList.
+		ShowSecondaryText(false).
+		SetBorder(true)
+
+	detailsBox := tview.NewTextView()
+	detailsBox.
+		SetDynamicColors(true).
+		SetBorder(true)
+
+	horiz := tview.NewFlex().
+		SetDirection(tview.FlexColumn).
+		AddItem(historyList, 0, 10, false).
+		AddItem(detailsBox, 0, 7, false)
+
+	layout := tview.NewFlex().
+		SetDirection(tview.FlexRow).
+		AddItem(inputBox, 3, 0, true).
+		AddItem(horiz, 0, 1, false)
+
+	return &historyModal{
+		s:      st,
+		ib:     inputBox,
+		hl:     historyList,
+		dt:     detailsBox,
+		layout: layout,
+	}
+}
+
+func (m *historyModal) updateItems(query string) {
+	m.items = m.s.history.search(query)
+	m.hl.Clear()
+	for i, item := range m.items {
+		name := item.String()
+		sb := strings.Builder{}
+		if item.saved != nil {
+			sb.WriteString("[yellow]saved:[white]")
+		}
+		for j := 0; j < len(name); j++ {
+			if contains(j, item.matchedIndexes) {
+				sb.WriteString(fmt.Sprintf("[green]%s[white]", string(name[j])))
+			} else {
+				sb.WriteByte(name[j])
+			}
+		}
+		m.hl.InsertItem(i, sb.String(), name, 0, nil)
+	}
+	m.updateDetails(0)
+}
+
+func (m *historyModal) updateDetails(i int) {
+	m.dt.Clear()
+	if i < 0 || i >= len(m.items) {
+		if len(m.s.history.Entries) == 0 && len(m.s.history.Saved) == 0 {
+			m.dt.SetText("No scripts have been run on this cluster yet.")
+		}
+		return
+	}
+	item := m.items[i]
+	if item.saved != nil {
+		fmt.Fprintf(m.dt, "%s %s\n", withAccent("Saved Query:"), tview.Escape(item.saved.Name))
+	}
+	fmt.Fprintf(m.dt, "%s %s\n", withAccent("Script:"), tview.Escape(item.entry.ScriptName))
+	if len(item.entry.Args) > 0 {
+		fmt.Fprintf(m.dt, "%s\n", withAccent("Args:"))
+		for name, value := range item.entry.Args {
+			fmt.Fprintf(m.dt, "  --%s=%s\n", tview.Escape(name), tview.Escape(value))
+		}
+	}
+	if !item.entry.ExecutedAt.IsZero() {
+		fmt.Fprintf(m.dt, "%s %s\n", withAccent("Last Run:"), item.entry.ExecutedAt.Local().Format("2006-01-02 15:04:05"))
+	}
+}
+
+func (m *historyModal) selectedItem() *historyItem {
+	i := m.hl.GetCurrentItem()
+	if i < 0 || i >= len(m.items) {
+		return nil
+	}
+	return m.items[i]
+}
+
+func (m *historyModal) run(item *historyItem) {
+	if item == nil {
+		return
+	}
+	es, err := item.entry.executableScript(m.s.br)
+	if err != nil {
+		m.dt.SetText(fmt.Sprintf("[red]Failed to load script:[white] %s", tview.Escape(err.Error())))
+		return
+	}
+	if m.scriptExecFunc != nil {
+		m.scriptExecFunc(es)
+	}
+}
+
+func (m *historyModal) startPinning(app *tview.Application, item *historyItem) {
+	if item == nil {
+		return
+	}
+	m.pinning = item
+	m.ib.SetLabel(historySaveLabel)
+	name := ""
+	if item.saved != nil {
+		name = item.saved.Name
+	}
+	m.ib.SetText(name)
+	app.SetFocus(m.ib)
+}
+
+func (m *historyModal) finishPinning(name string) {
+	item := m.pinning
+	m.pinning = nil
+	m.ib.SetLabel(historySearchLabel)
+	m.ib.SetText("")
+	if err := m.s.history.pin(name, item.entry); err != nil {
+		m.dt.SetText(fmt.Sprintf("[red]Failed to save query:[white] %s", tview.Escape(err.Error())))
+		return
+	}
+	m.updateItems("")
+	m.saveHistory()
+}
+
+func (m *historyModal) saveHistory() {
+	if err := m.s.history.save(); err != nil {
+		m.dt.SetText(fmt.Sprintf("[red]Failed to save history:[white] %s", tview.Escape(err.Error())))
+	}
+}
+
+// Show shows the modal.
+func (m *historyModal) Show(app *tview.Application) tview.Primitive {
+	m.updateItems("")
+
+	m.ib.SetChangedFunc(func(currentText string) {
+		if m.pinning != nil {
+			return
+		}
+		m.updateItems(currentText)
+	})
+
+	m.ib.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyDown:
+			if m.pinning == nil {
+				app.SetFocus(m.hl)
+				return nil
+			}
+		case tcell.KeyEnter:
+			if m.pinning != nil {
+				m.finishPinning(m.ib.GetText())
+				return nil
+			}
+			// Run the best match.
+			if len(m.items) > 0 {
+				m.run(m.items[0])
+			}
+			return nil
+		case tcell.KeyTAB:
+			if m.pinning == nil {
+				app.SetFocus(m.hl)
+			}
+			return nil
+		}
+		return event
+	})
+
+	m.hl.SetChangedFunc(func(i int, mainText string, secondaryText string, r rune) {
+		m.updateDetails(i)
+	})
+
+	m.hl.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyEnter:
+			m.run(m.selectedItem())
+			return nil
+		case tcell.KeyCtrlS:
+			m.startPinning(app, m.selectedItem())
+			return nil
+		case tcell.KeyCtrlD:
+			item := m.selectedItem()
+			if item != nil && item.saved != nil {
+				m.s.history.unpin(item.saved.Name)
+				m.updateItems(m.ib.GetText())
+				m.saveHistory()
+			}
+			return nil
+		case tcell.KeyUp:
+			// If you press up and on item zero move up to the input box.
+			if m.hl.GetCurrentItem() == 0 {
+				app.SetFocus(m.ib)
+				return nil
+			}
+		}
+		return event
+	})
+
+	app.SetFocus(m.ib)
+	return m.layout
+}
+
+// SetScriptExecFunc sets the script exec func for the modal.
+func (m *historyModal) SetScriptExecFunc(f func(s *script.ExecutableScript)) {
+	m.scriptExecFunc = f
+}
+
+// Close is called when the modal is closed. Nothing to do for the history modal.
+func (m *historyModal) Close(app *tview.Application) {}
@@ -0,0 +1,141 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package live
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"px.dev/pixie/src/pixie_cli/pkg/vizier"
+)
+
+func TestScriptHistory_Add(t *testing.T) {
+	h := &scriptHistory{}
+	h.add(&historyEntry{ScriptName: "px/cluster"})
+	h.add(&historyEntry{ScriptName: "px/namespace", Args: map[string]string{"namespace": "default"}})
+	h.add(&historyEntry{ScriptName: "px/namespace", Args: map[string]string{"namespace": "pl"}})
+	// Running the same script with the same args again moves it to the top.
+	h.add(&historyEntry{ScriptName: "px/cluster"})
+
+	require.Len(t, h.Entries, 3)
+	assert.Equal(t, "px/cluster", h.Entries[0].String())
+	assert.Equal(t, "px/namespace --namespace=pl", h.Entries[1].String())
+	assert.Equal(t, "px/namespace --namespace=default", h.Entries[2].String())
+}
+
+func TestScriptHistory_AddTruncates(t *testing.T) {
+	h := &scriptHistory{}
+	for i := 0; i < maxHistoryEntries+10; i++ {
+		h.add(&historyEntry{ScriptName: fmt.Sprintf("script_%d", i)})
+	}
+	require.Len(t, h.Entries, maxHistoryEntries)
+	assert.Equal(t, fmt.Sprintf("script_%d", maxHistoryEntries+9), h.Entries[0].ScriptName)
+}
+
+func TestScriptHistory_PinAndUnpin(t *testing.T) {
+	h := &scriptHistory{}
+	e := &historyEntry{ScriptName: "px/pod", Args: map[string]string{"pod": "pl/vizier-pem"}}
+
+	assert.Error(t, h.pin("", e))
+	assert.Error(t, h.pin("my pem", e))
+	require.NoError(t, h.pin("pem", e))
+	require.NoError(t, h.pin("cluster", &historyEntry{ScriptName: "px/cluster"}))
+	require.NoError(t, h.pin("pem", &historyEntry{ScriptName: "px/pod", Args: map[string]string{"pod": "pl/kelvin"}}))
+
+	require.Len(t, h.Saved, 2)
+	assert.Equal(t, "cluster", h.Saved[0].Name)
+	assert.Equal(t, "px/pod --pod=pl/kelvin", h.savedQuery("pem").Entry.String())
+
+	h.unpin("pem")
+	assert.Nil(t, h.savedQuery("pem"))
+	assert.Len(t, h.Saved, 1)
+}
+
+func TestScriptHistory_Search(t *testing.T) {
+	h := &scriptHistory{}
+	h.add(&historyEntry{ScriptName: "px/http_data"})
+	h.add(&historyEntry{ScriptName: "px/cluster"})
+	h.add(&historyEntry{ScriptName: "px/namespace", Args: map[string]string{"namespace": "default"}})
+	require.NoError(t, h.pin("http", &historyEntry{ScriptName: "px/http_data", Args: map[string]string{"start_time": "-30m"}}))
+
+	all := h.search("")
+	require.Len(t, all, 4)
+	assert.NotNil(t, all[0].saved)
+
+	results := h.search("http")
+	require.Len(t, results, 2)
+	// Saved queries are listed first.
+	assert.Equal(t, "http", results[0].saved.Name)
+	assert.Nil(t, results[1].saved)
+	assert.Equal(t, "px/http_data", results[1].entry.String())
+	assert.NotEmpty(t, results[1].matchedIndexes)
+
+	assert.Empty(t, h.search("zzz"))
+}
+
+func TestScriptHistory_SavedQuerySuggestions(t *testing.T) {
+	h := &scriptHistory{}
+	require.NoError(t, h.pin("errors", &historyEntry{ScriptName: "px/http_data_filtered"}))
+	require.NoError(t, h.pin("nodes", &historyEntry{ScriptName: "px/nodes"}))
+
+	assert.Len(t, h.savedQuerySuggestions(""), 2)
+
+	suggestions := h.savedQuerySuggestions("err")
+	require.Len(t, suggestions, 1)
+	assert.Equal(t, "errors", suggestions[0].name)
+	assert.Equal(t, "px/http_data_filtered", suggestions[0].desc)
+	assert.NotNil(t, suggestions[0].savedQuery)
+}
+
+func TestScriptHistory_SaveAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.json")
+
+	h, err := loadScriptHistory(path)
+	require.NoError(t, err)
+	assert.Empty(t, h.Entries)
+
+	executedAt := time.Unix(1600000000, 0).UTC()
+	h.add(&historyEntry{ScriptName: "px/cluster", ExecutedAt: executedAt})
+	h.add(&historyEntry{ScriptName: "<stdin_script>", ScriptString: "import px", IsLocal: true, ExecutedAt: executedAt})
+	require.NoError(t, h.pin("cluster", h.Entries[1]))
+	require.NoError(t, h.save())
+
+	loaded, err := loadScriptHistory(path)
+	require.NoError(t, err)
+	assert.Equal(t, h.Entries, loaded.Entries)
+	assert.Equal(t, h.Saved, loaded.Saved)
+}
+
+func TestHistoryKey(t *testing.T) {
+	clusterID := uuid.Must(uuid.NewV4())
+	single := []*vizier.Connector{{}}
+	multiple := []*vizier.Connector{{}, {}}
+
+	assert.Equal(t, clusterID.String(), historyKey(single, clusterID))
+	// Runs across all clusters don't share a history with any single cluster, or with the nil UUID.
+	assert.Equal(t, allClustersHistoryKey, historyKey(multiple, clusterID))
+	assert.Equal(t, allClustersHistoryKey, historyKey(multiple, uuid.Nil))
+	assert.Equal(t, allClustersHistoryKey, historyKey(single, uuid.Nil))
+}
@@ -57,6 +57,8 @@ const (
 	modalTypeUnknown modalType = iota
 	modalTypeHelp
 	modalTypeAutocomplete
+	modalTypeHistory
+	modalTypeTimeRange
 )
 
 var (
@@ -75,6 +77,8 @@ const (
 type appState struct {
 	br *script.BundleManager
 	ac autocompleter
+	// history is the persisted history and saved queries of the selected cluster.
+	history *scriptHistory
 
 	viziers []*vizier.Connector
 	// The last script that was executed. If nil, nothing was executed.
@@ -85,8 +89,18 @@ type appState struct {
 	// Sort state is tracked on a per table basis for each column. It is cleared when a new
 	// script is executed.
 	sortState [][]sortType
+	// charts are the chart specs of the current script's widgets, keyed by the widget's output table.
+	charts map[string]*chartSpec
+	// showTable tracks the tables that are displayed as a table rather than a chart. It is cleared
+	// when a new script is executed.
+	showTable map[string]bool
+	// lastRun is when the current script was last executed, and lastRefresh is when it last
+	// executed successfully.
+	lastRun     time.Time
+	lastRefresh time.Time
 	// ----- View Specific State ------
-	// The currently selected table. Will reset to zero when new tables are inserted.
+	// The currently selected table. Will reset to zero when new tables are inserted, unless the
+	// current script is being refreshed.
 	selectedTable int
 
 	scriptViewOpen bool
@@ -105,6 +119,7 @@ type View struct {
 	infoView          *tview.TextView
 	tvTable           *tview.Table
 	logoBox           *tview.TextView
+	statusView        *tview.TextView
 	bottomBar         *tview.Flex
 	searchBox         *tview.InputField
 	modal             Modal
@@ -113,6 +128,20 @@ type View struct {
 	cloudAddr         string
 	selectedClusterID uuid.UUID
 	vizierLister      *vizier.Lister
+	// refreshInterval is how often the current script is re-run. Zero disables auto-refresh.
+	refreshInterval time.Duration
+	// timeRange overrides the start_time argument of scripts. If nil, the script's argument is used.
+	timeRange *TimeRange
+}
+
+// refreshIntervals are the auto-refresh intervals that ctrl-a cycles through.
+var refreshIntervals = []time.Duration{
+	0,
+	5 * time.Second,
+	10 * time.Second,
+	30 * time.Second,
+	time.Minute,
+	5 * time.Minute,
 }
 
 // Modal is the interface for a pop-up view.
@@ -123,7 +152,8 @@ type Modal interface {
 
 // New creates a new live view.
 func New(br *script.BundleManager, viziers []*vizier.Connector, cloudAddr string, aClient cloudpb.AutocompleteServiceClient,
-	execScript *script.ExecutableScript, useNewAC, useEncryption bool, clusterID uuid.UUID) (*View, error) {
+	execScript *script.ExecutableScript, useNewAC, useEncryption bool, clusterID uuid.UUID, refreshInterval time.Duration,
+	timeRange *TimeRange) (*View, error) {
 	// App is the top level view. The layout is approximately as follows:
 	//  ------------------------------------------
 	//  | View Information ...                   |
@@ -133,7 +163,7 @@ func New(br *script.BundleManager, viziers []*vizier.Connector, cloudAddr string
 	//  |                                        |
 	//  |                                        |
 	//  |________________________________________|
-	//  | Table Selector                | Logo   |
+	//  | Table Selector       | Status  | Logo   |
 	//  ------------------------------------------
 
 	// Top of page.
@@ -161,9 +191,14 @@ func New(br *script.BundleManager, viziers []*vizier.Connector, cloudAddr string
 	fmt.Fprintf(logoBox, "\n  [%s]PIXIE[%s]", logoColor, textColor)
 
 	tableSelector := tview.NewTextView()
+	statusView := tview.NewTextView().
+		SetScrollable(false).
+		SetDynamicColors(true).
+		SetTextAlign(tview.AlignRight)
 	bottomBar := tview.NewFlex().
 		SetDirection(tview.FlexColumn).
 		AddItem(tableSelector, 0, 1, false).
+		AddItem(statusView, statusViewWidth, 1, false).
 		AddItem(logoBox, 8, 1, false)
 	bottomBar.SetBorderPadding(1, 0, 0, 0)
 
@@ -195,24 +230,31 @@ func New(br *script.BundleManager, viziers []*vizier.Connector, cloudAddr string
 		return nil, err
 	}
 
+	history := loadClusterHistory(historyKey(viziers, clusterID))
+
 	v := &View{
 		app:           app,
 		pages:         pages,
 		tableSelector: tableSelector,
 		infoView:      infoView,
 		logoBox:       logoBox,
+		statusView:    statusView,
 		searchBox:     searchBox,
 		bottomBar:     bottomBar,
 		s: &appState{
 			br:         br,
 			viziers:    viziers,
 			ac:         ac,
+			history:    history,
 			execScript: execScript,
+			showTable:  make(map[string]bool),
 		},
 		useNewAC:          useNewAC,
 		cloudAddr:         cloudAddr,
 		selectedClusterID: clusterID,
 		vizierLister:      lister,
+		refreshInterval:   refreshInterval,
+		timeRange:         timeRange,
 	}
 
 	// Wire up components.
@@ -233,6 +275,7 @@ func New(br *script.BundleManager, viziers []*vizier.Connector, cloudAddr string
 
 	searchBox.SetChangedFunc(v.search)
 	searchBox.SetInputCapture(v.searchInputCapture)
+	v.updateStatusView()
 	// If a default script was passed in execute it.
 	v.runScript(execScript, useEncryption)
 
@@ -243,9 +286,48 @@ func New(br *script.BundleManager, viziers []*vizier.Connector, cloudAddr string
 
 // Run runs the view.
 func (v *View) Run() error {
+	done := make(chan struct{})
+	defer close(done)
+	go v.refreshLoop(done)
 	return v.app.Run()
 }
 
+// refreshLoop periodically checks whether the current script is due to be refreshed.
+func (v *View) refreshLoop(done <-chan struct{}) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			v.app.QueueUpdate(func() {
+				if v.refreshIfDue(time.Now()) {
+					v.app.Draw()
+				}
+			})
+		}
+	}
+}
+
+// refreshIfDue re-runs the current script if auto-refresh is enabled and the interval has passed.
+// Refreshes are skipped while the user is interacting with a modal or the script view.
+func (v *View) refreshIfDue(now time.Time) bool {
+	if v.refreshInterval <= 0 || v.s.execScript == nil || v.modal != nil || v.s.scriptViewOpen {
+		return false
+	}
+	if now.Sub(v.s.lastRun) < v.refreshInterval {
+		return false
+	}
+	v.refreshScript()
+	return true
+}
+
+// refreshScript re-runs the current script, keeping the selected table, sort state and search.
+func (v *View) refreshScript() {
+	v.executeScript(v.s.execScript, true, true)
+}
+
 // Stop stops the view and kills the app.
 func (v *View) Stop() {
 	v.app.Stop()
@@ -253,17 +335,32 @@ func (v *View) Stop() {
 
 // runScript is the internal method to run an executable script and update relevant appState.
 func (v *View) runScript(execScript *script.ExecutableScript, useEncryption bool) {
+	v.executeScript(execScript, useEncryption, false)
+}
+
+// executeScript runs the script. If refresh is set, the script is the one that is already displayed, and
+// the state of the view is kept.
+func (v *View) executeScript(execScript *script.ExecutableScript, useEncryption bool, refresh bool) {
 	v.clearErrorIfAny()
 	if execScript == nil {
 		v.execCompleteWithError(errMissingScript)
 		return
 	}
+	var prevState *viewState
+	if refresh {
+		prevState = v.saveViewState()
+	}
 	v.s.execScript = execScript
+	v.s.lastRun = time.Now()
+	toRun, err := withTimeRange(execScript, v.timeRange, v.s.lastRun)
+	if err != nil {
+		v.execCompleteWithError(err)
+		return
+	}
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
 	var encOpts, decOpts *vizierpb.ExecuteScriptRequest_EncryptionOptions
-	var err error
 	if useEncryption {
 		encOpts, decOpts, err = apiutils.CreateEncryptionOptions()
 		if err != nil {
@@ -272,11 +369,15 @@ func (v *View) runScript(execScript *script.ExecutableScript, useEncryption bool
 		}
 	}
 
-	resp, err := vizier.RunScript(ctx, v.s.viziers, execScript, encOpts)
+	resp, err := vizier.RunScript(ctx, v.s.viziers, toRun, encOpts)
 	if err != nil {
 		v.execCompleteWithError(err)
 		return
 	}
+	// Refreshes re-run the script that is already displayed, so only new runs are added to the history.
+	if !refresh {
+		v.recordHistory(execScript)
+	}
 	tw := vizier.NewStreamOutputAdapter(ctx, resp, vizier.FormatInMemory, decOpts)
 	err = tw.Finish()
 	if err != nil {
@@ -302,12 +403,93 @@ func (v *View) runScript(execScript *script.ExecutableScript, useEncryption bool
 		// Default value is unsorted.
 		v.s.sortState[i] = make([]sortType, len(t.Header()))
 	}
+	v.s.charts = chartSpecsForVis(execScript.Vis)
+	if !refresh {
+		v.s.showTable = make(map[string]bool)
+	}
 	// The view can update with nil data if there is an error.
 	v.s.selectedTable = 0
+	v.s.lastRefresh = time.Now()
 
+	if prevState != nil {
+		v.refreshCompleteViewUpdate(prevState)
+		return
+	}
 	v.execCompleteViewUpdate()
 }
 
+// viewState is the state of the view that is kept when the current script is refreshed.
+type viewState struct {
+	selectedTable string
+	// sortState is keyed by table name.
+	sortState map[string][]sortType
+	// The selected cell of the current table.
+	row, col int
+}
+
+func (v *View) saveViewState() *viewState {
+	st := &viewState{sortState: make(map[string][]sortType)}
+	for i, t := range v.s.tables {
+		if i < len(v.s.sortState) {
+			st.sortState[t.Name()] = v.s.sortState[i]
+		}
+		if i == v.s.selectedTable {
+			st.selectedTable = t.Name()
+		}
+	}
+	if v.tvTable != nil {
+		st.row, st.col = v.tvTable.GetSelection()
+	}
+	return st
+}
+
+// restoreViewState applies the saved state to the new tables. Tables are matched by name, and sort
+// state is only kept if the table still has the same number of columns.
+func (v *View) restoreViewState(st *viewState) {
+	for i, t := range v.s.tables {
+		if ss, ok := st.sortState[t.Name()]; ok && len(ss) == len(v.s.sortState[i]) {
+			v.s.sortState[i] = ss
+		}
+		if t.Name() == st.selectedTable {
+			v.s.selectedTable = i
+		}
+	}
+}
+
+// allClustersHistoryKey is the history key used when a script runs across multiple clusters.
+const allClustersHistoryKey = "all_clusters"
+
+// historyKey returns the key of the history file to use. Scripts that run across multiple clusters
+// are kept apart from the history of any single cluster.
+func historyKey(viziers []*vizier.Connector, clusterID uuid.UUID) string {
+	if clusterID == uuid.Nil || len(viziers) > 1 {
+		return allClustersHistoryKey
+	}
+	return clusterID.String()
+}
+
+// loadClusterHistory loads the script history with the given key. If it can't be loaded, an empty history
+// that is only kept in memory is used instead.
+func loadClusterHistory(key string) *scriptHistory {
+	path, err := utils.EnsureLiveHistoryFilePath(key)
+	if err != nil {
+		utils.WithError(err).Error("Failed to create script history path")
+		return &scriptHistory{}
+	}
+	history, err := loadScriptHistory(path)
+	if err != nil {
+		utils.WithError(err).Error("Failed to load script history")
+	}
+	return history
+}
+
+func (v *View) recordHistory(execScript *script.ExecutableScript) {
+	v.s.history.add(newHistoryEntry(execScript))
+	if err := v.s.history.save(); err != nil {
+		utils.WithError(err).Error("Failed to save script history")
+	}
+}
+
 func (v *View) clearErrorIfAny() {
 	// Clear error pages if any.
 	if v.pages.HasPage("error") {
@@ -341,10 +523,79 @@ func (v *View) execCompleteViewUpdate() {
 	v.searchClear()
 
 	v.updateScriptInfoView()
+	v.updateStatusView()
 	v.updateTableNav()
 	v.renderCurrentTable()
 }
 
+func (v *View) refreshCompleteViewUpdate(st *viewState) {
+	v.restoreViewState(st)
+
+	v.updateScriptInfoView()
+	v.updateStatusView()
+	v.writeTableNav()
+	if len(v.s.tables) == 0 {
+		return
+	}
+	v.renderCurrentTable()
+	if v.tvTable != nil && st.row < v.tvTable.GetRowCount() {
+		v.tvTable.Select(st.row, st.col)
+	}
+	v.tableSelector.Highlight(strconv.Itoa(v.s.selectedTable)).ScrollToHighlight()
+
+	if v.s.searchBoxEnabled {
+		// Re-apply the search to the new data, and keep typing in the search box.
+		v.searchNext(false, false)
+		v.app.SetFocus(v.searchBox)
+	}
+}
+
+// statusViewWidth is the width of the status view in the bottom bar.
+const statusViewWidth = 56
+
+func (v *View) updateStatusView() {
+	v.statusView.Clear()
+	if !v.s.lastRefresh.IsZero() {
+		fmt.Fprintf(v.statusView, "%s %s", withAccent("Refreshed:"), v.s.lastRefresh.Format("15:04:05"))
+	}
+	refresh := "off"
+	if v.refreshInterval > 0 {
+		refresh = shortDuration(v.refreshInterval)
+	}
+	fmt.Fprintf(v.statusView, "  %s %s", withAccent("Auto:"), refresh)
+	if v.timeRange != nil {
+		fmt.Fprintf(v.statusView, "  %s %s", withAccent("Range:"), v.timeRange)
+	}
+}
+
+// cycleRefreshInterval switches to the next auto-refresh interval.
+func (v *View) cycleRefreshInterval() {
+	next := refreshIntervals[0]
+	for _, d := range refreshIntervals {
+		if d > v.refreshInterval {
+			next = d
+			break
+		}
+	}
+	v.refreshInterval = next
+	v.updateStatusView()
+}
+
+func (v *View) showTimeRangeModal() {
+	v.closeModal()
+	m := newTimeRangeModal(v.timeRange, func(t *TimeRange) {
+		v.timeRange = t
+		v.closeModal()
+		v.updateStatusView()
+		if v.s.execScript != nil {
+			v.refreshScript()
+		}
+	})
+	v.modal = m
+	v.pages.AddPage("modal", createModal(v.modal.Show(v.app),
+		50, 14), true, true)
+}
+
 func (v *View) updateScriptInfoView() {
 	v.infoView.Clear()
 
@@ -386,17 +637,57 @@ func (v *View) renderCurrentTable() {
 	}
 	table := v.s.tables[v.s.selectedTable]
 	formatter := v.s.tableFormatters[v.s.selectedTable]
+	if cv := v.createChartView(table, formatter); cv != nil {
+		v.tvTable = nil
+		v.pages.AddAndSwitchToPage("table", cv, true)
+		v.app.SetFocus(v.pages)
+		return
+	}
 	v.tvTable = v.createTviewTable(table, formatter, v.s.sortState[v.s.selectedTable])
 	v.pages.AddAndSwitchToPage("table", v.tvTable, true)
 	v.app.SetFocus(v.pages)
 }
 
+// createChartView returns the chart view for the table, or nil if the table should be shown as a table.
+func (v *View) createChartView(t components.TableView, formatter vizier.DataFormatter) *chartView {
+	if v.s.showTable[t.Name()] {
+		return nil
+	}
+	spec := chartSpecForTable(v.s.charts, t.Name())
+	if spec == nil {
+		return nil
+	}
+	c, err := newChart(spec, t, formatter)
+	if err != nil {
+		// Fall back to the table if the data doesn't match the chart.
+		return nil
+	}
+	return newChartView(c)
+}
+
+// toggleChart switches the current table between the chart and table display.
+func (v *View) toggleChart() {
+	if v.s.selectedTable >= len(v.s.tables) {
+		return
+	}
+	name := v.s.tables[v.s.selectedTable].Name()
+	if chartSpecForTable(v.s.charts, name) == nil {
+		return
+	}
+	v.s.showTable[name] = !v.s.showTable[name]
+	v.renderCurrentTable()
+}
+
 func (v *View) updateTableNav() {
+	v.writeTableNav()
+	v.showTableNav()
+}
+
+func (v *View) writeTableNav() {
 	v.tableSelector.Clear()
 	for idx, t := range v.s.tables {
 		fmt.Fprintf(v.tableSelector, `%d ["%d"]%s[""]  `, idx+1, idx, withAccent(t.Name()))
 	}
-	v.showTableNav()
 }
 
 func (v *View) selectNextTable() {
@@ -562,6 +853,17 @@ func (v *View) showAutcompleteModal() {
 		65, 30), true, true)
 }
 
+func (v *View) showHistoryModal() {
+	v.closeModal()
+	hm := newHistoryModal(v.s)
+	hm.SetScriptExecFunc(func(s *script.ExecutableScript) {
+		v.runScript(s, true)
+	})
+	v.modal = hm
+	v.pages.AddPage("modal", createModal(v.modal.Show(v.app),
+		80, 30), true, true)
+}
+
 func (v *View) showHelpModal() {
 	v.closeModal()
 	hm := &helpModal{}
@@ -600,6 +902,7 @@ func (v *View) showTableNav() {
 	v.bottomBar.
 		Clear().
 		AddItem(v.tableSelector, 0, 1, false).
+		AddItem(v.statusView, statusViewWidth, 1, false).
 		AddItem(v.logoBox, 8, 1, false)
 
 	// Switch focus back to the active table.
@@ -611,6 +914,7 @@ func (v *View) showSearchBox() {
 	v.bottomBar.
 		Clear().
 		AddItem(v.searchBox, 0, 1, false).
+		AddItem(v.statusView, statusViewWidth, 1, false).
 		AddItem(v.logoBox, 8, 1, false)
 	v.app.SetFocus(v.searchBox)
 }
@@ -644,6 +948,10 @@ func (v *View) activeModalType() modalType {
 		return modalTypeHelp
 	case *autocompleteModal:
 		return modalTypeAutocomplete
+	case *historyModal:
+		return modalTypeHistory
+	case *timeRangeModal:
+		return modalTypeTimeRange
 	default:
 		return modalTypeUnknown
 	}
@@ -801,6 +1109,16 @@ func (v *View) keyHandler(event *tcell.EventKey) *tcell.EventKey {
 				v.closeModal()
 				return nil
 			}
+		case tcell.KeyCtrlP:
+			if v.activeModalType() == modalTypeHistory {
+				v.closeModal()
+				return nil
+			}
+		case tcell.KeyCtrlT:
+			if v.activeModalType() == modalTypeTimeRange {
+				v.closeModal()
+				return nil
+			}
 		}
 		return event
 	}
@@ -820,8 +1138,11 @@ func (v *View) keyHandler(event *tcell.EventKey) *tcell.EventKey {
 		return nil
 	case tcell.KeyCtrlN:
 		v.selectNextTable()
-	case tcell.KeyCtrlP:
+	case tcell.KeyCtrlB:
 		v.selectPrevTable()
+	case tcell.KeyCtrlP:
+		v.showHistoryModal()
+		return nil
 	case tcell.KeyRune:
 		// Switch to a specific view. This will be a no-op if no tables are loaded.
 		r := event.Rune()
@@ -837,6 +1158,10 @@ func (v *View) keyHandler(event *tcell.EventKey) *tcell.EventKey {
 			v.showSearchBox()
 			return nil
 		}
+		if string(r) == "c" {
+			v.toggleChart()
+			return nil
+		}
 	case tcell.KeyCtrlS:
 		v.showSearchBox()
 		return nil
@@ -851,7 +1176,13 @@ func (v *View) keyHandler(event *tcell.EventKey) *tcell.EventKey {
 		v.showAutcompleteModal()
 		return nil
 	case tcell.KeyCtrlR:
-		v.runScript(v.s.execScript, true)
+		v.refreshScript()
+		return nil
+	case tcell.KeyCtrlT:
+		v.showTimeRangeModal()
+		return nil
+	case tcell.KeyCtrlA:
+		v.cycleRefreshInterval()
 		return nil
 	}
 
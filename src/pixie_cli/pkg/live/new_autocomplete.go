@@ -49,6 +49,8 @@ type suggestion struct {
 	name string
 	desc string
 	kind cloudpb.AutocompleteEntityKind
+	// savedQuery is set if the suggestion is one of the user's saved queries.
+	savedQuery *savedQuery
 
 	matchedIndexes []int
 }
@@ -144,7 +146,9 @@ func (m *tabAutocompleteModal) updateSuggestions() {
 
 	for i, s := range suggestions {
 		sb := strings.Builder{}
-		if l, ok := protoToKindLabelMap[s.kind]; ok {
+		if s.savedQuery != nil {
+			sb.WriteString("[yellow]saved:[white]")
+		} else if l, ok := protoToKindLabelMap[s.kind]; ok {
 			sb.WriteString(fmt.Sprintf("[yellow]%s:[white]", l))
 		}
 		for i := 0; i < len(s.name); i++ {
@@ -200,6 +204,19 @@ func (m *tabAutocompleteModal) parseCommand() (*script.ExecutableScript, error)
 }
 
 func (m *tabAutocompleteModal) selectSuggestion(app *tview.Application, s suggestion) {
+	// Saved queries already have all of their arguments, so they are run right away.
+	if s.savedQuery != nil {
+		es, err := s.savedQuery.Entry.executableScript(m.s.br)
+		if err != nil {
+			m.dt.SetText(fmt.Sprintf("[red]Failed to load saved query:[white] %s", err.Error()))
+			return
+		}
+		if m.scriptExecFunc != nil {
+			m.scriptExecFunc(es)
+		}
+		return
+	}
+
 	ts := m.tabStops[m.tabStopIndex]
 
 	kind := s.kind
@@ -309,10 +326,46 @@ func (m *tabAutocompleteModal) updateInput(app *tview.Application, text string,
 
 	m.tabStops = tabStops
 	m.suggestions = suggestions
+	m.addSavedQuerySuggestions()
 
 	m.updateSuggestions()
 }
 
+// addSavedQuerySuggestions offers the user's saved queries alongside the scripts suggested for the
+// current tab stop.
+func (m *tabAutocompleteModal) addSavedQuerySuggestions() {
+	if m.s.history == nil || m.tabStopIndex >= len(m.tabStops) {
+		return
+	}
+	ts := m.tabStops[m.tabStopIndex]
+	if ts.Index == nil {
+		return
+	}
+	current := m.suggestions[*ts.Index]
+	hasScripts := false
+	for _, s := range current {
+		if s.kind == cloudpb.AEK_SCRIPT {
+			hasScripts = true
+			break
+		}
+	}
+	if !hasScripts {
+		return
+	}
+	m.suggestions[*ts.Index] = append(m.s.history.savedQuerySuggestions(tabStopValue(ts)), current...)
+}
+
+// tabStopValue returns the value of the tab stop, without the cursor marker.
+func tabStopValue(t *TabStop) string {
+	value := ""
+	if t.Value != nil {
+		value = *t.Value
+	} else if t.Label != nil && !t.HasLabel {
+		value = *t.Label
+	}
+	return strings.Replace(value, "$0", "", 1)
+}
+
 // handleBackspace determines whether the next item is a label or value and modifies the string accordingly.
 func (m *tabAutocompleteModal) handleBackspace(app *tview.Application) bool {
 	curText := m.input.GetText()
@@ -0,0 +1,150 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package live
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"px.dev/pixie/src/utils/script"
+)
+
+// startTimeArg is the script argument that the time range is applied to.
+const startTimeArg = "start_time"
+
+// TimeRange is the window of data that scripts in the live view run over. It is applied by overriding
+// the script's start_time argument each time the script runs.
+type TimeRange struct {
+	// Relative is how long before now the range starts. If zero, Absolute is used.
+	Relative time.Duration
+	// Absolute is the fixed start of the range.
+	Absolute time.Time
+}
+
+var absoluteTimeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+}
+
+// ParseTimeRange parses a time range. It's either a duration such as "15m" or "-1h", which is relative
+// to when the script runs, or an absolute start time such as "2021-06-01 13:00" or an RFC3339 timestamp.
+func ParseTimeRange(s string) (*TimeRange, error) {
+	s = strings.TrimSpace(s)
+	if d, err := time.ParseDuration(strings.TrimPrefix(s, "-")); err == nil {
+		if d <= 0 {
+			return nil, fmt.Errorf("time range %q must be positive", s)
+		}
+		return &TimeRange{Relative: d}, nil
+	}
+	for _, layout := range absoluteTimeLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return &TimeRange{Absolute: t}, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid time range %q, expected a duration like 15m or a start time like 2006-01-02 15:04", s)
+}
+
+// startTime returns the value of the start_time argument for a script run at the given time.
+// Absolute ranges are converted to a relative start time, since that's what scripts accept.
+func (t *TimeRange) startTime(now time.Time) string {
+	d := t.Relative
+	if d == 0 {
+		d = now.Sub(t.Absolute)
+		if d < time.Second {
+			d = time.Second
+		}
+	}
+	switch {
+	case d%time.Hour == 0:
+		return fmt.Sprintf("-%dh", d/time.Hour)
+	case d%time.Minute == 0:
+		return fmt.Sprintf("-%dm", d/time.Minute)
+	default:
+		return fmt.Sprintf("-%ds", d/time.Second)
+	}
+}
+
+// String returns the time range as it is shown in the status bar.
+func (t *TimeRange) String() string {
+	if t.Relative != 0 {
+		return "last " + shortDuration(t.Relative)
+	}
+	return "since " + t.Absolute.Format("2006-01-02 15:04:05")
+}
+
+// shortDuration formats durations without trailing zero units, e.g. 5m instead of 5m0s.
+func shortDuration(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = s[:len(s)-2]
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = s[:len(s)-2]
+	}
+	return s
+}
+
+// hasStartTimeArg returns whether the script takes a start_time argument.
+func hasStartTimeArg(es *script.ExecutableScript) bool {
+	if es == nil || es.Vis == nil {
+		return false
+	}
+	for _, v := range es.Vis.Variables {
+		if v.Name == startTimeArg {
+			return true
+		}
+	}
+	return false
+}
+
+// withTimeRange returns the script with its start_time argument set to the time range. The time range is
+// applied to a copy of the script, so that the script's own start_time is used again once the time range is
+// cleared. Scripts that don't take a start_time argument are returned unchanged.
+func withTimeRange(es *script.ExecutableScript, t *TimeRange, now time.Time) (*script.ExecutableScript, error) {
+	if t == nil || !hasStartTimeArg(es) {
+		return es, nil
+	}
+	fs := es.GetFlagSet()
+	if fs == nil {
+		return es, nil
+	}
+	for name, arg := range es.Args {
+		if name == startTimeArg {
+			continue
+		}
+		if err := fs.Set(name, arg.Value); err != nil {
+			return nil, err
+		}
+	}
+	if err := fs.Set(startTimeArg, t.startTime(now)); err != nil {
+		return nil, err
+	}
+
+	clone := *es
+	clone.Args = make(map[string]script.Arg, len(es.Args))
+	for name, arg := range es.Args {
+		clone.Args[name] = arg
+	}
+	if err := clone.UpdateFlags(fs); err != nil {
+		return nil, err
+	}
+	return &clone, nil
+}
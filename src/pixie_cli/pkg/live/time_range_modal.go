@@ -0,0 +1,134 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package live
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gdamore/tcell"
+	"github.com/rivo/tview"
+)
+
+var timeRangePresets = []time.Duration{
+	5 * time.Minute,
+	15 * time.Minute,
+	30 * time.Minute,
+	time.Hour,
+	6 * time.Hour,
+	24 * time.Hour,
+}
+
+// timeRangeModal lets the user pick one of the preset time ranges, or type in a custom one.
+type timeRangeModal struct {
+	// The list of presets.
+	pl *tview.List
+	// The input box for custom time ranges.
+	ib *tview.InputField
+
+	layout *tview.Flex
+
+	// selectFunc is called with the chosen time range. A nil range means the script's default is used.
+	selectFunc func(*TimeRange)
+}
+
+func newTimeRangeModal(current *TimeRange, selectFunc func(*TimeRange)) *timeRangeModal {
+	presetList := tview.NewList()
+	presetList.
+		ShowSecondaryText(false).
+		SetBorder(true).
+		SetTitle(" Time Range ")
+
+	inputBox := tview.NewInputField()
+	inputBox.SetBackgroundColor(tcell.ColorBlack)
+	inputBox.
+		SetLabel("Custom: ").
+		SetPlaceholder("e.g. 2h or 2006-01-02 15:04").
+		SetFieldBackgroundColor(tcell.ColorBlack).
+		SetBorder(true)
+	if current != nil && current.Relative == 0 {
+		inputBox.SetText(current.Absolute.Format("2006-01-02 15:04:05"))
+	}
+
+	layout := tview.NewFlex().
+		SetDirection(tview.FlexRow).
+		AddItem(presetList, 0, 1, true).
+		AddItem(inputBox, 3, 0, false)
+
+	m := &timeRangeModal{
+		pl:         presetList,
+		ib:         inputBox,
+		layout:     layout,
+		selectFunc: selectFunc,
+	}
+
+	presetList.AddItem("Script default", "", 0, func() { m.selectFunc(nil) })
+	for i, d := range timeRangePresets {
+		d := d
+		presetList.AddItem(fmt.Sprintf("Last %s", shortDuration(d)), "", 0, func() {
+			m.selectFunc(&TimeRange{Relative: d})
+		})
+		if current != nil && current.Relative == d {
+			presetList.SetCurrentItem(i + 1)
+		}
+	}
+	return m
+}
+
+// Show shows the modal.
+func (m *timeRangeModal) Show(app *tview.Application) tview.Primitive {
+	m.pl.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyTAB:
+			app.SetFocus(m.ib)
+			return nil
+		case tcell.KeyDown:
+			// Move to the custom input box from the last preset.
+			if m.pl.GetCurrentItem() == m.pl.GetItemCount()-1 {
+				app.SetFocus(m.ib)
+				return nil
+			}
+		}
+		return event
+	})
+
+	m.ib.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyTAB, tcell.KeyUp:
+			app.SetFocus(m.pl)
+			return nil
+		case tcell.KeyEnter:
+			t, err := ParseTimeRange(m.ib.GetText())
+			if err != nil {
+				m.ib.SetBorderColor(tcell.ColorRed)
+				return nil
+			}
+			m.selectFunc(t)
+			return nil
+		}
+		m.ib.SetBorderColor(tcell.ColorWhite)
+		return event
+	})
+
+	app.SetFocus(m.pl)
+	return m.layout
+}
+
+// Close is called when the modal is closed. Nothing to do for the time range modal.
+func (m *timeRangeModal) Close(app *tview.Application) {}
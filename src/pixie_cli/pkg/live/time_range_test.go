@@ -0,0 +1,105 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package live
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gogo/protobuf/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"px.dev/pixie/src/api/proto/vispb"
+	"px.dev/pixie/src/utils/script"
+)
+
+func TestParseTimeRange(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected *TimeRange
+		err      bool
+	}{
+		{"relative", "15m", &TimeRange{Relative: 15 * time.Minute}, false},
+		{"negative relative", "-1h", &TimeRange{Relative: time.Hour}, false},
+		{"absolute", "2021-06-01 13:00", &TimeRange{Absolute: time.Date(2021, 6, 1, 13, 0, 0, 0, time.Local)}, false},
+		{"rfc3339", "2021-06-01T13:00:00Z", &TimeRange{Absolute: time.Date(2021, 6, 1, 13, 0, 0, 0, time.UTC)}, false},
+		{"zero", "0s", nil, true},
+		{"invalid", "yesterday", nil, true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tr, err := ParseTimeRange(tc.input)
+			if tc.err {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected.Relative, tr.Relative)
+			assert.True(t, tc.expected.Absolute.Equal(tr.Absolute))
+		})
+	}
+}
+
+func TestTimeRange_StartTime(t *testing.T) {
+	now := time.Date(2021, 6, 1, 13, 0, 0, 0, time.UTC)
+	assert.Equal(t, "-5m", (&TimeRange{Relative: 5 * time.Minute}).startTime(now))
+	assert.Equal(t, "-2h", (&TimeRange{Relative: 2 * time.Hour}).startTime(now))
+	assert.Equal(t, "-90s", (&TimeRange{Relative: 90 * time.Second}).startTime(now))
+	// Absolute ranges are converted relative to when the script runs.
+	abs := &TimeRange{Absolute: now.Add(-45 * time.Minute)}
+	assert.Equal(t, "-45m", abs.startTime(now))
+	assert.Equal(t, "-50m", abs.startTime(now.Add(5*time.Minute)))
+}
+
+func TestTimeRange_String(t *testing.T) {
+	assert.Equal(t, "last 15m", (&TimeRange{Relative: 15 * time.Minute}).String())
+	assert.Equal(t, "last 1h", (&TimeRange{Relative: time.Hour}).String())
+	assert.Equal(t, "last 1h30m", (&TimeRange{Relative: 90 * time.Minute}).String())
+	assert.Equal(t, "since 2021-06-01 13:00:00", (&TimeRange{Absolute: time.Date(2021, 6, 1, 13, 0, 0, 0, time.Local)}).String())
+}
+
+func TestWithTimeRange_ScriptDefault(t *testing.T) {
+	es := &script.ExecutableScript{
+		ScriptName: "px/test",
+		Vis: &vispb.Vis{
+			Variables: []*vispb.Vis_Variable{
+				{Name: "start_time", Type: vispb.PX_STRING, DefaultValue: &types.StringValue{Value: "-5m"}},
+				{Name: "namespace", Type: vispb.PX_NAMESPACE, DefaultValue: &types.StringValue{Value: ""}},
+			},
+		},
+		Args: map[string]script.Arg{
+			"start_time": {Name: "start_time", Value: "-30m"},
+			"namespace":  {Name: "namespace", Value: "default"},
+		},
+	}
+	now := time.Date(2021, 6, 1, 13, 0, 0, 0, time.UTC)
+
+	ranged, err := withTimeRange(es, &TimeRange{Relative: 2 * time.Hour}, now)
+	require.NoError(t, err)
+	assert.Equal(t, "-2h", ranged.Args["start_time"].Value)
+	assert.Equal(t, "default", ranged.Args["namespace"].Value)
+
+	// Switching back to the script default runs the script with its own start_time again.
+	ranged, err = withTimeRange(es, nil, now)
+	require.NoError(t, err)
+	assert.Equal(t, "-30m", ranged.Args["start_time"].Value)
+	assert.Equal(t, "default", ranged.Args["namespace"].Value)
+}
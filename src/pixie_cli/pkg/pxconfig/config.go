@@ -32,6 +32,11 @@ import (
 type ConfigInfo struct {
 	// UniqueClientID is the ID assigned to this user on first startup when auth information is not know. This can be later associated with the UserID.
 	UniqueClientID string `json:"uniqueClientID"`
+	// CurrentContext is the name of the context used when `--context` is not specified. Empty means
+	// no context is used, and the CLI falls back to its flags and the default auth file.
+	CurrentContext string `json:"currentContext,omitempty"`
+	// Contexts are the named profiles the user has configured.
+	Contexts []*Context `json:"contexts,omitempty"`
 }
 
 var (
@@ -58,6 +63,18 @@ func writeDefaultConfig(path string) (*ConfigInfo, error) {
 	return cfg, nil
 }
 
+func writeConfig(path string, cfg *ConfigInfo) error {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	return enc.Encode(cfg)
+}
+
 func readDefaultConfig(path string) (*ConfigInfo, error) {
 	f, err := os.Open(path)
 	if err != nil {
@@ -94,3 +111,12 @@ func Cfg() *ConfigInfo {
 	})
 	return config
 }
+
+// Save persists the config to the default config file.
+func (c *ConfigInfo) Save() error {
+	configPath, err := utils.EnsureDefaultConfigFilePath()
+	if err != nil {
+		return err
+	}
+	return writeConfig(configPath, c)
+}
@@ -0,0 +1,115 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package pxconfig
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/spf13/viper"
+)
+
+// Context is a named profile that holds the settings used to talk to a particular Pixie Cloud and
+// org. Credentials for a context are stored separately in its own auth file.
+type Context struct {
+	Name string `json:"name"`
+	// CloudAddr is the address of the Pixie Cloud this context points at.
+	CloudAddr string `json:"cloudAddr,omitempty"`
+	// ClusterID is the cluster used by default when a command doesn't specify one.
+	ClusterID string `json:"clusterID,omitempty"`
+	// DirectVizierAddr and DirectVizierKey are used to connect to a vizier directly instead of
+	// going through the cloud.
+	DirectVizierAddr string `json:"directVizierAddr,omitempty"`
+	DirectVizierKey  string `json:"directVizierKey,omitempty"`
+}
+
+var contextNameRegex = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+// ValidateContextName checks that the name can be used as a context name. Context names are used in
+// file names, so they are restricted to alphanumerics, '.', '_' and '-'.
+func ValidateContextName(name string) error {
+	if !contextNameRegex.MatchString(name) {
+		return fmt.Errorf("invalid context name %q: must start with an alphanumeric character and contain only alphanumerics, '.', '_' or '-'", name)
+	}
+	return nil
+}
+
+// GetContext returns the context with the given name, or nil if it doesn't exist.
+func (c *ConfigInfo) GetContext(name string) *Context {
+	for _, ctx := range c.Contexts {
+		if ctx.Name == name {
+			return ctx
+		}
+	}
+	return nil
+}
+
+// SetContext adds the context, or replaces an existing context with the same name.
+func (c *ConfigInfo) SetContext(ctx *Context) error {
+	if err := ValidateContextName(ctx.Name); err != nil {
+		return err
+	}
+	for i, existing := range c.Contexts {
+		if existing.Name == ctx.Name {
+			c.Contexts[i] = ctx
+			return nil
+		}
+	}
+	c.Contexts = append(c.Contexts, ctx)
+	return nil
+}
+
+// UseContext makes the named context the current context. An empty name clears the current context.
+func (c *ConfigInfo) UseContext(name string) error {
+	if name != "" && c.GetContext(name) == nil {
+		return fmt.Errorf("context %q does not exist", name)
+	}
+	c.CurrentContext = name
+	return nil
+}
+
+// ActiveContextName returns the name of the context selected by the `--context` flag, falling back
+// to the current context in the config.
+func (c *ConfigInfo) ActiveContextName() string {
+	if name := viper.GetString("context"); name != "" {
+		return name
+	}
+	return c.CurrentContext
+}
+
+// ActiveContext returns the context in use, or nil if no context is selected. An error is returned if
+// the selected context doesn't exist.
+func (c *ConfigInfo) ActiveContext() (*Context, error) {
+	name := c.ActiveContextName()
+	if name == "" {
+		return nil, nil
+	}
+	ctx := c.GetContext(name)
+	if ctx == nil {
+		return nil, fmt.Errorf("context %q does not exist, create it with `px config set-context %s`", name, name)
+	}
+	return ctx, nil
+}
+
+// ActiveContext returns the context in use for the default config. Unknown contexts are treated as
+// no context; callers that need to report the error should use ConfigInfo.ActiveContext.
+func ActiveContext() *Context {
+	ctx, _ := Cfg().ActiveContext()
+	return ctx
+}
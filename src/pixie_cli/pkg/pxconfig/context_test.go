@@ -0,0 +1,85 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package pxconfig
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigInfo_SetContext(t *testing.T) {
+	cfg := &ConfigInfo{}
+	require.NoError(t, cfg.SetContext(&Context{Name: "prod", CloudAddr: "withpixie.ai:443"}))
+	require.NoError(t, cfg.SetContext(&Context{Name: "staging", CloudAddr: "staging.example.com:443"}))
+	require.NoError(t, cfg.SetContext(&Context{Name: "prod", CloudAddr: "getcosmic.ai:443"}))
+
+	require.Len(t, cfg.Contexts, 2)
+	assert.Equal(t, "getcosmic.ai:443", cfg.GetContext("prod").CloudAddr)
+	assert.Nil(t, cfg.GetContext("dev"))
+
+	assert.Error(t, cfg.SetContext(&Context{Name: "../auth"}))
+	assert.Error(t, cfg.SetContext(&Context{Name: ""}))
+}
+
+func TestConfigInfo_ActiveContext(t *testing.T) {
+	defer viper.Set("context", "")
+
+	cfg := &ConfigInfo{}
+	require.NoError(t, cfg.SetContext(&Context{Name: "prod"}))
+	require.NoError(t, cfg.SetContext(&Context{Name: "staging"}))
+
+	ctx, err := cfg.ActiveContext()
+	require.NoError(t, err)
+	assert.Nil(t, ctx)
+
+	assert.Error(t, cfg.UseContext("dev"))
+	require.NoError(t, cfg.UseContext("prod"))
+	ctx, err = cfg.ActiveContext()
+	require.NoError(t, err)
+	assert.Equal(t, "prod", ctx.Name)
+
+	// The flag takes precedence over the current context.
+	viper.Set("context", "staging")
+	ctx, err = cfg.ActiveContext()
+	require.NoError(t, err)
+	assert.Equal(t, "staging", ctx.Name)
+
+	viper.Set("context", "dev")
+	_, err = cfg.ActiveContext()
+	assert.Error(t, err)
+}
+
+func TestWriteConfig_RoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	cfg := &ConfigInfo{UniqueClientID: "abc", CurrentContext: "prod"}
+	require.NoError(t, cfg.SetContext(&Context{
+		Name:      "prod",
+		CloudAddr: "withpixie.ai:443",
+		ClusterID: "7d6f1d2a-a0a5-4d12-9a4e-2ab8f1c2b8e5",
+	}))
+	require.NoError(t, writeConfig(path, cfg))
+
+	read, err := readDefaultConfig(path)
+	require.NoError(t, err)
+	assert.Equal(t, cfg, read)
+}
@@ -0,0 +1,376 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package scriptargs
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"px.dev/pixie/src/api/proto/cloudpb"
+	"px.dev/pixie/src/api/proto/vispb"
+)
+
+// Kind is the type of value that a script argument accepts, as understood by the CLI.
+type Kind string
+
+// The kinds of script arguments that are checked before a script is executed.
+const (
+	KindString    Kind = "string"
+	KindBoolean   Kind = "bool"
+	KindInt64     Kind = "int"
+	KindFloat64   Kind = "float"
+	KindTime      Kind = "time"
+	KindEnum      Kind = "enum"
+	KindService   Kind = "service"
+	KindPod       Kind = "pod"
+	KindContainer Kind = "container"
+	KindNamespace Kind = "namespace"
+	KindNode      Kind = "node"
+	KindList      Kind = "list"
+)
+
+// maxSuggestions is the maximum number of close matches that are suggested for a bad value.
+const maxSuggestions = 3
+
+// KindOf returns the kind of the given vis variable. Enums are variables that restrict their valid
+// values.
+func KindOf(v *vispb.Vis_Variable) Kind {
+	if len(v.ValidValues) > 0 {
+		return KindEnum
+	}
+	switch v.Type {
+	case vispb.PX_BOOLEAN:
+		return KindBoolean
+	case vispb.PX_INT64:
+		return KindInt64
+	case vispb.PX_FLOAT64:
+		return KindFloat64
+	case vispb.PX_SERVICE:
+		return KindService
+	case vispb.PX_POD:
+		return KindPod
+	case vispb.PX_CONTAINER:
+		return KindContainer
+	case vispb.PX_NAMESPACE:
+		return KindNamespace
+	case vispb.PX_NODE:
+		return KindNode
+	case vispb.PX_TIME:
+		return KindTime
+	case vispb.PX_LIST, vispb.PX_STRING_LIST:
+		return KindList
+	}
+	return KindString
+}
+
+// entityKind returns the autocomplete entity kind that is used to look up values of the given kind
+// in the cluster, if any.
+func entityKind(k Kind) (cloudpb.AutocompleteEntityKind, bool) {
+	switch k {
+	case KindService:
+		return cloudpb.AEK_SVC, true
+	case KindPod:
+		return cloudpb.AEK_POD, true
+	case KindNamespace:
+		return cloudpb.AEK_NAMESPACE, true
+	case KindNode:
+		return cloudpb.AEK_NODE, true
+	}
+	return cloudpb.AEK_UNKNOWN, false
+}
+
+// argValue is a flag.Value that records the raw value of an argument. Values are checked together
+// after all of the flags are parsed, so that every bad argument can be reported at once.
+type argValue struct {
+	value string
+}
+
+func (a *argValue) String() string {
+	if a == nil {
+		return ""
+	}
+	return a.value
+}
+
+func (a *argValue) Set(s string) error {
+	a.value = s
+	return nil
+}
+
+// NewFlagSet creates a flag set for the variables of the given vis spec. The usage of each flag
+// includes the kind of value it expects.
+func NewFlagSet(scriptName string, vis *vispb.Vis) *flag.FlagSet {
+	fs := flag.NewFlagSet(scriptName, flag.ContinueOnError)
+	if vis == nil {
+		return fs
+	}
+	for _, v := range vis.Variables {
+		val := &argValue{}
+		usage := v.Description
+		if usage != "" && !strings.HasSuffix(usage, ".") {
+			usage += "."
+		}
+		kind := KindOf(v)
+		switch {
+		case kind == KindEnum:
+			usage += fmt.Sprintf(" One of: %s.", strings.Join(v.ValidValues, ", "))
+		case kind == KindTime:
+			usage += " A relative time like -5m, or an absolute time like 2006-01-02T15:04:05Z."
+		case kind == KindPod || kind == KindService:
+			usage += fmt.Sprintf(" Full names are written as <namespace>/<%s>.", kind)
+		}
+		// The flag package uses the back-quoted word as the name of the flag's type in the usage.
+		usage = strings.TrimSpace(fmt.Sprintf("%s (type: `%s`)", usage, kind))
+		if v.DefaultValue == nil {
+			usage += " (required)"
+		} else {
+			val.value = v.DefaultValue.Value
+		}
+		fs.Var(val, v.Name, usage)
+	}
+	return fs
+}
+
+// Values returns the values of the flags that were explicitly set on the flag set.
+func Values(fs *flag.FlagSet) map[string]string {
+	values := make(map[string]string)
+	fs.Visit(func(f *flag.Flag) {
+		values[f.Name] = f.Value.String()
+	})
+	return values
+}
+
+// ArgError describes a single script argument with a bad value.
+type ArgError struct {
+	Name        string
+	Value       string
+	Reason      string
+	Suggestions []string
+}
+
+func (e *ArgError) Error() string {
+	var sb strings.Builder
+	if e.Value == "" {
+		fmt.Fprintf(&sb, "--%s: %s", e.Name, e.Reason)
+	} else {
+		fmt.Fprintf(&sb, "--%s %q: %s", e.Name, e.Value, e.Reason)
+	}
+	if len(e.Suggestions) > 0 {
+		quoted := make([]string, len(e.Suggestions))
+		for i, s := range e.Suggestions {
+			quoted[i] = strconv.Quote(s)
+		}
+		fmt.Fprintf(&sb, ", did you mean %s?", strings.Join(quoted, " or "))
+	}
+	return sb.String()
+}
+
+// ValidationError holds all of the bad arguments that were passed to a script.
+type ValidationError struct {
+	Errors []*ArgError
+}
+
+func (e *ValidationError) Error() string {
+	lines := make([]string, len(e.Errors))
+	for i, argErr := range e.Errors {
+		lines[i] = argErr.Error()
+	}
+	return fmt.Sprintf("invalid script arguments:\n  %s", strings.Join(lines, "\n  "))
+}
+
+// EntityResolver looks up the Kubernetes entities that script arguments refer to.
+type EntityResolver interface {
+	// Lookup returns whether an entity of the given kind with exactly the given name exists, along
+	// with the names of similar entities.
+	Lookup(ctx context.Context, kind cloudpb.AutocompleteEntityKind, name string) (found bool, similar []string, err error)
+}
+
+// Validate checks the values passed to a script against the variables in its vis spec. Values
+// holds the arguments that the user set, keyed by name. All of the bad arguments are returned
+// together in a ValidationError.
+//
+// If resolver is non-nil, entity arguments such as pods and namespaces are also looked up in the
+// cluster. Scripts often filter on partial entity names, so entities that aren't found are returned
+// as warnings, with similar names as suggestions, rather than as errors. The lookup is best effort,
+// and is skipped if the resolver fails.
+func Validate(ctx context.Context, vis *vispb.Vis, values map[string]string, resolver EntityResolver) ([]*ArgError, error) {
+	if vis == nil {
+		return nil, nil
+	}
+	var errs, warnings []*ArgError
+	for _, v := range vis.Variables {
+		value, ok := values[v.Name]
+		if !ok {
+			if v.DefaultValue == nil {
+				errs = append(errs, &ArgError{Name: v.Name, Reason: "missing required argument"})
+			}
+			continue
+		}
+		// Optional arguments may always be left empty.
+		if value == "" && v.DefaultValue != nil && v.DefaultValue.Value == "" {
+			continue
+		}
+		if argErr := checkValue(v, value); argErr != nil {
+			errs = append(errs, argErr)
+			continue
+		}
+		if resolver == nil {
+			continue
+		}
+		ek, ok := entityKind(KindOf(v))
+		if !ok {
+			continue
+		}
+		found, similar, err := resolver.Lookup(ctx, ek, value)
+		if err != nil {
+			// We can't tell whether the entity exists, so leave it to the script to decide.
+			resolver = nil
+			continue
+		}
+		if !found {
+			if len(similar) > maxSuggestions {
+				similar = similar[:maxSuggestions]
+			}
+			warnings = append(warnings, &ArgError{
+				Name:        v.Name,
+				Value:       value,
+				Reason:      fmt.Sprintf("no %s with this exact name in the cluster", KindOf(v)),
+				Suggestions: similar,
+			})
+		}
+	}
+	if len(errs) > 0 {
+		return warnings, &ValidationError{Errors: errs}
+	}
+	return warnings, nil
+}
+
+// checkValue checks that the value is well formed for the kind of the variable.
+func checkValue(v *vispb.Vis_Variable, value string) *ArgError {
+	kind := KindOf(v)
+	argErr := func(reason string, suggestions ...string) *ArgError {
+		return &ArgError{Name: v.Name, Value: value, Reason: reason, Suggestions: suggestions}
+	}
+	switch kind {
+	case KindEnum:
+		for _, valid := range v.ValidValues {
+			if value == valid {
+				return nil
+			}
+		}
+		return argErr(fmt.Sprintf("must be one of %s", strings.Join(v.ValidValues, ", ")),
+			closestMatches(value, v.ValidValues)...)
+	case KindBoolean:
+		if _, err := strconv.ParseBool(value); err != nil {
+			return argErr("expected true or false")
+		}
+	case KindInt64:
+		if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+			return argErr("expected an integer")
+		}
+	case KindFloat64:
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return argErr("expected a number")
+		}
+	case KindTime:
+		if !isValidTime(value) {
+			return argErr("expected a relative time like -5m, or an absolute time like 2006-01-02T15:04:05Z")
+		}
+	case KindNode:
+		if len(validation.IsDNS1123Subdomain(value)) > 0 {
+			return argErr("not a valid node name")
+		}
+	}
+	return nil
+}
+
+var absoluteTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+}
+
+// isValidTime returns whether the value is a time that PxL accepts: a duration relative to now, an
+// absolute timestamp, or a unix time in nanoseconds.
+func isValidTime(value string) bool {
+	if _, err := time.ParseDuration(strings.TrimPrefix(value, "-")); err == nil {
+		return true
+	}
+	if _, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return true
+	}
+	for _, layout := range absoluteTimeLayouts {
+		if _, err := time.Parse(layout, value); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// closestMatches returns the candidates that are most likely to be what the user meant to type,
+// best match first.
+func closestMatches(value string, candidates []string) []string {
+	type match struct {
+		name string
+		dist int
+	}
+	maxDist := max(2, len(value)/3)
+	var matches []match
+	for _, c := range candidates {
+		d := editDistance(strings.ToLower(value), strings.ToLower(c))
+		if d <= maxDist {
+			matches = append(matches, match{c, d})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].dist < matches[j].dist })
+	var names []string
+	for i := 0; i < len(matches) && i < maxSuggestions; i++ {
+		names = append(names, matches[i].name)
+	}
+	return names
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
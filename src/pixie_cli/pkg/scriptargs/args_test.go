@@ -0,0 +1,201 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package scriptargs_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/gogo/protobuf/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"px.dev/pixie/src/api/proto/cloudpb"
+	"px.dev/pixie/src/api/proto/vispb"
+	"px.dev/pixie/src/pixie_cli/pkg/scriptargs"
+)
+
+func testVis() *vispb.Vis {
+	return &vispb.Vis{
+		Variables: []*vispb.Vis_Variable{
+			{Name: "start_time", Type: vispb.PX_TIME, DefaultValue: &types.StringValue{Value: "-5m"}},
+			{Name: "namespace", Type: vispb.PX_NAMESPACE},
+			{Name: "pod", Type: vispb.PX_POD, DefaultValue: &types.StringValue{Value: ""}},
+			{Name: "container", Type: vispb.PX_CONTAINER, DefaultValue: &types.StringValue{Value: ""}},
+			{Name: "response_time", Type: vispb.PX_STRING, DefaultValue: &types.StringValue{Value: ""}},
+			{Name: "limit", Type: vispb.PX_INT64, DefaultValue: &types.StringValue{Value: "100"}},
+			{Name: "group_by", Type: vispb.PX_STRING, DefaultValue: &types.StringValue{Value: "pod"},
+				ValidValues: []string{"pod", "service", "namespace"}},
+		},
+	}
+}
+
+type fakeResolver struct {
+	entities map[cloudpb.AutocompleteEntityKind][]string
+	err      error
+}
+
+func (f *fakeResolver) Lookup(_ context.Context, kind cloudpb.AutocompleteEntityKind, name string) (bool, []string, error) {
+	if f.err != nil {
+		return false, nil, f.err
+	}
+	for _, e := range f.entities[kind] {
+		if e == name {
+			return true, nil, nil
+		}
+	}
+	return false, f.entities[kind], nil
+}
+
+func TestKindOf(t *testing.T) {
+	vis := testVis()
+	kinds := make([]scriptargs.Kind, len(vis.Variables))
+	for i, v := range vis.Variables {
+		kinds[i] = scriptargs.KindOf(v)
+	}
+	assert.Equal(t, []scriptargs.Kind{
+		scriptargs.KindTime,
+		scriptargs.KindNamespace,
+		scriptargs.KindPod,
+		scriptargs.KindContainer,
+		scriptargs.KindString,
+		scriptargs.KindInt64,
+		scriptargs.KindEnum,
+	}, kinds)
+}
+
+func TestNewFlagSet(t *testing.T) {
+	fs := scriptargs.NewFlagSet("px/test", testVis())
+	require.NoError(t, fs.Parse([]string{"--namespace", "pl", "--limit=abc"}))
+
+	assert.Equal(t, map[string]string{"namespace": "pl", "limit": "abc"}, scriptargs.Values(fs))
+	assert.Equal(t, "-5m", fs.Lookup("start_time").Value.String())
+	assert.Contains(t, fs.Lookup("namespace").Usage, "(required)")
+	assert.Contains(t, fs.Lookup("group_by").Usage, "One of: pod, service, namespace.")
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		values map[string]string
+		errs   []string
+	}{
+		{
+			name:   "valid",
+			values: map[string]string{"namespace": "pl", "start_time": "2021-06-01T13:00:00Z", "pod": "pl/vizier-pem-abc"},
+		},
+		{
+			// Scripts filter on partial pod and service names.
+			name:   "partial pod name",
+			values: map[string]string{"namespace": "pl", "pod": "vizier-pem"},
+		},
+		{
+			// Namespaces and containers are matched the same way as pods and services.
+			name:   "partial namespace and container names",
+			values: map[string]string{"namespace": "pl-", "container": "pl/vizier-pem"},
+		},
+		{
+			// Only variables with the time type are checked as times.
+			name:   "string ending in _time",
+			values: map[string]string{"namespace": "pl", "response_time": "> 100ms"},
+		},
+		{
+			name:   "optional empty value",
+			values: map[string]string{"namespace": "pl", "pod": ""},
+		},
+		{
+			name:   "missing required",
+			values: map[string]string{},
+			errs:   []string{"--namespace: missing required argument"},
+		},
+		{
+			name: "all bad args reported",
+			values: map[string]string{
+				"namespace":  "pl",
+				"start_time": "5 minutes ago",
+				"limit":      "ten",
+				"group_by":   "servce",
+			},
+			errs: []string{
+				`--start_time "5 minutes ago": expected a relative time like -5m, or an absolute time like 2006-01-02T15:04:05Z`,
+				`--limit "ten": expected an integer`,
+				`--group_by "servce": must be one of pod, service, namespace, did you mean "service"?`,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			warnings, err := scriptargs.Validate(context.Background(), testVis(), test.values, nil)
+			assert.Empty(t, warnings)
+			if len(test.errs) == 0 {
+				require.NoError(t, err)
+				return
+			}
+			var vErr *scriptargs.ValidationError
+			require.True(t, errors.As(err, &vErr))
+			var msgs []string
+			for _, e := range vErr.Errors {
+				msgs = append(msgs, e.Error())
+			}
+			assert.Equal(t, test.errs, msgs)
+		})
+	}
+}
+
+func TestValidate_Resolver(t *testing.T) {
+	r := &fakeResolver{
+		entities: map[cloudpb.AutocompleteEntityKind][]string{
+			cloudpb.AEK_NAMESPACE: {"default", "pl"},
+			cloudpb.AEK_POD:       {"pl/vizier-pem-abc"},
+		},
+	}
+
+	warnings, err := scriptargs.Validate(context.Background(), testVis(),
+		map[string]string{"namespace": "pl", "pod": "pl/vizier-pem-abc"}, r)
+	require.NoError(t, err)
+	assert.Empty(t, warnings)
+
+	// Entities that aren't found are only warnings, since scripts may match part of the name.
+	warnings, err = scriptargs.Validate(context.Background(), testVis(),
+		map[string]string{"namespace": "defualt", "pod": "vizier-pem"}, r)
+	require.NoError(t, err)
+	var msgs []string
+	for _, w := range warnings {
+		msgs = append(msgs, w.Error())
+	}
+	assert.Equal(t, []string{
+		`--namespace "defualt": no namespace with this exact name in the cluster, did you mean "default" or "pl"?`,
+		`--pod "vizier-pem": no pod with this exact name in the cluster, did you mean "pl/vizier-pem-abc"?`,
+	}, msgs)
+
+	// Warnings are returned along with errors.
+	warnings, err = scriptargs.Validate(context.Background(), testVis(),
+		map[string]string{"namespace": "defualt", "limit": "ten"}, r)
+	require.EqualError(t, err, "invalid script arguments:\n"+`  --limit "ten": expected an integer`)
+	assert.Len(t, warnings, 1)
+}
+
+func TestValidate_ResolverError(t *testing.T) {
+	r := &fakeResolver{err: errors.New("unavailable")}
+	warnings, err := scriptargs.Validate(context.Background(), testVis(), map[string]string{"namespace": "defualt"}, r)
+	require.NoError(t, err)
+	assert.Empty(t, warnings)
+}
@@ -0,0 +1,62 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package scriptargs
+
+import (
+	"context"
+
+	"github.com/gofrs/uuid"
+
+	"px.dev/pixie/src/api/proto/cloudpb"
+	"px.dev/pixie/src/pixie_cli/pkg/auth"
+)
+
+// cloudResolver looks up entities using the cloud's autocomplete service, which indexes the
+// metadata of each cluster.
+type cloudResolver struct {
+	client    cloudpb.AutocompleteServiceClient
+	clusterID uuid.UUID
+}
+
+// NewCloudResolver creates an EntityResolver that looks up entities in the given cluster.
+func NewCloudResolver(client cloudpb.AutocompleteServiceClient, clusterID uuid.UUID) EntityResolver {
+	return &cloudResolver{
+		client:    client,
+		clusterID: clusterID,
+	}
+}
+
+func (r *cloudResolver) Lookup(ctx context.Context, kind cloudpb.AutocompleteEntityKind, name string) (bool, []string, error) {
+	resp, err := r.client.AutocompleteField(auth.CtxWithCreds(ctx), &cloudpb.AutocompleteFieldRequest{
+		Input:      name,
+		FieldType:  kind,
+		ClusterUID: r.clusterID.String(),
+	})
+	if err != nil {
+		return false, nil, err
+	}
+	var similar []string
+	for _, s := range resp.Suggestions {
+		if s.Name == name {
+			return true, nil, nil
+		}
+		similar = append(similar, s.Name)
+	}
+	return false, similar, nil
+}
@@ -0,0 +1,281 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package scriptdev
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"px.dev/pixie/src/api/proto/vispb"
+	"px.dev/pixie/src/carnot/planner/plannerpb"
+	"px.dev/pixie/src/carnot/planner/pxlcompiler"
+)
+
+// Severity is how serious a lint issue is. Only errors fail the lint.
+type Severity string
+
+const (
+	// SeverityError is used for problems that prevent the script from running.
+	SeverityError Severity = "error"
+	// SeverityWarning is used for problems that don't break the script, such as unused widgets.
+	SeverityWarning Severity = "warning"
+)
+
+// Issue is a problem found while linting a script.
+type Issue struct {
+	Script   string   `json:"script"`
+	Severity Severity `json:"severity"`
+	// File is the file that the issue is in, either the PxL source or the vis spec.
+	File string `json:"file"`
+	// Line is the line of the PxL source that the issue is on, or zero if it isn't known.
+	Line    int    `json:"line,omitempty"`
+	Message string `json:"message"`
+}
+
+// HasErrors returns whether any of the issues is an error.
+func HasErrors(issues []*Issue) bool {
+	for _, i := range issues {
+		if i.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// funcParam is a parameter of a function defined in a PxL script.
+type funcParam struct {
+	hasDefault bool
+	// annotation is the type annotation of the parameter, such as "int" or "px.Service".
+	annotation string
+}
+
+// funcDef is the signature of a function defined in a PxL script.
+type funcDef struct {
+	name   string
+	line   int
+	params map[string]*funcParam
+	// order is the names of the parameters in the order they're declared.
+	order []string
+	// variadic is set for functions that take *args or **kwargs, which accept any argument.
+	variadic bool
+}
+
+var funcDefRegex = regexp.MustCompile(`(?ms)^def\s+(\w+)\s*\((.*?)\)\s*(?:->[^:]*)?:`)
+
+// parseFuncDefs finds the top level functions defined in the PxL source.
+func parseFuncDefs(query string) map[string]*funcDef {
+	defs := make(map[string]*funcDef)
+	for _, m := range funcDefRegex.FindAllStringSubmatchIndex(query, -1) {
+		def := &funcDef{
+			name:   query[m[2]:m[3]],
+			line:   strings.Count(query[:m[0]], "\n") + 1,
+			params: make(map[string]*funcParam),
+		}
+		for _, p := range splitParams(query[m[4]:m[5]]) {
+			if strings.HasPrefix(p, "*") {
+				def.variadic = def.variadic || len(p) > 1
+				continue
+			}
+			decl, _, hasDefault := strings.Cut(p, "=")
+			name, annotation, _ := strings.Cut(decl, ":")
+			name = strings.TrimSpace(name)
+			def.params[name] = &funcParam{hasDefault: hasDefault, annotation: strings.TrimSpace(annotation)}
+			def.order = append(def.order, name)
+		}
+		defs[def.name] = def
+	}
+	return defs
+}
+
+// splitParams splits a parameter list on the commas that aren't nested in brackets or strings.
+func splitParams(s string) []string {
+	var params []string
+	depth := 0
+	var quote rune
+	start := 0
+	for i, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"':
+			quote = r
+		case r == '(' || r == '[' || r == '{':
+			depth++
+		case r == ')' || r == ']' || r == '}':
+			depth--
+		case r == ',' && depth == 0:
+			params = append(params, s[start:i])
+			start = i + 1
+		}
+	}
+	params = append(params, s[start:])
+
+	var out []string
+	for _, p := range params {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// Lint checks a script. The vis spec is checked against the functions defined in the script, and
+// if c is non-nil the script is compiled.
+func Lint(s *Script, c pxlcompiler.Compiler) []*Issue {
+	var issues []*Issue
+	report := func(severity Severity, file string, line int, format string, args ...interface{}) {
+		issues = append(issues, &Issue{
+			Script:   s.Name,
+			Severity: severity,
+			File:     file,
+			Line:     line,
+			Message:  fmt.Sprintf(format, args...),
+		})
+	}
+
+	vis, err := s.LoadVis()
+	if err != nil {
+		report(SeverityError, s.VisPath, 0, "%v", err)
+		return issues
+	}
+	if vis != nil {
+		for _, i := range lintVis(s, vis) {
+			report(i.Severity, s.VisPath, i.Line, "%s", i.Message)
+		}
+	}
+
+	if c == nil || s.IsMutation() {
+		return issues
+	}
+	req := &plannerpb.QueryRequest{QueryStr: s.Query}
+	if vis != nil {
+		req.ExecFuncs = ExecFuncs(vis, nil)
+	}
+	compileErrs, err := c.Compile(req)
+	if err != nil {
+		report(SeverityError, s.PxlPath, 0, "failed to compile: %v", err)
+		return issues
+	}
+	for _, e := range compileErrs {
+		report(SeverityError, s.PxlPath, e.Line, "%s", e.Message)
+	}
+	return issues
+}
+
+// lintVis checks that the vis spec matches the functions defined in the script.
+func lintVis(s *Script, vis *vispb.Vis) []*Issue {
+	var issues []*Issue
+	report := func(severity Severity, format string, args ...interface{}) {
+		issues = append(issues, &Issue{Severity: severity, Message: fmt.Sprintf(format, args...)})
+	}
+
+	defs := parseFuncDefs(s.Query)
+	variables := make(map[string]bool)
+	for _, v := range vis.Variables {
+		variables[v.Name] = false
+	}
+
+	checkFunc := func(caller string, f *vispb.Widget_Func) {
+		for _, arg := range f.Args {
+			if name := arg.GetVariable(); name != "" {
+				if _, ok := variables[name]; !ok {
+					report(SeverityError, "%s binds argument %q to undefined variable %q", caller, arg.Name, name)
+				} else {
+					variables[name] = true
+				}
+			}
+		}
+		// Functions imported from other scripts can't be checked.
+		if strings.Contains(f.Name, ".") {
+			return
+		}
+		def, ok := defs[f.Name]
+		if !ok {
+			report(SeverityError, "%s calls %q, which is not defined in the script", caller, f.Name)
+			return
+		}
+		passed := make(map[string]bool)
+		for _, arg := range f.Args {
+			passed[arg.Name] = true
+			if _, ok := def.params[arg.Name]; !ok && !def.variadic {
+				report(SeverityError, "%s passes argument %q, but %s (line %d) doesn't take it",
+					caller, arg.Name, f.Name, def.line)
+			}
+		}
+		for _, param := range def.order {
+			if !def.params[param].hasDefault && !passed[param] {
+				report(SeverityError, "%s doesn't pass required argument %q to %s (line %d)",
+					caller, param, f.Name, def.line)
+			}
+		}
+	}
+
+	globalFuncs := make(map[string]bool)
+	for _, gf := range vis.GlobalFuncs {
+		globalFuncs[gf.OutputName] = false
+		if gf.Func == nil {
+			report(SeverityError, "global func %q has no func", gf.OutputName)
+			continue
+		}
+		checkFunc(fmt.Sprintf("global func %q", gf.OutputName), gf.Func)
+	}
+
+	widgetNames := make(map[string]bool)
+	for i, w := range vis.Widgets {
+		caller := fmt.Sprintf("widget %d", i)
+		if w.Name != "" {
+			caller = fmt.Sprintf("widget %q", w.Name)
+			if widgetNames[w.Name] {
+				report(SeverityError, "%s is defined more than once", caller)
+			}
+			widgetNames[w.Name] = true
+		}
+		switch {
+		case w.GetFunc() != nil:
+			checkFunc(caller, w.GetFunc())
+		case w.GetGlobalFuncOutputName() != "":
+			name := w.GetGlobalFuncOutputName()
+			if _, ok := globalFuncs[name]; !ok {
+				report(SeverityError, "%s uses global func %q, which is not defined", caller, name)
+			} else {
+				globalFuncs[name] = true
+			}
+		default:
+			report(SeverityWarning, "%s has no func or global func, so it has no data to show", caller)
+		}
+		if w.DisplaySpec == nil {
+			report(SeverityWarning, "%s has no display spec, so it won't be shown", caller)
+		}
+	}
+
+	for _, gf := range vis.GlobalFuncs {
+		if !globalFuncs[gf.OutputName] {
+			report(SeverityWarning, "global func %q is not used by any widget", gf.OutputName)
+		}
+	}
+	for _, v := range vis.Variables {
+		if !variables[v.Name] {
+			report(SeverityWarning, "variable %q is not passed to any func", v.Name)
+		}
+	}
+	return issues
+}
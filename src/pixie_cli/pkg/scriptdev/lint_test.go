@@ -0,0 +1,156 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package scriptdev_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"px.dev/pixie/src/carnot/planner/plannerpb"
+	"px.dev/pixie/src/carnot/planner/pxlcompiler"
+	"px.dev/pixie/src/pixie_cli/pkg/scriptdev"
+)
+
+const testPxl = `import px
+
+def http_data(start_time: str, namespace: px.Namespace, limit: int = 100):
+    df = px.DataFrame(table='http_events', start_time=start_time)
+    return df.head(limit)
+
+def summary(start_time: str,
+            *args):
+    return px.DataFrame(table='http_events', start_time=start_time)
+`
+
+const testVis = `{
+  "variables": [
+    {"name": "start_time", "type": "PX_STRING", "defaultValue": "-5m"},
+    {"name": "namespace", "type": "PX_NAMESPACE"},
+    {"name": "unused", "type": "PX_STRING"}
+  ],
+  "globalFuncs": [
+    {"outputName": "summary", "func": {"name": "summary", "args": [
+      {"name": "start_time", "variable": "start_time"},
+      {"name": "anything", "value": "1"}
+    ]}},
+    {"outputName": "unused_summary", "func": {"name": "summary", "args": [
+      {"name": "start_time", "variable": "start_time"}
+    ]}}
+  ],
+  "widgets": [
+    {"name": "HTTP", "func": {"name": "http_data", "args": [
+      {"name": "start_time", "variable": "start_time"},
+      {"name": "ns", "variable": "namespace"}
+    ]}, "displaySpec": {"@type": "types.px.dev/px.vispb.Table"}},
+    {"name": "Missing", "func": {"name": "missing_func", "args": [
+      {"name": "start_time", "variable": "end_time"}
+    ]}, "displaySpec": {"@type": "types.px.dev/px.vispb.Table"}},
+    {"name": "Summary", "globalFuncOutputName": "summary", "displaySpec": {"@type": "types.px.dev/px.vispb.Table"}},
+    {"name": "Orphan", "globalFuncOutputName": "nope"},
+    {"name": "Empty", "displaySpec": {"@type": "types.px.dev/px.vispb.Table"}}
+  ]
+}`
+
+func writeScript(t *testing.T, dir, pxl, vis string) {
+	require.NoError(t, os.MkdirAll(dir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "script.pxl"), []byte(pxl), 0644))
+	if vis != "" {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, scriptdev.VisFile), []byte(vis), 0644))
+	}
+}
+
+func TestFindScripts(t *testing.T) {
+	root := t.TempDir()
+	writeScript(t, filepath.Join(root, "team", "http"), testPxl, testVis)
+	writeScript(t, filepath.Join(root, "team", "plain"), "import px\n", "")
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "empty"), 0755))
+
+	scripts, err := scriptdev.FindScripts(root)
+	require.NoError(t, err)
+	require.Len(t, scripts, 2)
+	assert.Equal(t, "team/http", scripts[0].Name)
+	assert.NotEmpty(t, scripts[0].VisPath)
+	assert.Equal(t, "team/plain", scripts[1].Name)
+	assert.Empty(t, scripts[1].VisPath)
+}
+
+type fakeCompiler struct {
+	reqs []*plannerpb.QueryRequest
+	errs []*pxlcompiler.CompileError
+}
+
+func (f *fakeCompiler) Compile(req *plannerpb.QueryRequest) ([]*pxlcompiler.CompileError, error) {
+	f.reqs = append(f.reqs, req)
+	return f.errs, nil
+}
+
+func TestLint(t *testing.T) {
+	root := t.TempDir()
+	writeScript(t, filepath.Join(root, "http"), testPxl, testVis)
+	scripts, err := scriptdev.FindScripts(root)
+	require.NoError(t, err)
+	require.Len(t, scripts, 1)
+
+	c := &fakeCompiler{errs: []*pxlcompiler.CompileError{{Line: 4, Column: 10, Message: "Table 'http_events' not found."}}}
+	issues := scriptdev.Lint(scripts[0], c)
+
+	type issue struct {
+		severity scriptdev.Severity
+		line     int
+		message  string
+	}
+	var got []issue
+	for _, i := range issues {
+		assert.Equal(t, "http", i.Script)
+		got = append(got, issue{i.Severity, i.Line, i.Message})
+	}
+	assert.Equal(t, []issue{
+		{scriptdev.SeverityError, 0, `widget "HTTP" passes argument "ns", but http_data (line 3) doesn't take it`},
+		{scriptdev.SeverityError, 0, `widget "HTTP" doesn't pass required argument "namespace" to http_data (line 3)`},
+		{scriptdev.SeverityError, 0, `widget "Missing" binds argument "start_time" to undefined variable "end_time"`},
+		{scriptdev.SeverityError, 0, `widget "Missing" calls "missing_func", which is not defined in the script`},
+		{scriptdev.SeverityError, 0, `widget "Orphan" uses global func "nope", which is not defined`},
+		{scriptdev.SeverityWarning, 0, `widget "Orphan" has no display spec, so it won't be shown`},
+		{scriptdev.SeverityWarning, 0, `widget "Empty" has no func or global func, so it has no data to show`},
+		{scriptdev.SeverityWarning, 0, `global func "unused_summary" is not used by any widget`},
+		{scriptdev.SeverityWarning, 0, `variable "unused" is not passed to any func`},
+		{scriptdev.SeverityError, 4, "Table 'http_events' not found."},
+	}, got)
+	assert.True(t, scriptdev.HasErrors(issues))
+
+	require.Len(t, c.reqs, 1)
+	assert.Len(t, c.reqs[0].ExecFuncs, 4)
+	assert.Equal(t, "-5m", c.reqs[0].ExecFuncs[0].ArgValues[0].Value)
+}
+
+func TestLint_InvalidVis(t *testing.T) {
+	root := t.TempDir()
+	writeScript(t, filepath.Join(root, "bad"), testPxl, `{"widgets": [{"nme": "typo"}]}`)
+	scripts, err := scriptdev.FindScripts(root)
+	require.NoError(t, err)
+
+	issues := scriptdev.Lint(scripts[0], nil)
+	require.Len(t, issues, 1)
+	assert.Equal(t, scriptdev.SeverityError, issues[0].Severity)
+	assert.Contains(t, issues[0].Message, "invalid vis spec")
+}
@@ -0,0 +1,214 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+// Package scriptdev contains tools for developing PxL scripts locally, before they are deployed.
+package scriptdev
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gogo/protobuf/jsonpb"
+
+	"px.dev/pixie/src/api/proto/vispb"
+	"px.dev/pixie/src/carnot/planner/plannerpb"
+)
+
+// VisFile is the name of the vis spec in a script's directory.
+const VisFile = "vis.json"
+
+// Script is a PxL script in a local scripts directory. Each script lives in its own directory,
+// alongside its optional vis spec and tests.
+type Script struct {
+	// Name is the path of the script's directory, relative to the directory it was found in.
+	Name string
+	// Dir is the directory that contains the script.
+	Dir string
+	// PxlPath is the path of the PxL source.
+	PxlPath string
+	// VisPath is the path of the vis spec, or empty if the script doesn't have one.
+	VisPath string
+	// Query is the PxL source.
+	Query string
+}
+
+// FindScripts finds the scripts in the given directories. A directory that directly contains a
+// .pxl file is a script, and only one .pxl file is allowed per directory.
+func FindScripts(roots ...string) ([]*Script, error) {
+	var scripts []*Script
+	for _, root := range roots {
+		err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() {
+				return nil
+			}
+			s, err := loadScript(root, p)
+			if err != nil {
+				return err
+			}
+			if s != nil {
+				scripts = append(scripts, s)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	sort.Slice(scripts, func(i, j int) bool { return scripts[i].Dir < scripts[j].Dir })
+	return scripts, nil
+}
+
+func loadScript(root, dir string) (*Script, error) {
+	pxlPaths, err := filepath.Glob(filepath.Join(dir, "*.pxl"))
+	if err != nil {
+		return nil, err
+	}
+	if len(pxlPaths) == 0 {
+		return nil, nil
+	}
+	if len(pxlPaths) > 1 {
+		return nil, fmt.Errorf("found multiple pxl scripts in %s", dir)
+	}
+	b, err := os.ReadFile(pxlPaths[0])
+	if err != nil {
+		return nil, err
+	}
+	name, err := filepath.Rel(root, dir)
+	if err != nil || name == "." {
+		name = filepath.Base(dir)
+	}
+	s := &Script{
+		Name:    filepath.ToSlash(name),
+		Dir:     dir,
+		PxlPath: pxlPaths[0],
+		Query:   string(b),
+	}
+	visPath := filepath.Join(dir, VisFile)
+	if _, err := os.Stat(visPath); err == nil {
+		s.VisPath = visPath
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	return s, nil
+}
+
+// LoadVis reads the script's vis spec. Returns nil if the script doesn't have one.
+func (s *Script) LoadVis() (*vispb.Vis, error) {
+	if s.VisPath == "" {
+		return nil, nil
+	}
+	f, err := os.Open(s.VisPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	vis := &vispb.Vis{}
+	if err := jsonpb.Unmarshal(f, vis); err != nil {
+		return nil, fmt.Errorf("invalid vis spec: %w", err)
+	}
+	return vis, nil
+}
+
+// IsMutation returns whether the script deploys tracepoints. These can't be compiled or run
+// against fixtures, since they modify the cluster.
+func (s *Script) IsMutation() bool {
+	return strings.Contains(s.Query, "pxtrace")
+}
+
+// placeholderForType returns a value of the given type that is used for variables that don't have
+// a default when compiling a script.
+func placeholderForType(pxType vispb.PXType) string {
+	switch pxType {
+	case vispb.PX_BOOLEAN:
+		return "True"
+	case vispb.PX_INT64:
+		return "1"
+	case vispb.PX_FLOAT64:
+		return "1.0"
+	case vispb.PX_SERVICE, vispb.PX_POD, vispb.PX_CONTAINER, vispb.PX_NAMESPACE, vispb.PX_NODE:
+		return "pl"
+	case vispb.PX_TIME:
+		return "-5m"
+	case vispb.PX_LIST:
+		return "[]"
+	case vispb.PX_STRING_LIST:
+		return "[\"\"]"
+	}
+	return ""
+}
+
+// ExecFuncs returns the functions that the vis spec calls, with their arguments bound. Variables
+// take their values from values, and fall back to their defaults.
+func ExecFuncs(vis *vispb.Vis, values map[string]string) []*plannerpb.FuncToExecute {
+	variables := make(map[string]*vispb.Vis_Variable)
+	for _, v := range vis.Variables {
+		variables[v.Name] = v
+	}
+	argValue := func(arg *vispb.Widget_Func_FuncArg) string {
+		if arg.GetVariable() == "" {
+			return arg.GetValue()
+		}
+		if v, ok := values[arg.GetVariable()]; ok {
+			return v
+		}
+		variable, ok := variables[arg.GetVariable()]
+		switch {
+		case !ok:
+			return ""
+		case variable.GetDefaultValue().GetValue() != "":
+			return variable.GetDefaultValue().GetValue()
+		case len(variable.ValidValues) > 0:
+			return variable.ValidValues[0]
+		}
+		return placeholderForType(variable.Type)
+	}
+	execFunc := func(f *vispb.Widget_Func) *plannerpb.FuncToExecute {
+		ef := &plannerpb.FuncToExecute{
+			FuncName:          f.Name,
+			OutputTablePrefix: f.Name,
+		}
+		for _, arg := range f.Args {
+			ef.ArgValues = append(ef.ArgValues, &plannerpb.FuncToExecute_ArgValue{
+				Name:  arg.Name,
+				Value: argValue(arg),
+			})
+		}
+		return ef
+	}
+
+	var funcs []*plannerpb.FuncToExecute
+	for _, gf := range vis.GlobalFuncs {
+		if gf.Func != nil {
+			funcs = append(funcs, execFunc(gf.Func))
+		}
+	}
+	for _, w := range vis.Widgets {
+		if w.GetFunc() != nil {
+			funcs = append(funcs, execFunc(w.GetFunc()))
+		}
+	}
+	return funcs
+}
@@ -0,0 +1,249 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package scriptdev
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+
+	"px.dev/pixie/src/api/proto/vispb"
+)
+
+// TestSpecFile is the name of the file that defines a script's tests, in the script's directory.
+const TestSpecFile = "pxl_test.yaml"
+
+// TestSpec is the set of tests for a script.
+type TestSpec struct {
+	Tests []*TestCase `json:"tests"`
+}
+
+// TestCase runs a script against a fixture table, and compares the output to a golden file.
+type TestCase struct {
+	Name string `json:"name"`
+	// Func is the function in the script whose output is checked. If empty, the script is run as
+	// is, and it must display a single table.
+	Func string `json:"func,omitempty"`
+	// Args are the arguments that Func is called with.
+	Args map[string]string `json:"args,omitempty"`
+	// Table is the name of the table that the fixture is loaded into.
+	Table string `json:"table"`
+	// Fixture is the path of the CSV that holds the input table, relative to the script directory.
+	// The first row has the type of each column, and the second row has the column names.
+	Fixture string `json:"fixture"`
+	// Golden is the path of the CSV that holds the expected output, relative to the script
+	// directory.
+	Golden string `json:"golden"`
+}
+
+// LoadTestSpec reads the script's test spec. Returns nil if the script doesn't have any tests.
+func (s *Script) LoadTestSpec() (*TestSpec, error) {
+	b, err := os.ReadFile(filepath.Join(s.Dir, TestSpecFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	spec := &TestSpec{}
+	if err := yaml.UnmarshalStrict(b, spec); err != nil {
+		return nil, fmt.Errorf("invalid test spec: %w", err)
+	}
+	for i, tc := range spec.Tests {
+		if tc.Name == "" {
+			tc.Name = fmt.Sprintf("test_%d", i)
+		}
+		if tc.Table == "" || tc.Fixture == "" || tc.Golden == "" {
+			return nil, fmt.Errorf("invalid test spec: test %q must set table, fixture and golden", tc.Name)
+		}
+	}
+	return spec, nil
+}
+
+// TestResult is the outcome of a single test case.
+type TestResult struct {
+	Script  string `json:"script"`
+	Test    string `json:"test"`
+	Passed  bool   `json:"passed"`
+	Message string `json:"message,omitempty"`
+}
+
+// TestRunner runs script tests with carnot_executable, which executes a query on a table loaded
+// from a CSV file.
+type TestRunner struct {
+	// CarnotExecutable is the path of the carnot_executable binary.
+	CarnotExecutable string
+	// Update rewrites the golden files with the output of the scripts instead of comparing them.
+	Update bool
+}
+
+// Run runs the tests of the script.
+func (r *TestRunner) Run(ctx context.Context, s *Script) ([]*TestResult, error) {
+	spec, err := s.LoadTestSpec()
+	if err != nil || spec == nil {
+		return nil, err
+	}
+	vis, err := s.LoadVis()
+	if err != nil {
+		return nil, err
+	}
+	var results []*TestResult
+	for _, tc := range spec.Tests {
+		res := &TestResult{Script: s.Name, Test: tc.Name}
+		if err := r.runTest(ctx, s, vis, tc); err != nil {
+			res.Message = err.Error()
+		} else {
+			res.Passed = true
+		}
+		results = append(results, res)
+	}
+	return results, nil
+}
+
+func (r *TestRunner) runTest(ctx context.Context, s *Script, vis *vispb.Vis, tc *TestCase) error {
+	out, err := os.CreateTemp("", "pxl_test_*.csv")
+	if err != nil {
+		return err
+	}
+	out.Close()
+	defer os.Remove(out.Name())
+
+	cmd := exec.CommandContext(ctx, r.CarnotExecutable,
+		"--input_file="+filepath.Join(s.Dir, tc.Fixture),
+		"--output_file="+out.Name(),
+		"--table_name="+tc.Table,
+		"--query="+testQuery(s, vis, tc),
+	)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to run script: %v: %s", err, lastLine(stderr.String()))
+	}
+
+	got, err := os.ReadFile(out.Name())
+	if err != nil {
+		return err
+	}
+	goldenPath := filepath.Join(s.Dir, tc.Golden)
+	if r.Update {
+		return os.WriteFile(goldenPath, got, 0644)
+	}
+	want, err := os.ReadFile(goldenPath)
+	if err != nil {
+		return err
+	}
+	return compareOutput(string(got), string(want))
+}
+
+// testQuery returns the query that runs the test. If the test calls a function, a call to display
+// its output is added to the end of the script.
+func testQuery(s *Script, vis *vispb.Vis, tc *TestCase) string {
+	if tc.Func == "" {
+		return s.Query
+	}
+	// Arguments take their type from the vis variable of the same name, or else from the type
+	// annotation in the function's signature.
+	types := make(map[string]vispb.PXType)
+	if def, ok := parseFuncDefs(s.Query)[tc.Func]; ok {
+		for name, p := range def.params {
+			types[name] = annotationTypes[p.annotation]
+		}
+	}
+	if vis != nil {
+		for _, v := range vis.Variables {
+			types[v.Name] = v.Type
+		}
+	}
+	names := make([]string, 0, len(tc.Args))
+	for name := range tc.Args {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	args := make([]string, len(names))
+	for i, name := range names {
+		args[i] = fmt.Sprintf("%s=%s", name, pxlLiteral(types[name], tc.Args[name]))
+	}
+	return fmt.Sprintf("%s\npx.display(%s(%s), '%s')\n", s.Query, tc.Func, strings.Join(args, ", "), tc.Func)
+}
+
+// annotationTypes maps the type annotations of PxL function parameters to their types.
+var annotationTypes = map[string]vispb.PXType{
+	"int":   vispb.PX_INT64,
+	"float": vispb.PX_FLOAT64,
+	"bool":  vispb.PX_BOOLEAN,
+}
+
+// pxlLiteral formats an argument value as a PxL literal of the given type.
+func pxlLiteral(pxType vispb.PXType, value string) string {
+	switch pxType {
+	case vispb.PX_INT64, vispb.PX_FLOAT64, vispb.PX_LIST, vispb.PX_STRING_LIST:
+		return value
+	case vispb.PX_BOOLEAN:
+		if b, err := strconv.ParseBool(value); err == nil {
+			if b {
+				return "True"
+			}
+			return "False"
+		}
+		return value
+	}
+	return strconv.Quote(value)
+}
+
+// compareOutput compares the rows output by a test with the golden rows, and describes the first
+// difference.
+func compareOutput(got, want string) error {
+	gotRows := splitRows(got)
+	wantRows := splitRows(want)
+	for i := 0; i < len(gotRows) && i < len(wantRows); i++ {
+		if gotRows[i] != wantRows[i] {
+			return fmt.Errorf("row %d differs: got %q, want %q", i+1, gotRows[i], wantRows[i])
+		}
+	}
+	if len(gotRows) != len(wantRows) {
+		return fmt.Errorf("got %d rows, want %d", len(gotRows), len(wantRows))
+	}
+	return nil
+}
+
+func splitRows(s string) []string {
+	s = strings.TrimRight(s, "\n")
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
+
+func lastLine(s string) string {
+	lines := splitRows(strings.TrimSpace(s))
+	if len(lines) == 0 {
+		return ""
+	}
+	return lines[len(lines)-1]
+}
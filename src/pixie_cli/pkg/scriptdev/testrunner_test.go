@@ -0,0 +1,124 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package scriptdev_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"px.dev/pixie/src/pixie_cli/pkg/scriptdev"
+)
+
+// fakeCarnot stands in for carnot_executable. It outputs the fixture rows, and records the queries.
+const fakeCarnot = `#!/bin/sh
+for arg in "$@"; do
+  case "$arg" in
+    --input_file=*) in="${arg#--input_file=}" ;;
+    --output_file=*) out="${arg#--output_file=}" ;;
+    --query=*) printf '%s' "${arg#--query=}" >> "$(dirname "$0")/queries" ;;
+  esac
+done
+tail -n +3 "$in" > "$out"
+`
+
+const testSpec = `tests:
+- name: passes
+  func: http_data
+  args:
+    start_time: -5m
+    limit: "10"
+  table: http_events
+  fixture: testdata/http_events.csv
+  golden: testdata/passes.csv
+- name: fails
+  table: http_events
+  fixture: testdata/http_events.csv
+  golden: testdata/fails.csv
+`
+
+func setupTestScript(t *testing.T) (*scriptdev.Script, string) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "http")
+	writeScript(t, dir, testPxl, testVis)
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "testdata"), 0755))
+	files := map[string]string{
+		scriptdev.TestSpecFile:     testSpec,
+		"testdata/http_events.csv": "string,int64\nservice,latency\nfront-end,10\ncarts,20\n",
+		"testdata/passes.csv":      "front-end,10\ncarts,20\n",
+		"testdata/fails.csv":       "front-end,10\ncarts,25\n",
+	}
+	for name, contents := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(contents), 0644))
+	}
+
+	binDir := t.TempDir()
+	carnot := filepath.Join(binDir, "carnot_executable")
+	require.NoError(t, os.WriteFile(carnot, []byte(fakeCarnot), 0755))
+
+	scripts, err := scriptdev.FindScripts(root)
+	require.NoError(t, err)
+	require.Len(t, scripts, 1)
+	return scripts[0], carnot
+}
+
+func TestTestRunner(t *testing.T) {
+	s, carnot := setupTestScript(t)
+	r := &scriptdev.TestRunner{CarnotExecutable: carnot}
+
+	results, err := r.Run(context.Background(), s)
+	require.NoError(t, err)
+	assert.Equal(t, []*scriptdev.TestResult{
+		{Script: "http", Test: "passes", Passed: true},
+		{Script: "http", Test: "fails", Message: `row 2 differs: got "carts,20", want "carts,25"`},
+	}, results)
+
+	queries, err := os.ReadFile(filepath.Join(filepath.Dir(carnot), "queries"))
+	require.NoError(t, err)
+	assert.Contains(t, string(queries), "\npx.display(http_data(limit=10, start_time=\"-5m\"), 'http_data')\n")
+}
+
+func TestTestRunner_Update(t *testing.T) {
+	s, carnot := setupTestScript(t)
+	r := &scriptdev.TestRunner{CarnotExecutable: carnot, Update: true}
+
+	results, err := r.Run(context.Background(), s)
+	require.NoError(t, err)
+	for _, res := range results {
+		assert.True(t, res.Passed)
+	}
+	golden, err := os.ReadFile(filepath.Join(s.Dir, "testdata", "fails.csv"))
+	require.NoError(t, err)
+	assert.Equal(t, "front-end,10\ncarts,20\n", string(golden))
+}
+
+func TestTestRunner_NoTests(t *testing.T) {
+	root := t.TempDir()
+	writeScript(t, filepath.Join(root, "plain"), "import px\n", "")
+	scripts, err := scriptdev.FindScripts(root)
+	require.NoError(t, err)
+
+	results, err := (&scriptdev.TestRunner{CarnotExecutable: "false"}).Run(context.Background(), scripts[0])
+	require.NoError(t, err)
+	assert.Empty(t, results)
+}
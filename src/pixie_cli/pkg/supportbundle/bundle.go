@@ -0,0 +1,225 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+// Package supportbundle collects the state of a Vizier into a single redacted archive, which can be
+// shared with the Pixie team and inspected offline.
+package supportbundle
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ManifestPath is the path of the manifest in a support bundle.
+const ManifestPath = "manifest.json"
+
+const manifestVersion = 1
+
+// The kinds of files in a support bundle.
+const (
+	KindVizier       = "vizier"
+	KindPods         = "pods"
+	KindLogs         = "logs"
+	KindEvents       = "events"
+	KindNodes        = "nodes"
+	KindConfigMaps   = "configmaps"
+	KindScriptOutput = "script"
+)
+
+// FileEntry describes a file in a support bundle.
+type FileEntry struct {
+	Path      string `json:"path"`
+	Kind      string `json:"kind"`
+	Size      int    `json:"size"`
+	Namespace string `json:"namespace,omitempty"`
+	Pod       string `json:"pod,omitempty"`
+	Container string `json:"container,omitempty"`
+	// Previous is set for the logs of a container's previous instance.
+	Previous bool `json:"previous,omitempty"`
+}
+
+// CollectionError is a part of the bundle that couldn't be collected.
+type CollectionError struct {
+	Kind   string `json:"kind"`
+	Source string `json:"source"`
+	Error  string `json:"error"`
+}
+
+// Manifest is the index of a support bundle.
+type Manifest struct {
+	Version           int                `json:"version"`
+	CreatedAt         time.Time          `json:"createdAt"`
+	CLIVersion        string             `json:"cliVersion,omitempty"`
+	ClusterID         string             `json:"clusterID,omitempty"`
+	VizierNamespace   string             `json:"vizierNamespace,omitempty"`
+	OperatorNamespace string             `json:"operatorNamespace,omitempty"`
+	Files             []*FileEntry       `json:"files"`
+	Errors            []*CollectionError `json:"errors,omitempty"`
+}
+
+// Writer writes a gzipped tar support bundle. The manifest is written last, when the writer is closed.
+// It is safe to add files from multiple goroutines.
+type Writer struct {
+	mu       sync.Mutex
+	gz       *gzip.Writer
+	tw       *tar.Writer
+	manifest *Manifest
+}
+
+// NewWriter creates a Writer. The manifest's files and errors are filled in as they are added.
+func NewWriter(w io.Writer, manifest *Manifest) *Writer {
+	gz := gzip.NewWriter(w)
+	manifest.Version = manifestVersion
+	if manifest.CreatedAt.IsZero() {
+		manifest.CreatedAt = time.Now()
+	}
+	return &Writer{gz: gz, tw: tar.NewWriter(gz), manifest: manifest}
+}
+
+func (w *Writer) writeFile(path string, data []byte) error {
+	hdr := &tar.Header{
+		Name:    path,
+		Mode:    0644,
+		Size:    int64(len(data)),
+		ModTime: w.manifest.CreatedAt,
+	}
+	if err := w.tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err := w.tw.Write(data)
+	return err
+}
+
+// Add adds a file to the bundle and its entry to the manifest.
+func (w *Writer) Add(entry *FileEntry, data []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if entry.Path == ManifestPath {
+		return fmt.Errorf("%s is reserved for the bundle manifest", ManifestPath)
+	}
+	entry.Size = len(data)
+	if err := w.writeFile(entry.Path, data); err != nil {
+		return err
+	}
+	w.manifest.Files = append(w.manifest.Files, entry)
+	return nil
+}
+
+// AddError records a part of the bundle that couldn't be collected.
+func (w *Writer) AddError(kind, source string, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.manifest.Errors = append(w.manifest.Errors, &CollectionError{Kind: kind, Source: source, Error: err.Error()})
+}
+
+// Close writes the manifest and flushes the bundle. It doesn't close the underlying writer.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	sort.Slice(w.manifest.Files, func(i, j int) bool { return w.manifest.Files[i].Path < w.manifest.Files[j].Path })
+	b, err := json.MarshalIndent(w.manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := w.writeFile(ManifestPath, b); err != nil {
+		return err
+	}
+	if err := w.tw.Close(); err != nil {
+		return err
+	}
+	return w.gz.Close()
+}
+
+// Bundle is a support bundle that was read into memory.
+type Bundle struct {
+	Manifest *Manifest
+	Files    map[string][]byte
+}
+
+// Read reads a support bundle.
+func Read(r io.Reader) (*Bundle, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("not a support bundle: %w", err)
+	}
+	defer gz.Close()
+
+	b := &Bundle{Files: make(map[string][]byte)}
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		b.Files[hdr.Name] = data
+	}
+
+	m, ok := b.Files[ManifestPath]
+	if !ok {
+		return nil, errors.New("not a support bundle: missing manifest")
+	}
+	if err := json.Unmarshal(m, &b.Manifest); err != nil {
+		return nil, fmt.Errorf("invalid bundle manifest: %w", err)
+	}
+	if b.Manifest.Version > manifestVersion {
+		return nil, fmt.Errorf("bundle version %d is newer than this CLI supports, please update px", b.Manifest.Version)
+	}
+	delete(b.Files, ManifestPath)
+	return b, nil
+}
+
+// ReadFile reads a support bundle from a file.
+func ReadFile(path string) (*Bundle, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Read(f)
+}
+
+// FilesOfKind returns the manifest entries of the given kind.
+func (b *Bundle) FilesOfKind(kind string) []*FileEntry {
+	var entries []*FileEntry
+	for _, f := range b.Manifest.Files {
+		if f.Kind == kind {
+			entries = append(entries, f)
+		}
+	}
+	return entries
+}
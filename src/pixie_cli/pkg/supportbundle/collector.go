@@ -0,0 +1,248 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package supportbundle
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+
+	"golang.org/x/sync/errgroup"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/yaml"
+
+	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
+	"px.dev/pixie/src/operator/client/versioned"
+)
+
+const (
+	// DefaultMaxLogBytes is the default limit on the size of each container's logs in the bundle.
+	DefaultMaxLogBytes = 10 * 1024 * 1024
+	// maxConcurrentLogRequests limits the load on the K8s API server when there are many PEMs.
+	maxConcurrentLogRequests = 8
+)
+
+// Collector gathers the state of a Vizier from the K8s API into a support bundle. Secrets are never
+// collected, and credentials are redacted from everything that is.
+type Collector struct {
+	clientset         kubernetes.Interface
+	vzClient          versioned.Interface
+	vizierNamespace   string
+	operatorNamespace string
+	maxLogBytes       int64
+}
+
+// NewCollector creates a Collector for the Vizier in the given namespace. The operator namespace may
+// be empty if the Vizier wasn't deployed by the operator.
+func NewCollector(clientset kubernetes.Interface, vzClient versioned.Interface, vizierNamespace, operatorNamespace string, maxLogBytes int64) *Collector {
+	if maxLogBytes <= 0 {
+		maxLogBytes = DefaultMaxLogBytes
+	}
+	return &Collector{
+		clientset:         clientset,
+		vzClient:          vzClient,
+		vizierNamespace:   vizierNamespace,
+		operatorNamespace: operatorNamespace,
+		maxLogBytes:       maxLogBytes,
+	}
+}
+
+// Collect writes everything it can collect to the bundle. Parts that can't be collected are recorded
+// as errors in the bundle manifest rather than failing the whole collection.
+func (c *Collector) Collect(ctx context.Context, w *Writer) error {
+	namespaces := []string{c.vizierNamespace}
+	if c.operatorNamespace != "" && c.operatorNamespace != c.vizierNamespace {
+		namespaces = append(namespaces, c.operatorNamespace)
+	}
+
+	c.collectViziers(ctx, w)
+	c.collectNodes(ctx, w)
+	for _, ns := range namespaces {
+		c.collectEvents(ctx, w, ns)
+		c.collectConfigMaps(ctx, w, ns)
+		if err := c.collectPods(ctx, w, ns); err != nil {
+			return err
+		}
+	}
+	return ctx.Err()
+}
+
+// addYAML adds the object to the bundle as YAML, and records any failure to do so.
+func addYAML(w *Writer, entry *FileEntry, obj interface{}) {
+	b, err := yaml.Marshal(obj)
+	if err != nil {
+		w.AddError(entry.Kind, entry.Path, err)
+		return
+	}
+	if err := w.Add(entry, b); err != nil {
+		w.AddError(entry.Kind, entry.Path, err)
+	}
+}
+
+func (c *Collector) collectViziers(ctx context.Context, w *Writer) {
+	vzs, err := c.vzClient.PxV1alpha1().Viziers(c.vizierNamespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		w.AddError(KindVizier, c.vizierNamespace, err)
+		return
+	}
+	for i := range vzs.Items {
+		vz := &vzs.Items[i]
+		redactVizier(vz)
+		addYAML(w, &FileEntry{
+			Path:      path.Join("vizier", vz.Name+".yaml"),
+			Kind:      KindVizier,
+			Namespace: vz.Namespace,
+		}, vz)
+	}
+}
+
+func redactVizier(vz *v1alpha1.Vizier) {
+	if vz.Spec.DeployKey != "" {
+		vz.Spec.DeployKey = redacted
+	}
+	vz.ManagedFields = nil
+	delete(vz.Annotations, "kubectl.kubernetes.io/last-applied-configuration")
+}
+
+// nodeInfo is the subset of a node that is useful for debugging Pixie.
+type nodeInfo struct {
+	Name        string                 `json:"name"`
+	Labels      map[string]string      `json:"labels,omitempty"`
+	Taints      []corev1.Taint         `json:"taints,omitempty"`
+	NodeInfo    corev1.NodeSystemInfo  `json:"nodeInfo"`
+	Capacity    corev1.ResourceList    `json:"capacity,omitempty"`
+	Allocatable corev1.ResourceList    `json:"allocatable,omitempty"`
+	Conditions  []corev1.NodeCondition `json:"conditions,omitempty"`
+}
+
+func (c *Collector) collectNodes(ctx context.Context, w *Writer) {
+	nodes, err := c.clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		w.AddError(KindNodes, "nodes", err)
+		return
+	}
+	infos := make([]*nodeInfo, len(nodes.Items))
+	for i, n := range nodes.Items {
+		infos[i] = &nodeInfo{
+			Name:        n.Name,
+			Labels:      n.Labels,
+			Taints:      n.Spec.Taints,
+			NodeInfo:    n.Status.NodeInfo,
+			Capacity:    n.Status.Capacity,
+			Allocatable: n.Status.Allocatable,
+			Conditions:  n.Status.Conditions,
+		}
+	}
+	addYAML(w, &FileEntry{Path: "nodes.yaml", Kind: KindNodes}, infos)
+}
+
+func (c *Collector) collectEvents(ctx context.Context, w *Writer, namespace string) {
+	events, err := c.clientset.CoreV1().Events(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		w.AddError(KindEvents, namespace, err)
+		return
+	}
+	sort.SliceStable(events.Items, func(i, j int) bool {
+		return events.Items[i].LastTimestamp.Before(&events.Items[j].LastTimestamp)
+	})
+	for i := range events.Items {
+		events.Items[i].ManagedFields = nil
+		events.Items[i].Message = RedactText(events.Items[i].Message)
+	}
+	addYAML(w, &FileEntry{Path: path.Join("events", namespace+".yaml"), Kind: KindEvents, Namespace: namespace}, events)
+}
+
+func (c *Collector) collectConfigMaps(ctx context.Context, w *Writer, namespace string) {
+	cms, err := c.clientset.CoreV1().ConfigMaps(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		w.AddError(KindConfigMaps, namespace, err)
+		return
+	}
+	for i := range cms.Items {
+		cms.Items[i].ManagedFields = nil
+		delete(cms.Items[i].Annotations, "kubectl.kubernetes.io/last-applied-configuration")
+		redactConfigMap(&cms.Items[i])
+	}
+	addYAML(w, &FileEntry{Path: path.Join("configmaps", namespace+".yaml"), Kind: KindConfigMaps, Namespace: namespace}, cms)
+}
+
+func (c *Collector) collectPods(ctx context.Context, w *Writer, namespace string) error {
+	pods, err := c.clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		w.AddError(KindPods, namespace, err)
+		return nil
+	}
+	for i := range pods.Items {
+		redactPod(&pods.Items[i])
+	}
+	addYAML(w, &FileEntry{Path: path.Join("pods", namespace+".yaml"), Kind: KindPods, Namespace: namespace}, pods)
+
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(maxConcurrentLogRequests)
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		statuses := append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+		for _, s := range statuses {
+			container := s.Name
+			g.Go(func() error {
+				c.collectLogs(ctx, w, pod, container, false)
+				return ctx.Err()
+			})
+			if s.RestartCount > 0 {
+				g.Go(func() error {
+					c.collectLogs(ctx, w, pod, container, true)
+					return ctx.Err()
+				})
+			}
+		}
+	}
+	return g.Wait()
+}
+
+func (c *Collector) collectLogs(ctx context.Context, w *Writer, pod *corev1.Pod, container string, previous bool) {
+	name := container + ".log"
+	if previous {
+		name = container + ".previous.log"
+	}
+	p := path.Join("logs", pod.Namespace, pod.Name, name)
+
+	logs, err := c.clientset.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, &corev1.PodLogOptions{
+		Container:  container,
+		Previous:   previous,
+		LimitBytes: &c.maxLogBytes,
+	}).DoRaw(ctx)
+	if err != nil {
+		w.AddError(KindLogs, p, fmt.Errorf("failed to get logs: %w", err))
+		return
+	}
+	err = w.Add(&FileEntry{
+		Path:      p,
+		Kind:      KindLogs,
+		Namespace: pod.Namespace,
+		Pod:       pod.Name,
+		Container: container,
+		Previous:  previous,
+	}, []byte(RedactText(string(logs))))
+	if err != nil {
+		w.AddError(KindLogs, p, err)
+	}
+}
@@ -0,0 +1,171 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package supportbundle_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
+	vzfake "px.dev/pixie/src/operator/client/versioned/fake"
+	"px.dev/pixie/src/pixie_cli/pkg/supportbundle"
+)
+
+func TestCollectAndSummarize(t *testing.T) {
+	clientset := fake.NewSimpleClientset(
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "vizier-pem-abcd", Namespace: "pl"},
+			Spec: corev1.PodSpec{
+				NodeName: "node-1",
+				Containers: []corev1.Container{{
+					Name: "pem",
+					Env: []corev1.EnvVar{
+						{Name: "PL_DEPLOY_KEY", Value: "px-dep-0cd40c79-2ba9-4e19-a8a6-5f3e4ba4a1b3"},
+						{Name: "PL_CLUSTER_NAME", Value: "dev"},
+					},
+				}},
+			},
+			Status: corev1.PodStatus{
+				Phase: corev1.PodRunning,
+				ContainerStatuses: []corev1.ContainerStatus{{
+					Name:         "pem",
+					RestartCount: 2,
+					State:        corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}},
+				}},
+			},
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "vizier-operator-1234", Namespace: "px-operator"},
+			Status: corev1.PodStatus{
+				Phase:             corev1.PodRunning,
+				ContainerStatuses: []corev1.ContainerStatus{{Name: "app", Ready: true}},
+			},
+		},
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "pl-cloud-config", Namespace: "pl"},
+			Data: map[string]string{
+				"PL_CLOUD_ADDR":  "withpixie.ai:443",
+				"PL_SENTRY_DSN":  "https://abc@sentry.io/1",
+				"PL_CLUSTER_ENV": "password=hunter2",
+			},
+		},
+		&corev1.Event{
+			ObjectMeta:     metav1.ObjectMeta{Name: "pem-oom", Namespace: "pl"},
+			InvolvedObject: corev1.ObjectReference{Kind: "Pod", Name: "vizier-pem-abcd"},
+			Type:           corev1.EventTypeWarning,
+			Reason:         "BackOff",
+			Message:        "Back-off restarting failed container",
+			Count:          5,
+		},
+		&corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+			Status: corev1.NodeStatus{
+				NodeInfo:    corev1.NodeSystemInfo{KernelVersion: "5.15.0"},
+				Allocatable: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("8Gi")},
+				Conditions:  []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionTrue}},
+			},
+		},
+	)
+	vzClient := vzfake.NewSimpleClientset(&v1alpha1.Vizier{
+		ObjectMeta: metav1.ObjectMeta{Name: "pixie", Namespace: "pl"},
+		Spec:       v1alpha1.VizierSpec{DeployKey: "px-dep-0cd40c79-2ba9-4e19-a8a6-5f3e4ba4a1b3"},
+		Status: v1alpha1.VizierStatus{
+			Version:     "0.14.0",
+			VizierPhase: v1alpha1.VizierPhaseDegraded,
+		},
+	})
+
+	var buf bytes.Buffer
+	w := supportbundle.NewWriter(&buf, &supportbundle.Manifest{VizierNamespace: "pl", OperatorNamespace: "px-operator"})
+	c := supportbundle.NewCollector(clientset, vzClient, "pl", "px-operator", 0)
+	require.NoError(t, c.Collect(context.Background(), w))
+	require.NoError(t, w.Close())
+
+	b, err := supportbundle.Read(&buf)
+	require.NoError(t, err)
+
+	// Make sure that no credentials made it into the bundle.
+	for path, data := range b.Files {
+		assert.NotContains(t, string(data), "0cd40c79", path)
+		assert.NotContains(t, string(data), "hunter2", path)
+		assert.NotContains(t, string(data), "sentry.io", path)
+	}
+	assert.Contains(t, string(b.Files["configmaps/pl.yaml"]), "withpixie.ai:443")
+
+	assert.Contains(t, b.Files, "logs/pl/vizier-pem-abcd/pem.log")
+	assert.Contains(t, b.Files, "logs/pl/vizier-pem-abcd/pem.previous.log")
+	assert.Contains(t, b.Files, "logs/px-operator/vizier-operator-1234/app.log")
+
+	s, err := supportbundle.Summarize(b)
+	require.NoError(t, err)
+	require.Len(t, s.Viziers, 1)
+	assert.Equal(t, "Degraded", s.Viziers[0].Phase)
+	assert.Equal(t, "0.14.0", s.Viziers[0].Version)
+
+	require.Len(t, s.Pods, 2)
+	assert.Equal(t, &supportbundle.PodSummary{
+		Namespace: "pl",
+		Name:      "vizier-pem-abcd",
+		Phase:     "Running",
+		Ready:     "0/1",
+		Restarts:  2,
+		Node:      "node-1",
+		Reason:    "CrashLoopBackOff",
+	}, s.Pods[0])
+
+	require.Len(t, s.Nodes, 1)
+	assert.True(t, s.Nodes[0].Ready)
+	assert.Equal(t, "8Gi", s.Nodes[0].Memory)
+
+	require.Len(t, s.WarningEvents, 1)
+	assert.Equal(t, "Pod/vizier-pem-abcd", s.WarningEvents[0].Object)
+	assert.Len(t, s.Logs, 3)
+}
+
+func TestSummarizeLogErrors(t *testing.T) {
+	var buf bytes.Buffer
+	w := supportbundle.NewWriter(&buf, &supportbundle.Manifest{})
+	logs := strings.Join([]string{
+		`time="2023-01-01T00:00:00Z" level=info msg="Starting"`,
+		`time="2023-01-01T00:00:01Z" level=error msg="Failed to connect"`,
+		`I0101 00:00:02.000000 1 pem.cc:10] Started`,
+		`E0101 00:00:03.000000 1 pem.cc:20] Failed to deploy uprobes`,
+	}, "\n")
+	require.NoError(t, w.Add(&supportbundle.FileEntry{Path: "logs/pl/pem/pem.log", Kind: supportbundle.KindLogs}, []byte(logs)))
+	require.NoError(t, w.Add(&supportbundle.FileEntry{Path: "logs/pl/kelvin/app.log", Kind: supportbundle.KindLogs}, []byte("level=info\n")))
+	require.NoError(t, w.Close())
+
+	b, err := supportbundle.Read(&buf)
+	require.NoError(t, err)
+	s, err := supportbundle.Summarize(b)
+	require.NoError(t, err)
+	assert.Equal(t, []*supportbundle.LogSummary{
+		{Path: "logs/pl/pem/pem.log", Lines: 4, ErrorLines: 2, LastError: "E0101 00:00:03.000000 1 pem.cc:20] Failed to deploy uprobes"},
+		{Path: "logs/pl/kelvin/app.log", Lines: 1},
+	}, s.Logs)
+}
@@ -0,0 +1,100 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package supportbundle
+
+import (
+	"fmt"
+	"regexp"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+const redacted = "<redacted>"
+
+// sensitiveKeyRegex matches the names of config map keys and environment variables whose values
+// shouldn't leave the cluster.
+var sensitiveKeyRegex = regexp.MustCompile(`(?i)(passw(or)?d|secret|token|api_?key|deploy_?key|private_?key|credential|dsn)`)
+
+type textRedaction struct {
+	re   *regexp.Regexp
+	repl string
+}
+
+// textRedactions are applied to all free-form text in the bundle, such as logs.
+var textRedactions = []textRedaction{
+	// Pixie deploy keys and API keys.
+	{regexp.MustCompile(`px-(dep|api)-[0-9a-fA-F-]{36}`), "px-$1-" + redacted},
+	// JWTs, such as service tokens.
+	{regexp.MustCompile(`eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`), redacted},
+	{regexp.MustCompile(`(?i)(bearer\s+)[A-Za-z0-9._~+/=-]+`), "${1}" + redacted},
+	// key=value and key: value pairs for sensitive keys.
+	{regexp.MustCompile(`(?i)((?:passw(?:or)?d|secret|token|api_?key|deploy_?key)["']?\s*[:=]\s*["']?)[^\s"',}]+`), "${1}" + redacted},
+}
+
+// RedactText removes credentials from free-form text.
+func RedactText(s string) string {
+	for _, r := range textRedactions {
+		s = r.re.ReplaceAllString(s, r.repl)
+	}
+	return s
+}
+
+func isSensitiveKey(key string) bool {
+	return sensitiveKeyRegex.MatchString(key)
+}
+
+// redactConfigMap removes the values of sensitive keys from the config map, and credentials from
+// the other values. Binary data is always removed.
+func redactConfigMap(cm *corev1.ConfigMap) {
+	for k, v := range cm.Data {
+		if isSensitiveKey(k) {
+			cm.Data[k] = redacted
+			continue
+		}
+		cm.Data[k] = RedactText(v)
+	}
+	for k, v := range cm.BinaryData {
+		cm.BinaryData[k] = []byte(fmt.Sprintf("%s %d bytes", redacted, len(v)))
+	}
+}
+
+// redactPod removes literal values of sensitive environment variables from the pod spec.
+func redactPod(pod *corev1.Pod) {
+	redactContainers := func(containers []corev1.Container) {
+		for i := range containers {
+			for j := range containers[i].Env {
+				env := &containers[i].Env[j]
+				if env.Value == "" {
+					continue
+				}
+				if isSensitiveKey(env.Name) {
+					env.Value = redacted
+				} else {
+					env.Value = RedactText(env.Value)
+				}
+			}
+			for j := range containers[i].Args {
+				containers[i].Args[j] = RedactText(containers[i].Args[j])
+			}
+		}
+	}
+	redactContainers(pod.Spec.InitContainers)
+	redactContainers(pod.Spec.Containers)
+	pod.ManagedFields = nil
+}
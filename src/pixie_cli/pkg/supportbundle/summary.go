@@ -0,0 +1,229 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package supportbundle
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/yaml"
+
+	"px.dev/pixie/src/operator/apis/px.dev/v1alpha1"
+)
+
+// errorLineRegex matches error lines in both the Go (logrus) and C++ (glog) services' logs.
+var errorLineRegex = regexp.MustCompile(`level=(error|fatal|panic)|^[EF]\d{4} |^panic: `)
+
+// VizierSummary is the state of a Vizier in a support bundle.
+type VizierSummary struct {
+	Name                string `json:"name"`
+	Namespace           string `json:"namespace"`
+	Version             string `json:"version"`
+	OperatorVersion     string `json:"operatorVersion"`
+	Phase               string `json:"phase"`
+	Reason              string `json:"reason,omitempty"`
+	ReconciliationPhase string `json:"reconciliationPhase"`
+	Message             string `json:"message,omitempty"`
+}
+
+// PodSummary is the state of a pod in a support bundle.
+type PodSummary struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Phase     string `json:"phase"`
+	Ready     string `json:"ready"`
+	Restarts  int32  `json:"restarts"`
+	Node      string `json:"node"`
+	// Reason is why a container is waiting or was last terminated, if any.
+	Reason string `json:"reason,omitempty"`
+}
+
+// NodeSummary is the state of a node in a support bundle.
+type NodeSummary struct {
+	Name           string `json:"name"`
+	Ready          bool   `json:"ready"`
+	KernelVersion  string `json:"kernelVersion"`
+	KubeletVersion string `json:"kubeletVersion"`
+	OSImage        string `json:"osImage"`
+	Memory         string `json:"allocatableMemory"`
+}
+
+// EventSummary is a warning event in a support bundle.
+type EventSummary struct {
+	Namespace string `json:"namespace"`
+	Object    string `json:"object"`
+	Reason    string `json:"reason"`
+	Message   string `json:"message"`
+	Count     int32  `json:"count"`
+}
+
+// LogSummary is the number of error lines in a container log in the bundle.
+type LogSummary struct {
+	Path       string `json:"path"`
+	Lines      int    `json:"lines"`
+	ErrorLines int    `json:"errorLines"`
+	// LastError is the last error line in the log.
+	LastError string `json:"lastError,omitempty"`
+}
+
+// Summary summarizes the state of the cluster in a support bundle.
+type Summary struct {
+	Manifest      *Manifest        `json:"manifest"`
+	Viziers       []*VizierSummary `json:"viziers"`
+	Pods          []*PodSummary    `json:"pods"`
+	Nodes         []*NodeSummary   `json:"nodes"`
+	WarningEvents []*EventSummary  `json:"warningEvents"`
+	Logs          []*LogSummary    `json:"logs"`
+}
+
+func unmarshalFile(b *Bundle, entry *FileEntry, obj interface{}) error {
+	if err := yaml.Unmarshal(b.Files[entry.Path], obj); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", entry.Path, err)
+	}
+	return nil
+}
+
+// Summarize summarizes the support bundle.
+func Summarize(b *Bundle) (*Summary, error) {
+	s := &Summary{Manifest: b.Manifest}
+
+	for _, f := range b.FilesOfKind(KindVizier) {
+		var vz v1alpha1.Vizier
+		if err := unmarshalFile(b, f, &vz); err != nil {
+			return nil, err
+		}
+		s.Viziers = append(s.Viziers, &VizierSummary{
+			Name:                vz.Name,
+			Namespace:           vz.Namespace,
+			Version:             vz.Status.Version,
+			OperatorVersion:     vz.Status.OperatorVersion,
+			Phase:               string(vz.Status.VizierPhase),
+			Reason:              vz.Status.VizierReason,
+			ReconciliationPhase: string(vz.Status.ReconciliationPhase),
+			Message:             vz.Status.Message,
+		})
+	}
+
+	for _, f := range b.FilesOfKind(KindPods) {
+		var pods corev1.PodList
+		if err := unmarshalFile(b, f, &pods); err != nil {
+			return nil, err
+		}
+		for i := range pods.Items {
+			s.Pods = append(s.Pods, summarizePod(&pods.Items[i]))
+		}
+	}
+
+	for _, f := range b.FilesOfKind(KindNodes) {
+		var nodes []*nodeInfo
+		if err := unmarshalFile(b, f, &nodes); err != nil {
+			return nil, err
+		}
+		for _, n := range nodes {
+			s.Nodes = append(s.Nodes, summarizeNode(n))
+		}
+	}
+
+	for _, f := range b.FilesOfKind(KindEvents) {
+		var events corev1.EventList
+		if err := unmarshalFile(b, f, &events); err != nil {
+			return nil, err
+		}
+		for _, e := range events.Items {
+			if e.Type != corev1.EventTypeWarning {
+				continue
+			}
+			s.WarningEvents = append(s.WarningEvents, &EventSummary{
+				Namespace: e.Namespace,
+				Object:    fmt.Sprintf("%s/%s", e.InvolvedObject.Kind, e.InvolvedObject.Name),
+				Reason:    e.Reason,
+				Message:   e.Message,
+				Count:     e.Count,
+			})
+		}
+	}
+
+	for _, f := range b.FilesOfKind(KindLogs) {
+		s.Logs = append(s.Logs, summarizeLog(f.Path, b.Files[f.Path]))
+	}
+	// Show the noisiest logs first.
+	sort.SliceStable(s.Logs, func(i, j int) bool { return s.Logs[i].ErrorLines > s.Logs[j].ErrorLines })
+
+	return s, nil
+}
+
+func summarizePod(pod *corev1.Pod) *PodSummary {
+	ps := &PodSummary{
+		Namespace: pod.Namespace,
+		Name:      pod.Name,
+		Phase:     string(pod.Status.Phase),
+		Node:      pod.Spec.NodeName,
+	}
+	ready := 0
+	for _, cs := range pod.Status.ContainerStatuses {
+		if cs.Ready {
+			ready++
+		}
+		ps.Restarts += cs.RestartCount
+		switch {
+		case cs.State.Waiting != nil && cs.State.Waiting.Reason != "":
+			ps.Reason = cs.State.Waiting.Reason
+		case cs.LastTerminationState.Terminated != nil && ps.Reason == "":
+			ps.Reason = cs.LastTerminationState.Terminated.Reason
+		}
+	}
+	ps.Ready = fmt.Sprintf("%d/%d", ready, len(pod.Status.ContainerStatuses))
+	return ps
+}
+
+func summarizeNode(n *nodeInfo) *NodeSummary {
+	ns := &NodeSummary{
+		Name:           n.Name,
+		KernelVersion:  n.NodeInfo.KernelVersion,
+		KubeletVersion: n.NodeInfo.KubeletVersion,
+		OSImage:        n.NodeInfo.OSImage,
+	}
+	if mem, ok := n.Allocatable[corev1.ResourceMemory]; ok {
+		ns.Memory = mem.String()
+	}
+	for _, c := range n.Conditions {
+		if c.Type == corev1.NodeReady {
+			ns.Ready = c.Status == corev1.ConditionTrue
+		}
+	}
+	return ns
+}
+
+func summarizeLog(path string, data []byte) *LogSummary {
+	ls := &LogSummary{Path: path}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		ls.Lines++
+		if line := scanner.Text(); errorLineRegex.MatchString(line) {
+			ls.ErrorLines++
+			ls.LastError = line
+		}
+	}
+	return ls
+}
@@ -20,7 +20,11 @@ package update
 
 import (
 	"context"
+	"crypto/sha256"
+	"crypto/subtle"
 	"encoding/hex"
+	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"os"
@@ -178,22 +182,31 @@ func (c *CLIUpdater) UpdateSelf(version string) error {
 		_ = os.Remove(tempFile.Name())
 	}()
 
+	checksum, err := hex.DecodeString(strings.TrimSpace(resp.SHA256))
+	if err != nil {
+		return fmt.Errorf("invalid sha256 digest for CLI version %s: %w", version, err)
+	}
+	if len(checksum) != sha256.Size {
+		return fmt.Errorf("missing sha256 digest for CLI version %s, refusing to update", version)
+	}
+
 	downloader := newDownloadWithProgress(resp.Url, tempFile.Name())
 	err = downloader.Download()
 	if err != nil {
 		return err
 	}
 
-	utils.Info("Download complete, applying update ...")
-	checksum, err := hex.DecodeString(resp.SHA256)
-	if err != nil {
+	utils.Info("Download complete, verifying checksum ...")
+	if err := verifySHA256(tempFile.Name(), checksum); err != nil {
 		return err
 	}
 
+	utils.Info("Checksum verified, applying update ...")
 	f, err := os.Open(tempFile.Name())
 	if err != nil {
 		return err
 	}
+	defer f.Close()
 
 	err = update.Apply(f, update.Options{
 		Checksum: checksum,
@@ -202,6 +215,26 @@ func (c *CLIUpdater) UpdateSelf(version string) error {
 	return err
 }
 
+var errChecksumMismatch = errors.New("downloaded CLI does not match the expected sha256 digest")
+
+// verifySHA256 checks that the file at the given path has the expected sha256 digest.
+func verifySHA256(path string, expected []byte) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return err
+	}
+	if subtle.ConstantTimeCompare(h.Sum(nil), expected) != 1 {
+		return errChecksumMismatch
+	}
+	return nil
+}
+
 type downloadWithProgress struct {
 	url      string
 	savePath string
@@ -0,0 +1,165 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package utils
+
+import (
+	"errors"
+	"sync"
+)
+
+// CheckStatus is the outcome of a check in a check report.
+type CheckStatus string
+
+const (
+	// CheckPassed means the check passed.
+	CheckPassed CheckStatus = "pass"
+	// CheckFailed means a required check failed.
+	CheckFailed CheckStatus = "fail"
+	// CheckWarning means a recommended check failed.
+	CheckWarning CheckStatus = "warn"
+	// CheckSkipped means the check couldn't be run, or doesn't apply to the cluster.
+	CheckSkipped CheckStatus = "skip"
+)
+
+// ErrCheckSkipped is returned (possibly wrapped) by checks that don't apply to the cluster.
+var ErrCheckSkipped = errors.New("check skipped")
+
+// CheckError is a failed check with a hint on how to fix the problem.
+type CheckError struct {
+	Err         error
+	Remediation string
+}
+
+func (e *CheckError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *CheckError) Unwrap() error {
+	return e.Err
+}
+
+// WithRemediation attaches a remediation hint to a check failure.
+func WithRemediation(err error, remediation string) error {
+	if err == nil {
+		return nil
+	}
+	return &CheckError{Err: err, Remediation: remediation}
+}
+
+// DetailedChecker is a Checker that also describes what it found when it passes.
+type DetailedChecker interface {
+	Checker
+	// CheckDetails returns a description of the result for pass, or error.
+	CheckDetails() (string, error)
+}
+
+type detailedCheck struct {
+	name  string
+	check func() (string, error)
+}
+
+func (c *detailedCheck) Name() string {
+	return c.name
+}
+
+func (c *detailedCheck) Check() error {
+	_, err := c.check()
+	return err
+}
+
+func (c *detailedCheck) CheckDetails() (string, error) {
+	return c.check()
+}
+
+// NamedDetailedCheck is used to easily create a DetailedChecker with a name.
+func NamedDetailedCheck(name string, check func() (string, error)) DetailedChecker {
+	return &detailedCheck{name: name, check: check}
+}
+
+// SuiteCheck is a check in a check report.
+type SuiteCheck struct {
+	Checker
+	// Required is whether the check must pass to deploy Pixie. Failures of other checks are
+	// reported as warnings.
+	Required bool
+}
+
+// CheckResult is the result of a single check in a check report.
+type CheckResult struct {
+	Name        string      `json:"name"`
+	Status      CheckStatus `json:"status"`
+	Required    bool        `json:"required"`
+	Message     string      `json:"message,omitempty"`
+	Remediation string      `json:"remediation,omitempty"`
+}
+
+func runSuiteCheck(c SuiteCheck) *CheckResult {
+	res := &CheckResult{Name: c.Name(), Required: c.Required, Status: CheckPassed}
+
+	var err error
+	if dc, ok := c.Checker.(DetailedChecker); ok {
+		res.Message, err = dc.CheckDetails()
+	} else {
+		err = c.Check()
+	}
+	if err == nil {
+		return res
+	}
+
+	res.Message = err.Error()
+	var checkErr *CheckError
+	if errors.As(err, &checkErr) {
+		res.Remediation = checkErr.Remediation
+	}
+	switch {
+	case errors.Is(err, ErrCheckSkipped):
+		res.Status = CheckSkipped
+	case c.Required:
+		res.Status = CheckFailed
+	default:
+		res.Status = CheckWarning
+	}
+	return res
+}
+
+// RunCheckSuite runs all of the checks in parallel, and returns their results in the same order
+// as the checks.
+func RunCheckSuite(checks []SuiteCheck) []*CheckResult {
+	results := make([]*CheckResult, len(checks))
+	var wg sync.WaitGroup
+	for i := range checks {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = runSuiteCheck(checks[i])
+		}(i)
+	}
+	wg.Wait()
+	return results
+}
+
+// CheckSuitePassed returns whether none of the required checks failed.
+func CheckSuitePassed(results []*CheckResult) bool {
+	for _, r := range results {
+		if r.Status == CheckFailed {
+			return false
+		}
+	}
+	return true
+}
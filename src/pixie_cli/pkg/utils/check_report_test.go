@@ -0,0 +1,112 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package utils_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"px.dev/pixie/src/pixie_cli/pkg/utils"
+)
+
+func TestRunCheckSuite(t *testing.T) {
+	checks := []utils.SuiteCheck{
+		{Checker: utils.NamedCheck("pass", func() error { return nil }), Required: true},
+		{Checker: utils.NamedDetailedCheck("details", func() (string, error) { return "3 nodes checked", nil })},
+		{Checker: utils.NamedCheck("required", func() error {
+			return utils.WithRemediation(errors.New("bad kernel"), "upgrade the kernel")
+		}), Required: true},
+		{Checker: utils.NamedCheck("recommended", func() error { return errors.New("no btf") })},
+		{Checker: utils.NamedCheck("skipped", func() error {
+			return fmt.Errorf("%w: not an EKS cluster", utils.ErrCheckSkipped)
+		}), Required: true},
+	}
+
+	results := utils.RunCheckSuite(checks)
+	assert.Equal(t, []*utils.CheckResult{
+		{Name: "pass", Status: utils.CheckPassed, Required: true},
+		{Name: "details", Status: utils.CheckPassed, Message: "3 nodes checked"},
+		{Name: "required", Status: utils.CheckFailed, Required: true, Message: "bad kernel", Remediation: "upgrade the kernel"},
+		{Name: "recommended", Status: utils.CheckWarning, Message: "no btf"},
+		{Name: "skipped", Status: utils.CheckSkipped, Required: true, Message: "check skipped: not an EKS cluster"},
+	}, results)
+	assert.False(t, utils.CheckSuitePassed(results))
+	assert.True(t, utils.CheckSuitePassed(results[1:2]))
+}
+
+func TestParseCustomChecks(t *testing.T) {
+	checks, err := utils.ParseCustomChecks([]byte(`
+checks:
+- name: echo
+  command: echo hello world
+  expectOutput: "^hello"
+  required: true
+- name: mismatch
+  command: echo goodbye
+  expectOutput: "^hello"
+  remediation: say hello
+- name: exit code
+  command: exit 3
+  remediation: fix it
+- name: timeout
+  command: sleep 5
+  timeout: 10ms
+`))
+	require.NoError(t, err)
+	require.Len(t, checks, 4)
+
+	results := utils.RunCheckSuite(checks)
+	assert.Equal(t, utils.CheckPassed, results[0].Status)
+	assert.True(t, results[0].Required)
+
+	assert.Equal(t, utils.CheckWarning, results[1].Status)
+	assert.Equal(t, `output "goodbye" doesn't match "^hello"`, results[1].Message)
+	assert.Equal(t, "say hello", results[1].Remediation)
+
+	assert.Equal(t, utils.CheckWarning, results[2].Status)
+	assert.Contains(t, results[2].Message, "exit status 3")
+	assert.Equal(t, "fix it", results[2].Remediation)
+
+	assert.Equal(t, "command timed out after 10ms", results[3].Message)
+}
+
+func TestParseCustomChecks_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		err  string
+	}{
+		{"missing name", "checks:\n- command: 'true'\n", "name is required"},
+		{"missing command", "checks:\n- name: a\n", "command is required"},
+		{"bad regex", "checks:\n- name: a\n  command: 'true'\n  expectOutput: '('\n", "invalid expectOutput"},
+		{"bad timeout", "checks:\n- name: a\n  command: 'true'\n  timeout: soon\n", "invalid timeout"},
+		{"unknown field", "checks:\n- name: a\n  command: 'true'\n  shell: bash\n", "field shell not found"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := utils.ParseCustomChecks([]byte(test.yaml))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), test.err)
+		})
+	}
+}
@@ -93,12 +93,22 @@ func (j jobAdapter) Run() error {
 // RunClusterChecks will run a list of checks and print out their results.
 // The first error is returned, but we continue to run all checks.
 func RunClusterChecks(checks []Checker) error {
+	jr := NewSerialTaskRunner(checkTasks(checks))
+	return jr.RunAndMonitor()
+}
+
+// RunClusterChecksWithEvents runs a list of checks and reports their results as progress events.
+func RunClusterChecksWithEvents(checks []Checker, w *EventWriter) error {
+	jr := NewEventTaskRunner(checkTasks(checks), "check", w)
+	return jr.RunAndMonitor()
+}
+
+func checkTasks(checks []Checker) []Task {
 	jobs := make([]Task, len(checks))
 	for i, check := range checks {
 		jobs[i] = checkWrapper(check)
 	}
-	jr := NewSerialTaskRunner(jobs)
-	return jr.RunAndMonitor()
+	return jobs
 }
 
 // RunDefaultClusterChecks runs the default configured checks.
@@ -0,0 +1,129 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+const defaultCustomCheckTimeout = 30 * time.Second
+
+// CustomCheckSpec is a team-specific check that is defined in a YAML file, for example:
+//
+//	checks:
+//	- name: Nodes are labeled for Pixie
+//	  command: kubectl get nodes -l pixie=allowed -o name
+//	  expectOutput: "node/"
+//	  remediation: Label the nodes that should run Pixie with pixie=allowed.
+//	  required: true
+type CustomCheckSpec struct {
+	// Name is shown in the check report.
+	Name string `yaml:"name"`
+	// Command is run with /bin/sh. The check fails if it exits with a non-zero status.
+	Command string `yaml:"command"`
+	// ExpectOutput is an optional regular expression that the command's output must match.
+	ExpectOutput string `yaml:"expectOutput"`
+	// Remediation is shown when the check fails.
+	Remediation string `yaml:"remediation"`
+	// Required is whether the check must pass to deploy Pixie.
+	Required bool `yaml:"required"`
+	// Timeout is how long the command may run, defaulting to 30s.
+	Timeout string `yaml:"timeout"`
+}
+
+// ParseCustomChecks parses custom check specs and creates the checks they define.
+func ParseCustomChecks(b []byte) ([]SuiteCheck, error) {
+	var spec struct {
+		Checks []*CustomCheckSpec `yaml:"checks"`
+	}
+	if err := yaml.UnmarshalStrict(b, &spec); err != nil {
+		return nil, err
+	}
+
+	checks := make([]SuiteCheck, len(spec.Checks))
+	for i, s := range spec.Checks {
+		c, err := newCustomCheck(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid check %d: %w", i, err)
+		}
+		checks[i] = SuiteCheck{Checker: c, Required: s.Required}
+	}
+	return checks, nil
+}
+
+// LoadCustomChecks reads custom check specs from a YAML file.
+func LoadCustomChecks(path string) ([]SuiteCheck, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseCustomChecks(b)
+}
+
+func newCustomCheck(s *CustomCheckSpec) (Checker, error) {
+	if s.Name == "" {
+		return nil, errors.New("name is required")
+	}
+	if s.Command == "" {
+		return nil, fmt.Errorf("%s: command is required", s.Name)
+	}
+	var expect *regexp.Regexp
+	if s.ExpectOutput != "" {
+		var err error
+		if expect, err = regexp.Compile(s.ExpectOutput); err != nil {
+			return nil, fmt.Errorf("%s: invalid expectOutput: %w", s.Name, err)
+		}
+	}
+	timeout := defaultCustomCheckTimeout
+	if s.Timeout != "" {
+		var err error
+		if timeout, err = time.ParseDuration(s.Timeout); err != nil {
+			return nil, fmt.Errorf("%s: invalid timeout: %w", s.Name, err)
+		}
+	}
+
+	return NamedCheck(s.Name, func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+
+		cmd := exec.CommandContext(ctx, "/bin/sh", "-c", s.Command)
+		// Don't wait on processes started by the shell that are still holding the output open.
+		cmd.WaitDelay = time.Second
+		out, err := cmd.CombinedOutput()
+		output := strings.TrimSpace(string(out))
+		switch {
+		case ctx.Err() != nil:
+			err = fmt.Errorf("command timed out after %s", timeout)
+		case err != nil:
+			err = fmt.Errorf("command failed: %w: %s", err, output)
+		case expect != nil && !expect.MatchString(output):
+			err = fmt.Errorf("output %q doesn't match %q", output, s.ExpectOutput)
+		}
+		return WithRemediation(err, s.Remediation)
+	}), nil
+}
@@ -19,6 +19,7 @@
 package utils
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 )
@@ -27,6 +28,9 @@ const (
 	pixieDotPath    = ".pixie"
 	pixieConfigFile = "config.json"
 	pixieAuthFile   = "auth.json"
+	// pixieContextAuthFile is the auth file for a named context.
+	pixieContextAuthFile = "auth-%s.json"
+	pixieLiveHistoryDir  = "live_history"
 )
 
 // ensureDotFolderPath returns and creates the dot folder for cli config/auth.
@@ -68,3 +72,33 @@ func EnsureDefaultAuthFilePath() (string, error) {
 	pixieAuthFilePath := filepath.Join(pixieDirPath, pixieAuthFile)
 	return pixieAuthFilePath, nil
 }
+
+// EnsureContextAuthFilePath returns the file path for the auth file of the named context.
+func EnsureContextAuthFilePath(contextName string) (string, error) {
+	pixieDirPath, err := ensureDotFolderPath()
+	if err != nil {
+		return "", err
+	}
+
+	pixieAuthFilePath := filepath.Join(pixieDirPath, fmt.Sprintf(pixieContextAuthFile, contextName))
+	return pixieAuthFilePath, nil
+}
+
+// EnsureLiveHistoryFilePath returns the file path for the `px live` history with the given key, which is
+// usually a cluster ID.
+func EnsureLiveHistoryFilePath(key string) (string, error) {
+	pixieDirPath, err := ensureDotFolderPath()
+	if err != nil {
+		return "", err
+	}
+
+	historyDirPath := filepath.Join(pixieDirPath, pixieLiveHistoryDir)
+	if _, err := os.Stat(historyDirPath); os.IsNotExist(err) {
+		err = os.Mkdir(historyDirPath, 0744)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return filepath.Join(historyDirPath, key+".json"), nil
+}
@@ -0,0 +1,80 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package utils
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// EventStatus is the status of the step that a progress event reports on.
+type EventStatus string
+
+const (
+	// EventStarted is reported when a step starts.
+	EventStarted EventStatus = "started"
+	// EventSucceeded is reported when a step completes successfully.
+	EventSucceeded EventStatus = "succeeded"
+	// EventFailed is reported when a step fails.
+	EventFailed EventStatus = "failed"
+	// EventInfo is reported for information that is not tied to the progress of a step.
+	EventInfo EventStatus = "info"
+)
+
+// Event is a machine-readable progress event.
+type Event struct {
+	Time time.Time `json:"time"`
+	// Phase is the group of steps that the event belongs to, for example "check" or "deploy".
+	Phase  string      `json:"phase"`
+	Step   string      `json:"step,omitempty"`
+	Status EventStatus `json:"status"`
+	// DurationMs is how long the step took. Only set when the step completes.
+	DurationMs int64                  `json:"durationMs,omitempty"`
+	Message    string                 `json:"message,omitempty"`
+	Error      string                 `json:"error,omitempty"`
+	Data       map[string]interface{} `json:"data,omitempty"`
+}
+
+// EventWriter writes progress events as JSON lines.
+type EventWriter struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewEventWriter creates an EventWriter that writes to w.
+func NewEventWriter(w io.Writer) *EventWriter {
+	return &EventWriter{enc: json.NewEncoder(w)}
+}
+
+// Write writes the event. The time is filled in if it is unset.
+func (e *EventWriter) Write(ev *Event) error {
+	if ev.Time.IsZero() {
+		ev.Time = time.Now()
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.enc.Encode(ev)
+}
+
+// Info writes an informational event for the phase.
+func (e *EventWriter) Info(phase, message string, data map[string]interface{}) error {
+	return e.Write(&Event{Phase: phase, Status: EventInfo, Message: message, Data: data})
+}
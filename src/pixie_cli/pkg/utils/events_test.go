@@ -0,0 +1,102 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package utils_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"px.dev/pixie/src/pixie_cli/pkg/utils"
+)
+
+type testTask struct {
+	name string
+	err  error
+	ran  bool
+}
+
+func (t *testTask) Name() string {
+	return t.name
+}
+
+func (t *testTask) Run() error {
+	t.ran = true
+	return t.err
+}
+
+func readEvents(t *testing.T, b *bytes.Buffer) []*utils.Event {
+	var events []*utils.Event
+	s := bufio.NewScanner(b)
+	for s.Scan() {
+		ev := &utils.Event{}
+		require.NoError(t, json.Unmarshal(s.Bytes(), ev))
+		events = append(events, ev)
+	}
+	return events
+}
+
+func TestEventTaskRunner(t *testing.T) {
+	var b bytes.Buffer
+	w := utils.NewEventWriter(&b)
+
+	tasks := []*testTask{
+		{name: "first"},
+		{name: "second", err: errors.New("failed")},
+		{name: "third"},
+	}
+	jr := utils.NewEventTaskRunner([]utils.Task{tasks[0], tasks[1], tasks[2]}, "deploy", w)
+	err := jr.RunAndMonitor()
+	require.Error(t, err)
+	assert.False(t, tasks[2].ran)
+
+	events := readEvents(t, &b)
+	require.Len(t, events, 4)
+	for _, ev := range events {
+		assert.Equal(t, "deploy", ev.Phase)
+		assert.False(t, ev.Time.IsZero())
+	}
+	assert.Equal(t, "first", events[0].Step)
+	assert.Equal(t, utils.EventStarted, events[0].Status)
+	assert.Equal(t, "first", events[1].Step)
+	assert.Equal(t, utils.EventSucceeded, events[1].Status)
+	assert.Equal(t, "second", events[2].Step)
+	assert.Equal(t, utils.EventStarted, events[2].Status)
+	assert.Equal(t, "second", events[3].Step)
+	assert.Equal(t, utils.EventFailed, events[3].Status)
+	assert.Equal(t, "failed", events[3].Error)
+}
+
+func TestEventWriter_Info(t *testing.T) {
+	var b bytes.Buffer
+	w := utils.NewEventWriter(&b)
+	require.NoError(t, w.Info("deploy", "Found 3 nodes", map[string]interface{}{"nodes": 3}))
+
+	events := readEvents(t, &b)
+	require.Len(t, events, 1)
+	assert.Equal(t, utils.EventInfo, events[0].Status)
+	assert.Equal(t, "Found 3 nodes", events[0].Message)
+	assert.Equal(t, float64(3), events[0].Data["nodes"])
+	assert.Empty(t, events[0].Step)
+}
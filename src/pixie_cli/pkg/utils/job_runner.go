@@ -19,6 +19,8 @@
 package utils
 
 import (
+	"time"
+
 	"golang.org/x/sync/errgroup"
 
 	"px.dev/pixie/src/pixie_cli/pkg/components"
@@ -30,6 +32,11 @@ type Task interface {
 	Run() error
 }
 
+// TaskRunner runs a list of tasks and reports their progress.
+type TaskRunner interface {
+	RunAndMonitor() error
+}
+
 // SerialTaskRunner runs tasks in serial and displays them in a table.
 type SerialTaskRunner struct {
 	tasks []Task
@@ -86,3 +93,44 @@ func (s *ParallelTaskRunner) RunAndMonitor() error {
 	st.Wait()
 	return err
 }
+
+// EventTaskRunner runs tasks in serial and reports their progress as events, rather than
+// displaying them in a table.
+type EventTaskRunner struct {
+	tasks []Task
+	phase string
+	w     *EventWriter
+}
+
+// NewEventTaskRunner creates a new EventTaskRunner that writes events for the phase to w.
+func NewEventTaskRunner(tasks []Task, phase string, w *EventWriter) *EventTaskRunner {
+	return &EventTaskRunner{
+		tasks: tasks,
+		phase: phase,
+		w:     w,
+	}
+}
+
+// RunAndMonitor runs tasks and writes an event when each task starts and completes.
+func (s *EventTaskRunner) RunAndMonitor() error {
+	for _, t := range s.tasks {
+		_ = s.w.Write(&Event{Phase: s.phase, Step: t.Name(), Status: EventStarted})
+		start := time.Now()
+		err := t.Run()
+		ev := &Event{
+			Phase:      s.phase,
+			Step:       t.Name(),
+			Status:     EventSucceeded,
+			DurationMs: time.Since(start).Milliseconds(),
+		}
+		if err != nil {
+			ev.Status = EventFailed
+			ev.Error = err.Error()
+		}
+		_ = s.w.Write(ev)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
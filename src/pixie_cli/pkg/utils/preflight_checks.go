@@ -0,0 +1,454 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package utils
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"px.dev/pixie/src/operator/storage"
+	"px.dev/pixie/src/utils/shared/k8s"
+)
+
+const (
+	// DefaultPEMMemoryLimit is the memory limit of the PEMs when none is specified at deploy time.
+	DefaultPEMMemoryLimit = "2Gi"
+	// DefaultImageRegistry is the registry that Pixie images are pulled from by default.
+	DefaultImageRegistry = "gcr.io"
+	// DefaultProbeImage is the image used by the probe pod.
+	DefaultProbeImage = "busybox:1.36"
+
+	probeTimeout       = 2 * time.Minute
+	registryTimeout    = 10 * time.Second
+	podSecurityEnforce = "pod-security.kubernetes.io/enforce"
+)
+
+// PreflightOptions configures the checks that are run by PreflightChecks.
+type PreflightOptions struct {
+	// Namespace is the namespace that Pixie will be deployed to.
+	Namespace string
+	// Registry is the image registry that Pixie images will be pulled from.
+	Registry string
+	// PEMMemoryLimit is the memory limit that the PEMs will be deployed with.
+	PEMMemoryLimit string
+	// ProbeImage is the image used by the probe pod.
+	ProbeImage string
+	// SkipProbe disables the checks that need to run a pod on the cluster.
+	SkipProbe bool
+}
+
+// PreflightChecks returns the full suite of checks run by `px check`. This is a superset of the checks
+// that are run on deploy.
+func PreflightChecks(opts *PreflightOptions) []SuiteCheck {
+	probe := &clusterProbe{namespace: opts.Namespace, image: opts.ProbeImage, skip: opts.SkipProbe}
+	return []SuiteCheck{
+		{Checker: nodeKernelVersionCheck(), Required: true},
+		{Checker: clusterTypeIsSupported, Required: true},
+		{Checker: k8sVersionCheck, Required: true},
+		{Checker: hasKubectlCheck, Required: true},
+		{Checker: userCanCreateNamespace, Required: true},
+		{Checker: podSecurityAdmissionCheck(opts.Namespace), Required: true},
+		{Checker: probe.privilegedPodCheck(), Required: true},
+		{Checker: allowListClusterCheck},
+		{Checker: podSecurityPolicyCheck()},
+		{Checker: probe.bpfCheck()},
+		{Checker: probe.btfCheck()},
+		{Checker: probe.cgroupCheck()},
+		{Checker: defaultStorageClassCheck()},
+		{Checker: csiDriverCheck()},
+		{Checker: registryReachableCheck(opts.Registry)},
+		{Checker: nodeMemoryCheck(opts.PEMMemoryLimit)},
+	}
+}
+
+func clientset() kubernetes.Interface {
+	return k8s.GetClientset(k8s.GetConfig())
+}
+
+// nodeKernelVersionCheck is like kernelVersionCheck, but reports all of the nodes with an unsupported kernel.
+func nodeKernelVersionCheck() DetailedChecker {
+	return NamedDetailedCheck(fmt.Sprintf("Kernel version > %s on all nodes", kernelMinVersion), func() (string, error) {
+		nodes, err := clientset().CoreV1().Nodes().List(context.Background(), metav1.ListOptions{})
+		if err != nil {
+			return "", err
+		}
+
+		var unsupported []string
+		for _, node := range nodes.Items {
+			version := node.Status.NodeInfo.KernelVersion
+			compatible, err := VersionCompatible(version, kernelMinVersion)
+			if err != nil {
+				return "", fmt.Errorf("failed to parse kernel version (%s) of node %s: %w", version, node.Name, err)
+			}
+			if !compatible {
+				unsupported = append(unsupported, fmt.Sprintf("%s (%s)", node.Name, version))
+			}
+		}
+		if len(unsupported) > 0 {
+			return "", WithRemediation(
+				fmt.Errorf("%d of %d nodes have an unsupported kernel: %s", len(unsupported), len(nodes.Items), strings.Join(unsupported, ", ")),
+				fmt.Sprintf("Upgrade the node OS to a kernel >= %s, or use a nodeSelector patch to keep the PEMs off of these nodes.", kernelMinVersion))
+		}
+		return fmt.Sprintf("%d nodes checked", len(nodes.Items)), nil
+	})
+}
+
+// podSecurityAdmissionCheck checks that Pod Security Admission allows privileged pods in the namespace.
+func podSecurityAdmissionCheck(namespace string) DetailedChecker {
+	return NamedDetailedCheck("Pod Security Admission allows privileged pods", func() (string, error) {
+		ns, err := clientset().CoreV1().Namespaces().Get(context.Background(), namespace, metav1.GetOptions{})
+		if k8serrors.IsNotFound(err) {
+			return fmt.Sprintf("namespace %s will be created without a pod security level", namespace), nil
+		}
+		if err != nil {
+			return "", err
+		}
+		level, ok := ns.Labels[podSecurityEnforce]
+		if !ok || level == "privileged" {
+			return fmt.Sprintf("namespace %s doesn't restrict privileged pods", namespace), nil
+		}
+		return "", WithRemediation(
+			fmt.Errorf("namespace %s enforces the %q pod security level", namespace, level),
+			fmt.Sprintf("The PEMs need to run privileged. Run: kubectl label namespace %s %s=privileged --overwrite", namespace, podSecurityEnforce))
+	})
+}
+
+// podSecurityPolicyCheck warns when the PodSecurityPolicy admission controller may be active.
+func podSecurityPolicyCheck() DetailedChecker {
+	return NamedDetailedCheck("No PodSecurityPolicy restrictions", func() (string, error) {
+		resources, err := k8s.GetDiscoveryClient(k8s.GetConfig()).ServerResourcesForGroupVersion("policy/v1beta1")
+		if k8serrors.IsNotFound(err) {
+			return "PodSecurityPolicy is not served by this cluster", nil
+		}
+		if err != nil {
+			return "", err
+		}
+		for _, r := range resources.APIResources {
+			if r.Name == "podsecuritypolicies" {
+				return "", WithRemediation(
+					errors.New("the cluster serves PodSecurityPolicies, which may block the privileged PEM pods"),
+					"Make sure a PodSecurityPolicy allows privileged pods, hostPID and hostPath volumes for the service accounts in the Pixie namespace.")
+			}
+		}
+		return "PodSecurityPolicy is not served by this cluster", nil
+	})
+}
+
+// clusterProbe runs a short-lived privileged pod on the cluster to inspect a node. The pod is only
+// run once, and its results are shared by all of the checks that need them.
+type clusterProbe struct {
+	namespace string
+	image     string
+	skip      bool
+
+	once    sync.Once
+	node    string
+	results map[string]string
+	err     error
+}
+
+// probeScript prints key=value pairs that describe the node's BPF and cgroup support. The node's /sys
+// is mounted at /host/sys. Filesystems are reported by their magic number in hex, since the probe image
+// may use busybox, whose stat doesn't know the names of the bpf and cgroup2 filesystems.
+const probeScript = `
+if [ -e /host/sys/kernel/btf/vmlinux ]; then echo btf=true; else echo btf=false; fi
+echo bpffs=$(stat -f -c %t /host/sys/fs/bpf 2>/dev/null)
+echo cgroupfs=$(stat -f -c %t /host/sys/fs/cgroup 2>/dev/null)
+`
+
+// Filesystem magic numbers, as printed by stat -f -c %t.
+const (
+	bpfFSMagic   = "cafe4a11"
+	cgroup2Magic = "63677270"
+	tmpfsMagic   = "1021994"
+)
+
+func (p *clusterProbe) run() (string, map[string]string, error) {
+	p.once.Do(func() {
+		if p.skip {
+			p.err = fmt.Errorf("%w: the probe pod is disabled", ErrCheckSkipped)
+			return
+		}
+		p.node, p.results, p.err = p.runPod()
+	})
+	return p.node, p.results, p.err
+}
+
+func (p *clusterProbe) probeNamespace(ctx context.Context, cs kubernetes.Interface) string {
+	// Prefer the Pixie namespace so that the probe is subject to the same admission policies as the PEMs.
+	if _, err := cs.CoreV1().Namespaces().Get(ctx, p.namespace, metav1.GetOptions{}); err == nil {
+		return p.namespace
+	}
+	return "default"
+}
+
+func (p *clusterProbe) runPod() (string, map[string]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), probeTimeout)
+	defer cancel()
+
+	cs := clientset()
+	namespace := p.probeNamespace(ctx, cs)
+	privileged := true
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: "px-check-probe-",
+			Labels:       map[string]string{"app": "px-check-probe"},
+		},
+		Spec: corev1.PodSpec{
+			RestartPolicy: corev1.RestartPolicyNever,
+			Tolerations:   []corev1.Toleration{{Operator: corev1.TolerationOpExists}},
+			Containers: []corev1.Container{{
+				Name:            "probe",
+				Image:           p.image,
+				Command:         []string{"/bin/sh", "-c", probeScript},
+				SecurityContext: &corev1.SecurityContext{Privileged: &privileged},
+				VolumeMounts:    []corev1.VolumeMount{{Name: "sys", MountPath: "/host/sys", ReadOnly: true}},
+			}},
+			Volumes: []corev1.Volume{{
+				Name:         "sys",
+				VolumeSource: corev1.VolumeSource{HostPath: &corev1.HostPathVolumeSource{Path: "/sys"}},
+			}},
+		},
+	}
+
+	pod, err := cs.CoreV1().Pods(namespace).Create(ctx, pod, metav1.CreateOptions{})
+	if err != nil {
+		if k8serrors.IsForbidden(err) {
+			return "", nil, WithRemediation(fmt.Errorf("privileged probe pod was rejected: %w", err),
+				fmt.Sprintf("Allow privileged pods in the %s namespace. Check Pod Security Admission labels, PodSecurityPolicies and admission webhooks such as OPA Gatekeeper or Kyverno.", namespace))
+		}
+		return "", nil, err
+	}
+	defer func() {
+		gracePeriod := int64(0)
+		_ = cs.CoreV1().Pods(namespace).Delete(context.Background(), pod.Name, metav1.DeleteOptions{GracePeriodSeconds: &gracePeriod})
+	}()
+
+	for {
+		pod, err = cs.CoreV1().Pods(namespace).Get(ctx, pod.Name, metav1.GetOptions{})
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to get probe pod: %w", err)
+		}
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return "", nil, WithRemediation(fmt.Errorf("probe pod %s/%s did not complete in %s", namespace, pod.Name, probeTimeout),
+				fmt.Sprintf("Make sure the cluster can pull %s, or pass a reachable image with --probe_image.", p.image))
+		case <-time.After(2 * time.Second):
+		}
+	}
+
+	logs, err := cs.CoreV1().Pods(namespace).GetLogs(pod.Name, &corev1.PodLogOptions{}).DoRaw(ctx)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to read probe pod logs: %w", err)
+	}
+	if pod.Status.Phase == corev1.PodFailed {
+		return "", nil, fmt.Errorf("probe pod failed: %s", strings.TrimSpace(string(logs)))
+	}
+	return pod.Spec.NodeName, parseProbeOutput(string(logs)), nil
+}
+
+func parseProbeOutput(out string) map[string]string {
+	results := make(map[string]string)
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	for scanner.Scan() {
+		kv := strings.SplitN(strings.TrimSpace(scanner.Text()), "=", 2)
+		if len(kv) == 2 {
+			results[kv[0]] = kv[1]
+		}
+	}
+	return results
+}
+
+func (p *clusterProbe) privilegedPodCheck() DetailedChecker {
+	return NamedDetailedCheck("Privileged pods can run", func() (string, error) {
+		node, _, err := p.run()
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("probe pod ran on node %s", node), nil
+	})
+}
+
+func (p *clusterProbe) bpfCheck() DetailedChecker {
+	return NamedDetailedCheck("BPF filesystem is available", func() (string, error) {
+		node, results, err := p.run()
+		if err != nil {
+			return "", err
+		}
+		return checkBPFFS(node, results)
+	})
+}
+
+func (p *clusterProbe) btfCheck() DetailedChecker {
+	return NamedDetailedCheck("Kernel BTF is available", func() (string, error) {
+		node, results, err := p.run()
+		if err != nil {
+			return "", err
+		}
+		return checkBTF(node, results)
+	})
+}
+
+func (p *clusterProbe) cgroupCheck() DetailedChecker {
+	return NamedDetailedCheck("cgroup version is supported", func() (string, error) {
+		node, results, err := p.run()
+		if err != nil {
+			return "", err
+		}
+		return checkCgroupFS(node, results)
+	})
+}
+
+// checkBPFFS checks the probe results for a BPF filesystem mounted at /sys/fs/bpf.
+func checkBPFFS(node string, results map[string]string) (string, error) {
+	if fs := results["bpffs"]; fs != bpfFSMagic {
+		return "", WithRemediation(fmt.Errorf("/sys/fs/bpf on node %s is not a BPF filesystem (found filesystem type %q)", node, fs),
+			"Make sure the kernel is built with CONFIG_BPF_SYSCALL and mount the BPF filesystem: mount -t bpf bpf /sys/fs/bpf")
+	}
+	return fmt.Sprintf("/sys/fs/bpf is mounted on node %s", node), nil
+}
+
+// checkBTF checks the probe results for kernel BTF.
+func checkBTF(node string, results map[string]string) (string, error) {
+	if results["btf"] != "true" {
+		return "", WithRemediation(fmt.Errorf("node %s has no /sys/kernel/btf/vmlinux", node),
+			"Pixie will fall back to kernel headers, which need to be installed on the node or downloaded by the PEM. Use a kernel built with CONFIG_DEBUG_INFO_BTF to avoid this.")
+	}
+	return fmt.Sprintf("node %s exposes BTF", node), nil
+}
+
+// checkCgroupFS checks the probe results for the cgroup filesystem mounted at /sys/fs/cgroup.
+func checkCgroupFS(node string, results map[string]string) (string, error) {
+	switch fs := results["cgroupfs"]; fs {
+	case cgroup2Magic:
+		return fmt.Sprintf("node %s uses cgroup v2", node), nil
+	case tmpfsMagic:
+		return fmt.Sprintf("node %s uses cgroup v1", node), nil
+	default:
+		return "", WithRemediation(fmt.Errorf("unrecognized cgroup filesystem type %q on node %s", fs, node),
+			"Pixie needs the cgroup filesystem to be mounted at /sys/fs/cgroup to find containers on the node.")
+	}
+}
+
+func defaultStorageClassCheck() DetailedChecker {
+	return NamedDetailedCheck("Cluster has a default storage class", func() (string, error) {
+		n, err := storage.NumDefaultStorageClasses(clientset())
+		if err != nil {
+			return "", err
+		}
+		if n != 1 {
+			return "", WithRemediation(fmt.Errorf("expected 1 default storage class, found %d", n),
+				"Without a single default storage class, Pixie falls back to the etcd operator for its metadata store. Mark one storage class as the default: kubectl patch storageclass <name> -p '{\"metadata\": {\"annotations\":{\"storageclass.kubernetes.io/is-default-class\":\"true\"}}}'")
+		}
+		return "", nil
+	})
+}
+
+func csiDriverCheck() DetailedChecker {
+	return NamedDetailedCheck("Default storage class has its CSI driver", func() (string, error) {
+		version, err := k8s.GetDiscoveryClient(k8s.GetConfig()).ServerVersion()
+		if err != nil {
+			return "", err
+		}
+		if !storage.NeedsCSIDriverCheck(version.GitVersion) {
+			return "", fmt.Errorf("%w: K8s %s still has in-tree volume plugins", ErrCheckSkipped, version.GitVersion)
+		}
+		ok, err := storage.DefaultStorageClassHasCSIDriver(clientset())
+		if err != nil {
+			return "", err
+		}
+		if !ok {
+			return "", WithRemediation(errors.New("the CSI driver for the default storage class is not installed"),
+				"Install the Amazon EBS CSI driver add-on. Otherwise Pixie falls back to the etcd operator for its metadata store.")
+		}
+		return "", nil
+	})
+}
+
+// registryReachableCheck checks that the registry's API is reachable from the machine running the CLI.
+// This doesn't guarantee that the nodes can reach it, but catches typos and firewalled registries.
+func registryReachableCheck(registry string) DetailedChecker {
+	if registry == "" {
+		registry = DefaultImageRegistry
+	}
+	host := strings.SplitN(registry, "/", 2)[0]
+	return NamedDetailedCheck(fmt.Sprintf("Image registry %s is reachable", host), func() (string, error) {
+		client := &http.Client{Timeout: registryTimeout}
+		resp, err := client.Get(fmt.Sprintf("https://%s/v2/", host))
+		if err != nil {
+			return "", WithRemediation(err,
+				"Make sure the nodes can reach the registry, or mirror the Pixie images to a reachable registry and deploy with --registry.")
+		}
+		defer resp.Body.Close()
+		// The registry API returns 401 to anonymous clients, which still shows the registry is up.
+		if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusUnauthorized {
+			return "", WithRemediation(fmt.Errorf("registry returned %s", resp.Status),
+				"Make sure the registry serves the Docker Registry v2 API.")
+		}
+		return "", nil
+	})
+}
+
+// nodeMemoryCheck checks that every node has enough allocatable memory to schedule a PEM.
+func nodeMemoryCheck(pemMemoryLimit string) DetailedChecker {
+	if pemMemoryLimit == "" {
+		pemMemoryLimit = DefaultPEMMemoryLimit
+	}
+	return NamedDetailedCheck(fmt.Sprintf("Nodes have %s of memory for the PEM", pemMemoryLimit), func() (string, error) {
+		limit, err := resource.ParseQuantity(pemMemoryLimit)
+		if err != nil {
+			return "", fmt.Errorf("invalid PEM memory limit %q: %w", pemMemoryLimit, err)
+		}
+		nodes, err := clientset().CoreV1().Nodes().List(context.Background(), metav1.ListOptions{})
+		if err != nil {
+			return "", err
+		}
+
+		var small []string
+		for _, node := range nodes.Items {
+			allocatable := node.Status.Allocatable[corev1.ResourceMemory]
+			if allocatable.Cmp(limit) < 0 {
+				small = append(small, fmt.Sprintf("%s (%s)", node.Name, allocatable.String()))
+			}
+		}
+		sort.Strings(small)
+		if len(small) > 0 {
+			return "", WithRemediation(
+				fmt.Errorf("%d of %d nodes have less allocatable memory than the PEM limit: %s", len(small), len(nodes.Items), strings.Join(small, ", ")),
+				"Use larger nodes, or deploy with a lower --pem_memory_limit. PEMs with less memory keep less data.")
+		}
+		return fmt.Sprintf("%d nodes checked", len(nodes.Items)), nil
+	})
+}
@@ -0,0 +1,123 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package utils
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProbeOutputChecks(t *testing.T) {
+	tests := []struct {
+		name       string
+		output     string
+		check      func(string, map[string]string) (string, error)
+		wantDetail string
+		wantErr    bool
+	}{
+		{
+			name:       "bpf filesystem",
+			output:     "btf=true\nbpffs=cafe4a11\ncgroupfs=63677270\n",
+			check:      checkBPFFS,
+			wantDetail: "/sys/fs/bpf is mounted on node node-1",
+		},
+		{
+			name:    "bpf filesystem not mounted",
+			output:  "btf=true\nbpffs=62656572\ncgroupfs=63677270\n",
+			check:   checkBPFFS,
+			wantErr: true,
+		},
+		{
+			// Busybox prints UNKNOWN for the name of filesystems it doesn't know, such as bpf.
+			name:    "bpf filesystem name instead of magic",
+			output:  "bpffs=UNKNOWN\n",
+			check:   checkBPFFS,
+			wantErr: true,
+		},
+		{
+			name:    "bpf filesystem missing",
+			output:  "btf=true\nbpffs=\n",
+			check:   checkBPFFS,
+			wantErr: true,
+		},
+		{
+			name:       "btf",
+			output:     "btf=true\n",
+			check:      checkBTF,
+			wantDetail: "node node-1 exposes BTF",
+		},
+		{
+			name:    "no btf",
+			output:  "btf=false\n",
+			check:   checkBTF,
+			wantErr: true,
+		},
+		{
+			name:       "cgroup v2",
+			output:     "btf=true\nbpffs=cafe4a11\ncgroupfs=63677270\n",
+			check:      checkCgroupFS,
+			wantDetail: "node node-1 uses cgroup v2",
+		},
+		{
+			name:       "cgroup v1",
+			output:     "btf=false\nbpffs=cafe4a11\ncgroupfs=1021994\n",
+			check:      checkCgroupFS,
+			wantDetail: "node node-1 uses cgroup v1",
+		},
+		{
+			name:    "cgroup unknown",
+			output:  "cgroupfs=ef53\n",
+			check:   checkCgroupFS,
+			wantErr: true,
+		},
+		{
+			name:    "empty output",
+			output:  "",
+			check:   checkCgroupFS,
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			detail, err := test.check("node-1", parseProbeOutput(test.output))
+			if test.wantErr {
+				require.Error(t, err)
+				var checkErr *CheckError
+				assert.True(t, errors.As(err, &checkErr))
+				assert.NotEmpty(t, checkErr.Remediation)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.wantDetail, detail)
+		})
+	}
+}
+
+func TestParseProbeOutput(t *testing.T) {
+	results := parseProbeOutput("btf=true\n  bpffs=cafe4a11  \nnot a result\ncgroupfs=\n")
+	assert.Equal(t, map[string]string{
+		"btf":      "true",
+		"bpffs":    "cafe4a11",
+		"cgroupfs": "",
+	}, results)
+}
@@ -44,9 +44,15 @@ func NewLister(cloudAddr string) (*Lister, error) {
 
 // GetViziersInfo returns information about connected viziers.
 func (l *Lister) GetViziersInfo() ([]*cloudpb.ClusterInfo, error) {
+	return l.GetViziersInfoBySelector("")
+}
+
+// GetViziersInfoBySelector returns information about connected viziers whose labels match the
+// label selector. An empty selector matches all viziers.
+func (l *Lister) GetViziersInfoBySelector(selector string) ([]*cloudpb.ClusterInfo, error) {
 	ctx := auth.CtxWithCreds(context.Background())
 
-	c, err := l.vc.GetClusterInfo(ctx, &cloudpb.GetClusterInfoRequest{})
+	c, err := l.vc.GetClusterInfo(ctx, &cloudpb.GetClusterInfoRequest{LabelSelector: selector})
 	if err != nil {
 		return nil, err
 	}
@@ -64,3 +70,20 @@ func (l *Lister) GetVizierInfo(id uuid.UUID) ([]*cloudpb.ClusterInfo, error) {
 	}
 	return c.Clusters, nil
 }
+
+// WatchClusterMetadata streams the changes to K8s resources in a cluster.
+func (l *Lister) WatchClusterMetadata(ctx context.Context, req *cloudpb.WatchClusterMetadataRequest) (cloudpb.VizierClusterInfo_WatchClusterMetadataClient, error) {
+	return l.vc.WatchClusterMetadata(auth.CtxWithCreds(ctx), req)
+}
+
+// GetUpdatePolicy returns the policy for automatically updating the viziers in the current org.
+func (l *Lister) GetUpdatePolicy() (*cloudpb.VizierUpdatePolicy, error) {
+	ctx := auth.CtxWithCreds(context.Background())
+	return l.vc.GetVizierUpdatePolicy(ctx, &cloudpb.GetVizierUpdatePolicyRequest{})
+}
+
+// SetUpdatePolicy replaces the policy for automatically updating the viziers in the current org.
+func (l *Lister) SetUpdatePolicy(policy *cloudpb.VizierUpdatePolicy) (*cloudpb.VizierUpdatePolicy, error) {
+	ctx := auth.CtxWithCreds(context.Background())
+	return l.vc.SetVizierUpdatePolicy(ctx, &cloudpb.SetVizierUpdatePolicyRequest{Policy: policy})
+}
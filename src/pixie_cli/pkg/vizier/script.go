@@ -32,6 +32,7 @@ import (
 
 	apiutils "px.dev/pixie/src/api/go/pxapi/utils"
 	"px.dev/pixie/src/api/proto/vizierpb"
+	"px.dev/pixie/src/pixie_cli/pkg/components"
 	"px.dev/pixie/src/pixie_cli/pkg/pxanalytics"
 	"px.dev/pixie/src/pixie_cli/pkg/pxconfig"
 	"px.dev/pixie/src/pixie_cli/pkg/utils"
@@ -61,9 +62,9 @@ func (t *taskWrapper) Run() error {
 // RunScriptAndOutputResults runs the specified script on vizier and outputs based on format string.
 func RunScriptAndOutputResults(ctx context.Context, conns []*Connector, execScript *script.ExecutableScript, format string, useEncryption bool) error {
 	// Check for the presence of df.stream() in the query.
-	if strings.Contains(execScript.ScriptString, "stream()") && format != "json" {
+	if strings.Contains(execScript.ScriptString, "stream()") && !components.IsStructuredFormat(format) {
 		return fmt.Errorf("Cannot execute a query containing df.stream() using px run with table output. " +
-			"Please try using `px live` instead or setting output format to json (`-o json`).")
+			"Please try using `px live` instead or setting output format to json, go-template or jsonpath (`-o json`).")
 	}
 
 	tw, err := runScript(ctx, conns, execScript, format, useEncryption)
@@ -94,7 +94,8 @@ const FormatInMemory string = "inmemory"
 func NewStreamOutputAdapterWithFactory(ctx context.Context, stream chan *ExecData, format string,
 	decOpts *vizierpb.ExecuteScriptRequest_EncryptionOptions,
 	factoryFunc func(*vizierpb.ExecuteScriptResponse_MetaData) components.OutputStreamWriter) *StreamOutputAdapter {
-	enableFormat := format != "json" && format != FormatInMemory
+	// Structured formats, such as JSON and templates, get the raw values rather than the ones formatted for humans.
+	enableFormat := !components.IsStructuredFormat(format) && format != FormatInMemory
 
 	adapter := &StreamOutputAdapter{
 		tableNameToInfo:     make(map[string]*TableInfo),
@@ -31,6 +31,7 @@ import (
 	"k8s.io/client-go/rest"
 
 	"px.dev/pixie/src/api/proto/cloudpb"
+	"px.dev/pixie/src/pixie_cli/pkg/pxconfig"
 	cliUtils "px.dev/pixie/src/pixie_cli/pkg/utils"
 	"px.dev/pixie/src/utils"
 	"px.dev/pixie/src/utils/shared/k8s"
@@ -195,12 +196,14 @@ func FirstHealthyVizier(cloudAddr string) (uuid.UUID, error) {
 	return uuid.Nil, errors.New("no healthy Viziers available")
 }
 
-// GetCurrentVizier tries to get the ID of the current Vizier, even if it is unhealthy.
+// GetCurrentVizier tries to get the ID of the current Vizier, even if it is unhealthy. The default cluster of the
+// active CLI context takes precedence over the current kubeconfig context.
 func GetCurrentVizier(cloudAddr string) (uuid.UUID, error) {
-	var clusterID uuid.UUID
-	config := k8s.GetConfig()
-	if config != nil {
-		clusterID = GetClusterIDFromKubeConfig(config)
+	clusterID := contextClusterID()
+	if clusterID == uuid.Nil {
+		if config := k8s.GetConfig(); config != nil {
+			clusterID = GetClusterIDFromKubeConfig(config)
+		}
 	}
 	if clusterID != uuid.Nil {
 		_, err := GetVizierInfo(cloudAddr, clusterID)
@@ -215,11 +218,31 @@ func GetCurrentVizier(cloudAddr string) (uuid.UUID, error) {
 	return clusterID, nil
 }
 
-// GetCurrentOrFirstHealthyVizier tries to get the vizier from the current context. If unavailable, it gets the ID of
-// the first healthy Vizier.
+// contextClusterID returns the default cluster of the active CLI context, if one is set.
+func contextClusterID() uuid.UUID {
+	ctx := pxconfig.ActiveContext()
+	if ctx == nil {
+		return uuid.Nil
+	}
+	return uuid.FromStringOrNil(ctx.ClusterID)
+}
+
+// GetCurrentOrFirstHealthyVizier tries to get the default cluster of the active CLI context, and then the vizier from
+// the current kubeconfig context. If unavailable, it gets the ID of the first healthy Vizier.
 func GetCurrentOrFirstHealthyVizier(cloudAddr string) (uuid.UUID, error) {
-	var clusterID uuid.UUID
 	var err error
+	if clusterID := contextClusterID(); clusterID != uuid.Nil {
+		clusterInfo, err := GetVizierInfo(cloudAddr, clusterID)
+		if err != nil {
+			cliUtils.WithError(err).Error("The default cluster of the CLI context not found within this org.")
+		} else if clusterInfo.Status != cloudpb.CS_HEALTHY && clusterInfo.Status != cloudpb.CS_DEGRADED {
+			cliUtils.Errorf("'%s', the default cluster of the CLI context, is unhealthy.", clusterInfo.PrettyClusterName)
+		} else {
+			return clusterID, nil
+		}
+	}
+
+	var clusterID uuid.UUID
 	config := k8s.GetConfig()
 	if config != nil {
 		clusterID = GetClusterIDFromKubeConfig(config)
@@ -325,6 +348,36 @@ func ConnectToAllViziers(cloudAddr string) ([]*Connector, error) {
 	return conns, nil
 }
 
+// ConnectToViziersBySelector connects to all healthy viziers whose labels match the label selector.
+func ConnectToViziersBySelector(cloudAddr string, selector string) ([]*Connector, error) {
+	l, err := NewLister(cloudAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	vzInfos, err := l.GetViziersInfoBySelector(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	var conns []*Connector
+	for _, vzInfo := range vzInfos {
+		if vzInfo.Status != cloudpb.CS_HEALTHY && vzInfo.Status != cloudpb.CS_DEGRADED {
+			continue
+		}
+		c, err := createVizierConnection(cloudAddr, vzInfo)
+		if err != nil {
+			return nil, err
+		}
+		conns = append(conns, c)
+	}
+
+	if len(conns) == 0 {
+		return nil, fmt.Errorf("no healthy Viziers match the selector %q", selector)
+	}
+	return conns, nil
+}
+
 // GetClusterIDFromKubeConfig returns the clusterID given the kubeconfig. If anything fails, then will return a nil UUID.
 func GetClusterIDFromKubeConfig(config *rest.Config) uuid.UUID {
 	if config == nil {
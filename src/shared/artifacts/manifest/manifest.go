@@ -19,6 +19,7 @@
 package manifest
 
 import (
+	"bytes"
 	"encoding/json"
 	"io"
 
@@ -29,18 +30,40 @@ import (
 // Internally, the artifacts are sorted by their versions, with newer versions first.
 type ArtifactManifest struct {
 	sets map[string]*sortedArtifactSet
+
+	// raw holds the exact bytes the manifest was read from, which is what detached signatures are
+	// computed over. It is empty for manifests that were constructed in memory.
+	raw []byte
+	// signature is the detached signature for raw, if one was provided.
+	signature []byte
 }
 
 // ReadArtifactManifest reads an ArtifactManifest from a json stream.
 func ReadArtifactManifest(r io.Reader) (*ArtifactManifest, error) {
-	dec := json.NewDecoder(r)
+	raw, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
 	m := &ArtifactManifest{}
 	if err := dec.Decode(m); err != nil {
 		return nil, err
 	}
+	m.raw = raw
 	return m, nil
 }
 
+// SetSignature attaches a detached signature to the manifest. The signature is checked against the
+// raw manifest bytes by a Verifier.
+func (a *ArtifactManifest) SetSignature(sig []byte) {
+	a.signature = sig
+}
+
+// Signature returns the detached signature attached to the manifest, if any.
+func (a *ArtifactManifest) Signature() []byte {
+	return a.signature
+}
+
 // Write writes an artifact manifest in JSON format to the given io.Writer.
 func (a *ArtifactManifest) Write(w io.Writer) error {
 	enc := json.NewEncoder(w)
@@ -19,7 +19,11 @@
 package manifest_test
 
 import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
 	"encoding/json"
+	"encoding/pem"
 	"fmt"
 	"strings"
 	"testing"
@@ -1398,3 +1402,78 @@ func TestManifest_ListArtifacts(t *testing.T) {
 		})
 	}
 }
+
+func TestVerifier_Verify(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	otherPub, otherPriv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	contents := `[{"name": "cli", "artifact": [{"versionStr": "0.1.0"}]}]`
+
+	testCases := []struct {
+		name        string
+		signature   []byte
+		keys        []ed25519.PublicKey
+		expectedErr error
+	}{
+		{
+			name:      "valid signature",
+			signature: manifest.Sign(priv, []byte(contents)),
+			keys:      []ed25519.PublicKey{pub},
+		},
+		{
+			name:      "valid signature with rotated keys",
+			signature: manifest.Sign(priv, []byte(contents)),
+			keys:      []ed25519.PublicKey{otherPub, pub},
+		},
+		{
+			name:        "signed by untrusted key",
+			signature:   manifest.Sign(otherPriv, []byte(contents)),
+			keys:        []ed25519.PublicKey{pub},
+			expectedErr: manifest.ErrInvalidSignature,
+		},
+		{
+			name:        "signature over different contents",
+			signature:   manifest.Sign(priv, []byte(contents+" ")),
+			keys:        []ed25519.PublicKey{pub},
+			expectedErr: manifest.ErrInvalidSignature,
+		},
+		{
+			name:        "unsigned",
+			keys:        []ed25519.PublicKey{pub},
+			expectedErr: manifest.ErrManifestUnsigned,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			m, err := manifest.ReadArtifactManifest(strings.NewReader(contents))
+			require.NoError(t, err)
+			m.SetSignature(tc.signature)
+
+			err = manifest.NewVerifier(tc.keys...).Verify(m)
+			if tc.expectedErr != nil {
+				require.ErrorIs(t, err, tc.expectedErr)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestParsePublicKeys(t *testing.T) {
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	require.NoError(t, err)
+	block := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+
+	keys, err := manifest.ParsePublicKeys(append(block, block...))
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+	require.Equal(t, pub, keys[0])
+
+	_, err = manifest.ParsePublicKeys([]byte("not a key"))
+	require.Error(t, err)
+}
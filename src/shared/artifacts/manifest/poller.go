@@ -98,8 +98,6 @@ func (p *pollerImpl) poll() error {
 		return nil
 	}
 
-	p.lastChecksum = cs
-
 	r, err := p.loc.ManifestReader(ctx)
 	if err != nil {
 		return err
@@ -110,8 +108,18 @@ func (p *pollerImpl) poll() error {
 	if err != nil {
 		return err
 	}
+	if sl, ok := p.loc.(SignedLocation); ok {
+		sig, err := sl.Signature(ctx)
+		if err != nil {
+			return err
+		}
+		m.SetSignature(sig)
+	}
+	// The checksum is only recorded once the manifest is accepted, so that a manifest that failed to load or was
+	// rejected is retried on the next poll. This lets a signature uploaded after its manifest be picked up.
 	if err := p.cb(m); err != nil {
 		return err
 	}
+	p.lastChecksum = cs
 	return nil
 }
@@ -0,0 +1,116 @@
+/*
+ * Copyright 2018- The Pixie Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package manifest
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+)
+
+// SignatureSuffix is appended to the manifest location to find its detached signature.
+const SignatureSuffix = ".sig"
+
+var (
+	// ErrManifestUnsigned is returned when verifying a manifest that has no signature attached.
+	ErrManifestUnsigned = errors.New("artifact manifest is not signed")
+	// ErrManifestNotRaw is returned when verifying a manifest that was not read from a stream, and
+	// so has no bytes to verify the signature against.
+	ErrManifestNotRaw = errors.New("artifact manifest has no raw contents to verify")
+	// ErrInvalidSignature is returned when a manifest signature does not match any trusted key.
+	ErrInvalidSignature = errors.New("artifact manifest signature does not match any trusted key")
+)
+
+// Sign creates a detached signature over the given manifest contents. The signature is base64
+// encoded, which matches the format produced by `cosign sign-blob` for ed25519 keys.
+func Sign(key ed25519.PrivateKey, contents []byte) []byte {
+	sig := ed25519.Sign(key, contents)
+	out := make([]byte, base64.StdEncoding.EncodedLen(len(sig)))
+	base64.StdEncoding.Encode(out, sig)
+	return out
+}
+
+// Verifier checks detached manifest signatures against a set of trusted ed25519 public keys.
+type Verifier struct {
+	keys []ed25519.PublicKey
+}
+
+// NewVerifier creates a Verifier that trusts the given public keys. Multiple keys are supported so
+// that signing keys can be rotated without downtime.
+func NewVerifier(keys ...ed25519.PublicKey) *Verifier {
+	return &Verifier{keys: keys}
+}
+
+// ParsePublicKeys parses one or more PEM encoded PKIX ed25519 public keys.
+func ParsePublicKeys(b []byte) ([]ed25519.PublicKey, error) {
+	var keys []ed25519.PublicKey
+	rest := b
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "PUBLIC KEY" {
+			return nil, fmt.Errorf("unexpected PEM block type %q", block.Type)
+		}
+		pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		key, ok := pub.(ed25519.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("unsupported public key type %T, only ed25519 keys are supported", pub)
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("no public keys found")
+	}
+	return keys, nil
+}
+
+// Verify checks that the manifest's detached signature was produced by one of the trusted keys.
+func (v *Verifier) Verify(m *ArtifactManifest) error {
+	if len(m.signature) == 0 {
+		return ErrManifestUnsigned
+	}
+	if len(m.raw) == 0 {
+		return ErrManifestNotRaw
+	}
+	return v.VerifyBytes(m.raw, m.signature)
+}
+
+// VerifyBytes checks a detached base64 encoded signature over the given contents.
+func (v *Verifier) VerifyBytes(contents []byte, sig []byte) error {
+	decoded, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(sig)))
+	if err != nil {
+		return fmt.Errorf("malformed signature: %w", err)
+	}
+	for _, k := range v.keys {
+		if ed25519.Verify(k, contents, decoded) {
+			return nil
+		}
+	}
+	return ErrInvalidSignature
+}
@@ -20,6 +20,8 @@ package manifest
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"io"
 	"net/http"
 
@@ -32,6 +34,13 @@ type Location interface {
 	ManifestReader(context.Context) (io.ReadCloser, error)
 }
 
+// SignedLocation is a Location that also stores a detached signature for the manifest.
+// Signature returns nil if the location has no signature for the manifest.
+type SignedLocation interface {
+	Location
+	Signature(context.Context) ([]byte, error)
+}
+
 type gcsManifest struct {
 	client       *storage.Client
 	bucket       string
@@ -62,9 +71,23 @@ func (gcs *gcsManifest) ManifestReader(ctx context.Context) (io.ReadCloser, erro
 	return obj.NewReader(ctx)
 }
 
+func (gcs *gcsManifest) Signature(ctx context.Context) ([]byte, error) {
+	obj := gcs.client.Bucket(gcs.bucket).Object(gcs.manifestPath + SignatureSuffix)
+	r, err := obj.NewReader(ctx)
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
 type httpManifest struct {
 	shaURL      string
 	manifestURL string
+	sigURL      string
 }
 
 // NewHTTPLocation returns a new Location for a manifest stored at an arbitrary http endpoint.
@@ -75,6 +98,16 @@ func NewHTTPLocation(shaURL string, manifestURL string) Location {
 	}
 }
 
+// NewSignedHTTPLocation returns a new SignedLocation for a manifest stored at an arbitrary http
+// endpoint, with its detached signature stored at sigURL.
+func NewSignedHTTPLocation(shaURL string, manifestURL string, sigURL string) SignedLocation {
+	return &httpManifest{
+		shaURL:      shaURL,
+		manifestURL: manifestURL,
+		sigURL:      sigURL,
+	}
+}
+
 func (h *httpManifest) Checksum(ctx context.Context) ([]byte, error) {
 	req, err := http.NewRequestWithContext(ctx, "GET", h.shaURL, http.NoBody)
 	if err != nil {
@@ -101,3 +134,26 @@ func (h *httpManifest) ManifestReader(ctx context.Context) (io.ReadCloser, error
 	}
 	return resp.Body, nil
 }
+
+func (h *httpManifest) Signature(ctx context.Context) ([]byte, error) {
+	if h.sigURL == "" {
+		return nil, nil
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", h.sigURL, http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch manifest signature: %s", resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}